@@ -2,45 +2,167 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+	"threadbound/internal/android"
 	"threadbound/internal/api"
+	"threadbound/internal/archive"
+	"threadbound/internal/assets"
 	"threadbound/internal/book"
+	"threadbound/internal/database"
+	"threadbound/internal/debugbundle"
+	"threadbound/internal/doctor"
+	"threadbound/internal/filter"
+	"threadbound/internal/logging"
+	"threadbound/internal/messenger"
 	"threadbound/internal/models"
+	"threadbound/internal/printing"
 	"threadbound/internal/service"
+	"threadbound/internal/themes"
+	"threadbound/internal/wizard"
+	"threadbound/pkg/client"
 )
 
 var config models.BookConfig
 var configFile string
+var profile string
 var apiPort int
+var apiOptions api.Options
+var drainTimeout time.Duration
+var archiveDir string
+var archivePackage bool
+var fromArchive string
+var fromAndroid string
+var fromMessenger string
+var remoteURL string
+var previewFormat string
+var previewDir string
+var previewThemes []string
+var initOptions wizard.Options
+var listChatsJSON bool
+var statsJSON bool
+var patchFrom string
+var patchTo string
+var buildChapters []string
+var debugOptions debugbundle.Options
+var verbose bool
+var quiet bool
+var jsonLogs bool
 
 var rootCmd = &cobra.Command{
 	Use:   "threadbound",
 	Short: "Convert iMessages database to a book",
 	Long: `A tool to extract iMessages from a SQLite database and convert them
 into a formatted book using XeLaTeX.`,
+	PersistentPreRunE: configureLogging,
 }
 
 var generateCmd = &cobra.Command{
-	Use:   "generate",
-	Short: "Generate TeX from iMessages database",
-	Long:  `Extract messages from the SQLite database and generate a TeX file`,
+	Use:     "generate",
+	Short:   "Generate TeX from iMessages database",
+	Long:    `Extract messages from the SQLite database and generate a TeX file`,
 	PreRunE: loadConfig,
-	RunE:  runGenerate,
+	RunE:    runGenerate,
 }
 
 var buildCmd = &cobra.Command{
-	Use:   "build-pdf",
-	Short: "Build PDF from TeX using XeLaTeX",
-	Long:  `Convert the generated TeX to PDF using XeLaTeX`,
+	Use:     "build-pdf",
+	Short:   "Build PDF from TeX using XeLaTeX",
+	Long:    `Convert the generated TeX to PDF using XeLaTeX`,
 	PreRunE: loadConfig,
-	RunE:  runBuildPDF,
+	RunE:    runBuildPDF,
+}
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Archive the database, attachments and a JSON export",
+	Long: `Copy the source database, every original attachment, decoded link metadata
+and a JSON export of the conversation into a checksummed archive directory`,
+	PreRunE: loadConfig,
+	RunE:    runArchive,
+}
+
+var themesCmd = &cobra.Command{
+	Use:   "themes",
+	Short: "Compare the installed book themes",
+}
+
+var themesPreviewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Render a sample chapter in each theme",
+	Long: `Render the same sample chapter once per installed theme and write a contact sheet
+comparing them side by side, with the config snippet that selects each one`,
+	PreRunE: loadConfig,
+	RunE:    runThemesPreview,
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up a config file by answering a few questions",
+	Long: `Find chat.db, pick a conversation from the list, name its participants and choose
+a page size, then write a config file for generate`,
+	RunE: runInit,
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Print detailed statistics without generating a book",
+	Long: `Count the messages the book would include: per person, attachments by kind,
+reactions by emoji and the date range, honoring the same chat, filter and exclusion options
+as generate`,
+	PreRunE: loadConfig,
+	RunE:    runStats,
+}
+
+var listChatsCmd = &cobra.Command{
+	Use:   "list-chats",
+	Short: "List the conversations in the database",
+	Long: `Print each chat in the database with its ROWID, name, participants, message count
+and date range, to pick one for generate --chat`,
+	PreRunE: loadConfig,
+	RunE:    runListChats,
+}
+
+var patchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Regenerate the chapters for a date range in an existing TeX book",
+	Long: `Regenerate the month chapters covering --from up to --to and splice them into the
+TeX book in place of the old ones, for fixes such as a contact's name without a full
+rebuild. Use the same config as generate, then rebuild the PDF with build-pdf.`,
+	PreRunE: loadConfig,
+	RunE:    runPatch,
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that everything needed to build a PDF is installed",
+	Long: `Check for XeLaTeX, the LaTeX packages the book uses, the theme's fonts, ImageMagick
+and a readable database, and say how to fix anything missing before a build fails part-way`,
+	PreRunE: loadConfig,
+	RunE:    runDoctor,
+}
+
+var debugBundleCmd = &cobra.Command{
+	Use:   "debug-bundle",
+	Short: "Collect a sanitized zip to attach to a bug report",
+	Long: `Collect the environment, config, template checksums, database schema and the shape of
+messages that render wrongly into a zip that can be shared when reporting a bug. Names,
+contact details, paths and message text are left out or redacted.`,
+	PreRunE: loadConfig,
+	RunE:    runDebugBundle,
 }
 
 var serveCmd = &cobra.Command{
@@ -53,6 +175,10 @@ var serveCmd = &cobra.Command{
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to config file (YAML format)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Use the settings of this profile from the config file's profiles section")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Also log details, such as each link preview fetched")
+	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Only log warnings and errors")
+	rootCmd.PersistentFlags().BoolVar(&jsonLogs, "json-logs", false, "Log JSON records instead of plain lines")
 
 	// Initialize config with defaults
 	defaultConfig := models.GetDefaultConfig()
@@ -62,115 +188,448 @@ func init() {
 	generateCmd.Flags().StringVar(&config.DatabasePath, "db", "chat.db", "Path to iMessages database")
 	generateCmd.Flags().StringVar(&config.AttachmentsPath, "attachments", "Attachments", "Path to attachments directory")
 	generateCmd.Flags().StringVar(&config.OutputPath, "output", "book.tex", "Output TeX file")
+	generateCmd.Flags().BoolVar(&config.BuildPDF, "pdf", false, "Also build the TeX output into a PDF beside it, in one step")
+	generateCmd.Flags().StringVar(&config.PDFEngine, "pdf-engine", "", "PDF engine: xelatex, lualatex, latexmk or tectonic (default: xelatex, or tectonic if only it is installed)")
+	generateCmd.Flags().StringVar(&config.WorkDir, "workdir", "", "Folder for each run's intermediate build files (default: the system temp folder)")
+	generateCmd.Flags().StringVar(&config.Format, "format", "", "Output format such as poster (default: from the output file extension)")
 	generateCmd.Flags().StringVar(&config.Title, "title", "Our Messages", "Book title")
 	generateCmd.Flags().StringVar(&config.Author, "author", "", "Book author")
 	generateCmd.Flags().StringVar(&config.PageWidth, "page-width", "5.5in", "Page width")
 	generateCmd.Flags().StringVar(&config.PageHeight, "page-height", "8.5in", "Page height")
+	generateCmd.Flags().StringVar(&config.PrintPreset, "print-preset", "", "Print-on-demand preset adding bleed and service margins: kdp, ingram or lulu")
+	generateCmd.Flags().IntVar(&config.PrintPages, "print-pages", 0, "Expected page count used to size the gutter (default: estimated)")
 	generateCmd.Flags().BoolVar(&config.IncludeImages, "include-images", true, "Include images in output")
+	generateCmd.Flags().StringVar(&remoteURL, "remote", "", "Generate on a threadbound server at this URL, uploading the database")
+	generateCmd.Flags().StringVar(&fromArchive, "from-archive", "", "Rebuild from a directory created by the archive command instead of --db and --attachments")
+	generateCmd.Flags().StringVar(&fromAndroid, "from-android", "", "Generate from an SMS Backup & Restore XML file instead of --db and --attachments")
+	generateCmd.Flags().StringVar(&fromMessenger, "from-messenger", "", "Generate from a Facebook Messenger or Instagram JSON data download instead of --db and --attachments")
+	generateCmd.MarkFlagsMutuallyExclusive("from-archive", "from-android", "from-messenger")
+	generateCmd.Flags().IntVar(&config.AttachmentWorkers, "workers", 0, "Concurrent attachment workers (0 = one per CPU)")
+	generateCmd.Flags().StringVar(&config.AttachmentReport, "report", "", "Write a JSON report of attachments missing from disk, unreadable or unsupported to this file")
+	generateCmd.Flags().BoolVar(&config.Timings, "timings", false, "Print a per-stage timing breakdown and record it in the manifest")
+	generateCmd.Flags().StringVar(&config.ImageConverter, "image-converter", "auto", "Image converter: auto, magick, sips, heif-convert or native")
+	generateCmd.Flags().IntVar(&config.Images.TargetDPI, "target-dpi", 0, "Warn about photos printing below this resolution (0 = 300)")
+	generateCmd.Flags().IntVar(&config.Images.MaxDimension, "max-image-size", 0, "Longest side of converted photos in pixels (0 = 1200)")
+	generateCmd.Flags().IntVar(&config.Images.JPEGQuality, "jpeg-quality", 0, "JPEG quality of converted photos, 1-100 (0 = 85)")
+	generateCmd.Flags().BoolVar(&config.Images.Grayscale, "grayscale", false, "Convert photos to grayscale for black-and-white printing")
+	generateCmd.Flags().StringVar(&config.MemoriesPath, "memories", "", "Folder of extra photos to insert by capture date")
+	generateCmd.Flags().IntVar(&config.NotesPages.Count, "notes-pages", 0, "Blank or ruled pages to insert for handwritten notes")
+	generateCmd.Flags().StringVar(&config.NotesPages.Style, "notes-style", "ruled", "Notes page style: blank or ruled")
+	generateCmd.Flags().StringSliceVar(&config.NotesPages.Positions, "notes-at", []string{"end"}, "Where to insert notes pages: end, year")
+	generateCmd.Flags().IntVar(&config.Volumes.MaxPages, "max-pages", 0, "Split the book into volumes of at most about this many pages (0 = no limit)")
+	generateCmd.Flags().StringVar(&config.Volumes.SplitBy, "split-by", "", "Split the book into volumes: year")
+	generateCmd.Flags().StringVar(&config.Volumes.Numbering, "page-numbering", "restart", "Page numbers of volumes: restart at 1 or continue from the previous volume")
+	generateCmd.Flags().BoolVar(&config.ChapterStats, "chapter-stats", false, "End each chapter with its message and photo counts")
+	generateCmd.Flags().BoolVar(&config.StatsChapter, "stats-chapter", false, "Add a \"By the Numbers\" chapter at the end of the book")
+	generateCmd.Flags().BoolVar(&config.CalendarPage, "calendar-page", false, "Add a calendar heatmap of every day to the front of the book")
+	generateCmd.Flags().StringVar(&config.WordCloud, "word-cloud", "", "Add a divider page with a cloud of the most used words: book, before the first chapter, or year, before each year's first chapter")
+	generateCmd.Flags().IntVar(&config.GreatestHits, "greatest-hits", 0, "Add a \"Greatest Hits\" page of this many messages that got the most reactions (0 to leave it out)")
+	generateCmd.Flags().BoolVar(&config.PhotoIndex, "photo-index", false, "Add a \"List of Photos\" with the date, sender and page of every photo to the back of a TeX book")
+	generateCmd.Flags().StringVar(&config.PhotoLayout, "photo-layout", "", "Photos in a TeX book: inline (default), or insert for thumbnails in the text and full photos on pages ending each chapter")
+	generateCmd.Flags().BoolVar(&config.Dedupe, "dedupe", false, "Collapse identical consecutive messages from the same sender")
+	generateCmd.Flags().IntVar(&config.URLWorkers, "url-workers", 0, "Messages whose link previews are made at once (0 = 8)")
+	generateCmd.Flags().DurationVar(&config.URLTimeout, "url-timeout", 0, "Time budget for link preview downloads; later links get a plain card (0 = 5m)")
+	generateCmd.Flags().DurationVar(&config.DedupeWindow, "dedupe-window", time.Minute, "Max time between messages collapsed by --dedupe")
+	generateCmd.Flags().StringSliceVar(&config.ExcludeContacts, "exclude-contacts", nil, "Drop messages from these contacts (phone numbers, emails or display names)")
+	generateCmd.Flags().StringSliceVar(&config.ExcludeKeywords, "exclude-keywords", nil, "Drop messages containing any of these phrases")
+	generateCmd.Flags().BoolVar(&config.FilterSpam, "filter-spam", false, "Drop messages from short codes and one-time passcode texts")
+	generateCmd.Flags().BoolVar(&config.Digest, "digest", false, "Keep only highlights (long messages, messages with reactions or photos) and the messages around them, for a condensed book; digest_rules in the config file sets the scores")
+	generateCmd.Flags().IntVar(&config.ChatID, "chat", 0, "Only include this conversation, by its chat ROWID (see threadbound init)")
+	generateCmd.Flags().StringVar(&config.Filter, "filter", "", `Keep only messages matching a query, e.g. 'from:Alice has:image before:2022-07-01 text:"camping"'`)
+	generateCmd.Flags().StringVar(&config.Timezone, "timezone", "", "Time zone messages are dated and grouped into days in, such as America/New_York (default: the local zone)")
+	generateCmd.Flags().StringVar(&config.Locale, "locale", "", "Language of headings, dates and \"Me\": en (default), de, es or fr")
+	generateCmd.Flags().StringVar(&config.Preview, "preview", "", "Build only this many messages, or days such as 30d, to check the layout quickly")
+	generateCmd.Flags().StringVar(&config.PreviewFrom, "preview-from", filter.PreviewFromStart, "Take the preview from the start or end of the conversation")
+	generateCmd.Flags().StringVar(&config.OnThisDay, "on-this-day", "", "Keep only the messages sent on this date (MM-DD or YYYY-MM-DD) in every year, for an anniversary book")
+	generateCmd.Flags().BoolVar(&config.ShowEffects, "show-effects", false, "Note the bubble or screen effect a message was sent with, e.g. \"sent with Confetti 🎉\"")
+	generateCmd.Flags().BoolVar(&config.SmartTypography, "smart-typography", false, "Curl straight quotes, turn -- into dashes and ... into an ellipsis, and space punctuation as the book's locale does")
+	generateCmd.Flags().BoolVar(&config.ShowAvatars, "show-avatars", false, "Draw each contact's avatar, or their initials, beside the first bubble of their messages in a row")
+	generateCmd.Flags().BoolVar(&config.URLFootnotes, "url-footnotes", false, "Keep links as text and print each URL as a numbered footnote instead of a preview image")
+	generateCmd.Flags().StringVar(&config.LinkStyle, "link-style", "", "How links without a preview image are printed: url (default) as sent, or footnote: the site in blue with the full address in a footnote (a real link in HTML)")
+	generateCmd.Flags().BoolVar(&config.SelfContained, "self-contained", false, "Inline the images of an HTML book as data URIs, making a single file that can be shared without the attachments folder")
+	generateCmd.Flags().BoolVar(&config.SplitChapters, "split-chapters", false, "Write each month chapter of a TeX book to its own file, included from the main file")
+	generateCmd.Flags().BoolVar(&config.Incremental, "incremental", false, "Cache each message's TeX beside the book and only render messages that changed since the last incremental run")
+	generateCmd.Flags().StringVar(&config.Text.Format, "text-format", "", "Format of .txt output: plain (default), or jsonl for a JSON object per message")
+	generateCmd.Flags().BoolVar(&config.Text.GUIDs, "text-guids", false, "Record each message's GUID in .txt output")
+	generateCmd.Flags().BoolVar(&config.Text.ISOTimestamps, "text-iso-timestamps", false, "Time messages in .txt output with full ISO 8601 dates")
+	generateCmd.Flags().BoolVar(&config.Text.SystemMessages, "text-system-messages", false, "Mark events such as a renamed conversation in .txt output rather than leave them out")
+	generateCmd.Flags().IntVar(&config.Text.ChunkTokens, "text-chunk-tokens", 0, "Also split .txt output into files of about this many tokens in <output>-chunks, for AI tools (0 = one file)")
+	generateCmd.Flags().StringVar(&config.ChapterStrategy, "chapter-strategy", "", "What each chapter of a TeX book covers: month (default), quarter, year, chat or none")
+	generateCmd.Flags().BoolVar(&config.HideTOC, "hide-toc", false, "Leave the table of contents out of a TeX book")
+	generateCmd.Flags().IntVar(&config.TOCDepth, "toc-depth", 0, "Levels in the table of contents: 1 for chapters, 2 to list each day too (default 2)")
+	generateCmd.Flags().IntVar(&config.GapDays, "gap-days", 0, "Mark a silence of at least this many days between messages with a separator such as \"three weeks later\" (0 = never)")
+	generateCmd.Flags().BoolVar(&config.Summaries.Enabled, "summaries", false, "Open each month chapter with an \"In this chapter\" summary written by the model at summaries.endpoint in the config file, which each month's messages are sent to")
+	generateCmd.Flags().IntVar(&config.DayMinLines, "day-min-lines", 0, "Lines that must fit below a day's heading, or the day starts on the next page (default 6)")
+	generateCmd.Flags().BoolVar(&config.RectoChapters, "recto-chapters", false, "Start each chapter of a TeX book on a right-hand page")
+	generateCmd.Flags().StringVar(&config.Dedication, "dedication", "", "Dedication printed on a page of its own after the copyright page")
+	generateCmd.Flags().StringVar(&config.ForewordPath, "foreword", "", "Markdown (.md) or TeX (.tex) file to print as a foreword before the first chapter")
+	generateCmd.Flags().IntVar(&config.OverflowLines, "overflow-lines", 0, "Cut messages longer than this many lines and print them in full in an appendix (0 = never)")
+	generateCmd.Flags().StringVar(&config.RedactProfile, "redact-profile", "", "Redaction profile to apply to message text (contacts, strict, or one from the config file)")
 
 	// Always enable URL previews
 	config.IncludePreviews = true
 
 	// Build command flags
 	buildCmd.Flags().StringVar(&config.OutputPath, "input", "book.tex", "Input TeX file")
-	buildCmd.Flags().StringVar(&config.TemplateDir, "template-dir", "internal/templates/tex", "Template directory")
+	buildCmd.Flags().StringVar(&config.TemplateDir, "template-dir", "", "Template directory (default: templates in the data directory, else built-in)")
 	buildCmd.Flags().StringVar(&config.PageWidth, "page-width", "5.5in", "Page width")
 	buildCmd.Flags().StringVar(&config.PageHeight, "page-height", "8.5in", "Page height")
+	buildCmd.Flags().BoolVar(&config.Timings, "timings", false, "Print a per-stage timing breakdown and record it in the manifest")
+	buildCmd.Flags().StringVar(&config.PDFEngine, "pdf-engine", "", "PDF engine: xelatex, lualatex, latexmk or tectonic (default: xelatex, or tectonic if only it is installed)")
+	buildCmd.Flags().StringVar(&config.WorkDir, "workdir", "", "Folder for each run's intermediate build files (default: the system temp folder)")
+	buildCmd.Flags().StringSliceVar(&buildChapters, "chapters", nil, "Build only these chapters of a book generated with --split-chapters, as YYYY-MM (comma-separated)")
+
+	// Themes command flags
+	themesPreviewCmd.Flags().StringVar(&previewFormat, "format", themes.FormatHTML, "Preview format: html or pdf (requires XeLaTeX)")
+	themesPreviewCmd.Flags().StringVar(&previewDir, "output", "theme-preview", "Directory for the previews and contact sheet")
+	themesPreviewCmd.Flags().StringSliceVar(&previewThemes, "themes", nil, "Themes to compare (default: all installed)")
+	themesPreviewCmd.Flags().StringVar(&config.TemplateDir, "template-dir", "", "Template directory (default: templates in the data directory, else built-in)")
+	themesPreviewCmd.Flags().StringVar(&config.PageWidth, "page-width", "5.5in", "Page width")
+	themesPreviewCmd.Flags().StringVar(&config.PageHeight, "page-height", "8.5in", "Page height")
+	themesCmd.AddCommand(themesPreviewCmd)
+
+	// Init command flags
+	initCmd.Flags().StringVar(&initOptions.DatabasePath, "db", "", "Suggested database (default: ~/Library/Messages/chat.db if present)")
+	initCmd.Flags().StringVar(&initOptions.ConfigPath, "output", wizard.DefaultConfigPath, "Config file to write")
+	initCmd.Flags().BoolVar(&initOptions.Force, "force", false, "Overwrite an existing config file without asking")
+
+	// Stats command flags
+	statsCmd.Flags().StringVar(&config.DatabasePath, "db", "chat.db", "Path to iMessages database")
+	statsCmd.Flags().IntVar(&config.ChatID, "chat", 0, "Only count this conversation, by its chat ROWID (see threadbound list-chats)")
+	statsCmd.Flags().StringVar(&config.Filter, "filter", "", "Only count messages matching a query, as for generate")
+	statsCmd.Flags().StringVar(&config.Timezone, "timezone", "", "Time zone the date range and filter dates are in (default: the local zone)")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print the statistics as JSON")
+
+	// List chats command flags
+	listChatsCmd.Flags().StringVar(&config.DatabasePath, "db", "chat.db", "Path to iMessages database")
+	listChatsCmd.Flags().BoolVar(&listChatsJSON, "json", false, "Print the chats as JSON")
+
+	// Patch command flags
+	patchCmd.Flags().StringVar(&config.DatabasePath, "db", "chat.db", "Path to iMessages database")
+	patchCmd.Flags().StringVar(&config.AttachmentsPath, "attachments", "Attachments", "Path to attachments directory")
+	patchCmd.Flags().StringVar(&config.OutputPath, "input", "book.tex", "TeX book to patch")
+	patchCmd.Flags().StringVar(&patchFrom, "from", "", "First day to regenerate, YYYY-MM-DD; its whole month is regenerated")
+	patchCmd.Flags().StringVar(&patchTo, "to", "", "Day to stop before, YYYY-MM-DD; months up to it are regenerated")
+	patchCmd.MarkFlagRequired("from")
+	patchCmd.MarkFlagRequired("to")
+
+	// Doctor command flags
+	doctorCmd.Flags().StringVar(&config.DatabasePath, "db", "chat.db", "Path to iMessages database")
+
+	// Debug bundle command flags
+	debugBundleCmd.Flags().StringVar(&config.DatabasePath, "db", "chat.db", "Path to iMessages database")
+	debugBundleCmd.Flags().StringVar(&debugOptions.Output, "output", debugbundle.DefaultOutput, "Zip file to write")
+	debugBundleCmd.Flags().IntSliceVar(&debugOptions.MessageIDs, "message", nil, "ROWID of a message that renders wrongly (repeatable)")
+	debugBundleCmd.Flags().StringSliceVar(&debugOptions.Logs, "log", nil, "Saved output of a failing run to include, scrubbed (repeatable)")
+
+	// Archive command flags
+	archiveCmd.Flags().StringVar(&config.DatabasePath, "db", "chat.db", "Path to iMessages database")
+	archiveCmd.Flags().StringVar(&config.AttachmentsPath, "attachments", "Attachments", "Path to attachments directory")
+	archiveCmd.Flags().StringVar(&archiveDir, "output", "threadbound-archive", "Archive directory to create")
+	archiveCmd.Flags().BoolVar(&archivePackage, "package", false, "Also package the archive as <output>.tar.zst (requires zstd)")
 
 	// Serve command flags
 	serveCmd.Flags().IntVar(&apiPort, "port", 8080, "API server port")
+	serveCmd.Flags().StringVar(&apiOptions.Workspace, "workspace", "", "Directory for job output and uploaded databases (default: threadbound in the temp directory)")
+	serveCmd.Flags().DurationVar(&drainTimeout, "drain-timeout", 5*time.Minute, "How long shutdown waits for running jobs before saving them to resume on the next start")
+	serveCmd.Flags().StringSliceVar(&apiOptions.AllowedOutputDirs, "allow-output-dir", nil, "Directory requests may name an absolute output_path in (repeatable)")
+	serveCmd.Flags().IntVar(&apiOptions.MaxConcurrentJobs, "max-jobs", api.DefaultMaxConcurrentJobs, "How many jobs generate at once; others wait in the queue")
+	serveCmd.Flags().IntVar(&apiOptions.MaxQueuedJobs, "max-queued", api.DefaultMaxQueuedJobs, "How many jobs may wait before generate requests get 429 Too Many Requests")
+	serveCmd.Flags().Int64Var(&apiOptions.MaxUploadSize, "max-upload-size", api.DefaultMaxUploadSize, "Largest database upload accepted, in bytes")
+	serveCmd.Flags().DurationVar(&apiOptions.UploadTTL, "upload-ttl", api.DefaultUploadTTL, "How long an upload no job has used is kept")
 
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(listChatsCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(debugBundleCmd)
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(patchCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(themesCmd)
 	rootCmd.AddCommand(serveCmd)
 }
 
-// loadConfig loads configuration from file if specified, otherwise uses defaults and flags
+// loadConfig sets config from the config file and THREADBOUND_* environment variables,
+// keeping the flags given and the defaults of the rest
 func loadConfig(cmd *cobra.Command, args []string) error {
+	if configFile == "" {
+		configFile = os.Getenv(models.EnvPrefix + "CONFIG")
+	}
+	if profile == "" {
+		profile = os.Getenv(models.EnvPrefix + "PROFILE")
+	}
+
+	// Every setting comes from its flag if given, else its THREADBOUND_* environment
+	// variable, else the config file, else its default
+	if err := config.Bind(configFile, profile, os.LookupEnv, cmd.Flags().Changed); err != nil {
+		return err
+	}
 	if configFile != "" {
-		fileConfig, err := models.LoadConfigFromFile(configFile)
+		// IncludePreviews is always enabled for now
+		config.IncludePreviews = true
+	}
+
+	// Flags may also carry Windows-style or ~ paths
+	config.NormalizePaths()
+
+	// Installed binaries look for custom templates in the data directory
+	if config.TemplateDir == "" {
+		config.TemplateDir = assets.Dir(assets.Templates)
+	}
+	return nil
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	fmt.Printf("📚 iMessages Book Generator\n")
+	if fromArchive != "" {
+		fmt.Printf("Archive: %s\n", fromArchive)
+		cleanup, err := archive.Open(fromArchive, &config)
 		if err != nil {
 			return err
 		}
-
-		// Merge file config with command-line flags
-		// Command-line flags take precedence over config file
-		if !cmd.Flags().Changed("title") && fileConfig.Title != "" {
-			config.Title = fileConfig.Title
-		}
-		if !cmd.Flags().Changed("author") && fileConfig.Author != "" {
-			config.Author = fileConfig.Author
-		}
-		if !cmd.Flags().Changed("db") && fileConfig.DatabasePath != "" {
-			config.DatabasePath = fileConfig.DatabasePath
-		}
-		if !cmd.Flags().Changed("attachments") && fileConfig.AttachmentsPath != "" {
-			config.AttachmentsPath = fileConfig.AttachmentsPath
-		}
-		if !cmd.Flags().Changed("output") && fileConfig.OutputPath != "" {
-			config.OutputPath = fileConfig.OutputPath
-		}
-		if !cmd.Flags().Changed("template-dir") && fileConfig.TemplateDir != "" {
-			config.TemplateDir = fileConfig.TemplateDir
-		}
-		if !cmd.Flags().Changed("include-images") {
-			config.IncludeImages = fileConfig.IncludeImages
-		}
-		if !cmd.Flags().Changed("page-width") && fileConfig.PageWidth != "" {
-			config.PageWidth = fileConfig.PageWidth
-		}
-		if !cmd.Flags().Changed("page-height") && fileConfig.PageHeight != "" {
-			config.PageHeight = fileConfig.PageHeight
+		defer cleanup()
+	}
+	if fromAndroid != "" {
+		fmt.Printf("Android backup: %s\n", fromAndroid)
+		cleanup, err := android.Open(fromAndroid, &config)
+		if err != nil {
+			return err
 		}
-
-		// Merge contact names from config file
-		if fileConfig.ContactNames != nil {
-			config.ContactNames = fileConfig.ContactNames
+		defer cleanup()
+	}
+	if fromMessenger != "" {
+		fmt.Printf("Messenger export: %s\n", fromMessenger)
+		cleanup, err := messenger.Open(fromMessenger, &config)
+		if err != nil {
+			return err
 		}
+		defer cleanup()
+	}
+	if remoteURL != "" {
+		return runRemoteGenerate(cmd.Context(), remoteURL)
+	}
+	fmt.Printf("Database: %s\n", config.DatabasePath)
+	fmt.Printf("Output: %s\n", config.OutputPath)
+	if config.BuildPDF {
+		fmt.Printf("PDF: %s\n", book.PDFPathFor(config.OutputPath))
+	}
+	fmt.Printf("Title: %s\n", config.Title)
+	fmt.Println()
 
-		// Merge my_name from config file
-		if fileConfig.MyName != "" {
-			config.MyName = fileConfig.MyName
-		}
+	// Use service layer for generation
+	genService := service.NewGeneratorService(&config)
 
-		// IncludePreviews is always enabled for now
-		config.IncludePreviews = true
+	// Generate the book
+	result, err := genService.Generate()
+	if err != nil {
+		return err
+	}
+	printStats(result.Stats)
+	if result.PDFPath != "" {
+		fmt.Printf("\n✅ Built %s from %s\n", result.PDFPath, result.OutputPath)
+	}
+	if len(result.Volumes) > 0 {
+		printVolumes(result.Volumes)
+		return nil
+	}
+	if result.PageCount > 0 {
+		fmt.Printf("📊 PDF Info:\n")
+		printPageCount(result.PageCount)
 	}
 	return nil
 }
 
-func runGenerate(cmd *cobra.Command, args []string) error {
-	fmt.Printf("📚 iMessages Book Generator\n")
+// runRemoteGenerate uploads the database to a threadbound server, generates the book there
+// and downloads the result to the output path. Attachments stay local, so the server uses
+// its own attachments folder if it has one.
+// remoteRedactProfiles converts custom redaction profiles for a generate request
+func remoteRedactProfiles(profiles map[string]models.RedactRules) map[string]client.RedactRules {
+	if len(profiles) == 0 {
+		return nil
+	}
+	converted := make(map[string]client.RedactRules, len(profiles))
+	for name, rules := range profiles {
+		converted[name] = client.RedactRules(rules)
+	}
+	return converted
+}
+
+func runRemoteGenerate(ctx context.Context, serverURL string) error {
+	fmt.Printf("Server: %s\n", serverURL)
 	fmt.Printf("Database: %s\n", config.DatabasePath)
 	fmt.Printf("Output: %s\n", config.OutputPath)
 	fmt.Printf("Title: %s\n", config.Title)
 	fmt.Println()
 
-	// Use service layer for generation
-	genService := service.NewGeneratorService(&config)
+	c := client.New(serverURL)
+	if err := c.Health(ctx); err != nil {
+		return fmt.Errorf("server not reachable: %w", err)
+	}
+
+	// Upload a consistent copy of a database Messages is using
+	dbPath := config.DatabasePath
+	if database.NeedsSnapshot(dbPath) {
+		snapshotPath, cleanup, err := database.Snapshot(dbPath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		dbPath = snapshotPath
+	}
+
+	fmt.Println("⬆️  Uploading database...")
+	upload, err := c.Upload(ctx, dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to upload database: %w", err)
+	}
+
+	gen, err := c.Generate(ctx, client.GenerateRequest{
+		UploadID:      upload.UploadID,
+		OutputPath:    filepath.Base(config.OutputPath),
+		Title:         config.Title,
+		Author:        config.Author,
+		PageWidth:     config.PageWidth,
+		PageHeight:    config.PageHeight,
+		IncludeImages: config.IncludeImages,
+		ContactNames:  config.ContactNames,
+		MyName:        config.MyName,
+		Filter:        config.Filter,
+		ChatID:        config.ChatID,
+		Timezone:      config.Timezone,
+		Locale:        config.Locale,
+
+		RedactProfile:   config.RedactProfile,
+		RedactProfiles:  remoteRedactProfiles(config.RedactProfiles),
+		ExcludeContacts: config.ExcludeContacts,
+		ExcludeKeywords: config.ExcludeKeywords,
+		FilterSpam:      config.FilterSpam,
+		Dedupe:          config.Dedupe,
+		Theme:           client.Theme(config.Theme),
+		PrintPreset:     config.PrintPreset,
+		PrintPages:      config.PrintPages,
+		NotesPages:      client.NotesPages(config.NotesPages),
+		ChapterStats:    config.ChapterStats,
+		StatsChapter:    config.StatsChapter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start generation: %w", err)
+	}
+	fmt.Printf("⏳ Job %s submitted, waiting for it to finish...\n", gen.JobID)
+
+	job, err := c.Wait(ctx, gen.JobID, time.Second)
+	if err != nil {
+		// Show the end of the server's output so the failure can be diagnosed locally
+		if logs, logErr := c.Logs(ctx, gen.JobID, 20); logErr == nil && len(logs.Lines) > 0 {
+			fmt.Printf("📜 Last %d lines of the job log:\n", len(logs.Lines))
+			for _, line := range logs.Lines {
+				fmt.Printf("   %s\n", line)
+			}
+		}
+		return err
+	}
 
-	// Get and show statistics first
-	stats, err := genService.GetStats()
+	if stats := job.Stats; stats != nil {
+		fmt.Printf("📊 Book Statistics:\n")
+		fmt.Printf("   Messages: %d (%d with text)\n", stats.TotalMessages, stats.TextMessages)
+		fmt.Printf("   Contacts: %d\n", stats.TotalContacts)
+		fmt.Printf("   Attachments: %d\n", stats.AttachmentCount)
+		fmt.Println()
+	}
+
+	out, err := os.Create(config.OutputPath)
 	if err != nil {
-		return fmt.Errorf("failed to get stats: %w", err)
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := c.Download(ctx, gen.JobID, out); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Book downloaded to %s\n", config.OutputPath)
+	if job.PageCount > 0 {
+		fmt.Printf("📊 PDF Info:\n")
+		printPageCount(job.PageCount)
+	}
+	return nil
+}
+
+// printFonts lists the fonts of a PDF, warning of any not embedded, which print services
+// reject
+func printFonts(fonts []models.PDFFont) {
+	if len(fonts) == 0 {
+		return
+	}
+	var names, missing []string
+	for _, font := range fonts {
+		names = append(names, font.Name)
+		if !font.Embedded {
+			missing = append(missing, font.Name)
+		}
+	}
+	fmt.Printf("   Fonts: %s\n", strings.Join(names, ", "))
+	if len(missing) > 0 {
+		fmt.Printf("   ⚠️  Not embedded: %s\n", strings.Join(missing, ", "))
 	}
+}
 
-	fmt.Printf("📊 Book Statistics:\n")
+// printStats shows what went into a generated book
+func printStats(stats *models.BookStats) {
+	if stats == nil {
+		return
+	}
+	fmt.Printf("\n📊 Book Statistics:\n")
 	fmt.Printf("   Messages: %d (%d with text)\n", stats.TotalMessages, stats.TextMessages)
 	fmt.Printf("   Contacts: %d\n", stats.TotalContacts)
 	fmt.Printf("   Attachments: %d\n", stats.AttachmentCount)
+	if stats.ExcludedByContact > 0 || stats.ExcludedByKeyword > 0 || stats.ExcludedAsSpam > 0 {
+		fmt.Printf("   Excluded: %d by contact, %d by keyword, %d as spam\n",
+			stats.ExcludedByContact, stats.ExcludedByKeyword, stats.ExcludedAsSpam)
+	}
+	if config.Filter != "" {
+		fmt.Printf("   Filter: %s (%d messages left out)\n", config.Filter, stats.ExcludedByFilter)
+	}
 	if !stats.StartDate.IsZero() && !stats.EndDate.IsZero() {
 		fmt.Printf("   Date Range: %s to %s\n",
 			stats.StartDate.Format("Jan 2, 2006"),
 			stats.EndDate.Format("Jan 2, 2006"))
 	}
-	fmt.Println()
+}
 
-	// Generate the book
-	_, err = genService.Generate()
-	return err
+// printVolumes lists the volumes a split book was written as, with each one's pages
+func printVolumes(volumes []book.Volume) {
+	fmt.Printf("\n📚 Volumes:\n")
+	for _, volume := range volumes {
+		path := volume.Path
+		if config.BuildPDF {
+			path = book.PDFPathFor(volume.Path)
+		}
+		pages := fmt.Sprintf("%d pages", volume.Pages)
+		if volume.Estimated {
+			pages = fmt.Sprintf("about %d pages", volume.Pages)
+		}
+		fmt.Printf("   %d. %s: %s to %s, %d messages, %s from page %d\n", volume.Number, path,
+			volume.From.Format("Jan 2006"), volume.To.Format("Jan 2006"), volume.Messages, pages, volume.FirstPage)
+	}
 }
 
+// printPageCount shows the page count and the spine width it gives on each paper stock, for
+// sizing a cover. Nothing is printed when the page count is unknown.
+func printPageCount(pages int) {
+	if pages <= 0 {
+		return
+	}
+	fmt.Printf("   Pages: %d\n", pages)
+	fmt.Printf("   Spine Width:")
+	for _, stock := range printing.Stocks() {
+		fmt.Printf(" %.3fin %s", stock.SpineWidth(pages), stock.Name)
+	}
+	fmt.Println()
+}
 
 func runBuildPDF(cmd *cobra.Command, args []string) error {
 	fmt.Printf("📚 iMessages PDF Builder\n")
@@ -181,13 +640,13 @@ func runBuildPDF(cmd *cobra.Command, args []string) error {
 
 	// Create PDF builder
 	pdfBuilder := book.NewPDFBuilder(&config)
+	if len(buildChapters) > 0 {
+		fmt.Printf("📑 Building only: %s\n\n", strings.Join(buildChapters, ", "))
+		pdfBuilder.OnlyChapters(buildChapters)
+	}
 
 	// Generate output filename
-	outputPDF := "book.pdf"
-	if config.OutputPath != "book.tex" && len(config.OutputPath) > 4 && config.OutputPath[len(config.OutputPath)-4:] == ".tex" {
-		// Replace .tex extension with .pdf
-		outputPDF = config.OutputPath[:len(config.OutputPath)-4] + ".pdf"
-	}
+	outputPDF := book.PDFPathFor(config.OutputPath)
 
 	// Build the PDF
 	err := pdfBuilder.BuildPDF(config.OutputPath, outputPDF)
@@ -202,8 +661,20 @@ func runBuildPDF(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Printf("📊 PDF Info:\n")
 		fmt.Printf("   File: %s\n", info.FilePath)
+		fmt.Printf("   Title: %s\n", info.Title)
+		if info.Author != "" {
+			fmt.Printf("   Author: %s\n", info.Author)
+		}
+		if !info.CreationDate.IsZero() {
+			fmt.Printf("   Created: %s\n", info.CreationDate.Format("2006-01-02 15:04:05 MST"))
+		}
+		if info.Producer != "" {
+			fmt.Printf("   Producer: %s\n", info.Producer)
+		}
+		printFonts(info.Fonts)
 		fmt.Printf("   Size: %d bytes (%.2f MB)\n", info.FileSize, float64(info.FileSize)/(1024*1024))
 		fmt.Printf("   Dimensions: %s x %s\n", info.PageWidth, info.PageHeight)
+		printPageCount(info.PageCount)
 	}
 
 	// Suggest preview command
@@ -213,9 +684,366 @@ func runBuildPDF(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runPatch(cmd *cobra.Command, args []string) error {
+	from, err := time.Parse(filter.QueryDateFormat, patchFrom)
+	if err != nil {
+		return fmt.Errorf("invalid --from date %q: use YYYY-MM-DD", patchFrom)
+	}
+	to, err := time.Parse(filter.QueryDateFormat, patchTo)
+	if err != nil {
+		return fmt.Errorf("invalid --to date %q: use YYYY-MM-DD", patchTo)
+	}
+
+	if _, err := os.Stat(config.DatabasePath); err != nil {
+		return fmt.Errorf("database not found: %s", config.DatabasePath)
+	}
+
+	fmt.Printf("🩹 Patching %s from %s up to %s\n", config.OutputPath, patchFrom, patchTo)
+	months, err := service.NewGeneratorService(&config).Patch(from, to)
+	if err != nil {
+		return err
+	}
+	if len(months) == 0 {
+		fmt.Println("ℹ️  The book has no chapters in that range; nothing was replaced")
+		return nil
+	}
+	fmt.Printf("✅ Replaced chapters: %s\n", strings.Join(months, ", "))
+	fmt.Printf("Rebuild the PDF with: threadbound build-pdf --input %s\n", config.OutputPath)
+	return nil
+}
+
+func runArchive(cmd *cobra.Command, args []string) error {
+	fmt.Printf("🗃️  iMessages Archiver\n")
+	fmt.Printf("Database: %s\n", config.DatabasePath)
+	fmt.Printf("Archive: %s\n", archiveDir)
+	fmt.Println()
+
+	manifest, err := archive.Create(&config, archiveDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n📊 Archive Contents:\n")
+	fmt.Printf("   Messages: %d\n", manifest.Messages)
+	fmt.Printf("   Contacts: %d\n", manifest.Handles)
+	fmt.Printf("   Attachments: %d (%d missing)\n", manifest.Attachments, manifest.MissingAttachments)
+	fmt.Printf("   Files: %d\n", len(manifest.Files))
+
+	if archivePackage {
+		fmt.Println("\n📦 Packaging archive...")
+		packaged, err := archive.Package(archiveDir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Packaged: %s\n", packaged)
+	}
+
+	fmt.Printf("\n✅ Archive written to %s\n", archiveDir)
+	return nil
+}
+
+func runThemesPreview(cmd *cobra.Command, args []string) error {
+	installed, err := themes.Installed()
+	if err != nil {
+		return err
+	}
+	selected, err := themes.Select(installed, previewThemes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🎨 Theme Preview\n")
+	fmt.Printf("Themes: %d\n", len(selected))
+	fmt.Printf("Format: %s\n", previewFormat)
+	fmt.Println()
+
+	sheet, err := themes.Preview(selected, config, previewFormat, previewDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ Contact sheet: %s\n", sheet)
+	fmt.Printf("📖 Copy the theme section under a preview into your config file to use it\n")
+	return nil
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	_, err := wizard.Run(os.Stdin, os.Stdout, initOptions)
+	if errors.Is(err, wizard.ErrCanceled) {
+		fmt.Println("Canceled; no config file written")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ Wrote %s\n", initOptions.ConfigPath)
+	fmt.Printf("   Next: threadbound generate --config %s\n", initOptions.ConfigPath)
+	return nil
+}
+
+// statsOutput is the statistics as stats --json prints them
+type statsOutput struct {
+	Messages        int            `json:"messages"`
+	TextMessages    int            `json:"text_messages"`
+	Contacts        int            `json:"contacts"`
+	WithAttachments int            `json:"messages_with_attachments"`
+	FirstMessage    *time.Time     `json:"first_message,omitempty"`
+	LastMessage     *time.Time     `json:"last_message,omitempty"`
+	Excluded        map[string]int `json:"excluded"`
+	People          []statsPerson  `json:"people"`
+	Attachments     map[string]int `json:"attachments"`
+	Reactions       map[string]int `json:"reactions"`
+}
+
+// statsPerson is one sender's counts as stats --json prints them
+type statsPerson struct {
+	Name     string `json:"name"`
+	Messages int    `json:"messages"`
+	Words    int    `json:"words"`
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	checks := doctor.Run(&config)
+	for _, check := range checks {
+		switch check.Status {
+		case doctor.Pass:
+			fmt.Printf("✅ %s: %s\n", check.Name, check.Detail)
+		case doctor.Warn:
+			fmt.Printf("⚠️  %s: %s\n", check.Name, check.Detail)
+		default:
+			fmt.Printf("❌ %s: %s\n", check.Name, check.Detail)
+		}
+		if check.Fix != "" {
+			fmt.Printf("   → %s\n", check.Fix)
+		}
+	}
+
+	if failed := doctor.Failed(checks); failed > 0 {
+		// The fixes above say what to do; the usage text wouldn't help
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	fmt.Println("\n🎉 Ready to build PDFs")
+	return nil
+}
+
+func runDebugBundle(cmd *cobra.Command, args []string) error {
+	fmt.Printf("🧰 Collecting debug bundle...\n")
+	debugOptions.Config = &config
+	files, err := debugbundle.Create(debugOptions)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		fmt.Printf("   %s\n", file)
+	}
+	fmt.Printf("\n✅ Debug bundle written to %s\n", debugOptions.Output)
+	fmt.Println("   Look through it before attaching it to a bug report")
+	return nil
+}
+
+// configureLogging sets the level and format of progress logging from the global flags,
+// and creates the logger every command's progress goes to
+func configureLogging(cmd *cobra.Command, args []string) error {
+	if verbose && quiet {
+		return fmt.Errorf("--verbose and --quiet can't be used together")
+	}
+	options := logging.Options{Level: slog.LevelInfo, JSON: jsonLogs}
+	if verbose {
+		options.Level = slog.LevelDebug
+	}
+	if quiet {
+		options.Level = slog.LevelWarn
+	}
+	logging.Configure(options)
+	config.Logger = logging.Default(os.Stdout)
+	return nil
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(config.DatabasePath); err != nil {
+		return fmt.Errorf("database not found: %s", config.DatabasePath)
+	}
+	// Keep progress out of the JSON
+	if statsJSON {
+		config.Logger = logging.Default(os.Stderr)
+	}
+
+	stats, breakdown, err := service.NewGeneratorService(&config).GetDetailedStats()
+	if err != nil {
+		return err
+	}
+
+	out := statsOutput{
+		Messages:        stats.TotalMessages,
+		TextMessages:    stats.TextMessages,
+		Contacts:        stats.TotalContacts,
+		WithAttachments: stats.AttachmentCount,
+		Excluded: map[string]int{
+			"by_contact": stats.ExcludedByContact,
+			"by_keyword": stats.ExcludedByKeyword,
+			"as_spam":    stats.ExcludedAsSpam,
+			"by_filter":  stats.ExcludedByFilter,
+		},
+		People:      []statsPerson{},
+		Attachments: breakdown.Attachments,
+		Reactions:   breakdown.Reactions,
+	}
+	if !stats.StartDate.IsZero() {
+		out.FirstMessage, out.LastMessage = &stats.StartDate, &stats.EndDate
+	}
+	for _, person := range breakdown.People {
+		out.People = append(out.People, statsPerson{Name: person.Name, Messages: person.Messages, Words: person.Words})
+	}
+
+	if statsJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	}
+
+	fmt.Printf("📊 Statistics for %s\n", config.DatabasePath)
+	fmt.Printf("   Messages: %d (%d with text, %d with attachments)\n", out.Messages, out.TextMessages, out.WithAttachments)
+	fmt.Printf("   Contacts: %d\n", out.Contacts)
+	if out.FirstMessage != nil {
+		fmt.Printf("   Date Range: %s to %s\n", out.FirstMessage.Format("Jan 2, 2006"), out.LastMessage.Format("Jan 2, 2006"))
+	}
+	if excluded := stats.ExcludedByContact + stats.ExcludedByKeyword + stats.ExcludedAsSpam + stats.ExcludedByFilter; excluded > 0 {
+		fmt.Printf("   Excluded: %d by contact, %d by keyword, %d as spam, %d by filter\n",
+			stats.ExcludedByContact, stats.ExcludedByKeyword, stats.ExcludedAsSpam, stats.ExcludedByFilter)
+	}
+
+	fmt.Printf("\n👥 People\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "   NAME\tMESSAGES\tWORDS")
+	for _, person := range out.People {
+		fmt.Fprintf(w, "   %s\t%d\t%d\n", person.Name, person.Messages, person.Words)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	printCounts("📎 Attachments", out.Attachments)
+	printCounts("👍 Reactions", out.Reactions)
+	return nil
+}
+
+// printCounts prints a heading and its counts, largest first
+func printCounts(heading string, counts map[string]int) {
+	fmt.Printf("\n%s\n", heading)
+	if len(counts) == 0 {
+		fmt.Println("   none")
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		fmt.Printf("   %s: %d\n", key, counts[key])
+	}
+}
+
+// listedChat is a chat as list-chats --json prints it
+type listedChat struct {
+	ID           int                 `json:"id"`
+	Identifier   string              `json:"identifier"`
+	DisplayName  string              `json:"display_name,omitempty"`
+	Participants []listedParticipant `json:"participants"`
+	MessageCount int                 `json:"message_count"`
+	FirstMessage *time.Time          `json:"first_message,omitempty"`
+	LastMessage  *time.Time          `json:"last_message,omitempty"`
+}
+
+// listedParticipant is a chat participant as list-chats --json prints it
+type listedParticipant struct {
+	Contact string `json:"contact"`
+	Name    string `json:"name"`
+}
+
+func runListChats(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(config.DatabasePath); err != nil {
+		return fmt.Errorf("database not found: %s", config.DatabasePath)
+	}
+	db, err := database.New(config.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	chats, err := db.ListChats()
+	if err != nil {
+		return err
+	}
+	handles, err := db.GetHandles(config.ContactNames)
+	if err != nil {
+		return fmt.Errorf("failed to get handles: %w", err)
+	}
+
+	listed := make([]listedChat, len(chats))
+	for i, chat := range chats {
+		listed[i] = listedChat{
+			ID:           chat.ID,
+			Identifier:   chat.Identifier,
+			DisplayName:  chat.DisplayName,
+			Participants: []listedParticipant{},
+			MessageCount: chat.MessageCount,
+		}
+		for _, id := range chat.HandleIDs {
+			if handle, exists := handles[id]; exists {
+				listed[i].Participants = append(listed[i].Participants, listedParticipant{Contact: handle.Contact, Name: handle.DisplayName})
+			}
+		}
+		if !chat.FirstMessage.IsZero() {
+			first, last := chat.FirstMessage, chat.LastMessage
+			listed[i].FirstMessage, listed[i].LastMessage = &first, &last
+		}
+	}
+
+	if listChatsJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listed)
+	}
+
+	if len(listed) == 0 {
+		fmt.Println("No conversations found in the database")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPARTICIPANTS\tMESSAGES\tFIRST\tLAST")
+	for _, chat := range listed {
+		name := chat.DisplayName
+		if name == "" {
+			name = "-"
+		}
+		var participants []string
+		for _, p := range chat.Participants {
+			participants = append(participants, p.Name)
+		}
+		first, last := "-", "-"
+		if chat.FirstMessage != nil {
+			first, last = chat.FirstMessage.Format("2006-01-02"), chat.LastMessage.Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n", chat.ID, name, strings.Join(participants, ", "), chat.MessageCount, first, last)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nGenerate one with: threadbound generate --chat <ID>\n")
+	return nil
+}
+
 func runServe(cmd *cobra.Command, args []string) error {
-	// Create API server
-	server := api.NewServer(apiPort)
+	// Create API server, logging through the --quiet and --json-logs settings
+	apiOptions.Logger = config.Log()
+	server := api.NewServer(apiPort, apiOptions)
 
 	// Set up graceful shutdown
 	stop := make(chan os.Signal, 1)
@@ -234,14 +1062,28 @@ func runServe(cmd *cobra.Command, args []string) error {
 	case err := <-errChan:
 		return fmt.Errorf("server error: %w", err)
 	case <-stop:
-		fmt.Println("\n🛑 Shutting down server...")
+		config.Log().Info("🛑 Shutting down server, waiting for running jobs...", "timeout", drainTimeout)
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancelDrain()
+
+		// A second signal skips the wait; unfinished jobs are still saved
+		go func() {
+			<-stop
+			config.Log().Info("⏭️  Skipping the wait for running jobs")
+			cancelDrain()
+		}()
+
+		if err := server.Drain(drainCtx); err != nil {
+			config.Log().Warn("⚠️  Could not save unfinished jobs", "error", err)
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
 		if err := server.Shutdown(ctx); err != nil {
 			return fmt.Errorf("server shutdown error: %w", err)
 		}
-		fmt.Println("✅ Server stopped gracefully")
+		config.Log().Info("✅ Server stopped gracefully")
 	}
 
 	return nil
@@ -252,4 +1094,4 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
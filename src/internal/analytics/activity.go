@@ -0,0 +1,73 @@
+package analytics
+
+import (
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// MonthCount is the number of messages in one calendar month
+type MonthCount struct {
+	Month time.Time // First day of the month
+	Count int
+}
+
+// HourGrid counts messages by weekday (indexed by time.Weekday) and hour of day
+type HourGrid [7][24]int
+
+// Max returns the largest count in the grid
+func (g *HourGrid) Max() int {
+	max := 0
+	for _, hours := range g {
+		for _, count := range hours {
+			if count > max {
+				max = count
+			}
+		}
+	}
+	return max
+}
+
+// MonthlyVolume counts messages per month from the first month to the last, including
+// months without messages so gaps show up in charts
+func MonthlyVolume(messages []models.Message) []MonthCount {
+	counts := make(map[string]int)
+	var first, last time.Time
+
+	for _, msg := range messages {
+		if msg.Memory != nil {
+			continue
+		}
+		counts[msg.FormattedDate.Format(ChapterKeyFormat)]++
+
+		year, month, _ := msg.FormattedDate.Date()
+		start := time.Date(year, month, 1, 0, 0, 0, 0, msg.FormattedDate.Location())
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	var months []MonthCount
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		months = append(months, MonthCount{Month: month, Count: counts[month.Format(ChapterKeyFormat)]})
+	}
+	return months
+}
+
+// HourlyActivity counts messages by weekday and hour of day in each message's local time
+func HourlyActivity(messages []models.Message) *HourGrid {
+	var grid HourGrid
+	for _, msg := range messages {
+		if msg.Memory != nil {
+			continue
+		}
+		grid[msg.FormattedDate.Weekday()][msg.FormattedDate.Hour()]++
+	}
+	return &grid
+}
@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestMonthlyVolume(t *testing.T) {
+	date := func(month time.Month, d int) time.Time { return time.Date(2023, month, d, 12, 0, 0, 0, time.UTC) }
+	messages := []models.Message{
+		{FormattedDate: date(1, 5)},
+		{FormattedDate: date(1, 20)},
+		{FormattedDate: date(3, 1)},
+		{FormattedDate: date(4, 2), Memory: &models.Memory{Path: "/photos/beach.jpg"}},
+	}
+
+	months := MonthlyVolume(messages)
+
+	expected := []int{2, 0, 1}
+	if len(months) != len(expected) {
+		t.Fatalf("Expected %d months, got %+v", len(expected), months)
+	}
+	for i, count := range expected {
+		if months[i].Count != count {
+			t.Errorf("Expected %d messages in %s, got %d", count, months[i].Month.Format("Jan"), months[i].Count)
+		}
+	}
+	if months[1].Month.Month() != time.February {
+		t.Errorf("Expected the empty month to be February, got %s", months[1].Month.Month())
+	}
+
+	if MonthlyVolume(nil) != nil {
+		t.Error("Expected no months without messages")
+	}
+}
+
+func TestHourlyActivity(t *testing.T) {
+	// March 4, 2023 was a Saturday
+	saturday := time.Date(2023, 3, 4, 21, 15, 0, 0, time.UTC)
+	messages := []models.Message{
+		{FormattedDate: saturday},
+		{FormattedDate: saturday.Add(10 * time.Minute)},
+		{FormattedDate: saturday.Add(3 * time.Hour)},
+	}
+
+	grid := HourlyActivity(messages)
+	if grid[time.Saturday][21] != 2 {
+		t.Errorf("Expected 2 messages Saturday at 21h, got %d", grid[time.Saturday][21])
+	}
+	if grid[time.Sunday][0] != 1 {
+		t.Errorf("Expected 1 message Sunday at midnight, got %d", grid[time.Sunday][0])
+	}
+	if grid.Max() != 2 {
+		t.Errorf("Expected max 2, got %d", grid.Max())
+	}
+}
@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// Breakdown details the book's messages for the stats command
+type Breakdown struct {
+	People      []PersonStats  // Most messages first
+	Attachments map[string]int // By kind: image, video, audio or other
+	Reactions   map[string]int // By the emoji the book shows them as
+}
+
+// Break counts messages per person, attachments by kind and reactions by emoji. Messages
+// need their attachments loaded; reactions are counted only for the messages given.
+func Break(messages []models.Message, reactions map[string][]models.Reaction, senderName func(models.Message) string) *Breakdown {
+	breakdown := &Breakdown{
+		People:      Summarize(messages, senderName).People,
+		Attachments: make(map[string]int),
+		Reactions:   make(map[string]int),
+	}
+
+	for _, msg := range messages {
+		if msg.Memory != nil {
+			continue
+		}
+		for _, att := range msg.Attachments {
+			breakdown.Attachments[AttachmentKind(att)]++
+		}
+		for _, reaction := range reactions[msg.GUID] {
+			// Types 3000 and up record a reaction being taken back, not a reaction
+			if reaction.Type >= 2000 && reaction.Type < 3000 {
+				breakdown.Reactions[reaction.ReactionEmoji]++
+			}
+		}
+	}
+	return breakdown
+}
+
+// AttachmentKind sorts an attachment into image, video, audio or other by its MIME type
+// or, failing that, its file extension
+func AttachmentKind(att models.Attachment) string {
+	mimeType := ""
+	if att.MimeType != nil {
+		mimeType = *att.MimeType
+	} else if att.Filename != nil {
+		mimeType = mime.TypeByExtension(strings.ToLower(filepath.Ext(*att.Filename)))
+	}
+
+	if kind, _, found := strings.Cut(strings.ToLower(mimeType), "/"); found {
+		switch kind {
+		case "image", "video", "audio":
+			return kind
+		}
+	}
+	if isPhoto(att) {
+		return "image"
+	}
+	return "other"
+}
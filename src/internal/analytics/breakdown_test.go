@@ -0,0 +1,49 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestBreak(t *testing.T) {
+	str := func(s string) *string { return &s }
+	date := time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	messages := []models.Message{
+		{GUID: "a", FormattedDate: date, Text: str("Look"), Attachments: []models.Attachment{
+			{MimeType: str("image/heic")}, {Filename: str("clip.MOV")}, {Filename: str("notes.pdf")},
+		}},
+		{GUID: "b", FormattedDate: date, Text: str("Nice"), IsFromMe: true, Attachments: []models.Attachment{
+			{Filename: str("Audio Message.caf"), MimeType: str("audio/x-caf")},
+		}},
+		{GUID: "c", FormattedDate: date, Memory: &models.Memory{Path: "/photos/beach.jpg"}},
+	}
+	reactions := map[string][]models.Reaction{
+		"a": {{Type: 2000, ReactionEmoji: "❤️"}, {Type: 2003, ReactionEmoji: "😂"}, {Type: 3000, ReactionEmoji: "❤️"}},
+		"b": {{Type: 2000, ReactionEmoji: "❤️"}},
+		"x": {{Type: 2001, ReactionEmoji: "👍"}},
+	}
+	name := func(msg models.Message) string {
+		if msg.IsFromMe {
+			return "Me"
+		}
+		return "Alice"
+	}
+
+	breakdown := Break(messages, reactions, name)
+
+	if len(breakdown.People) != 2 || breakdown.People[0].Name != "Alice" || breakdown.People[0].Messages != 1 {
+		t.Errorf("Unexpected people %+v", breakdown.People)
+	}
+	for kind, want := range map[string]int{"image": 1, "video": 1, "audio": 1, "other": 1} {
+		if breakdown.Attachments[kind] != want {
+			t.Errorf("Expected %d %s attachments, got %v", want, kind, breakdown.Attachments)
+		}
+	}
+	// Removals and reactions to messages outside the book aren't counted
+	if breakdown.Reactions["❤️"] != 2 || breakdown.Reactions["😂"] != 1 || breakdown.Reactions["👍"] != 0 {
+		t.Errorf("Unexpected reactions %v", breakdown.Reactions)
+	}
+}
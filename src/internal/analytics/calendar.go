@@ -0,0 +1,71 @@
+package analytics
+
+import (
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// DayKeyFormat is the time layout used to key days
+const DayKeyFormat = "2006-01-02"
+
+// CalendarDay is one day of a calendar heatmap
+type CalendarDay struct {
+	Date    time.Time
+	Week    int // Column, counting from the week holding January 1
+	Weekday int // Row, Monday 0 through Sunday 6
+	Count   int // Messages that day
+}
+
+// CalendarYear lays out every day of one year for a calendar heatmap: a column per week,
+// Monday at the top, as on a wall calendar turned on its side
+type CalendarYear struct {
+	Year  int
+	Days  []CalendarDay
+	Weeks int // Columns the year spans
+	Max   int // Most messages on one day, for shading
+}
+
+// DailyCounts counts messages per day, keyed by DayKeyFormat. Memories aren't counted.
+func DailyCounts(messages []models.Message) map[string]int {
+	daily := make(map[string]int)
+	for _, msg := range messages {
+		if msg.Memory == nil {
+			daily[msg.FormattedDate.Format(DayKeyFormat)]++
+		}
+	}
+	return daily
+}
+
+// Calendar lays out one year of daily counts
+func Calendar(year int, daily map[string]int) CalendarYear {
+	calendar := CalendarYear{Year: year}
+
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// Weeks start on the Monday on or before January 1
+	offset := (int(jan1.Weekday()) + 6) % 7
+	for day := jan1; day.Year() == year; day = day.AddDate(0, 0, 1) {
+		index := offset + day.YearDay() - 1
+		count := daily[day.Format(DayKeyFormat)]
+		calendar.Days = append(calendar.Days, CalendarDay{Date: day, Week: index / 7, Weekday: index % 7, Count: count})
+		if count > calendar.Max {
+			calendar.Max = count
+		}
+	}
+	calendar.Weeks = calendar.Days[len(calendar.Days)-1].Week + 1
+	return calendar
+}
+
+// Calendars lays out every year with messages, oldest first
+func Calendars(messages []models.Message) []CalendarYear {
+	daily := DailyCounts(messages)
+	var calendars []CalendarYear
+	for _, msg := range messages {
+		year := msg.FormattedDate.Year()
+		if msg.Memory != nil || (len(calendars) > 0 && calendars[len(calendars)-1].Year >= year) {
+			continue
+		}
+		calendars = append(calendars, Calendar(year, daily))
+	}
+	return calendars
+}
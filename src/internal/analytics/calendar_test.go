@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestCalendar(t *testing.T) {
+	calendar := Calendar(2023, map[string]int{"2023-01-01": 4, "2023-07-04": 1, "2022-12-31": 9})
+
+	if len(calendar.Days) != 365 || calendar.Max != 4 {
+		t.Fatalf("Expected 365 days with a max of 4, got %d and %d", len(calendar.Days), calendar.Max)
+	}
+
+	// January 1, 2023 was a Sunday, the bottom of the first week
+	first := calendar.Days[0]
+	if first.Week != 0 || first.Weekday != 6 || first.Count != 4 {
+		t.Errorf("Unexpected January 1: %+v", first)
+	}
+	// January 2 starts the second week
+	if second := calendar.Days[1]; second.Week != 1 || second.Weekday != 0 {
+		t.Errorf("Unexpected January 2: %+v", second)
+	}
+	if july4 := calendar.Days[184]; july4.Date.Format(DayKeyFormat) != "2023-07-04" || july4.Count != 1 {
+		t.Errorf("Unexpected July 4: %+v", july4)
+	}
+	if calendar.Weeks != 53 {
+		t.Errorf("Expected 53 weeks, got %d", calendar.Weeks)
+	}
+
+	// Leap years have every day too
+	if got := len(Calendar(2024, nil).Days); got != 366 {
+		t.Errorf("Expected 366 days in 2024, got %d", got)
+	}
+}
+
+func TestCalendars(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+	messages := []models.Message{
+		{FormattedDate: date(2021, 12, 31)},
+		{FormattedDate: date(2023, 1, 1)},
+		{FormattedDate: date(2023, 1, 1)},
+		{FormattedDate: date(2023, 1, 1), Memory: &models.Memory{}},
+	}
+
+	calendars := Calendars(messages)
+	if len(calendars) != 2 || calendars[0].Year != 2021 || calendars[1].Year != 2023 {
+		t.Fatalf("Expected calendars for 2021 and 2023, got %+v", calendars)
+	}
+	// Memories aren't messages
+	if calendars[1].Days[0].Count != 2 || calendars[1].Max != 2 {
+		t.Errorf("Expected 2 messages on January 1, got %d", calendars[1].Days[0].Count)
+	}
+}
@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// ChapterKeyFormat is the time layout used to key chapters by calendar month
+const ChapterKeyFormat = "2006-01"
+
+// ChapterStats summarizes one chapter (calendar month) of the book
+type ChapterStats struct {
+	Month           time.Time // First day of the month
+	Messages        int       // Messages sent or received, excluding memories
+	Photos          int       // Image attachments plus inserted memories
+	MostActiveDay   time.Time // Day with the most messages
+	MostActiveCount int       // Messages on MostActiveDay
+}
+
+// photoExts identifies image attachments when no MIME type was recorded
+var photoExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".bmp": true, ".tiff": true, ".webp": true, ".heic": true, ".heif": true,
+}
+
+// ByChapter computes stats for every month that has messages, keyed by ChapterKeyFormat
+func ByChapter(messages []models.Message) map[string]*ChapterStats {
+	chapters := make(map[string]*ChapterStats)
+	dayCounts := make(map[string]map[string]int)
+
+	for _, msg := range messages {
+		key := msg.FormattedDate.Format(ChapterKeyFormat)
+		chapter, exists := chapters[key]
+		if !exists {
+			year, month, _ := msg.FormattedDate.Date()
+			chapter = &ChapterStats{Month: time.Date(year, month, 1, 0, 0, 0, 0, msg.FormattedDate.Location())}
+			chapters[key] = chapter
+			dayCounts[key] = make(map[string]int)
+		}
+
+		if msg.Memory != nil {
+			chapter.Photos++
+			continue
+		}
+
+		chapter.Messages++
+		for _, att := range msg.Attachments {
+			if isPhoto(att) {
+				chapter.Photos++
+			}
+		}
+
+		dayKey := msg.FormattedDate.Format("2006-01-02")
+		dayCounts[key][dayKey]++
+		count := dayCounts[key][dayKey]
+		// Ties go to the earlier day since messages arrive in date order
+		if count > chapter.MostActiveCount {
+			chapter.MostActiveCount = count
+			chapter.MostActiveDay = msg.FormattedDate
+		}
+	}
+
+	return chapters
+}
+
+// isPhoto reports whether an attachment is an image
+func isPhoto(att models.Attachment) bool {
+	if att.MimeType != nil && strings.HasPrefix(*att.MimeType, "image/") {
+		return true
+	}
+	return att.Filename != nil && photoExts[strings.ToLower(filepath.Ext(*att.Filename))]
+}
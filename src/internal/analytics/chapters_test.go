@@ -0,0 +1,47 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestByChapter(t *testing.T) {
+	day := func(month time.Month, d, hour int) time.Time {
+		return time.Date(2023, month, d, hour, 0, 0, 0, time.UTC)
+	}
+	photo := "IMG_0001.HEIC"
+	pdf := "notes.pdf"
+
+	messages := []models.Message{
+		{FormattedDate: day(3, 1, 9)},
+		{FormattedDate: day(3, 2, 9), Attachments: []models.Attachment{{Filename: &photo}, {Filename: &pdf}}},
+		{FormattedDate: day(3, 2, 10)},
+		{FormattedDate: day(3, 5, 12), Memory: &models.Memory{Path: "/photos/beach.jpg"}},
+		{FormattedDate: day(4, 1, 8)},
+	}
+
+	chapters := ByChapter(messages)
+	if len(chapters) != 2 {
+		t.Fatalf("Expected 2 chapters, got %d", len(chapters))
+	}
+
+	march := chapters["2023-03"]
+	if march.Messages != 3 {
+		t.Errorf("Expected 3 messages in March, got %d", march.Messages)
+	}
+	if march.Photos != 2 {
+		t.Errorf("Expected 2 photos in March (attachment + memory), got %d", march.Photos)
+	}
+	if march.MostActiveCount != 2 || march.MostActiveDay.Day() != 2 {
+		t.Errorf("Expected March 2 with 2 messages, got day %d with %d", march.MostActiveDay.Day(), march.MostActiveCount)
+	}
+	if !march.Month.Equal(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected month to start on March 1, got %v", march.Month)
+	}
+
+	if april := chapters["2023-04"]; april.Messages != 1 || april.Photos != 0 {
+		t.Errorf("Expected 1 message and no photos in April, got %+v", april)
+	}
+}
@@ -0,0 +1,57 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// Hit is one of the messages that got the most reactions
+type Hit struct {
+	Message   models.Message
+	Reactions []ReactionCount // By emoji, in the order each was first used
+	Total     int
+}
+
+// ReactionCount is how many people reacted to a message with one emoji
+type ReactionCount struct {
+	Emoji string
+	Count int
+}
+
+// GreatestHits returns the n messages with text that got the most reactions, most first,
+// the earlier of two with as many first. Messages without reactions aren't hits.
+func GreatestHits(messages []models.Message, reactions map[string][]models.Reaction, n int) []Hit {
+	var hits []Hit
+	for _, msg := range messages {
+		messageReactions := reactions[msg.GUID]
+		// Photos alone aren't quotes
+		if len(messageReactions) == 0 || msg.Memory != nil || msg.Text == nil || strings.TrimSpace(strings.ReplaceAll(*msg.Text, "\uFFFC", "")) == "" {
+			continue
+		}
+
+		hit := Hit{Message: msg, Total: len(messageReactions)}
+		index := make(map[string]int)
+		for _, reaction := range messageReactions {
+			if i, seen := index[reaction.ReactionEmoji]; seen {
+				hit.Reactions[i].Count++
+				continue
+			}
+			index[reaction.ReactionEmoji] = len(hit.Reactions)
+			hit.Reactions = append(hit.Reactions, ReactionCount{Emoji: reaction.ReactionEmoji, Count: 1})
+		}
+		hits = append(hits, hit)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Total != hits[j].Total {
+			return hits[i].Total > hits[j].Total
+		}
+		return hits[i].Message.FormattedDate.Before(hits[j].Message.FormattedDate)
+	})
+	if len(hits) > n {
+		hits = hits[:n]
+	}
+	return hits
+}
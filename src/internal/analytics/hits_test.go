@@ -0,0 +1,39 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestGreatestHits(t *testing.T) {
+	text := func(s string) *string { return &s }
+	date := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	messages := []models.Message{
+		{GUID: "a", Text: text("first joke"), FormattedDate: date},
+		{GUID: "b", Text: text("best joke"), FormattedDate: date.Add(time.Hour)},
+		{GUID: "c", Text: text("another"), FormattedDate: date.Add(2 * time.Hour)},
+		{GUID: "d", Text: text("\uFFFC"), FormattedDate: date.Add(3 * time.Hour)},
+		{GUID: "e", Text: text("ignored"), FormattedDate: date.Add(4 * time.Hour)},
+	}
+	laugh := models.Reaction{ReactionEmoji: "😂"}
+	love := models.Reaction{ReactionEmoji: "❤️"}
+	reactions := map[string][]models.Reaction{
+		"a": {laugh},
+		"b": {laugh, love, laugh},
+		"c": {love},
+		"d": {love, love, love, love},
+	}
+
+	hits := GreatestHits(messages, reactions, 2)
+	if len(hits) != 2 || hits[0].Message.GUID != "b" || hits[1].Message.GUID != "a" {
+		t.Fatalf("Expected b, then a as the earlier of two with one reaction, got %+v", hits)
+	}
+	if hits[0].Total != 3 || len(hits[0].Reactions) != 2 || hits[0].Reactions[0] != (ReactionCount{"😂", 2}) || hits[0].Reactions[1] != (ReactionCount{"❤️", 1}) {
+		t.Errorf("Unexpected reactions %+v", hits[0])
+	}
+	if got := len(GreatestHits(messages, reactions, 10)); got != 3 {
+		t.Errorf("Expected only messages with text and reactions, got %d", got)
+	}
+}
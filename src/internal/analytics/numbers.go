@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// TopEmojiCount is how many emoji the "By the Numbers" chapter lists
+const TopEmojiCount = 5
+
+// Numbers summarizes the whole conversation for the "By the Numbers" chapter
+type Numbers struct {
+	Messages        int           // Messages sent or received, excluding memories
+	Words           int           // Whitespace-separated words across all message text
+	ActiveDays      int           // Days with at least one message
+	People          []PersonStats // Most messages first
+	BusiestDay      time.Time     // Day with the most messages
+	BusiestDayCount int           // Messages on BusiestDay
+	TopEmoji        []EmojiCount  // Most used first, at most TopEmojiCount
+	LongestStreak   int           // Most consecutive days with at least one message
+	StreakStart     time.Time     // First day of the longest streak
+	StreakEnd       time.Time     // Last day of the longest streak
+	Monthly         []MonthCount  // Messages per month, for the volume chart
+	Hourly          *HourGrid     // Messages by weekday and hour, for the heatmap
+}
+
+// PersonStats counts one sender's messages and words
+type PersonStats struct {
+	Name     string
+	Messages int
+	Words    int
+}
+
+// EmojiCount is how often one emoji was used in message text
+type EmojiCount struct {
+	Emoji string
+	Count int
+}
+
+// Summarize computes the "By the Numbers" summary. senderName resolves a message to the
+// name it is shown under in the book, so people are grouped the same way as in the text.
+func Summarize(messages []models.Message, senderName func(models.Message) string) *Numbers {
+	numbers := &Numbers{}
+	people := make(map[string]*PersonStats)
+	emoji := make(map[string]int)
+	dayCounts := make(map[string]int)
+	var days []time.Time
+
+	for _, msg := range messages {
+		if msg.Memory != nil {
+			continue
+		}
+		numbers.Messages++
+
+		name := senderName(msg)
+		person, exists := people[name]
+		if !exists {
+			person = &PersonStats{Name: name}
+			people[name] = person
+		}
+		person.Messages++
+
+		if msg.Text != nil {
+			words := len(strings.Fields(*msg.Text))
+			person.Words += words
+			numbers.Words += words
+			countEmoji(*msg.Text, emoji)
+		}
+
+		dayKey := msg.FormattedDate.Format("2006-01-02")
+		if dayCounts[dayKey] == 0 {
+			year, month, day := msg.FormattedDate.Date()
+			days = append(days, time.Date(year, month, day, 0, 0, 0, 0, msg.FormattedDate.Location()))
+		}
+		dayCounts[dayKey]++
+		// Ties go to the earlier day since messages arrive in date order
+		if dayCounts[dayKey] > numbers.BusiestDayCount {
+			numbers.BusiestDayCount = dayCounts[dayKey]
+			numbers.BusiestDay = days[len(days)-1]
+		}
+	}
+
+	numbers.ActiveDays = len(days)
+	numbers.Monthly = MonthlyVolume(messages)
+	numbers.Hourly = HourlyActivity(messages)
+	numbers.LongestStreak, numbers.StreakStart, numbers.StreakEnd = longestStreak(days)
+
+	for _, person := range people {
+		numbers.People = append(numbers.People, *person)
+	}
+	sort.Slice(numbers.People, func(i, j int) bool {
+		if numbers.People[i].Messages != numbers.People[j].Messages {
+			return numbers.People[i].Messages > numbers.People[j].Messages
+		}
+		return numbers.People[i].Name < numbers.People[j].Name
+	})
+
+	for e, count := range emoji {
+		numbers.TopEmoji = append(numbers.TopEmoji, EmojiCount{Emoji: e, Count: count})
+	}
+	sort.Slice(numbers.TopEmoji, func(i, j int) bool {
+		if numbers.TopEmoji[i].Count != numbers.TopEmoji[j].Count {
+			return numbers.TopEmoji[i].Count > numbers.TopEmoji[j].Count
+		}
+		return numbers.TopEmoji[i].Emoji < numbers.TopEmoji[j].Emoji
+	})
+	if len(numbers.TopEmoji) > TopEmojiCount {
+		numbers.TopEmoji = numbers.TopEmoji[:TopEmojiCount]
+	}
+
+	return numbers
+}
+
+// longestStreak finds the longest run of consecutive days in a sorted list of days
+func longestStreak(days []time.Time) (int, time.Time, time.Time) {
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	best, bestStart, bestEnd := 0, time.Time{}, time.Time{}
+	run, runStart := 0, time.Time{}
+	for i, day := range days {
+		// AddDate rather than 24h so daylight saving changes don't break a streak
+		if i > 0 && days[i-1].AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run, runStart = 1, day
+		}
+		if run > best {
+			best, bestStart, bestEnd = run, runStart, day
+		}
+	}
+	return best, bestStart, bestEnd
+}
+
+// countEmoji tallies the emoji in text. Skin tone modifiers, variation selectors and
+// joiners are dropped, so each emoji is counted by its base character. Flags are made of
+// two regional indicator letters and are skipped rather than counted as letters.
+func countEmoji(text string, counts map[string]int) {
+	for _, r := range text {
+		if IsEmoji(r) && !(r >= 0x1F1E6 && r <= 0x1F1FF) {
+			counts[string(r)]++
+		}
+	}
+}
+
+// IsEmoji reports whether r is a pictographic emoji base character
+func IsEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F3FB && r <= 0x1F3FF: // Skin tone modifiers
+		return false
+	case r >= 0x1F000 && r <= 0x1FAFF: // Pictographs, emoticons, transport, flags, supplemental symbols
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // Technical symbols such as ⌚ and ⏰
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // Arrows and shapes such as ⬆️ and ⭐
+		return true
+	case r == 0x203C || r == 0x2049 || r == 0x3030 || r == 0x303D || r == 0x3297 || r == 0x3299:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestSummarize(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2023, 3, d, hour, 0, 0, 0, time.UTC) }
+	text := func(s string) *string { return &s }
+
+	messages := []models.Message{
+		{FormattedDate: day(1, 9), Text: text("good morning ☀️")},
+		{FormattedDate: day(2, 9), Text: text("coffee? ☕"), IsFromMe: true},
+		{FormattedDate: day(2, 10), Text: text("yes 😂😂")},
+		{FormattedDate: day(2, 11), Text: text("😂👍🏽")},
+		{FormattedDate: day(3, 12), Memory: &models.Memory{Path: "/photos/beach.jpg"}},
+		{FormattedDate: day(5, 8), Text: text("see you soon"), IsFromMe: true},
+		{FormattedDate: day(6, 8), Text: text("bye")},
+		{FormattedDate: day(7, 8)},
+	}
+	name := func(msg models.Message) string {
+		if msg.IsFromMe {
+			return "Me"
+		}
+		return "Alice"
+	}
+
+	numbers := Summarize(messages, name)
+
+	if numbers.Messages != 7 {
+		t.Errorf("Expected 7 messages, got %d", numbers.Messages)
+	}
+	if numbers.Words != 12 {
+		t.Errorf("Expected 12 words, got %d", numbers.Words)
+	}
+	if numbers.ActiveDays != 5 {
+		t.Errorf("Expected 5 active days, got %d", numbers.ActiveDays)
+	}
+	if len(numbers.People) != 2 || numbers.People[0].Name != "Alice" || numbers.People[0].Messages != 5 {
+		t.Errorf("Expected Alice first with 5 messages, got %+v", numbers.People)
+	}
+	if !numbers.BusiestDay.Equal(day(2, 0)) || numbers.BusiestDayCount != 3 {
+		t.Errorf("Expected busiest day March 2 with 3, got %v with %d", numbers.BusiestDay, numbers.BusiestDayCount)
+	}
+	if numbers.LongestStreak != 3 || !numbers.StreakStart.Equal(day(5, 0)) || !numbers.StreakEnd.Equal(day(7, 0)) {
+		t.Errorf("Expected a 3 day streak March 5-7, got %d from %v to %v",
+			numbers.LongestStreak, numbers.StreakStart, numbers.StreakEnd)
+	}
+
+	expected := []EmojiCount{{"😂", 3}, {"☀", 1}, {"☕", 1}, {"👍", 1}}
+	if len(numbers.TopEmoji) != len(expected) {
+		t.Fatalf("Expected emoji %v, got %v", expected, numbers.TopEmoji)
+	}
+	for i := range expected {
+		if numbers.TopEmoji[i] != expected[i] {
+			t.Errorf("Expected emoji %v, got %v", expected, numbers.TopEmoji)
+			break
+		}
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	numbers := Summarize(nil, func(models.Message) string { return "" })
+	if numbers.Messages != 0 || numbers.LongestStreak != 0 || len(numbers.People) != 0 {
+		t.Errorf("Expected an empty summary, got %+v", numbers)
+	}
+}
@@ -0,0 +1,217 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"threadbound/internal/models"
+)
+
+// WordCloudSize is how many words a word cloud shows at most
+const WordCloudSize = 60
+
+// minWordLength is the fewest letters a word needs to be counted, leaving out the likes of
+// "ok" and "so" in every language
+const minWordLength = 3
+
+// Word clouds are laid out in a box of this shape, in points, and scaled to the page
+const (
+	WordCloudWidth  = 400
+	WordCloudHeight = 280
+)
+
+// WordCloud is the laid-out cloud of the whole book, or of one year
+type WordCloud struct {
+	Year  int       // Zero for the whole book
+	From  time.Time // Date of the first message it covers
+	Words []CloudWord
+}
+
+// WordClouds lays out a cloud of the words used most in the whole book, or, by year, one
+// for each year, oldest first. Years without words to show have no cloud.
+func WordClouds(messages []models.Message, by string, isStopword func(string) bool) []WordCloud {
+	var groups []WordCloud
+	var grouped [][]models.Message
+	for _, msg := range messages {
+		year := 0
+		if by == models.WordCloudYear {
+			year = msg.FormattedDate.Year()
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Year != year {
+			groups = append(groups, WordCloud{Year: year, From: msg.FormattedDate})
+			grouped = append(grouped, nil)
+		}
+		grouped[len(grouped)-1] = append(grouped[len(grouped)-1], msg)
+	}
+
+	var clouds []WordCloud
+	for i, cloud := range groups {
+		cloud.Words = LayoutWordCloud(TopWords(grouped[i], isStopword, WordCloudSize), WordCloudWidth, WordCloudHeight)
+		if len(cloud.Words) > 0 {
+			clouds = append(clouds, cloud)
+		}
+	}
+	return clouds
+}
+
+// WordCount is how often one word was used in message text
+type WordCount struct {
+	Word  string // Lowercase
+	Count int
+}
+
+// TopWords counts the words in message text and returns the n most used, most used first,
+// then alphabetically. Words are counted in lowercase; stopwords, links, numbers and words
+// of fewer than three letters are left out.
+func TopWords(messages []models.Message, isStopword func(string) bool, n int) []WordCount {
+	counts := make(map[string]int)
+	for _, msg := range messages {
+		if msg.Memory != nil || msg.Text == nil {
+			continue
+		}
+		for _, field := range strings.Fields(*msg.Text) {
+			if strings.Contains(field, "://") || strings.HasPrefix(strings.ToLower(field), "www.") {
+				continue
+			}
+			for _, word := range splitWords(field) {
+				if len([]rune(word)) >= minWordLength && !isStopword(word) {
+					counts[word]++
+				}
+			}
+		}
+	}
+
+	words := make([]WordCount, 0, len(counts))
+	for word, count := range counts {
+		words = append(words, WordCount{Word: word, Count: count})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Count != words[j].Count {
+			return words[i].Count > words[j].Count
+		}
+		return words[i].Word < words[j].Word
+	})
+	if len(words) > n {
+		words = words[:n]
+	}
+	return words
+}
+
+// splitWords returns the runs of letters in text, lowercased, keeping apostrophes within
+// them as in "don't" or "aujourd'hui"
+func splitWords(text string) []string {
+	runes := []rune(strings.ToLower(strings.ReplaceAll(text, "’", "'")))
+	var words []string
+	var word []rune
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r):
+			word = append(word, r)
+			continue
+		case r == '\'' && len(word) > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			word = append(word, r)
+			continue
+		}
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// CloudWord is a word placed in a word cloud. Positions and sizes are in the units of the
+// width and height the cloud was laid out in, with y counting down from the top.
+type CloudWord struct {
+	Word   string
+	Count  int
+	X, Y   float64 // Center of the word
+	Size   float64 // Font size: the height of a line of the word
+	Weight float64 // How used the word is among the cloud's, from 0 for the least to 1 for the most
+}
+
+// Shape of a word: how wide its characters are for their size, and the room kept around it
+const (
+	charWidth     = 0.6 // Most letters of a proportional font are narrower, so words don't collide
+	wideCharWidth = 1.0 // CJK characters are square
+	wordPadding   = 0.1 // Of the word's size, on each side
+)
+
+// LayoutWordCloud places words, most used first, in a width by height cloud: each is sized
+// by how much it was used and placed on a spiral out from the center at the first spot it
+// fits without touching another. Words that don't fit anywhere are left out. The layout
+// depends only on the words, so the same words always make the same cloud.
+func LayoutWordCloud(words []WordCount, width, height float64) []CloudWord {
+	if len(words) == 0 {
+		return nil
+	}
+	most, least := words[0].Count, words[len(words)-1].Count
+	maxSize := height / 8
+	minSize := maxSize / 4
+
+	var placed []CloudWord
+	for _, word := range words {
+		weight := 1.0
+		if most > least {
+			weight = float64(word.Count-least) / float64(most-least)
+		}
+		// Square roots keep the rarer words readable beside the most used
+		size := minSize + (maxSize-minSize)*math.Sqrt(weight)
+		if w := wordWidth(word.Word, size); w > width*0.9 {
+			size *= width * 0.9 / w
+		}
+
+		w, h := wordWidth(word.Word, size), size
+		pad := size * wordPadding
+		for step := 0; ; step++ {
+			// An Archimedean spiral, stretched to the cloud's shape
+			angle := float64(step) * 0.1
+			radius := angle * height / 400
+			if radius > width && radius > height {
+				break
+			}
+			x := width/2 + radius*math.Cos(angle)*width/height
+			y := height/2 + radius*math.Sin(angle)
+			if x-w/2 < 0 || x+w/2 > width || y-h/2 < 0 || y+h/2 > height {
+				continue
+			}
+			if overlaps(placed, x, y, w+2*pad, h+2*pad) {
+				continue
+			}
+			placed = append(placed, CloudWord{Word: word.Word, Count: word.Count, X: x, Y: y, Size: size, Weight: weight})
+			break
+		}
+	}
+	return placed
+}
+
+// wordWidth estimates how wide a word is set at size
+func wordWidth(word string, size float64) float64 {
+	width := 0.0
+	for _, r := range word {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			width += wideCharWidth
+		} else {
+			width += charWidth
+		}
+	}
+	return width * size
+}
+
+// overlaps reports whether a w by h box centered on x, y touches a placed word
+func overlaps(placed []CloudWord, x, y, w, h float64) bool {
+	for _, other := range placed {
+		ow := wordWidth(other.Word, other.Size) + 2*other.Size*wordPadding
+		oh := other.Size * (1 + 2*wordPadding)
+		if math.Abs(x-other.X)*2 < w+ow && math.Abs(y-other.Y)*2 < h+oh {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestTopWords(t *testing.T) {
+	text := func(s string) *string { return &s }
+	messages := []models.Message{
+		{Text: text("Pizza tonight? The pizza place on 5th")},
+		{Text: text("PIZZA! Don’t be late, see https://pizza.example/menu")},
+		{Text: text("late again")},
+		{Memory: &models.Memory{}, Text: text("pizza")},
+	}
+	stopwords := map[string]bool{"the": true, "again": true}
+
+	got := TopWords(messages, func(word string) bool { return stopwords[word] }, 4)
+	want := []WordCount{{"pizza", 3}, {"late", 2}, {"don't", 1}, {"place", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestLayoutWordCloud(t *testing.T) {
+	var words []WordCount
+	for i := 0; i < 30; i++ {
+		words = append(words, WordCount{Word: fmt.Sprintf("word%c%c", 'a'+i%26, 'a'+i/26), Count: 60 - 2*i})
+	}
+	words = append(words, WordCount{Word: "extraordinarilylongwordindeed", Count: 1})
+
+	cloud := LayoutWordCloud(words, 100, 70)
+	if len(cloud) < 25 {
+		t.Fatalf("Expected most words placed, got %d", len(cloud))
+	}
+	if first := cloud[0]; first.X != 50 || first.Y != 35 || first.Weight != 1 {
+		t.Errorf("Expected the most used word in the middle, got %+v", first)
+	}
+	for i, a := range cloud {
+		w := wordWidth(a.Word, a.Size)
+		if a.X-w/2 < 0 || a.X+w/2 > 100 || a.Y-a.Size/2 < 0 || a.Y+a.Size/2 > 70 {
+			t.Errorf("Expected %q inside the cloud, got %+v", a.Word, a)
+		}
+		for _, b := range cloud[i+1:] {
+			if math.Abs(a.X-b.X)*2 < w+wordWidth(b.Word, b.Size) && math.Abs(a.Y-b.Y)*2 < a.Size+b.Size {
+				t.Errorf("Expected %q and %q not to overlap", a.Word, b.Word)
+			}
+		}
+	}
+	if !reflect.DeepEqual(cloud, LayoutWordCloud(words, 100, 70)) {
+		t.Error("Expected the same words to make the same cloud")
+	}
+	if LayoutWordCloud(nil, 100, 70) != nil {
+		t.Error("Expected no cloud without words")
+	}
+}
+
+func TestWordClouds(t *testing.T) {
+	text := func(s string) *string { return &s }
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+	messages := []models.Message{
+		{Text: text("pizza tonight"), FormattedDate: date(2022, 12, 30)},
+		{Text: text("ok"), FormattedDate: date(2023, 1, 2)},
+		{Text: text("happy new year"), FormattedDate: date(2024, 1, 1)},
+	}
+	never := func(string) bool { return false }
+
+	book := WordClouds(messages, models.WordCloudBook, never)
+	if len(book) != 1 || book[0].Year != 0 || len(book[0].Words) != 5 || !book[0].From.Equal(date(2022, 12, 30)) {
+		t.Errorf("Expected one cloud of the whole book, got %+v", book)
+	}
+
+	// 2023 has no words long enough to show
+	years := WordClouds(messages, models.WordCloudYear, never)
+	if len(years) != 2 || years[0].Year != 2022 || years[1].Year != 2024 || years[1].Words[0].Word != "happy" {
+		t.Errorf("Expected clouds of 2022 and 2024, got %+v", years)
+	}
+}
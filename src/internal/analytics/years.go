@@ -0,0 +1,91 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// Limits of each year on the year-in-review poster
+const (
+	TopPhotoCount = 6
+	TopQuoteCount = 3
+)
+
+// YearReview summarizes one calendar year for the year-in-review poster
+type YearReview struct {
+	Year     int
+	Numbers  *Numbers         // Summarize over the year's messages
+	Calendar CalendarYear     // Messages per day
+	Photos   []string         // Processed images, most reacted to first, at most TopPhotoCount
+	Quotes   []models.Message // Text messages with the most reactions, at most TopQuoteCount
+}
+
+// rankedPhoto is a processed image and the reactions to the message it came with
+type rankedPhoto struct {
+	path      string
+	reactions int
+}
+
+// ReviewYears summarizes each calendar year with messages, oldest first. Photos and quotes
+// are ranked by their reactions, ties going to the earlier one; only messages someone
+// reacted to are quoted, and only images already processed for the book are shown.
+func ReviewYears(messages []models.Message, reactions map[string][]models.Reaction, senderName func(models.Message) string) []YearReview {
+	byYear := make(map[int][]models.Message)
+	var years []int
+	for _, msg := range messages {
+		year := msg.FormattedDate.Year()
+		if _, exists := byYear[year]; !exists {
+			years = append(years, year)
+		}
+		byYear[year] = append(byYear[year], msg)
+	}
+	sort.Ints(years)
+
+	reviews := make([]YearReview, 0, len(years))
+	for _, year := range years {
+		yearMessages := byYear[year]
+		review := YearReview{
+			Year:     year,
+			Numbers:  Summarize(yearMessages, senderName),
+			Calendar: Calendar(year, DailyCounts(yearMessages)),
+		}
+
+		var photos []rankedPhoto
+		var quotes []models.Message
+		for _, msg := range yearMessages {
+			if msg.Memory != nil {
+				if msg.Memory.ProcessedPath != "" {
+					photos = append(photos, rankedPhoto{path: msg.Memory.ProcessedPath})
+				}
+				continue
+			}
+			count := len(reactions[msg.GUID])
+			for _, att := range msg.Attachments {
+				if att.ProcessedPath != "" && isPhoto(att) {
+					photos = append(photos, rankedPhoto{path: att.ProcessedPath, reactions: count})
+				}
+			}
+			if count > 0 && msg.Text != nil && strings.TrimSpace(strings.ReplaceAll(*msg.Text, "\uFFFC", "")) != "" {
+				quotes = append(quotes, msg)
+			}
+		}
+
+		sort.SliceStable(photos, func(i, j int) bool { return photos[i].reactions > photos[j].reactions })
+		for i := 0; i < len(photos) && i < TopPhotoCount; i++ {
+			review.Photos = append(review.Photos, photos[i].path)
+		}
+
+		sort.SliceStable(quotes, func(i, j int) bool {
+			return len(reactions[quotes[i].GUID]) > len(reactions[quotes[j].GUID])
+		})
+		if len(quotes) > TopQuoteCount {
+			quotes = quotes[:TopQuoteCount]
+		}
+		review.Quotes = quotes
+
+		reviews = append(reviews, review)
+	}
+	return reviews
+}
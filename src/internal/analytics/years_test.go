@@ -0,0 +1,63 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestReviewYears(t *testing.T) {
+	text := func(s string) *string { return &s }
+	jpeg := "image/jpeg"
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	photo := func(path string) []models.Attachment {
+		return []models.Attachment{{MimeType: &jpeg, ProcessedPath: path}}
+	}
+
+	messages := []models.Message{
+		{GUID: "a", FormattedDate: date(2022, 12, 30), Text: text("Happy new year soon")},
+		{GUID: "b", FormattedDate: date(2023, 1, 1), Text: text("Happy new year! 🎉")},
+		{GUID: "c", FormattedDate: date(2023, 1, 1), Text: text("\uFFFC"), Attachments: photo("/tmp/fireworks.jpg")},
+		{GUID: "d", FormattedDate: date(2023, 2, 14), Text: text("\uFFFC"), Attachments: photo("/tmp/roses.jpg")},
+		{GUID: "e", FormattedDate: date(2023, 3, 1), Memory: &models.Memory{ProcessedPath: "/tmp/beach.jpg"}},
+		{GUID: "f", FormattedDate: date(2023, 3, 2), Text: text("Unprocessed"), Attachments: photo("")},
+	}
+	reactions := map[string][]models.Reaction{
+		"b": {{Type: 2000}},
+		"c": {{Type: 2000}},
+		"d": {{Type: 2000}, {Type: 2001}},
+	}
+	name := func(models.Message) string { return "Alice" }
+
+	reviews := ReviewYears(messages, reactions, name)
+	if len(reviews) != 2 || reviews[0].Year != 2022 || reviews[1].Year != 2023 {
+		t.Fatalf("Expected 2022 and 2023, got %+v", reviews)
+	}
+
+	review := reviews[1]
+	if review.Numbers.Messages != 4 || review.Calendar.Days[0].Count != 2 || review.Calendar.Max != 2 {
+		t.Errorf("Unexpected counts %d %+v", review.Numbers.Messages, review.Calendar.Days[0])
+	}
+
+	// Most reactions first, then in date order
+	want := []string{"/tmp/roses.jpg", "/tmp/fireworks.jpg", "/tmp/beach.jpg"}
+	if len(review.Photos) != len(want) {
+		t.Fatalf("Expected photos %v, got %v", want, review.Photos)
+	}
+	for i := range want {
+		if review.Photos[i] != want[i] {
+			t.Errorf("Photo %d: expected %s, got %s", i, want[i], review.Photos[i])
+		}
+	}
+
+	// Only text someone reacted to is quoted
+	if len(review.Quotes) != 1 || review.Quotes[0].GUID != "b" {
+		t.Errorf("Expected the reacted-to text quoted, got %+v", review.Quotes)
+	}
+	if len(reviews[0].Quotes) != 0 || len(reviews[0].Photos) != 0 {
+		t.Errorf("Expected nothing to show for 2022, got %+v", reviews[0])
+	}
+}
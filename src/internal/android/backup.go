@@ -0,0 +1,204 @@
+// Package android reads backups made by the "SMS Backup & Restore" app and converts them
+// to a chat.db-shaped database, so Android conversations can be made into a book like any
+// iMessage export
+package android
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SMS message box types
+const (
+	smsReceived = 1
+	smsDraft    = 3
+)
+
+// MMS message boxes and address roles
+const (
+	mmsInbox = 1
+	mmsDraft = 3
+
+	addrFrom = 137
+	addrTo   = 151
+)
+
+// SMS is a text message, from an <sms> element
+type SMS struct {
+	Address     string `xml:"address,attr"`
+	Date        int64  `xml:"date,attr"` // Milliseconds since the Unix epoch
+	Type        int    `xml:"type,attr"` // 1 received, 2 sent, 3 draft, 4-6 outbox, failed or queued
+	Subject     string `xml:"subject,attr"`
+	Body        string `xml:"body,attr"`
+	Read        int    `xml:"read,attr"`
+	ContactName string `xml:"contact_name,attr"`
+}
+
+// MMS is a multimedia or group message, from an <mms> element
+type MMS struct {
+	Address     string `xml:"address,attr"` // Every participant, separated by ~
+	Date        int64  `xml:"date,attr"`    // Milliseconds since the Unix epoch
+	MsgBox      int    `xml:"msg_box,attr"` // 1 inbox, 2 sent, 3 draft, 4 outbox
+	Subject     string `xml:"sub,attr"`
+	Read        int    `xml:"read,attr"`
+	ContactName string `xml:"contact_name,attr"`
+	Parts       []Part `xml:"parts>part"`
+	Addrs       []Addr `xml:"addrs>addr"`
+}
+
+// Part is one piece of an MMS: its text, a SMIL layout, or a media file
+type Part struct {
+	Seq             int    `xml:"seq,attr"`
+	ContentType     string `xml:"ct,attr"`
+	Name            string `xml:"name,attr"`
+	Filename        string `xml:"fn,attr"`
+	ContentLocation string `xml:"cl,attr"`
+	Text            string `xml:"text,attr"`
+	Data            string `xml:"data,attr"` // Base64 file contents
+}
+
+// Addr is a participant in an MMS and their role in it
+type Addr struct {
+	Address string `xml:"address,attr"`
+	Type    int    `xml:"type,attr"`
+}
+
+// IsFromMe reports whether the message was sent from the phone
+func (s SMS) IsFromMe() bool {
+	return s.Type != smsReceived
+}
+
+// IsFromMe reports whether the message was sent from the phone
+func (m MMS) IsFromMe() bool {
+	return m.MsgBox != mmsInbox
+}
+
+// Text joins the message's plain-text parts
+func (m MMS) Text() string {
+	var texts []string
+	for _, part := range m.Parts {
+		if part.ContentType == "text/plain" {
+			if text := value(part.Text); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Counterpart returns the other person in the conversation: the sender of a received
+// message, or the first recipient of a sent one
+func (m MMS) Counterpart() string {
+	role := addrFrom
+	if m.IsFromMe() {
+		role = addrTo
+	}
+	for _, addr := range m.Addrs {
+		if addr.Type == role && value(addr.Address) != "" && addr.Address != "insert-address-token" {
+			return addr.Address
+		}
+	}
+	return strings.Split(m.Address, "~")[0]
+}
+
+// Decode reads a backup, calling onSMS and onMMS for each message in file order.
+// Drafts are skipped.
+func Decode(r io.Reader, onSMS func(SMS) error, onMMS func(MMS) error) error {
+	decoder := xml.NewDecoder(&surrogateReader{src: bufio.NewReaderSize(r, 64*1024)})
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "sms":
+			var sms SMS
+			if err := decoder.DecodeElement(&sms, &start); err != nil {
+				return fmt.Errorf("failed to read sms: %w", err)
+			}
+			if sms.Type == smsDraft {
+				continue
+			}
+			if err := onSMS(sms); err != nil {
+				return err
+			}
+		case "mms":
+			var mms MMS
+			if err := decoder.DecodeElement(&mms, &start); err != nil {
+				return fmt.Errorf("failed to read mms: %w", err)
+			}
+			if mms.MsgBox == mmsDraft {
+				continue
+			}
+			if err := onMMS(mms); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// value treats the literal "null" the app writes for missing attributes as empty
+func value(s string) string {
+	if s == "null" {
+		return ""
+	}
+	return s
+}
+
+// The app escapes emoji as a pair of UTF-16 surrogate character references, which
+// encoding/xml would turn into two replacement characters
+var surrogatePair = regexp.MustCompile(`&#(5[5-7]\d{3});&#(5[5-7]\d{3});`)
+
+// surrogateReader rewrites surrogate pair references as one reference to the real
+// character. The app writes one message per line, so pairs never span lines.
+type surrogateReader struct {
+	src *bufio.Reader
+	buf []byte
+	err error
+}
+
+func (s *surrogateReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		var line []byte
+		line, s.err = s.src.ReadBytes('\n')
+		s.buf = joinSurrogates(line)
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// joinSurrogates combines the surrogate pair references in line
+func joinSurrogates(line []byte) []byte {
+	if !bytes.Contains(line, []byte("&#5")) {
+		return line
+	}
+	return surrogatePair.ReplaceAllFunc(line, func(ref []byte) []byte {
+		match := surrogatePair.FindSubmatch(ref)
+		high, _ := strconv.Atoi(string(match[1]))
+		low, _ := strconv.Atoi(string(match[2]))
+		if high < 0xD800 || high > 0xDBFF || low < 0xDC00 || low > 0xDFFF {
+			return ref
+		}
+		char := 0x10000 + (high-0xD800)<<10 + (low - 0xDC00)
+		return []byte("&#" + strconv.Itoa(char) + ";")
+	})
+}
@@ -0,0 +1,266 @@
+package android
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// AttachmentsDir is where MMS media is written, beside the book. It outlives the run
+// because the TeX output refers to the images converted inside it.
+const AttachmentsDir = "android-attachments"
+
+// Result summarizes a converted backup
+type Result struct {
+	Messages     int
+	Attachments  int
+	ContactNames map[string]string // Names the phone had for each address
+}
+
+// Open converts a backup into a temporary database, saves its media to AttachmentsDir
+// beside the output and points config at both. Contact names from the phone fill in any
+// the config doesn't set. Call the returned cleanup function once generation is done.
+func Open(backupPath string, config *models.BookConfig) (func(), error) {
+	workDir, err := os.MkdirTemp("", "threadbound-android-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(workDir) }
+
+	dbPath := filepath.Join(workDir, "chat.db")
+	attachmentsPath := filepath.Join(filepath.Dir(config.OutputPath), AttachmentsDir)
+	result, err := Convert(backupPath, dbPath, attachmentsPath)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	config.Log().Info("📱 Imported Android backup", "messages", result.Messages, "attachments", result.Attachments)
+
+	if config.ContactNames == nil {
+		config.ContactNames = make(map[string]string)
+	}
+	for address, name := range result.ContactNames {
+		if _, exists := config.ContactNames[address]; !exists {
+			config.ContactNames[address] = name
+		}
+	}
+
+	config.DatabasePath = dbPath
+	config.AttachmentsPath = attachmentsPath
+	return cleanup, nil
+}
+
+// Convert writes the messages in the backup at backupPath to a new database at dbPath,
+// saving MMS media under attachmentsPath
+func Convert(backupPath, dbPath, attachmentsPath string) (*Result, error) {
+	file, err := os.Open(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	conn := db.GetConnection()
+	if _, err := conn.Exec(database.Schema); err != nil {
+		return nil, fmt.Errorf("failed to create database: %w", err)
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c := &converter{
+		tx:              tx,
+		attachmentsPath: attachmentsPath,
+		handles:         make(map[string]int64),
+		result:          &Result{ContactNames: make(map[string]string)},
+	}
+	if err := Decode(file, c.addSMS, c.addMMS); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to save messages: %w", err)
+	}
+	return c.result, nil
+}
+
+// converter inserts decoded messages, giving each address one handle
+type converter struct {
+	tx              *sql.Tx
+	attachmentsPath string
+	handles         map[string]int64
+	result          *Result
+}
+
+func (c *converter) addSMS(sms SMS) error {
+	handleID, err := c.handle(sms.Address, sms.ContactName)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.addMessage(fmt.Sprintf("sms-%d", c.result.Messages+1), value(sms.Body), value(sms.Subject),
+		sms.Date, sms.IsFromMe(), sms.Read, handleID, false)
+	return err
+}
+
+func (c *converter) addMMS(mms MMS) error {
+	// Group messages list every participant's name together, which fits no one handle
+	contactName := mms.ContactName
+	if strings.Contains(mms.Address, "~") {
+		contactName = ""
+	}
+	handleID, err := c.handle(mms.Counterpart(), contactName)
+	if err != nil {
+		return err
+	}
+
+	var media []Part
+	for _, part := range mms.Parts {
+		if value(part.Data) != "" && part.ContentType != "application/smil" {
+			media = append(media, part)
+		}
+	}
+
+	// Like chat.db, mark each attachment's place in the text, so photos sent without a
+	// caption still appear
+	text := mms.Text()
+	if text == "" {
+		text = strings.Repeat("\uFFFC", len(media))
+	}
+
+	guid := fmt.Sprintf("mms-%d", c.result.Messages+1)
+	messageID, err := c.addMessage(guid, text, value(mms.Subject),
+		mms.Date, mms.IsFromMe(), mms.Read, handleID, len(media) > 0)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range media {
+		if err := c.addAttachment(messageID, fmt.Sprintf("%s-part-%d", guid, part.Seq), part, mms.IsFromMe()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMessage inserts a message and returns its ROWID
+func (c *converter) addMessage(guid, text, subject string, dateMillis int64, fromMe bool, read int, handleID int64, hasAttachments bool) (int64, error) {
+	date := time.UnixMilli(dateMillis).Sub(database.AppleEpoch).Nanoseconds()
+	res, err := c.tx.Exec(`INSERT INTO message (guid, text, subject, date, date_read, date_delivered,
+		is_from_me, is_read, handle_id, cache_has_attachments) VALUES (?, ?, ?, ?, 0, 0, ?, ?, ?, ?)`,
+		guid, database.Nullable(text), database.Nullable(subject), date, fromMe, read, handleID, hasAttachments)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert message: %w", err)
+	}
+	c.result.Messages++
+	return res.LastInsertId()
+}
+
+// addAttachment saves an MMS part to disk in the archive layout (<guid>/<filename>) that
+// attachment lookup already understands, and links it to its message
+func (c *converter) addAttachment(messageID int64, guid string, part Part, outgoing bool) error {
+	data, err := base64.StdEncoding.DecodeString(part.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decode MMS part %s: %w", guid, err)
+	}
+
+	name := partFilename(part)
+	dir := filepath.Join(c.attachmentsPath, guid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to save MMS part %s: %w", guid, err)
+	}
+
+	res, err := c.tx.Exec(`INSERT INTO attachment (guid, filename, mime_type, total_bytes, is_outgoing)
+		VALUES (?, ?, ?, ?, ?)`, guid, name, part.ContentType, len(data), outgoing)
+	if err != nil {
+		return fmt.Errorf("failed to insert attachment: %w", err)
+	}
+	attachmentID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if _, err := c.tx.Exec(`INSERT INTO message_attachment_join (message_id, attachment_id) VALUES (?, ?)`,
+		messageID, attachmentID); err != nil {
+		return fmt.Errorf("failed to link attachment: %w", err)
+	}
+	c.result.Attachments++
+	return nil
+}
+
+// handle returns the handle for an address, creating it the first time it is seen
+func (c *converter) handle(address, contactName string) (int64, error) {
+	address = NormalizeAddress(address)
+	if id, exists := c.handles[address]; exists {
+		return id, nil
+	}
+
+	res, err := c.tx.Exec(`INSERT INTO handle (id, country, service) VALUES (?, '', 'SMS')`, address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert handle: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	c.handles[address] = id
+
+	if name := value(contactName); name != "" && name != "(Unknown)" {
+		c.result.ContactNames[address] = name
+	}
+	return id, nil
+}
+
+// NormalizeAddress strips the formatting phones add to numbers, so "+1 (555) 123-4567" and
+// "+15551234567" are one handle. Email addresses and short codes with letters are kept,
+// lower-cased.
+func NormalizeAddress(address string) string {
+	address = strings.TrimSpace(address)
+	var digits strings.Builder
+	for i, r := range address {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return strings.ToLower(address)
+		}
+	}
+	return digits.String()
+}
+
+// partFilename picks a safe file name for an MMS part, inventing one from the content
+// type when the part has none
+func partFilename(part Part) string {
+	for _, name := range []string{part.ContentLocation, part.Filename, part.Name} {
+		name = filepath.Base(strings.ReplaceAll(value(name), `\`, "/"))
+		if name != "" && name != "." && name != "/" && name != ".." {
+			return name
+		}
+	}
+
+	ext := ""
+	if exts, err := mime.ExtensionsByType(part.ContentType); err == nil && len(exts) > 0 {
+		ext = exts[0]
+	}
+	return fmt.Sprintf("part-%d%s", part.Seq, ext)
+}
@@ -0,0 +1,187 @@
+package android
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"threadbound/internal/attachments"
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// A 1x1 PNG
+const testImage = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAIAAACQd1PeAAAADElEQVR4nGP4z8AAAAMBAQDJ/pLvAAAAAElFTkSuQmCC"
+
+const testBackup = `<?xml version='1.0' encoding='UTF-8' standalone='yes' ?>
+<smses count="5">
+  <sms protocol="0" address="+1 (555) 123-4567" date="1700000000000" type="1" subject="null" body="Hi &#55357;&#56842;" read="1" contact_name="Alex" />
+  <sms protocol="0" address="+15551234567" date="1700000060000" type="2" subject="null" body="Hey!" read="1" contact_name="Alex" />
+  <sms protocol="0" address="+15551234567" date="1700000090000" type="3" subject="null" body="unsent draft" read="1" contact_name="Alex" />
+  <mms date="1700000120000" msg_box="1" address="+15551234567" sub="null" read="1" contact_name="Alex">
+    <parts>
+      <part seq="-1" ct="application/smil" name="null" fn="null" cl="smil.xml" text="&lt;smil/&gt;" />
+      <part seq="0" ct="image/png" name="null" fn="null" cl="IMG_0001.png" text="null" data="` + testImage + `" />
+      <part seq="1" ct="text/plain" name="null" fn="null" cl="text_0.txt" text="Look at this" />
+    </parts>
+    <addrs>
+      <addr address="+15551234567" type="137" charset="106" />
+      <addr address="insert-address-token" type="151" charset="106" />
+    </addrs>
+  </mms>
+  <mms date="1700000180000" msg_box="2" address="+15551234567~+15559876543" sub="null" read="1" contact_name="Alex, Sam">
+    <parts>
+      <part seq="0" ct="image/png" name="null" fn="null" cl="null" text="null" data="` + testImage + `" />
+    </parts>
+    <addrs>
+      <addr address="insert-address-token" type="137" charset="106" />
+      <addr address="+15559876543" type="151" charset="106" />
+      <addr address="+15551234567" type="151" charset="106" />
+    </addrs>
+  </mms>
+</smses>
+`
+
+func convertTestBackup(t *testing.T) (*Result, *database.DB, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	backupPath := filepath.Join(dir, "sms.xml")
+	if err := os.WriteFile(backupPath, []byte(testBackup), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	attachmentsPath := filepath.Join(dir, AttachmentsDir)
+	result, err := Convert(backupPath, filepath.Join(dir, "chat.db"), attachmentsPath)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	db, err := database.New(filepath.Join(dir, "chat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return result, db, attachmentsPath
+}
+
+func TestConvertMessages(t *testing.T) {
+	result, db, _ := convertTestBackup(t)
+
+	if result.Messages != 4 || result.Attachments != 2 {
+		t.Errorf("Expected 4 messages and 2 attachments, got %d and %d", result.Messages, result.Attachments)
+	}
+	if result.ContactNames["+15551234567"] != "Alex" || len(result.ContactNames) != 1 {
+		t.Errorf("Unexpected contact names %v", result.ContactNames)
+	}
+
+	messages, err := db.GetMessages()
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 messages (draft skipped), got %d", len(messages))
+	}
+
+	if got := *messages[0].Text; got != "Hi 😊" {
+		t.Errorf("Expected emoji joined from surrogate pair, got %q", got)
+	}
+	if messages[0].IsFromMe || !messages[1].IsFromMe {
+		t.Error("Expected first message received and second sent")
+	}
+	if want := "2023-11-14 22:13:20"; messages[0].FormattedDate.Format("2006-01-02 15:04:05") != want {
+		t.Errorf("Expected date %s, got %s", want, messages[0].FormattedDate)
+	}
+	if *messages[0].HandleID != *messages[1].HandleID {
+		t.Error("Expected formatted and plain numbers to share a handle")
+	}
+	if got := *messages[2].Text; got != "Look at this" || !messages[2].HasAttachments {
+		t.Errorf("Expected MMS text with attachment, got %q", got)
+	}
+
+	if got := *messages[3].Text; got != "\uFFFC" {
+		t.Errorf("Expected placeholder text for uncaptioned photo, got %q", got)
+	}
+
+	handles, err := db.GetHandles(nil)
+	if err != nil {
+		t.Fatalf("GetHandles failed: %v", err)
+	}
+	if len(handles) != 2 {
+		t.Errorf("Expected 2 handles, got %d", len(handles))
+	}
+	if handle := handles[*messages[3].HandleID]; handle.Contact != "+15559876543" {
+		t.Errorf("Expected sent group MMS to use its first recipient, got %q", handle.Contact)
+	}
+}
+
+func TestConvertAttachments(t *testing.T) {
+	_, db, attachmentsPath := convertTestBackup(t)
+
+	all, err := db.GetAllAttachments()
+	if err != nil {
+		t.Fatalf("GetAllAttachments failed: %v", err)
+	}
+
+	var names []string
+	for _, atts := range all {
+		for _, att := range atts {
+			path, err := attachments.Locate(attachmentsPath, att.GUID, *att.Filename)
+			if err != nil {
+				t.Errorf("Attachment %s not found: %v", att.GUID, err)
+			}
+			if !strings.HasPrefix(path, attachmentsPath) {
+				t.Errorf("Attachment saved outside attachments folder: %s", path)
+			}
+			names = append(names, *att.Filename)
+		}
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "IMG_0001.png" || names[1] != "part-0.png" {
+		t.Errorf("Unexpected attachment names %v", names)
+	}
+}
+
+func TestOpenKeepsConfiguredContactNames(t *testing.T) {
+	dir := t.TempDir()
+	backupPath := filepath.Join(dir, "sms.xml")
+	if err := os.WriteFile(backupPath, []byte(testBackup), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &models.BookConfig{
+		OutputPath:   filepath.Join(dir, "book.tex"),
+		ContactNames: map[string]string{"+15551234567": "Alexandra"},
+	}
+	cleanup, err := Open(backupPath, config)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer cleanup()
+
+	if config.ContactNames["+15551234567"] != "Alexandra" {
+		t.Errorf("Expected configured name kept, got %q", config.ContactNames["+15551234567"])
+	}
+	if _, err := os.Stat(config.DatabasePath); err != nil {
+		t.Errorf("Expected database at %s: %v", config.DatabasePath, err)
+	}
+	if config.AttachmentsPath != filepath.Join(dir, AttachmentsDir) {
+		t.Errorf("Expected attachments beside the output, got %s", config.AttachmentsPath)
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := map[string]string{
+		"+1 (555) 123-4567": "+15551234567",
+		"555.123.4567":      "5551234567",
+		"Alex@Example.com":  "alex@example.com",
+		"GOOGLE":            "google",
+	}
+	for address, want := range tests {
+		if got := NormalizeAddress(address); got != want {
+			t.Errorf("NormalizeAddress(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"threadbound/internal/book"
+	"threadbound/internal/manifest"
+	"threadbound/internal/service"
+)
+
+// jobFormats are the formats a generate request may ask for
+var jobFormats = []string{"tex", "pdf", "html", "txt"}
+
+// formatRun is how one format of a multi-format job went
+type formatRun struct {
+	Format    string
+	Status    JobStatus
+	Error     error
+	Artifacts []artifact
+}
+
+// newFormatRuns starts a pending run for each format
+func newFormatRuns(formats []string) []formatRun {
+	if len(formats) == 0 {
+		return nil
+	}
+	runs := make([]formatRun, len(formats))
+	for i, format := range formats {
+		runs[i] = formatRun{Format: format, Status: JobStatusPending}
+	}
+	return runs
+}
+
+// normalizeFormats checks the formats of a generate request, returning them in lower case
+// without repeats
+func normalizeFormats(formats []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		known := false
+		for _, jobFormat := range jobFormats {
+			known = known || format == jobFormat
+		}
+		if !known {
+			return nil, fmt.Errorf("unsupported format %q; formats are %s", format, strings.Join(jobFormats, ", "))
+		}
+		if !seen[format] {
+			seen[format] = true
+			normalized = append(normalized, format)
+		}
+	}
+	return normalized, nil
+}
+
+// generateFormats writes the book in each of the job's formats, beside its output path
+// with each format's extension. A PDF is built from the TeX with the configured engine, in
+// the same run as the TeX when both are asked for. It returns the result of the first
+// format written, and an error only when none was.
+func (jm *JobManager) generateFormats(job *Job) (*service.GenerateResult, error) {
+	base := strings.TrimSuffix(job.Config.OutputPath, filepath.Ext(job.Config.OutputPath))
+	tex, pdf := -1, -1
+	for i, run := range job.Runs {
+		switch run.Format {
+		case "tex":
+			tex = i
+		case "pdf":
+			pdf = i
+		}
+	}
+
+	var first *service.GenerateResult
+	var failures []string
+	for i, run := range job.Runs {
+		if i == tex && pdf >= 0 {
+			continue // Written by the PDF's run
+		}
+
+		config := *job.Config
+		config.Format = run.Format
+		config.OutputPath = base + "." + run.Format
+		covered := []int{i}
+		if i == pdf {
+			config.Format = "tex"
+			config.OutputPath = base + ".tex"
+			config.BuildPDF = true
+			if tex >= 0 {
+				covered = append(covered, tex)
+			}
+		}
+
+		jm.updateRuns(job, covered, nil, nil)
+		config.Log().Info("━━ Writing", "format", run.Format, "path", config.OutputPath)
+		result, err := service.NewGeneratorService(&config).Generate()
+		jm.updateRuns(job, covered, result, err)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", run.Format, err))
+			config.Log().Error("❌ Could not write format", "format", run.Format, "error", err)
+			continue
+		}
+		if first == nil {
+			first = result
+		}
+	}
+
+	if first == nil {
+		return nil, errors.New(strings.Join(failures, "; "))
+	}
+	return first, nil
+}
+
+// updateRuns marks the runs at indexes running while result and err are nil, and
+// otherwise records how they went
+func (jm *JobManager) updateRuns(job *Job, indexes []int, result *service.GenerateResult, err error) {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	for _, i := range indexes {
+		run := &job.Runs[i]
+		switch {
+		case err != nil:
+			run.Status = JobStatusFailed
+			run.Error = err
+		case result != nil:
+			run.Status = JobStatusCompleted
+			run.Artifacts = artifactsFor(formatFiles(run.Format, result))
+		default:
+			run.Status = JobStatusRunning
+		}
+	}
+	job.UpdatedAt = time.Now()
+}
+
+// formatFiles lists the files written for a format: the book, or each volume of it
+func formatFiles(format string, result *service.GenerateResult) []string {
+	if format == "pdf" && result.PDFPath != "" {
+		return []string{result.PDFPath}
+	}
+	if len(result.Volumes) == 0 {
+		return []string{result.OutputPath}
+	}
+	files := make([]string, len(result.Volumes))
+	for i, volume := range result.Volumes {
+		files[i] = volume.Path
+		if format == "pdf" {
+			files[i] = book.PDFPathFor(volume.Path)
+		}
+	}
+	return files
+}
+
+// runArtifacts gathers the files every format wrote, and the manifest they share
+func runArtifacts(runs []formatRun, outputPath string) []artifact {
+	var artifacts []artifact
+	for _, run := range runs {
+		artifacts = append(artifacts, run.Artifacts...)
+	}
+	return append(artifacts, artifactsFor([]string{manifest.PathFor(outputPath)})...)
+}
+
+// formatList returns the public status of each format of a job
+func formatList(runs []formatRun) []FormatStatus {
+	if len(runs) == 0 {
+		return nil
+	}
+	list := make([]FormatStatus, len(runs))
+	for i, run := range runs {
+		list[i] = FormatStatus{Format: run.Format, Status: run.Status, Artifacts: artifactList(run.Artifacts)}
+		if run.Error != nil {
+			list[i].Error = run.Error.Error()
+		}
+	}
+	return list
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"threadbound/internal/database"
+)
+
+// newTestDB writes a database with a short conversation
+func newTestDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "chat.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.GetConnection().Exec(database.Schema + `
+		INSERT INTO handle (ROWID, id, country, service) VALUES (1, '+15551234567', 'us', 'iMessage');
+		INSERT INTO message (ROWID, guid, text, date, handle_id, is_from_me) VALUES
+			(1, 'm1', 'Dinner tonight?', 700000000000000000, 1, 0),
+			(2, 'm2', 'Yes please', 700000060000000000, 0, 1);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGenerateEndpointFormats(t *testing.T) {
+	handler := NewHandlerWithOptions(Options{Workspace: t.TempDir()})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	post := func(req GenerateRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body)))
+		return w
+	}
+
+	if w := post(GenerateRequest{DatabasePath: "/tmp/test.db", Formats: []string{"tex", "epub"}}); w.Code != http.StatusBadRequest ||
+		!strings.Contains(w.Body.String(), `unsupported format \"epub\"`) {
+		t.Errorf("Expected status 400 for an unsupported format, got %d %s", w.Code, w.Body.String())
+	}
+
+	w := post(GenerateRequest{DatabasePath: newTestDB(t), AttachmentsPath: t.TempDir(), Formats: []string{"HTML", "txt", "html"}})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d %s", w.Code, w.Body.String())
+	}
+	var created GenerateResponse
+	json.NewDecoder(w.Body).Decode(&created)
+
+	var status JobStatusResponse
+	for i := 0; i < 500; i++ {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/jobs/"+created.JobID, nil))
+		status = JobStatusResponse{}
+		json.NewDecoder(w.Body).Decode(&status)
+		if status.Status == JobStatusCompleted || status.Status == JobStatusFailed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status.Status != JobStatusCompleted {
+		t.Fatalf("Expected the job to complete, got %+v", status)
+	}
+
+	// Each format is written beside the output path, with its own download links
+	if len(status.Formats) != 2 {
+		t.Fatalf("Expected html and txt once each, got %+v", status.Formats)
+	}
+	for i, want := range []string{"html", "txt"} {
+		format := status.Formats[i]
+		if format.Format != want || format.Status != JobStatusCompleted || len(format.Artifacts) != 1 || format.Artifacts[0].Name != "book."+want {
+			t.Errorf("Unexpected %s status %+v", want, format)
+			continue
+		}
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", format.Artifacts[0].URL, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Dinner tonight?") {
+			t.Errorf("Expected the %s book, got %d %q", want, w.Code, w.Body.String())
+		}
+	}
+	if len(status.Artifacts) < 2 {
+		t.Errorf("Expected the job to list every format's files, got %+v", status.Artifacts)
+	}
+}
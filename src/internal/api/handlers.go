@@ -1,71 +1,189 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"threadbound/internal/assets"
+	"threadbound/internal/filter"
+	"threadbound/internal/i18n"
 	"threadbound/internal/models"
+	"threadbound/internal/printing"
+	"threadbound/internal/redact"
 )
 
 // Handler manages API request handling
 type Handler struct {
 	jobManager *JobManager
+	options    Options
+
+	uploads      map[string]*upload // By upload ID
+	uploadsMutex sync.Mutex
 }
 
-// NewHandler creates a new API handler
+// NewHandler creates a new API handler with default options
 func NewHandler() *Handler {
-	return &Handler{
-		jobManager: NewJobManager(),
+	return NewHandlerWithOptions(Options{})
+}
+
+// NewHandlerWithOptions creates a new API handler that writes where options allow
+func NewHandlerWithOptions(options Options) *Handler {
+	options = options.withDefaults()
+	h := &Handler{
+		jobManager: NewJobManagerWithLimits(options.MaxConcurrentJobs, options.MaxQueuedJobs),
+		options:    options,
+		uploads:    make(map[string]*upload),
 	}
+	h.jobManager.finished = h.jobFinished
+	return h
 }
 
+// sqliteHeader starts every SQLite database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
 // RegisterRoutes registers all API routes
 func (h *Handler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/api/uploads", h.handleUpload).Methods("POST")
 	r.HandleFunc("/api/generate", h.handleGenerate).Methods("POST")
 	r.HandleFunc("/api/jobs/{job_id}", h.handleGetJobStatus).Methods("GET")
+	r.HandleFunc("/api/jobs/{job_id}/output", h.handleGetJobOutput).Methods("GET")
+	r.HandleFunc("/api/jobs/{job_id}/logs", h.handleGetJobLogs).Methods("GET")
+	r.HandleFunc("/api/artifacts/{artifact_id}", h.handleGetArtifact).Methods("GET")
 	r.HandleFunc("/api/jobs", h.handleListJobs).Methods("GET")
 	r.HandleFunc("/api/health", h.handleHealth).Methods("GET")
+	r.HandleFunc("/api/openapi.json", h.handleOpenAPI).Methods("GET")
 }
 
 // handleGenerate handles POST /api/generate
 func (h *Handler) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	if h.jobManager.Draining() {
+		respondError(w, http.StatusServiceUnavailable, "Server is shutting down", ErrDraining)
+		return
+	}
+
 	var req GenerateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
+	h.expireUploads()
+	var jobID string
+	if req.UploadID != "" {
+		dir, exists := h.holdUpload(req.UploadID)
+		if !exists {
+			respondError(w, http.StatusNotFound, "Upload not found", nil)
+			return
+		}
+		// Until a job owns the upload, a refused request leaves it for the next
+		defer func() {
+			if jobID == "" {
+				h.unholdUpload(req.UploadID)
+			}
+		}()
+		req.DatabasePath = filepath.Join(dir, "chat.db")
+	}
+
 	// Validate required fields
 	if req.DatabasePath == "" {
 		respondError(w, http.StatusBadRequest, "database_path is required", nil)
 		return
 	}
+	if _, err := filter.ParseQuery(req.Filter); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid filter", err)
+		return
+	}
+	if _, err := (&models.BookConfig{Timezone: req.Timezone}).Location(); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid timezone", err)
+		return
+	}
+	if _, err := i18n.Lookup(req.Locale); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid locale", err)
+		return
+	}
+	if req.RedactProfile != "" {
+		if _, err := redact.Profile(req.RedactProfile, req.RedactProfiles); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid redaction profile", err)
+			return
+		}
+	}
+	if req.PrintPreset != "" {
+		if _, err := printing.Lookup(req.PrintPreset); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid print preset", err)
+			return
+		}
+	}
+	formats, err := normalizeFormats(req.Formats)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid formats", err)
+		return
+	}
+
+	// Turn the request away before making it a directory when the queue is already full
+	if h.jobManager.QueueFull() {
+		w.Header().Set("Retry-After", "60")
+		respondError(w, http.StatusTooManyRequests, "Too many jobs queued", ErrQueueFull)
+		return
+	}
+
+	// Books go in a directory of their own unless the request names an allowed path
+	outputPath, err := h.resolveOutputPath(req.OutputPath)
+	if errors.Is(err, errOutputNotAllowed) {
+		respondError(w, http.StatusForbidden, "Output path not allowed", err)
+		return
+	} else if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create job directory", err)
+		return
+	}
 
 	// Create book config from request
 	config := &models.BookConfig{
 		DatabasePath:    req.DatabasePath,
 		AttachmentsPath: req.AttachmentsPath,
-		OutputPath:      req.OutputPath,
+		OutputPath:      outputPath,
 		Title:           req.Title,
 		Author:          req.Author,
 		PageWidth:       req.PageWidth,
 		PageHeight:      req.PageHeight,
-		TemplateDir:     "", // Templates are embedded in binary
+		TemplateDir:     assets.Dir(assets.Templates), // Empty falls back to templates embedded in binary
 		IncludeImages:   req.IncludeImages,
 		IncludePreviews: true,
 		ContactNames:    req.ContactNames,
 		MyName:          req.MyName,
+		Filter:          req.Filter,
+		ChatID:          req.ChatID,
+		Timezone:        req.Timezone,
+		Locale:          req.Locale,
+
+		RedactProfile:   req.RedactProfile,
+		RedactProfiles:  req.RedactProfiles,
+		ExcludeContacts: req.ExcludeContacts,
+		ExcludeKeywords: req.ExcludeKeywords,
+		FilterSpam:      req.FilterSpam,
+		Dedupe:          req.Dedupe,
+		Theme:           req.Theme,
+		PrintPreset:     req.PrintPreset,
+		PrintPages:      req.PrintPages,
+		NotesPages:      req.NotesPages,
+		ChapterStats:    req.ChapterStats,
+		StatsChapter:    req.StatsChapter,
 	}
 
 	// Set defaults
 	if config.AttachmentsPath == "" {
 		config.AttachmentsPath = "Attachments"
 	}
-	if config.OutputPath == "" {
-		config.OutputPath = "book.tex"
-	}
 	if config.Title == "" {
 		config.Title = "Our Messages"
 	}
@@ -76,8 +194,21 @@ func (h *Handler) handleGenerate(w http.ResponseWriter, r *http.Request) {
 		config.PageHeight = "8.5in"
 	}
 
-	// Create and start job
-	jobID := h.jobManager.CreateJob(config)
+	// Create and start job. The queue can fill between the check above and here, so a
+	// refused job's directory is removed again.
+	jobID, err = h.jobManager.CreateJobWithFormats(config, formats)
+	if err != nil && h.ownsDir(filepath.Dir(outputPath), "job") {
+		os.RemoveAll(filepath.Dir(outputPath))
+	}
+	if errors.Is(err, ErrQueueFull) {
+		w.Header().Set("Retry-After", "60")
+		respondError(w, http.StatusTooManyRequests, "Too many jobs queued", err)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusServiceUnavailable, "Server is shutting down", err)
+		return
+	}
 
 	// Return response
 	resp := GenerateResponse{
@@ -90,6 +221,131 @@ func (h *Handler) handleGenerate(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusAccepted, resp)
 }
 
+// handleUpload handles POST /api/uploads. The request body is a chat.db file, stored so
+// generate requests can refer to it by upload ID until the jobs using it finish, or until
+// it expires unused.
+func (h *Handler) handleUpload(w http.ResponseWriter, r *http.Request) {
+	h.expireUploads()
+
+	// Message databases can take longer to arrive than the server's read timeout allows,
+	// though not forever, and no larger than the limit
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadReadTimeout))
+	body := http.MaxBytesReader(w, r.Body, h.options.MaxUploadSize)
+
+	dir, err := h.workspaceDir("upload")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to store upload", err)
+		return
+	}
+
+	size, err := saveDatabase(body, filepath.Join(dir, "chat.db"))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		os.RemoveAll(dir)
+		respondError(w, http.StatusRequestEntityTooLarge, "Database upload too large", err)
+		return
+	} else if err != nil {
+		os.RemoveAll(dir)
+		respondError(w, http.StatusBadRequest, "Invalid database upload", err)
+		return
+	}
+
+	uploadID := uuid.New().String()
+	h.addUpload(uploadID, dir)
+
+	respondJSON(w, http.StatusCreated, UploadResponse{UploadID: uploadID, Size: size})
+}
+
+// saveDatabase writes an uploaded database to path after checking it is SQLite
+func saveDatabase(body io.Reader, path string) (int64, error) {
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(body, header); err != nil || !bytes.Equal(header, sqliteHeader) {
+		return 0, fmt.Errorf("body is not an SQLite database")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(file, io.MultiReader(bytes.NewReader(header), body))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return size, err
+}
+
+// handleGetJobOutput handles GET /api/jobs/{job_id}/output, sending the generated file
+func (h *Handler) handleGetJobOutput(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobManager.GetJob(mux.Vars(r)["job_id"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Job not found", err)
+		return
+	}
+	if job.Status != JobStatusCompleted || job.Result == nil {
+		respondError(w, http.StatusConflict, "Job has not completed", nil)
+		return
+	}
+
+	serveFile(w, r, job.Result.OutputPath)
+}
+
+// handleGetArtifact handles GET /api/artifacts/{artifact_id}
+func (h *Handler) handleGetArtifact(w http.ResponseWriter, r *http.Request) {
+	artifact, err := h.jobManager.GetArtifact(mux.Vars(r)["artifact_id"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Artifact not found", err)
+		return
+	}
+
+	serveFile(w, r, artifact.path)
+}
+
+// serveFile sends a file produced by a job as a download
+func serveFile(w http.ResponseWriter, r *http.Request, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Output file not found", err)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to read output file", err)
+		return
+	}
+
+	// Books with images can take longer to send than the server's write timeout allows
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	name := filepath.Base(path)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// handleGetJobLogs handles GET /api/jobs/{job_id}/logs, returning the last ?tail= lines
+// of the job's output (every line kept when tail is absent)
+func (h *Handler) handleGetJobLogs(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobManager.GetJob(mux.Vars(r)["job_id"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Job not found", err)
+		return
+	}
+
+	tail := 0
+	if value := r.URL.Query().Get("tail"); value != "" {
+		tail, err = strconv.Atoi(value)
+		if err != nil || tail < 0 {
+			respondError(w, http.StatusBadRequest, "tail must be a non-negative number of lines", nil)
+			return
+		}
+	}
+
+	resp := JobLogsResponse{JobID: job.ID, Status: job.Status, Lines: []string{}}
+	if job.Log != nil {
+		resp.Lines, resp.TotalLines = job.Log.Tail(tail)
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
 // handleGetJobStatus handles GET /api/jobs/{job_id}
 func (h *Handler) handleGetJobStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -112,8 +368,9 @@ func (h *Handler) handleGetJobStatus(w http.ResponseWriter, r *http.Request) {
 		resp.Error = job.Error.Error()
 	}
 
+	resp.Artifacts = artifactList(job.Artifacts)
+	resp.Formats = formatList(job.Runs)
 	if job.Result != nil {
-		resp.OutputPath = job.Result.OutputPath
 		if job.Result.Stats != nil {
 			resp.Stats = &JobStats{
 				TotalMessages:   job.Result.Stats.TotalMessages,
@@ -122,19 +379,37 @@ func (h *Handler) handleGetJobStatus(w http.ResponseWriter, r *http.Request) {
 				AttachmentCount: job.Result.Stats.AttachmentCount,
 				StartDate:       job.Result.Stats.StartDate,
 				EndDate:         job.Result.Stats.EndDate,
+
+				ExcludedByContact: job.Result.Stats.ExcludedByContact,
+				ExcludedByKeyword: job.Result.Stats.ExcludedByKeyword,
+				ExcludedAsSpam:    job.Result.Stats.ExcludedAsSpam,
 			}
 		}
+		if job.Result.PageCount > 0 {
+			resp.PageCount = job.Result.PageCount
+			resp.SpineWidths = printing.SpineWidths(job.Result.PageCount)
+		}
+		resp.AttachmentReport = job.Result.Attachments
 	}
 
 	switch job.Status {
 	case JobStatusPending:
 		resp.Message = "Job is pending"
+		if ahead := h.jobManager.QueuePosition(job.ID); ahead > 0 {
+			resp.Message = fmt.Sprintf("Job is queued behind %d others", ahead)
+		}
 	case JobStatusRunning:
 		resp.Message = "Job is running"
 	case JobStatusCompleted:
 		resp.Message = "Job completed successfully"
+		for _, format := range resp.Formats {
+			if format.Status == JobStatusFailed {
+				resp.Message = "Job completed, but some formats failed; see /api/jobs/" + job.ID + "/logs for its output"
+				break
+			}
+		}
 	case JobStatusFailed:
-		resp.Message = "Job failed"
+		resp.Message = "Job failed; see /api/jobs/" + job.ID + "/logs for its output"
 	}
 
 	respondJSON(w, http.StatusOK, resp)
@@ -157,9 +432,8 @@ func (h *Handler) handleListJobs(w http.ResponseWriter, r *http.Request) {
 			resp.Error = job.Error.Error()
 		}
 
-		if job.Result != nil {
-			resp.OutputPath = job.Result.OutputPath
-		}
+		resp.Artifacts = artifactList(job.Artifacts)
+		resp.Formats = formatList(job.Runs)
 
 		responses = append(responses, resp)
 	}
@@ -167,10 +441,26 @@ func (h *Handler) handleListJobs(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, responses)
 }
 
+// artifactList returns the public descriptions of a job's artifacts
+func artifactList(artifacts []artifact) []Artifact {
+	if len(artifacts) == 0 {
+		return nil
+	}
+	list := make([]Artifact, len(artifacts))
+	for i, artifact := range artifacts {
+		list[i] = artifact.Artifact
+	}
+	return list
+}
+
 // handleHealth handles GET /api/health
 func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := "ok"
+	if h.jobManager.Draining() {
+		status = "draining"
+	}
 	respondJSON(w, http.StatusOK, map[string]string{
-		"status": "ok",
+		"status": status,
 	})
 }
 
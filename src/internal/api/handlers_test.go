@@ -3,12 +3,18 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
+	"threadbound/internal/models"
+	"threadbound/internal/service"
 )
 
 func TestHealthEndpoint(t *testing.T) {
@@ -112,6 +118,76 @@ func TestGenerateEndpointMissingDatabasePath(t *testing.T) {
 	}
 }
 
+func TestGenerateEndpointInvalidFilter(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body, _ := json.Marshal(GenerateRequest{DatabasePath: "/tmp/test.db", Filter: "has:gif"})
+	req := httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGenerateEndpointBookOptions(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body, _ := json.Marshal(GenerateRequest{
+		DatabasePath:    "/nonexistent/chat.db",
+		AttachmentsPath: t.TempDir(),
+		RedactProfile:   "family",
+		RedactProfiles:  map[string]models.RedactRules{"family": {Words: []string{"Grandma"}}},
+		ExcludeContacts: []string{"+15550100"},
+		ExcludeKeywords: []string{"password"},
+		FilterSpam:      true,
+		Dedupe:          true,
+		Theme:           models.ThemeConfig{SentColor: "#AACCEE"},
+		PrintPreset:     "kdp",
+		NotesPages:      models.NotesPagesConfig{Count: 2},
+		StatsChapter:    true,
+	})
+	req := httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var response GenerateResponse
+	json.NewDecoder(w.Body).Decode(&response)
+
+	job, err := handler.jobManager.GetJob(response.JobID)
+	if err != nil {
+		t.Fatalf("Expected the job: %v", err)
+	}
+	config := job.Config
+	if config.RedactProfile != "family" || len(config.RedactProfiles["family"].Words) != 1 ||
+		len(config.ExcludeContacts) != 1 || len(config.ExcludeKeywords) != 1 || !config.FilterSpam || !config.Dedupe ||
+		config.Theme.SentColor != "#AACCEE" || config.PrintPreset != "kdp" || config.NotesPages.Count != 2 || !config.StatsChapter {
+		t.Errorf("Expected the book options passed to the job, got %+v", config)
+	}
+
+	// Options the server can't apply are turned away rather than ignored
+	for _, bad := range []GenerateRequest{
+		{DatabasePath: "/nonexistent/chat.db", RedactProfile: "unknown"},
+		{DatabasePath: "/nonexistent/chat.db", PrintPreset: "unknown"},
+	} {
+		body, _ := json.Marshal(bad)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %+v, got %d", bad, w.Code)
+		}
+	}
+}
+
 func TestGetJobStatus(t *testing.T) {
 	handler := NewHandler()
 	router := mux.NewRouter()
@@ -223,3 +299,382 @@ func TestListJobs(t *testing.T) {
 		t.Errorf("Expected at least 2 jobs, got %d", len(jobs))
 	}
 }
+
+func TestUploadEndpoint(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	db := append([]byte("SQLite format 3\x00"), make([]byte, 84)...)
+	req := httptest.NewRequest("POST", "/api/uploads", bytes.NewReader(db))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	var upload UploadResponse
+	if err := json.NewDecoder(w.Body).Decode(&upload); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if upload.Size != int64(len(db)) {
+		t.Errorf("Expected size %d, got %d", len(db), upload.Size)
+	}
+
+	dir := handler.uploads[upload.UploadID].dir
+	defer os.RemoveAll(dir)
+	stored, err := os.ReadFile(filepath.Join(dir, "chat.db"))
+	if err != nil || !bytes.Equal(stored, db) {
+		t.Errorf("Uploaded database not stored intact (err %v)", err)
+	}
+
+	// Generating from the upload reads it in place and writes to the job's own directory
+	body, _ := json.Marshal(GenerateRequest{UploadID: upload.UploadID, OutputPath: "book.tex"})
+	req = httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", w.Code)
+	}
+	var genResponse GenerateResponse
+	json.NewDecoder(w.Body).Decode(&genResponse)
+	job, _ := handler.jobManager.GetJob(genResponse.JobID)
+	if job.Config.DatabasePath != filepath.Join(dir, "chat.db") {
+		t.Errorf("Expected database in upload dir, got %s", job.Config.DatabasePath)
+	}
+	if filepath.Base(job.Config.OutputPath) != "book.tex" || filepath.Dir(job.Config.OutputPath) == dir {
+		t.Errorf("Expected output in a job dir, got %s", job.Config.OutputPath)
+	}
+}
+
+func TestUploadEndpointRejectsNonSQLite(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest("POST", "/api/uploads", bytes.NewReader([]byte("not a database")))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if len(handler.uploads) != 0 {
+		t.Errorf("Expected no uploads recorded, got %d", len(handler.uploads))
+	}
+}
+
+func TestUploadEndpointRejectsLargeUpload(t *testing.T) {
+	workspace := t.TempDir()
+	handler := NewHandlerWithOptions(Options{Workspace: workspace, MaxUploadSize: 100})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	db := append([]byte("SQLite format 3\x00"), make([]byte, 200)...)
+	req := httptest.NewRequest("POST", "/api/uploads", bytes.NewReader(db))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", w.Code)
+	}
+	if entries, _ := os.ReadDir(workspace); len(entries) != 0 {
+		t.Errorf("Expected the partial upload removed, found %d entries", len(entries))
+	}
+}
+
+func TestUploadRemovedAfterJob(t *testing.T) {
+	handler := NewHandlerWithOptions(Options{Workspace: t.TempDir()})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	db := append([]byte("SQLite format 3\x00"), make([]byte, 84)...)
+	req := httptest.NewRequest("POST", "/api/uploads", bytes.NewReader(db))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var upload UploadResponse
+	json.NewDecoder(w.Body).Decode(&upload)
+	dir := handler.uploads[upload.UploadID].dir
+
+	// The job fails on the blank database, and its upload goes with it
+	body, _ := json.Marshal(GenerateRequest{UploadID: upload.UploadID})
+	req = httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", w.Code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the upload removed once its job finished")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	handler.uploadsMutex.Lock()
+	defer handler.uploadsMutex.Unlock()
+	if len(handler.uploads) != 0 {
+		t.Errorf("Expected no uploads left, got %d", len(handler.uploads))
+	}
+}
+
+func TestUploadExpires(t *testing.T) {
+	handler := NewHandlerWithOptions(Options{Workspace: t.TempDir(), UploadTTL: time.Millisecond})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	db := append([]byte("SQLite format 3\x00"), make([]byte, 84)...)
+	var dirs []string
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/api/uploads", bytes.NewReader(db))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var upload UploadResponse
+		json.NewDecoder(w.Body).Decode(&upload)
+		dirs = append(dirs, handler.uploads[upload.UploadID].dir)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The second upload sweeps away the first, which expired unused
+	if _, err := os.Stat(dirs[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected the expired upload removed, got %v", err)
+	}
+	if _, err := os.Stat(dirs[1]); err != nil {
+		t.Errorf("Expected the new upload kept, got %v", err)
+	}
+}
+
+func TestGenerateEndpointUnknownUpload(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body, _ := json.Marshal(GenerateRequest{UploadID: "missing"})
+	req := httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGetJobOutput(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	outputPath := filepath.Join(t.TempDir(), "book.tex")
+	if err := os.WriteFile(outputPath, []byte("\\documentclass{book}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handler.jobManager.jobs["done"] = &Job{
+		ID:     "done",
+		Status: JobStatusCompleted,
+		Result: &service.GenerateResult{OutputPath: outputPath},
+	}
+	handler.jobManager.jobs["running"] = &Job{ID: "running", Status: JobStatusRunning}
+
+	req := httptest.NewRequest("GET", "/api/jobs/done/output", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Body.String() != "\\documentclass{book}" {
+		t.Errorf("Unexpected output body %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="book.tex"` {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+
+	req = httptest.NewRequest("GET", "/api/jobs/running/output", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for running job, got %d", w.Code)
+	}
+}
+
+func TestGetJobStatusPageCount(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	handler.jobManager.jobs["pdf"] = &Job{
+		ID:     "pdf",
+		Status: JobStatusCompleted,
+		Result: &service.GenerateResult{OutputPath: "book.pdf", PageCount: 200},
+	}
+
+	req := httptest.NewRequest("GET", "/api/jobs/pdf", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var status JobStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.PageCount != 200 {
+		t.Errorf("Expected page count 200, got %d", status.PageCount)
+	}
+	if status.SpineWidths["cream"] != 0.5 {
+		t.Errorf("Expected 0.5in cream spine, got %v", status.SpineWidths["cream"])
+	}
+}
+
+func TestGetJobLogs(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	log := &jobLog{}
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(log, "line %d\n", i)
+	}
+	handler.jobManager.jobs["failed"] = &Job{ID: "failed", Status: JobStatusFailed, Log: log}
+
+	req := httptest.NewRequest("GET", "/api/jobs/failed/logs?tail=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var logs JobLogsResponse
+	if err := json.NewDecoder(w.Body).Decode(&logs); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(logs.Lines) != 2 || logs.Lines[1] != "line 5" || logs.TotalLines != 5 {
+		t.Errorf("Unexpected logs %+v", logs)
+	}
+
+	req = httptest.NewRequest("GET", "/api/jobs/failed/logs?tail=lots", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid tail, got %d", w.Code)
+	}
+}
+
+func TestJobCapturesLog(t *testing.T) {
+	jm := NewJobManager()
+	jobID, _ := jm.CreateJob(&models.BookConfig{DatabasePath: "/nonexistent/chat.db", OutputPath: "book.tex"})
+
+	var job *Job
+	for i := 0; i < 100; i++ {
+		job, _ = jm.GetJob(jobID)
+		jm.mutex.RLock()
+		status := job.Status
+		jm.mutex.RUnlock()
+		if status == JobStatusFailed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	lines, _ := job.Log.Tail(0)
+	if len(lines) == 0 || !strings.HasPrefix(lines[len(lines)-1], "❌") {
+		t.Errorf("Expected failure recorded in job log, got %q", lines)
+	}
+}
+
+func TestGenerateEndpointOutputPaths(t *testing.T) {
+	allowed := t.TempDir()
+	handler := NewHandlerWithOptions(Options{Workspace: t.TempDir(), AllowedOutputDirs: []string{allowed}})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	tests := []struct {
+		outputPath string
+		wantStatus int
+	}{
+		{"book.pdf", http.StatusAccepted},
+		{filepath.Join(allowed, "books", "book.tex"), http.StatusAccepted},
+		{filepath.Join(allowed, "..", "book.tex"), http.StatusForbidden},
+		{"/etc/book.tex", http.StatusForbidden},
+		{"../book.tex", http.StatusForbidden},
+		{"books/book.tex", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(GenerateRequest{DatabasePath: "/path/to/test.db", OutputPath: tt.outputPath})
+		req := httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("output_path %q: expected status %d, got %d", tt.outputPath, tt.wantStatus, w.Code)
+		}
+	}
+}
+
+func TestResolveOutputPathJobDir(t *testing.T) {
+	workspace := t.TempDir()
+	handler := NewHandlerWithOptions(Options{Workspace: workspace})
+
+	first, err := handler.resolveOutputPath("")
+	if err != nil {
+		t.Fatalf("resolveOutputPath failed: %v", err)
+	}
+	second, _ := handler.resolveOutputPath("")
+
+	if filepath.Base(first) != "book.tex" || filepath.Dir(filepath.Dir(first)) != workspace {
+		t.Errorf("Expected book.tex in a job dir under the workspace, got %s", first)
+	}
+	if filepath.Dir(first) == filepath.Dir(second) {
+		t.Error("Expected each job to get its own directory")
+	}
+}
+
+func TestGetArtifact(t *testing.T) {
+	handler := NewHandlerWithOptions(Options{Workspace: t.TempDir()})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	outputPath := filepath.Join(t.TempDir(), "book.tex")
+	if err := os.WriteFile(outputPath, []byte("book"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handler.jobManager.jobs["done"] = &Job{
+		ID:        "done",
+		Status:    JobStatusCompleted,
+		Result:    &service.GenerateResult{OutputPath: outputPath},
+		Artifacts: collectArtifacts(outputPath),
+	}
+
+	req := httptest.NewRequest("GET", "/api/jobs/done", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var status JobStatusResponse
+	json.NewDecoder(w.Body).Decode(&status)
+	if len(status.Artifacts) != 1 || status.Artifacts[0].Name != "book.tex" || status.Artifacts[0].Size != 4 {
+		t.Fatalf("Unexpected artifacts %+v", status.Artifacts)
+	}
+	if strings.Contains(status.Artifacts[0].URL, outputPath) {
+		t.Errorf("Artifact URL reveals the output path: %s", status.Artifacts[0].URL)
+	}
+
+	req = httptest.NewRequest("GET", status.Artifacts[0].URL, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "book" {
+		t.Errorf("Expected artifact contents, got %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/artifacts/missing", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown artifact, got %d", w.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxJobLogLines is how many of a job's most recent log lines are kept
+const maxJobLogLines = 5000
+
+// jobLog collects a job's progress output line by line, keeping only the most recent lines
+type jobLog struct {
+	lines   []string
+	partial string // Text after the last newline
+	total   int    // Complete lines written, including those dropped
+	mutex   sync.Mutex
+}
+
+// Write implements io.Writer so the log can stand in for stdout
+func (l *jobLog) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	text := l.partial + string(p)
+	parts := strings.Split(text, "\n")
+	l.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		l.lines = append(l.lines, line)
+		l.total++
+	}
+
+	// Trim in batches so writes stay cheap
+	if len(l.lines) > 2*maxJobLogLines {
+		l.lines = append([]string(nil), l.lines[len(l.lines)-maxJobLogLines:]...)
+	}
+	return len(p), nil
+}
+
+// Tail returns up to n of the most recent lines (all kept lines when n <= 0), including
+// an unfinished last line, and the total number of lines written
+func (l *jobLog) Tail(n int) ([]string, int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	lines := l.lines
+	if len(lines) > maxJobLogLines {
+		lines = lines[len(lines)-maxJobLogLines:]
+	}
+	total := l.total
+	if l.partial != "" {
+		lines = append(lines[:len(lines):len(lines)], l.partial)
+		total++
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append([]string(nil), lines...), total
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestJobLogTail(t *testing.T) {
+	log := &jobLog{}
+	fmt.Fprintf(log, "first\nsec")
+	fmt.Fprintf(log, "ond\nthird")
+
+	lines, total := log.Tail(0)
+	if total != 3 || len(lines) != 3 || lines[1] != "second" || lines[2] != "third" {
+		t.Errorf("Unexpected lines %q (total %d)", lines, total)
+	}
+
+	lines, _ = log.Tail(2)
+	if len(lines) != 2 || lines[0] != "second" {
+		t.Errorf("Expected last 2 lines, got %q", lines)
+	}
+}
+
+func TestJobLogKeepsRecentLines(t *testing.T) {
+	log := &jobLog{}
+	for i := 0; i < 3*maxJobLogLines; i++ {
+		fmt.Fprintf(log, "line %d\n", i)
+	}
+
+	lines, total := log.Tail(0)
+	if total != 3*maxJobLogLines {
+		t.Errorf("Expected total %d, got %d", 3*maxJobLogLines, total)
+	}
+	if len(lines) != maxJobLogLines {
+		t.Fatalf("Expected %d lines kept, got %d", maxJobLogLines, len(lines))
+	}
+	if want := fmt.Sprintf("line %d", 3*maxJobLogLines-1); lines[len(lines)-1] != want {
+		t.Errorf("Expected last line %q, got %q", want, lines[len(lines)-1])
+	}
+}
@@ -1,11 +1,17 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"threadbound/internal/logging"
 	"threadbound/internal/models"
 	"threadbound/internal/service"
 )
@@ -15,8 +21,12 @@ type Job struct {
 	ID         string
 	Status     JobStatus
 	Config     *models.BookConfig
+	Formats    []string    // Output formats asked for; empty writes Config.OutputPath as it is
+	Runs       []formatRun // How each of Formats went
 	Result     *service.GenerateResult
 	Error      error
+	Log        *jobLog    // Progress output, including XeLaTeX's when a build fails
+	Artifacts  []artifact // Files produced, registered when the job completes
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	cancelFunc func()
@@ -24,37 +34,185 @@ type Job struct {
 
 // JobManager manages async job processing
 type JobManager struct {
-	jobs  map[string]*Job
-	mutex sync.RWMutex
+	jobs      map[string]*Job
+	mutex     sync.RWMutex
+	running   sync.WaitGroup
+	draining  bool          // Set once shutdown begins; no new jobs are accepted
+	slots     chan struct{} // Holds a token for each job generating, up to the concurrency cap
+	stop      chan struct{} // Closed by Drain so queued jobs stop waiting for a slot
+	maxQueued int
+	finished  func(job *Job) // Called with a copy of each job that completes or fails
 }
 
-// NewJobManager creates a new job manager
+// ErrDraining is returned for jobs submitted while the server shuts down
+var ErrDraining = errors.New("server is shutting down and not accepting new jobs")
+
+// ErrQueueFull is returned for jobs submitted while the queue is at its depth limit
+var ErrQueueFull = errors.New("too many jobs are queued; try again later")
+
+// NewJobManager creates a new job manager with the default limits
 func NewJobManager() *JobManager {
+	return NewJobManagerWithLimits(DefaultMaxConcurrentJobs, DefaultMaxQueuedJobs)
+}
+
+// NewJobManagerWithLimits creates a job manager that generates at most maxConcurrent jobs
+// at once and holds at most maxQueued more waiting for their turn
+func NewJobManagerWithLimits(maxConcurrent, maxQueued int) *JobManager {
+	if maxConcurrent <= 0 {
+		maxConcurrent = DefaultMaxConcurrentJobs
+	}
 	return &JobManager{
-		jobs: make(map[string]*Job),
+		jobs:      make(map[string]*Job),
+		slots:     make(chan struct{}, maxConcurrent),
+		stop:      make(chan struct{}),
+		maxQueued: maxQueued,
 	}
 }
 
 // CreateJob creates a new job and starts processing it asynchronously
-func (jm *JobManager) CreateJob(config *models.BookConfig) string {
+func (jm *JobManager) CreateJob(config *models.BookConfig) (string, error) {
+	return jm.CreateJobWithFormats(config, nil)
+}
+
+// CreateJobWithFormats creates a job writing the book in each of formats, such as tex and
+// html, and starts processing it asynchronously
+func (jm *JobManager) CreateJobWithFormats(config *models.BookConfig, formats []string) (string, error) {
+	return jm.startJob(uuid.New().String(), config, formats, time.Now(), true)
+}
+
+// startJob registers a job under the given ID and queues it to be processed once a slot is
+// free. With bounded set, it's refused when the queue is already full.
+func (jm *JobManager) startJob(jobID string, config *models.BookConfig, formats []string, createdAt time.Time, bounded bool) (string, error) {
 	jm.mutex.Lock()
 	defer jm.mutex.Unlock()
 
-	jobID := uuid.New().String()
+	if jm.draining {
+		return "", ErrDraining
+	}
+	if bounded && jm.queueFull() {
+		return "", ErrQueueFull
+	}
+
+	log := &jobLog{}
+	// Keep logging to the server console, tagged with the job, while capturing the job's own
+	// output as plain lines for /api/jobs/{job_id}/logs
+	config.Logger = slog.New(logging.Tee(
+		logging.NewHandler(log, logging.Options{Level: logging.Defaults().Level}),
+		logging.Default(os.Stdout).With("job_id", jobID).Handler(),
+	))
 	job := &Job{
 		ID:        jobID,
 		Status:    JobStatusPending,
 		Config:    config,
-		CreatedAt: time.Now(),
+		Formats:   formats,
+		Runs:      newFormatRuns(formats),
+		Log:       log,
+		CreatedAt: createdAt,
 		UpdatedAt: time.Now(),
 	}
 
 	jm.jobs[jobID] = job
 
-	// Start processing in background
-	go jm.processJob(jobID)
+	// Take a free slot now, so the job runs even if draining begins before it starts
+	started := false
+	select {
+	case jm.slots <- struct{}{}:
+		started = true
+	default:
+	}
+
+	// Start processing in background, once a slot is free if none was
+	jm.running.Add(1)
+	go func() {
+		defer jm.running.Done()
+		if !started {
+			select {
+			case jm.slots <- struct{}{}:
+			case <-jm.stop:
+				return // Left pending, so Drain saves it
+			}
+		}
+		defer func() { <-jm.slots }()
+		jm.processJob(jobID)
+	}()
+
+	return jobID, nil
+}
 
-	return jobID
+// queued returns the jobs waiting for a slot, oldest first. The caller holds the mutex.
+func (jm *JobManager) queued() []*Job {
+	var queued []*Job
+	for _, job := range jm.jobs {
+		if job.Status == JobStatusPending {
+			queued = append(queued, job)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool { return queued[i].CreatedAt.Before(queued[j].CreatedAt) })
+	return queued
+}
+
+// QueuePosition returns how many queued jobs are ahead of a pending job
+func (jm *JobManager) QueuePosition(jobID string) int {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+
+	for i, job := range jm.queued() {
+		if job.ID == jobID {
+			return i
+		}
+	}
+	return 0
+}
+
+// Drain stops accepting jobs and starting queued ones, and waits for the ones in progress to
+// finish or for ctx to end. It returns the jobs still unfinished, queued ones included.
+func (jm *JobManager) Drain(ctx context.Context) []*Job {
+	jm.mutex.Lock()
+	if !jm.draining {
+		jm.draining = true
+		close(jm.stop)
+	}
+	jm.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		jm.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+
+	var unfinished []*Job
+	for _, job := range jm.jobs {
+		if job.Status == JobStatusPending || job.Status == JobStatusRunning {
+			unfinished = append(unfinished, job)
+		}
+	}
+	return unfinished
+}
+
+// QueueFull reports whether new jobs would be refused for the queue being at its depth limit
+func (jm *JobManager) QueueFull() bool {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	return jm.queueFull()
+}
+
+// queueFull is QueueFull for a caller holding the mutex
+func (jm *JobManager) queueFull() bool {
+	return jm.maxQueued > 0 && len(jm.queued()) >= jm.maxQueued
+}
+
+// Draining reports whether the manager has stopped accepting jobs
+func (jm *JobManager) Draining() bool {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	return jm.draining
 }
 
 // processJob processes a job asynchronously
@@ -69,13 +227,21 @@ func (jm *JobManager) processJob(jobID string) {
 	job.UpdatedAt = time.Now()
 	jm.mutex.Unlock()
 
-	// Create generator service
-	genService := service.NewGeneratorService(job.Config)
-
-	// Execute generation
-	result, err := genService.Generate()
+	var result *service.GenerateResult
+	var err error
+	if len(job.Formats) > 0 {
+		result, err = jm.generateFormats(job)
+	} else {
+		result, err = service.NewGeneratorService(job.Config).Generate()
+	}
 
-	// Update job with result
+	// Update job with result, then report it finished once the lock is released
+	var finished *Job
+	defer func() {
+		if jm.finished != nil {
+			jm.finished(finished)
+		}
+	}()
 	jm.mutex.Lock()
 	defer jm.mutex.Unlock()
 
@@ -83,13 +249,21 @@ func (jm *JobManager) processJob(jobID string) {
 	if err != nil {
 		job.Status = JobStatusFailed
 		job.Error = err
+		job.Config.Log().Error("❌ Job failed", "error", err)
 	} else {
 		job.Status = JobStatusCompleted
 		job.Result = result
+		if len(job.Runs) > 0 {
+			job.Artifacts = runArtifacts(job.Runs, job.Config.OutputPath)
+		} else {
+			job.Artifacts = collectArtifacts(result.OutputPath)
+		}
 	}
+	finished = job.snapshot()
 }
 
-// GetJob retrieves a job by ID
+// GetJob retrieves a copy of a job by ID, taken under the lock so it can be read while the
+// job runs
 func (jm *JobManager) GetJob(jobID string) (*Job, error) {
 	jm.mutex.RLock()
 	defer jm.mutex.RUnlock()
@@ -99,17 +273,43 @@ func (jm *JobManager) GetJob(jobID string) (*Job, error) {
 		return nil, fmt.Errorf("job not found: %s", jobID)
 	}
 
-	return job, nil
+	return job.snapshot(), nil
+}
+
+// snapshot copies the job along with the slices processJob and updateRuns change. The
+// caller holds the mutex.
+func (job *Job) snapshot() *Job {
+	copied := *job
+	copied.Formats = append([]string(nil), job.Formats...)
+	copied.Runs = append([]formatRun(nil), job.Runs...)
+	copied.Artifacts = append([]artifact(nil), job.Artifacts...)
+	return &copied
+}
+
+// GetArtifact finds a file produced by any job
+func (jm *JobManager) GetArtifact(artifactID string) (*artifact, error) {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+
+	for _, job := range jm.jobs {
+		for i := range job.Artifacts {
+			if job.Artifacts[i].ID == artifactID {
+				found := job.Artifacts[i]
+				return &found, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("artifact not found: %s", artifactID)
 }
 
-// ListJobs returns all jobs
+// ListJobs returns copies of all jobs
 func (jm *JobManager) ListJobs() []*Job {
 	jm.mutex.RLock()
 	defer jm.mutex.RUnlock()
 
 	jobs := make([]*Job, 0, len(jm.jobs))
 	for _, job := range jm.jobs {
-		jobs = append(jobs, job)
+		jobs = append(jobs, job.snapshot())
 	}
 
 	return jobs
@@ -1,6 +1,11 @@
 package api
 
-import "time"
+import (
+	"time"
+
+	"threadbound/internal/attachments"
+	"threadbound/internal/models"
+)
 
 // JobStatus represents the status of a generation job
 type JobStatus string
@@ -14,9 +19,10 @@ const (
 
 // GenerateRequest represents a request to generate a book
 type GenerateRequest struct {
-	DatabasePath    string            `json:"database_path"`
+	DatabasePath    string            `json:"database_path,omitempty"`
+	UploadID        string            `json:"upload_id,omitempty"` // Use a database sent to /api/uploads instead of DatabasePath
 	AttachmentsPath string            `json:"attachments_path,omitempty"`
-	OutputPath      string            `json:"output_path,omitempty"`
+	OutputPath      string            `json:"output_path,omitempty"` // File name in the job's directory, or an allowed absolute path
 	Title           string            `json:"title,omitempty"`
 	Author          string            `json:"author,omitempty"`
 	PageWidth       string            `json:"page_width,omitempty"`
@@ -24,6 +30,31 @@ type GenerateRequest struct {
 	IncludeImages   bool              `json:"include_images"`
 	ContactNames    map[string]string `json:"contact_names,omitempty"`
 	MyName          string            `json:"my_name,omitempty"`
+	Filter          string            `json:"filter,omitempty"`   // Filter query, as for --filter
+	ChatID          int               `json:"chat_id,omitempty"`  // Only include this conversation, by chat ROWID
+	Timezone        string            `json:"timezone,omitempty"` // Zone messages are dated in, such as America/New_York (default: the server's)
+	Locale          string            `json:"locale,omitempty"`   // Language of headings, dates and "Me": en (default), de, es or fr
+	Formats         []string          `json:"formats,omitempty"`  // tex, pdf, html and/or txt, each written beside output_path; empty writes output_path alone
+
+	RedactProfile   string                        `json:"redact_profile,omitempty"`   // contacts, strict, or one of RedactProfiles
+	RedactProfiles  map[string]models.RedactRules `json:"redact_profiles,omitempty"`  // Custom profiles, overriding built-ins by name
+	ExcludeContacts []string                      `json:"exclude_contacts,omitempty"` // Drop messages from these contact IDs or display names
+	ExcludeKeywords []string                      `json:"exclude_keywords,omitempty"` // Drop messages containing any of these phrases
+	FilterSpam      bool                          `json:"filter_spam,omitempty"`      // Drop short-code senders and one-time passcodes
+	Dedupe          bool                          `json:"dedupe,omitempty"`           // Collapse identical consecutive messages from one sender
+
+	Theme        models.ThemeConfig      `json:"theme"`                   // Fonts and message bubble style of the TeX book
+	PrintPreset  string                  `json:"print_preset,omitempty"`  // kdp, ingram or lulu: add bleed and service margins
+	PrintPages   int                     `json:"print_pages,omitempty"`   // Expected page count for the gutter (0 = estimate)
+	NotesPages   models.NotesPagesConfig `json:"notes_pages"`             // Blank or ruled pages for handwritten notes
+	ChapterStats bool                    `json:"chapter_stats,omitempty"` // End each chapter with a small stats box
+	StatsChapter bool                    `json:"stats_chapter,omitempty"` // Add a "By the Numbers" chapter at the end of the book
+}
+
+// UploadResponse identifies a database uploaded for a later generate request
+type UploadResponse struct {
+	UploadID string `json:"upload_id"`
+	Size     int64  `json:"size"`
 }
 
 // GenerateResponse represents the response to a generate request
@@ -36,14 +67,40 @@ type GenerateResponse struct {
 
 // JobStatusResponse represents the status of a job
 type JobStatusResponse struct {
-	JobID      string    `json:"job_id"`
-	Status     JobStatus `json:"status"`
-	Message    string    `json:"message,omitempty"`
-	Error      string    `json:"error,omitempty"`
-	OutputPath string    `json:"output_path,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	Stats      *JobStats `json:"stats,omitempty"`
+	JobID     string     `json:"job_id"`
+	Status    JobStatus  `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Artifacts []Artifact `json:"artifacts,omitempty"` // Files the job produced, once it completes
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Stats     *JobStats  `json:"stats,omitempty"`
+
+	// Set when the request asked for formats: how each went and the files it produced
+	Formats []FormatStatus `json:"formats,omitempty"`
+
+	// Set for PDF output, to size the cover. Spine widths are in inches, keyed by paper stock.
+	PageCount   int                `json:"page_count,omitempty"`
+	SpineWidths map[string]float64 `json:"spine_widths,omitempty"`
+
+	// Attachments missing from disk, unreadable or unsupported, once the job completes
+	AttachmentReport *attachments.Report `json:"attachment_report,omitempty"`
+}
+
+// Artifact is a file produced by a job, downloaded by ID from /api/artifacts/{artifact_id}
+type Artifact struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	URL  string `json:"url"` // Download path on this server
+}
+
+// FormatStatus is how one of the formats a job was asked for went
+type FormatStatus struct {
+	Format    string     `json:"format"`
+	Status    JobStatus  `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
 }
 
 // JobStats contains statistics about the generated book
@@ -54,6 +111,18 @@ type JobStats struct {
 	AttachmentCount int       `json:"attachment_count"`
 	StartDate       time.Time `json:"start_date,omitempty"`
 	EndDate         time.Time `json:"end_date,omitempty"`
+
+	ExcludedByContact int `json:"excluded_by_contact,omitempty"`
+	ExcludedByKeyword int `json:"excluded_by_keyword,omitempty"`
+	ExcludedAsSpam    int `json:"excluded_as_spam,omitempty"`
+}
+
+// JobLogsResponse holds the most recent lines of a job's output
+type JobLogsResponse struct {
+	JobID      string    `json:"job_id"`
+	Status     JobStatus `json:"status"`
+	Lines      []string  `json:"lines"`
+	TotalLines int       `json:"total_lines"` // Lines written so far, including any not returned
 }
 
 // ErrorResponse represents an error response
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"threadbound/internal/attachments"
+	"threadbound/internal/models"
+)
+
+// openAPIDocument is the part of the spec the tests check
+type openAPIDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	handler := NewHandler()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/api/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var spec openAPIDocument
+	if err := json.NewDecoder(w.Body).Decode(&spec); err != nil {
+		t.Fatalf("Spec is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+
+	// Every route the server registers is described
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			if _, exists := spec.Paths[path][strings.ToLower(method)]; !exists {
+				t.Errorf("Route %s %s is missing from the spec", method, path)
+			}
+		}
+		return nil
+	})
+
+	// Every $ref names a schema
+	for _, ref := range specRefs(string(openAPISpec)) {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if _, exists := spec.Components.Schemas[name]; !exists {
+			t.Errorf("Spec refers to undefined schema %s", ref)
+		}
+	}
+
+	// Schemas have the same fields as the types the server encodes
+	types := map[string]interface{}{
+		"GenerateRequest":       GenerateRequest{},
+		"UploadResponse":        UploadResponse{},
+		"GenerateResponse":      GenerateResponse{},
+		"JobStatusResponse":     JobStatusResponse{},
+		"Artifact":              Artifact{},
+		"FormatStatus":          FormatStatus{},
+		"JobStats":              JobStats{},
+		"JobLogsResponse":       JobLogsResponse{},
+		"ErrorResponse":         ErrorResponse{},
+		"RedactRules":           models.RedactRules{},
+		"Theme":                 models.ThemeConfig{},
+		"NotesPages":            models.NotesPagesConfig{},
+		"AttachmentReport":      attachments.Report{},
+		"AttachmentReportEntry": attachments.ReportEntry{},
+	}
+	for name, value := range types {
+		schema, exists := spec.Components.Schemas[name]
+		if !exists {
+			t.Errorf("Schema %s is missing from the spec", name)
+			continue
+		}
+		var documented []string
+		for property := range schema.Properties {
+			documented = append(documented, property)
+		}
+		sort.Strings(documented)
+		if fields := jsonFields(reflect.TypeOf(value)); !reflect.DeepEqual(fields, documented) {
+			t.Errorf("Schema %s has properties %v, but the type encodes %v", name, documented, fields)
+		}
+	}
+}
+
+// specRefs returns every $ref in the spec's text
+func specRefs(text string) []string {
+	var refs []string
+	for _, part := range strings.Split(text, `"$ref": "`)[1:] {
+		refs = append(refs, part[:strings.Index(part, `"`)])
+	}
+	return refs
+}
+
+// jsonFields returns the JSON names of a struct's fields, sorted
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// QueueFile holds jobs left unfinished at shutdown, in the server's workspace
+const QueueFile = "queued-jobs.json"
+
+// queuedJob is an unfinished job saved so the next server can run it again
+type queuedJob struct {
+	ID        string             `json:"id"`
+	Config    *models.BookConfig `json:"config"`
+	Formats   []string           `json:"formats,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// SaveQueue writes jobs to path so RestoreQueue can resubmit them
+func SaveQueue(path string, jobs []*Job) error {
+	queued := make([]queuedJob, 0, len(jobs))
+	for _, job := range jobs {
+		queued = append(queued, queuedJob{ID: job.ID, Config: job.Config, Formats: job.Formats, CreatedAt: job.CreatedAt})
+	}
+
+	data, err := json.MarshalIndent(queued, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode queued jobs: %w", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// RestoreQueue resubmits the jobs saved at path under their original IDs and removes
+// the file. A missing file restores nothing. The jobs were accepted before, so they're
+// queued even past the depth limit.
+func (jm *JobManager) RestoreQueue(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var queued []queuedJob
+	if err := json.Unmarshal(data, &queued); err != nil {
+		return 0, fmt.Errorf("failed to read queued jobs from %s: %w", path, err)
+	}
+	for _, job := range queued {
+		if _, err := jm.startJob(job.ID, job.Config, job.Formats, job.CreatedAt, false); err != nil {
+			return 0, err
+		}
+	}
+	return len(queued), os.Remove(path)
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"threadbound/internal/models"
+)
+
+func TestDrainRejectsNewJobs(t *testing.T) {
+	handler := NewHandlerWithOptions(Options{Workspace: t.TempDir()})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	// A job on a missing database fails quickly, so draining waits for it and finds nothing left
+	if _, err := handler.jobManager.CreateJob(&models.BookConfig{DatabasePath: "/nonexistent/chat.db"}); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if unfinished := handler.jobManager.Drain(ctx); len(unfinished) != 0 {
+		t.Errorf("Expected no unfinished jobs, got %d", len(unfinished))
+	}
+
+	if _, err := handler.jobManager.CreateJob(&models.BookConfig{}); !errors.Is(err, ErrDraining) {
+		t.Errorf("Expected ErrDraining, got %v", err)
+	}
+
+	body, _ := json.Marshal(GenerateRequest{DatabasePath: "/path/to/test.db"})
+	req := httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/health", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var health map[string]string
+	json.NewDecoder(w.Body).Decode(&health)
+	if health["status"] != "draining" {
+		t.Errorf("Expected health status 'draining', got %q", health["status"])
+	}
+}
+
+func TestSaveAndRestoreQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), QueueFile)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	jobs := []*Job{{
+		ID:        "queued-job",
+		Status:    JobStatusPending,
+		Config:    &models.BookConfig{DatabasePath: "/nonexistent/chat.db", Title: "Saved"},
+		CreatedAt: created,
+	}}
+	if err := SaveQueue(path, jobs); err != nil {
+		t.Fatalf("SaveQueue failed: %v", err)
+	}
+
+	jm := NewJobManager()
+	restored, err := jm.RestoreQueue(path)
+	if err != nil {
+		t.Fatalf("RestoreQueue failed: %v", err)
+	}
+	if restored != 1 {
+		t.Fatalf("Expected 1 restored job, got %d", restored)
+	}
+
+	job, err := jm.GetJob("queued-job")
+	if err != nil {
+		t.Fatalf("Restored job not found: %v", err)
+	}
+	if job.Config.Title != "Saved" || !job.CreatedAt.Equal(created) {
+		t.Errorf("Restored job lost its config or creation time: %+v", job)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected queue file removed after restoring")
+	}
+
+	if restored, err := jm.RestoreQueue(path); err != nil || restored != 0 {
+		t.Errorf("Expected nothing to restore from a missing file, got %d (err %v)", restored, err)
+	}
+}
+
+func TestQueueDepthLimit(t *testing.T) {
+	handler := NewHandlerWithOptions(Options{Workspace: t.TempDir(), MaxConcurrentJobs: 1, MaxQueuedJobs: 1})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	jm := handler.jobManager
+
+	// Take the only slot, as a long XeLaTeX run would, so new jobs have to wait
+	jm.slots <- struct{}{}
+
+	first, err := jm.CreateJob(&models.BookConfig{DatabasePath: "/nonexistent/chat.db"})
+	if err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if _, err := jm.CreateJob(&models.BookConfig{DatabasePath: "/nonexistent/chat.db"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	body, _ := json.Marshal(GenerateRequest{DatabasePath: "/path/to/test.db"})
+	req := httptest.NewRequest("POST", "/api/generate", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 with the queue full, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+	if entries, _ := os.ReadDir(handler.options.Workspace); len(entries) != 0 {
+		t.Errorf("Expected no job directory for a refused job, found %d entries", len(entries))
+	}
+
+	job, _ := jm.GetJob(first)
+	if job.Status != JobStatusPending {
+		t.Errorf("Expected the queued job to wait for a slot, got %s", job.Status)
+	}
+
+	// Queued jobs aren't started once draining begins, so they're saved for the next start
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	unfinished := jm.Drain(ctx)
+	if len(unfinished) != 1 || unfinished[0].ID != first {
+		t.Errorf("Expected the queued job left unfinished, got %d jobs", len(unfinished))
+	}
+}
+
+func TestQueuePosition(t *testing.T) {
+	jm := NewJobManagerWithLimits(1, 10)
+	jm.slots <- struct{}{}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := jm.CreateJob(&models.BookConfig{DatabasePath: "/nonexistent/chat.db"})
+		if err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	for i, id := range ids {
+		if ahead := jm.QueuePosition(id); ahead != i {
+			t.Errorf("Expected job %d to have %d jobs ahead, got %d", i, i, ahead)
+		}
+	}
+
+	// Freeing the slot lets the queue run; the jobs fail on the missing database
+	<-jm.slots
+	deadline := time.Now().Add(5 * time.Second)
+	for _, id := range ids {
+		for {
+			job, _ := jm.GetJob(id)
+			jm.mutex.RLock()
+			status := job.Status
+			jm.mutex.RUnlock()
+			if status == JobStatusFailed {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected job %s to run once the slot was free, still %s", id, status)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
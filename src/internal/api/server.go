@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -19,9 +22,9 @@ type Server struct {
 }
 
 // NewServer creates a new API server
-func NewServer(port int) *Server {
+func NewServer(port int, options Options) *Server {
 	router := mux.NewRouter()
-	handler := NewHandler()
+	handler := NewHandlerWithOptions(options)
 
 	// Register routes
 	handler.RegisterRoutes(router)
@@ -65,17 +68,54 @@ func (s *Server) Start() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	fmt.Printf("🚀 API server starting on port %d\n", s.port)
-	fmt.Printf("📡 Endpoints:\n")
-	fmt.Printf("   POST   http://localhost:%d/api/generate\n", s.port)
-	fmt.Printf("   GET    http://localhost:%d/api/jobs/{job_id}\n", s.port)
-	fmt.Printf("   GET    http://localhost:%d/api/jobs\n", s.port)
-	fmt.Printf("   GET    http://localhost:%d/api/health\n", s.port)
-	fmt.Println()
+	log := s.handler.options.Logger
+	base := fmt.Sprintf("http://localhost:%d", s.port)
+	log.Info("🚀 API server starting", "port", s.port, "workspace", s.handler.options.Workspace)
+	for _, endpoint := range []string{
+		"POST /api/uploads",
+		"POST /api/generate",
+		"GET /api/jobs/{job_id}",
+		"GET /api/jobs/{job_id}/output",
+		"GET /api/jobs/{job_id}/logs",
+		"GET /api/artifacts/{artifact_id}",
+		"GET /api/jobs",
+		"GET /api/health",
+		"GET /api/openapi.json",
+	} {
+		method, path, _ := strings.Cut(endpoint, " ")
+		log.Info("📡 Endpoint", "method", method, "url", base+path)
+	}
+
+	// Pick up jobs the last server left unfinished when it shut down
+	queuePath := filepath.Join(s.handler.options.Workspace, QueueFile)
+	if restored, err := s.handler.jobManager.RestoreQueue(queuePath); err != nil {
+		log.Warn("⚠️  Could not restore queued jobs", "error", err)
+	} else if restored > 0 {
+		log.Info("♻️  Resumed jobs queued before the last shutdown", "count", restored)
+	}
 
 	return s.httpServer.ListenAndServe()
 }
 
+// Drain stops accepting jobs while still answering status and download requests, waits
+// for running jobs until ctx ends, and saves any left unfinished so the next start resumes them
+func (s *Server) Drain(ctx context.Context) error {
+	unfinished := s.handler.jobManager.Drain(ctx)
+	if len(unfinished) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(s.handler.options.Workspace, 0755); err != nil {
+		return err
+	}
+	queuePath := filepath.Join(s.handler.options.Workspace, QueueFile)
+	if err := SaveQueue(queuePath, unfinished); err != nil {
+		return fmt.Errorf("failed to save queued jobs: %w", err)
+	}
+	s.handler.options.Logger.Info("💾 Saved unfinished jobs", "count", len(unfinished), "path", queuePath)
+	return nil
+}
+
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	if s.httpServer != nil {
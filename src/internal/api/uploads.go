@@ -0,0 +1,94 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// upload is a database sent to /api/uploads. It's kept until the jobs generating from it
+// finish, or until it expires when no job uses it.
+type upload struct {
+	dir     string
+	expires time.Time
+	jobs    int // Jobs generating from it that haven't finished
+}
+
+// addUpload registers a stored database under its upload ID
+func (h *Handler) addUpload(id, dir string) {
+	h.uploadsMutex.Lock()
+	defer h.uploadsMutex.Unlock()
+	h.uploads[id] = &upload{dir: dir, expires: time.Now().Add(h.options.UploadTTL)}
+}
+
+// holdUpload marks an upload as used by one more job, so it isn't removed while the job
+// reads it. It returns the upload's directory, or false when there's no such upload.
+func (h *Handler) holdUpload(id string) (string, bool) {
+	h.uploadsMutex.Lock()
+	defer h.uploadsMutex.Unlock()
+
+	u, exists := h.uploads[id]
+	if !exists {
+		return "", false
+	}
+	u.jobs++
+	return u.dir, true
+}
+
+// unholdUpload undoes holdUpload for a job that couldn't be created, leaving the upload
+// for another request
+func (h *Handler) unholdUpload(id string) {
+	h.uploadsMutex.Lock()
+	defer h.uploadsMutex.Unlock()
+
+	if u, exists := h.uploads[id]; exists && u.jobs > 0 {
+		u.jobs--
+	}
+}
+
+// jobFinished removes the upload a finished job generated from once no other job uses it.
+// Jobs resumed from a saved queue aren't in the upload list, so their upload directory is
+// removed directly.
+func (h *Handler) jobFinished(job *Job) {
+	if job.Config == nil {
+		return
+	}
+	dir := filepath.Dir(job.Config.DatabasePath)
+	if !h.ownsDir(dir, "upload") {
+		return
+	}
+
+	h.uploadsMutex.Lock()
+	for id, u := range h.uploads {
+		if u.dir != dir {
+			continue
+		}
+		if u.jobs--; u.jobs > 0 {
+			h.uploadsMutex.Unlock()
+			return
+		}
+		delete(h.uploads, id)
+	}
+	h.uploadsMutex.Unlock()
+
+	os.RemoveAll(dir)
+}
+
+// expireUploads removes the uploads no job has used before they expired
+func (h *Handler) expireUploads() {
+	now := time.Now()
+	var expired []string
+
+	h.uploadsMutex.Lock()
+	for id, u := range h.uploads {
+		if u.jobs == 0 && now.After(u.expires) {
+			expired = append(expired, u.dir)
+			delete(h.uploads, id)
+		}
+	}
+	h.uploadsMutex.Unlock()
+
+	for _, dir := range expired {
+		os.RemoveAll(dir)
+	}
+}
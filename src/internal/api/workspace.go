@@ -0,0 +1,159 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"threadbound/internal/manifest"
+)
+
+// Options configures where the API server keeps and writes files
+type Options struct {
+	// Workspace holds per-job output directories and uploaded databases
+	// (default: a threadbound folder in the system temp directory)
+	Workspace string
+	// AllowedOutputDirs are the only directories a request may name an explicit output
+	// path in. With none, every book is written to its job's own directory.
+	AllowedOutputDirs []string
+	// MaxConcurrentJobs is how many jobs generate at once (default: 2). Others wait their
+	// turn in the queue.
+	MaxConcurrentJobs int
+	// MaxQueuedJobs is how many jobs may wait for a turn before generate requests are
+	// turned away with 429 Too Many Requests (default: 20)
+	MaxQueuedJobs int
+	// MaxUploadSize is the largest database /api/uploads accepts, in bytes (default: 4 GiB)
+	MaxUploadSize int64
+	// UploadTTL is how long an upload no job has used is kept (default: 1 hour). Uploads a
+	// job uses are removed when the job finishes.
+	UploadTTL time.Duration
+	// Logger receives the startup banner and queue messages (default: slog.Default())
+	Logger *slog.Logger
+}
+
+// Default job limits, so a burst of requests can't start dozens of XeLaTeX runs at once
+const (
+	DefaultMaxConcurrentJobs = 2
+	DefaultMaxQueuedJobs     = 20
+)
+
+// Default upload limits, so uploads can't fill the disk or hold a connection forever
+const (
+	DefaultMaxUploadSize = 4 << 30
+	DefaultUploadTTL     = time.Hour
+	uploadReadTimeout    = 30 * time.Minute
+)
+
+// withDefaults fills in the workspace, job and upload limits and makes allowed directories absolute
+func (o Options) withDefaults() Options {
+	if o.Workspace == "" {
+		o.Workspace = filepath.Join(os.TempDir(), "threadbound")
+	}
+	if o.MaxConcurrentJobs <= 0 {
+		o.MaxConcurrentJobs = DefaultMaxConcurrentJobs
+	}
+	if o.MaxQueuedJobs <= 0 {
+		o.MaxQueuedJobs = DefaultMaxQueuedJobs
+	}
+	if o.MaxUploadSize <= 0 {
+		o.MaxUploadSize = DefaultMaxUploadSize
+	}
+	if o.UploadTTL <= 0 {
+		o.UploadTTL = DefaultUploadTTL
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+
+	allowed := make([]string, 0, len(o.AllowedOutputDirs))
+	for _, dir := range o.AllowedOutputDirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			allowed = append(allowed, abs)
+		}
+	}
+	o.AllowedOutputDirs = allowed
+	return o
+}
+
+// errOutputNotAllowed is returned for explicit output paths outside the allowlist
+var errOutputNotAllowed = errors.New("output_path must be a file name, or an absolute path inside a directory the server allows")
+
+// workspaceDir creates a new directory in the workspace, named with prefix
+func (h *Handler) workspaceDir(prefix string) (string, error) {
+	if err := os.MkdirAll(h.options.Workspace, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(h.options.Workspace, prefix+"-*")
+}
+
+// ownsDir reports whether dir is one workspaceDir created with prefix
+func (h *Handler) ownsDir(dir, prefix string) bool {
+	return filepath.Dir(dir) == filepath.Clean(h.options.Workspace) && strings.HasPrefix(filepath.Base(dir), prefix+"-")
+}
+
+// resolveOutputPath decides where a job writes its book. A bare file name is placed in a
+// new directory for the job; anything else must be an absolute path inside an allowed
+// directory.
+func (h *Handler) resolveOutputPath(requested string) (string, error) {
+	if requested == "" {
+		requested = "book.tex"
+	}
+
+	if !strings.ContainsAny(requested, `/\`) && requested != "." && requested != ".." {
+		dir, err := h.workspaceDir("job")
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, requested), nil
+	}
+
+	if !filepath.IsAbs(requested) {
+		return "", errOutputNotAllowed
+	}
+	path := filepath.Clean(requested)
+	for _, dir := range h.options.AllowedOutputDirs {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path, nil
+		}
+	}
+	return "", errOutputNotAllowed
+}
+
+// artifact is a file a job produced, downloadable by ID without revealing its path
+type artifact struct {
+	Artifact
+	path string
+}
+
+// collectArtifacts registers the book and its manifest, when present, under new IDs
+func collectArtifacts(outputPath string) []artifact {
+	return artifactsFor([]string{outputPath, manifest.PathFor(outputPath)})
+}
+
+// artifactsFor registers the files at paths that exist under new IDs
+func artifactsFor(paths []string) []artifact {
+	var artifacts []artifact
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		id := uuid.New().String()
+		artifacts = append(artifacts, artifact{
+			Artifact: Artifact{
+				ID:   id,
+				Name: filepath.Base(path),
+				Size: info.Size(),
+				URL:  "/api/artifacts/" + id,
+			},
+			path: path,
+		})
+	}
+	return artifacts
+}
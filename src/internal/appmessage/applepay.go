@@ -0,0 +1,43 @@
+package appmessage
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ApplePayBundleID is Apple Cash, which sends and requests money in Messages
+const ApplePayBundleID = "com.apple.PassbookUIService.PeerPaymentMessagesExtension"
+
+// amountPattern finds an amount of money such as "$25", "€12,50" or "10.00 USD"
+var amountPattern = regexp.MustCompile(`[$€£¥]\s?\d[\d,.]*|\d[\d,.]*\s?(?:USD|EUR|GBP|CAD|AUD)\b`)
+
+// applePayDecoder summarizes Apple Cash payments and requests with their amount
+type applePayDecoder struct{}
+
+func (applePayDecoder) Matches(appBundleID string) bool {
+	return appBundleID == ApplePayBundleID
+}
+
+func (applePayDecoder) Summarize(payload *Payload) (string, error) {
+	kind := "payment"
+	amount := payload.Query("amount", "amt")
+	if currency := payload.Query("currency"); amount != "" && currency != "" {
+		amount += " " + currency
+	}
+	for _, text := range []string{payload.LayoutText, payload.Caption, payload.Subcaption} {
+		if strings.Contains(strings.ToLower(text), "request") {
+			kind = "request"
+		}
+		if amount == "" {
+			amount = amountPattern.FindString(text)
+		}
+	}
+
+	if amount == "" {
+		return "💵 Apple Cash " + kind, nil
+	}
+	if kind == "request" {
+		return "💵 Apple Cash request for " + amount, nil
+	}
+	return "💵 Apple Cash payment of " + amount, nil
+}
@@ -0,0 +1,134 @@
+// Package appmessage turns messages sent from iMessage apps, such as polls, Check In and
+// Apple Cash, into readable summaries. Each kind of app is handled by a Decoder; messages no
+// decoder understands get a generic placeholder naming the app.
+package appmessage
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"threadbound/internal/plist"
+)
+
+// ExtensionBundlePrefix starts the balloon_bundle_id of every iMessage app message. The app's
+// own bundle ID follows after the developer team ID, separated by colons.
+const ExtensionBundlePrefix = "com.apple.messages.MSMessageExtensionBalloonPlugin"
+
+// Placeholder is shown for app messages whose payload can't be read at all
+const Placeholder = "🧩 iMessage app message"
+
+// Payload is the layout an app attached to its message, decoded from payload_data
+type Payload struct {
+	AppBundleID string
+	AppName     string
+	Caption     string
+	Subcaption  string
+	LayoutText  string   // Text drawn on the message bubble
+	URL         *url.URL // Where apps keep their state, usually in the query string
+}
+
+// Decoder summarizes the messages of one kind of app
+type Decoder interface {
+	// Matches reports whether the decoder understands messages from the app with this bundle ID
+	Matches(appBundleID string) bool
+	// Summarize describes the message in one line, or returns an error if the payload lacks
+	// what the decoder needs
+	Summarize(payload *Payload) (string, error)
+}
+
+// decoders are tried in registration order
+var decoders = []Decoder{checkInDecoder{}, applePayDecoder{}, pollDecoder{}}
+
+// Register adds a decoder, tried after the built-in ones
+func Register(decoder Decoder) {
+	decoders = append(decoders, decoder)
+}
+
+// AppBundleID returns the bundle ID of the app that sent a message, or false if the
+// balloon bundle ID isn't an iMessage app's
+func AppBundleID(balloonBundleID string) (string, bool) {
+	if !strings.HasPrefix(balloonBundleID, ExtensionBundlePrefix+":") {
+		return "", false
+	}
+	parts := strings.Split(balloonBundleID, ":")
+	return parts[len(parts)-1], true
+}
+
+// Summarize describes an app message using the first decoder that matches its app, falling
+// back to a placeholder naming the app
+func Summarize(appBundleID string, data []byte) string {
+	payload, err := Decode(appBundleID, data)
+	if err != nil {
+		return Placeholder
+	}
+
+	for _, decoder := range decoders {
+		if !decoder.Matches(appBundleID) {
+			continue
+		}
+		if summary, err := decoder.Summarize(payload); err == nil && summary != "" {
+			return summary
+		}
+	}
+	return placeholder(payload)
+}
+
+// Decode reads the layout fields out of an app message's payload_data
+func Decode(appBundleID string, data []byte) (*Payload, error) {
+	archive, err := plist.DecodeKeyedArchive(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode app message payload: %w", err)
+	}
+
+	payload := &Payload{
+		AppBundleID: appBundleID,
+		AppName:     archive.FindString("an"),
+		Caption:     archive.FindString("caption"),
+		Subcaption:  archive.FindString("subcaption"),
+		LayoutText:  archive.FindString("ldtext"),
+	}
+	// NSURL archives its string under NS.relative
+	if raw := archive.FindString("NS.relative"); raw != "" {
+		if parsed, err := url.Parse(raw); err == nil {
+			payload.URL = parsed
+		}
+	}
+	return payload, nil
+}
+
+// Text returns the most descriptive text the app put on the bubble
+func (p *Payload) Text() string {
+	for _, text := range []string{p.LayoutText, p.Caption, p.Subcaption} {
+		if text = strings.TrimSpace(text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// Query returns the first non-empty value of any of the keys in the payload URL's query
+func (p *Payload) Query(keys ...string) string {
+	if p.URL == nil {
+		return ""
+	}
+	query := p.URL.Query()
+	for _, key := range keys {
+		if value := strings.TrimSpace(query.Get(key)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// placeholder names the app and repeats its bubble text when there is any
+func placeholder(payload *Payload) string {
+	name := payload.AppName
+	if name == "" {
+		name = "iMessage app"
+	}
+	if text := payload.Text(); text != "" {
+		return fmt.Sprintf("🧩 %s: %s", name, text)
+	}
+	return fmt.Sprintf("🧩 %s message", name)
+}
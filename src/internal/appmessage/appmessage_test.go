@@ -0,0 +1,92 @@
+package appmessage
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Keyed archives in the shape of iMessage app payloads, generated with Python's plistlib
+
+// pollArchive has app name "Polls", caption "Vote now" and the question and options in its URL
+const pollArchive = "" +
+	"62706c6973743030d4010203040506171a5924617263686976657258246f626a656374735424746f7058247665727369" +
+	"6f6e5f100f4e534b657965644172636869766572a607080f10111655246e756c6cd3090a0b0c0d0e5355524c52616e57" +
+	"63617074696f6e80048002800355506f6c6c7358566f7465206e6f77d212131415574e532e626173655b4e532e72656c" +
+	"6174697665800080055f103a646174613a3f7175657374696f6e3d44696e6e65722532307768657265253346266f7074" +
+	"696f6e733d5461636f732c53757368692c50697a7a61d1181954726f6f74800112000186a008111b242932444b51585c" +
+	"5f67696b6d737c8189959799d6d9dee00000000000000101000000000000001b000000000000000000000000000000e5"
+
+// checkInArchive has caption "Check In" and subcaption "Arrived"
+const checkInArchive = "" +
+	"62706c6973743030d4010203040506171a5924617263686976657258246f626a656374735424746f7058247665727369" +
+	"6f6e5f100f4e534b657965644172636869766572a607080f10111655246e756c6cd3090a0b0c0d0e5355524c57636170" +
+	"74696f6e5a73756263617074696f6e80048002800358436865636b20496e5741727269766564d212131415574e532e62" +
+	"6173655b4e532e72656c6174697665800080055f1013646174613a3f6d657373616765547970653d32d1181954726f6f" +
+	"74800112000186a008111b242932444b51585c646f7173757e868b939fa1a3b9bcc1c300000000000001010000000000" +
+	"00001b000000000000000000000000000000c8"
+
+// stickerArchive has app name "Sticker Maker" and bubble text "Sent a sticker"
+const stickerArchive = "" +
+	"62706c6973743030d40102030405060f125924617263686976657258246f626a656374735424746f7058247665727369" +
+	"6f6e5f100f4e534b657965644172636869766572a407080d0e55246e756c6cd2090a0b0c52616e566c64746578748002" +
+	"80035d537469636b6572204d616b65725e53656e74206120737469636b6572d1101154726f6f74800112000186a00811" +
+	"1b24293244494f54575e6062707f82878900000000000001010000000000000013000000000000000000000000000000" +
+	"8e"
+
+// cashRequestArchive has caption "Apple Cash" and bubble text "$25.00 Request"
+const cashRequestArchive = "" +
+	"62706c6973743030d40102030405060f125924617263686976657258246f626a656374735424746f7058247665727369" +
+	"6f6e5f100f4e534b657965644172636869766572a407080d0e55246e756c6cd2090a0b0c5763617074696f6e566c6474" +
+	"657874800380025e2432352e303020526571756573745a4170706c652043617368d1101154726f6f74800112000186a0" +
+	"08111b24293244494f545c636567768184898b00000000000001010000000000000013000000000000000000000000000000" +
+	"90"
+
+// cashPaymentArchive has caption "Apple Cash" and bubble text "€12,50"
+const cashPaymentArchive = "" +
+	"62706c6973743030d40102030405060f125924617263686976657258246f626a656374735424746f7058247665727369" +
+	"6f6e5f100f4e534b657965644172636869766572a407080d0e55246e756c6cd2090a0b0c5763617074696f6e566c6474" +
+	"657874800280035a4170706c6520436173686620ac00310032002c00350030d1101154726f6f74800112000186a00811" +
+	"1b24293244494f545c636567727f82878900000000000001010000000000000013000000000000000000000000000000" +
+	"8e"
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name        string
+		appBundleID string
+		archive     string
+		want        string
+	}{
+		{"poll", "com.example.QuickPolls.MessagesExtension", pollArchive, "📊 Poll: Dinner where? (Tacos · Sushi · Pizza)"},
+		{"check in", CheckInBundleID, checkInArchive, "📍 Check In: Arrived"},
+		{"apple cash request", ApplePayBundleID, cashRequestArchive, "💵 Apple Cash request for $25.00"},
+		{"apple cash payment", ApplePayBundleID, cashPaymentArchive, "💵 Apple Cash payment of €12,50"},
+		{"unknown app", "com.example.StickerMaker", stickerArchive, "🧩 Sticker Maker: Sent a sticker"},
+		{"poll decoder skipped for other apps", "com.example.StickerMaker", pollArchive, "🧩 Polls: Vote now"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.archive)
+			if err != nil {
+				t.Fatalf("Invalid fixture: %v", err)
+			}
+			if got := Summarize(tt.appBundleID, data); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if got := Summarize("com.example.Anything", []byte("not a plist")); got != Placeholder {
+		t.Errorf("Expected placeholder for unreadable payload, got %q", got)
+	}
+}
+
+func TestAppBundleID(t *testing.T) {
+	id, ok := AppBundleID("com.apple.messages.MSMessageExtensionBalloonPlugin:0000000000:" + CheckInBundleID)
+	if !ok || id != CheckInBundleID {
+		t.Errorf("Expected %s, got %q (ok: %v)", CheckInBundleID, id, ok)
+	}
+	if _, ok := AppBundleID("com.apple.messages.URLBalloonProvider"); ok {
+		t.Error("Expected link previews not to count as app messages")
+	}
+}
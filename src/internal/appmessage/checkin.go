@@ -0,0 +1,24 @@
+package appmessage
+
+import "strings"
+
+// CheckInBundleID is Apple's Check In, which tells friends when you arrive safely
+const CheckInBundleID = "com.apple.SafetyMonitorApp.SafetyMonitorMessages"
+
+// checkInDecoder summarizes Check In messages with their status, such as "Arrived"
+type checkInDecoder struct{}
+
+func (checkInDecoder) Matches(appBundleID string) bool {
+	return appBundleID == CheckInBundleID
+}
+
+func (checkInDecoder) Summarize(payload *Payload) (string, error) {
+	// The caption is usually just "Check In"; the status is in the other fields
+	for _, status := range []string{payload.Subcaption, payload.LayoutText, payload.Caption} {
+		status = strings.TrimSpace(status)
+		if status != "" && !strings.EqualFold(status, "Check In") {
+			return "📍 Check In: " + status, nil
+		}
+	}
+	return "📍 Check In", nil
+}
@@ -0,0 +1,55 @@
+package appmessage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pollDecoder summarizes polls from apps that keep the question and options in the
+// message URL, as most poll apps do so every participant sees the same poll
+type pollDecoder struct{}
+
+func (pollDecoder) Matches(appBundleID string) bool {
+	return strings.Contains(strings.ToLower(appBundleID), "poll")
+}
+
+func (pollDecoder) Summarize(payload *Payload) (string, error) {
+	question := payload.Query("question", "q", "title")
+	if question == "" {
+		question = payload.Text()
+	}
+	if question == "" {
+		return "", fmt.Errorf("poll has no question")
+	}
+
+	options := pollOptions(payload)
+	if len(options) == 0 {
+		return "📊 Poll: " + question, nil
+	}
+	return fmt.Sprintf("📊 Poll: %s (%s)", question, strings.Join(options, " · ")), nil
+}
+
+// pollOptions reads the options from repeated query keys or one comma- or pipe-separated value
+func pollOptions(payload *Payload) []string {
+	if payload.URL == nil {
+		return nil
+	}
+	query := payload.URL.Query()
+	for _, key := range []string{"options", "option", "choices", "answers"} {
+		values := query[key]
+		if len(values) == 1 {
+			values = strings.FieldsFunc(values[0], func(r rune) bool { return r == ',' || r == '|' })
+		}
+
+		var options []string
+		for _, value := range values {
+			if value = strings.TrimSpace(value); value != "" {
+				options = append(options, value)
+			}
+		}
+		if len(options) > 0 {
+			return options
+		}
+	}
+	return nil
+}
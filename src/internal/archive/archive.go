@@ -0,0 +1,320 @@
+// Package archive creates lossless, checksummed exports of a conversation for long-term preservation
+package archive
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"threadbound/internal/attachments"
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// FormatVersion is bumped whenever the archive layout or export schema changes incompatibly
+const FormatVersion = 1
+
+// Paths inside an archive directory
+const (
+	ManifestFile   = "archive.json"
+	ChecksumsFile  = "SHA256SUMS"
+	DatabaseDir    = "database"
+	AttachmentsDir = "attachments"
+	ExportFile     = "export/messages.json"
+)
+
+// Manifest describes an archive and lists a checksum for every file in it
+type Manifest struct {
+	FormatVersion      int       `json:"format_version"`
+	CreatedAt          time.Time `json:"created_at"`
+	SourceDatabase     string    `json:"source_database"`
+	SourceAttachments  string    `json:"source_attachments"`
+	Messages           int       `json:"messages"`
+	Handles            int       `json:"handles"`
+	Attachments        int       `json:"attachments"`
+	MissingAttachments int       `json:"missing_attachments"`
+	Files              []File    `json:"files"`
+}
+
+// File is one archived file with its size and SHA-256 checksum
+type File struct {
+	Path   string `json:"path"` // Slash-separated and relative to the archive root
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// writer accumulates checksummed files while an archive is being built
+type writer struct {
+	root  string
+	files []File
+}
+
+// Create copies the database, original attachments and a JSON export of the conversation
+// into dir, which must not already contain files
+func Create(config *models.BookConfig, dir string) (*Manifest, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("archive directory %s is not empty", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	w := &writer{root: dir}
+	manifest := &Manifest{
+		FormatVersion:     FormatVersion,
+		CreatedAt:         time.Now(),
+		SourceDatabase:    config.DatabasePath,
+		SourceAttachments: config.AttachmentsPath,
+	}
+
+	// Archive a consistent snapshot of a live database so uncheckpointed writes are kept
+	config.Log().Info("🗄️  Copying database...")
+	dbSource := config.DatabasePath
+	if database.NeedsSnapshot(dbSource) {
+		snapshotPath, cleanup, err := database.Snapshot(dbSource)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		dbSource = snapshotPath
+	}
+	if err := w.copyFile(dbSource, DatabaseDir+"/"+filepath.Base(config.DatabasePath)); err != nil {
+		return nil, fmt.Errorf("failed to copy database: %w", err)
+	}
+
+	export, err := readExport(config, dbSource)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy every original attachment, recording where each one landed in the export
+	config.Log().Info("📎 Copying attachments...")
+	for i := range export.Messages {
+		for j := range export.Messages[i].Attachments {
+			att := &export.Messages[i].Attachments[j]
+			manifest.Attachments++
+
+			source := ""
+			if att.Filename != nil {
+				source, err = attachments.Locate(config.AttachmentsPath, att.GUID, *att.Filename)
+			}
+			if source == "" || err != nil {
+				att.Missing = true
+				manifest.MissingAttachments++
+				continue
+			}
+
+			target := AttachmentsDir + "/" + att.GUID + "/" + filepath.Base(source)
+			if err := w.copyFile(source, target); err != nil {
+				return nil, fmt.Errorf("failed to copy attachment %s: %w", att.GUID, err)
+			}
+			att.ArchivePath = target
+		}
+	}
+
+	config.Log().Info("📝 Writing export...")
+	if err := w.writeJSON(ExportFile, export); err != nil {
+		return nil, err
+	}
+
+	manifest.Messages = len(export.Messages)
+	manifest.Handles = len(export.Handles)
+	manifest.Files = w.files
+	if err := w.finish(manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// readExport loads the conversation from the database at dbPath into its export form
+func readExport(config *models.BookConfig, dbPath string) (*Export, error) {
+	db, err := database.New(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	messages, err := db.GetMessages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	handles, err := db.GetHandles(config.ContactNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get handles: %w", err)
+	}
+	reactions, err := db.GetReactions(handles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	}
+	attachmentsByMessage, err := db.GetAllAttachments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+	payloads, err := db.GetPayloadData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link metadata: %w", err)
+	}
+
+	export := &Export{
+		FormatVersion: FormatVersion,
+		ExportedAt:    time.Now(),
+		Handles:       exportHandles(handles),
+		Messages:      make([]ExportMessage, 0, len(messages)),
+	}
+
+	for _, msg := range messages {
+		exported := newExportMessage(msg)
+		for _, att := range attachmentsByMessage[msg.ID] {
+			exported.Attachments = append(exported.Attachments, newExportAttachment(att))
+		}
+		for _, reaction := range reactions[msg.GUID] {
+			exported.Reactions = append(exported.Reactions, ExportReaction{
+				Type:       reaction.Type,
+				SenderName: reaction.SenderName,
+				Timestamp:  reaction.Timestamp,
+				Emoji:      reaction.ReactionEmoji,
+			})
+		}
+		if payload, exists := payloads[msg.ID]; exists {
+			exported.Link = decodeLinkMetadata(payload)
+		}
+		export.Messages = append(export.Messages, exported)
+	}
+
+	return export, nil
+}
+
+// copyFile copies src to a path inside the archive, checksumming it on the way
+func (w *writer) copyFile(src, rel string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return w.write(rel, func(out io.Writer) error {
+		_, err := io.Copy(out, in)
+		return err
+	})
+}
+
+// writeJSON writes value as indented JSON inside the archive
+func (w *writer) writeJSON(rel string, value interface{}) error {
+	return w.write(rel, func(out io.Writer) error {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	})
+}
+
+// write creates a file inside the archive and records its size and checksum
+func (w *writer) write(rel string, fill func(io.Writer) error) error {
+	path := filepath.Join(w.root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(out, hash)}
+	if err := fill(counter); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+
+	w.files = append(w.files, File{Path: rel, Size: counter.n, SHA256: hex.EncodeToString(hash.Sum(nil))})
+	return nil
+}
+
+// finish writes the checksum list and the manifest
+func (w *writer) finish(manifest *Manifest) error {
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+
+	// SHA256SUMS uses the sha256sum format so archives can be checked without threadbound
+	var sums strings.Builder
+	for _, file := range manifest.Files {
+		fmt.Fprintf(&sums, "%s  %s\n", file.SHA256, file.Path)
+	}
+	if err := os.WriteFile(filepath.Join(w.root, ChecksumsFile), []byte(sums.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write checksums: %w", err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(w.root, ManifestFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write archive manifest: %w", err)
+	}
+	return nil
+}
+
+// LoadManifest reads the manifest of an archive directory
+func LoadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse archive manifest: %w", err)
+	}
+	if manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("archive format version %d is newer than supported version %d", manifest.FormatVersion, FormatVersion)
+	}
+	return &manifest, nil
+}
+
+// Verify recomputes the checksum of every file listed in an archive's manifest
+func Verify(dir string) error {
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range manifest.Files {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			return fmt.Errorf("archive file missing: %s", file.Path)
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, bufio.NewReader(f))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Path, err)
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+			return fmt.Errorf("checksum mismatch: %s", file.Path)
+		}
+	}
+	return nil
+}
+
+// countingWriter tracks how many bytes pass through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
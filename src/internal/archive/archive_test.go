@@ -0,0 +1,211 @@
+package archive
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/attachments"
+	"threadbound/internal/database"
+	"threadbound/internal/logging"
+	"threadbound/internal/models"
+)
+
+// testSchema is the subset of the iMessage chat.db schema the archive reads
+const testSchema = `
+	CREATE TABLE handle (ROWID INTEGER PRIMARY KEY, id TEXT, country TEXT, service TEXT);
+	CREATE TABLE message (
+		ROWID INTEGER PRIMARY KEY, guid TEXT, text TEXT, date INTEGER, date_read INTEGER,
+		date_delivered INTEGER, is_from_me INTEGER DEFAULT 0, is_delivered INTEGER DEFAULT 1,
+		is_read INTEGER DEFAULT 1, handle_id INTEGER, cache_has_attachments INTEGER DEFAULT 0,
+		subject TEXT, is_audio_message INTEGER DEFAULT 0, associated_message_guid TEXT,
+		associated_message_type INTEGER DEFAULT 0, item_type INTEGER DEFAULT 0, payload_data BLOB,
+		expressive_send_style_id TEXT, balloon_bundle_id TEXT
+	);
+	CREATE TABLE attachment (
+		ROWID INTEGER PRIMARY KEY, guid TEXT, filename TEXT, uti TEXT, mime_type TEXT,
+		total_bytes INTEGER DEFAULT 0, is_sticker INTEGER DEFAULT 0, is_outgoing INTEGER DEFAULT 0
+	);
+	CREATE TABLE message_attachment_join (message_id INTEGER, attachment_id INTEGER);
+`
+
+// newTestSource builds a chat.db and attachments folder with one found and one missing attachment
+func newTestSource(t *testing.T) *models.BookConfig {
+	t.Helper()
+	dir := t.TempDir()
+
+	attachmentsPath := filepath.Join(dir, "Attachments")
+	if err := os.MkdirAll(filepath.Join(attachmentsPath, "ab"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(attachmentsPath, "ab", "photo.jpg"), []byte("jpeg data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPath := filepath.Join(dir, "chat.db")
+	db, err := database.New(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.GetConnection().Exec(testSchema + `
+		INSERT INTO handle (ROWID, id, country, service) VALUES (1, '+15551234567', 'us', 'iMessage');
+		INSERT INTO message (ROWID, guid, text, date, handle_id, cache_has_attachments) VALUES
+			(1, 'm1', 'hello', 700000000000000000, 1, 0),
+			(2, 'm2', NULL, 700000060000000000, 1, 1);
+		INSERT INTO attachment (ROWID, guid, filename, mime_type) VALUES
+			(10, 'a10', '~/Library/Messages/Attachments/ab/photo.jpg', 'image/jpeg'),
+			(11, 'a11', '~/Library/Messages/Attachments/cd/gone.jpg', 'image/jpeg');
+		INSERT INTO message_attachment_join (message_id, attachment_id) VALUES (2, 10), (2, 11);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+
+	return &models.BookConfig{
+		DatabasePath:    dbPath,
+		AttachmentsPath: attachmentsPath,
+		ContactNames:    map[string]string{"+15551234567": "Alice"},
+	}
+}
+
+func TestCreate(t *testing.T) {
+	config := newTestSource(t)
+	dir := filepath.Join(t.TempDir(), "archive")
+
+	manifest, err := Create(config, dir)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if manifest.Messages != 2 || manifest.Handles != 1 {
+		t.Errorf("Expected 2 messages and 1 handle, got %d and %d", manifest.Messages, manifest.Handles)
+	}
+	if manifest.Attachments != 2 || manifest.MissingAttachments != 1 {
+		t.Errorf("Expected 2 attachments with 1 missing, got %d with %d missing", manifest.Attachments, manifest.MissingAttachments)
+	}
+
+	copied, err := os.ReadFile(filepath.Join(dir, AttachmentsDir, "a10", "photo.jpg"))
+	if err != nil || string(copied) != "jpeg data" {
+		t.Errorf("Expected original attachment to be copied, got %q (%v)", copied, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ExportFile)))
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatalf("Failed to parse export: %v", err)
+	}
+	if export.Handles[0].DisplayName != "Alice" {
+		t.Errorf("Expected handle display name Alice, got %q", export.Handles[0].DisplayName)
+	}
+	atts := export.Messages[1].Attachments
+	if len(atts) != 2 || atts[0].ArchivePath != "attachments/a10/photo.jpg" || !atts[1].Missing {
+		t.Errorf("Unexpected exported attachments: %+v", atts)
+	}
+
+	sums, err := os.ReadFile(filepath.Join(dir, ChecksumsFile))
+	if err != nil {
+		t.Fatalf("Failed to read checksums: %v", err)
+	}
+	if !strings.Contains(string(sums), "  database/chat.db\n") {
+		t.Errorf("Expected database checksum in %s, got:\n%s", ChecksumsFile, sums)
+	}
+
+	if err := Verify(dir); err != nil {
+		t.Errorf("Verify failed on a fresh archive: %v", err)
+	}
+}
+
+func TestCreateRefusesNonEmptyDir(t *testing.T) {
+	config := newTestSource(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Create(config, dir); err == nil {
+		t.Error("Expected an error when the archive directory is not empty")
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	config := newTestSource(t)
+	dir := filepath.Join(t.TempDir(), "archive")
+	if _, err := Create(config, dir); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, AttachmentsDir, "a10", "photo.jpg"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Verify(dir)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	source := newTestSource(t)
+	dir := filepath.Join(t.TempDir(), "archive")
+	if _, err := Create(source, dir); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Rebuilding must not depend on the original files
+	os.Remove(source.DatabasePath)
+	os.RemoveAll(source.AttachmentsPath)
+
+	workDir := t.TempDir()
+	config := &models.BookConfig{DatabasePath: "chat.db", AttachmentsPath: "Attachments", WorkDir: workDir,
+		Logger: logging.New(io.Discard, logging.Options{})}
+	cleanup, err := Open(dir, config)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer cleanup()
+
+	if config.AttachmentsPath != filepath.Join(dir, AttachmentsDir) {
+		t.Errorf("Expected attachments path inside the archive, got %s", config.AttachmentsPath)
+	}
+	if strings.HasPrefix(config.DatabasePath, dir) {
+		t.Errorf("Expected a working copy of the database outside the archive, got %s", config.DatabasePath)
+	}
+
+	db, err := database.New(config.DatabasePath)
+	if err != nil {
+		t.Fatalf("Failed to open archived database: %v", err)
+	}
+	messages, err := db.GetMessages()
+	db.Close()
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("Expected 2 messages from the archived database, got %d (%v)", len(messages), err)
+	}
+
+	path, err := attachments.Locate(config.AttachmentsPath, "a10", "~/Library/Messages/Attachments/ab/photo.jpg")
+	if err != nil {
+		t.Errorf("Expected archived attachment to be found by GUID: %v", err)
+	} else if filepath.Dir(path) != filepath.Join(dir, AttachmentsDir, "a10") {
+		t.Errorf("Unexpected attachment path %s", path)
+	}
+
+	// Files made from the attachments go to the run's own folder, not into the archive
+	if filepath.Dir(config.DerivedPath) != workDir {
+		t.Errorf("Expected converted attachments in a folder of the work dir, got %s", config.DerivedPath)
+	}
+	attachments.New(config)
+	if _, err := os.Stat(filepath.Join(config.DerivedPath, "processed")); err != nil {
+		t.Errorf("Expected the processed folder in %s: %v", config.DerivedPath, err)
+	}
+
+	if err := Verify(dir); err != nil {
+		t.Errorf("Archive changed while reading it: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"threadbound/internal/models"
+	"threadbound/internal/plist"
+)
+
+// Export is the JSON form of a conversation, complete enough to rebuild a book without chat.db
+type Export struct {
+	FormatVersion int             `json:"format_version"`
+	ExportedAt    time.Time       `json:"exported_at"`
+	Handles       []ExportHandle  `json:"handles"`
+	Messages      []ExportMessage `json:"messages"`
+}
+
+// ExportHandle is a contact as stored in the handle table plus its resolved display name
+type ExportHandle struct {
+	ID          int    `json:"id"`
+	Contact     string `json:"contact"`
+	Service     string `json:"service,omitempty"`
+	Country     string `json:"country,omitempty"`
+	DisplayName string `json:"display_name"`
+}
+
+// ExportMessage keeps every message column threadbound reads, plus decoded extras
+type ExportMessage struct {
+	ID                    int       `json:"id"`
+	GUID                  string    `json:"guid"`
+	Date                  time.Time `json:"date"`
+	AppleDate             int64     `json:"apple_date"` // Nanoseconds since 2001-01-01 UTC, as stored
+	DateRead              *int64    `json:"date_read,omitempty"`
+	DateDelivered         *int64    `json:"date_delivered,omitempty"`
+	Text                  *string   `json:"text,omitempty"`
+	Subject               *string   `json:"subject,omitempty"`
+	IsFromMe              bool      `json:"is_from_me"`
+	IsDelivered           bool      `json:"is_delivered"`
+	IsRead                bool      `json:"is_read"`
+	HandleID              *int      `json:"handle_id,omitempty"`
+	HasAttachments        bool      `json:"has_attachments"`
+	IsAudioMessage        bool      `json:"is_audio_message"`
+	AssociatedMessageGUID *string   `json:"associated_message_guid,omitempty"`
+	AssociatedMessageType int       `json:"associated_message_type,omitempty"`
+	ItemType              int       `json:"item_type,omitempty"`
+	ExpressiveSendStyleID *string   `json:"expressive_send_style_id,omitempty"`
+	BalloonBundleID       *string   `json:"balloon_bundle_id,omitempty"`
+	ReplyToGUID           *string   `json:"reply_to_guid,omitempty"`
+	ThreadOriginatorGUID  *string   `json:"thread_originator_guid,omitempty"`
+	ThreadOriginatorPart  *string   `json:"thread_originator_part,omitempty"`
+
+	Attachments []ExportAttachment `json:"attachments,omitempty"`
+	Reactions   []ExportReaction   `json:"reactions,omitempty"`
+	Link        *LinkMetadata      `json:"link,omitempty"`
+}
+
+// ExportAttachment describes an attachment and where its original file lives in the archive
+type ExportAttachment struct {
+	ID          int     `json:"id"`
+	GUID        string  `json:"guid"`
+	Filename    *string `json:"filename,omitempty"` // Original path recorded in chat.db
+	UTI         *string `json:"uti,omitempty"`
+	MimeType    *string `json:"mime_type,omitempty"`
+	TotalBytes  int64   `json:"total_bytes"`
+	IsSticker   bool    `json:"is_sticker"`
+	IsOutgoing  bool    `json:"is_outgoing"`
+	ArchivePath string  `json:"archive_path,omitempty"` // Relative to the archive root; empty when missing
+	Missing     bool    `json:"missing,omitempty"`
+}
+
+// ExportReaction is a tapback on a message
+type ExportReaction struct {
+	Type       int       `json:"type"`
+	SenderName string    `json:"sender_name"`
+	Timestamp  time.Time `json:"timestamp"`
+	Emoji      string    `json:"emoji"`
+}
+
+// LinkMetadata is the rich link preview decoded from a message's payload_data
+type LinkMetadata struct {
+	Title    string   `json:"title,omitempty"`
+	Summary  string   `json:"summary,omitempty"`
+	SiteName string   `json:"site_name,omitempty"`
+	URLs     []string `json:"urls,omitempty"`
+}
+
+// newExportMessage copies a message's stored fields into its export form
+func newExportMessage(msg models.Message) ExportMessage {
+	return ExportMessage{
+		ID:                    msg.ID,
+		GUID:                  msg.GUID,
+		Date:                  msg.FormattedDate,
+		AppleDate:             msg.Date,
+		DateRead:              msg.DateRead,
+		DateDelivered:         msg.DateDelivered,
+		Text:                  msg.Text,
+		Subject:               msg.Subject,
+		IsFromMe:              msg.IsFromMe,
+		IsDelivered:           msg.IsDelivered,
+		IsRead:                msg.IsRead,
+		HandleID:              msg.HandleID,
+		HasAttachments:        msg.HasAttachments,
+		IsAudioMessage:        msg.IsAudioMessage,
+		AssociatedMessageGUID: msg.AssociatedMessageGUID,
+		AssociatedMessageType: msg.AssociatedMessageType,
+		ItemType:              msg.ItemType,
+		ExpressiveSendStyleID: msg.ExpressiveSendStyleID,
+		BalloonBundleID:       msg.BalloonBundleID,
+		ReplyToGUID:           msg.ReplyToGUID,
+		ThreadOriginatorGUID:  msg.ThreadOriginatorGUID,
+		ThreadOriginatorPart:  msg.ThreadOriginatorPart,
+	}
+}
+
+// newExportAttachment copies an attachment's stored fields into its export form
+func newExportAttachment(att models.Attachment) ExportAttachment {
+	return ExportAttachment{
+		ID:         att.ID,
+		GUID:       att.GUID,
+		Filename:   att.Filename,
+		UTI:        att.UTI,
+		MimeType:   att.MimeType,
+		TotalBytes: att.TotalBytes,
+		IsSticker:  att.IsSticker,
+		IsOutgoing: att.IsOutgoing,
+	}
+}
+
+// exportHandles lists handles in ID order so exports are stable across runs
+func exportHandles(handles map[int]models.Handle) []ExportHandle {
+	exported := make([]ExportHandle, 0, len(handles))
+	for _, handle := range handles {
+		exported = append(exported, ExportHandle{
+			ID:          handle.ID,
+			Contact:     handle.Contact,
+			Service:     handle.Service,
+			Country:     handle.Country,
+			DisplayName: handle.DisplayName,
+		})
+	}
+	sort.Slice(exported, func(i, j int) bool { return exported[i].ID < exported[j].ID })
+	return exported
+}
+
+// decodeLinkMetadata extracts the readable parts of a rich link payload, or nil if there are none
+func decodeLinkMetadata(payload []byte) *LinkMetadata {
+	archive, err := plist.DecodeKeyedArchive(payload)
+	if err != nil {
+		return nil
+	}
+
+	link := &LinkMetadata{
+		Title:    archive.FindString("title"),
+		Summary:  archive.FindString("summary"),
+		SiteName: archive.FindString("siteName"),
+	}
+	seen := make(map[string]bool)
+	for _, s := range archive.Strings() {
+		if strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://") {
+			if !seen[s] {
+				seen[s] = true
+				link.URLs = append(link.URLs, s)
+			}
+		}
+	}
+
+	if link.Title == "" && link.Summary == "" && link.SiteName == "" && len(link.URLs) == 0 {
+		return nil
+	}
+	return link
+}
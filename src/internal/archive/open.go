@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"threadbound/internal/models"
+	"threadbound/internal/workspace"
+)
+
+// Open verifies an archive and points config at its database and attachments so a book
+// can be rebuilt without the original chat.db. The archive must stay unchanged, so the
+// database is read from a temporary copy, as SQLite may create or checkpoint -wal and -shm
+// files beside it, and converted photos, link previews and summaries go to a folder of the
+// run's own in config.WorkDir, kept for the book to refer to. Call the returned cleanup
+// function once generation is done.
+func Open(dir string, config *models.BookConfig) (func(), error) {
+	if err := Verify(dir); err != nil {
+		return nil, fmt.Errorf("archive %s failed verification: %w", dir, err)
+	}
+
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir, err := os.MkdirTemp("", "threadbound-archive-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(workDir) }
+
+	dbPath := ""
+	w := &writer{root: workDir}
+	for _, file := range manifest.Files {
+		if !strings.HasPrefix(file.Path, DatabaseDir+"/") {
+			continue
+		}
+		name := path.Base(file.Path)
+		if err := w.copyFile(filepath.Join(dir, filepath.FromSlash(file.Path)), name); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to copy archived database: %w", err)
+		}
+		if !strings.HasSuffix(name, "-wal") && !strings.HasSuffix(name, "-shm") {
+			dbPath = filepath.Join(workDir, name)
+		}
+	}
+	if dbPath == "" {
+		cleanup()
+		return nil, fmt.Errorf("archive %s has no database", dir)
+	}
+
+	derived, err := workspace.New(config.WorkDir, "archive")
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	config.Log().Info("🗂️  Converted attachments kept", "dir", derived.Dir)
+
+	config.DatabasePath = dbPath
+	config.AttachmentsPath = filepath.Join(dir, AttachmentsDir)
+	config.DerivedPath = derived.Dir
+	return cleanup, nil
+}
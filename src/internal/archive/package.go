@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Package bundles an archive directory into <dir>.tar.zst using the zstd command
+func Package(dir string) (string, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return "", fmt.Errorf("zstd not found: install it (e.g. brew install zstd) to package archives")
+	}
+
+	dir = filepath.Clean(dir)
+	target := dir + ".tar.zst"
+
+	cmd := exec.Command("zstd", "-q", "-f", "-19", "-o", target)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start zstd: %w", err)
+	}
+
+	writeErr := writeTar(stdin, dir)
+	stdin.Close()
+	waitErr := cmd.Wait()
+
+	if writeErr != nil {
+		os.Remove(target)
+		return "", fmt.Errorf("failed to write tar stream: %w", writeErr)
+	}
+	if waitErr != nil {
+		os.Remove(target)
+		return "", fmt.Errorf("zstd failed: %w\n%s", waitErr, stderr.String())
+	}
+
+	return target, nil
+}
+
+// writeTar streams dir as a tar archive whose entries are rooted at the directory's name
+func writeTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	base := filepath.Base(dir)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
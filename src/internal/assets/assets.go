@@ -0,0 +1,112 @@
+package assets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// EnvDataDir names the environment variable that overrides every other data directory
+const EnvDataDir = "THREADBOUND_DATA_DIR"
+
+// Kinds of asset stored under a data directory
+const (
+	Templates = "templates"
+	Themes    = "themes"
+	Fonts     = "fonts"
+)
+
+// SearchDirs returns candidate data directories in priority order. Directories
+// are not checked for existence; use Dir or Find for that.
+func SearchDirs() []string {
+	if dir := os.Getenv(EnvDataDir); dir != "" {
+		return []string{dir}
+	}
+
+	var dirs []string
+
+	// Installed layouts: Homebrew and make install put the binary in <prefix>/bin
+	// and data in <prefix>/share/threadbound; archives ship data next to the binary
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		exeDir := filepath.Dir(exe)
+		dirs = append(dirs,
+			filepath.Join(exeDir, "..", "share", "threadbound"),
+			filepath.Join(exeDir, "share"),
+		)
+	}
+
+	dirs = append(dirs, userDataDirs()...)
+	return dirs
+}
+
+// userDataDirs returns the per-user and system data directories for this OS
+func userDataDirs() []string {
+	home, _ := os.UserHomeDir()
+
+	switch runtime.GOOS {
+	case "darwin":
+		var dirs []string
+		if home != "" {
+			dirs = append(dirs, filepath.Join(home, "Library", "Application Support", "threadbound"))
+		}
+		return append(dirs, "/Library/Application Support/threadbound")
+	case "windows":
+		var dirs []string
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			dirs = append(dirs, filepath.Join(appData, "threadbound"))
+		}
+		if programData := os.Getenv("ProgramData"); programData != "" {
+			dirs = append(dirs, filepath.Join(programData, "threadbound"))
+		}
+		return dirs
+	}
+
+	// XDG Base Directory specification
+	var dirs []string
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" && home != "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	if dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "threadbound"))
+	}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range strings.Split(dataDirs, ":") {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "threadbound"))
+		}
+	}
+	return dirs
+}
+
+// Dir returns the first existing directory of the given kind, or "" if none exists.
+// Callers fall back to the assets embedded in the binary.
+func Dir(kind string) string {
+	for _, dir := range SearchDirs() {
+		candidate := filepath.Join(dir, kind)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return filepath.Clean(candidate)
+		}
+	}
+	return ""
+}
+
+// Find locates a named asset of the given kind across all data directories
+func Find(kind, name string) (string, error) {
+	for _, dir := range SearchDirs() {
+		candidate := filepath.Join(dir, kind, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return filepath.Clean(candidate), nil
+		}
+	}
+	return "", fmt.Errorf("%s %q not found in data directories (set %s to override)", strings.TrimSuffix(kind, "s"), name, EnvDataDir)
+}
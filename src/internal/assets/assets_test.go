@@ -0,0 +1,54 @@
+package assets
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestEnvOverride(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv(EnvDataDir, dataDir)
+
+	dirs := SearchDirs()
+	if len(dirs) != 1 || dirs[0] != dataDir {
+		t.Fatalf("Expected only %s, got %v", dataDir, dirs)
+	}
+
+	// Nothing installed yet: callers fall back to embedded assets
+	if dir := Dir(Templates); dir != "" {
+		t.Errorf("Expected no templates dir, got '%s'", dir)
+	}
+	if _, err := Find(Themes, "classic.yaml"); err == nil {
+		t.Error("Expected error for missing theme")
+	}
+
+	templates := filepath.Join(dataDir, Templates)
+	os.MkdirAll(templates, 0755)
+	os.WriteFile(filepath.Join(templates, "book.tex"), []byte("%"), 0644)
+
+	if dir := Dir(Templates); dir != templates {
+		t.Errorf("Expected templates dir '%s', got '%s'", templates, dir)
+	}
+	path, err := Find(Templates, "book.tex")
+	if err != nil || path != filepath.Join(templates, "book.tex") {
+		t.Errorf("Expected to find book.tex, got '%s' (%v)", path, err)
+	}
+}
+
+func TestXDGDataHome(t *testing.T) {
+	// XDG only applies on Linux and other Unix systems
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("XDG directories are not used on " + runtime.GOOS)
+	}
+
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	expected := filepath.Join(dataHome, "threadbound")
+	dirs := userDataDirs()
+	if len(dirs) == 0 || dirs[0] != expected {
+		t.Errorf("Expected %s first among data dirs %v", expected, dirs)
+	}
+}
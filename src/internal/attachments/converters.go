@@ -0,0 +1,285 @@
+package attachments
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Register GIF decoding for the native converter
+	"image/jpeg"
+	_ "image/png" // Register PNG decoding for the native converter
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gen2brain/heic"
+
+	"threadbound/internal/models"
+)
+
+// ConvertOptions are the size, quality and color of converted images
+type ConvertOptions struct {
+	MaxDimension int // Longest side in pixels
+	Quality      int // JPEG quality, 1-100
+	Grayscale    bool
+}
+
+// OptionsFromConfig reads the conversion settings from the book's image config
+func OptionsFromConfig(images models.ImageConfig) ConvertOptions {
+	images = images.WithDefaults()
+	return ConvertOptions{MaxDimension: images.MaxDimension, Quality: images.JPEGQuality, Grayscale: images.Grayscale}
+}
+
+// fileSuffix tells images converted with non-default options apart in the processed folder
+func (o ConvertOptions) fileSuffix() string {
+	var suffix string
+	if o.MaxDimension != models.DefaultMaxDimension || o.Quality != models.DefaultJPEGQuality {
+		suffix = fmt.Sprintf("-%dpx-q%d", o.MaxDimension, o.Quality)
+	}
+	if o.Grayscale {
+		suffix += "-gray"
+	}
+	return suffix
+}
+
+// Converter turns a source image into a JPEG that XeLaTeX can embed
+type Converter interface {
+	// Name identifies the converter in config and log output
+	Name() string
+
+	// Available reports whether the converter can run on this machine
+	Available() bool
+
+	// Supports reports whether the converter can read files with this extension
+	Supports(ext string) bool
+
+	// Convert writes src as a JPEG to dst, shrinking it to fit opts.MaxDimension
+	Convert(src, dst string, opts ConvertOptions) error
+}
+
+// DefaultConverters returns the built-in converters in order of preference
+func DefaultConverters() []Converter {
+	return []Converter{
+		&magickConverter{},
+		&sipsConverter{},
+		&heifConvertConverter{},
+		&nativeConverter{},
+	}
+}
+
+// selectConverters filters converters to those available, optionally pinning one by name
+func selectConverters(converters []Converter, name string) []Converter {
+	var selected []Converter
+	for _, converter := range converters {
+		if name != "" && name != "auto" && converter.Name() != name {
+			continue
+		}
+		if converter.Available() {
+			selected = append(selected, converter)
+		}
+	}
+	return selected
+}
+
+// isHEIF checks for the HEIC/HEIF container used by iPhone photos
+func isHEIF(ext string) bool {
+	return ext == ".heic" || ext == ".heif"
+}
+
+// magickConverter uses ImageMagick, which reads nearly every format
+type magickConverter struct{}
+
+func (c *magickConverter) Name() string { return "magick" }
+
+func (c *magickConverter) Available() bool {
+	_, err := exec.LookPath("magick")
+	return err == nil
+}
+
+func (c *magickConverter) Supports(ext string) bool { return true }
+
+func (c *magickConverter) Convert(src, dst string, opts ConvertOptions) error {
+	// Only the first frame of animated images is usable in print
+	if strings.EqualFold(filepath.Ext(src), ".gif") {
+		src += "[0]"
+	}
+
+	size := fmt.Sprintf("%dx%d>", opts.MaxDimension, opts.MaxDimension)
+	args := []string{src, "-auto-orient", "-resize", size, "-quality", strconv.Itoa(opts.Quality), "-strip"}
+	if opts.Grayscale {
+		args = append(args, "-colorspace", "Gray")
+	}
+	return run(exec.Command("magick", append(args, dst)...))
+}
+
+// sipsConverter uses the scriptable image tool that ships with macOS
+type sipsConverter struct{}
+
+func (c *sipsConverter) Name() string { return "sips" }
+
+func (c *sipsConverter) Available() bool {
+	_, err := exec.LookPath("sips")
+	return err == nil
+}
+
+func (c *sipsConverter) Supports(ext string) bool { return true }
+
+func (c *sipsConverter) Convert(src, dst string, opts ConvertOptions) error {
+	if err := run(exec.Command("sips",
+		"-s", "format", "jpeg",
+		"-s", "formatOptions", strconv.Itoa(opts.Quality),
+		"-Z", strconv.Itoa(opts.MaxDimension),
+		src, "--out", dst)); err != nil {
+		return err
+	}
+
+	// sips cannot drop color, so re-encode the JPEG it produced in place
+	if opts.Grayscale {
+		return (&nativeConverter{}).Convert(dst, dst, opts)
+	}
+	return nil
+}
+
+// heifConvertConverter uses libheif's heif-convert, a small binary that can be
+// bundled alongside threadbound on systems without ImageMagick
+type heifConvertConverter struct{}
+
+func (c *heifConvertConverter) Name() string { return "heif-convert" }
+
+func (c *heifConvertConverter) Available() bool {
+	_, err := exec.LookPath("heif-convert")
+	return err == nil
+}
+
+func (c *heifConvertConverter) Supports(ext string) bool { return isHEIF(ext) }
+
+func (c *heifConvertConverter) Convert(src, dst string, opts ConvertOptions) error {
+	if err := run(exec.Command("heif-convert", "-q", strconv.Itoa(opts.Quality), src, dst)); err != nil {
+		return err
+	}
+
+	// heif-convert cannot resize, so shrink the JPEG it produced in place
+	return (&nativeConverter{}).Convert(dst, dst, opts)
+}
+
+// nativeConverter re-encodes formats the Go standard library can decode, and HEIC with a
+// decoder built into threadbound. It needs no external tools.
+type nativeConverter struct{}
+
+func (c *nativeConverter) Name() string { return "native" }
+
+func (c *nativeConverter) Available() bool { return true }
+
+func (c *nativeConverter) Supports(ext string) bool {
+	return ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif" || isHEIF(ext)
+}
+
+func (c *nativeConverter) Convert(src, dst string, opts ConvertOptions) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	var img image.Image
+	if isHEIF(strings.ToLower(filepath.Ext(src))) {
+		// HEIF files that aren't brand heic aren't recognized by image.Decode
+		img, err = heic.Decode(in)
+	} else {
+		img, _, err = image.Decode(in)
+	}
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", src, err)
+	}
+
+	// JPEG has no transparency, so transparent areas print on white rather than black
+	img = onWhite(shrink(img, opts.MaxDimension))
+	if opts.Grayscale {
+		gray := image.NewGray(img.Bounds())
+		draw.Draw(gray, gray.Bounds(), img, img.Bounds().Min, draw.Src)
+		img = gray
+	}
+
+	// Write to a temp file first so converting a file onto itself is safe
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(out, img, &jpeg.Options{Quality: opts.Quality}); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to encode %s: %w", dst, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// onWhite returns img drawn over a white background, or img itself when it's opaque
+func onWhite(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
+
+// shrink downsamples img by box averaging so its longest side is at most limit
+func shrink(img image.Image, limit int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= limit && height <= limit {
+		return img
+	}
+
+	newWidth, newHeight := limit, height*limit/width
+	if height > width {
+		newWidth, newHeight = width*limit/height, limit
+	}
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		y0 := bounds.Min.Y + y*height/newHeight
+		y1 := bounds.Min.Y + (y+1)*height/newHeight
+		for x := 0; x < newWidth; x++ {
+			x0 := bounds.Min.X + x*width/newWidth
+			x1 := bounds.Min.X + (x+1)*width/newWidth
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// run executes a converter command, including its output in any error
+func run(cmd *exec.Cmd) error {
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", filepath.Base(cmd.Path), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
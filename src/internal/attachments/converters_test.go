@@ -0,0 +1,204 @@
+package attachments
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestNativeConverterShrinksPNG(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "wide.png")
+	dst := filepath.Join(dir, "wide.jpg")
+
+	img := image.NewRGBA(image.Rect(0, 0, 2400, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 2400; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	if err := (&nativeConverter{}).Convert(src, dst, OptionsFromConfig(models.ImageConfig{})); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	out, err := os.Open(dst)
+	if err != nil {
+		t.Fatalf("Expected output file: %v", err)
+	}
+	defer out.Close()
+	config, err := jpeg.DecodeConfig(out)
+	if err != nil {
+		t.Fatalf("Output is not a JPEG: %v", err)
+	}
+	if config.Width != models.DefaultMaxDimension || config.Height != 300 {
+		t.Errorf("Expected %dx300, got %dx%d", models.DefaultMaxDimension, config.Width, config.Height)
+	}
+}
+
+func TestNativeConverterOptions(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "red.png")
+	dst := filepath.Join(dir, "red.jpg")
+
+	img := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	f, _ := os.Create(src)
+	png.Encode(f, img)
+	f.Close()
+
+	if err := (&nativeConverter{}).Convert(src, dst, ConvertOptions{MaxDimension: 200, Quality: 50, Grayscale: true}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	out, err := os.Open(dst)
+	if err != nil {
+		t.Fatalf("Expected output file: %v", err)
+	}
+	defer out.Close()
+	converted, err := jpeg.Decode(out)
+	if err != nil {
+		t.Fatalf("Output is not a JPEG: %v", err)
+	}
+	if bounds := converted.Bounds(); bounds.Dx() != 200 || bounds.Dy() != 100 {
+		t.Errorf("Expected 200x100, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+	if _, ok := converted.(*image.Gray); !ok {
+		t.Errorf("Expected a grayscale JPEG, got %T", converted)
+	}
+}
+
+func TestNativeConverterReadsHEIC(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "IMG_0001.HEIC")
+	dst := filepath.Join(dir, "IMG_0001.jpg")
+	data, err := os.ReadFile(filepath.Join("testdata", "photo.heic"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	os.WriteFile(src, data, 0644)
+
+	converter := &nativeConverter{}
+	if !converter.Supports(".heic") {
+		t.Fatal("Expected the native converter to support HEIC")
+	}
+	if err := converter.Convert(src, dst, OptionsFromConfig(models.ImageConfig{})); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	out, err := os.Open(dst)
+	if err != nil {
+		t.Fatalf("Expected output file: %v", err)
+	}
+	defer out.Close()
+	if config, err := jpeg.DecodeConfig(out); err != nil || config.Width == 0 || config.Height == 0 {
+		t.Errorf("Expected a JPEG, got %+v: %v", config, err)
+	}
+}
+
+func TestNativeConverterFlattensTransparency(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "sticker.png")
+	dst := filepath.Join(dir, "sticker.jpg")
+
+	// Transparent on the left, opaque red on the right
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 20; x < 40; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, A: 255})
+		}
+	}
+	f, _ := os.Create(src)
+	png.Encode(f, img)
+	f.Close()
+
+	if err := (&nativeConverter{}).Convert(src, dst, OptionsFromConfig(models.ImageConfig{})); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	out, _ := os.Open(dst)
+	defer out.Close()
+	converted, err := jpeg.Decode(out)
+	if err != nil {
+		t.Fatalf("Output is not a JPEG: %v", err)
+	}
+
+	tests := []struct {
+		x       int
+		r, g, b uint32
+	}{
+		{5, 255, 255, 255},
+		{35, 200, 0, 0},
+	}
+	for _, tt := range tests {
+		r, g, b, _ := converted.At(tt.x, 10).RGBA()
+		if diff(r>>8, tt.r) > 8 || diff(g>>8, tt.g) > 8 || diff(b>>8, tt.b) > 8 {
+			t.Errorf("Expected (%d, %d, %d) at x=%d, got (%d, %d, %d)", tt.r, tt.g, tt.b, tt.x, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+// diff returns how far apart two color components are
+func diff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func TestConvertOptionsFileSuffix(t *testing.T) {
+	if suffix := OptionsFromConfig(models.ImageConfig{}).fileSuffix(); suffix != "" {
+		t.Errorf("Expected default images to keep their names, got %q", suffix)
+	}
+	if suffix := OptionsFromConfig(models.ImageConfig{MaxDimension: 2400, Grayscale: true}).fileSuffix(); suffix != "-2400px-q85-gray" {
+		t.Errorf("Unexpected suffix %q", suffix)
+	}
+}
+
+func TestProcessImageReportsMissingHEICConverter(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "IMG_0001.HEIC")
+	os.WriteFile(src, []byte("not really heic"), 0644)
+
+	processor := &Processor{
+		config:       &models.BookConfig{AttachmentsPath: dir},
+		processedDir: dir,
+		converters:   nil,
+	}
+	att := &models.Attachment{GUID: "heic-guid", LocalPath: src}
+
+	err := processor.ProcessImage(att)
+	if err == nil || !strings.Contains(err.Error(), "HEIC") {
+		t.Errorf("Expected HEIC converter error, got: %v", err)
+	}
+	if att.ProcessedPath != "" {
+		t.Errorf("Expected no ProcessedPath, got '%s'", att.ProcessedPath)
+	}
+}
+
+func TestSelectConvertersPinsByName(t *testing.T) {
+	selected := selectConverters(DefaultConverters(), "native")
+	if len(selected) != 1 || selected[0].Name() != "native" {
+		t.Errorf("Expected only the native converter, got %d converters", len(selected))
+	}
+
+	// The native converter is always available, so auto never comes back empty
+	if len(selectConverters(DefaultConverters(), "auto")) == 0 {
+		t.Error("Expected at least one converter for auto")
+	}
+}
@@ -0,0 +1,141 @@
+package attachments
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"threadbound/internal/models"
+)
+
+// ProgressFunc is called after each attachment is processed with the number done so far
+type ProgressFunc func(done, total int)
+
+// Result summarizes a pipeline run
+type Result struct {
+	AttachmentCount int // Attachments found on disk
+	ImageCount      int // Images converted for the book
+	Failed          int // Attachments that were missing or failed to convert
+	LowResolution   int // Images that print below the target DPI
+	Report          Report
+}
+
+// Pipeline processes attachments concurrently using a fixed pool of workers
+type Pipeline struct {
+	processor     *Processor
+	concurrency   int
+	includeImages bool
+	progress      ProgressFunc
+}
+
+// NewPipeline creates a pipeline; a concurrency of zero or less uses one worker per CPU
+func NewPipeline(processor *Processor, concurrency int, includeImages bool, progress ProgressFunc) *Pipeline {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	return &Pipeline{
+		processor:     processor,
+		concurrency:   concurrency,
+		includeImages: includeImages,
+		progress:      progress,
+	}
+}
+
+// Run processes every attachment in place, blocking until all workers finish
+func (p *Pipeline) Run(atts []*models.Attachment) Result {
+	result := Result{Report: newReport()}
+	result.Report.Total = len(atts)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan *models.Attachment)
+	done := 0
+
+	for w := 0; w < p.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for att := range jobs {
+				found, converted, err := p.process(att)
+
+				mutex.Lock()
+				if found {
+					result.AttachmentCount++
+				}
+				if converted {
+					result.ImageCount++
+				}
+				if converted && p.warnResolution(att) {
+					result.LowResolution++
+				}
+				if err != nil {
+					result.Failed++
+					p.processor.config.Log().Warn("⚠️  Could not process attachment", "error", err)
+				}
+				switch {
+				case !found:
+					result.Report.Missing = append(result.Report.Missing, reportEntry(att, err))
+				case err != nil:
+					result.Report.Unreadable = append(result.Report.Unreadable, reportEntry(att, err))
+				default:
+					result.Report.Found++
+					if !p.processor.IsImageFile(att) {
+						result.Report.Unsupported = append(result.Report.Unsupported, reportEntry(att, nil))
+					}
+				}
+				done++
+				if p.progress != nil {
+					p.progress(done, len(atts))
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, att := range atts {
+		jobs <- att
+	}
+	close(jobs)
+	wg.Wait()
+
+	result.Report.sort()
+	return result
+}
+
+// process locates a single attachment and converts it if it is an image
+func (p *Pipeline) process(att *models.Attachment) (found, converted bool, err error) {
+	if err := p.processor.ProcessAttachment(att); err != nil {
+		return false, false, err
+	}
+	// A file can be listed but not readable, such as without Full Disk Access
+	file, err := os.Open(att.LocalPath)
+	if err != nil {
+		return true, false, fmt.Errorf("cannot read attachment %s: %w", att.LocalPath, err)
+	}
+	file.Close()
+
+	if !p.includeImages || !p.processor.IsImageFile(att) {
+		return true, false, nil
+	}
+
+	if err := p.processor.ProcessImage(att); err != nil {
+		return true, false, fmt.Errorf("failed to process image %s: %w", att.LocalPath, err)
+	}
+
+	return true, true, nil
+}
+
+// warnResolution warns when a converted image prints below the target DPI, so blurry
+// photos can be replaced before the book is ordered. It reports whether it warned.
+func (p *Pipeline) warnResolution(att *models.Attachment) bool {
+	target := p.processor.config.Images.WithDefaults().TargetDPI
+	dpi, err := p.processor.PrintDPI(att)
+	if err != nil || dpi >= target {
+		return false
+	}
+	p.processor.config.Log().Warn("🔍 Photo prints below the target resolution and may look blurry", "file", filepath.Base(att.LocalPath), "dpi", dpi, "target_dpi", target)
+	return true
+}
@@ -0,0 +1,180 @@
+package attachments
+
+import (
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/logging"
+	"threadbound/internal/models"
+)
+
+func TestPipelineLocatesAttachments(t *testing.T) {
+	attachmentsDir := t.TempDir()
+
+	// Lay out files the way an exported Attachments folder looks on disk
+	present := filepath.Join(attachmentsDir, "ab", "12", "GUID-1", "notes.pdf")
+	if err := os.MkdirAll(filepath.Dir(present), 0755); err != nil {
+		t.Fatalf("Failed to create attachment dir: %v", err)
+	}
+	if err := os.WriteFile(present, []byte("pdf"), 0644); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+
+	found := "~/Library/Messages/Attachments/ab/12/GUID-1/notes.pdf"
+	missing := "~/Library/Messages/Attachments/cd/34/GUID-2/gone.pdf"
+
+	atts := []*models.Attachment{
+		{GUID: "GUID-1", Filename: &found},
+		{GUID: "GUID-2", Filename: &missing},
+		{GUID: "GUID-3"},
+	}
+
+	config := &models.BookConfig{AttachmentsPath: attachmentsDir}
+
+	var calls, lastDone int
+	pipeline := NewPipeline(New(config), 2, true, func(done, total int) {
+		calls++
+		lastDone = done
+		if total != len(atts) {
+			t.Errorf("Expected total %d, got %d", len(atts), total)
+		}
+	})
+
+	result := pipeline.Run(atts)
+
+	if result.AttachmentCount != 1 {
+		t.Errorf("Expected 1 attachment found, got %d", result.AttachmentCount)
+	}
+	if result.Failed != 2 {
+		t.Errorf("Expected 2 failures, got %d", result.Failed)
+	}
+	if result.ImageCount != 0 {
+		t.Errorf("Expected no images, got %d", result.ImageCount)
+	}
+	if atts[0].LocalPath != present {
+		t.Errorf("Expected LocalPath '%s', got '%s'", present, atts[0].LocalPath)
+	}
+	if calls != len(atts) || lastDone != len(atts) {
+		t.Errorf("Expected %d progress calls ending at %d, got %d ending at %d", len(atts), len(atts), calls, lastDone)
+	}
+}
+
+func TestIsImageFile(t *testing.T) {
+	processor := &Processor{}
+
+	jpeg := "IMG_0001.JPG"
+	heic := "photo.heic"
+	pdf := "notes.pdf"
+	mime := "image/png"
+
+	tests := []struct {
+		att      models.Attachment
+		expected bool
+	}{
+		{models.Attachment{Filename: &jpeg}, true},
+		{models.Attachment{Filename: &heic}, true},
+		{models.Attachment{Filename: &pdf}, false},
+		{models.Attachment{MimeType: &mime}, true},
+		{models.Attachment{}, false},
+	}
+
+	for _, test := range tests {
+		if result := processor.IsImageFile(&test.att); result != test.expected {
+			t.Errorf("IsImageFile(%v) = %v, expected %v", test.att.Filename, result, test.expected)
+		}
+	}
+}
+
+func TestPrintDPI(t *testing.T) {
+	tests := []struct {
+		width, height, dpi int
+	}{
+		{1200, 900, 480}, // Landscape photos fill the width
+		{900, 1200, 400}, // Portrait ones the height
+		{600, 450, 240},  // Small ones are blown up
+		{0, 100, 0},
+	}
+	for _, test := range tests {
+		if dpi := PrintDPI(test.width, test.height); dpi != test.dpi {
+			t.Errorf("PrintDPI(%d, %d) = %d, expected %d", test.width, test.height, dpi, test.dpi)
+		}
+	}
+}
+
+func TestPipelineWarnsLowResolution(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, width, height int) string {
+		path := filepath.Join(dir, name)
+		f, _ := os.Create(path)
+		png.Encode(f, image.NewRGBA(image.Rect(0, 0, width, height)))
+		f.Close()
+		return path
+	}
+	large, small := write("large.png", 1600, 1200), write("small.png", 400, 300)
+
+	var output strings.Builder
+	config := &models.BookConfig{AttachmentsPath: dir, ImageConverter: "native", Logger: logging.New(&output, logging.Options{})}
+	atts := []*models.Attachment{
+		{GUID: "GUID-1", Filename: &large},
+		{GUID: "GUID-2", Filename: &small},
+	}
+
+	result := NewPipeline(New(config), 1, true, nil).Run(atts)
+	if result.ImageCount != 2 || result.LowResolution != 1 {
+		t.Errorf("Expected 2 images, 1 below 300 DPI, got %+v", result)
+	}
+	if !strings.Contains(output.String(), "file=small.png dpi=160 target_dpi=300") || strings.Contains(output.String(), "large.png") {
+		t.Errorf("Expected a warning for the small image only, got:\n%s", output.String())
+	}
+}
+
+func TestPipelineReport(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, data, 0644)
+		return path
+	}
+	var photo strings.Builder
+	png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	good := write("good.png", []byte(photo.String()))
+	broken := write("broken.png", []byte("not a png"))
+	video := write("clip.mov", []byte("mov"))
+	missing := filepath.Join(dir, "gone.jpg")
+
+	config := &models.BookConfig{AttachmentsPath: dir, ImageConverter: "native", Logger: logging.New(io.Discard, logging.Options{})}
+	atts := []*models.Attachment{
+		{GUID: "GUID-1", Filename: &good},
+		{GUID: "GUID-2", Filename: &broken},
+		{GUID: "GUID-3", Filename: &video},
+		{GUID: "GUID-4", Filename: &missing},
+		{GUID: "GUID-5"},
+	}
+
+	report := NewPipeline(New(config), 2, true, nil).Run(atts).Report
+	if report.Total != 5 || report.Found != 2 || report.Problems() != 4 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if len(report.Missing) != 2 || report.Missing[0].GUID != "GUID-4" || report.Missing[0].Filename != missing || report.Missing[1].GUID != "GUID-5" {
+		t.Errorf("Expected the missing attachments in GUID order, got %+v", report.Missing)
+	}
+	if len(report.Unreadable) != 1 || report.Unreadable[0].Path != broken || report.Unreadable[0].Error == "" {
+		t.Errorf("Expected the broken image unreadable, got %+v", report.Unreadable)
+	}
+	if len(report.Unsupported) != 1 || report.Unsupported[0].GUID != "GUID-3" {
+		t.Errorf("Expected the video unsupported, got %+v", report.Unsupported)
+	}
+
+	path := filepath.Join(dir, "report.json")
+	if err := report.Save(path); err != nil {
+		t.Fatalf("Failed to save report: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"unsupported": [`) || !strings.Contains(string(data), `"guid": "GUID-2"`) {
+		t.Errorf("Unexpected report file:\n%s", data)
+	}
+}
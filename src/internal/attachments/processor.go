@@ -0,0 +1,177 @@
+package attachments
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg" // Register JPEG decoding to measure converted images
+	"os"
+	"path/filepath"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// Processor locates attachment files on disk and prepares images for the book
+type Processor struct {
+	config       *models.BookConfig
+	processedDir string
+	converters   []Converter
+	options      ConvertOptions
+}
+
+// New creates a new attachment processor
+func New(config *models.BookConfig) *Processor {
+	// Converted images are cached next to the attachments so reruns are cheap
+	processedDir := filepath.Join(config.DerivedDir(), "processed")
+	os.MkdirAll(processedDir, 0755)
+
+	return &Processor{
+		config:       config,
+		processedDir: processedDir,
+		converters:   selectConverters(DefaultConverters(), config.ImageConverter),
+		options:      OptionsFromConfig(config.Images),
+	}
+}
+
+// ProcessAttachment resolves the attachment's file on disk and sets LocalPath
+func (p *Processor) ProcessAttachment(att *models.Attachment) error {
+	if att.Filename == nil || *att.Filename == "" {
+		return fmt.Errorf("attachment %s has no filename", att.GUID)
+	}
+
+	path, err := Locate(p.config.AttachmentsPath, att.GUID, *att.Filename)
+	if err != nil {
+		return err
+	}
+	att.LocalPath = path
+	return nil
+}
+
+// Locate finds an attachment's file on disk given its GUID and the filename recorded in chat.db
+func Locate(attachmentsPath, guid, filename string) (string, error) {
+	for _, candidate := range candidatePaths(attachmentsPath, guid, filename) {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("attachment not found: %s", filename)
+}
+
+// candidatePaths returns the locations an attachment may live at, most specific first
+func candidatePaths(attachmentsPath, guid, filename string) []string {
+	var candidates []string
+
+	// iMessage stores paths like ~/Library/Messages/Attachments/ab/12/GUID/IMG_0001.HEIC.
+	// Exports copy the Attachments folder elsewhere, so re-root everything after it.
+	if idx := strings.Index(filename, "Attachments/"); idx != -1 {
+		relative := filename[idx+len("Attachments/"):]
+		candidates = append(candidates, filepath.Join(attachmentsPath, relative))
+	}
+
+	// Archives made by the archive command keep each file under its attachment GUID
+	if guid != "" {
+		candidates = append(candidates, filepath.Join(attachmentsPath, guid, filepath.Base(filename)))
+	}
+
+	// Fall back to the original location for databases read in place
+	if strings.HasPrefix(filename, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			candidates = append(candidates, filepath.Join(home, filename[2:]))
+		}
+	} else if filepath.IsAbs(filename) {
+		candidates = append(candidates, filename)
+	}
+
+	return candidates
+}
+
+// IsImageFile checks if the attachment is an image that can be placed in the book
+func (p *Processor) IsImageFile(att *models.Attachment) bool {
+	if att.MimeType != nil && strings.HasPrefix(*att.MimeType, "image/") {
+		return true
+	}
+	if att.Filename == nil {
+		return false
+	}
+
+	ext := strings.ToLower(filepath.Ext(*att.Filename))
+	imageExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tiff", ".webp", ".heic", ".heif"}
+	for _, imgExt := range imageExts {
+		if ext == imgExt {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessImage converts and resizes an image into a format XeLaTeX can embed and sets ProcessedPath
+func (p *Processor) ProcessImage(att *models.Attachment) error {
+	if att.LocalPath == "" {
+		return fmt.Errorf("attachment %s has not been located", att.GUID)
+	}
+
+	targetPath := filepath.Join(p.processedDir, att.GUID+p.options.fileSuffix()+".jpg")
+
+	// Reuse images converted by a previous run with the same options
+	if _, err := os.Stat(targetPath); err == nil {
+		att.ProcessedPath = targetPath
+		return nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(att.LocalPath))
+
+	// Try each capable converter in order of preference until one succeeds
+	var lastErr error
+	for _, converter := range p.converters {
+		if !converter.Supports(ext) {
+			continue
+		}
+		if lastErr = converter.Convert(att.LocalPath, targetPath, p.options); lastErr == nil {
+			att.ProcessedPath = targetPath
+			return nil
+		}
+		os.Remove(targetPath)
+	}
+
+	if lastErr != nil {
+		return lastErr
+	}
+	if isHEIF(ext) {
+		return fmt.Errorf("no HEIC converter available - install ImageMagick or libheif (heif-convert), or use the native converter")
+	}
+	return fmt.Errorf("no image converter available for %s files", ext)
+}
+
+// The largest an image is printed in the book, in inches, as image-attachment.tex sizes it
+const (
+	PrintedImageWidth  = 2.5
+	PrintedImageHeight = 3.0
+)
+
+// PrintDPI returns the resolution a converted image prints at when scaled to fit the
+// printed size
+func (p *Processor) PrintDPI(att *models.Attachment) (int, error) {
+	file, err := os.Open(att.ProcessedPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", att.ProcessedPath, err)
+	}
+	return PrintDPI(config.Width, config.Height), nil
+}
+
+// PrintDPI returns the resolution an image of width by height pixels prints at, scaled to
+// fit PrintedImageWidth by PrintedImageHeight
+func PrintDPI(width, height int) int {
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	scale := PrintedImageWidth / float64(width)
+	if byHeight := PrintedImageHeight / float64(height); byHeight < scale {
+		scale = byHeight
+	}
+	return int(1 / scale)
+}
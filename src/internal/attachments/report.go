@@ -0,0 +1,73 @@
+package attachments
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"threadbound/internal/models"
+)
+
+// Report compares the attachments the messages refer to with what was found on disk, so
+// the Attachments copy can be fixed before committing to a print run
+type Report struct {
+	Total       int           `json:"total"`       // Attachments the messages refer to
+	Found       int           `json:"found"`       // Found on disk and readable
+	Missing     []ReportEntry `json:"missing"`     // Not on disk, or with no file name recorded
+	Unreadable  []ReportEntry `json:"unreadable"`  // On disk, but couldn't be read or converted
+	Unsupported []ReportEntry `json:"unsupported"` // Readable, but a kind the book only names, such as videos
+}
+
+// ReportEntry is an attachment the report lists
+type ReportEntry struct {
+	GUID     string `json:"guid"`
+	Filename string `json:"filename,omitempty"` // As recorded in chat.db
+	MimeType string `json:"mime_type,omitempty"`
+	Path     string `json:"path,omitempty"` // Where it was found on disk
+	Error    string `json:"error,omitempty"`
+}
+
+// newReport starts an empty report, whose lists encode as [] rather than null
+func newReport() Report {
+	return Report{Missing: []ReportEntry{}, Unreadable: []ReportEntry{}, Unsupported: []ReportEntry{}}
+}
+
+// reportEntry describes an attachment for the report
+func reportEntry(att *models.Attachment, err error) ReportEntry {
+	entry := ReportEntry{GUID: att.GUID, Path: att.LocalPath}
+	if att.Filename != nil {
+		entry.Filename = *att.Filename
+	}
+	if att.MimeType != nil {
+		entry.MimeType = *att.MimeType
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// sort orders each list by GUID, as the workers finish in any order
+func (r *Report) sort() {
+	for _, entries := range [][]ReportEntry{r.Missing, r.Unreadable, r.Unsupported} {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].GUID < entries[j].GUID })
+	}
+}
+
+// Problems counts the attachments that won't be printed as they were sent
+func (r *Report) Problems() int {
+	return len(r.Missing) + len(r.Unreadable) + len(r.Unsupported)
+}
+
+// Save writes the report as indented JSON
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode attachment report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write attachment report %s: %w", path, err)
+	}
+	return nil
+}
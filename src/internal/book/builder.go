@@ -2,39 +2,142 @@ package book
 
 import (
 	"fmt"
+	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"threadbound/internal/analytics"
+	"threadbound/internal/appmessage"
 	"threadbound/internal/attachments"
 	"threadbound/internal/database"
+	"threadbound/internal/filter"
+	"threadbound/internal/handwriting"
+	"threadbound/internal/i18n"
+	"threadbound/internal/manifest"
+	"threadbound/internal/memories"
 	"threadbound/internal/models"
 	"threadbound/internal/output"
 	_ "threadbound/internal/plugins" // Import to register plugins
+	"threadbound/internal/printing"
+	"threadbound/internal/redact"
+	"threadbound/internal/summaries"
+	"threadbound/internal/timing"
+	"threadbound/internal/typedstream"
+	"threadbound/internal/vcard"
 )
 
 // Builder orchestrates the book generation process
 type Builder struct {
-	config *models.BookConfig
-	db     *database.DB
+	config        *models.BookConfig
+	db            *database.DB
+	timings       *timing.Recorder
+	filterResults []filter.Result
+	query         *filter.Query                  // Parsed from config.Filter; nil keeps every message
+	queryRest     *filter.Query                  // Terms of query left to match in memory after the SQL ones
+	queryDropped  int                            // Messages the SQL terms of query left out of the last load
+	preview       *filter.Preview                // Parsed from config.Preview; nil builds the whole book
+	onThisDay     *filter.OnThisDay              // Parsed from config.OnThisDay; nil keeps every day
+	location      *time.Location                 // Zone messages are dated in, from config.Timezone
+	cleanup       func()                         // Removes the database snapshot, if one was taken
+	pageCount     int                            // Pages typeset by the last generation, when the format reports them
+	volumes       []Volume                       // Written by the last generation when the book was split
+	report        *attachments.Report            // What became of the attachments in the last generation
+	stats         *models.BookStats              // Counted by the last generation
+	afterVolume   func(path string) (int, error) // Called with each volume as it's written
 }
 
 // New creates a new book builder
 func New(config *models.BookConfig) (*Builder, error) {
+	location, err := config.Location()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := i18n.Lookup(config.Locale); err != nil {
+		return nil, err
+	}
+	query, err := filter.ParseQueryInLocation(config.Filter, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	preview, err := filter.ParsePreview(config.Preview, config.PreviewFrom)
+	if err != nil {
+		return nil, err
+	}
+	onThisDay, err := filter.ParseOnThisDay(config.OnThisDay)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Volumes.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Images.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.URLPolicies.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Avatars.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Summaries.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Text.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.ValidateContents(); err != nil {
+		return nil, err
+	}
+
+	// A live chat.db is locked and written to by Messages, and keeps recent messages in its
+	// write-ahead log; read a consistent snapshot instead so none go missing and every
+	// reader sees the same data
+	var cleanup func()
+	if database.NeedsSnapshot(config.DatabasePath) {
+		config.Log().Info("📸 Snapshotting the live database...")
+		snapshotPath, done, err := database.Snapshot(config.DatabasePath)
+		if err != nil {
+			return nil, err
+		}
+
+		// Plugins open the database by path as well, so point the whole build at the snapshot
+		snapshotConfig := *config
+		snapshotConfig.DatabasePath = snapshotPath
+		config = &snapshotConfig
+		cleanup = done
+	}
+
 	db, err := database.New(config.DatabasePath)
 	if err != nil {
+		if cleanup != nil {
+			cleanup()
+		}
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetLocation(location)
 
 	return &Builder{
-		config: config,
-		db:     db,
+		config:    config,
+		db:        db,
+		timings:   timing.New(config.Timings),
+		query:     query,
+		preview:   preview,
+		onThisDay: onThisDay,
+		location:  location,
+		cleanup:   cleanup,
 	}, nil
 }
 
-// Close closes the database connection
+// Close closes the database connection and removes any snapshot
 func (b *Builder) Close() error {
-	return b.db.Close()
+	err := b.db.Close()
+	if b.cleanup != nil {
+		b.cleanup()
+	}
+	return err
 }
 
 // Generate creates the book using the output format determined by file extension
@@ -44,8 +147,12 @@ func (b *Builder) Generate() error {
 	return b.GenerateWithFormat(format)
 }
 
-// detectFormat determines the output format from the file extension
+// detectFormat determines the output format from the config, or else the file extension
 func (b *Builder) detectFormat() string {
+	if b.config.Format != "" {
+		return b.config.Format
+	}
+
 	ext := strings.TrimPrefix(filepath.Ext(b.config.OutputPath), ".")
 	if ext == "" {
 		return "tex" // Default to TeX if no extension
@@ -62,134 +169,676 @@ func (b *Builder) detectFormat() string {
 
 // GenerateWithFormat creates the book using the specified output plugin
 func (b *Builder) GenerateWithFormat(format string) error {
-	fmt.Println("📱 Extracting messages from database...")
+	messages, handles, reactions, attachmentsByMessage, err := b.extract()
+	if err != nil {
+		return err
+	}
+
+	// Count the book's messages before attachments and memories are added to them
+	stats := b.bookStats(messages, handles, b.filterResults)
+	b.stats = stats
+
+	// Process attachments for messages that have them
+	b.config.Log().Info("📎 Processing attachments...")
+	stopAttachments := b.timings.Start("attachments")
+	err = b.processAttachments(messages, attachmentsByMessage)
+	stopAttachments()
+	if err != nil {
+		return fmt.Errorf("failed to process attachments: %w", err)
+	}
+
+	// Insert out-of-band photos into the timeline as memory pages
+	if b.config.MemoriesPath != "" {
+		b.config.Log().Info("🖼️  Adding memories...")
+		stopMemories := b.timings.Start("memories")
+		messages, err = b.addMemories(messages)
+		stopMemories()
+		if err != nil {
+			return fmt.Errorf("failed to add memories: %w", err)
+		}
+	}
+
+	chatNames, err := b.chatNames(handles)
+	if err != nil {
+		return err
+	}
+	chapterSummaries := b.summarize(messages, handles)
+
+	// Long conversations can be split into several books
+	if b.config.Volumes.Enabled() {
+		parts := printing.SplitVolumes(messages, b.config.Volumes, b.config.IncludeImages)
+		if len(parts) > 1 {
+			return b.generateVolumes(format, parts, handles, reactions, stats, chatNames, chapterSummaries)
+		}
+	}
+
+	// Create generation context
+	ctx := output.CreateContext(messages, handles, reactions, b.config, stats)
+	ctx.Timings = b.timings
+	ctx.ChatNames = chatNames
+	ctx.Summaries = chapterSummaries
+	ctx.Fragments = b.loadFragments(b.config.OutputPath)
+
+	// Generate using plugin system
+	b.config.Log().Info("📝 Generating output", "format", format)
+	generator := output.New()
+	stopRendering := b.timings.Start("rendering")
+	data, filename, err := generator.Generate(format, ctx)
+	stopRendering()
+	if err != nil {
+		return fmt.Errorf("failed to generate %s: %w", format, err)
+	}
+	b.pageCount = ctx.PageCount
+
+	if err := b.write(format, filename, data); err != nil {
+		return err
+	}
+	b.saveFragments(ctx.Fragments)
+	b.config.Log().Info("✅ Generated book", "path", filename)
+
+	b.reportTimings(filename)
+	if err := manifest.RecordFilters(filename, b.filterResults); err != nil {
+		b.config.Log().Warn("⚠️  Could not record filter results", "error", err)
+	}
+	return nil
+}
+
+// chatNames names each conversation for chapters by chat, or returns nil when the book's
+// chapters are split some other way
+func (b *Builder) chatNames(handles map[int]models.Handle) (map[int]string, error) {
+	if b.config.Chapters() != models.ChaptersByChat {
+		return nil, nil
+	}
+	chats, err := b.db.ListChats()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(chats))
+	for _, chat := range chats {
+		names[chat.ID] = chat.Name(handles)
+	}
+	return names, nil
+}
+
+// write saves generated output, with each chapter in a file of its own when splitting a
+// TeX book, and text output split into chunks as well when it has a token budget
+func (b *Builder) write(format, filename string, data []byte) error {
+	if b.config.SplitChapters && strings.EqualFold(format, "tex") {
+		chapters, err := writeSplitBook(filename, string(data))
+		if err != nil {
+			return err
+		}
+		b.config.Log().Info("📚 Split chapters", "chapters", chapters, "dir", ChapterDirFor(filename))
+		return nil
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if tokens := b.config.Text.ChunkTokens; tokens > 0 && strings.EqualFold(format, "txt") {
+		chunks, err := writeChunks(filename, string(data), tokens)
+		if err != nil {
+			return err
+		}
+		b.config.Log().Info("✂️  Split into files", "files", chunks, "tokens", tokens, "dir", ChunkDirFor(filename))
+	}
+	return nil
+}
+
+// extract reads the messages, contacts and reactions the book is made from, with the
+// filters and redaction applied, for generating and for statistics alike. It also returns
+// every attachment by message ID, read once for the stages that need them.
+func (b *Builder) extract() ([]models.Message, map[int]models.Handle, map[string][]models.Reaction, map[int][]models.Attachment, error) {
+	b.config.Log().Info("📱 Extracting messages from database...")
+	defer b.timings.Start("extraction")()
+	b.filterResults = nil
 
 	// Get all messages
-	messages, err := b.db.GetMessages()
+	messages, err := b.loadMessages()
 	if err != nil {
-		return fmt.Errorf("failed to get messages: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to get messages: %w", err)
 	}
 
 	if len(messages) == 0 {
-		return fmt.Errorf("no messages found in database")
+		return nil, nil, nil, nil, fmt.Errorf("no messages found in database")
 	}
 
-	fmt.Printf("✅ Found %d messages\n", len(messages))
+	b.config.Log().Info("✅ Found messages", "count", len(messages))
 
 	// Get handles (contacts)
 	handles, err := b.db.GetHandles(b.config.ContactNames)
 	if err != nil {
-		return fmt.Errorf("failed to get handles: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to get handles: %w", err)
 	}
 
-	fmt.Printf("👥 Found %d contacts\n", len(handles))
+	b.config.Log().Info("👥 Found contacts", "count", len(handles))
+	// Without my_name or author, the owner's phone number or Apple ID labels the messages
+	// you sent and signs the book
+	if b.config.MyName == "" || b.config.Author == "" {
+		if owner := b.db.DetectOwner(); owner != "" {
+			if b.config.MyName == "" {
+				b.config.MyName = owner
+			}
+			if b.config.Author == "" {
+				b.config.Author = owner
+			}
+			b.config.Log().Info("👤 Account owner found; used where my_name or author is unset", "owner", owner)
+		}
+	}
 
 	// Get reactions
-	fmt.Println("👍 Loading message reactions...")
+	b.config.Log().Info("👍 Loading message reactions...")
 	reactions, err := b.db.GetReactions(handles)
 	if err != nil {
-		return fmt.Errorf("failed to get reactions: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to get reactions: %w", err)
 	}
+	output.NameMyReactions(reactions, b.config)
 
-	fmt.Printf("❤️ Found reactions for %d messages\n", len(reactions))
+	b.config.Log().Info("❤️ Found reactions", "messages", len(reactions))
 
-	// Process attachments for messages that have them
-	fmt.Println("📎 Processing attachments...")
-	err = b.processAttachments(messages)
+	attachmentsByMessage, err := b.db.GetAllAttachments()
 	if err != nil {
-		return fmt.Errorf("failed to process attachments: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+
+	// Newer versions of Messages keep some messages' text only in attributedBody
+	if err := b.decodeAttributedBodies(messages); err != nil {
+		return nil, nil, nil, nil, err
 	}
 
-	// Get book statistics
-	stats, err := b.GetStats()
+	// Give iMessage app messages, such as polls and Check In, readable text
+	b.summarizeAppMessages(messages)
+
+	// Shared contacts and locations become text, so filters and redaction see them too
+	b.describeCards(messages, attachmentsByMessage)
+
+	// Drop or collapse messages that shouldn't appear in the book
+	messages, err = b.applyFilters(messages, handles, reactions, attachmentsByMessage)
 	if err != nil {
-		return fmt.Errorf("failed to get stats: %w", err)
+		return nil, nil, nil, nil, err
 	}
 
-	// Create generation context
-	ctx := output.CreateContext(messages, handles, reactions, b.config, stats)
+	// Mask sensitive text before any plugin sees it
+	if b.config.RedactProfile != "" {
+		if err := b.redact(messages); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("failed to redact messages: %w", err)
+		}
+	}
 
-	// Generate using plugin system
-	fmt.Printf("📝 Generating %s output...\n", format)
-	generator := output.New()
-	data, filename, err := generator.Generate(format, ctx)
+	return messages, handles, reactions, attachmentsByMessage, nil
+}
+
+// AttachmentReport returns what became of the attachments in the last book generated, or
+// nil before one is
+func (b *Builder) AttachmentReport() *attachments.Report {
+	return b.report
+}
+
+// PageCount returns the number of pages in the last book generated, or 0 for formats that
+// aren't typeset into pages
+func (b *Builder) PageCount() int {
+	return b.pageCount
+}
+
+// applyFilters runs the enabled message filters and keeps their results for the manifest
+func (b *Builder) applyFilters(messages []models.Message, handles map[int]models.Handle, reactions map[string][]models.Reaction,
+	attachmentsByMessage map[int][]models.Attachment) ([]models.Message, error) {
+	messages, results, err := b.exclude(messages, handles, reactions, attachmentsByMessage)
 	if err != nil {
-		return fmt.Errorf("failed to generate %s: %w", format, err)
+		return nil, err
+	}
+	for _, result := range results {
+		b.config.Log().Info("🚫 Excluded messages", "filter", result.Name, "count", result.Removed)
+	}
+	b.filterResults = append(b.filterResults, results...)
+	if b.onThisDay != nil {
+		b.config.Log().Info("📅 On this day: keeping the day of every year", "day", b.onThisDay)
+	}
+	if b.preview != nil {
+		b.config.Log().Info("👀 Preview: building only part of the book", "preview", b.preview)
+	}
+
+	if b.config.Dedupe {
+		window := b.config.DedupeWindow
+		if window <= 0 {
+			window = filter.DefaultDedupeWindow
+		}
+
+		var collapsed map[string]string
+		messages, collapsed = filter.Dedupe(messages, window)
+		filter.MergeReactions(reactions, collapsed)
+
+		b.filterResults = append(b.filterResults, filter.Result{Name: "dedupe", Removed: len(collapsed)})
+		b.config.Log().Info("🧹 Collapsed duplicate messages", "count", len(collapsed))
+	}
+
+	return messages, nil
+}
+
+// loadMessages reads the messages from the database, only those in the configured chat
+// when there is one. The filter query's terms that compile to SQL are applied by the
+// query itself; exclude matches the rest.
+func (b *Builder) loadMessages() ([]models.Message, error) {
+	var where string
+	var args []interface{}
+	if b.config.ChatID != 0 {
+		where, args = database.ChatPredicate(b.config.ChatID)
+	}
+	if b.query == nil {
+		return b.db.GetMessagesWhere(where, args...)
 	}
 
-	// Write to file
-	err = os.WriteFile(filename, data, 0644)
+	total, err := b.db.CountMessages(where, args...)
 	if err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+		return nil, err
+	}
+	queryWhere, queryArgs, rest := b.query.SQL()
+	if queryWhere != "" {
+		if where != "" {
+			where += " AND "
+		}
+		where += queryWhere
+		args = append(args, queryArgs...)
+	}
+	messages, err := b.db.GetMessagesWhere(where, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	b.queryRest = rest
+	b.queryDropped = total - len(messages)
+	return messages, nil
+}
+
+// exclude keeps only the messages of an "on this day" book's date, drops messages matching
+// the configured contact, keyword and spam exclusions, and those left by loadMessages that
+// don't match the filter query, then keeps only the highlights of a digest, scored with
+// reactions and attachments, and the preview's messages when building a preview
+func (b *Builder) exclude(messages []models.Message, handles map[int]models.Handle, reactions map[string][]models.Reaction,
+	attachmentsByMessage map[int][]models.Attachment) ([]models.Message, []filter.Result, error) {
+	var results []filter.Result
+	var removed int
+
+	// First, so every other filter works on the day's messages alone
+	if b.onThisDay != nil {
+		messages, removed = b.onThisDay.Apply(messages)
+		results = append(results, filter.Result{Name: "on_this_day", Removed: removed})
+	}
+
+	if b.query != nil {
+		// Reactions aren't needed: has:reaction always compiles to SQL
+		messages, removed = filter.ApplyQuery(messages, b.queryRest, handles, nil)
+		results = append(results, filter.Result{Name: "filter", Removed: b.queryDropped + removed})
+	}
+
+	if len(b.config.ExcludeContacts) > 0 {
+		messages, removed = filter.ExcludeContacts(messages, handles, b.config.ExcludeContacts)
+		results = append(results, filter.Result{Name: "exclude_contacts", Removed: removed})
+	}
+	if len(b.config.ExcludeKeywords) > 0 {
+		messages, removed = filter.ExcludeKeywords(messages, b.config.ExcludeKeywords)
+		results = append(results, filter.Result{Name: "exclude_keywords", Removed: removed})
+	}
+	if b.config.FilterSpam {
+		messages, removed = filter.Spam(messages, handles)
+		results = append(results, filter.Result{Name: "spam", Removed: removed})
+	}
+
+	if b.config.Digest {
+		// Attachments are put on the messages later, once the digest has dropped most
+		messages, removed = filter.Digest(messages, reactions, attachmentsByMessage, b.config.DigestRules)
+		results = append(results, filter.Result{Name: "digest", Removed: removed})
+	}
+
+	// Last, so the preview is of the book's own messages
+	if b.preview != nil {
+		messages, removed = b.preview.Apply(messages)
+		results = append(results, filter.Result{Name: "preview", Removed: removed})
+	}
+
+	return messages, results, nil
+}
+
+// summarize writes the "In this chapter" summary of each month chapter when summaries are
+// enabled, returning nil otherwise without sending anything
+func (b *Builder) summarize(messages []models.Message, handles map[int]models.Handle) map[string]string {
+	if !b.config.Summaries.Enabled || b.config.Chapters() != models.ChaptersByMonth {
+		return nil
+	}
+	b.config.Log().Info("🤖 Summarizing chapters", "model", b.config.Summaries.Model, "endpoint", b.config.Summaries.Endpoint)
+	defer b.timings.Start("summaries")()
+	return summaries.ByMonth(messages, handles, b.config)
+}
+
+// loadFragments reads the messages rendered by the last incremental run of the book at
+// outputPath, or returns nil when generation isn't incremental
+func (b *Builder) loadFragments(outputPath string) *output.FragmentCache {
+	if !b.config.Incremental {
+		return nil
+	}
+	cache, err := output.LoadFragmentCache(output.FragmentCachePath(outputPath))
+	if err != nil {
+		b.config.Log().Warn("⚠️  Rendering every message again", "error", err)
+	}
+	return cache
+}
+
+// saveFragments keeps the messages rendered by this run for the next, and reports how many
+// were reused
+func (b *Builder) saveFragments(cache *output.FragmentCache) {
+	// Formats that don't render TeX leave the cache unused
+	if cache.Hits()+cache.Misses() == 0 {
+		return
+	}
+	b.config.Log().Info("♻️  Reused rendered messages", "reused", cache.Hits(), "rendered", cache.Misses())
+	if err := cache.Save(); err != nil {
+		b.config.Log().Warn("⚠️  Could not save rendered messages", "error", err)
+	}
+}
+
+// reportTimings logs the stage breakdown and records it in the output's manifest
+func (b *Builder) reportTimings(outputPath string) {
+	if b.timings == nil {
+		return
+	}
+
+	logTimings(b.config.Log(), b.timings)
+	if err := manifest.RecordTimings(outputPath, b.timings); err != nil {
+		b.config.Log().Warn("⚠️  Could not record timings", "error", err)
+	}
+}
+
+// logTimings logs how long each recorded stage took and its share of the total, a record
+// per stage
+func logTimings(log *slog.Logger, timings *timing.Recorder) {
+	total := timings.Elapsed()
+	for _, stage := range timings.Stages() {
+		log.Info("⏱️  Timing", "stage", stage.Name, "duration", stage.Duration.Round(time.Millisecond),
+			"percent", math.Round(float64(stage.Duration)/float64(total)*1000)/10)
 	}
+	log.Info("⏱️  Timing", "stage", "total", "duration", total.Round(time.Millisecond))
+}
 
-	fmt.Printf("✅ Generated book: %s\n", filename)
+// redact applies the configured redaction profile to message text in place
+func (b *Builder) redact(messages []models.Message) error {
+	rules, err := redact.Profile(b.config.RedactProfile, b.config.RedactProfiles)
+	if err != nil {
+		return err
+	}
+
+	redactor, err := redact.New(rules)
+	if err != nil {
+		return err
+	}
+
+	count := redactor.Apply(messages)
+	b.config.Log().Info("🔒 Redacted", "items", count, "profile", b.config.RedactProfile)
 	return nil
 }
 
-// processAttachments loads attachment data for messages
-func (b *Builder) processAttachments(messages []models.Message) error {
+// processAttachments puts the attachments extract read on their messages and processes the
+// files concurrently
+func (b *Builder) processAttachments(messages []models.Message, attachmentsByMessage map[int][]models.Attachment) error {
+	// Attach the lists to their messages first so the workers update them in place
+	for i := range messages {
+		if !messages[i].HasAttachments {
+			continue
+		}
+		attachmentList, exists := attachmentsByMessage[messages[i].ID]
+		if !exists {
+			continue
+		}
+		// Contact and location cards were turned into text by describeCards
+		messages[i].Attachments = nil
+		for _, att := range attachmentList {
+			if !vcard.IsCard(att) {
+				messages[i].Attachments = append(messages[i].Attachments, att)
+			}
+		}
+	}
+
+	// The processor creates the processed folder recovered drawings are written to
 	processor := attachments.New(b.config)
-	attachmentCount := 0
-	imageCount := 0
+	b.addHandwriting(messages)
 
+	var work []*models.Attachment
 	for i := range messages {
-		if !messages[i].HasAttachments {
+		for j := range messages[i].Attachments {
+			work = append(work, &messages[i].Attachments[j])
+		}
+	}
+
+	pipeline := attachments.NewPipeline(processor, b.config.AttachmentWorkers, b.config.IncludeImages,
+		func(done, total int) {
+			if done%100 == 0 || done == total {
+				b.config.Log().Info("📎 Processing attachments", "done", done, "total", total)
+			}
+		})
+	result := pipeline.Run(work)
+
+	b.config.Log().Info("✅ Processed attachments", "count", result.AttachmentCount, "images", result.ImageCount)
+	b.report = &result.Report
+	if problems := result.Report.Problems(); problems > 0 {
+		b.config.Log().Warn("📋 Some attachments couldn't be included", "missing", len(result.Report.Missing),
+			"unreadable", len(result.Report.Unreadable), "only_named", len(result.Report.Unsupported))
+	}
+	if b.config.AttachmentReport != "" {
+		if err := result.Report.Save(b.config.AttachmentReport); err != nil {
+			return err
+		}
+		b.config.Log().Info("📋 Attachment report written", "path", b.config.AttachmentReport)
+	}
+	if result.LowResolution > 0 {
+		b.config.Log().Warn("🔍 Photos print below the target resolution and may look blurry", "photos", result.LowResolution, "target_dpi", b.config.Images.WithDefaults().TargetDPI)
+	}
+	return nil
+}
+
+// summarizeAppMessages replaces the empty or placeholder text of messages sent from iMessage
+// apps with a summary decoded from their payload
+func (b *Builder) summarizeAppMessages(messages []models.Message) {
+	for i := range messages {
+		msg := &messages[i]
+		if msg.BalloonBundleID == nil {
+			continue
+		}
+		appBundleID, ok := appmessage.AppBundleID(*msg.BalloonBundleID)
+		if !ok || (msg.Text != nil && strings.Trim(*msg.Text, " \n\uFFFC") != "") {
+			continue
+		}
+
+		payload, err := b.db.GetPayload(msg.ID)
+		if err != nil {
 			continue
 		}
+		summary := appmessage.Summarize(appBundleID, payload)
+		msg.Text = &summary
+	}
+}
 
-		attachmentList, err := b.db.GetAttachmentsForMessage(messages[i].ID)
+// decodeAttributedBodies gives messages without text the text archived in their
+// attributedBody. Bodies that can't be read leave their message without text.
+func (b *Builder) decodeAttributedBodies(messages []models.Message) error {
+	bodies, err := b.db.GetAttributedBodies()
+	if err != nil {
+		return err
+	}
+	decoded := 0
+	for i := range messages {
+		msg := &messages[i]
+		body, exists := bodies[msg.ID]
+		if !exists || (msg.Text != nil && *msg.Text != "") {
+			continue
+		}
+		text, err := typedstream.Text(body)
 		if err != nil {
-			return fmt.Errorf("failed to get attachments for message %d: %w", messages[i].ID, err)
+			b.config.Log().Debug("Could not read the text of a message", "guid", msg.GUID, "error", err)
+			continue
 		}
+		msg.Text = &text
+		decoded++
+	}
+	if decoded > 0 {
+		b.config.Log().Info("🔤 Decoded message text from attributedBody", "count", decoded)
+	}
+	return nil
+}
 
-		// Process each attachment
-		for j := range attachmentList {
-			att := &attachmentList[j]
+// describeCards adds the contact or place in each .vcf attachment, as Messages sends shared
+// contacts and locations, to its message's text. A card whose file is missing is still
+// named, from its file name.
+func (b *Builder) describeCards(messages []models.Message, attachmentsByMessage map[int][]models.Attachment) {
+	for i := range messages {
+		msg := &messages[i]
+		if !msg.HasAttachments {
+			continue
+		}
 
-			// Try to find the attachment file
-			err := processor.ProcessAttachment(att)
-			if err != nil {
-				fmt.Printf("⚠️  %v\n", err)
+		var lines []string
+		if msg.Text != nil {
+			if text := strings.TrimSpace(strings.ReplaceAll(*msg.Text, "\uFFFC", "")); text != "" {
+				lines = append(lines, text)
+			}
+		}
+		cards := 0
+		for _, att := range attachmentsByMessage[msg.ID] {
+			if !vcard.IsCard(att) {
 				continue
 			}
+			cards++
+			lines = append(lines, b.describeCard(att))
+		}
+		if cards > 0 {
+			text := strings.Join(lines, "\n")
+			msg.Text = &text
+		}
+	}
+}
 
-			attachmentCount++
+// describeCard reads one .vcf attachment, falling back to a label when it can't be read
+func (b *Builder) describeCard(att models.Attachment) string {
+	if att.Filename == nil {
+		return vcard.Label(att)
+	}
+	path, err := attachments.Locate(b.config.AttachmentsPath, att.GUID, *att.Filename)
+	if err != nil {
+		return vcard.Label(att)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return vcard.Label(att)
+	}
+	card, err := vcard.Parse(data)
+	if err != nil {
+		b.config.Log().Warn("⚠️  Could not read card", "file", filepath.Base(path), "error", err)
+		return vcard.Label(att)
+	}
+	return card.Summary()
+}
 
-			// If it's an image, process it for the book
-			if processor.IsImageFile(att) && b.config.IncludeImages {
-				err := processor.ProcessImage(att)
-				if err != nil {
-					fmt.Printf("⚠️  Failed to process image %s: %v\n", *att.Filename, err)
-				} else {
-					imageCount++
-				}
-			}
+// addHandwriting labels handwritten and Digital Touch messages, which have no text, and
+// attaches the drawing from each one's payload so it is placed like a photo
+func (b *Builder) addHandwriting(messages []models.Message) {
+	dir := filepath.Join(b.config.DerivedDir(), "processed")
+	for i := range messages {
+		msg := &messages[i]
+		if !msg.IsHandwritten() {
+			continue
 		}
 
-		messages[i].Attachments = attachmentList
+		if msg.Text == nil || strings.TrimSpace(*msg.Text) == "" || *msg.Text == "\uFFFC" {
+			label := handwriting.Label(*msg)
+			msg.Text = &label
+		}
+
+		payload, err := b.db.GetPayload(msg.ID)
+		if err != nil || len(payload) == 0 {
+			continue
+		}
+		guid := "handwriting-" + msg.GUID
+		path := filepath.Join(dir, guid+".png")
+		if err := handwriting.Extract(payload, path); err != nil {
+			b.config.Log().Warn("⚠️  Could not recover drawing", "guid", msg.GUID, "error", err)
+			continue
+		}
+
+		mimeType := "image/png"
+		msg.Attachments = append(msg.Attachments, models.Attachment{GUID: guid, Filename: &path, MimeType: &mimeType})
+		msg.HasAttachments = true
 	}
+}
 
-	fmt.Printf("✅ Processed %d attachments (%d images)\n", attachmentCount, imageCount)
-	return nil
+// addMemories converts the photos in the memories folder and merges them into messages by capture date
+func (b *Builder) addMemories(messages []models.Message) ([]models.Message, error) {
+	photos, err := memories.Scan(b.config.MemoriesPath, b.location)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert photos through the attachment pipeline so HEIC and resizing are handled the same way
+	atts := make([]models.Attachment, len(photos))
+	work := make([]*models.Attachment, len(photos))
+	for i := range photos {
+		path := photos[i].Path
+		atts[i] = models.Attachment{GUID: memories.AttachmentGUID(path), Filename: &path}
+		work[i] = &atts[i]
+	}
+
+	pipeline := attachments.NewPipeline(attachments.New(b.config), b.config.AttachmentWorkers, true, nil)
+	pipeline.Run(work)
+
+	// Drop photos that could not be converted rather than leaving empty pages
+	var ready []models.Memory
+	for i := range photos {
+		if atts[i].ProcessedPath == "" {
+			continue
+		}
+		photos[i].ProcessedPath = atts[i].ProcessedPath
+		ready = append(ready, photos[i])
+	}
+
+	b.config.Log().Info("✅ Added memories", "count", len(ready))
+	return memories.Insert(messages, ready), nil
 }
 
-// GetStats returns statistics about the messages
+// GetStats returns statistics about the messages of the book, read and filtered just as
+// generating it reads them
 func (b *Builder) GetStats() (*models.BookStats, error) {
-	messages, err := b.db.GetMessages()
+	messages, handles, _, _, err := b.extract()
 	if err != nil {
 		return nil, err
 	}
+	return b.bookStats(messages, handles, b.filterResults), nil
+}
 
-	handles, err := b.db.GetHandles(b.config.ContactNames)
+// Stats returns the statistics of the last book generated, or nil before one is
+func (b *Builder) Stats() *models.BookStats {
+	return b.stats
+}
+
+// GetDetailedStats returns the book statistics with a breakdown of messages per person,
+// attachments by kind and reactions by emoji, for the stats command
+func (b *Builder) GetDetailedStats() (*models.BookStats, *analytics.Breakdown, error) {
+	messages, handles, reactions, attachmentsByMessage, err := b.extract()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	stats := b.bookStats(messages, handles, b.filterResults)
 
+	for i := range messages {
+		messages[i].Attachments = attachmentsByMessage[messages[i].ID]
+	}
+	senderName := func(msg models.Message) string {
+		return output.GetSenderName(msg, handles, b.config)
+	}
+	return stats, analytics.Break(messages, reactions, senderName), nil
+}
+
+// bookStats counts the messages of the book
+func (b *Builder) bookStats(messages []models.Message, handles map[int]models.Handle, results []filter.Result) *models.BookStats {
 	stats := &models.BookStats{
-		TotalMessages:    len(messages),
-		TotalContacts:    len(handles),
-		AttachmentCount:  0,
+		TotalMessages:   len(messages),
+		TotalContacts:   len(handles),
+		AttachmentCount: 0,
 	}
 
 	// Count messages with text
@@ -205,11 +854,24 @@ func (b *Builder) GetStats() (*models.BookStats, error) {
 
 	stats.TextMessages = textMessages
 
+	for _, result := range results {
+		switch result.Name {
+		case "exclude_contacts":
+			stats.ExcludedByContact = result.Removed
+		case "exclude_keywords":
+			stats.ExcludedByKeyword = result.Removed
+		case "spam":
+			stats.ExcludedAsSpam = result.Removed
+		case "filter":
+			stats.ExcludedByFilter = result.Removed
+		}
+	}
+
 	// Find date range
 	if len(messages) > 0 {
 		stats.StartDate = messages[0].FormattedDate
 		stats.EndDate = messages[len(messages)-1].FormattedDate
 	}
 
-	return stats, nil
-}
\ No newline at end of file
+	return stats
+}
@@ -0,0 +1,39 @@
+package book
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"threadbound/internal/plugins/text"
+)
+
+// ChunkDirFor returns the folder the chunks of text output are written to: beside it,
+// named after it (book.txt -> book-chunks)
+func ChunkDirFor(textPath string) string {
+	return strings.TrimSuffix(textPath, filepath.Ext(textPath)) + "-chunks"
+}
+
+// writeChunks splits the text output written to path into files of about tokens each, in
+// order as 001.txt, 002.txt and so on in ChunkDirFor(path), and returns how many there are
+func writeChunks(path, output string, tokens int) (int, error) {
+	dir := ChunkDirFor(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create chunk folder: %w", err)
+	}
+	// Chunks from an earlier build may be past the end of this one
+	stale, _ := filepath.Glob(filepath.Join(dir, "*"+filepath.Ext(path)))
+	for _, file := range stale {
+		os.Remove(file)
+	}
+
+	chunks := text.Chunk(output, tokens)
+	for i, chunk := range chunks {
+		name := fmt.Sprintf("%03d%s", i+1, filepath.Ext(path))
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(chunk), 0644); err != nil {
+			return 0, fmt.Errorf("failed to write chunk %s: %w", name, err)
+		}
+	}
+	return len(chunks), nil
+}
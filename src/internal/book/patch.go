@@ -0,0 +1,101 @@
+package book
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"threadbound/internal/analytics"
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+	"threadbound/internal/plugins/tex"
+)
+
+// Patch regenerates the month chapters covering from up to (not including) to and splices
+// them into the TeX book at config.OutputPath, so a fix such as a contact's name doesn't
+// need a full rebuild. It returns the months replaced, keyed by analytics.ChapterKeyFormat.
+func (b *Builder) Patch(from, to time.Time) ([]string, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("--to must be after --from")
+	}
+	if !strings.EqualFold(b.detectFormat(), "tex") {
+		return nil, fmt.Errorf("only TeX books can be patched; pass the .tex file with --input and rebuild the PDF with build-pdf")
+	}
+	// Chapters are found again by month
+	if b.config.Chapters() != models.ChaptersByMonth {
+		return nil, fmt.Errorf("only books with month chapters can be patched; generate the book again in full")
+	}
+	// Overflow cross-references are numbered through the whole book
+	if b.config.OverflowLines > 0 {
+		return nil, fmt.Errorf("books with overflow_lines can't be patched; generate the book again in full")
+	}
+
+	book, split, err := readSplitBook(b.config.OutputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	months := make(map[string]bool)
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(to); month = month.AddDate(0, 1, 0) {
+		months[month.Format(analytics.ChapterKeyFormat)] = true
+	}
+
+	messages, handles, reactions, attachmentsByMessage, err := b.extract()
+	if err != nil {
+		return nil, err
+	}
+	messages = inMonths(messages, months)
+	b.config.Log().Info("🩹 Regenerating months", "months", len(months), "messages", len(messages))
+
+	b.config.Log().Info("📎 Processing attachments...")
+	if err := b.processAttachments(messages, attachmentsByMessage); err != nil {
+		return nil, fmt.Errorf("failed to process attachments: %w", err)
+	}
+	if b.config.MemoriesPath != "" {
+		b.config.Log().Info("🖼️  Adding memories...")
+		if messages, err = b.addMemories(messages); err != nil {
+			return nil, fmt.Errorf("failed to add memories: %w", err)
+		}
+		// Memories from other months belong to chapters that aren't being replaced
+		messages = inMonths(messages, months)
+	}
+
+	// Only the chapters are spliced, so skip the pages around them
+	patchConfig := *b.config
+	patchConfig.StatsChapter = false
+	patchConfig.CalendarPage = false
+	patchConfig.NotesPages.Count = 0
+
+	ctx := output.CreateContext(messages, handles, reactions, &patchConfig, nil)
+	ctx.Timings = b.timings
+	ctx.Summaries = b.summarize(messages, handles)
+	patch, _, err := output.New().Generate("tex", ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate chapters: %w", err)
+	}
+
+	result, replaced, err := tex.SpliceChapters(book, string(patch), months, b.config.Theme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to splice chapters into %s: %w", b.config.OutputPath, err)
+	}
+	if split {
+		_, err = writeSplitBook(b.config.OutputPath, result)
+		return replaced, err
+	}
+	if err := os.WriteFile(b.config.OutputPath, []byte(result), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write book: %w", err)
+	}
+	return replaced, nil
+}
+
+// inMonths keeps the messages sent in the given months
+func inMonths(messages []models.Message, months map[string]bool) []models.Message {
+	kept := make([]models.Message, 0, len(messages))
+	for _, msg := range messages {
+		if months[msg.FormattedDate.Format(analytics.ChapterKeyFormat)] {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
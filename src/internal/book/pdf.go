@@ -4,28 +4,71 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 
 	"threadbound/internal/latex"
+	"threadbound/internal/manifest"
 	"threadbound/internal/models"
+	"threadbound/internal/pdffile"
+	"threadbound/internal/timing"
 )
 
 // PDFBuilder handles PDF generation using XeLaTeX
 type PDFBuilder struct {
-	config        *models.BookConfig
+	config       *models.BookConfig
 	latexBuilder *latex.Builder
+	timings      *timing.Recorder
+	onlyChapters []string
 }
 
 // NewPDFBuilder creates a new PDF builder
 func NewPDFBuilder(config *models.BookConfig) *PDFBuilder {
+	timings := timing.New(config.Timings)
+	latexBuilder := latex.NewBuilder(config)
+	latexBuilder.SetTimings(timings)
+
 	return &PDFBuilder{
-		config:        config,
-		latexBuilder: latex.NewBuilder(config),
+		config:       config,
+		latexBuilder: latexBuilder,
+		timings:      timings,
 	}
 }
 
+// OnlyChapters limits the next build of a split book to the chapters of the given months,
+// keyed by analytics.ChapterKeyFormat, for quicker rebuilds while working on templates
+func (p *PDFBuilder) OnlyChapters(months []string) {
+	p.onlyChapters = months
+}
+
 // BuildPDF converts TeX to PDF using XeLaTeX
 func (p *PDFBuilder) BuildPDF(inputFile, outputFile string) error {
-	return p.latexBuilder.BuildPDF(inputFile, outputFile)
+	if len(p.onlyChapters) > 0 {
+		names, err := includedNames(inputFile, p.onlyChapters)
+		if err != nil {
+			return err
+		}
+		p.latexBuilder.SetIncludeOnly(names)
+	}
+
+	if err := p.latexBuilder.BuildPDF(inputFile, outputFile); err != nil {
+		return err
+	}
+
+	if p.timings != nil {
+		logTimings(p.config.Log(), p.timings)
+		// Record against the TeX source so generate and build-pdf share one manifest
+		if err := manifest.RecordTimings(inputFile, p.timings); err != nil {
+			p.config.Log().Warn("⚠️  Could not record timings", "error", err)
+		}
+	}
+	return nil
+}
+
+// PDFPathFor returns where the PDF built from a TeX file goes: beside it, as .pdf
+func PDFPathFor(texPath string) string {
+	return strings.TrimSuffix(texPath, filepath.Ext(texPath)) + ".pdf"
 }
 
 // GetPDFInfo returns information about the generated PDF
@@ -45,6 +88,27 @@ func (p *PDFBuilder) GetPDFInfo(pdfPath string) (*models.PDFInfo, error) {
 		CreatedAt:  fileInfo.ModTime(),
 		PageWidth:  p.config.PageWidth,
 		PageHeight: p.config.PageHeight,
+		PageCount:  p.latexBuilder.PageCount(),
+		Title:      p.config.Title,
+		Author:     p.config.Author,
+	}
+
+	// The PDF's own metadata, read from the file
+	if document, err := pdffile.Read(pdfPath); err == nil {
+		if document.Title != "" {
+			info.Title = document.Title
+		}
+		if document.Author != "" {
+			info.Author = document.Author
+		}
+		info.Producer = document.Producer
+		info.CreationDate = document.CreationDate
+		for _, font := range document.Fonts {
+			info.Fonts = append(info.Fonts, models.PDFFont{Name: font.Name, Embedded: font.Embedded})
+		}
+		if info.PageCount == 0 {
+			info.PageCount = document.Pages
+		}
 	}
 
 	return info, nil
@@ -52,20 +116,23 @@ func (p *PDFBuilder) GetPDFInfo(pdfPath string) (*models.PDFInfo, error) {
 
 // PreviewCommand returns the command to open the PDF for preview
 func (p *PDFBuilder) PreviewCommand(pdfPath string) string {
-	// macOS
-	if _, err := exec.LookPath("open"); err == nil {
-		return fmt.Sprintf("open %s", pdfPath)
+	switch runtime.GOOS {
+	case "darwin":
+		return fmt.Sprintf(`open "%s"`, pdfPath)
+	case "windows":
+		// start treats the first quoted argument as a window title
+		return fmt.Sprintf(`start "" "%s"`, pdfPath)
 	}
 
-	// Linux
+	// Linux and other Unix desktops
 	if _, err := exec.LookPath("xdg-open"); err == nil {
-		return fmt.Sprintf("xdg-open %s", pdfPath)
+		return fmt.Sprintf(`xdg-open "%s"`, pdfPath)
 	}
 
-	// Windows (if running under WSL or similar)
-	if _, err := exec.LookPath("cmd.exe"); err == nil {
-		return fmt.Sprintf("cmd.exe /c start %s", pdfPath)
+	// Windows Subsystem for Linux
+	if _, err := exec.LookPath("wslview"); err == nil {
+		return fmt.Sprintf(`wslview "%s"`, pdfPath)
 	}
 
 	return fmt.Sprintf("Please open %s with your PDF viewer", pdfPath)
-}
\ No newline at end of file
+}
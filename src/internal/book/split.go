@@ -0,0 +1,83 @@
+package book
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"threadbound/internal/plugins/tex"
+)
+
+// ChapterDirFor returns the folder a split TeX book keeps its chapter files in: beside
+// it, named after it (book.tex -> book-chapters)
+func ChapterDirFor(texPath string) string {
+	return strings.TrimSuffix(texPath, filepath.Ext(texPath)) + "-chapters"
+}
+
+// writeSplitBook writes book to path as a master file that includes each month chapter
+// from its own file in ChapterDirFor(path), and returns how many chapters were written
+func writeSplitBook(path, book string) (int, error) {
+	dir := ChapterDirFor(path)
+	master, chapters, err := tex.SplitChapters(book, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create chapter folder: %w", err)
+	}
+	// Chapters from an earlier build may be for months no longer in the book
+	stale, _ := filepath.Glob(filepath.Join(dir, "*.tex"))
+	for _, file := range stale {
+		os.Remove(file)
+	}
+
+	for key, chapter := range chapters {
+		if err := os.WriteFile(filepath.Join(dir, key+".tex"), []byte(chapter), 0644); err != nil {
+			return 0, fmt.Errorf("failed to write chapter %s: %w", key, err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(master), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write output file: %w", err)
+	}
+	return len(chapters), nil
+}
+
+// readSplitBook reads the TeX book at path with the chapters of a split book put back in
+// place, and reports whether it was split
+func readSplitBook(path string) (string, bool, error) {
+	master, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read book: %w", err)
+	}
+	return tex.JoinChapters(string(master), func(name string) (string, error) {
+		chapter, err := os.ReadFile(filepath.FromSlash(name) + ".tex")
+		return string(chapter), err
+	})
+}
+
+// includedNames maps months to the names the split book at path includes their chapters by
+func includedNames(path string, months []string) ([]string, error) {
+	master, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read book: %w", err)
+	}
+	included, err := tex.IncludedChapters(string(master))
+	if err != nil {
+		return nil, err
+	}
+	if len(included) == 0 {
+		return nil, fmt.Errorf("%s isn't split into chapter files; generate it with --split-chapters", path)
+	}
+
+	names := make([]string, len(months))
+	for i, month := range months {
+		name, exists := included[month]
+		if !exists {
+			return nil, fmt.Errorf("the book has no chapter for %s (use YYYY-MM)", month)
+		}
+		names[i] = name
+	}
+	return names, nil
+}
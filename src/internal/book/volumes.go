@@ -0,0 +1,116 @@
+package book
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"threadbound/internal/manifest"
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+	"threadbound/internal/printing"
+)
+
+// Volume is one of the books a conversation was split into
+type Volume struct {
+	Number    int
+	Path      string
+	From, To  time.Time
+	Messages  int
+	FirstPage int
+	Pages     int  // Typeset page count, or an estimate when Estimated
+	Estimated bool // Pages is estimated because the volume wasn't typeset
+}
+
+// VolumePath returns where a volume of the book at path is written (book.tex -> book-vol2.tex)
+func VolumePath(path string, number int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-vol%d%s", strings.TrimSuffix(path, ext), number, ext)
+}
+
+// Volumes returns the volumes written by the last generation, or nil if the book wasn't split
+func (b *Builder) Volumes() []Volume {
+	return b.volumes
+}
+
+// AfterVolume calls fn with each volume's path as soon as it's written, such as to build its
+// PDF. fn returns the volume's page count, which continued numbering starts the next
+// volume after; 0 falls back to an estimate.
+func (b *Builder) AfterVolume(fn func(path string) (int, error)) {
+	b.afterVolume = fn
+}
+
+// generateVolumes writes each part of the conversation as a book of its own, with its own
+// title and copyright pages
+func (b *Builder) generateVolumes(format string, parts [][]models.Message, handles map[int]models.Handle,
+	reactions map[string][]models.Reaction, stats *models.BookStats, chatNames map[int]string, chapterSummaries map[string]string) error {
+	b.config.Log().Info("📚 Splitting the book into volumes", "volumes", len(parts))
+
+	generator := output.New()
+	b.volumes = nil
+	b.pageCount = 0
+	nextPage := 1
+	for i, part := range parts {
+		volumeConfig := *b.config
+		volumeConfig.OutputPath = VolumePath(b.config.OutputPath, i+1)
+
+		ctx := output.CreateContext(part, handles, reactions, &volumeConfig, stats)
+		ctx.Timings = b.timings
+		ctx.ChatNames = chatNames
+		ctx.Summaries = chapterSummaries
+		ctx.Fragments = b.loadFragments(volumeConfig.OutputPath)
+		ctx.Volume = &output.Volume{
+			Number:    i + 1,
+			Count:     len(parts),
+			From:      part[0].FormattedDate,
+			To:        part[len(part)-1].FormattedDate,
+			FirstPage: 1,
+		}
+		if b.config.Volumes.Numbering == models.NumberingContinue {
+			ctx.Volume.FirstPage = nextPage
+		}
+
+		b.config.Log().Info("📝 Generating volume", "volume", i+1, "volumes", len(parts), "range", ctx.Volume.Range(), "messages", len(part))
+		stopRendering := b.timings.Start(fmt.Sprintf("rendering volume %d", i+1))
+		data, filename, err := generator.Generate(format, ctx)
+		stopRendering()
+		if err != nil {
+			return fmt.Errorf("failed to generate volume %d: %w", i+1, err)
+		}
+		if err := b.write(format, filename, data); err != nil {
+			return err
+		}
+		b.saveFragments(ctx.Fragments)
+		b.config.Log().Info("✅ Generated volume", "volume", i+1, "path", filename)
+
+		volume := Volume{
+			Number:    i + 1,
+			Path:      filename,
+			From:      ctx.Volume.From,
+			To:        ctx.Volume.To,
+			Messages:  len(part),
+			FirstPage: ctx.Volume.FirstPage,
+			Pages:     ctx.PageCount,
+		}
+		if b.afterVolume != nil {
+			if volume.Pages, err = b.afterVolume(filename); err != nil {
+				return fmt.Errorf("volume %d: %w", i+1, err)
+			}
+		}
+		if volume.Pages == 0 {
+			volume.Pages = printing.EstimatePages(part, b.config.IncludeImages)
+			volume.Estimated = true
+		}
+		nextPage += volume.Pages
+		b.pageCount += volume.Pages
+		b.volumes = append(b.volumes, volume)
+
+		if err := manifest.RecordFilters(filename, b.filterResults); err != nil {
+			b.config.Log().Warn("⚠️  Could not record filter results", "error", err)
+		}
+	}
+
+	b.reportTimings(b.volumes[0].Path)
+	return nil
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"threadbound/internal/models"
+)
+
+// ListChats returns every conversation with its participants, message count and the dates
+// of its first and last messages, busiest first. Reactions aren't counted.
+func (db *DB) ListChats() ([]models.Chat, error) {
+	query := `
+		SELECT
+			c.ROWID, COALESCE(c.chat_identifier, ''), COALESCE(c.display_name, ''),
+			COUNT(m.ROWID), MIN(m.date), MAX(m.date)
+		FROM chat c
+		LEFT JOIN chat_message_join cmj ON cmj.chat_id = c.ROWID
+		LEFT JOIN message m ON m.ROWID = cmj.message_id AND m.associated_message_guid IS NULL
+		GROUP BY c.ROWID
+	`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chats: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []models.Chat
+	byID := make(map[int]int)
+	for rows.Next() {
+		var chat models.Chat
+		var first, last sql.NullInt64
+		if err := rows.Scan(&chat.ID, &chat.Identifier, &chat.DisplayName, &chat.MessageCount, &first, &last); err != nil {
+			return nil, fmt.Errorf("failed to scan chat: %w", err)
+		}
+		if first.Valid {
+			chat.FirstMessage = db.timeOf(first.Int64)
+			chat.LastMessage = db.timeOf(last.Int64)
+		}
+		byID[chat.ID] = len(chats)
+		chats = append(chats, chat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	handleRows, err := db.conn.Query(`SELECT chat_id, handle_id FROM chat_handle_join ORDER BY chat_id, handle_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat participants: %w", err)
+	}
+	defer handleRows.Close()
+
+	for handleRows.Next() {
+		var chatID, handleID int
+		if err := handleRows.Scan(&chatID, &handleID); err != nil {
+			return nil, fmt.Errorf("failed to scan chat participant: %w", err)
+		}
+		if i, exists := byID[chatID]; exists {
+			chats[i].HandleIDs = append(chats[i].HandleIDs, handleID)
+		}
+	}
+	if err := handleRows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(chats, func(i, j int) bool { return chats[i].MessageCount > chats[j].MessageCount })
+	return chats, nil
+}
+
+// ChatPredicate is a GetMessagesWhere predicate keeping only the messages in one chat
+func ChatPredicate(chatID int) (string, []interface{}) {
+	return "m.ROWID IN (SELECT message_id FROM chat_message_join WHERE chat_id = ?)", []interface{}{chatID}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// ownerQueries find the address the owner's messages were sent from, most trusted first.
+// destination_caller_id is the owner's own phone number or Apple ID on each message; older
+// databases only have the account the message went through, stored as e:<Apple ID> or
+// p:<phone number>. Databases without a column just skip its query.
+var ownerQueries = []string{
+	`SELECT destination_caller_id FROM message
+		WHERE is_from_me = 1 AND COALESCE(destination_caller_id, '') != ''
+		GROUP BY destination_caller_id ORDER BY COUNT(*) DESC LIMIT 1`,
+	`SELECT account FROM message
+		WHERE is_from_me = 1 AND COALESCE(account, '') != ''
+		GROUP BY account ORDER BY COUNT(*) DESC LIMIT 1`,
+	`SELECT account_login FROM chat
+		WHERE COALESCE(account_login, '') != ''
+		GROUP BY account_login ORDER BY COUNT(*) DESC LIMIT 1`,
+}
+
+// DetectOwner returns the phone number or Apple ID of the database's owner, as recorded
+// on the messages they sent, or an empty string when the database doesn't say
+func (db *DB) DetectOwner() string {
+	for _, query := range ownerQueries {
+		var owner sql.NullString
+		if err := db.conn.QueryRow(query).Scan(&owner); err != nil {
+			continue
+		}
+		if address := trimAccountPrefix(owner.String); address != "" {
+			return address
+		}
+	}
+	return ""
+}
+
+// trimAccountPrefix strips the e: or p: an account is stored with
+func trimAccountPrefix(account string) string {
+	account = strings.TrimSpace(account)
+	if len(account) > 2 && account[1] == ':' && strings.ContainsRune("eEpP", rune(account[0])) {
+		return account[2:]
+	}
+	return account
+}
@@ -0,0 +1,39 @@
+package database
+
+import "time"
+
+// Schema is the part of chat.db that threadbound reads. Importers create it to convert
+// other message exports into a database the book builder can open.
+const Schema = `
+	CREATE TABLE handle (ROWID INTEGER PRIMARY KEY, id TEXT, country TEXT, service TEXT);
+	CREATE TABLE message (
+		ROWID INTEGER PRIMARY KEY, guid TEXT, text TEXT, date INTEGER, date_read INTEGER,
+		date_delivered INTEGER, is_from_me INTEGER DEFAULT 0, is_delivered INTEGER DEFAULT 1,
+		is_read INTEGER DEFAULT 1, handle_id INTEGER, cache_has_attachments INTEGER DEFAULT 0,
+		subject TEXT, is_audio_message INTEGER DEFAULT 0, associated_message_guid TEXT,
+		associated_message_type INTEGER DEFAULT 0, item_type INTEGER DEFAULT 0, payload_data BLOB,
+		expressive_send_style_id TEXT, balloon_bundle_id TEXT, account TEXT, destination_caller_id TEXT,
+		associated_message_emoji TEXT, attributedBody BLOB
+	);
+	CREATE TABLE attachment (
+		ROWID INTEGER PRIMARY KEY, guid TEXT, filename TEXT, uti TEXT, mime_type TEXT,
+		total_bytes INTEGER DEFAULT 0, is_sticker INTEGER DEFAULT 0, is_outgoing INTEGER DEFAULT 0
+	);
+	CREATE TABLE message_attachment_join (message_id INTEGER, attachment_id INTEGER);
+	CREATE TABLE chat (
+		ROWID INTEGER PRIMARY KEY, guid TEXT, chat_identifier TEXT, display_name TEXT, account_login TEXT
+	);
+	CREATE TABLE chat_handle_join (chat_id INTEGER, handle_id INTEGER);
+	CREATE TABLE chat_message_join (chat_id INTEGER, message_id INTEGER);
+`
+
+// AppleEpoch is where chat.db dates count from, in nanoseconds
+var AppleEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Nullable stores empty strings as NULL, as chat.db does for missing text
+func Nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// HasWAL reports whether the database at path has a non-empty write-ahead log beside it.
+// Messages keeps chat.db in WAL mode, so recent messages may live only in chat.db-wal
+// until SQLite checkpoints them into the main file.
+func HasWAL(path string) bool {
+	info, err := os.Stat(path + "-wal")
+	return err == nil && info.Size() > 0
+}
+
+// IsLive reports whether path is the chat.db Messages itself uses, which may be locked or
+// written to while it's read
+func IsLive(path string) bool {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return absPath == filepath.Join(home, "Library", "Messages", "chat.db")
+}
+
+// NeedsSnapshot reports whether the database should be read through a Snapshot rather than
+// in place: it's the live database, or has changes still in its write-ahead log
+func NeedsSnapshot(path string) bool {
+	return IsLive(path) || HasWAL(path)
+}
+
+// PermissionHint explains how to let threadbound read a database the system won't let it
+func PermissionHint() string {
+	if runtime.GOOS == "darwin" {
+		return "Give your terminal Full Disk Access in System Settings > Privacy & Security, or copy chat.db somewhere readable"
+	}
+	return "Make the file readable by your user, or copy it somewhere readable"
+}
+
+// accessError describes a database that can't be read, with how to fix it when access
+// was refused
+func accessError(path string, err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("cannot read %s: %w. %s", path, err, PermissionHint())
+	}
+	return fmt.Errorf("cannot read %s: %w", path, err)
+}
+
+// Snapshot writes a consistent copy of the database at path, including any changes still
+// in its write-ahead log, to a single file in a temporary directory. The live database is
+// opened read-only and copied with VACUUM INTO inside one read transaction, so writes made
+// while the snapshot runs can't leave it half-updated. Call cleanup to remove the copy.
+func Snapshot(path string) (snapshotPath string, cleanup func(), err error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", nil, err
+	}
+	// SQLite only says it can't open the file; find out why first
+	file, err := os.Open(absPath)
+	if err != nil {
+		return "", nil, accessError(path, err)
+	}
+	file.Close()
+
+	dir, err := os.MkdirTemp("", "threadbound-db-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	snapshotPath = filepath.Join(dir, filepath.Base(path))
+
+	source := url.URL{Scheme: "file", Path: filepath.ToSlash(absPath), RawQuery: "mode=ro"}
+	if err := vacuumInto(source.String(), snapshotPath); err != nil {
+		// Reading a WAL database in place needs its shared-memory file, which a locked or
+		// read-only Messages folder may not give us; read a copy of the files instead
+		os.Remove(snapshotPath)
+		if copyErr := snapshotFromCopy(absPath, filepath.Join(dir, "copy"), snapshotPath); copyErr != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to snapshot database: %w (copying it failed too: %v)", err, copyErr)
+		}
+	}
+
+	return snapshotPath, cleanup, nil
+}
+
+// vacuumInto writes the database at source, a path or file: URI, to snapshotPath
+func vacuumInto(source, snapshotPath string) error {
+	conn, err := sql.Open("sqlite", source)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Exec(`VACUUM INTO ?`, snapshotPath)
+	return err
+}
+
+// snapshotFromCopy copies the database at path and its write-ahead log into dir, then
+// writes the copy, with the log applied, to snapshotPath. The shared-memory file is left
+// behind: SQLite rebuilds it from the log, where a stale copy could hide recent messages.
+// The files are copied one after another, so unlike VACUUM INTO this can miss a write
+// made meanwhile.
+func snapshotFromCopy(path, dir, snapshotPath string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	copyPath := filepath.Join(dir, filepath.Base(path))
+	for _, suffix := range []string{"", "-wal"} {
+		if err := copyFile(path+suffix, copyPath+suffix); err != nil {
+			if suffix != "" && os.IsNotExist(err) {
+				continue
+			}
+			return accessError(path+suffix, err)
+		}
+	}
+	return vacuumInto(copyPath, snapshotPath)
+}
+
+// copyFile copies the file at src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotIncludesWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.db")
+	live, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer live.Close()
+
+	// Keep the writer open so the inserts stay in the write-ahead log, as with Messages running
+	setup := `PRAGMA journal_mode=WAL; PRAGMA wal_autocheckpoint=0;` + Schema + `
+		INSERT INTO message (ROWID, guid, text, date) VALUES (1, 'm1', 'old', 1), (2, 'm2', 'new', 2);
+	`
+	if _, err := live.GetConnection().Exec(setup); err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if !HasWAL(path) {
+		t.Fatal("Expected the test database to have a write-ahead log")
+	}
+
+	snapshotPath, cleanup, err := Snapshot(path)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	snapshot, err := New(snapshotPath)
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	messages, err := snapshot.GetMessages()
+	snapshot.Close()
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Errorf("Expected both messages in the snapshot, got %d", len(messages))
+	}
+
+	cleanup()
+	if _, err := os.Stat(snapshotPath); !os.IsNotExist(err) {
+		t.Error("Expected cleanup to remove the snapshot")
+	}
+}
+
+func TestHasWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.db")
+	if HasWAL(path) {
+		t.Error("Expected no WAL for a missing database")
+	}
+
+	if err := os.WriteFile(path+"-wal", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if HasWAL(path) {
+		t.Error("Expected an empty WAL to be ignored")
+	}
+}
+
+func TestSnapshotFromCopy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chat.db")
+	live, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer live.Close()
+	setup := `PRAGMA journal_mode=WAL; PRAGMA wal_autocheckpoint=0;` + Schema + `
+		INSERT INTO message (ROWID, guid, text, date) VALUES (1, 'm1', 'old', 1), (2, 'm2', 'new', 2);
+	`
+	if _, err := live.GetConnection().Exec(setup); err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+
+	snapshotPath := filepath.Join(dir, "snapshot.db")
+	if err := snapshotFromCopy(path, filepath.Join(dir, "copy"), snapshotPath); err != nil {
+		t.Fatalf("snapshotFromCopy failed: %v", err)
+	}
+	snapshot, err := New(snapshotPath)
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer snapshot.Close()
+	if messages, err := snapshot.GetMessages(); err != nil || len(messages) != 2 {
+		t.Errorf("Expected both messages from the copied log, got %d: %v", len(messages), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "copy")); !os.IsNotExist(err) {
+		t.Error("Expected the copied files removed")
+	}
+}
+
+func TestNeedsSnapshot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if !NeedsSnapshot(filepath.Join(home, "Library", "Messages", "chat.db")) {
+		t.Error("Expected the live database to need a snapshot")
+	}
+	if NeedsSnapshot(filepath.Join(home, "Desktop", "chat.db")) {
+		t.Error("Expected a copied database without a log to be read in place")
+	}
+}
+
+func TestAccessError(t *testing.T) {
+	err := accessError("chat.db", &os.PathError{Op: "open", Path: "chat.db", Err: os.ErrPermission})
+	if !errors.Is(err, os.ErrPermission) || !strings.Contains(err.Error(), PermissionHint()) {
+		t.Errorf("Expected a refused database to say how to allow it, got %v", err)
+	}
+	if err := accessError("chat.db", os.ErrNotExist); strings.Contains(err.Error(), PermissionHint()) {
+		t.Errorf("Expected no permission hint for a missing file, got %v", err)
+	}
+}
@@ -3,20 +3,30 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
-	"threadbound/internal/models"
 	_ "modernc.org/sqlite"
+	"threadbound/internal/models"
 )
 
 // DB wraps the SQLite database connection
 type DB struct {
-	conn *sql.DB
+	conn     *sql.DB
+	location *time.Location // Zone message dates are returned in
 }
 
 // New creates a new database connection
 func New(dbPath string) (*DB, error) {
+	// SQLite only says it can't open a file it isn't allowed to read; say why instead
+	if file, err := os.Open(dbPath); err == nil {
+		file.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, accessError(dbPath, err)
+	}
+
 	conn, err := sql.Open("sqlite", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -26,7 +36,18 @@ func New(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{conn: conn}, nil
+	return &DB{conn: conn, location: time.Local}, nil
+}
+
+// SetLocation sets the time zone message dates are returned in, the local zone until it's
+// set, so dates fall on the days they were sent there
+func (db *DB) SetLocation(loc *time.Location) {
+	db.location = loc
+}
+
+// timeOf converts a chat.db date, in nanoseconds since 2001, to a time in the DB's zone
+func (db *DB) timeOf(date int64) time.Time {
+	return AppleEpoch.Add(time.Duration(date)).In(db.location)
 }
 
 // Close closes the database connection
@@ -41,18 +62,48 @@ func (db *DB) GetConnection() *sql.DB {
 
 // GetMessages retrieves all messages ordered by date, excluding reactions
 func (db *DB) GetMessages() ([]models.Message, error) {
+	return db.GetMessagesWhere("")
+}
+
+// CountMessages returns how many messages GetMessagesWhere would retrieve for the predicate
+func (db *DB) CountMessages(where string, args ...interface{}) (int, error) {
+	if where != "" {
+		where = "AND (" + where + ")"
+	}
+
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM message m WHERE m.associated_message_guid IS NULL `+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+	return count, nil
+}
+
+// GetMessagesWhere retrieves the messages matching an extra SQL predicate on the message
+// table, aliased m, such as one compiled from a filter query. An empty predicate matches
+// every message.
+func (db *DB) GetMessagesWhere(where string, args ...interface{}) ([]models.Message, error) {
+	if where != "" {
+		where = "AND (" + where + ")"
+	}
+	// Databases made by other tools may not record which chat messages are in
+	chatColumn := "0"
+	if db.hasColumn("chat_message_join", "chat_id") {
+		chatColumn = "COALESCE((SELECT MIN(cmj.chat_id) FROM chat_message_join cmj WHERE cmj.message_id = m.ROWID), 0)"
+	}
 	query := `
 		SELECT
 			m.ROWID, m.guid, m.text, m.date, m.date_read, m.date_delivered,
 			m.is_from_me, m.is_delivered, m.is_read, m.handle_id,
 			m.cache_has_attachments, m.subject, m.is_audio_message,
-			m.associated_message_guid, m.associated_message_type, m.item_type
+			m.associated_message_guid, m.associated_message_type, m.item_type,
+			m.expressive_send_style_id, m.balloon_bundle_id, ` + chatColumn + `
 		FROM message m
-		WHERE m.associated_message_guid IS NULL
+		WHERE m.associated_message_guid IS NULL ` + where + `
 		ORDER BY m.date ASC
 	`
 
-	rows, err := db.conn.Query(query)
+	rows, err := db.conn.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query messages: %w", err)
 	}
@@ -66,15 +117,13 @@ func (db *DB) GetMessages() ([]models.Message, error) {
 			&msg.IsFromMe, &msg.IsDelivered, &msg.IsRead, &msg.HandleID,
 			&msg.HasAttachments, &msg.Subject, &msg.IsAudioMessage,
 			&msg.AssociatedMessageGUID, &msg.AssociatedMessageType, &msg.ItemType,
+			&msg.ExpressiveSendStyleID, &msg.BalloonBundleID, &msg.ChatID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
 
-		// Convert Apple's timestamp to Go time
-		// Apple uses seconds since January 1, 2001
-		appleEpoch := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
-		msg.FormattedDate = appleEpoch.Add(time.Duration(msg.Date) * time.Nanosecond)
+		msg.FormattedDate = db.timeOf(msg.Date)
 
 		messages = append(messages, msg)
 	}
@@ -116,6 +165,103 @@ func (db *DB) GetAttachmentsForMessage(messageID int) ([]models.Attachment, erro
 	return attachments, rows.Err()
 }
 
+// GetAllAttachments retrieves every message attachment in one query, keyed by message ID
+func (db *DB) GetAllAttachments() (map[int][]models.Attachment, error) {
+	query := `
+		SELECT
+			maj.message_id, a.ROWID, a.guid, a.filename, a.uti, a.mime_type,
+			a.total_bytes, a.is_sticker, a.is_outgoing
+		FROM attachment a
+		JOIN message_attachment_join maj ON a.ROWID = maj.attachment_id
+		ORDER BY maj.message_id ASC, a.ROWID ASC
+	`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := make(map[int][]models.Attachment)
+	for rows.Next() {
+		var messageID int
+		var att models.Attachment
+		err := rows.Scan(
+			&messageID, &att.ID, &att.GUID, &att.Filename, &att.UTI, &att.MimeType,
+			&att.TotalBytes, &att.IsSticker, &att.IsOutgoing,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments[messageID] = append(attachments[messageID], att)
+	}
+
+	return attachments, rows.Err()
+}
+
+// GetPayloadData retrieves the raw rich link payload (an NSKeyedArchiver plist) for every message that has one
+func (db *DB) GetPayloadData() (map[int][]byte, error) {
+	rows, err := db.conn.Query(`SELECT ROWID, payload_data FROM message WHERE payload_data IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payload data: %w", err)
+	}
+	defer rows.Close()
+
+	payloads := make(map[int][]byte)
+	for rows.Next() {
+		var id int
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan payload data: %w", err)
+		}
+		if len(data) > 0 {
+			payloads[id] = data
+		}
+	}
+
+	return payloads, rows.Err()
+}
+
+// GetAttributedBodies retrieves the raw attributedBody (a typedstream NSAttributedString) of
+// every message without text that has one, as newer versions of Messages keep the text
+// only there. Databases without the column have none.
+func (db *DB) GetAttributedBodies() (map[int][]byte, error) {
+	bodies := make(map[int][]byte)
+	if !db.hasColumn("message", "attributedBody") {
+		return bodies, nil
+	}
+
+	rows, err := db.conn.Query(`SELECT ROWID, attributedBody FROM message
+		WHERE (text IS NULL OR text = '') AND attributedBody IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attributed bodies: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan attributed body: %w", err)
+		}
+		if len(data) > 0 {
+			bodies[id] = data
+		}
+	}
+
+	return bodies, rows.Err()
+}
+
+// GetPayload retrieves the raw payload_data of one message, or nil if it has none
+func (db *DB) GetPayload(messageID int) ([]byte, error) {
+	var data []byte
+	err := db.conn.QueryRow(`SELECT payload_data FROM message WHERE ROWID = ?`, messageID).Scan(&data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payload data: %w", err)
+	}
+	return data, nil
+}
+
 // GetHandles retrieves all contact handles
 func (db *DB) GetHandles(contactNames map[string]string) (map[int]models.Handle, error) {
 	query := `
@@ -170,10 +316,15 @@ func (db *DB) GetHandles(contactNames map[string]string) (map[int]models.Handle,
 
 // GetReactions retrieves all reactions keyed by the original message GUID
 func (db *DB) GetReactions(handles map[int]models.Handle) (map[string][]models.Reaction, error) {
+	// Newer versions of Messages keep the emoji of a custom emoji tapback in its own column
+	emojiColumn := "NULL"
+	if db.hasColumn("message", "associated_message_emoji") {
+		emojiColumn = "m.associated_message_emoji"
+	}
 	query := `
 		SELECT
 			m.associated_message_guid, m.associated_message_type, m.date,
-			m.handle_id, m.is_from_me
+			m.handle_id, m.is_from_me, m.text, ` + emojiColumn + `
 		FROM message m
 		WHERE m.associated_message_guid IS NOT NULL
 		ORDER BY m.date ASC
@@ -192,8 +343,9 @@ func (db *DB) GetReactions(handles map[int]models.Handle) (map[string][]models.R
 		var date int64
 		var handleID *int
 		var isFromMe bool
+		var text, emoji *string
 
-		err := rows.Scan(&associatedGUID, &reactionType, &date, &handleID, &isFromMe)
+		err := rows.Scan(&associatedGUID, &reactionType, &date, &handleID, &isFromMe, &text, &emoji)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan reaction: %w", err)
 		}
@@ -208,9 +360,7 @@ func (db *DB) GetReactions(handles map[int]models.Handle) (map[string][]models.R
 			}
 		}
 
-		// Convert Apple's timestamp to Go time
-		appleEpoch := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
-		timestamp := appleEpoch.Add(time.Duration(date) * time.Nanosecond)
+		timestamp := db.timeOf(date)
 
 		// Determine sender name
 		var senderName string
@@ -231,8 +381,9 @@ func (db *DB) GetReactions(handles map[int]models.Handle) (map[string][]models.R
 		reaction := models.Reaction{
 			Type:          reactionType,
 			SenderName:    senderName,
+			IsFromMe:      isFromMe,
 			Timestamp:     timestamp,
-			ReactionEmoji: reactionTypeToEmoji(reactionType),
+			ReactionEmoji: reactionEmoji(reactionType, emoji, text),
 		}
 
 		reactions[originalGUID] = append(reactions[originalGUID], reaction)
@@ -241,6 +392,33 @@ func (db *DB) GetReactions(handles map[int]models.Handle) (map[string][]models.R
 	return reactions, rows.Err()
 }
 
+// customReactionPattern finds the emoji in the text Messages gives a custom emoji tapback,
+// such as "Reacted 🎉 to “See you there”"
+var customReactionPattern = regexp.MustCompile(`^Reacted (.+?) to `)
+
+// reactionEmoji returns the emoji a reaction shows. Custom emoji tapbacks show the emoji
+// that was picked, taken from its column or else from the reaction's text.
+func reactionEmoji(reactionType int, emoji, text *string) string {
+	if reactionType == int(models.ReactionCustom) {
+		if emoji != nil && strings.TrimSpace(*emoji) != "" {
+			return strings.TrimSpace(*emoji)
+		}
+		if text != nil {
+			if match := customReactionPattern.FindStringSubmatch(*text); match != nil {
+				return match[1]
+			}
+		}
+	}
+	return reactionTypeToEmoji(reactionType)
+}
+
+// hasColumn reports whether a table has a column, for columns older databases lack
+func (db *DB) hasColumn(table, column string) bool {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	return err == nil && count > 0
+}
+
 // reactionTypeToEmoji converts iMessage reaction types to Unicode emoji
 func reactionTypeToEmoji(reactionType int) string {
 	switch reactionType {
@@ -255,8 +433,6 @@ func reactionTypeToEmoji(reactionType int) string {
 	case 2004:
 		return "‼️" // Emphasize/!!
 	case 2005:
-		return "❤️" // Love (alternative)
-	case 2006:
 		return "❓" // Question/?
 	default:
 		return "❤️" // Default fallback
@@ -281,4 +457,4 @@ func (db *DB) GetChatInfo() (*models.Handle, error) {
 	return &models.Handle{
 		DisplayName: fmt.Sprintf("Group Chat (%d messages)", count),
 	}, nil
-}
\ No newline at end of file
+}
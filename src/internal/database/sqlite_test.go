@@ -0,0 +1,256 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestDB creates a chat.db-shaped database in a temp dir and runs the given setup SQL
+func newTestDB(t *testing.T, setup string) *DB {
+	t.Helper()
+
+	db, err := New(filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.GetConnection().Exec(Schema + setup); err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	return db
+}
+
+func TestGetAllAttachments(t *testing.T) {
+	db := newTestDB(t, `
+		INSERT INTO message (ROWID, guid, text, date, cache_has_attachments) VALUES
+			(1, 'm1', 'photos', 0, 1), (2, 'm2', 'no files', 0, 0), (3, 'm3', 'doc', 0, 1);
+		INSERT INTO attachment (ROWID, guid, filename, mime_type) VALUES
+			(10, 'a10', '~/Library/Messages/Attachments/a/1.jpg', 'image/jpeg'),
+			(11, 'a11', '~/Library/Messages/Attachments/a/2.jpg', 'image/jpeg'),
+			(12, 'a12', '~/Library/Messages/Attachments/b/3.pdf', 'application/pdf');
+		INSERT INTO message_attachment_join (message_id, attachment_id) VALUES (1, 11), (1, 10), (3, 12);
+	`)
+
+	attachments, err := db.GetAllAttachments()
+	if err != nil {
+		t.Fatalf("GetAllAttachments failed: %v", err)
+	}
+
+	if len(attachments) != 2 {
+		t.Fatalf("Expected attachments for 2 messages, got %d", len(attachments))
+	}
+	if len(attachments[1]) != 2 || attachments[1][0].GUID != "a10" || attachments[1][1].GUID != "a11" {
+		t.Errorf("Expected message 1 to have a10, a11 in order, got %+v", attachments[1])
+	}
+	if len(attachments[3]) != 1 || *attachments[3][0].MimeType != "application/pdf" {
+		t.Errorf("Expected message 3 to have one PDF, got %+v", attachments[3])
+	}
+	if _, exists := attachments[2]; exists {
+		t.Error("Did not expect attachments for message 2")
+	}
+}
+
+func TestGetPayloadData(t *testing.T) {
+	db := newTestDB(t, `
+		INSERT INTO message (ROWID, guid, text, date, payload_data) VALUES
+			(1, 'm1', 'https://example.com', 0, x'62706c6973743030'),
+			(2, 'm2', 'plain', 0, NULL),
+			(3, 'm3', 'empty', 0, x'');
+	`)
+
+	payloads, err := db.GetPayloadData()
+	if err != nil {
+		t.Fatalf("GetPayloadData failed: %v", err)
+	}
+
+	if len(payloads) != 1 {
+		t.Fatalf("Expected 1 payload, got %d", len(payloads))
+	}
+	if string(payloads[1]) != "bplist00" {
+		t.Errorf("Expected message 1 payload to be returned unchanged, got %q", payloads[1])
+	}
+}
+
+func TestGetAttributedBodies(t *testing.T) {
+	db := newTestDB(t, `
+		INSERT INTO message (ROWID, guid, text, date, attributedBody) VALUES
+			(1, 'm1', NULL, 0, x'73747265616d7479706564'),
+			(2, 'm2', 'has text', 0, x'73747265616d7479706564'),
+			(3, 'm3', '', 0, x'0102'),
+			(4, 'm4', NULL, 0, NULL);
+	`)
+
+	bodies, err := db.GetAttributedBodies()
+	if err != nil {
+		t.Fatalf("GetAttributedBodies failed: %v", err)
+	}
+	if len(bodies) != 2 || string(bodies[1]) != "streamtyped" || len(bodies[3]) != 2 {
+		t.Errorf("Expected the bodies of messages 1 and 3 without text, got %q", bodies)
+	}
+}
+
+func TestGetMessagesSendEffect(t *testing.T) {
+	db := newTestDB(t, `
+		INSERT INTO message (ROWID, guid, text, date, expressive_send_style_id) VALUES
+			(1, 'm1', 'surprise', 0, 'com.apple.messages.effect.CKConfettiEffect'),
+			(2, 'm2', 'plain', 1, NULL),
+			(3, 'm3', 'future', 2, 'com.apple.messages.effect.CKUnreleasedEffect');
+	`)
+
+	messages, err := db.GetMessages()
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+
+	for i, want := range []string{"Confetti 🎉", "", ""} {
+		if got := messages[i].GetSendEffect(); got != want {
+			t.Errorf("Message %s: expected effect %q, got %q", messages[i].GUID, want, got)
+		}
+	}
+}
+
+func TestListChats(t *testing.T) {
+	db := newTestDB(t, `
+		INSERT INTO handle (ROWID, id, country, service) VALUES (1, '+15551234567', 'us', 'iMessage'), (2, 'sam@example.com', 'us', 'iMessage');
+		INSERT INTO chat (ROWID, chat_identifier, display_name) VALUES (7, 'chat123', 'Camping Crew'), (8, '+15551234567', NULL), (9, 'old', NULL);
+		INSERT INTO chat_handle_join (chat_id, handle_id) VALUES (7, 2), (7, 1), (8, 1);
+		INSERT INTO message (ROWID, guid, text, date, handle_id, associated_message_guid) VALUES
+			(1, 'm1', 'Tents?', 1, 1, NULL), (2, 'm2', 'Hi', 2, 1, NULL), (3, 'm3', 'Hey', 3, 1, NULL),
+			(4, 'r1', NULL, 4, 1, 'p:0/m1');
+		INSERT INTO chat_message_join (chat_id, message_id) VALUES (7, 1), (7, 4), (8, 2), (8, 3);
+	`)
+
+	chats, err := db.ListChats()
+	if err != nil {
+		t.Fatalf("ListChats failed: %v", err)
+	}
+	if len(chats) != 3 {
+		t.Fatalf("Expected 3 chats, got %+v", chats)
+	}
+
+	// Busiest first, with reactions left out of the count
+	if chats[0].ID != 8 || chats[0].MessageCount != 2 || chats[0].DisplayName != "" {
+		t.Errorf("Unexpected first chat %+v", chats[0])
+	}
+	if chats[1].ID != 7 || chats[1].MessageCount != 1 || len(chats[1].HandleIDs) != 2 || chats[1].HandleIDs[0] != 1 {
+		t.Errorf("Unexpected second chat %+v", chats[1])
+	}
+
+	// Dates span the chat's messages; a chat without any has none
+	if !chats[0].FirstMessage.Equal(AppleEpoch.Add(2)) || !chats[0].LastMessage.Equal(AppleEpoch.Add(3)) {
+		t.Errorf("Unexpected date range %v to %v", chats[0].FirstMessage, chats[0].LastMessage)
+	}
+	if chats[2].ID != 9 || chats[2].MessageCount != 0 || !chats[2].FirstMessage.IsZero() {
+		t.Errorf("Unexpected empty chat %+v", chats[2])
+	}
+
+	where, args := ChatPredicate(7)
+	messages, err := db.GetMessagesWhere(where, args...)
+	if err != nil || len(messages) != 1 || messages[0].GUID != "m1" {
+		t.Errorf("Expected only the chat's message, got %+v, %v", messages, err)
+	}
+}
+
+func TestDetectOwner(t *testing.T) {
+	db := newTestDB(t, `
+		INSERT INTO message (ROWID, guid, date, is_from_me, account, destination_caller_id) VALUES
+			(1, 'm1', 0, 1, 'e:jordan@icloud.com', '+15557654321'),
+			(2, 'm2', 0, 1, 'e:jordan@icloud.com', '+15557654321'),
+			(3, 'm3', 0, 1, 'e:jordan@icloud.com', 'jordan@icloud.com'),
+			(4, 'm4', 0, 0, 'e:jordan@icloud.com', 'jordan@icloud.com'),
+			(5, 'm5', 0, 0, 'e:jordan@icloud.com', 'jordan@icloud.com');
+	`)
+	if owner := db.DetectOwner(); owner != "+15557654321" {
+		t.Errorf("Expected the address most sent from, got %q", owner)
+	}
+
+	// Older databases only record the account
+	old, err := New(filepath.Join(t.TempDir(), "old.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	_, err = old.GetConnection().Exec(`
+		CREATE TABLE message (ROWID INTEGER PRIMARY KEY, is_from_me INTEGER, account TEXT);
+		INSERT INTO message (is_from_me, account) VALUES (1, 'p:+15557654321'), (0, NULL);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner := old.DetectOwner(); owner != "+15557654321" {
+		t.Errorf("Expected the account without its prefix, got %q", owner)
+	}
+
+	if owner := newTestDB(t, "").DetectOwner(); owner != "" {
+		t.Errorf("Expected no owner in an empty database, got %q", owner)
+	}
+}
+
+func TestGetReactionsCustomEmoji(t *testing.T) {
+	db := newTestDB(t, `
+		INSERT INTO message (ROWID, guid, text, date, is_from_me, associated_message_guid, associated_message_type, associated_message_emoji) VALUES
+			(1, 'm1', 'See you there', 0, 0, NULL, 0, NULL),
+			(2, 'r1', 'Loved “See you there”', 1, 1, 'p:0/m1', 2000, NULL),
+			(3, 'r2', 'Reacted 🎉 to “See you there”', 2, 1, 'p:0/m1', 2006, '🎉'),
+			(4, 'r3', 'Reacted 🫶🏽 to “See you there”', 3, 1, 'p:0/m1', 2006, NULL),
+			(5, 'r4', 'Questioned “See you there”', 4, 1, 'p:0/m1', 2005, NULL);
+	`)
+
+	reactions, err := db.GetReactions(nil)
+	if err != nil {
+		t.Fatalf("GetReactions failed: %v", err)
+	}
+	var got []string
+	for _, reaction := range reactions["m1"] {
+		got = append(got, reaction.ReactionEmoji)
+	}
+	want := []string{"❤️", "🎉", "🫶🏽", "❓"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected reactions %v, got %v", want, got)
+	}
+
+	// Databases from before custom emoji tapbacks don't have the emoji column
+	if _, err := db.GetConnection().Exec("ALTER TABLE message DROP COLUMN associated_message_emoji"); err != nil {
+		t.Fatalf("Failed to drop column: %v", err)
+	}
+	reactions, err = db.GetReactions(nil)
+	if err != nil {
+		t.Fatalf("GetReactions without the emoji column failed: %v", err)
+	}
+	if emoji := reactions["m1"][1].ReactionEmoji; emoji != "🎉" {
+		t.Errorf("Expected the emoji read from the text, got %q", emoji)
+	}
+}
+
+func TestGetMessagesLocation(t *testing.T) {
+	db := newTestDB(t, "")
+	sent := time.Date(2023, 3, 9, 3, 30, 0, 0, time.UTC)
+	if _, err := db.GetConnection().Exec(`INSERT INTO message (ROWID, guid, text, date) VALUES (1, 'm1', 'late', ?)`,
+		sent.Sub(AppleEpoch).Nanoseconds()); err != nil {
+		t.Fatal(err)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+	db.SetLocation(newYork)
+	messages, err := db.GetMessages()
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+
+	date := messages[0].FormattedDate
+	if !date.Equal(sent) || date.Location() != newYork {
+		t.Errorf("Expected %v in New York, got %v", sent, date)
+	}
+	if date.Day() != 8 || date.Hour() != 22 {
+		t.Errorf("Expected a text sent at 3:30 UTC to fall on the evening before in New York, got %v", date)
+	}
+}
@@ -0,0 +1,390 @@
+// Package debugbundle collects what's needed to diagnose a bug report into a zip that
+// can be shared without giving away the conversation: no message text, names, contact
+// details or paths leave the machine
+package debugbundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"threadbound/internal/doctor"
+	"threadbound/internal/manifest"
+	"threadbound/internal/models"
+	"threadbound/internal/plugins/html"
+	"threadbound/internal/plugins/tex"
+	"threadbound/internal/redact"
+)
+
+// DefaultOutput is where the bundle is written when no path is given
+const DefaultOutput = "threadbound-debug.zip"
+
+// Redacted stands in for personal values in the bundle
+const Redacted = "[redacted]"
+
+// Paths inside a bundle
+const (
+	ReadmeFile      = "README.txt"
+	EnvironmentFile = "environment.json"
+	ConfigFile      = "config.yaml"
+	TemplatesFile   = "templates.json"
+	SchemaFile      = "schema.json"
+	ManifestFile    = "manifest.json"
+	MessagesFile    = "messages.json"
+	LogsDir         = "logs"
+)
+
+// Options selects what goes into a bundle
+type Options struct {
+	Config     *models.BookConfig
+	Output     string   // Zip to write (default: threadbound-debug.zip)
+	MessageIDs []int    // ROWIDs of messages that render wrongly, sampled alongside any found automatically
+	Logs       []string // Saved output of earlier runs, scrubbed before they're added
+}
+
+// Environment describes the machine and the result of threadbound doctor
+type Environment struct {
+	CreatedAt time.Time `json:"created_at"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	GoVersion string    `json:"go_version"`
+	Checks    []Check   `json:"checks"`
+}
+
+// Check is one doctor check
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // pass, warn or fail
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// Template is the checksum of one template, so edited or outdated copies can be spotted
+type Template struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Source string `json:"source"`         // built-in or template_dir
+	Note   string `json:"note,omitempty"` // How a template_dir copy compares with the built-in one
+}
+
+// Create writes a bundle and returns the paths of the files in it
+func Create(opts Options) ([]string, error) {
+	if opts.Output == "" {
+		opts.Output = DefaultOutput
+	}
+	scrub := newScrubber(opts.Config)
+
+	out, err := os.Create(opts.Output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle: %w", err)
+	}
+	zw := zip.NewWriter(out)
+	w := &writer{zip: zw}
+
+	w.writeText(ReadmeFile, readme)
+	w.writeJSON(EnvironmentFile, environment(opts.Config, scrub))
+	w.writeYAML(ConfigFile, SanitizeConfig(opts.Config))
+	w.writeJSON(TemplatesFile, templateHashes(opts.Config.TemplateDir))
+
+	if schema, err := ReadSchema(opts.Config.DatabasePath); err == nil {
+		w.writeJSON(SchemaFile, schema)
+	} else {
+		w.writeText(SchemaFile+".error.txt", scrub.Scrub(err.Error())+"\n")
+	}
+
+	// The manifest records timings and filter counts, and nothing from the messages
+	if m, err := manifest.Load(manifest.PathFor(opts.Config.OutputPath)); err == nil && m.Output != "" {
+		m.Output = filepath.Base(m.Output)
+		w.writeJSON(ManifestFile, m)
+	}
+
+	for i, path := range opts.Logs {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			w.err = fmt.Errorf("failed to read log %s: %w", path, err)
+			break
+		}
+		w.writeText(fmt.Sprintf("%s/%d-%s", LogsDir, i+1, scrub.Scrub(filepath.Base(path))), scrub.Scrub(string(data)))
+	}
+
+	if samples, err := SampleMessages(opts.Config.DatabasePath, opts.MessageIDs); err == nil {
+		w.writeJSON(MessagesFile, samples)
+	} else {
+		w.writeText(MessagesFile+".error.txt", scrub.Scrub(err.Error())+"\n")
+	}
+
+	if w.err == nil {
+		w.err = zw.Close()
+	}
+	if closeErr := out.Close(); w.err == nil {
+		w.err = closeErr
+	}
+	if w.err != nil {
+		os.Remove(opts.Output)
+		return nil, fmt.Errorf("failed to write bundle: %w", w.err)
+	}
+	return w.files, nil
+}
+
+// readme explains the bundle to whoever opens it, including the person sharing it
+const readme = `threadbound debug bundle
+
+This bundle helps diagnose a problem without sharing your conversation:
+
+  environment.json  Operating system and the checks threadbound doctor runs
+  config.yaml       Your settings, with names, the dedication, avatars, keywords, filters and paths redacted
+  templates.json    Checksums of the built-in templates and any custom ones
+  schema.json       The database schema version, tables, columns and row counts
+  manifest.json     Timings and filter counts from the last generate, if any
+  messages.json     The shape of sampled messages: letters become x or X and digits 0,
+                    so layout problems show without the words
+  logs/             Logs you added, with emails, phone numbers, URLs, names and paths removed
+
+Nothing here contains message text, attachments or contact details, but please look
+through it before sharing.
+`
+
+// statusNames spell out doctor statuses in environment.json
+var statusNames = map[doctor.Status]string{doctor.Pass: "pass", doctor.Warn: "warn", doctor.Fail: "fail"}
+
+// environment describes the machine, with doctor's details scrubbed of paths
+func environment(config *models.BookConfig, scrub *scrubber) Environment {
+	var checks []Check
+	for _, check := range doctor.Run(config) {
+		checks = append(checks, Check{
+			Name:   check.Name,
+			Status: statusNames[check.Status],
+			Detail: scrub.Scrub(check.Detail),
+			Fix:    scrub.Scrub(check.Fix),
+		})
+	}
+	return Environment{
+		CreatedAt: time.Now().UTC(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		Checks:    checks,
+	}
+}
+
+// SanitizeConfig returns a copy of config that's safe to share. Names, the dedication,
+// contacts, avatars, keywords, redaction words and the filter query are replaced with
+// Redacted or a count; paths keep only their last element.
+func SanitizeConfig(config *models.BookConfig) models.BookConfig {
+	c := *config
+	c.Logger = nil
+
+	for _, field := range []*string{&c.Title, &c.Author, &c.MyName, &c.Filter, &c.Dedication} {
+		if *field != "" {
+			*field = Redacted
+		}
+	}
+	for _, path := range []*string{&c.DatabasePath, &c.AttachmentsPath, &c.OutputPath, &c.TemplateDir, &c.MemoriesPath,
+		&c.ForewordPath, &c.WorkDir, &c.AttachmentReport} {
+		if *path != "" {
+			*path = filepath.Base(*path)
+		}
+	}
+
+	c.ContactNames = redactMap(c.ContactNames)
+	c.Avatars = redactMap(c.Avatars)
+	c.ExcludeContacts = redactList(c.ExcludeContacts)
+	c.ExcludeKeywords = redactList(c.ExcludeKeywords)
+
+	if c.RedactProfiles != nil {
+		profiles := make(map[string]models.RedactRules, len(c.RedactProfiles))
+		for name, rules := range c.RedactProfiles {
+			rules.Words = redactList(rules.Words)
+			rules.Patterns = redactList(rules.Patterns)
+			profiles[name] = rules
+		}
+		c.RedactProfiles = profiles
+	}
+	return c
+}
+
+// redactList keeps how many values there are, but not the values
+func redactList(values []string) []string {
+	if len(values) == 0 {
+		return values
+	}
+	redacted := make([]string, len(values))
+	for i := range redacted {
+		redacted[i] = Redacted
+	}
+	return redacted
+}
+
+// redactMap replaces contact IDs and names, and what they map to, with numbered placeholders
+func redactMap(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return values
+	}
+	redacted := make(map[string]string, len(values))
+	for i := 1; i <= len(values); i++ {
+		redacted[fmt.Sprintf("contact %d", i)] = Redacted
+	}
+	return redacted
+}
+
+// templateHashes checksums the built-in TeX and HTML templates and any in templateDir.
+// Built-in TeX templates are preferred when a name exists in both, while HTML ones in
+// templateDir replace the built-in ones; the notes point out which differ.
+func templateHashes(templateDir string) []Template {
+	builtin := make(map[string]string)
+	var templates []Template
+	for _, embedded := range []fs.FS{tex.Templates(), html.Templates()} {
+		fs.WalkDir(embedded, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := fs.ReadFile(embedded, path)
+			if err != nil {
+				return err
+			}
+			builtin[path] = checksum(data)
+			templates = append(templates, Template{Name: path, SHA256: builtin[path], Source: "built-in"})
+			return nil
+		})
+	}
+
+	if templateDir != "" {
+		entries, _ := os.ReadDir(templateDir)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(templateDir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			t := Template{Name: entry.Name(), SHA256: checksum(data), Source: "template_dir"}
+			switch hash, exists := builtin[entry.Name()]; {
+			case !exists:
+				t.Note = "not a built-in template"
+			case hash == t.SHA256:
+				t.Note = "same as built-in"
+			default:
+				t.Note = "differs from built-in"
+			}
+			templates = append(templates, t)
+		}
+	}
+
+	sort.SliceStable(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// checksum returns the hex SHA-256 of data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Patterns scrubbed from logs beyond what the contacts redaction profile finds
+var (
+	urlRegex  = regexp.MustCompile(`(?i)\b(?:https?|file)://\S+`)
+	pathRegex = regexp.MustCompile(`(?:~|[A-Za-z]:)?(?:[/\\][^\s/\\:'"]+){2,}`)
+)
+
+// scrubber removes personal details from free text such as logs
+type scrubber struct {
+	redactor *redact.Redactor
+}
+
+// newScrubber masks emails, phone numbers and every name and keyword in config
+func newScrubber(config *models.BookConfig) *scrubber {
+	rules := models.RedactRules{Emails: true, Phones: true}
+	rules.Words = append(rules.Words, config.Title, config.Author, config.MyName, config.Dedication)
+	for id, name := range config.ContactNames {
+		rules.Words = append(rules.Words, id, name)
+	}
+	for contact := range config.Avatars {
+		rules.Words = append(rules.Words, contact)
+	}
+	rules.Words = append(rules.Words, config.ExcludeContacts...)
+	rules.Words = append(rules.Words, config.ExcludeKeywords...)
+	for _, profile := range config.RedactProfiles {
+		rules.Words = append(rules.Words, profile.Words...)
+	}
+	// Longer phrases go first so a name inside a title doesn't leave the rest behind
+	sort.SliceStable(rules.Words, func(i, j int) bool { return len(rules.Words[i]) > len(rules.Words[j]) })
+
+	redactor, _ := redact.New(rules)
+	return &scrubber{redactor: redactor}
+}
+
+// Scrub removes URLs, paths apart from their last element, and personal details from text
+func (s *scrubber) Scrub(text string) string {
+	text = urlRegex.ReplaceAllString(text, "[url]")
+	text = pathRegex.ReplaceAllStringFunc(text, func(path string) string {
+		return ".../" + filepath.Base(filepath.FromSlash(strings.ReplaceAll(path, `\`, "/")))
+	})
+	text, _ = s.redactor.Redact(text)
+	return text
+}
+
+// writer adds files to the zip, remembering the first error
+type writer struct {
+	zip   *zip.Writer
+	files []string
+	err   error
+}
+
+// write adds one file to the bundle
+func (w *writer) write(name string, fill func(io.Writer) error) {
+	if w.err != nil {
+		return
+	}
+	out, err := w.zip.Create(name)
+	if err == nil {
+		err = fill(out)
+	}
+	if err != nil {
+		w.err = fmt.Errorf("failed to write %s: %w", name, err)
+		return
+	}
+	w.files = append(w.files, name)
+}
+
+// writeText adds a text file
+func (w *writer) writeText(name, text string) {
+	w.write(name, func(out io.Writer) error {
+		_, err := io.WriteString(out, text)
+		return err
+	})
+}
+
+// writeJSON adds value as indented JSON
+func (w *writer) writeJSON(name string, value interface{}) {
+	w.write(name, func(out io.Writer) error {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	})
+}
+
+// writeYAML adds value as YAML
+func (w *writer) writeYAML(name string, value interface{}) {
+	w.write(name, func(out io.Writer) error {
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		return encoder.Close()
+	})
+}
@@ -0,0 +1,137 @@
+package debugbundle
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestSanitizeConfig(t *testing.T) {
+	config := &models.BookConfig{
+		Title:            "Ana & Me",
+		MyName:           "Sam",
+		DatabasePath:     "/Users/sam/Library/Messages/chat.db",
+		ContactNames:     map[string]string{"+15551234567": "Ana"},
+		ExcludeKeywords:  []string{"surprise party"},
+		RedactProfiles:   map[string]models.RedactRules{"family": {Emails: true, Words: []string{"Grandma"}}},
+		Filter:           "from:Ana",
+		PageWidth:        "6in",
+		Dedication:       "For Ana, who kept every text",
+		Avatars:          models.Avatars{"Ana": "/Users/sam/Pictures/ana.jpg"},
+		ForewordPath:     "/Users/sam/Documents/foreword.md",
+		WorkDir:          "/Users/sam/tmp/threadbound",
+		AttachmentReport: "/Users/sam/Desktop/report.json",
+	}
+
+	c := SanitizeConfig(config)
+	if c.Title != Redacted || c.MyName != Redacted || c.Filter != Redacted || c.Author != "" {
+		t.Errorf("Expected names and the filter redacted, got %q %q %q %q", c.Title, c.MyName, c.Filter, c.Author)
+	}
+	if c.DatabasePath != "chat.db" {
+		t.Errorf("Expected only the file name, got %q", c.DatabasePath)
+	}
+	if c.ContactNames["contact 1"] != Redacted || len(c.ContactNames) != 1 {
+		t.Errorf("Expected contacts replaced, got %v", c.ContactNames)
+	}
+	if c.ExcludeKeywords[0] != Redacted || c.RedactProfiles["family"].Words[0] != Redacted || !c.RedactProfiles["family"].Emails {
+		t.Errorf("Expected keywords and words redacted but rules kept, got %v %+v", c.ExcludeKeywords, c.RedactProfiles)
+	}
+	if c.Dedication != Redacted || c.Avatars["contact 1"] != Redacted || len(c.Avatars) != 1 {
+		t.Errorf("Expected the dedication and avatars redacted, got %q %v", c.Dedication, c.Avatars)
+	}
+	if c.ForewordPath != "foreword.md" || c.WorkDir != "threadbound" || c.AttachmentReport != "report.json" {
+		t.Errorf("Expected only the last element of paths, got %q %q %q", c.ForewordPath, c.WorkDir, c.AttachmentReport)
+	}
+	if c.PageWidth != "6in" {
+		t.Errorf("Expected layout settings kept, got %q", c.PageWidth)
+	}
+
+	// The original config is untouched
+	if config.ContactNames["+15551234567"] != "Ana" || config.RedactProfiles["family"].Words[0] != "Grandma" || config.Avatars["Ana"] == "" {
+		t.Error("SanitizeConfig modified the config it was given")
+	}
+}
+
+func TestScrub(t *testing.T) {
+	scrub := newScrubber(&models.BookConfig{
+		Title:        "Ana and Sam",
+		ContactNames: map[string]string{"ana@example.com": "Ana"},
+	})
+	got := scrub.Scrub("Warning: Ana's photo /Users/sam/Pictures/IMG_1.heic failed; see https://example.com/x or call 555-123-4567\n")
+	want := "Warning: [redacted]'s photo .../IMG_1.heic failed; see [url] or call [redacted]\n"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := scrub.Scrub("Title: Ana and Sam"); got != "Title: [redacted]" {
+		t.Errorf("Expected the whole title redacted, got %q", got)
+	}
+
+	scrub = newScrubber(&models.BookConfig{Dedication: "For Bea", Avatars: models.Avatars{"bea@example.org": "bea.jpg", "Cy": "cy.jpg"}})
+	if got := scrub.Scrub("For Bea; avatar for Cy and bea@example.org"); got != "[redacted]; avatar for [redacted] and [redacted]" {
+		t.Errorf("Expected the dedication and avatar contacts redacted, got %q", got)
+	}
+}
+
+func TestTemplateHashes(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "sent-message.tex"), []byte("custom"), 0644)
+	os.WriteFile(filepath.Join(dir, "extra.tex"), []byte("extra"), 0644)
+
+	notes := make(map[string]string)
+	builtin := 0
+	for _, template := range templateHashes(dir) {
+		if template.Source == "built-in" {
+			builtin++
+			continue
+		}
+		notes[template.Name] = template.Note
+	}
+	if builtin == 0 {
+		t.Error("Expected the built-in templates hashed")
+	}
+	if notes["sent-message.tex"] != "differs from built-in" || notes["extra.tex"] != "not a built-in template" {
+		t.Errorf("Unexpected notes %v", notes)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dbPath := createDatabase(t, `INSERT INTO message (ROWID, guid, text, date) VALUES (1, 'a', 'Secret plans with Ana', 0), (2, 'b', '', 0);`)
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "run.log")
+	os.WriteFile(logPath, []byte("Generating Ana's book from "+dbPath+"\n"), 0644)
+
+	output := filepath.Join(dir, "debug.zip")
+	files, err := Create(Options{
+		Config:     &models.BookConfig{DatabasePath: dbPath, OutputPath: filepath.Join(dir, "book.tex"), ContactNames: map[string]string{"+15551234567": "Ana"}},
+		Output:     output,
+		MessageIDs: []int{1},
+		Logs:       []string{logPath},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(files) != 7 {
+		t.Errorf("Expected 7 files without a manifest, got %v", files)
+	}
+
+	zr, err := zip.OpenReader(output)
+	if err != nil {
+		t.Fatalf("Failed to open bundle: %v", err)
+	}
+	defer zr.Close()
+	for _, file := range zr.File {
+		f, _ := file.Open()
+		data, _ := io.ReadAll(f)
+		f.Close()
+		for _, secret := range []string{"Secret", "plans", "Ana", "+15551234567", dir} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains %q", file.Name, secret)
+			}
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package debugbundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// MaxSuspicious caps how many messages are sampled automatically
+const MaxSuspicious = 20
+
+// maxShape caps how much of a long message's shape is kept
+const maxShape = 2000
+
+// Schema describes the database's structure without any of its rows
+type Schema struct {
+	UserVersion   int     `json:"user_version"`             // PRAGMA user_version
+	ClientVersion string  `json:"client_version,omitempty"` // Messages' own version, from _SqliteDatabaseProperties
+	Tables        []Table `json:"tables"`
+}
+
+// Table is one table's columns and row count
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int      `json:"rows"`
+}
+
+// Sample is a message reduced to what matters for rendering: its metadata, attachment
+// types and the shape of its text
+type Sample struct {
+	ID                    int          `json:"id"`
+	Reasons               []string     `json:"reasons"` // Why it was sampled
+	Date                  *time.Time   `json:"date,omitempty"`
+	IsFromMe              bool         `json:"is_from_me"`
+	ItemType              int          `json:"item_type"`
+	AssociatedMessageType int          `json:"associated_message_type"`
+	BalloonBundleID       string       `json:"balloon_bundle_id,omitempty"`
+	Effect                string       `json:"effect,omitempty"`
+	IsAudioMessage        bool         `json:"is_audio_message,omitempty"`
+	HasSubject            bool         `json:"has_subject,omitempty"`
+	HasAttachments        bool         `json:"has_attachments"`
+	Text                  *TextShape   `json:"text,omitempty"`
+	Attachments           []FileSample `json:"attachments,omitempty"`
+}
+
+// TextShape keeps the layout of message text but not its words: letters become x or X,
+// digits 0, and characters that often render wrongly are spelled out as <U+XXXX>.
+// Whitespace, punctuation, symbols and emoji are kept as they are.
+type TextShape struct {
+	Runes     int      `json:"runes"`
+	Lines     int      `json:"lines"`
+	Shape     string   `json:"shape"`
+	Truncated bool     `json:"truncated,omitempty"`
+	Scripts   []string `json:"scripts,omitempty"` // Writing systems of the letters, e.g. Latin or Cyrillic
+}
+
+// FileSample describes an attachment without its name or content
+type FileSample struct {
+	MimeType  string `json:"mime_type,omitempty"`
+	UTI       string `json:"uti,omitempty"`
+	Extension string `json:"extension,omitempty"`
+	Bytes     int64  `json:"bytes"`
+	Sticker   bool   `json:"sticker,omitempty"`
+}
+
+// openExisting opens a database, refusing to create one that doesn't exist
+func openExisting(path string) (*database.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("database %s: %w", path, err)
+	}
+	return database.New(path)
+}
+
+// ReadSchema lists the database's version, tables, columns and row counts
+func ReadSchema(path string) (*Schema, error) {
+	db, err := openExisting(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	conn := db.GetConnection()
+
+	schema := &Schema{}
+	if err := conn.QueryRow("PRAGMA user_version").Scan(&schema.UserVersion); err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	rows, err := conn.Query("SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	for _, name := range names {
+		table := Table{Name: name}
+		quoted := `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		if err := conn.QueryRow("SELECT COUNT(*) FROM " + quoted).Scan(&table.Rows); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %w", name, err)
+		}
+
+		columns, err := conn.Query("SELECT name FROM pragma_table_info(?)", name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", name, err)
+		}
+		for columns.Next() {
+			var column string
+			if err := columns.Scan(&column); err != nil {
+				columns.Close()
+				return nil, err
+			}
+			table.Columns = append(table.Columns, column)
+		}
+		columns.Close()
+		schema.Tables = append(schema.Tables, table)
+
+		// Messages records its schema version here rather than in user_version
+		if name == "_SqliteDatabaseProperties" {
+			conn.QueryRow("SELECT value FROM _SqliteDatabaseProperties WHERE key = '_ClientVersion'").Scan(&schema.ClientVersion)
+		}
+	}
+	return schema, nil
+}
+
+// SampleMessages samples the messages with the given ROWIDs, then up to MaxSuspicious
+// more that look likely to render wrongly
+func SampleMessages(path string, ids []int) ([]Sample, error) {
+	db, err := openExisting(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	messages, err := db.GetMessages()
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[int]bool)
+	for _, id := range ids {
+		requested[id] = true
+	}
+
+	var samples []Sample
+	found := make(map[int]bool)
+	suspicious := 0
+	for _, msg := range messages {
+		var reasons []string
+		if requested[msg.ID] {
+			reasons = append(reasons, "requested")
+			found[msg.ID] = true
+		}
+		if problems := Suspicious(msg); len(problems) > 0 && suspicious < MaxSuspicious {
+			reasons = append(reasons, problems...)
+			suspicious++
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		sample := sampleMessage(msg, reasons)
+		if msg.HasAttachments {
+			attachments, err := db.GetAttachmentsForMessage(msg.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, att := range attachments {
+				sample.Attachments = append(sample.Attachments, sampleAttachment(att))
+			}
+		}
+		samples = append(samples, sample)
+	}
+
+	// Reactions and other messages the book never reads can't be sampled
+	for _, id := range ids {
+		if !found[id] {
+			samples = append(samples, Sample{ID: id, Reasons: []string{"requested", "not found among the book's messages"}})
+			found[id] = true
+		}
+	}
+	return samples, nil
+}
+
+// Suspicious explains why a message might render wrongly, or returns nothing
+func Suspicious(msg models.Message) []string {
+	text := ""
+	if msg.Text != nil {
+		text = *msg.Text
+	}
+
+	var reasons []string
+	if strings.TrimSpace(text) == "" && !msg.HasAttachments && msg.BalloonBundleID == nil && !msg.IsAudioMessage {
+		reasons = append(reasons, "no text or attachments")
+	}
+	if strings.Contains(text, "\uFFFC") && !msg.HasAttachments {
+		reasons = append(reasons, "attachment placeholder without an attachment")
+	}
+
+	var control, private, unassigned bool
+	for _, r := range text {
+		switch {
+		case r == '\n' || r == '\t' || r == '\r':
+		case unicode.IsControl(r):
+			control = true
+		case unicode.Is(unicode.Co, r):
+			private = true
+		case !isAssigned(r):
+			unassigned = true
+		}
+	}
+	if control {
+		reasons = append(reasons, "control characters")
+	}
+	if private {
+		reasons = append(reasons, "private use characters")
+	}
+	if unassigned {
+		reasons = append(reasons, "unassigned characters")
+	}
+	return reasons
+}
+
+// isAssigned reports whether r is in any Unicode category this Go release knows
+func isAssigned(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z, unicode.C)
+}
+
+// sampleMessage copies a message's metadata and the shape of its text
+func sampleMessage(msg models.Message, reasons []string) Sample {
+	sample := Sample{
+		ID:                    msg.ID,
+		Reasons:               reasons,
+		Date:                  &msg.FormattedDate,
+		IsFromMe:              msg.IsFromMe,
+		ItemType:              msg.ItemType,
+		AssociatedMessageType: msg.AssociatedMessageType,
+		IsAudioMessage:        msg.IsAudioMessage,
+		HasSubject:            msg.Subject != nil && *msg.Subject != "",
+		HasAttachments:        msg.HasAttachments,
+	}
+	if msg.BalloonBundleID != nil {
+		sample.BalloonBundleID = *msg.BalloonBundleID
+	}
+	if msg.ExpressiveSendStyleID != nil {
+		sample.Effect = *msg.ExpressiveSendStyleID
+	}
+	if msg.Text != nil {
+		shape := Shape(*msg.Text)
+		sample.Text = &shape
+	}
+	return sample
+}
+
+// sampleAttachment keeps an attachment's type and size
+func sampleAttachment(att models.Attachment) FileSample {
+	sample := FileSample{Bytes: att.TotalBytes, Sticker: att.IsSticker}
+	if att.MimeType != nil {
+		sample.MimeType = *att.MimeType
+	}
+	if att.UTI != nil {
+		sample.UTI = *att.UTI
+	}
+	if att.Filename != nil {
+		sample.Extension = strings.ToLower(filepath.Ext(*att.Filename))
+	}
+	return sample
+}
+
+// Shape reduces text to its layout, as described on TextShape
+func Shape(text string) TextShape {
+	shape := TextShape{Lines: strings.Count(text, "\n") + 1}
+	scripts := make(map[string]bool)
+
+	var b strings.Builder
+	for _, r := range text {
+		shape.Runes++
+		if shape.Runes > maxShape {
+			shape.Truncated = true
+			continue
+		}
+		switch {
+		case unicode.IsLetter(r):
+			if unicode.IsUpper(r) {
+				b.WriteByte('X')
+			} else {
+				b.WriteByte('x')
+			}
+			if script := scriptOf(r); script != "" {
+				scripts[script] = true
+			}
+		case unicode.IsNumber(r):
+			b.WriteByte('0')
+		case r == '\n' || r == '\t' || r == ' ' || unicode.IsPunct(r) || unicode.IsSymbol(r):
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "<U+%04X>", r)
+		}
+	}
+	shape.Shape = b.String()
+
+	for script := range scripts {
+		shape.Scripts = append(shape.Scripts, script)
+	}
+	sort.Strings(shape.Scripts)
+	return shape
+}
+
+// scriptOf names the writing system a letter belongs to
+func scriptOf(r rune) string {
+	if r < unicode.MaxLatin1 {
+		return "Latin"
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package debugbundle
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// createDatabase writes a chat.db with the rows given as SQL
+func createDatabase(t *testing.T, rows string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chat.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetConnection().Exec(database.Schema + rows); err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	return path
+}
+
+func TestShape(t *testing.T) {
+	shape := Shape("Hi Ana, 12 cats!\nПривет 🎉\u200d")
+	if shape.Shape != "Xx Xxx, 00 xxxx!\nXxxxxx 🎉<U+200D>" {
+		t.Errorf("Unexpected shape %q", shape.Shape)
+	}
+	if shape.Runes != 26 || shape.Lines != 2 {
+		t.Errorf("Expected 26 runes on 2 lines, got %d on %d", shape.Runes, shape.Lines)
+	}
+	if !reflect.DeepEqual(shape.Scripts, []string{"Cyrillic", "Latin"}) {
+		t.Errorf("Unexpected scripts %v", shape.Scripts)
+	}
+
+	long := Shape(strings.Repeat("a", maxShape+10))
+	if !long.Truncated || len(long.Shape) != maxShape || long.Runes != maxShape+10 {
+		t.Errorf("Expected a truncated shape, got %d runes of shape from %d", len(long.Shape), long.Runes)
+	}
+}
+
+func TestSuspicious(t *testing.T) {
+	text := func(s string) *string { return &s }
+	tests := []struct {
+		name string
+		msg  models.Message
+		want []string
+	}{
+		{"plain text", models.Message{Text: text("hello")}, nil},
+		{"empty", models.Message{Text: text(" ")}, []string{"no text or attachments"}},
+		{"photo", models.Message{HasAttachments: true}, nil},
+		{"placeholder", models.Message{Text: text("\uFFFC")}, []string{"attachment placeholder without an attachment"}},
+		{"odd characters", models.Message{Text: text("a\x07b\uE000")}, []string{"control characters", "private use characters"}},
+	}
+	for _, tt := range tests {
+		if got := Suspicious(tt.msg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestSampleMessages(t *testing.T) {
+	path := createDatabase(t, `
+		INSERT INTO message (ROWID, guid, text, date, cache_has_attachments) VALUES
+			(1, 'a', 'Meet Ana at 5', 0, 0),
+			(2, 'b', '', 0, 0),
+			(3, 'c', 'look', 0, 1);
+		INSERT INTO attachment (ROWID, guid, filename, mime_type, total_bytes) VALUES (1, 'att', '~/Library/Messages/Attachments/IMG_1.HEIC', 'image/heic', 2048);
+		INSERT INTO message_attachment_join VALUES (3, 1);
+	`)
+
+	samples, err := SampleMessages(path, []int{3, 42})
+	if err != nil {
+		t.Fatalf("SampleMessages failed: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("Expected the empty message, the requested one and the missing one, got %+v", samples)
+	}
+	if samples[0].ID != 2 || samples[0].Reasons[0] != "no text or attachments" {
+		t.Errorf("Expected the empty message found, got %+v", samples[0])
+	}
+	att := samples[1].Attachments
+	if samples[1].ID != 3 || len(att) != 1 || att[0].Extension != ".heic" || att[0].MimeType != "image/heic" || att[0].Bytes != 2048 {
+		t.Errorf("Expected the requested message with its attachment type, got %+v", samples[1])
+	}
+	if samples[2].ID != 42 || samples[2].Date != nil || len(samples[2].Reasons) != 2 {
+		t.Errorf("Expected the missing message noted, got %+v", samples[2])
+	}
+
+	if _, err := SampleMessages(filepath.Join(t.TempDir(), "missing.db"), nil); err == nil {
+		t.Error("Expected an error for a missing database")
+	}
+}
+
+func TestReadSchema(t *testing.T) {
+	path := createDatabase(t, `INSERT INTO message (ROWID, guid, text, date) VALUES (1, 'a', 'hi', 0); PRAGMA user_version = 7;`)
+
+	schema, err := ReadSchema(path)
+	if err != nil {
+		t.Fatalf("ReadSchema failed: %v", err)
+	}
+	if schema.UserVersion != 7 {
+		t.Errorf("Expected user_version 7, got %d", schema.UserVersion)
+	}
+	for _, table := range schema.Tables {
+		if table.Name == "message" {
+			if table.Rows != 1 || table.Columns[0] != "ROWID" {
+				t.Errorf("Unexpected message table %+v", table)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected the message table, got %+v", schema.Tables)
+}
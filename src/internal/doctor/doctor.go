@@ -0,0 +1,331 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"threadbound/internal/database"
+	"threadbound/internal/latex"
+	"threadbound/internal/models"
+)
+
+// Status is the outcome of a check
+type Status int
+
+const (
+	Pass Status = iota // Found and working
+	Warn               // Works, with something missing that some books need
+	Fail               // Generating a PDF will fail
+)
+
+// Check is the result of verifying one part of the environment
+type Check struct {
+	Name   string
+	Status Status
+	Detail string // What was found
+	Fix    string // What to do about a warning or failure
+}
+
+// Package is a LaTeX package the book's TeX needs
+type Package struct {
+	Name    string // As in \usepackage
+	File    string // The file kpsewhich looks for
+	TeXLive string // The TeX Live package it ships in, for tlmgr
+}
+
+// RequiredPackages are the packages checked for beyond what every TeX distribution has
+var RequiredPackages = []Package{
+	{Name: "tikz", File: "tikz.sty", TeXLive: "pgf"},
+	{Name: "adjustbox", File: "adjustbox.sty", TeXLive: "adjustbox"},
+	{Name: "newunicodechar", File: "newunicodechar.sty", TeXLive: "newunicodechar"},
+	{Name: "fontspec", File: "fontspec.sty", TeXLive: "fontspec"},
+}
+
+// Commands are found and run through these, so tests can stand in for them
+var (
+	lookPath   = exec.LookPath
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		return exec.Command(name, args...).Output()
+	}
+)
+
+// Run checks everything generate needs to build a PDF from config: the PDF engine and
+// its packages, the theme's fonts, ImageMagick and the database
+func Run(config *models.BookConfig) []Check {
+	theme := config.Theme.WithDefaults()
+	checks := CheckPDFEngine(config.PDFEngine)
+	// Tectonic fetches packages as it needs them, so there's no TeX distribution to look in
+	if engine, err := latex.SelectEngine(config.PDFEngine, installed); err == nil && !engine.FetchesPackages() {
+		checks = append(checks, CheckPackages(RequiredPackages)...)
+	}
+	checks = append(checks,
+		CheckFont("Emoji font", theme.EmojiFont, "theme.emoji_font"),
+		CheckFont("Text font", theme.Font, "theme.font"),
+		CheckImageMagick(config.ImageConverter),
+		CheckDatabase(config.DatabasePath),
+	)
+	return checks
+}
+
+// Failed counts the checks that failed
+func Failed(checks []Check) int {
+	failed := 0
+	for _, check := range checks {
+		if check.Status == Fail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// CheckXeLaTeX verifies xelatex runs and reports its version
+func CheckXeLaTeX() Check {
+	return checkProgram("XeLaTeX", "xelatex")
+}
+
+// CheckPDFEngine verifies the programs the pdf_engine setting runs. Without the setting
+// that's XeLaTeX, or Tectonic when only it is installed.
+func CheckPDFEngine(name string) []Check {
+	engine, err := latex.SelectEngine(name, installed)
+	if err != nil {
+		return []Check{{
+			Name:   "PDF engine",
+			Status: Fail,
+			Detail: err.Error(),
+			Fix:    "Set pdf_engine to one of: " + strings.Join(latex.EngineNames(), ", "),
+		}}
+	}
+
+	var checks []Check
+	for _, program := range engine.Programs() {
+		check := checkProgram(programNames[program], program)
+		if name == "" && check.Status == Fail && !installed(program) {
+			check.Fix += ". Or install Tectonic instead, which needs no TeX distribution: " + tectonicInstall()
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// installed reports whether a program is on PATH
+func installed(program string) bool {
+	_, err := lookPath(program)
+	return err == nil
+}
+
+// programNames are how the engines' programs are named in checks
+var programNames = map[string]string{
+	"xelatex":  "XeLaTeX",
+	"lualatex": "LuaLaTeX",
+	"latexmk":  "latexmk",
+	"tectonic": "Tectonic",
+}
+
+// checkProgram verifies a TeX program runs and reports its version
+func checkProgram(name, program string) Check {
+	check := Check{Name: name}
+	if _, err := lookPath(program); err != nil {
+		check.Status = Fail
+		check.Detail = program + " not found"
+		check.Fix = fmt.Sprintf("Install a TeX distribution with %s: %s", name, texInstall())
+		if program == "tectonic" {
+			check.Fix = "Install Tectonic: " + tectonicInstall()
+		}
+		return check
+	}
+
+	output, err := runCommand(program, "--version")
+	if err != nil {
+		check.Status = Fail
+		check.Detail = fmt.Sprintf("%s --version failed: %v", program, err)
+		check.Fix = "Reinstall your TeX distribution: " + texInstall()
+		return check
+	}
+	check.Detail = firstLine(output)
+	return check
+}
+
+// CheckPackages looks up each package with kpsewhich, as XeLaTeX would
+func CheckPackages(packages []Package) []Check {
+	if _, err := lookPath("kpsewhich"); err != nil {
+		return []Check{{
+			Name:   "LaTeX packages",
+			Status: Fail,
+			Detail: "kpsewhich not found, so packages can't be looked up",
+			Fix:    "Install a TeX distribution: " + texInstall(),
+		}}
+	}
+
+	checks := make([]Check, len(packages))
+	for i, pkg := range packages {
+		checks[i] = Check{Name: "LaTeX package " + pkg.Name}
+		output, err := runCommand("kpsewhich", pkg.File)
+		if path := firstLine(output); err == nil && path != "" {
+			checks[i].Detail = path
+			continue
+		}
+		checks[i].Status = Fail
+		checks[i].Detail = pkg.File + " not found"
+		checks[i].Fix = packageInstall(pkg)
+	}
+	return checks
+}
+
+// CheckFont looks a font family up with fontconfig, which XeLaTeX finds fonts through.
+// Without fc-list the font can't be checked, which is only a warning.
+func CheckFont(name, family, setting string) Check {
+	check := Check{Name: name}
+	if _, err := lookPath("fc-list"); err != nil {
+		check.Status = Warn
+		check.Detail = fmt.Sprintf("fc-list not found, so %q can't be checked", family)
+		check.Fix = fmt.Sprintf("Make sure %q is installed, or set %s to an installed font", family, setting)
+		return check
+	}
+
+	output, err := runCommand("fc-list", ":family="+family, "family")
+	if err == nil && strings.TrimSpace(string(output)) != "" {
+		check.Detail = family
+		return check
+	}
+	check.Status = Fail
+	check.Detail = family + " not installed"
+	check.Fix = fmt.Sprintf("Install %s or set %s to an installed font", family, setting)
+	if family == models.DefaultEmojiFont {
+		check.Fix = fmt.Sprintf("Install %s (%s) or set %s to an installed font", family, symbolaInstall(), setting)
+	}
+	return check
+}
+
+// CheckImageMagick looks for magick. Images, HEIC photos included, still convert without it
+// unless it was chosen with image_converter, but link preview images need it.
+func CheckImageMagick(converter string) Check {
+	check := Check{Name: "ImageMagick"}
+	if _, err := lookPath("magick"); err == nil {
+		output, _ := runCommand("magick", "--version")
+		check.Detail = firstLine(output)
+		return check
+	}
+
+	check.Status = Warn
+	if converter == "magick" {
+		check.Status = Fail
+	}
+	check.Detail = "magick not found"
+	check.Fix = "Install ImageMagick (" + magickInstall() + ") for link preview images"
+	return check
+}
+
+// CheckDatabase opens the database and counts its messages
+func CheckDatabase(path string) Check {
+	check := Check{Name: "Database", Status: Fail}
+
+	file, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		check.Detail = path + " not found"
+		check.Fix = "Pass --db with the path to chat.db (on a Mac, ~/Library/Messages/chat.db) or copy it here"
+		return check
+	case errors.Is(err, fs.ErrPermission):
+		check.Detail = path + " isn't readable"
+		check.Fix = permissionFix()
+		return check
+	case err != nil:
+		check.Detail = err.Error()
+		return check
+	}
+	file.Close()
+
+	db, err := database.New(path)
+	if err == nil {
+		defer db.Close()
+		var count int
+		if count, err = db.CountMessages(""); err == nil {
+			check.Status = Pass
+			check.Detail = fmt.Sprintf("%s, %d messages", path, count)
+			return check
+		}
+	}
+	check.Detail = fmt.Sprintf("%s can't be read: %v", path, err)
+	if strings.Contains(err.Error(), "authoriz") {
+		check.Fix = permissionFix()
+	} else {
+		check.Fix = "Make sure the file is an iMessage chat.db; copy it again from ~/Library/Messages with Messages closed"
+	}
+	return check
+}
+
+// firstLine returns the first non-empty line of command output
+func firstLine(output []byte) string {
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// permissionFix explains how to let threadbound read the database
+func permissionFix() string {
+	return database.PermissionHint()
+}
+
+// texInstall suggests how to install XeLaTeX on this platform
+func texInstall() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "brew install --cask mactex"
+	case "windows":
+		return "install MiKTeX from https://miktex.org/ or TeX Live"
+	default:
+		return "sudo apt-get install texlive-xetex texlive-fonts-recommended"
+	}
+}
+
+// tectonicInstall suggests how to install Tectonic on this platform
+func tectonicInstall() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "brew install tectonic"
+	case "windows":
+		return "download it from https://github.com/tectonic-typesetting/tectonic/releases"
+	default:
+		return "curl --proto '=https' --tlsv1.2 -fsSL https://drop-sh.fullyjustified.net | sh"
+	}
+}
+
+// packageInstall suggests how to install a missing LaTeX package on this platform
+func packageInstall(pkg Package) string {
+	switch runtime.GOOS {
+	case "windows":
+		return fmt.Sprintf("Install the %s package with the MiKTeX Console, or run: tlmgr install %s", pkg.Name, pkg.TeXLive)
+	case "darwin":
+		return "Run: sudo tlmgr install " + pkg.TeXLive
+	default:
+		return fmt.Sprintf("Run: sudo tlmgr install %s (or sudo apt-get install texlive-latex-extra)", pkg.TeXLive)
+	}
+}
+
+// symbolaInstall suggests how to install the default emoji font on this platform
+func symbolaInstall() string {
+	if runtime.GOOS == "linux" {
+		return "sudo apt-get install fonts-symbola"
+	}
+	return "download Symbola and open the font file to install it"
+}
+
+// magickInstall suggests how to install ImageMagick on this platform
+func magickInstall() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "brew install imagemagick"
+	case "windows":
+		return "https://imagemagick.org/"
+	default:
+		return "sudo apt-get install imagemagick"
+	}
+}
@@ -0,0 +1,190 @@
+package doctor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// fakeCommands stands in for the commands on PATH: installed maps each command to its
+// output, keyed by the command and its arguments joined with spaces
+func fakeCommands(t *testing.T, installed map[string]string) {
+	t.Helper()
+	origLookPath, origRunCommand := lookPath, runCommand
+	t.Cleanup(func() { lookPath, runCommand = origLookPath, origRunCommand })
+
+	lookPath = func(name string) (string, error) {
+		for command := range installed {
+			if strings.Fields(command)[0] == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		output, ok := installed[strings.Join(append([]string{name}, args...), " ")]
+		if !ok {
+			return nil, errors.New("exit status 1")
+		}
+		return []byte(output), nil
+	}
+}
+
+func TestCheckXeLaTeX(t *testing.T) {
+	fakeCommands(t, map[string]string{"xelatex --version": "\nXeTeX 3.141592653-2.6-0.999995 (TeX Live 2023)\nmore\n"})
+	if check := CheckXeLaTeX(); check.Status != Pass || check.Detail != "XeTeX 3.141592653-2.6-0.999995 (TeX Live 2023)" {
+		t.Errorf("Unexpected check: %+v", check)
+	}
+
+	fakeCommands(t, nil)
+	if check := CheckXeLaTeX(); check.Status != Fail || check.Fix == "" {
+		t.Errorf("Expected a failure with a fix, got %+v", check)
+	}
+}
+
+func TestCheckPDFEngine(t *testing.T) {
+	fakeCommands(t, map[string]string{"xelatex --version": "XeTeX 3.141592653-2.6-0.999995 (TeX Live 2023)\n"})
+	if checks := CheckPDFEngine(""); len(checks) != 1 || checks[0].Name != "XeLaTeX" || checks[0].Status != Pass {
+		t.Errorf("Expected xelatex checked by default, got %+v", checks)
+	}
+
+	checks := CheckPDFEngine("latexmk")
+	if len(checks) != 2 || checks[0].Name != "latexmk" || checks[0].Status != Fail || checks[1].Status != Pass {
+		t.Errorf("Expected latexmk missing and xelatex found, got %+v", checks)
+	}
+
+	fakeCommands(t, map[string]string{"tectonic --version": "Tectonic 0.15.0\n"})
+	if checks := CheckPDFEngine(""); len(checks) != 1 || checks[0].Name != "Tectonic" || checks[0].Status != Pass {
+		t.Errorf("Expected tectonic checked when only it is installed, got %+v", checks)
+	}
+
+	fakeCommands(t, nil)
+	if checks := CheckPDFEngine(""); len(checks) != 1 || !strings.Contains(checks[0].Fix, "Tectonic") {
+		t.Errorf("Expected Tectonic suggested when nothing is installed, got %+v", checks)
+	}
+
+	if checks := CheckPDFEngine("pdflatex"); len(checks) != 1 || checks[0].Status != Fail || !strings.Contains(checks[0].Fix, "tectonic") {
+		t.Errorf("Expected an unknown engine to fail with the choices, got %+v", checks)
+	}
+}
+
+func TestCheckPackages(t *testing.T) {
+	fakeCommands(t, map[string]string{
+		"kpsewhich tikz.sty":     "/texmf/tex/latex/pgf/frontendlayer/tikz.sty\n",
+		"kpsewhich fontspec.sty": "/texmf/tex/latex/fontspec/fontspec.sty\n",
+	})
+	checks := CheckPackages(RequiredPackages)
+	if len(checks) != len(RequiredPackages) {
+		t.Fatalf("Expected a check per package, got %d", len(checks))
+	}
+	if checks[0].Status != Pass || checks[3].Status != Pass {
+		t.Errorf("Expected tikz and fontspec found, got %+v", checks)
+	}
+	if checks[1].Status != Fail || !strings.Contains(checks[1].Fix, "adjustbox") {
+		t.Errorf("Expected adjustbox missing with an install hint, got %+v", checks[1])
+	}
+
+	// Without TeX there's nothing to look packages up with
+	fakeCommands(t, nil)
+	if checks := CheckPackages(RequiredPackages); len(checks) != 1 || checks[0].Status != Fail {
+		t.Errorf("Expected a single failure, got %+v", checks)
+	}
+}
+
+func TestCheckFont(t *testing.T) {
+	fakeCommands(t, map[string]string{"fc-list :family=Symbola family": "Symbola\n"})
+	if check := CheckFont("Emoji font", "Symbola", "theme.emoji_font"); check.Status != Pass {
+		t.Errorf("Expected Symbola found, got %+v", check)
+	}
+	check := CheckFont("Emoji font", "Noto Emoji", "theme.emoji_font")
+	if check.Status != Fail || !strings.Contains(check.Fix, "theme.emoji_font") {
+		t.Errorf("Expected a missing font pointing at the setting, got %+v", check)
+	}
+
+	fakeCommands(t, nil)
+	if check := CheckFont("Emoji font", "Symbola", "theme.emoji_font"); check.Status != Warn {
+		t.Errorf("Expected a warning without fontconfig, got %+v", check)
+	}
+}
+
+func TestCheckImageMagick(t *testing.T) {
+	fakeCommands(t, nil)
+	if check := CheckImageMagick("auto"); check.Status != Warn {
+		t.Errorf("Expected a warning when other converters can stand in, got %+v", check)
+	}
+	if check := CheckImageMagick("magick"); check.Status != Fail {
+		t.Errorf("Expected a failure when ImageMagick was chosen, got %+v", check)
+	}
+
+	fakeCommands(t, map[string]string{"magick --version": "Version: ImageMagick 7.1.1-15\n"})
+	if check := CheckImageMagick("magick"); check.Status != Pass || check.Detail != "Version: ImageMagick 7.1.1-15" {
+		t.Errorf("Unexpected check: %+v", check)
+	}
+}
+
+func TestCheckDatabase(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing.db")
+	if check := CheckDatabase(missing); check.Status != Fail || !strings.Contains(check.Fix, "--db") {
+		t.Errorf("Expected a missing database, got %+v", check)
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("Checking a missing database should not create it")
+	}
+
+	notDB := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notDB, []byte("not a database"), 0644)
+	if check := CheckDatabase(notDB); check.Status != Fail || check.Fix == "" {
+		t.Errorf("Expected an unreadable database, got %+v", check)
+	}
+
+	path := filepath.Join(dir, "chat.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	if _, err := db.GetConnection().Exec(database.Schema + `INSERT INTO message (ROWID, guid, text, date) VALUES (1, 'a', 'hi', 0), (2, 'b', 'hello', 0);`); err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	db.Close()
+
+	if check := CheckDatabase(path); check.Status != Pass || !strings.HasSuffix(check.Detail, "2 messages") {
+		t.Errorf("Expected a readable database, got %+v", check)
+	}
+}
+
+func TestRun(t *testing.T) {
+	fakeCommands(t, nil)
+	checks := Run(&models.BookConfig{DatabasePath: filepath.Join(t.TempDir(), "chat.db"), ImageConverter: "auto"})
+
+	// xelatex, packages, two fonts, ImageMagick and the database
+	if len(checks) != 6 {
+		t.Fatalf("Expected 6 checks, got %d", len(checks))
+	}
+	if checks[2].Name != "Emoji font" || !strings.Contains(checks[2].Detail, models.DefaultEmojiFont) {
+		t.Errorf("Expected the default emoji font checked, got %+v", checks[2])
+	}
+	// Font and ImageMagick checks only warn
+	if got := Failed(checks); got != 3 {
+		t.Errorf("Expected 3 failures, got %d", got)
+	}
+}
+
+func TestRunTectonic(t *testing.T) {
+	fakeCommands(t, map[string]string{"tectonic --version": "Tectonic 0.15.0\n"})
+	checks := Run(&models.BookConfig{DatabasePath: filepath.Join(t.TempDir(), "chat.db"), ImageConverter: "auto"})
+
+	// Tectonic, two fonts, ImageMagick and the database, but no packages to look up
+	if len(checks) != 5 {
+		t.Fatalf("Expected 5 checks, got %+v", checks)
+	}
+	if checks[0].Name != "Tectonic" || checks[0].Status != Pass {
+		t.Errorf("Expected Tectonic found, got %+v", checks[0])
+	}
+}
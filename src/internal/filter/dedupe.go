@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"strings"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// DefaultDedupeWindow is how close together identical messages must be to count as duplicates
+const DefaultDedupeWindow = time.Minute
+
+// Dedupe collapses identical consecutive messages from the same sender sent within
+// window of each other, such as accidental double-sends or repeated "?". It returns the
+// remaining messages and maps each removed message's GUID to the GUID it was folded into.
+func Dedupe(messages []models.Message, window time.Duration) ([]models.Message, map[string]string) {
+	collapsed := make(map[string]string)
+	if len(messages) == 0 {
+		return messages, collapsed
+	}
+
+	kept := make([]models.Message, 0, len(messages))
+	var last *models.Message // Most recent message seen, kept or not
+	keptGUID := ""
+
+	for i := range messages {
+		msg := &messages[i]
+		if last != nil && isDuplicate(last, msg, window) {
+			collapsed[msg.GUID] = keptGUID
+			last = msg
+			continue
+		}
+
+		kept = append(kept, *msg)
+		keptGUID = msg.GUID
+		last = msg
+	}
+
+	return kept, collapsed
+}
+
+// isDuplicate reports whether msg repeats prev: same sender, same text, no attachments, inside the window
+func isDuplicate(prev, msg *models.Message, window time.Duration) bool {
+	if prev.Memory != nil || msg.Memory != nil {
+		return false
+	}
+	if prev.HasAttachments || msg.HasAttachments {
+		return false
+	}
+	if prev.IsFromMe != msg.IsFromMe || !sameHandle(prev.HandleID, msg.HandleID) {
+		return false
+	}
+	if prev.Text == nil || msg.Text == nil {
+		return false
+	}
+
+	text := strings.TrimSpace(*msg.Text)
+	if text == "" || text != strings.TrimSpace(*prev.Text) {
+		return false
+	}
+
+	return msg.FormattedDate.Sub(prev.FormattedDate) <= window
+}
+
+// sameHandle compares optional handle IDs
+func sameHandle(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// MergeReactions moves reactions on removed messages to the message they were folded into
+func MergeReactions(reactions map[string][]models.Reaction, collapsed map[string]string) {
+	for removed, kept := range collapsed {
+		if moved, exists := reactions[removed]; exists {
+			reactions[kept] = append(reactions[kept], moved...)
+			delete(reactions, removed)
+		}
+	}
+}
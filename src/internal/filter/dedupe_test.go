@@ -0,0 +1,61 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestDedupe(t *testing.T) {
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	text := func(s string) *string { return &s }
+	alice, bob := 1, 2
+
+	messages := []models.Message{
+		{GUID: "1", Text: text("?"), HandleID: &alice, FormattedDate: start},
+		{GUID: "2", Text: text("?"), HandleID: &alice, FormattedDate: start.Add(10 * time.Second)},
+		{GUID: "3", Text: text("? "), HandleID: &alice, FormattedDate: start.Add(20 * time.Second)},
+		{GUID: "4", Text: text("?"), HandleID: &bob, FormattedDate: start.Add(30 * time.Second)},
+		{GUID: "5", Text: text("on my way"), IsFromMe: true, FormattedDate: start.Add(time.Minute)},
+		{GUID: "6", Text: text("on my way"), IsFromMe: true, FormattedDate: start.Add(5 * time.Minute)},
+		{GUID: "7", Text: text("look"), IsFromMe: true, HasAttachments: true, FormattedDate: start.Add(6 * time.Minute)},
+		{GUID: "8", Text: text("look"), IsFromMe: true, HasAttachments: true, FormattedDate: start.Add(6 * time.Minute)},
+	}
+
+	kept, collapsed := Dedupe(messages, time.Minute)
+
+	var guids []string
+	for _, msg := range kept {
+		guids = append(guids, msg.GUID)
+	}
+	expected := []string{"1", "4", "5", "6", "7", "8"}
+	if len(guids) != len(expected) {
+		t.Fatalf("Expected %v kept, got %v", expected, guids)
+	}
+	for i := range expected {
+		if guids[i] != expected[i] {
+			t.Fatalf("Expected %v kept, got %v", expected, guids)
+		}
+	}
+
+	if collapsed["2"] != "1" || collapsed["3"] != "1" || len(collapsed) != 2 {
+		t.Errorf("Expected 2 and 3 folded into 1, got %v", collapsed)
+	}
+}
+
+func TestMergeReactions(t *testing.T) {
+	reactions := map[string][]models.Reaction{
+		"1": {{SenderName: "Bob", ReactionEmoji: "👍"}},
+		"2": {{SenderName: "Carol", ReactionEmoji: "😂"}},
+	}
+
+	MergeReactions(reactions, map[string]string{"2": "1"})
+
+	if len(reactions["1"]) != 2 {
+		t.Errorf("Expected 2 reactions on the kept message, got %d", len(reactions["1"]))
+	}
+	if _, exists := reactions["2"]; exists {
+		t.Error("Expected reactions on the removed message to be moved")
+	}
+}
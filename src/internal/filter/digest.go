@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"unicode/utf8"
+
+	"threadbound/internal/models"
+)
+
+// Digest keeps the highlights of a long conversation and the messages around them, for a
+// condensed book, and returns how many messages were dropped. Reactions are keyed by the
+// GUID of the message they're on; attachments, which haven't been loaded onto the messages
+// yet, by its ROWID. Context is counted within each conversation, so a highlight isn't
+// surrounded by messages from another chat. Memories are always kept. Messages must be in
+// date order.
+func Digest(messages []models.Message, reactions map[string][]models.Reaction, attachments map[int][]models.Attachment, rules models.DigestRules) ([]models.Message, int) {
+	rules = rules.WithDefaults()
+
+	// Where each message falls in its own conversation
+	chats := make(map[int][]int)
+	for i := range messages {
+		chats[messages[i].ChatID] = append(chats[messages[i].ChatID], i)
+	}
+
+	kept := make([]bool, len(messages))
+	for _, indexes := range chats {
+		for n, i := range indexes {
+			if messages[i].Memory != nil {
+				kept[i] = true
+				continue
+			}
+			if Score(&messages[i], reactions[messages[i].GUID], attachments[messages[i].ID], rules) < rules.MinScore {
+				continue
+			}
+			for c := max(0, n-rules.Context); c <= min(len(indexes)-1, n+rules.Context); c++ {
+				kept[indexes[c]] = true
+			}
+		}
+	}
+
+	i := 0
+	return keep(messages, func(*models.Message) bool {
+		i++
+		return kept[i-1]
+	})
+}
+
+// Score rates how much of a highlight a message is under the rules, from its length, its
+// reactions and whether it, or the attachments given for it, hold a photo
+func Score(msg *models.Message, reactions []models.Reaction, attachments []models.Attachment, rules models.DigestRules) float64 {
+	var score float64
+	if msg.Text != nil && utf8.RuneCountInString(*msg.Text) >= rules.LongLength {
+		score += rules.LongScore
+	}
+	score += rules.ReactionScore * float64(len(reactions))
+
+	withAttachments := *msg
+	if len(attachments) > 0 {
+		withAttachments.Attachments = attachments
+	}
+	if hasMedia(&withAttachments, "image") {
+		score += rules.PhotoScore
+	}
+	return score
+}
@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestDigest(t *testing.T) {
+	text := func(s string) *string { return &s }
+	jpeg := "image/jpeg"
+	var messages []models.Message
+	for _, guid := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		messages = append(messages, models.Message{ID: len(messages) + 1, GUID: guid, Text: text("ok"), ChatID: 1})
+	}
+	messages[1].Text = text(strings.Repeat("so much to say ", 20)) // b is long
+	messages[5].ChatID = 2                                         // f is in another chat, around h's photo in time
+	messages[7].ChatID = 2
+
+	reactions := map[string][]models.Reaction{"e": {{}}, "j": {{}, {}}}
+	attachments := map[int][]models.Attachment{8: {{MimeType: &jpeg}}}
+
+	kept, removed := Digest(messages, reactions, attachments, models.DigestRules{Context: 1})
+	assertGUIDs(t, kept, "a", "b", "c", "f", "h", "i", "j")
+	if removed != 3 {
+		t.Errorf("Expected 3 removed, got %d", removed)
+	}
+
+	// One reaction is a highlight once it scores enough
+	kept, _ = Digest(messages, reactions, nil, models.DigestRules{ReactionScore: 1, Context: -1})
+	assertGUIDs(t, kept, "b", "e", "j")
+}
+
+func TestScore(t *testing.T) {
+	text := "hi"
+	rules := models.DigestRules{}.WithDefaults()
+	if score := Score(&models.Message{Text: &text}, make([]models.Reaction, 3), nil, rules); score != 1.5 {
+		t.Errorf("Expected 1.5 for three reactions, got %v", score)
+	}
+	png := "photo.png"
+	if score := Score(&models.Message{Attachments: []models.Attachment{{Filename: &png}}}, nil, nil, rules); score != 1 {
+		t.Errorf("Expected 1 for a photo, got %v", score)
+	}
+}
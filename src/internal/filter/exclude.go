@@ -0,0 +1,109 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+
+	"threadbound/internal/models"
+)
+
+// ExcludeContacts drops messages received from any of the given contacts. A contact matches
+// a handle's ID (phone number or email) or its display name, ignoring case; phone numbers
+// also match when only their formatting differs. Messages sent by you are always kept.
+func ExcludeContacts(messages []models.Message, handles map[int]models.Handle, contacts []string) ([]models.Message, int) {
+	if len(contacts) == 0 {
+		return messages, 0
+	}
+
+	excluded := make(map[int]bool)
+	for id, handle := range handles {
+		for _, contact := range contacts {
+			if matchesContact(handle, contact) {
+				excluded[id] = true
+				break
+			}
+		}
+	}
+	if len(excluded) == 0 {
+		return messages, 0
+	}
+
+	return keep(messages, func(msg *models.Message) bool {
+		return msg.IsFromMe || msg.HandleID == nil || !excluded[*msg.HandleID]
+	})
+}
+
+// ExcludeKeywords drops messages whose text or subject contains any of the given phrases, ignoring case
+func ExcludeKeywords(messages []models.Message, keywords []string) ([]models.Message, int) {
+	var phrases []string
+	for _, keyword := range keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			phrases = append(phrases, strings.ToLower(keyword))
+		}
+	}
+	if len(phrases) == 0 {
+		return messages, 0
+	}
+
+	return keep(messages, func(msg *models.Message) bool {
+		return !containsAny(msg.Text, phrases) && !containsAny(msg.Subject, phrases)
+	})
+}
+
+// keep returns the messages for which ok is true and how many were dropped
+func keep(messages []models.Message, ok func(*models.Message) bool) ([]models.Message, int) {
+	kept := make([]models.Message, 0, len(messages))
+	for i := range messages {
+		if ok(&messages[i]) {
+			kept = append(kept, messages[i])
+		}
+	}
+	return kept, len(messages) - len(kept)
+}
+
+// matchesContact reports whether contact names this handle
+func matchesContact(handle models.Handle, contact string) bool {
+	contact = strings.TrimSpace(contact)
+	if contact == "" {
+		return false
+	}
+	if strings.EqualFold(handle.Contact, contact) || strings.EqualFold(handle.DisplayName, contact) {
+		return true
+	}
+
+	// "+1 (555) 123-4567" and "+15551234567" are the same number
+	digits := phoneDigits(contact)
+	return digits != "" && digits == phoneDigits(handle.Contact)
+}
+
+// phoneDigits returns the digits of a phone number, or "" if s doesn't look like one
+func phoneDigits(s string) string {
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case strings.ContainsRune("+-(). ", r):
+		default:
+			return ""
+		}
+	}
+	if digits.Len() < 7 {
+		return ""
+	}
+	return digits.String()
+}
+
+// containsAny reports whether text contains one of the lowercase phrases
+func containsAny(text *string, phrases []string) bool {
+	if text == nil || *text == "" {
+		return false
+	}
+	lower := strings.ToLower(*text)
+	for _, phrase := range phrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+// guids lists message GUIDs in order
+func guids(messages []models.Message) []string {
+	var out []string
+	for _, msg := range messages {
+		out = append(out, msg.GUID)
+	}
+	return out
+}
+
+func assertGUIDs(t *testing.T, messages []models.Message, expected ...string) {
+	t.Helper()
+	got := guids(messages)
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestExcludeContacts(t *testing.T) {
+	alice, bob, carol := 1, 2, 3
+	handles := map[int]models.Handle{
+		alice: {ID: alice, Contact: "+15551234567", DisplayName: "Alice"},
+		bob:   {ID: bob, Contact: "bob@example.com", DisplayName: "Bob"},
+		carol: {ID: carol, Contact: "+15559876543", DisplayName: "Carol"},
+	}
+	messages := []models.Message{
+		{GUID: "1", HandleID: &alice},
+		{GUID: "2", HandleID: &bob},
+		{GUID: "3", HandleID: &carol},
+		{GUID: "4", HandleID: &alice, IsFromMe: true},
+		{GUID: "5"},
+	}
+
+	kept, removed := ExcludeContacts(messages, handles, []string{"+1 (555) 123-4567", "BOB@example.com"})
+	if removed != 2 {
+		t.Errorf("Expected 2 removed, got %d", removed)
+	}
+	assertGUIDs(t, kept, "3", "4", "5")
+
+	kept, removed = ExcludeContacts(messages, handles, []string{"carol"})
+	if removed != 1 {
+		t.Errorf("Expected display name match to remove 1, got %d", removed)
+	}
+	assertGUIDs(t, kept, "1", "2", "4", "5")
+
+	if _, removed := ExcludeContacts(messages, handles, []string{"5551234"}); removed != 0 {
+		t.Errorf("Expected partial numbers not to match, got %d removed", removed)
+	}
+}
+
+func TestExcludeKeywords(t *testing.T) {
+	text := func(s string) *string { return &s }
+	messages := []models.Message{
+		{GUID: "1", Text: text("Happy birthday!")},
+		{GUID: "2", Text: text("Your verification CODE is 1234")},
+		{GUID: "3", Subject: text("Surprise party"), Text: text("shh")},
+		{GUID: "4"},
+	}
+
+	kept, removed := ExcludeKeywords(messages, []string{"verification code", "surprise", "  "})
+	if removed != 2 {
+		t.Errorf("Expected 2 removed, got %d", removed)
+	}
+	assertGUIDs(t, kept, "1", "4")
+
+	if kept, removed := ExcludeKeywords(messages, nil); removed != 0 || len(kept) != len(messages) {
+		t.Errorf("Expected no keywords to keep everything, got %d removed", removed)
+	}
+}
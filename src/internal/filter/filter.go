@@ -0,0 +1,8 @@
+// Package filter removes messages that shouldn't appear in the book before any plugin runs
+package filter
+
+// Result records how many messages one filter removed, for the console and the build manifest
+type Result struct {
+	Name    string `json:"name"`
+	Removed int    `json:"removed"`
+}
@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// OnThisDay keeps the messages sent on one calendar date in every year, for an anniversary
+// book of everything said on a wedding day or a birthday
+type OnThisDay struct {
+	Month time.Month
+	Day   int
+}
+
+// ParseOnThisDay parses the date of an "on this day" book, as MM-DD, or as YYYY-MM-DD with
+// the year ignored so the date of the occasion itself can be given. An empty date parses to
+// nil, which keeps every message.
+func ParseOnThisDay(date string) (*OnThisDay, error) {
+	date = strings.TrimSpace(date)
+	if date == "" {
+		return nil, nil
+	}
+
+	// Parsed in a leap year, so February 29 is a date
+	t, err := time.Parse("2006-01-02", "2024-"+date)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("invalid on-this-day date %q: use MM-DD or YYYY-MM-DD", date)
+		}
+	}
+	return &OnThisDay{Month: t.Month(), Day: t.Day()}, nil
+}
+
+// String describes the date for the console, e.g. "June 14"
+func (o *OnThisDay) String() string {
+	return fmt.Sprintf("%s %d", o.Month, o.Day)
+}
+
+// Matches reports whether t falls on the date in its year. February 29 falls on February 28
+// in the years without one, as the anniversaries of leap days are kept.
+func (o *OnThisDay) Matches(t time.Time) bool {
+	if o.Month == time.February && o.Day == 29 && !isLeap(t.Year()) {
+		return t.Month() == time.February && t.Day() == 28
+	}
+	return t.Month() == o.Month && t.Day() == o.Day
+}
+
+// Apply keeps the messages sent on the date in any year and returns how many were dropped
+func (o *OnThisDay) Apply(messages []models.Message) ([]models.Message, int) {
+	if o == nil {
+		return messages, 0
+	}
+
+	var kept []models.Message
+	for _, msg := range messages {
+		if o.Matches(msg.FormattedDate) {
+			kept = append(kept, msg)
+		}
+	}
+	return kept, len(messages) - len(kept)
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
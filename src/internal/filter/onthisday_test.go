@@ -0,0 +1,70 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestParseOnThisDay(t *testing.T) {
+	day, err := ParseOnThisDay("")
+	if err != nil || day != nil {
+		t.Errorf("Expected no date, got %+v, %v", day, err)
+	}
+
+	for _, date := range []string{"06-14", "2015-06-14", " 06-14 "} {
+		day, err := ParseOnThisDay(date)
+		if err != nil || day.Month != time.June || day.Day != 14 {
+			t.Errorf("Unexpected date %+v, %v for %q", day, err, date)
+		}
+	}
+	if day, err := ParseOnThisDay("02-29"); err != nil || day.String() != "February 29" {
+		t.Errorf("Unexpected date %+v, %v", day, err)
+	}
+
+	for _, date := range []string{"6/14", "13-01", "02-30", "2023-02-29", "June 14"} {
+		if _, err := ParseOnThisDay(date); err == nil {
+			t.Errorf("Expected an error for %q", date)
+		}
+	}
+}
+
+func TestOnThisDayApply(t *testing.T) {
+	messages := []models.Message{
+		{GUID: "1", FormattedDate: time.Date(2019, 6, 14, 9, 0, 0, 0, time.UTC)},
+		{GUID: "2", FormattedDate: time.Date(2019, 6, 15, 9, 0, 0, 0, time.UTC)},
+		{GUID: "3", FormattedDate: time.Date(2020, 6, 14, 23, 59, 0, 0, time.UTC)},
+		{GUID: "4", FormattedDate: time.Date(2021, 7, 14, 9, 0, 0, 0, time.UTC)},
+	}
+
+	kept, dropped := (&OnThisDay{Month: time.June, Day: 14}).Apply(messages)
+	assertGUIDs(t, kept, "1", "3")
+	if dropped != 2 {
+		t.Errorf("Expected 2 dropped, got %d", dropped)
+	}
+
+	var none *OnThisDay
+	if kept, _ := none.Apply(messages); len(kept) != 4 {
+		t.Error("Expected no date to keep every message")
+	}
+}
+
+func TestOnThisDayLeapDay(t *testing.T) {
+	leapDay := &OnThisDay{Month: time.February, Day: 29}
+	tests := []struct {
+		date time.Time
+		want bool
+	}{
+		{time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2020, 2, 28, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2021, 2, 28, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2100, 2, 28, 12, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := leapDay.Matches(tt.date); got != tt.want {
+			t.Errorf("Matches(%s) = %v, want %v", tt.date.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
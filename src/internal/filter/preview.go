@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// Ends of the conversation a preview can be taken from
+const (
+	PreviewFromStart = "start"
+	PreviewFromEnd   = "end"
+)
+
+// Preview keeps only one end of the conversation, so a book can be built in seconds while
+// working on its layout
+type Preview struct {
+	Count   int  // Messages, or days when Days is set
+	Days    bool // Count calendar days rather than messages
+	FromEnd bool // Keep the last messages or days instead of the first
+}
+
+// ParsePreview parses a preview size, N messages or Nd days, taken from the start or end
+// of the conversation. An empty size parses to nil, which keeps every message.
+func ParsePreview(size, from string) (*Preview, error) {
+	size = strings.TrimSpace(size)
+	if size == "" {
+		return nil, nil
+	}
+
+	preview := &Preview{}
+	switch strings.ToLower(from) {
+	case "", PreviewFromStart:
+	case PreviewFromEnd:
+		preview.FromEnd = true
+	default:
+		return nil, fmt.Errorf("preview must be taken from %q or %q, not %q", PreviewFromStart, PreviewFromEnd, from)
+	}
+
+	if strings.HasSuffix(size, "d") {
+		preview.Days = true
+		size = strings.TrimSuffix(size, "d")
+	}
+	count, err := strconv.Atoi(size)
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("invalid preview size %q: use a number of messages, or days such as 30d", size)
+	}
+	preview.Count = count
+	return preview, nil
+}
+
+// String describes the preview for the console, e.g. "the last 30 days"
+func (p *Preview) String() string {
+	end := "first"
+	if p.FromEnd {
+		end = "last"
+	}
+	unit := "messages"
+	if p.Days {
+		unit = "days"
+	}
+	if p.Count == 1 {
+		return fmt.Sprintf("the %s %s", end, strings.TrimSuffix(unit, "s"))
+	}
+	return fmt.Sprintf("the %s %d %s", end, p.Count, unit)
+}
+
+// Apply keeps the messages the preview covers and returns how many were dropped. Messages
+// must be in date order; days are calendar days from the first or last message's.
+func (p *Preview) Apply(messages []models.Message) ([]models.Message, int) {
+	if p == nil || len(messages) == 0 {
+		return messages, 0
+	}
+
+	count := p.Count
+	if p.Days {
+		count = p.dayMessages(messages)
+	}
+	if count >= len(messages) {
+		return messages, 0
+	}
+
+	if p.FromEnd {
+		return messages[len(messages)-count:], len(messages) - count
+	}
+	return messages[:count], len(messages) - count
+}
+
+// dayMessages counts the messages within the preview's days of the first or last message
+func (p *Preview) dayMessages(messages []models.Message) int {
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+
+	if p.FromEnd {
+		first := day(messages[len(messages)-1].FormattedDate).AddDate(0, 0, 1-p.Count)
+		for i, msg := range messages {
+			if !day(msg.FormattedDate).Before(first) {
+				return len(messages) - i
+			}
+		}
+		return 0
+	}
+
+	end := day(messages[0].FormattedDate).AddDate(0, 0, p.Count)
+	for i, msg := range messages {
+		if !day(msg.FormattedDate).Before(end) {
+			return i
+		}
+	}
+	return len(messages)
+}
@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestParsePreview(t *testing.T) {
+	preview, err := ParsePreview("", "end")
+	if err != nil || preview != nil {
+		t.Errorf("Expected no preview, got %+v, %v", preview, err)
+	}
+
+	preview, err = ParsePreview("30d", "end")
+	if err != nil || preview.Count != 30 || !preview.Days || !preview.FromEnd {
+		t.Errorf("Unexpected preview %+v, %v", preview, err)
+	}
+	if preview.String() != "the last 30 days" {
+		t.Errorf("Unexpected description %q", preview.String())
+	}
+
+	preview, err = ParsePreview("200", "")
+	if err != nil || preview.Count != 200 || preview.Days || preview.FromEnd {
+		t.Errorf("Unexpected preview %+v, %v", preview, err)
+	}
+
+	for _, size := range []string{"0", "-5", "d", "2w"} {
+		if _, err := ParsePreview(size, ""); err == nil {
+			t.Errorf("Expected an error for size %q", size)
+		}
+	}
+	if _, err := ParsePreview("10", "middle"); err == nil {
+		t.Error("Expected an error for an unknown end")
+	}
+}
+
+func TestPreviewApply(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2023, 5, d, hour, 0, 0, 0, time.UTC) }
+	messages := []models.Message{
+		{GUID: "1", FormattedDate: day(1, 9)},
+		{GUID: "2", FormattedDate: day(1, 23)},
+		{GUID: "3", FormattedDate: day(2, 8)},
+		{GUID: "4", FormattedDate: day(4, 8)},
+		{GUID: "5", FormattedDate: day(5, 20)},
+	}
+
+	kept, dropped := (&Preview{Count: 2}).Apply(messages)
+	assertGUIDs(t, kept, "1", "2")
+	if dropped != 3 {
+		t.Errorf("Expected 3 dropped, got %d", dropped)
+	}
+
+	kept, _ = (&Preview{Count: 2, FromEnd: true}).Apply(messages)
+	assertGUIDs(t, kept, "4", "5")
+
+	// Days count from the first message's day, whatever its time
+	kept, _ = (&Preview{Count: 2, Days: true}).Apply(messages)
+	assertGUIDs(t, kept, "1", "2", "3")
+
+	// May 4 and 5, including a day with no messages
+	kept, _ = (&Preview{Count: 2, Days: true, FromEnd: true}).Apply(messages)
+	assertGUIDs(t, kept, "4", "5")
+
+	kept, dropped = (&Preview{Count: 10}).Apply(messages)
+	if len(kept) != 5 || dropped != 0 {
+		t.Errorf("Expected every message kept, got %d", len(kept))
+	}
+
+	var none *Preview
+	if kept, _ := none.Apply(messages); len(kept) != 5 {
+		t.Error("Expected no preview to keep every message")
+	}
+}
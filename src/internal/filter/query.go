@@ -0,0 +1,322 @@
+package filter
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// QueryDateFormat is the layout of before: and after: dates
+const QueryDateFormat = "2006-01-02"
+
+// hasKinds are the values has: accepts
+var hasKinds = map[string]bool{
+	"attachment": true, "image": true, "video": true, "audio": true, "link": true, "reaction": true,
+}
+
+// Query is a parsed filter expression that keeps only the messages matching every term:
+//
+//	from:Alice has:image before:2022-07-01 text:"camping"
+//
+// from: names a contact as exclude_contacts does, or "me". has: is one of attachment,
+// image, video, audio, link or reaction. before: is exclusive and after: inclusive, both
+// YYYY-MM-DD and starting at midnight in the book's time zone. text: and bare words or "quoted phrases" match message text or
+// subject, ignoring case. A leading - negates a term.
+type Query struct {
+	source string
+	terms  []queryTerm
+}
+
+// queryTerm is one condition of a query
+type queryTerm struct {
+	field  string // from, has, before, after or text
+	value  string
+	date   time.Time // For before and after
+	negate bool
+}
+
+// ParseQuery parses a filter expression, with its dates in UTC. An empty expression parses
+// to nil, which matches every message.
+func ParseQuery(expr string) (*Query, error) {
+	return ParseQueryInLocation(expr, time.UTC)
+}
+
+// ParseQueryInLocation parses a filter expression like ParseQuery, with before: and after:
+// dates starting at midnight in loc
+func ParseQueryInLocation(expr string, loc *time.Location) (*Query, error) {
+	tokens, err := splitQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	q := &Query{source: strings.TrimSpace(expr)}
+	for _, token := range tokens {
+		term, err := parseTerm(token, loc)
+		if err != nil {
+			return nil, err
+		}
+		q.terms = append(q.terms, term)
+	}
+	return q, nil
+}
+
+// String returns the expression the query was parsed from
+func (q *Query) String() string {
+	if q == nil {
+		return ""
+	}
+	return q.source
+}
+
+// queryToken is a term as written, before its field is checked
+type queryToken struct {
+	field  string
+	value  string
+	negate bool
+}
+
+// splitQuery breaks an expression into terms at whitespace outside double quotes
+func splitQuery(expr string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var token queryToken
+		if runes[i] == '-' {
+			token.negate = true
+			i++
+		}
+
+		var text strings.Builder
+		quoted := false
+		for ; i < len(runes) && (quoted || !unicode.IsSpace(runes[i])); i++ {
+			switch {
+			case runes[i] == '"':
+				quoted = !quoted
+			case runes[i] == ':' && !quoted && token.field == "" && text.Len() > 0:
+				token.field = strings.ToLower(text.String())
+				text.Reset()
+			default:
+				text.WriteRune(runes[i])
+			}
+		}
+		if quoted {
+			return nil, fmt.Errorf("unterminated quote in filter %q", expr)
+		}
+
+		token.value = text.String()
+		if token.value == "" {
+			if token.field != "" {
+				return nil, fmt.Errorf("filter term %s: has no value", token.field)
+			}
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// parseTerm checks a token's field and value, reading dates in loc
+func parseTerm(token queryToken, loc *time.Location) (queryTerm, error) {
+	term := queryTerm{field: token.field, value: token.value, negate: token.negate}
+	switch token.field {
+	case "":
+		term.field = "text"
+	case "text", "from":
+	case "has":
+		term.value = strings.ToLower(token.value)
+		if !hasKinds[term.value] {
+			return term, fmt.Errorf("unknown filter has:%s (use attachment, image, video, audio, link or reaction)", token.value)
+		}
+	case "before", "after":
+		date, err := time.ParseInLocation(QueryDateFormat, token.value, loc)
+		if err != nil {
+			return term, fmt.Errorf("invalid date in filter %s:%s (use YYYY-MM-DD)", token.field, token.value)
+		}
+		term.date = date
+	default:
+		return term, fmt.Errorf("unknown filter field %q (use from, has, before, after or text)", token.field)
+	}
+	return term, nil
+}
+
+// SQL compiles the terms SQLite can evaluate into a predicate on the message table, aliased
+// m, with its arguments. The terms it can't, such as contact names, which come from the
+// config rather than the database, are returned as the rest, to be matched in memory once
+// the messages are loaded. The predicate is empty when no term compiles, and rest is nil
+// when every term does.
+func (q *Query) SQL() (string, []interface{}, *Query) {
+	if q == nil {
+		return "", nil, nil
+	}
+
+	var clauses []string
+	var args []interface{}
+	rest := &Query{source: q.source}
+	for _, term := range q.terms {
+		clause, termArgs, ok := term.sql()
+		if !ok {
+			rest.terms = append(rest.terms, term)
+			continue
+		}
+		if term.negate {
+			clause = "NOT (" + clause + ")"
+		}
+		clauses = append(clauses, clause)
+		args = append(args, termArgs...)
+	}
+
+	if len(rest.terms) == 0 {
+		rest = nil
+	}
+	return strings.Join(clauses, " AND "), args, rest
+}
+
+// sql returns the term as an SQL predicate, or false when SQLite can't match it exactly.
+// Media kinds are judged by MIME type alone here, without Match's file extension fallback.
+func (t queryTerm) sql() (string, []interface{}, bool) {
+	switch t.field {
+	case "from":
+		if !strings.EqualFold(t.value, "me") {
+			return "", nil, false
+		}
+		return "m.is_from_me = 1", nil, true
+	case "before":
+		return "m.date < ?", []interface{}{t.date.Sub(database.AppleEpoch).Nanoseconds()}, true
+	case "after":
+		return "m.date >= ?", []interface{}{t.date.Sub(database.AppleEpoch).Nanoseconds()}, true
+	case "text":
+		// LIKE only ignores the case of ASCII letters
+		if !isASCII(t.value) {
+			return "", nil, false
+		}
+		pattern := "%" + escapeLike(t.value) + "%"
+		return `(COALESCE(m.text, '') LIKE ? ESCAPE '\' OR COALESCE(m.subject, '') LIKE ? ESCAPE '\')`,
+			[]interface{}{pattern, pattern}, true
+	case "has":
+		switch t.value {
+		case "attachment":
+			return "m.cache_has_attachments = 1", nil, true
+		case "link":
+			return "(COALESCE(m.text, '') LIKE '%http://%' OR COALESCE(m.text, '') LIKE '%https://%')", nil, true
+		case "image", "video", "audio":
+			clause := `EXISTS (SELECT 1 FROM message_attachment_join maj JOIN attachment a ON a.ROWID = maj.attachment_id
+				WHERE maj.message_id = m.ROWID AND LOWER(a.mime_type) LIKE ?)`
+			if t.value == "audio" {
+				clause = "(m.is_audio_message = 1 OR " + clause + ")"
+			}
+			return clause, []interface{}{t.value + "/%"}, true
+		case "reaction":
+			// Reactions point at their message as p:N/<guid>, as GetReactions reads them
+			return `EXISTS (SELECT 1 FROM message r WHERE r.associated_message_guid = m.guid
+				OR r.associated_message_guid LIKE 'p:_/' || m.guid)`, nil, true
+		}
+	}
+	return "", nil, false
+}
+
+// Match reports whether msg matches every term. Media kinds are judged by msg.Attachments,
+// so they only match once attachments are loaded; reactions are looked up by GUID.
+func (q *Query) Match(msg *models.Message, handles map[int]models.Handle, reactions map[string][]models.Reaction) bool {
+	if q == nil {
+		return true
+	}
+	for _, term := range q.terms {
+		if term.match(msg, handles, reactions) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether msg satisfies the term, ignoring negation
+func (t queryTerm) match(msg *models.Message, handles map[int]models.Handle, reactions map[string][]models.Reaction) bool {
+	switch t.field {
+	case "from":
+		if strings.EqualFold(t.value, "me") {
+			return msg.IsFromMe
+		}
+		if msg.IsFromMe || msg.HandleID == nil {
+			return false
+		}
+		handle, exists := handles[*msg.HandleID]
+		return exists && matchesContact(handle, t.value)
+	case "before":
+		return msg.FormattedDate.Before(t.date)
+	case "after":
+		return !msg.FormattedDate.Before(t.date)
+	case "text":
+		phrases := []string{strings.ToLower(t.value)}
+		return containsAny(msg.Text, phrases) || containsAny(msg.Subject, phrases)
+	case "has":
+		switch t.value {
+		case "attachment":
+			return msg.HasAttachments
+		case "link":
+			return containsAny(msg.Text, []string{"http://", "https://"})
+		case "reaction":
+			return len(reactions[msg.GUID]) > 0
+		default:
+			return hasMedia(msg, t.value)
+		}
+	}
+	return false
+}
+
+// ApplyQuery drops messages that don't match q and returns how many were dropped
+func ApplyQuery(messages []models.Message, q *Query, handles map[int]models.Handle, reactions map[string][]models.Reaction) ([]models.Message, int) {
+	if q == nil {
+		return messages, 0
+	}
+	return keep(messages, func(msg *models.Message) bool {
+		return q.Match(msg, handles, reactions)
+	})
+}
+
+// hasMedia reports whether one of the message's attachments is an image, video or audio
+// file, by its MIME type or, failing that, its file extension
+func hasMedia(msg *models.Message, kind string) bool {
+	if kind == "audio" && msg.IsAudioMessage {
+		return true
+	}
+	for _, att := range msg.Attachments {
+		mimeType := ""
+		if att.MimeType != nil {
+			mimeType = *att.MimeType
+		} else if att.Filename != nil {
+			mimeType = mime.TypeByExtension(filepath.Ext(*att.Filename))
+		}
+		if strings.HasPrefix(strings.ToLower(mimeType), kind+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isASCII reports whether s has only ASCII characters
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeLike escapes LIKE wildcards so s matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
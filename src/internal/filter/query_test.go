@@ -0,0 +1,197 @@
+package filter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(`from:Alice -has:image before:2022-07-01 text:"camping trip" tent`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	want := []queryTerm{
+		{field: "from", value: "Alice"},
+		{field: "has", value: "image", negate: true},
+		{field: "before", value: "2022-07-01", date: time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{field: "text", value: "camping trip"},
+		{field: "text", value: "tent"},
+	}
+	if len(q.terms) != len(want) {
+		t.Fatalf("Expected %d terms, got %+v", len(want), q.terms)
+	}
+	for i := range want {
+		if q.terms[i] != want[i] {
+			t.Errorf("Term %d: expected %+v, got %+v", i, want[i], q.terms[i])
+		}
+	}
+
+	if q, err := ParseQuery("   "); q != nil || err != nil {
+		t.Errorf("Expected nil query for empty expression, got %v, %v", q, err)
+	}
+
+	for expr, wantErr := range map[string]string{
+		`text:"camping`:       "unterminated quote",
+		"has:gif":             "unknown filter has:gif",
+		"before:07/01/2022":   "invalid date",
+		"to:Alice":            `unknown filter field "to"`,
+		"from:":               "has no value",
+		"https://example.com": `unknown filter field "https"`,
+	} {
+		if _, err := ParseQuery(expr); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("ParseQuery(%q): expected error containing %q, got %v", expr, wantErr, err)
+		}
+	}
+}
+
+// queryFixture is a small conversation with Alice and Bob, in memory and as a database
+type queryFixture struct {
+	messages  []models.Message
+	handles   map[int]models.Handle
+	reactions map[string][]models.Reaction
+	db        *database.DB
+}
+
+func newQueryFixture(t *testing.T) *queryFixture {
+	t.Helper()
+
+	alice, bob := 1, 2
+	text := func(s string) *string { return &s }
+	png, pdf := "image/png", "application/pdf"
+	day := func(d int) time.Time { return time.Date(2022, 6, d, 12, 0, 0, 0, time.UTC) }
+
+	f := &queryFixture{
+		messages: []models.Message{
+			{ID: 1, GUID: "1", Text: text("Camping this weekend?"), HandleID: &alice, FormattedDate: day(28)},
+			{ID: 2, GUID: "2", Text: text("\uFFFC"), HandleID: &alice, HasAttachments: true, FormattedDate: day(29),
+				Attachments: []models.Attachment{{MimeType: &png}}},
+			{ID: 3, GUID: "3", Text: text("Tent list https://example.com/tents"), IsFromMe: true, FormattedDate: day(30)},
+			{ID: 4, GUID: "4", Text: text("\uFFFC"), HandleID: &bob, HasAttachments: true, FormattedDate: time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC),
+				Attachments: []models.Attachment{{MimeType: &pdf}}},
+			{ID: 5, GUID: "5", Text: text("Café après le camping"), HandleID: &bob, FormattedDate: time.Date(2022, 7, 2, 9, 0, 0, 0, time.UTC)},
+		},
+		handles: map[int]models.Handle{
+			alice: {ID: alice, Contact: "+15551234567", DisplayName: "Alice"},
+			bob:   {ID: bob, Contact: "bob@example.com", DisplayName: "Bob"},
+		},
+		reactions: map[string][]models.Reaction{"3": {{Type: 2000}}},
+	}
+
+	db, err := database.New(filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	f.db = db
+
+	conn := db.GetConnection()
+	if _, err := conn.Exec(database.Schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range f.handles {
+		conn.Exec(`INSERT INTO handle (ROWID, id, country, service) VALUES (?, ?, 'us', 'iMessage')`, h.ID, h.Contact)
+	}
+	for _, msg := range f.messages {
+		conn.Exec(`INSERT INTO message (ROWID, guid, text, date, is_from_me, handle_id, cache_has_attachments)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, msg.ID, msg.GUID, *msg.Text, msg.FormattedDate.Sub(database.AppleEpoch).Nanoseconds(),
+			msg.IsFromMe, msg.HandleID, msg.HasAttachments)
+		for i, att := range msg.Attachments {
+			id := msg.ID*10 + i
+			conn.Exec(`INSERT INTO attachment (ROWID, guid, mime_type) VALUES (?, ?, ?)`, id, msg.GUID, *att.MimeType)
+			conn.Exec(`INSERT INTO message_attachment_join (message_id, attachment_id) VALUES (?, ?)`, msg.ID, id)
+		}
+	}
+	conn.Exec(`INSERT INTO message (guid, date, handle_id, associated_message_guid, associated_message_type)
+		VALUES ('r1', 0, 1, 'p:0/3', 2000)`)
+	return f
+}
+
+func TestQueryMatchAndSQL(t *testing.T) {
+	f := newQueryFixture(t)
+
+	tests := map[string][]string{
+		"from:Alice":                             {"1", "2"},
+		"from:me":                                {"3"},
+		"-from:me":                               {"1", "2", "4", "5"},
+		"has:attachment":                         {"2", "4"},
+		"has:image":                              {"2"},
+		"-has:image has:attachment":              {"4"},
+		"has:link":                               {"3"},
+		"has:reaction":                           {"3"},
+		"before:2022-07-01":                      {"1", "2", "3"},
+		"after:2022-07-01":                       {"4", "5"},
+		"camping":                                {"1", "5"},
+		`text:"APRÈS LE"`:                        {"5"},
+		`from:bob@example.com -café`:             {"4"},
+		"from:Alice has:image before:2022-07-01": {"2"},
+	}
+
+	for expr, want := range tests {
+		q, err := ParseQuery(expr)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) failed: %v", expr, err)
+		}
+
+		// In memory
+		kept, removed := ApplyQuery(f.messages, q, f.handles, f.reactions)
+		if got := strings.Join(guids(kept), ","); got != strings.Join(want, ",") {
+			t.Errorf("%s in memory: expected %v, got %s", expr, want, got)
+		}
+		if removed != len(f.messages)-len(want) {
+			t.Errorf("%s in memory: expected %d removed, got %d", expr, len(f.messages)-len(want), removed)
+		}
+
+		// The SQL terms in the query, then the rest in memory
+		where, args, rest := q.SQL()
+		loaded, err := f.db.GetMessagesWhere(where, args...)
+		if err != nil {
+			t.Fatalf("%s: query failed: %v", expr, err)
+		}
+		kept, _ = ApplyQuery(loaded, rest, f.handles, nil)
+		if got := strings.Join(guids(kept), ","); got != strings.Join(want, ",") {
+			t.Errorf("%s in SQL: expected %v, got %s", expr, want, got)
+		}
+	}
+}
+
+func TestQuerySQLSplitsTerms(t *testing.T) {
+	q, err := ParseQuery("from:Alice from:me café has:link")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	where, args, rest := q.SQL()
+	if !strings.Contains(where, "m.is_from_me = 1") || !strings.Contains(where, "LIKE '%https://%'") || len(args) != 0 {
+		t.Errorf("Expected from:me and has:link in SQL, got %q %v", where, args)
+	}
+	if rest == nil || len(rest.terms) != 2 || rest.terms[0].value != "Alice" || rest.terms[1].value != "café" {
+		t.Errorf("Expected contact name and non-ASCII text left to match in memory, got %+v", rest)
+	}
+
+	if _, _, rest := (&Query{terms: []queryTerm{{field: "has", value: "link"}}}).SQL(); rest != nil {
+		t.Errorf("Expected nothing left when every term compiles, got %+v", rest)
+	}
+}
+
+func TestParseQueryInLocation(t *testing.T) {
+	zone := time.FixedZone("UTC-4", -4*60*60)
+	q, err := ParseQueryInLocation("after:2022-07-01", zone)
+	if err != nil {
+		t.Fatalf("ParseQueryInLocation failed: %v", err)
+	}
+
+	// 2:00 UTC on July 1 is still June 30 four hours west
+	late := models.Message{FormattedDate: time.Date(2022, 7, 1, 2, 0, 0, 0, time.UTC)}
+	if q.Match(&late, nil, nil) {
+		t.Error("Expected after: to start at midnight in the query's zone")
+	}
+	morning := models.Message{FormattedDate: time.Date(2022, 7, 1, 5, 0, 0, 0, time.UTC)}
+	if !q.Match(&morning, nil, nil) {
+		t.Error("Expected a message after midnight in the query's zone to match")
+	}
+}
@@ -0,0 +1,69 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"threadbound/internal/models"
+)
+
+// otpKeywordRegex matches the wording services use around one-time codes
+var otpKeywordRegex = regexp.MustCompile(`(?i)\b(code|passcode|otp|one[- ]time|verification|verify|2fa|two[- ]factor|authenticat\w*)\b`)
+
+// otpCodeRegex matches the code itself: 4-8 digits, optionally split or prefixed (G-123456, 123-456)
+var otpCodeRegex = regexp.MustCompile(`\b(?:[A-Z]-)?\d{3,4}[- ]?\d{1,4}\b`)
+
+// Spam drops messages received from short codes and alphanumeric sender IDs, along with
+// received one-time passcodes such as "Your verification code is 123456". Messages sent
+// by you are always kept.
+func Spam(messages []models.Message, handles map[int]models.Handle) ([]models.Message, int) {
+	return keep(messages, func(msg *models.Message) bool {
+		if msg.IsFromMe {
+			return true
+		}
+		if msg.HandleID != nil {
+			if handle, exists := handles[*msg.HandleID]; exists && IsShortCode(handle.Contact) {
+				return false
+			}
+		}
+		return msg.Text == nil || !IsOTP(*msg.Text)
+	})
+}
+
+// IsShortCode reports whether contact is an automated sender rather than a person: a short
+// code of at most six digits (e.g. 32665) or an alphanumeric sender ID (e.g. "AMAZON")
+func IsShortCode(contact string) bool {
+	contact = strings.TrimSpace(contact)
+	if contact == "" || strings.Contains(contact, "@") {
+		return false
+	}
+
+	digits, letters := 0, 0
+	for _, r := range contact {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case unicode.IsLetter(r):
+			letters++
+		case strings.ContainsRune("+-(). ", r):
+		default:
+			return false
+		}
+	}
+
+	if letters > 0 {
+		// Alphanumeric sender IDs are limited to 11 characters by the SMS spec
+		return len(contact) <= 11
+	}
+	return digits >= 3 && digits <= 6
+}
+
+// IsOTP reports whether text looks like a one-time passcode message
+func IsOTP(text string) bool {
+	// Codes arrive in short texts; longer messages that mention a code are usually conversation
+	if len(text) > 300 {
+		return false
+	}
+	return otpKeywordRegex.MatchString(text) && otpCodeRegex.MatchString(text)
+}
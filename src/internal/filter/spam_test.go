@@ -0,0 +1,74 @@
+package filter
+
+import (
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestIsShortCode(t *testing.T) {
+	tests := []struct {
+		contact  string
+		expected bool
+	}{
+		{"32665", true},
+		{"262966", true},
+		{"AMAZON", true},
+		{"Chase-Bank", true},
+		{"+15551234567", false},
+		{"(555) 123-4567", false},
+		{"friend@example.com", false},
+		{"12", false},
+		{"VeryLongSenderName", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsShortCode(tt.contact); got != tt.expected {
+			t.Errorf("IsShortCode(%q) = %v, expected %v", tt.contact, got, tt.expected)
+		}
+	}
+}
+
+func TestIsOTP(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"Your verification code is 123456", true},
+		{"G-482913 is your Google verification code.", true},
+		{"Your Apple ID Code is: 634 128. Don't share it with anyone.", true},
+		{"Use 8841 to authenticate your login", true},
+		{"See you at 7!", false},
+		{"The code for dinner is casual", false},
+		{"Call me at 5551234567", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsOTP(tt.text); got != tt.expected {
+			t.Errorf("IsOTP(%q) = %v, expected %v", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestSpam(t *testing.T) {
+	text := func(s string) *string { return &s }
+	alice, shortCode := 1, 2
+	handles := map[int]models.Handle{
+		alice:     {ID: alice, Contact: "+15551234567"},
+		shortCode: {ID: shortCode, Contact: "32665"},
+	}
+	messages := []models.Message{
+		{GUID: "1", Text: text("Lunch?"), HandleID: &alice},
+		{GUID: "2", Text: text("50% off everything today only!"), HandleID: &shortCode},
+		{GUID: "3", Text: text("Your code is 123456"), HandleID: &alice},
+		{GUID: "4", Text: text("My new verification code is 9912, can you check it?"), IsFromMe: true},
+		{GUID: "5", Text: text("Sure")},
+	}
+
+	kept, removed := Spam(messages, handles)
+	if removed != 2 {
+		t.Errorf("Expected 2 removed, got %d", removed)
+	}
+	assertGUIDs(t, kept, "1", "4", "5")
+}
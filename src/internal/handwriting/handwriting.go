@@ -0,0 +1,104 @@
+// Package handwriting recovers handwritten and Digital Touch messages, which Messages stores
+// as a drawing in payload_data rather than as text or an attachment
+package handwriting
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+
+	"threadbound/internal/models"
+	"threadbound/internal/plist"
+)
+
+// Labels shown in the bubble, with the drawing (when one can be recovered) attached below
+const (
+	HandwritingLabel  = "✍️ Handwritten message"
+	DigitalTouchLabel = "👆 Digital Touch message"
+)
+
+// Label returns the bubble text for a handwritten or Digital Touch message
+func Label(msg models.Message) string {
+	if msg.BalloonBundleID != nil && *msg.BalloonBundleID == models.HandwritingBundleID {
+		return HandwritingLabel
+	}
+	return DigitalTouchLabel
+}
+
+// Image finds the rendered drawing in a message payload. The payload may be the image
+// itself or a plist with the image stored as data; the largest image found is returned.
+func Image(payload []byte) (image.Image, error) {
+	if img, _, err := image.Decode(bytes.NewReader(payload)); err == nil {
+		return img, nil
+	}
+
+	root, err := plist.Decode(payload)
+	if err != nil {
+		return nil, fmt.Errorf("payload is neither an image nor a plist: %w", err)
+	}
+
+	var best image.Image
+	walk(root, func(data []byte) {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		if best == nil || area(img) > area(best) {
+			best = img
+		}
+	})
+	if best == nil {
+		return nil, fmt.Errorf("payload has no rendered image")
+	}
+	return best, nil
+}
+
+// Extract writes the drawing in payload to path as a PNG. Strokes are drawn on a transparent
+// background, so the image is flattened onto white to survive conversion to JPEG for print.
+func Extract(payload []byte, path string) error {
+	img, err := Image(payload)
+	if err != nil {
+		return err
+	}
+
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(out, flat); err != nil {
+		out.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return out.Close()
+}
+
+// walk calls fn for every data value in a decoded plist
+func walk(value interface{}, fn func([]byte)) {
+	switch v := value.(type) {
+	case []byte:
+		fn(v)
+	case []interface{}:
+		for _, item := range v {
+			walk(item, fn)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			walk(item, fn)
+		}
+	}
+}
+
+func area(img image.Image) int {
+	return img.Bounds().Dx() * img.Bounds().Dy()
+}
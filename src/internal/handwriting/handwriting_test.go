@@ -0,0 +1,71 @@
+package handwriting
+
+import (
+	"encoding/hex"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+// strokesArchive is a keyed archive holding a 2x1 PNG of one black pixel beside a transparent
+// one, generated with Python's plistlib
+const strokesArchive = "" +
+	"62706c6973743030d2010203045924617263686976657258246f626a656374735f100f4e534b65796564417263686976" +
+	"6572a305060955246e756c6cd10708577374726f6b657380024f104889504e470d0a1a0a0000000d4948445200000002" +
+	"000000010806000000f4227f8a0000000f49444154789c63606060f80fc40c0005040100b00548920000000049454e44" +
+	"ae426082080d172032363c3f47490000000000000101000000000000000a00000000000000000000000000000094"
+
+func TestExtract(t *testing.T) {
+	payload, err := hex.DecodeString(strokesArchive)
+	if err != nil {
+		t.Fatalf("Invalid fixture: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "drawing.png")
+	if err := Extract(payload, path); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Expected a PNG, got: %v", err)
+	}
+
+	// The stroke stays black and the transparent background becomes white
+	if r, g, b, _ := img.At(0, 0).RGBA(); r != 0 || g != 0 || b != 0 {
+		t.Errorf("Expected black stroke, got %v", img.At(0, 0))
+	}
+	if got := color.RGBAModel.Convert(img.At(1, 0)); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("Expected white background, got %v", got)
+	}
+}
+
+func TestExtractWithoutImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drawing.png")
+	if err := Extract([]byte("compressed strokes we cannot read"), path); err == nil {
+		t.Error("Expected an error for a payload without an image")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected no file to be written")
+	}
+}
+
+func TestLabel(t *testing.T) {
+	handwritten := models.HandwritingBundleID
+	touch := models.DigitalTouchBundleID
+	if got := Label(models.Message{BalloonBundleID: &handwritten}); got != HandwritingLabel {
+		t.Errorf("Expected %q, got %q", HandwritingLabel, got)
+	}
+	if got := Label(models.Message{BalloonBundleID: &touch}); got != DigitalTouchLabel {
+		t.Errorf("Expected %q, got %q", DigitalTouchLabel, got)
+	}
+}
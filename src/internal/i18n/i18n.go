@@ -0,0 +1,315 @@
+// Package i18n translates the text threadbound writes into books, such as headings, "Me"
+// and the copyright page, and formats dates with the month and weekday names of a locale.
+// Each locale's names and date formats are a YAML catalog embedded from catalogs/, and its
+// messages a go-i18n message file embedded from messages/; messages missing from one fall
+// back to English.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	i18ntemplate "github.com/nicksnyder/go-i18n/v2/i18n/template"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is the locale of books that don't set one
+const DefaultLocale = "en"
+
+// DateStyle names a date format, which each locale writes in its own order
+type DateStyle string
+
+// Date styles, with how English writes them
+const (
+	DayFormat      DateStyle = "day"        // Monday, January 2, 2006
+	DateFormat     DateStyle = "date"       // January 2, 2006
+	ShortDate      DateStyle = "short_date" // Jan 2, 2006
+	MonthFormat    DateStyle = "month"      // January 2006
+	MonthDay       DateStyle = "month_day"  // January 2
+	DayMonth       DateStyle = "day_month"  // Monday, January 2
+	TimeFormat     DateStyle = "time"       // 3:04 PM
+	DateTimeFormat DateStyle = "date_time"  // January 2, 2006, 3:04 PM
+	DayTimeFormat  DateStyle = "day_time"   // Monday, January 2, 2006, 3:04 PM
+)
+
+//go:embed catalogs/*.yaml messages/*.yaml
+var catalogFS embed.FS
+
+// catalog is a locale's names and date formats
+type catalog struct {
+	Name          string               `yaml:"name"`
+	Months        []string             `yaml:"months"`         // January first
+	MonthsShort   []string             `yaml:"months_short"`   // Jan first
+	Weekdays      []string             `yaml:"weekdays"`       // Sunday first, as time.Weekday counts
+	WeekdaysShort []string             `yaml:"weekdays_short"` // Sun first
+	Numbers       []string             `yaml:"numbers"`        // Small numbers spelled out, zero first
+	Stopwords     []string             `yaml:"stopwords"`      // Common words left out of word clouds, lowercase
+	Formats       map[DateStyle]string `yaml:"formats"`        // Go layouts, with English names replaced by the locale's
+
+	stopwords map[string]bool
+}
+
+// messageParser fills in messages' template actions, such as {{.Count}}, leaving out any
+// the caller gave no value for
+var messageParser = &i18ntemplate.TextParser{Option: "missingkey=zero"}
+
+var (
+	catalogs     map[string]*catalog
+	bundle       *goi18n.Bundle
+	catalogsErr  error
+	catalogsOnce sync.Once
+)
+
+// loadCatalogs parses every embedded catalog, keyed by locale, and loads every message
+// file into the bundle
+func loadCatalogs() (map[string]*catalog, error) {
+	catalogsOnce.Do(func() {
+		catalogs = make(map[string]*catalog)
+		files, err := catalogFS.ReadDir("catalogs")
+		if err != nil {
+			catalogsErr = err
+			return
+		}
+		for _, file := range files {
+			data, err := catalogFS.ReadFile(path.Join("catalogs", file.Name()))
+			if err != nil {
+				catalogsErr = err
+				return
+			}
+			var c catalog
+			if err := yaml.Unmarshal(data, &c); err != nil {
+				catalogsErr = fmt.Errorf("failed to parse catalog %s: %w", file.Name(), err)
+				return
+			}
+			c.stopwords = make(map[string]bool, len(c.Stopwords))
+			for _, word := range c.Stopwords {
+				c.stopwords[word] = true
+			}
+			catalogs[strings.TrimSuffix(file.Name(), path.Ext(file.Name()))] = &c
+		}
+
+		bundle = goi18n.NewBundle(language.English)
+		bundle.RegisterUnmarshalFunc("yaml", yaml.Unmarshal)
+		if files, err = catalogFS.ReadDir("messages"); err != nil {
+			catalogsErr = err
+			return
+		}
+		for _, file := range files {
+			if _, err := bundle.LoadMessageFileFS(catalogFS, path.Join("messages", file.Name())); err != nil {
+				catalogsErr = fmt.Errorf("failed to parse messages %s: %w", file.Name(), err)
+				return
+			}
+		}
+	})
+	return catalogs, catalogsErr
+}
+
+// Locales lists the locales books can be written in, sorted
+func Locales() []string {
+	loaded, _ := loadCatalogs()
+	locales := make([]string, 0, len(loaded))
+	for locale := range loaded {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Localizer writes the text and dates of a book in one locale
+type Localizer struct {
+	locale   string
+	catalog  *catalog
+	fallback *catalog // English, for names and formats the locale's catalog lacks
+	messages *goi18n.Localizer
+}
+
+// Lookup returns the localizer for a locale such as fr, or fr-CA or fr_CA for French. An
+// empty locale is English.
+func Lookup(locale string) (*Localizer, error) {
+	loaded, err := loadCatalogs()
+	if err != nil {
+		return nil, err
+	}
+	language := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(language, "-_."); i >= 0 {
+		language = language[:i]
+	}
+	if language == "" {
+		language = DefaultLocale
+	}
+
+	c, exists := loaded[language]
+	if !exists {
+		return nil, fmt.Errorf("unknown locale %q (use %s)", locale, strings.Join(Locales(), ", "))
+	}
+	return &Localizer{
+		locale:   language,
+		catalog:  c,
+		fallback: loaded[DefaultLocale],
+		messages: goi18n.NewLocalizer(bundle, language),
+	}, nil
+}
+
+// For returns the localizer for a locale, or English when the locale is unknown
+func For(locale string) *Localizer {
+	if l, err := Lookup(locale); err == nil {
+		return l
+	}
+	l, err := Lookup(DefaultLocale)
+	if err != nil {
+		panic(err) // The English catalog is embedded, so this can't fail
+	}
+	return l
+}
+
+// Locale returns the language the localizer writes, such as fr
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// T returns the message with the given ID. Its template actions are filled in from args,
+// which alternate names and values: T("volume_of", "Number", 1, "Count", 3).
+func (l *Localizer) T(id string, args ...interface{}) string {
+	return l.localize(&goi18n.LocalizeConfig{MessageID: id, TemplateData: templateData(args)})
+}
+
+// Plural returns the form of the message with the given ID for count, which its template
+// actions see as Count, as well as any args: Plural("messages", 3) is "3 messages".
+func (l *Localizer) Plural(id string, count int, args ...interface{}) string {
+	return l.localize(&goi18n.LocalizeConfig{
+		MessageID:    id,
+		PluralCount:  count,
+		TemplateData: templateData(append([]interface{}{"Count", count}, args...)),
+	})
+}
+
+// localize finds a message in the locale's messages, else in English, and returns its ID
+// when neither has it. go-i18n picks the plural form by the locale's rules, and uses the
+// other form when a translation lacks the one they call for.
+func (l *Localizer) localize(config *goi18n.LocalizeConfig) string {
+	config.TemplateParser = messageParser
+	text, err := l.messages.Localize(config)
+	if text == "" && err != nil {
+		return config.MessageID
+	}
+	return text
+}
+
+// templateData turns args, alternating names and values, into a message's template data
+func templateData(args []interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		if name, ok := args[i].(string); ok {
+			data[name] = args[i+1]
+		}
+	}
+	return data
+}
+
+// Format writes t in one of the date styles
+func (l *Localizer) Format(t time.Time, style DateStyle) string {
+	layout, exists := l.catalog.Formats[style]
+	if !exists {
+		layout = l.fallback.Formats[style]
+	}
+	return l.FormatLayout(t, layout)
+}
+
+// nameTokens are the parts of a Go layout that write English names, longest first so
+// January isn't taken for Jan
+var nameTokens = []string{"January", "Monday", "Jan", "Mon"}
+
+// FormatLayout formats t like time.Format, with the locale's month and weekday names in
+// place of January, Jan, Monday and Mon
+func (l *Localizer) FormatLayout(t time.Time, layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		index, token := -1, ""
+		for _, candidate := range nameTokens {
+			if i := strings.Index(layout, candidate); i >= 0 && (index < 0 || i < index) {
+				index, token = i, candidate
+			}
+		}
+		if index < 0 {
+			b.WriteString(t.Format(layout))
+			break
+		}
+		b.WriteString(t.Format(layout[:index]))
+		b.WriteString(l.name(t, token))
+		layout = layout[index+len(token):]
+	}
+	return b.String()
+}
+
+// name returns the locale's name for t's month or weekday, in the form of a layout token
+func (l *Localizer) name(t time.Time, token string) string {
+	switch token {
+	case "January":
+		return l.MonthName(t.Month())
+	case "Jan":
+		return pick(l.catalog.MonthsShort, l.fallback.MonthsShort, int(t.Month())-1)
+	case "Monday":
+		return l.WeekdayName(t.Weekday())
+	default:
+		return pick(l.catalog.WeekdaysShort, l.fallback.WeekdaysShort, int(t.Weekday()))
+	}
+}
+
+// MonthName returns the locale's name for a month, such as janvier
+func (l *Localizer) MonthName(month time.Month) string {
+	return pick(l.catalog.Months, l.fallback.Months, int(month)-1)
+}
+
+// WeekdayName returns the locale's name for a day of the week, such as lundi
+func (l *Localizer) WeekdayName(day time.Weekday) string {
+	return pick(l.catalog.Weekdays, l.fallback.Weekdays, int(day))
+}
+
+// ShortWeekdayName returns the locale's abbreviation for a day of the week, such as lun.
+func (l *Localizer) ShortWeekdayName(day time.Weekday) string {
+	return pick(l.catalog.WeekdaysShort, l.fallback.WeekdaysShort, int(day))
+}
+
+// pick returns names[i], or fallback[i] when the locale's catalog doesn't list it
+func pick(names, fallback []string, i int) string {
+	if i >= 0 && i < len(names) {
+		return names[i]
+	}
+	return fallback[i]
+}
+
+// Number spells out n in the locale's words, such as three or trois, when the catalog
+// has a word for it, and writes it in digits otherwise
+func (l *Localizer) Number(n int) string {
+	if n >= 0 && n < len(l.catalog.Numbers) {
+		return l.catalog.Numbers[n]
+	}
+	return strconv.Itoa(n)
+}
+
+// IsStopword reports whether word, lowercase, is too common in the locale to say anything
+// about a conversation, such as "the" or "und"
+func (l *Localizer) IsStopword(word string) bool {
+	return l.catalog.stopwords[word]
+}
+
+// Funcs returns the functions templates translate with: t and plural, as T and Plural, and
+// date, as Format with a style name such as {{date .Month "month"}}
+func (l *Localizer) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"t":      l.T,
+		"plural": l.Plural,
+		"date": func(t time.Time, style string) string {
+			return l.Format(t, DateStyle(style))
+		},
+	}
+}
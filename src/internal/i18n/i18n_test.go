@@ -0,0 +1,172 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		{"de-AT", "de"},
+		{"es_MX.UTF-8", "es"},
+		{" FR ", "fr"},
+	}
+	for _, test := range tests {
+		l, err := Lookup(test.locale)
+		if err != nil {
+			t.Errorf("Lookup(%q) failed: %v", test.locale, err)
+			continue
+		}
+		if l.Locale() != test.want {
+			t.Errorf("Lookup(%q) = %s, expected %s", test.locale, l.Locale(), test.want)
+		}
+	}
+
+	if _, err := Lookup("tlh"); err == nil || !strings.Contains(err.Error(), "de, en, es, fr") {
+		t.Errorf("Expected an error listing the locales, got %v", err)
+	}
+	if For("tlh").Locale() != DefaultLocale {
+		t.Error("Expected an unknown locale to fall back to English")
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	english := For(DefaultLocale).catalog
+	englishMessages := parseMessages(t, DefaultLocale)
+	for _, locale := range Locales() {
+		c := For(locale).catalog
+		if len(c.Months) != 12 || len(c.MonthsShort) != 12 || len(c.Weekdays) != 7 || len(c.WeekdaysShort) != 7 {
+			t.Errorf("%s: expected 12 months and 7 weekdays in each form", locale)
+		}
+		if len(c.Numbers) != len(english.Numbers) {
+			t.Errorf("%s: expected numbers spelled out up to %d", locale, len(english.Numbers)-1)
+		}
+		if len(c.Stopwords) == 0 {
+			t.Errorf("%s: expected stopwords", locale)
+		}
+		for _, word := range c.Stopwords {
+			if word != strings.ToLower(word) {
+				t.Errorf("%s: expected stopword %q in lowercase", locale, word)
+			}
+		}
+		for style := range english.Formats {
+			if c.Formats[style] == "" {
+				t.Errorf("%s: missing date format %s", locale, style)
+			}
+		}
+
+		messages := parseMessages(t, locale)
+		for id, m := range englishMessages {
+			translated, exists := messages[id]
+			if !exists {
+				t.Errorf("%s: missing message %s", locale, id)
+			} else if (m.One == "") != (translated.One == "") {
+				t.Errorf("%s: message %s should have the same forms as in English", locale, id)
+			}
+		}
+	}
+}
+
+// parseMessages reads a locale's message file, keyed by message ID
+func parseMessages(t *testing.T, locale string) map[string]*goi18n.Message {
+	t.Helper()
+	data, err := catalogFS.ReadFile("messages/" + locale + ".yaml")
+	if err != nil {
+		t.Fatalf("%s: %v", locale, err)
+	}
+	file, err := goi18n.ParseMessageFileBytes(data, locale+".yaml", map[string]goi18n.UnmarshalFunc{"yaml": yaml.Unmarshal})
+	if err != nil {
+		t.Fatalf("%s: %v", locale, err)
+	}
+	messages := make(map[string]*goi18n.Message, len(file.Messages))
+	for _, m := range file.Messages {
+		messages[m.ID] = m
+	}
+	return messages
+}
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2023, time.March, 9, 21, 5, 0, 0, time.UTC) // A Thursday
+	tests := []struct {
+		locale string
+		style  DateStyle
+		want   string
+	}{
+		{"en", DayFormat, "Thursday, March 9, 2023"},
+		{"en", ShortDate, "Mar 9, 2023"},
+		{"en", TimeFormat, "9:05 PM"},
+		{"de", DayFormat, "Donnerstag, 9. März 2023"},
+		{"de", TimeFormat, "21:05"},
+		{"es", DayFormat, "jueves, 9 de marzo de 2023"},
+		{"fr", DayFormat, "jeudi 9 mars 2023"},
+		{"fr", ShortDate, "9 mars 2023"},
+		{"fr", MonthFormat, "mars 2023"},
+	}
+	for _, test := range tests {
+		if got := For(test.locale).Format(date, test.style); got != test.want {
+			t.Errorf("%s %s: expected %q, got %q", test.locale, test.style, test.want, got)
+		}
+	}
+
+	if got := For("fr").FormatLayout(date, "Mon Jan 2"); got != "jeu. mars 9" {
+		t.Errorf("Expected short names in place of Mon and Jan, got %q", got)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	en, fr := For("en"), For("fr")
+	tests := []struct {
+		got, want string
+	}{
+		{en.T("me"), "Me"},
+		{fr.T("me"), "Moi"},
+		{en.T("volume_of", "Number", 2, "Count", 3), "Volume 2 of 3"},
+		{en.Plural("messages", 1), "1 message"},
+		{en.Plural("messages", 0), "0 messages"},
+		{fr.Plural("messages", 0), "0 message"},
+		{en.Plural("streak", 4, "Start", "May 1", "End", "May 4"), "4 days in a row, from May 1 to May 4"},
+		{en.T("no_such_message"), "no_such_message"},
+		{en.Number(3), "three"},
+		{fr.Number(12), "douze"},
+		{en.Number(13), "13"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("Expected %q, got %q", test.want, test.got)
+		}
+	}
+}
+
+func TestIsStopword(t *testing.T) {
+	en, de := For("en"), For("de")
+	if !en.IsStopword("the") || !en.IsStopword("don't") || en.IsStopword("pizza") {
+		t.Error("Expected English stopwords to be found and other words not")
+	}
+	if !de.IsStopword("und") || de.IsStopword("the") {
+		t.Error("Expected only German stopwords in German")
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	tmpl := template.Must(template.New("test").Funcs(For("de").Funcs()).Parse(
+		`{{t "by_the_numbers"}}: {{plural "photos" .Photos}}, {{date .Month "month"}}`))
+
+	var b strings.Builder
+	err := tmpl.Execute(&b, map[string]interface{}{"Photos": 3, "Month": time.Date(2023, time.May, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "In Zahlen: 3 Fotos, Mai 2023"; b.String() != want {
+		t.Errorf("Expected %q, got %q", want, b.String())
+	}
+}
@@ -5,25 +5,50 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"threadbound/internal/models"
+	"threadbound/internal/timing"
+	"threadbound/internal/workspace"
 )
 
-// Builder handles PDF generation using XeLaTeX
+// includeRegex finds the files a split book includes
+var includeRegex = regexp.MustCompile(`(?m)^\\include\{([^}]*)\}`)
+
+// Builder handles PDF generation with a TeX engine, XeLaTeX unless configured otherwise
 type Builder struct {
-	config *models.BookConfig
+	config      *models.BookConfig
+	timings     *timing.Recorder
+	pageCount   int
+	includeOnly []string
 }
 
-// NewBuilder creates a new XeLaTeX builder
+// NewBuilder creates a new PDF builder
 func NewBuilder(config *models.BookConfig) *Builder {
 	return &Builder{config: config}
 }
 
-// BuildPDF converts TeX to PDF using XeLaTeX
-func (b *Builder) BuildPDF(inputFile, outputFile string) error {
-	// Check if XeLaTeX is available
-	if err := b.checkXeLaTeX(); err != nil {
+// SetTimings records each engine pass in the given recorder
+func (b *Builder) SetTimings(timings *timing.Recorder) {
+	b.timings = timings
+}
+
+// SetIncludeOnly builds only the given \include'd files of a split book, by the names
+// they're included with, leaving the other chapters out
+func (b *Builder) SetIncludeOnly(names []string) {
+	b.includeOnly = names
+}
+
+// BuildPDF converts TeX to PDF with the engine chosen by pdf_engine
+func (b *Builder) BuildPDF(inputFile, outputFile string) (err error) {
+	engine, err := SelectEngine(b.config.PDFEngine, Installed)
+	if err != nil {
+		return err
+	}
+
+	// Check the engine is available
+	if err := b.checkEngine(engine); err != nil {
 		return err
 	}
 
@@ -32,45 +57,89 @@ func (b *Builder) BuildPDF(inputFile, outputFile string) error {
 		return fmt.Errorf("input file not found: %s", inputFile)
 	}
 
-	fmt.Printf("🔨 Building PDF with XeLaTeX...\n")
-	fmt.Printf("📄 Input: %s\n", inputFile)
-	fmt.Printf("📖 Output: %s\n", outputFile)
+	b.config.Log().Info("🔨 Building PDF", "engine", engine.Name())
+	b.config.Log().Info("📄 Input", "path", inputFile)
+	b.config.Log().Info("📖 Output", "path", outputFile)
 	if b.config != nil {
-		fmt.Printf("📐 Page Size: %s x %s\n", b.config.PageWidth, b.config.PageHeight)
+		b.config.Log().Info("📐 Page size", "width", b.config.PageWidth, "height", b.config.PageHeight)
 	}
 
-	// Get output directory and base filename
-	outputDir := filepath.Dir(outputFile)
-	baseFilename := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+	// The engine writes its intermediate files to a workspace of its own, from which the
+	// PDF is moved out once it's built. A failed build's workspace is kept, with its log.
+	ws, err := workspace.New(b.config.WorkDir, "pdf")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			b.config.Log().Info("🗂️  Build files kept", "dir", ws.Dir)
+			return
+		}
+		ws.Remove()
+	}()
+	outputDir := ws.Dir
+	baseFilename := jobName(inputFile)
 
-	// Clean up XeLaTeX temporary files after completion
-	defer b.cleanupXeLaTeXFiles(filepath.Join(outputDir, baseFilename))
+	// The engine writes an .aux for each included file under the output directory but
+	// won't create the folders for them
+	included, err := prepareIncludes(inputFile, outputDir)
+	if err != nil {
+		return err
+	}
 
-	// Run XeLaTeX multiple times for TOC and cross-references
-	// Pass 1: Generate .aux files
-	fmt.Printf("🔄 XeLaTeX pass 1/3...\n")
-	if err := b.runXeLaTeX(inputFile, outputDir); err != nil {
-		return fmt.Errorf("xelatex pass 1 failed: %w", err)
+	logFile := filepath.Join(outputDir, baseFilename+".log")
+	if engine.FetchesPackages() {
+		b.config.Log().Info("📦 Downloading the packages the engine needs on first use, which can take a few minutes", "engine", engine.Name())
 	}
+	if engine.Reruns() {
+		b.config.Log().Info("🔄 Running", "engine", engine.Name())
+		stop := b.timings.Start(engine.Name())
+		err := b.runEngine(engine, inputFile, outputDir)
+		stop()
+		if err != nil {
+			return err
+		}
+	} else {
+		// Pass 1 writes the .aux and .toc files, and each later pass reads them back,
+		// until a pass leaves them as they were and asks for no rerun
+		auxFiles := []string{
+			filepath.Join(outputDir, baseFilename+".aux"),
+			filepath.Join(outputDir, baseFilename+".toc"),
+			filepath.Join(outputDir, baseFilename+".out"),
+		}
+		for _, name := range included {
+			auxFiles = append(auxFiles, filepath.Join(outputDir, filepath.FromSlash(name)+".aux"))
+		}
 
-	// Pass 2: Read .aux and generate TOC
-	fmt.Printf("🔄 XeLaTeX pass 2/3...\n")
-	if err := b.runXeLaTeX(inputFile, outputDir); err != nil {
-		return fmt.Errorf("xelatex pass 2 failed: %w", err)
+		for pass := 1; ; pass++ {
+			before := auxState(auxFiles)
+			b.config.Log().Info("🔄 Running again", "engine", engine.Name(), "pass", pass)
+			stop := b.timings.Start(fmt.Sprintf("%s pass %d", engine.Name(), pass))
+			err := b.runEngine(engine, inputFile, outputDir)
+			stop()
+			if err != nil {
+				return fmt.Errorf("%s pass %d failed: %w", engine.Name(), pass, err)
+			}
+
+			log, _ := os.ReadFile(logFile)
+			if auxState(auxFiles) == before && !NeedsRerun(log) {
+				break
+			}
+			if pass == MaxPasses {
+				b.config.Log().Warn("⚠️  Cross-references still changing; page numbers in the contents may be off", "passes", MaxPasses)
+				break
+			}
+		}
 	}
 
-	// Pass 3: Finalize page numbers in TOC
-	fmt.Printf("🔄 XeLaTeX pass 3/3...\n")
-	if err := b.runXeLaTeX(inputFile, outputDir); err != nil {
-		return fmt.Errorf("xelatex pass 3 failed: %w", err)
+	// Read the page count before the log is cleaned up
+	if log, err := os.ReadFile(logFile); err == nil {
+		b.pageCount = ParseLogPageCount(log)
 	}
 
 	// Move the generated PDF to the desired output location
-	generatedPDF := filepath.Join(outputDir, baseFilename+".pdf")
-	if generatedPDF != outputFile {
-		if err := os.Rename(generatedPDF, outputFile); err != nil {
-			return fmt.Errorf("failed to move PDF to output location: %w", err)
-		}
+	if err := ws.Export(baseFilename+".pdf", outputFile); err != nil {
+		return fmt.Errorf("failed to move PDF to output location: %w", err)
 	}
 
 	// Check if output file was created
@@ -78,80 +147,135 @@ func (b *Builder) BuildPDF(inputFile, outputFile string) error {
 		return fmt.Errorf("PDF was not created: %s", outputFile)
 	}
 
-	fmt.Printf("✅ PDF generated successfully: %s\n", outputFile)
+	if b.pageCount == 0 {
+		b.pageCount, _ = PDFPageCount(outputFile)
+	}
+
+	b.config.Log().Info("✅ PDF generated successfully", "path", outputFile)
 	return nil
 }
 
-// runXeLaTeX executes a single XeLaTeX compilation pass
-func (b *Builder) runXeLaTeX(inputFile, outputDir string) error {
-	args := []string{
-		"-interaction=nonstopmode",
-		"-output-directory=" + outputDir,
-		inputFile,
+// PageCount returns the number of pages in the last PDF built, or 0 if it couldn't be read
+func (b *Builder) PageCount() int {
+	return b.pageCount
+}
+
+// runEngine executes a single run of the engine
+func (b *Builder) runEngine(engine Engine, inputFile, outputDir string) error {
+	args, err := engine.Command(inputFile, outputDir, b.includeOnly)
+	if err != nil {
+		return err
 	}
 
-	cmd := exec.Command("xelatex", args...)
+	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = "."
 
 	// Capture output
 	output, err := cmd.CombinedOutput()
 
-	// XeLaTeX may return an error even on success (warnings treated as errors)
+	// The engine may return an error even on success (warnings treated as errors)
 	// Check if PDF was actually created
-	baseFilename := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
-	pdfPath := filepath.Join(outputDir, baseFilename+".pdf")
+	pdfPath := filepath.Join(outputDir, jobName(inputFile)+".pdf")
 	pdfExists := false
 	if _, statErr := os.Stat(pdfPath); statErr == nil {
 		pdfExists = true
 	}
 
 	if err != nil && !pdfExists {
-		fmt.Printf("❌ XeLaTeX failed with error: %v\n", err)
-		fmt.Printf("Output:\n%s\n", string(output))
-		return fmt.Errorf("xelatex failed: %w", err)
+		logFile := filepath.Join(outputDir, jobName(inputFile)+".log")
+		if logErr := diagnose(logFile, inputFile); logErr != nil {
+			b.config.Log().Error("❌ "+engine.Name()+" failed", append(logErr.Attrs(), "log", logFile)...)
+			return fmt.Errorf("%s failed: %w", engine.Name(), logErr)
+		}
+		b.config.Log().Error("❌ "+engine.Name()+" failed", "error", err, "output", string(output))
+		return fmt.Errorf("%s failed: %w", engine.Name(), err)
 	}
 
 	if err != nil && pdfExists {
-		fmt.Printf("⚠️  XeLaTeX completed with warnings (likely font/emoji issues)\n")
+		b.config.Log().Warn("⚠️  Completed with warnings (likely font/emoji issues)", "engine", engine.Name())
 	}
 
 	return nil
 }
 
-// checkXeLaTeX verifies that XeLaTeX is installed and available
-func (b *Builder) checkXeLaTeX() error {
-	cmd := exec.Command("xelatex", "--version")
-	output, err := cmd.Output()
+// diagnose finds the first error in a failed run's log and the message it's in
+func diagnose(logFile, inputFile string) *LogError {
+	log, err := os.ReadFile(logFile)
 	if err != nil {
-		return fmt.Errorf("xelatex not found - please install XeLaTeX (part of TeX Live or MiKTeX) to generate PDFs")
+		return nil
+	}
+	logErr := ParseLogError(log)
+	if logErr == nil {
+		return nil
+	}
+
+	// Without a file named, the line is only known to be the book's when it isn't split
+	if logErr.File == "" {
+		source, err := os.ReadFile(inputFile)
+		if err != nil || includeRegex.Match(source) {
+			return logErr
+		}
+		logErr.File = inputFile
+	}
+	if logErr.Line > 0 {
+		logErr.Source = MessageAt(logErr.File, logErr.Line)
+	}
+	return logErr
+}
+
+// checkEngine verifies that the engine's programs are installed and available
+func (b *Builder) checkEngine(engine Engine) error {
+	programs := engine.Programs()
+	for _, program := range programs {
+		if _, err := exec.LookPath(program); err != nil {
+			return fmt.Errorf("%s not found - please install it (%s) to generate PDFs", program, installHint(program))
+		}
 	}
 
-	// Parse version for informational purposes
-	lines := strings.Split(string(output), "\n")
-	if len(lines) > 0 {
-		fmt.Printf("📋 Using %s\n", strings.TrimSpace(lines[0]))
+	// Report the version for informational purposes
+	output, err := exec.Command(programs[0], "--version").Output()
+	if err != nil {
+		return fmt.Errorf("%s --version failed: %w", programs[0], err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			b.config.Log().Info("📋 Using", "version", line)
+			break
+		}
 	}
 
 	return nil
 }
 
-// cleanupXeLaTeXFiles removes temporary files created by XeLaTeX
-func (b *Builder) cleanupXeLaTeXFiles(baseFilename string) {
-	// List of common XeLaTeX temporary file extensions
-	tempExtensions := []string{
-		".aux",         // Auxiliary file for cross-references
-		".log",         // Log file
-		".toc",         // Table of contents
-		".out",         // PDF outline/bookmarks
-		".lof",         // List of figures
-		".lot",         // List of tables
-		".fls",         // File list
-		".fdb_latexmk", // Latexmk database
-	}
-
-	// Remove each temporary file
-	for _, ext := range tempExtensions {
-		tempFile := baseFilename + ext
-		os.Remove(tempFile) // Ignore errors for cleanup
+// installHint says where a program the engines run comes from
+func installHint(program string) string {
+	switch program {
+	case "tectonic":
+		return "see https://tectonic-typesetting.github.io"
+	case "xelatex":
+		// Tectonic is picked up automatically when pdf_engine isn't set
+		return "part of TeX Live or MiKTeX, or install the single Tectonic binary instead"
+	}
+	return "part of TeX Live or MiKTeX"
+}
+
+// prepareIncludes creates the folders under outputDir that XeLaTeX writes the .aux files
+// of a split book's chapters to, and returns the names the chapters are included by
+func prepareIncludes(inputFile, outputDir string) ([]string, error) {
+	source, err := os.ReadFile(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", inputFile, err)
+	}
+
+	var names []string
+	for _, match := range includeRegex.FindAllSubmatch(source, -1) {
+		name := string(match[1])
+		names = append(names, name)
+
+		dir := filepath.Join(outputDir, filepath.Dir(filepath.FromSlash(name)))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return names, fmt.Errorf("failed to create folder for chapter output: %w", err)
+		}
 	}
+	return names, nil
 }
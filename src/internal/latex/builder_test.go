@@ -0,0 +1,27 @@
+package latex
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrepareIncludes(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "book.tex")
+	os.WriteFile(input, []byte("\\begin{document}\n\\include{out/book-chapters/2024-01}\n\\include{out/book-chapters/2024-02}\n\\end{document}\n"), 0644)
+	outputDir := filepath.Join(dir, "out")
+	os.Mkdir(outputDir, 0755)
+
+	included, err := prepareIncludes(input, outputDir)
+	if err != nil {
+		t.Fatalf("prepareIncludes failed: %v", err)
+	}
+	if len(included) != 2 || included[1] != "out/book-chapters/2024-02" {
+		t.Errorf("Unexpected names %v", included)
+	}
+	auxDir := filepath.Join(outputDir, "out", "book-chapters")
+	if _, err := os.Stat(auxDir); err != nil {
+		t.Fatalf("Expected the .aux folder created: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package latex
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultEngine is used when pdf_engine isn't set
+const DefaultEngine = "xelatex"
+
+// FallbackEngine is used instead of DefaultEngine when pdf_engine isn't set and only it
+// is installed, so a PDF can be built without a TeX distribution
+const FallbackEngine = "tectonic"
+
+// MaxPasses caps how many times an engine that doesn't rerun itself is run while
+// cross-references settle
+const MaxPasses = 5
+
+// rerunPattern matches the warnings LaTeX, hyperref and friends log when another pass
+// would change the output
+var rerunPattern = regexp.MustCompile(`Rerun to get|Label\(s\) may have changed|Rerun LaTeX|rerunfilecheck Warning`)
+
+// Engine is a program that typesets a TeX file into a PDF
+type Engine interface {
+	// Name identifies the engine in config and log output
+	Name() string
+
+	// Programs lists the commands the engine needs on PATH, the one it runs first
+	Programs() []string
+
+	// Command returns the arguments to run for compiling inputFile into outputDir,
+	// building only the includeOnly files of a split book when it's set
+	Command(inputFile, outputDir string, includeOnly []string) ([]string, error)
+
+	// Reruns reports whether the engine runs as many passes as it needs by itself
+	Reruns() bool
+
+	// FetchesPackages reports whether the engine downloads the LaTeX packages it needs
+	// rather than using those of an installed TeX distribution
+	FetchesPackages() bool
+}
+
+// Engines returns the built-in engines, the default first
+func Engines() []Engine {
+	return []Engine{
+		&latexEngine{program: "xelatex"},
+		&latexEngine{program: "lualatex"},
+		&latexmkEngine{},
+		&tectonicEngine{},
+	}
+}
+
+// EngineNames lists the names pdf_engine accepts
+func EngineNames() []string {
+	var names []string
+	for _, engine := range Engines() {
+		names = append(names, engine.Name())
+	}
+	return names
+}
+
+// LookupEngine finds an engine by name, or the default for an empty name
+func LookupEngine(name string) (Engine, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+	for _, engine := range Engines() {
+		if engine.Name() == name {
+			return engine, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown PDF engine %q (use %s)", name, strings.Join(EngineNames(), ", "))
+}
+
+// SelectEngine finds the engine pdf_engine names. When it's empty that's DefaultEngine,
+// or FallbackEngine if only its programs are installed, as reported by installed.
+func SelectEngine(name string, installed func(program string) bool) (Engine, error) {
+	if name != "" {
+		return LookupEngine(name)
+	}
+	engine, _ := LookupEngine(DefaultEngine)
+	if !hasPrograms(engine, installed) {
+		if fallback, _ := LookupEngine(FallbackEngine); hasPrograms(fallback, installed) {
+			return fallback, nil
+		}
+	}
+	return engine, nil
+}
+
+// Installed reports whether a program is on PATH
+func Installed(program string) bool {
+	_, err := exec.LookPath(program)
+	return err == nil
+}
+
+// hasPrograms reports whether every program the engine needs is installed
+func hasPrograms(engine Engine, installed func(program string) bool) bool {
+	for _, program := range engine.Programs() {
+		if !installed(program) {
+			return false
+		}
+	}
+	return true
+}
+
+// jobName is the name TeX gives its output files for inputFile
+func jobName(inputFile string) string {
+	return strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+}
+
+// includeOnlyInput sets \includeonly before the book is read
+func includeOnlyInput(inputFile string, includeOnly []string) string {
+	return `\includeonly{` + strings.Join(includeOnly, ",") + `}\input{` + filepath.ToSlash(inputFile) + `}`
+}
+
+// latexEngine runs xelatex or lualatex once per pass
+type latexEngine struct {
+	program string
+}
+
+func (e *latexEngine) Name() string          { return e.program }
+func (e *latexEngine) Programs() []string    { return []string{e.program} }
+func (e *latexEngine) Reruns() bool          { return false }
+func (e *latexEngine) FetchesPackages() bool { return false }
+
+func (e *latexEngine) Command(inputFile, outputDir string, includeOnly []string) ([]string, error) {
+	args := []string{
+		e.program,
+		"-interaction=nonstopmode",
+		"-file-line-error", // So errors in a split book's chapters name their file
+		"-output-directory=" + outputDir,
+	}
+	if len(includeOnly) == 0 {
+		return append(args, inputFile), nil
+	}
+	// Keep the job named after the book while \includeonly is set before it's read
+	return append(args, "-jobname="+jobName(inputFile), includeOnlyInput(inputFile, includeOnly)), nil
+}
+
+// latexmkEngine has latexmk run xelatex until the output settles
+type latexmkEngine struct{}
+
+func (e *latexmkEngine) Name() string          { return "latexmk" }
+func (e *latexmkEngine) Programs() []string    { return []string{"latexmk", "xelatex"} }
+func (e *latexmkEngine) Reruns() bool          { return true }
+func (e *latexmkEngine) FetchesPackages() bool { return false }
+
+func (e *latexmkEngine) Command(inputFile, outputDir string, includeOnly []string) ([]string, error) {
+	args := []string{
+		"latexmk",
+		"-xelatex",
+		"-f", // Carry on past errors, as nonstopmode does, since emoji often cause some
+		"-interaction=nonstopmode",
+		"-file-line-error",
+		"-output-directory=" + outputDir,
+	}
+	if len(includeOnly) > 0 {
+		args = append(args, `-usepretex=\includeonly{`+strings.Join(includeOnly, ",")+`}`)
+	}
+	return append(args, inputFile), nil
+}
+
+// tectonicEngine runs Tectonic, which reruns itself and fetches packages as it needs them
+type tectonicEngine struct{}
+
+func (e *tectonicEngine) Name() string          { return "tectonic" }
+func (e *tectonicEngine) Programs() []string    { return []string{"tectonic"} }
+func (e *tectonicEngine) Reruns() bool          { return true }
+func (e *tectonicEngine) FetchesPackages() bool { return true }
+
+func (e *tectonicEngine) Command(inputFile, outputDir string, includeOnly []string) ([]string, error) {
+	if len(includeOnly) > 0 {
+		return nil, fmt.Errorf("tectonic can't build single chapters; use another pdf_engine for --chapters")
+	}
+	return []string{
+		"tectonic",
+		"--keep-logs", // The page count is read from the log
+		"--outdir", outputDir,
+		// Tectonic looks for files beside the input, but image paths are relative to
+		// the directory the book is built from
+		"-Z", "search-path=.",
+		inputFile,
+	}, nil
+}
+
+// NeedsRerun reports whether a pass's log asks for another pass
+func NeedsRerun(log []byte) bool {
+	return rerunPattern.Match(log)
+}
+
+// auxState fingerprints the files a pass writes for the next one to read, so a pass
+// that changed none of them shows the output has settled
+func auxState(files []string) string {
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+
+	hash := sha256.New()
+	for _, file := range sorted {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(hash, "%s missing\n", file)
+			continue
+		}
+		fmt.Fprintf(hash, "%s %d\n", file, len(data))
+		hash.Write(data)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
@@ -0,0 +1,119 @@
+package latex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookupEngine(t *testing.T) {
+	engine, err := LookupEngine("")
+	if err != nil || engine.Name() != DefaultEngine {
+		t.Errorf("Expected the default engine, got %v, %v", engine, err)
+	}
+	for _, name := range EngineNames() {
+		if engine, err := LookupEngine(name); err != nil || engine.Name() != name {
+			t.Errorf("Expected %s found, got %v, %v", name, engine, err)
+		}
+	}
+	if _, err := LookupEngine("pdflatex"); err == nil || !strings.Contains(err.Error(), "lualatex") {
+		t.Errorf("Expected an error listing the engines, got %v", err)
+	}
+}
+
+func TestSelectEngine(t *testing.T) {
+	only := func(programs ...string) func(string) bool {
+		return func(program string) bool {
+			for _, p := range programs {
+				if p == program {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		name      string
+		installed func(string) bool
+		want      string
+	}{
+		{"", only("xelatex", "tectonic"), "xelatex"},
+		{"", only("tectonic"), "tectonic"},
+		{"", only(), "xelatex"}, // So the error names what's usually wanted
+		{"xelatex", only("tectonic"), "xelatex"},
+		{"lualatex", only("xelatex"), "lualatex"},
+	}
+	for _, tt := range tests {
+		engine, err := SelectEngine(tt.name, tt.installed)
+		if err != nil || engine.Name() != tt.want {
+			t.Errorf("SelectEngine(%q): got %v, %v, want %s", tt.name, engine, err, tt.want)
+		}
+	}
+	if _, err := SelectEngine("pdflatex", only("pdflatex")); err == nil {
+		t.Error("Expected an unknown engine rejected")
+	}
+}
+
+func TestEngineCommand(t *testing.T) {
+	tests := []struct {
+		engine      string
+		includeOnly []string
+		want        string
+	}{
+		{"xelatex", nil, "xelatex -interaction=nonstopmode -file-line-error -output-directory=out book.tex"},
+		{"lualatex", []string{"ch/2024-01"}, `lualatex -interaction=nonstopmode -file-line-error -output-directory=out -jobname=book \includeonly{ch/2024-01}\input{book.tex}`},
+		{"latexmk", nil, "latexmk -xelatex -f -interaction=nonstopmode -file-line-error -output-directory=out book.tex"},
+		{"latexmk", []string{"ch/2024-01", "ch/2024-02"}, `latexmk -xelatex -f -interaction=nonstopmode -file-line-error -output-directory=out -usepretex=\includeonly{ch/2024-01,ch/2024-02} book.tex`},
+		{"tectonic", nil, "tectonic --keep-logs --outdir out -Z search-path=. book.tex"},
+	}
+	for _, tt := range tests {
+		engine, _ := LookupEngine(tt.engine)
+		args, err := engine.Command("book.tex", "out", tt.includeOnly)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.engine, err)
+			continue
+		}
+		if got := strings.Join(args, " "); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.engine, got, tt.want)
+		}
+	}
+
+	engine, _ := LookupEngine("tectonic")
+	if _, err := engine.Command("book.tex", "out", []string{"ch/2024-01"}); err == nil {
+		t.Error("Expected tectonic to refuse single chapters")
+	}
+}
+
+func TestNeedsRerun(t *testing.T) {
+	if !NeedsRerun([]byte("LaTeX Warning: Label(s) may have changed. Rerun to get cross-references right.")) {
+		t.Error("Expected a rerun for changed labels")
+	}
+	if !NeedsRerun([]byte("Package rerunfilecheck Warning: File `book.out' has changed.")) {
+		t.Error("Expected a rerun for changed bookmarks")
+	}
+	if NeedsRerun([]byte("Output written on book.pdf (42 pages).")) {
+		t.Error("Expected no rerun for a settled log")
+	}
+}
+
+func TestAuxState(t *testing.T) {
+	dir := t.TempDir()
+	aux := filepath.Join(dir, "book.aux")
+	toc := filepath.Join(dir, "book.toc")
+
+	empty := auxState([]string{aux, toc})
+	os.WriteFile(aux, []byte("\\relax"), 0644)
+	written := auxState([]string{aux, toc})
+	if written == empty {
+		t.Error("Expected a new .aux to change the state")
+	}
+	if auxState([]string{toc, aux}) != written {
+		t.Error("Expected the state not to depend on the order of the files")
+	}
+	os.WriteFile(toc, []byte("\\contentsline"), 0644)
+	if auxState([]string{aux, toc}) == written {
+		t.Error("Expected a changed .toc to change the state")
+	}
+}
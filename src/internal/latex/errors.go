@@ -0,0 +1,171 @@
+package latex
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageMarker starts the TeX comment written before each message, followed by the
+// message's date in MarkerDateFormat, its ROWID and its GUID, so errors can be traced
+// back to it
+const MessageMarker = "% threadbound:message "
+
+// MarkerDateFormat is the date format of message markers
+const MarkerDateFormat = time.RFC3339
+
+// chapterMarker starts the comments around each chapter, which no message runs past
+const chapterMarker = "% threadbound:"
+
+var (
+	// With -file-line-error, errors are logged as "./book.tex:123: Undefined control sequence."
+	fileLineErrorPattern = regexp.MustCompile(`(?m)^(\S[^:\n]*):(\d+): (.+)$`)
+	// Otherwise they start with "! ", and the line comes after as "l.123 \foo"
+	bangErrorPattern = regexp.MustCompile(`(?m)^! (.+)$`)
+	contextPattern   = regexp.MustCompile(`(?m)^l\.(\d+) (.*)$`)
+)
+
+// LogError is the first error a TeX engine logged, traced back to the message it's in
+// when the source has message markers
+type LogError struct {
+	File    string // Source file as the engine named it; empty when the log doesn't say
+	Line    int    // 0 when the log doesn't say
+	Message string // What went wrong, e.g. "Undefined control sequence."
+	Context string // The source the engine stopped at
+
+	Source *SourceMessage // The message the line belongs to, if it's in one
+}
+
+// SourceMessage is a message as its marker identifies it
+type SourceMessage struct {
+	ID   int
+	GUID string
+	Date time.Time
+}
+
+// Error describes where the error is and what went wrong
+func (e *LogError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	case e.File != "":
+		return e.File + ": " + e.Message
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// Diagnosis explains the error in a few lines, naming the message that caused it
+func (e *LogError) Diagnosis() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "❌ %s\n", e.Error())
+	if e.Context != "" {
+		fmt.Fprintf(&b, "   at: %s\n", e.Context)
+	}
+	if msg := e.Source; msg != nil {
+		fmt.Fprintf(&b, "   in message %d sent %s (GUID %s)\n", msg.ID, msg.Date.Format("Monday, January 2, 2006, 3:04 PM"), msg.GUID)
+		fmt.Fprintf(&b, "   Report it with: threadbound debug-bundle --message %d\n", msg.ID)
+	}
+	return b.String()
+}
+
+// Attrs returns what Diagnosis explains as log attributes
+func (e *LogError) Attrs() []interface{} {
+	attrs := []interface{}{"error", e.Error()}
+	if e.Context != "" {
+		attrs = append(attrs, "at", e.Context)
+	}
+	if msg := e.Source; msg != nil {
+		attrs = append(attrs, "message_id", msg.ID, "message_guid", msg.GUID, "message_date", msg.Date,
+			"report", fmt.Sprintf("threadbound debug-bundle --message %d", msg.ID))
+	}
+	return attrs
+}
+
+// ParseLogError finds the first error in a TeX log, or returns nil if there isn't one
+func ParseLogError(log []byte) *LogError {
+	text := string(log)
+	fileLine := fileLineErrorPattern.FindStringSubmatchIndex(text)
+	bang := bangErrorPattern.FindStringSubmatchIndex(text)
+
+	var logErr *LogError
+	var rest string
+	switch {
+	case fileLine != nil && (bang == nil || fileLine[0] < bang[0]):
+		line, _ := strconv.Atoi(text[fileLine[4]:fileLine[5]])
+		logErr = &LogError{
+			File:    text[fileLine[2]:fileLine[3]],
+			Line:    line,
+			Message: strings.TrimSpace(text[fileLine[6]:fileLine[7]]),
+		}
+		rest = text[fileLine[1]:]
+	case bang != nil:
+		logErr = &LogError{Message: strings.TrimSpace(text[bang[2]:bang[3]])}
+		rest = text[bang[1]:]
+	default:
+		return nil
+	}
+
+	// The context follows within a few lines, before the next error
+	if next := bangErrorPattern.FindStringIndex(rest); next != nil {
+		rest = rest[:next[0]]
+	}
+	if match := contextPattern.FindStringSubmatch(rest); match != nil {
+		if logErr.Line == 0 {
+			logErr.Line, _ = strconv.Atoi(match[1])
+		}
+		logErr.Context = strings.TrimSpace(match[2])
+	}
+	return logErr
+}
+
+// MessageAt finds the message a line of TeX source belongs to from the markers before it.
+// It returns nil for lines outside any message, such as the front matter.
+func MessageAt(path string, line int) *SourceMessage {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var msg *SourceMessage
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; n <= line && scanner.Scan(); n++ {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, MessageMarker):
+			msg = parseMarker(strings.TrimPrefix(text, MessageMarker))
+		case strings.HasPrefix(text, chapterMarker):
+			msg = nil
+		}
+	}
+	return msg
+}
+
+// parseMarker reads the date, ROWID and GUID after a message marker
+func parseMarker(marker string) *SourceMessage {
+	fields := strings.SplitN(marker, " ", 3)
+	if len(fields) != 3 {
+		return nil
+	}
+	date, err := time.Parse(MarkerDateFormat, fields[0])
+	if err != nil {
+		return nil
+	}
+	id, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil
+	}
+	return &SourceMessage{ID: id, GUID: fields[2], Date: date}
+}
+
+// Marker returns the message marker line for a message, without its newline
+func Marker(id int, guid string, date time.Time) string {
+	return MessageMarker + date.Format(MarkerDateFormat) + " " + strconv.Itoa(id) + " " + strings.Join(strings.Fields(guid), "")
+}
@@ -0,0 +1,92 @@
+package latex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogError(t *testing.T) {
+	log := `(./out/book-chapters/2024-01.tex
+Overfull \hbox (1.2pt too wide) in paragraph at lines 10--12
+./out/book-chapters/2024-01.tex:42: Undefined control sequence.
+l.42 Hello \foo
+               bar
+./book.tex:50: Emergency stop.
+`
+	logErr := ParseLogError([]byte(log))
+	if logErr == nil {
+		t.Fatal("Expected an error found")
+	}
+	if logErr.File != "./out/book-chapters/2024-01.tex" || logErr.Line != 42 || logErr.Message != "Undefined control sequence." {
+		t.Errorf("Unexpected error %+v", logErr)
+	}
+	if logErr.Context != `Hello \foo` {
+		t.Errorf("Unexpected context %q", logErr.Context)
+	}
+
+	// Tectonic logs errors without their file
+	logErr = ParseLogError([]byte("! Missing $ inserted.\n<inserted text>\n                $\nl.7 a_b\n\n! Emergency stop.\n"))
+	if logErr == nil || logErr.File != "" || logErr.Line != 7 || logErr.Message != "Missing $ inserted." || logErr.Context != "a_b" {
+		t.Errorf("Unexpected error %+v", logErr)
+	}
+	if got := logErr.Error(); got != "line 7: Missing $ inserted." {
+		t.Errorf("Unexpected message %q", got)
+	}
+
+	if logErr := ParseLogError([]byte("Output written on book.pdf (42 pages).\n")); logErr != nil {
+		t.Errorf("Expected no error, got %+v", logErr)
+	}
+}
+
+func TestMessageAt(t *testing.T) {
+	date := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	source := strings.Join([]string{
+		`\begin{document}`,                          // 1
+		`% threadbound:chapter 2024-01`,             // 2
+		`\chapter{January 2024}`,                    // 3
+		Marker(7, "p:0/ABC-123", date),              // 4
+		`\sentmessage{Hello}`,                       // 5
+		Marker(8, "DEF-456", date.Add(time.Minute)), // 6
+		`\receivedmessage{\foo}`,                    // 7
+		`% threadbound:end-chapter 2024-01`,         // 8
+		`\end{document}`,                            // 9
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "book.tex")
+	os.WriteFile(path, []byte(source), 0644)
+
+	if msg := MessageAt(path, 3); msg != nil {
+		t.Errorf("Expected no message for the chapter heading, got %+v", msg)
+	}
+	msg := MessageAt(path, 5)
+	if msg == nil || msg.ID != 7 || msg.GUID != "p:0/ABC-123" || !msg.Date.Equal(date) {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if msg := MessageAt(path, 7); msg == nil || msg.ID != 8 {
+		t.Errorf("Expected the second message, got %+v", msg)
+	}
+	if msg := MessageAt(path, 9); msg != nil {
+		t.Errorf("Expected no message after the chapter ends, got %+v", msg)
+	}
+	if msg := MessageAt(filepath.Join(t.TempDir(), "missing.tex"), 5); msg != nil {
+		t.Errorf("Expected no message for a missing file, got %+v", msg)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "book.tex")
+	os.WriteFile(input, []byte("\\begin{document}\n"+Marker(3, "GUID-3", time.Now())+"\n\\sentmessage{\\foo}\n\\end{document}\n"), 0644)
+	logFile := filepath.Join(dir, "book.log")
+	os.WriteFile(logFile, []byte("! Undefined control sequence.\nl.3 \\sentmessage{\\foo\n"), 0644)
+
+	logErr := diagnose(logFile, input)
+	if logErr == nil || logErr.File != input || logErr.Source == nil || logErr.Source.GUID != "GUID-3" {
+		t.Fatalf("Expected the error traced to GUID-3, got %+v", logErr)
+	}
+	if diagnosis := logErr.Diagnosis(); !strings.Contains(diagnosis, "debug-bundle --message 3") {
+		t.Errorf("Expected the diagnosis to say how to report the message, got %q", diagnosis)
+	}
+}
@@ -0,0 +1,36 @@
+package latex
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"threadbound/internal/pdffile"
+)
+
+// XeLaTeX ends its log with "Output written on book.pdf (42 pages).", wrapping long
+// filenames across lines
+var logPagesPattern = regexp.MustCompile(`Output written on [^(]*\((\d+) pages?`)
+
+// ParseLogPageCount returns the page count XeLaTeX reported in its log, or 0 if the log
+// doesn't say
+func ParseLogPageCount(log []byte) int {
+	matches := logPagesPattern.FindAllSubmatch(log, -1)
+	if len(matches) == 0 {
+		return 0
+	}
+	count, _ := strconv.Atoi(string(matches[len(matches)-1][1]))
+	return count
+}
+
+// PDFPageCount counts the pages of a PDF by reading its page tree
+func PDFPageCount(pdfPath string) (int, error) {
+	info, err := pdffile.Read(pdfPath)
+	if err != nil {
+		return 0, err
+	}
+	if info.Pages == 0 {
+		return 0, fmt.Errorf("no pages found in %s", pdfPath)
+	}
+	return info.Pages, nil
+}
@@ -0,0 +1,21 @@
+package latex
+
+import "testing"
+
+func TestParseLogPageCount(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want int
+	}{
+		{"single line", "Output written on book.pdf (42 pages).\n", 42},
+		{"one page", "Output written on book.pdf (1 page).\n", 1},
+		{"wrapped filename", "Output written on /Users/someone/Documents/books/a-very-long-directory-n\name/book.pdf (128 pages).\n", 128},
+		{"no output", "No pages of output.\n", 0},
+	}
+	for _, tt := range tests {
+		if got := ParseLogPageCount([]byte(tt.log)); got != tt.want {
+			t.Errorf("%s: ParseLogPageCount = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package logging builds the slog loggers that generation progress is written to: plain
+// lines by default, as the CLI has always printed them, or JSON records for servers
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Options control what is logged and how
+type Options struct {
+	Level slog.Level // Least severe level written (default: info)
+	JSON  bool       // Write JSON records instead of plain lines
+}
+
+// defaults are the options loggers are built with when no options are given
+var (
+	defaults      Options
+	defaultsMutex sync.RWMutex
+)
+
+// Configure sets the options Default builds loggers with, from the command line flags
+func Configure(options Options) {
+	defaultsMutex.Lock()
+	defer defaultsMutex.Unlock()
+	defaults = options
+}
+
+// Defaults returns the options set with Configure
+func Defaults() Options {
+	defaultsMutex.RLock()
+	defer defaultsMutex.RUnlock()
+	return defaults
+}
+
+// Default returns a logger writing to w with the options set with Configure
+func Default(w io.Writer) *slog.Logger {
+	return New(w, Defaults())
+}
+
+// New returns a logger writing to w
+func New(w io.Writer, options Options) *slog.Logger {
+	return slog.New(NewHandler(w, options))
+}
+
+// NewHandler returns the handler New's loggers use
+func NewHandler(w io.Writer, options Options) slog.Handler {
+	if options.JSON {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: options.Level})
+	}
+	return &lineHandler{w: w, level: options.Level, mutex: &sync.Mutex{}}
+}
+
+// lineHandler writes each record as its message, followed by any attributes as key=value
+type lineHandler struct {
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+	mutex *sync.Mutex
+}
+
+func (h *lineHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *lineHandler) Handle(_ context.Context, record slog.Record) error {
+	var line bytes.Buffer
+	line.WriteString(record.Message)
+	writeAttr := func(attr slog.Attr) bool {
+		fmt.Fprintf(&line, " %s=%v", attr.Key, attr.Value)
+		return true
+	}
+	for _, attr := range h.attrs {
+		writeAttr(attr)
+	}
+	record.Attrs(writeAttr)
+	if !bytes.HasSuffix(line.Bytes(), []byte("\n")) {
+		line.WriteByte('\n')
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	_, err := h.w.Write(line.Bytes())
+	return err
+}
+
+func (h *lineHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	handler.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &handler
+}
+
+// WithGroup is not supported by plain lines; attributes keep their own keys
+func (h *lineHandler) WithGroup(name string) slog.Handler {
+	return h
+}
+
+// Tee returns a handler passing each record to every one of handlers that wants it, such as
+// a job's own log and the server's
+func Tee(handlers ...slog.Handler) slog.Handler {
+	return teeHandler(handlers)
+}
+
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range t {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var first error
+	for _, handler := range t {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, handler := range t {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, handler := range t {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLineHandler(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, Options{})
+
+	logger.Debug("🔍 Fetching metadata")
+	logger.Info("✅ Found 2 messages")
+	logger.With("job_id", "abc").Warn("⚠️  Could not record timings")
+	logger.Info("\n⏱️  Timings:\nrender 1s\n")
+
+	want := "✅ Found 2 messages\n⚠️  Could not record timings job_id=abc\n\n⏱️  Timings:\nrender 1s\n"
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
+
+func TestLevels(t *testing.T) {
+	var out bytes.Buffer
+	quiet := New(&out, Options{Level: slog.LevelWarn})
+	quiet.Info("progress")
+	quiet.Warn("warning")
+	if out.String() != "warning\n" {
+		t.Errorf("Expected only the warning at warn level, got %q", out.String())
+	}
+
+	out.Reset()
+	verbose := New(&out, Options{Level: slog.LevelDebug})
+	verbose.Debug("detail")
+	if out.String() != "detail\n" {
+		t.Errorf("Expected debug lines at debug level, got %q", out.String())
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var out bytes.Buffer
+	New(&out, Options{JSON: true}).Info("✅ Generated book", "path", "book.tex")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %v", out.String(), err)
+	}
+	if record["msg"] != "✅ Generated book" || record["level"] != "INFO" || record["path"] != "book.tex" {
+		t.Errorf("Unexpected record: %v", record)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var job, server bytes.Buffer
+	logger := slog.New(Tee(
+		NewHandler(&job, Options{}),
+		NewHandler(&server, Options{Level: slog.LevelWarn, JSON: true}),
+	)).With("job_id", "abc")
+
+	logger.Info("📝 Generating tex output...")
+	logger.Error("❌ failed")
+
+	if job.String() != "📝 Generating tex output... job_id=abc\n❌ failed job_id=abc\n" {
+		t.Errorf("Unexpected job log %q", job.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(server.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"job_id":"abc"`) {
+		t.Errorf("Expected only the error in the server log, tagged with the job, got %q", server.String())
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(Options{})
+
+	Configure(Options{Level: slog.LevelWarn})
+	var out bytes.Buffer
+	Default(&out).Info("progress")
+	if out.Len() != 0 {
+		t.Errorf("Expected Default to use the configured level, got %q", out.String())
+	}
+}
@@ -0,0 +1,127 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"threadbound/internal/filter"
+	"threadbound/internal/timing"
+)
+
+// Manifest records how a book was produced and is written next to the output file
+type Manifest struct {
+	Output    string          `json:"output"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Timings   []timing.Stage  `json:"timings,omitempty"`
+	Filters   []filter.Result `json:"filters,omitempty"`
+}
+
+// PathFor returns the manifest path for an output file (book.tex -> book.manifest.json)
+func PathFor(outputPath string) string {
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	return base + ".manifest.json"
+}
+
+// Load reads a manifest, returning an empty one if the file does not exist yet
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest as indented JSON
+func (m *Manifest) Save(path string) error {
+	m.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// MergeTimings replaces stages with the same name and appends new ones, so
+// generate and build-pdf can each contribute their stages to one manifest
+func (m *Manifest) MergeTimings(stages []timing.Stage) {
+	for _, stage := range stages {
+		replaced := false
+		for i := range m.Timings {
+			if m.Timings[i].Name == stage.Name {
+				m.Timings[i] = stage
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			m.Timings = append(m.Timings, stage)
+		}
+	}
+}
+
+// MergeFilters replaces the results of filters that ran again and appends new ones
+func (m *Manifest) MergeFilters(results []filter.Result) {
+	for _, result := range results {
+		replaced := false
+		for i := range m.Filters {
+			if m.Filters[i].Name == result.Name {
+				m.Filters[i] = result
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			m.Filters = append(m.Filters, result)
+		}
+	}
+}
+
+// RecordFilters merges filter results into the manifest for outputPath
+func RecordFilters(outputPath string, results []filter.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	path := PathFor(outputPath)
+	m, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	m.Output = outputPath
+	m.MergeFilters(results)
+	return m.Save(path)
+}
+
+// RecordTimings merges the recorder's stages into the manifest for outputPath
+func RecordTimings(outputPath string, recorder *timing.Recorder) error {
+	if recorder == nil {
+		return nil
+	}
+
+	path := PathFor(outputPath)
+	m, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	m.Output = outputPath
+	m.MergeTimings(recorder.Stages())
+	return m.Save(path)
+}
@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"threadbound/internal/filter"
+	"threadbound/internal/timing"
+)
+
+func TestPathFor(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{"book.tex", "book.manifest.json"},
+		{"out/book.pdf", "out/book.manifest.json"},
+		{"book", "book.manifest.json"},
+	}
+
+	for _, test := range tests {
+		if result := PathFor(test.output); result != test.expected {
+			t.Errorf("PathFor(%s) = %s, expected %s", test.output, result, test.expected)
+		}
+	}
+}
+
+func TestMergeTimingsAcrossRuns(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "book.tex")
+
+	// generate records its stages first
+	generate := timing.New(true)
+	generate.Record("extraction", time.Second)
+	generate.Record("rendering", 2*time.Second)
+	if err := RecordTimings(outputPath, generate); err != nil {
+		t.Fatalf("Failed to record generate timings: %v", err)
+	}
+
+	// build-pdf adds its passes and a re-run replaces an existing stage
+	build := timing.New(true)
+	build.Record("xelatex pass 1", 3*time.Second)
+	build.Record("rendering", 4*time.Second)
+	if err := RecordTimings(outputPath, build); err != nil {
+		t.Fatalf("Failed to record build timings: %v", err)
+	}
+
+	m, err := Load(PathFor(outputPath))
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+
+	if len(m.Timings) != 3 {
+		t.Fatalf("Expected 3 stages, got %d", len(m.Timings))
+	}
+	if m.Timings[1].Name != "rendering" || m.Timings[1].Duration != 4*time.Second {
+		t.Errorf("Expected rendering to be replaced with 4s, got %s %s", m.Timings[1].Name, m.Timings[1].Duration)
+	}
+	if m.Output != outputPath {
+		t.Errorf("Expected output '%s', got '%s'", outputPath, m.Output)
+	}
+}
+
+func TestRecordTimingsDisabled(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "book.tex")
+	if err := RecordTimings(outputPath, timing.New(false)); err != nil {
+		t.Fatalf("Expected no error when timings are disabled, got: %v", err)
+	}
+}
+
+func TestRecordFilters(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "book.tex")
+
+	if err := RecordFilters(outputPath, []filter.Result{{Name: "dedupe", Removed: 3}}); err != nil {
+		t.Fatalf("Failed to record filters: %v", err)
+	}
+	if err := RecordFilters(outputPath, []filter.Result{{Name: "dedupe", Removed: 5}}); err != nil {
+		t.Fatalf("Failed to record filters: %v", err)
+	}
+
+	m, err := Load(PathFor(outputPath))
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if len(m.Filters) != 1 || m.Filters[0].Removed != 5 {
+		t.Errorf("Expected the re-run to replace the dedupe result, got %+v", m.Filters)
+	}
+}
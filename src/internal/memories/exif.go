@@ -0,0 +1,138 @@
+package memories
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// EXIF tags needed to date a photo
+const (
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+)
+
+// exifTimeLayout is the fixed format EXIF uses for date/time values
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// exifHeader precedes the TIFF block in JPEG APP1 segments and HEIC Exif items
+var exifHeader = []byte("Exif\x00\x00")
+
+// CaptureTime reads when a photo was taken from its EXIF metadata.
+// Times without a recorded offset are interpreted in loc.
+func CaptureTime(path string, loc *time.Location) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseCaptureTime(data, loc)
+}
+
+// parseCaptureTime finds the EXIF block in a JPEG or HEIC file and extracts its capture time
+func parseCaptureTime(data []byte, loc *time.Location) (time.Time, error) {
+	idx := bytes.Index(data, exifHeader)
+	if idx == -1 {
+		return time.Time{}, fmt.Errorf("no EXIF metadata")
+	}
+
+	tags, err := readTIFF(data[idx+len(exifHeader):])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	value := tags[tagDateTimeOriginal]
+	if value == "" {
+		value = tags[tagDateTime]
+	}
+	if value == "" {
+		return time.Time{}, fmt.Errorf("no capture date in EXIF metadata")
+	}
+
+	// OffsetTimeOriginal looks like "+01:00"; older cameras don't record it
+	if offset := tags[tagOffsetTimeOriginal]; offset != "" {
+		if t, err := time.Parse(exifTimeLayout+"-07:00", value+offset); err == nil {
+			return t, nil
+		}
+	}
+	return time.ParseInLocation(exifTimeLayout, value, loc)
+}
+
+// readTIFF walks IFD0 and the EXIF sub-IFD, returning the ASCII values of the tags we use
+func readTIFF(tiff []byte) (map[uint16]string, error) {
+	if len(tiff) < 8 {
+		return nil, fmt.Errorf("truncated TIFF header")
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid TIFF byte order")
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return nil, fmt.Errorf("invalid TIFF magic number")
+	}
+
+	tags := make(map[uint16]string)
+	exifOffset, err := readIFD(tiff, order, order.Uint32(tiff[4:8]), tags)
+	if err != nil {
+		return nil, err
+	}
+	if exifOffset != 0 {
+		if _, err := readIFD(tiff, order, exifOffset, tags); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// readIFD collects ASCII date tags from one IFD and returns the EXIF sub-IFD offset if present
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32, tags map[uint16]string) (uint32, error) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return 0, fmt.Errorf("IFD offset out of range")
+	}
+	count := int(order.Uint16(tiff[offset:]))
+
+	var exifOffset uint32
+	for i := 0; i < count; i++ {
+		entry := uint64(offset) + 2 + uint64(i)*12
+		if entry+12 > uint64(len(tiff)) {
+			return 0, fmt.Errorf("truncated IFD entry")
+		}
+
+		tag := order.Uint16(tiff[entry:])
+		fieldType := order.Uint16(tiff[entry+2:])
+		n := order.Uint32(tiff[entry+4:])
+		valueField := tiff[entry+8 : entry+12]
+
+		switch tag {
+		case tagExifIFD:
+			exifOffset = order.Uint32(valueField)
+		case tagDateTime, tagDateTimeOriginal, tagOffsetTimeOriginal:
+			// Type 2 is ASCII; values over four bytes are stored at an offset
+			if fieldType != 2 {
+				continue
+			}
+			var raw []byte
+			if n <= 4 {
+				raw = valueField[:n]
+			} else {
+				start := uint64(order.Uint32(valueField))
+				if start+uint64(n) > uint64(len(tiff)) {
+					continue
+				}
+				raw = tiff[start : start+uint64(n)]
+			}
+			tags[tag] = strings.TrimSpace(strings.TrimRight(string(raw), "\x00"))
+		}
+	}
+	return exifOffset, nil
+}
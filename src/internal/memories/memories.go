@@ -0,0 +1,104 @@
+package memories
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// photoExts lists the photo formats that can be placed on a memory page
+var photoExts = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".heic": true,
+	".heif": true,
+}
+
+// Scan finds photos under dir and dates them in loc, oldest first. Photos without
+// EXIF capture dates fall back to their modification time.
+func Scan(dir string, loc *time.Location) ([]models.Memory, error) {
+	var photos []models.Memory
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip hidden files and folders such as .DS_Store and .thumbnails
+		if strings.HasPrefix(entry.Name(), ".") && path != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !photoExts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		takenAt, err := CaptureTime(path, loc)
+		if err != nil {
+			info, statErr := entry.Info()
+			if statErr != nil {
+				return statErr
+			}
+			takenAt = info.ModTime()
+		}
+
+		photos = append(photos, models.Memory{Path: path, TakenAt: takenAt.In(loc)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(photos, func(i, j int) bool {
+		return photos[i].TakenAt.Before(photos[j].TakenAt)
+	})
+	return photos, nil
+}
+
+// AttachmentGUID returns a stable identifier for a photo so its converted
+// image is cached alongside message attachments
+func AttachmentGUID(path string) string {
+	sum := sha1.Sum([]byte(path))
+	return "memory-" + hex.EncodeToString(sum[:8])
+}
+
+// Insert merges photos into the message timeline at their capture dates.
+// Both slices must already be in chronological order.
+func Insert(messages []models.Message, photos []models.Memory) []models.Message {
+	if len(photos) == 0 {
+		return messages
+	}
+
+	merged := make([]models.Message, 0, len(messages)+len(photos))
+	p := 0
+	for _, msg := range messages {
+		for p < len(photos) && photos[p].TakenAt.Before(msg.FormattedDate) {
+			merged = append(merged, memoryMessage(&photos[p]))
+			p++
+		}
+		merged = append(merged, msg)
+	}
+	for ; p < len(photos); p++ {
+		merged = append(merged, memoryMessage(&photos[p]))
+	}
+	return merged
+}
+
+// memoryMessage wraps a photo in a placeholder message so it flows through chapter grouping
+func memoryMessage(photo *models.Memory) models.Message {
+	return models.Message{
+		GUID:          AttachmentGUID(photo.Path),
+		FormattedDate: photo.TakenAt,
+		Memory:        photo,
+	}
+}
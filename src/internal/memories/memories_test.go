@@ -0,0 +1,173 @@
+package memories
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// buildEXIF returns a minimal little-endian EXIF block with DateTimeOriginal
+// in the EXIF sub-IFD and an optional OffsetTimeOriginal
+func buildEXIF(dateTime, offset string) []byte {
+	var buf bytes.Buffer
+	order := binary.LittleEndian
+	le16 := func(v uint16) { binary.Write(&buf, order, v) }
+	le32 := func(v uint32) { binary.Write(&buf, order, v) }
+
+	exifEntries := 1
+	if offset != "" {
+		exifEntries++
+	}
+
+	// Layout: header(8) | IFD0 with one entry (2+12+4) | EXIF IFD | string data
+	ifd0 := uint32(8)
+	exifIFD := ifd0 + 2 + 12 + 4
+	data := exifIFD + 2 + uint32(exifEntries)*12 + 4
+
+	buf.WriteString("II")
+	le16(42)
+	le32(ifd0)
+
+	le16(1)
+	le16(tagExifIFD)
+	le16(4)
+	le32(1)
+	le32(exifIFD)
+	le32(0)
+
+	le16(uint16(exifEntries))
+	le16(tagDateTimeOriginal)
+	le16(2)
+	le32(uint32(len(dateTime) + 1))
+	le32(data)
+	if offset != "" {
+		le16(tagOffsetTimeOriginal)
+		le16(2)
+		le32(uint32(len(offset) + 1))
+		le32(data + uint32(len(dateTime)+1))
+	}
+	le32(0)
+
+	buf.WriteString(dateTime + "\x00")
+	if offset != "" {
+		buf.WriteString(offset + "\x00")
+	}
+
+	// Wrap in a JPEG APP1 segment
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x00}
+	jpeg = append(jpeg, exifHeader...)
+	return append(jpeg, buf.Bytes()...)
+}
+
+func TestParseCaptureTime(t *testing.T) {
+	got, err := parseCaptureTime(buildEXIF("2023:07:04 18:30:00", "-04:00"), time.UTC)
+	if err != nil {
+		t.Fatalf("parseCaptureTime failed: %v", err)
+	}
+
+	want := time.Date(2023, 7, 4, 22, 30, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestParseCaptureTimeWithoutOffset(t *testing.T) {
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	got, err := parseCaptureTime(buildEXIF("2022:12:25 09:15:00", ""), zone)
+	if err != nil {
+		t.Fatalf("parseCaptureTime failed: %v", err)
+	}
+
+	want := time.Date(2022, 12, 25, 9, 15, 0, 0, zone)
+	if !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestParseCaptureTimeMissing(t *testing.T) {
+	if _, err := parseCaptureTime([]byte{0xFF, 0xD8, 0xFF, 0xD9}, time.UTC); err == nil {
+		t.Error("Expected an error for a file without EXIF")
+	}
+	if _, err := parseCaptureTime(append([]byte("Exif\x00\x00"), "II*"...), time.UTC); err == nil {
+		t.Error("Expected an error for a truncated TIFF header")
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+
+	// One photo dated by EXIF, one by modification time, plus files to ignore
+	if err := os.WriteFile(filepath.Join(dir, "beach.jpg"), buildEXIF("2023:07:04 18:30:00", "+00:00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scan := filepath.Join(dir, "scan.png")
+	if err := os.WriteFile(scan, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(scan, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".hidden.jpg"), []byte("jpg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	photos, err := Scan(dir, time.UTC)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if len(photos) != 2 {
+		t.Fatalf("Expected 2 photos, got %d", len(photos))
+	}
+	if photos[0].Path != scan || !photos[0].TakenAt.Equal(modTime) {
+		t.Errorf("Expected scan.png first at %v, got %s at %v", modTime, photos[0].Path, photos[0].TakenAt)
+	}
+	if filepath.Base(photos[1].Path) != "beach.jpg" {
+		t.Errorf("Expected beach.jpg second, got %s", photos[1].Path)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 12, 0, 0, 0, time.UTC) }
+
+	messages := []models.Message{
+		{GUID: "a", FormattedDate: day(2)},
+		{GUID: "b", FormattedDate: day(4)},
+	}
+	photos := []models.Memory{
+		{Path: "/photos/1.jpg", TakenAt: day(1)},
+		{Path: "/photos/3.jpg", TakenAt: day(3)},
+		{Path: "/photos/5.jpg", TakenAt: day(5)},
+	}
+
+	merged := Insert(messages, photos)
+
+	var order []string
+	for _, msg := range merged {
+		if msg.Memory != nil {
+			order = append(order, filepath.Base(msg.Memory.Path))
+		} else {
+			order = append(order, msg.GUID)
+		}
+	}
+
+	expected := []string{"1.jpg", "a", "3.jpg", "b", "5.jpg"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, order)
+			break
+		}
+	}
+}
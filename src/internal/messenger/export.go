@@ -0,0 +1,218 @@
+// Package messenger reads the conversations in a Facebook Messenger or Instagram data
+// download ("Download Your Information", JSON format) and converts them to a chat.db-shaped
+// database, so those chats can be made into a book like any iMessage export
+package messenger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// threadFileRegex matches the files a conversation is split across: message_1.json,
+// message_2.json and so on, or messages_1.json in some exports
+var threadFileRegex = regexp.MustCompile(`^messages?_(\d+)\.json$`)
+
+// skippedFolders hold conversations the account never accepted
+var skippedFolders = map[string]bool{"filtered_threads": true, "message_requests": true}
+
+// Thread is one conversation, merged from all of its message_N.json files
+type Thread struct {
+	Dir          string        // Folder the thread's files were read from
+	Title        string        `json:"title"`
+	Participants []Participant `json:"participants"`
+	Messages     []Message     `json:"messages"` // Newest first, as exported
+}
+
+// Participant is a member of a conversation
+type Participant struct {
+	Name string `json:"name"`
+}
+
+// Message is one entry in a conversation
+type Message struct {
+	SenderName  string     `json:"sender_name"`
+	TimestampMS int64      `json:"timestamp_ms"`
+	Content     string     `json:"content"`
+	Photos      []Media    `json:"photos"`
+	Videos      []Media    `json:"videos"`
+	AudioFiles  []Media    `json:"audio_files"`
+	Files       []Media    `json:"files"`
+	Gifs        []Media    `json:"gifs"`
+	Sticker     *Media     `json:"sticker"`
+	Share       *Share     `json:"share"`
+	Reactions   []Reaction `json:"reactions"`
+	IsUnsent    bool       `json:"is_unsent"`
+}
+
+// Media is a file sent with a message. URI is relative to the root of the download.
+type Media struct {
+	URI string `json:"uri"`
+}
+
+// Share is a link or post shared in a message
+type Share struct {
+	Link      string `json:"link"`
+	ShareText string `json:"share_text"`
+}
+
+// Reaction is an emoji reaction to a message
+type Reaction struct {
+	Reaction string `json:"reaction"`
+	Actor    string `json:"actor"`
+}
+
+// AllMedia returns every file sent with the message, stickers last
+func (m Message) AllMedia() []Media {
+	var media []Media
+	for _, group := range [][]Media{m.Photos, m.Videos, m.Gifs, m.AudioFiles, m.Files} {
+		media = append(media, group...)
+	}
+	if m.Sticker != nil && m.Sticker.URI != "" {
+		media = append(media, *m.Sticker)
+	}
+	return media
+}
+
+// FindThreads reads every conversation under path, which may be the whole download, its
+// inbox folder, a single conversation folder or one message_N.json file. Message requests
+// and filtered conversations are skipped.
+func FindThreads(path string) ([]*Thread, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Messenger export: %w", err)
+	}
+	if !info.IsDir() {
+		thread, err := readThread(filepath.Dir(path), []string{path})
+		if err != nil {
+			return nil, err
+		}
+		return []*Thread{thread}, nil
+	}
+
+	filesByDir := make(map[string][]string)
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if skippedFolders[entry.Name()] && file != path {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if threadFileRegex.MatchString(entry.Name()) {
+			dir := filepath.Dir(file)
+			filesByDir[dir] = append(filesByDir[dir], file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Messenger export: %w", err)
+	}
+	if len(filesByDir) == 0 {
+		return nil, fmt.Errorf("no message_N.json files found in %s", path)
+	}
+
+	dirs := make([]string, 0, len(filesByDir))
+	for dir := range filesByDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	threads := make([]*Thread, 0, len(dirs))
+	for _, dir := range dirs {
+		thread, err := readThread(dir, filesByDir[dir])
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+// readThread merges a conversation's files in page order and repairs their text
+func readThread(dir string, files []string) (*Thread, error) {
+	sort.Slice(files, func(i, j int) bool { return filePage(files[i]) < filePage(files[j]) })
+
+	thread := &Thread{Dir: dir}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var page Thread
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if thread.Title == "" {
+			thread.Title = page.Title
+			thread.Participants = page.Participants
+		}
+		thread.Messages = append(thread.Messages, page.Messages...)
+	}
+
+	thread.fixEncoding()
+	return thread, nil
+}
+
+// filePage returns N from message_N.json, so message_10.json sorts after message_9.json
+func filePage(file string) int {
+	match := threadFileRegex.FindStringSubmatch(filepath.Base(file))
+	if match == nil {
+		return 0
+	}
+	page, _ := strconv.Atoi(match[1])
+	return page
+}
+
+// fixEncoding repairs every string in the thread
+func (t *Thread) fixEncoding() {
+	t.Title = FixEncoding(t.Title)
+	for i := range t.Participants {
+		t.Participants[i].Name = FixEncoding(t.Participants[i].Name)
+	}
+	for i := range t.Messages {
+		msg := &t.Messages[i]
+		msg.SenderName = FixEncoding(msg.SenderName)
+		msg.Content = FixEncoding(msg.Content)
+		if msg.Share != nil {
+			msg.Share.ShareText = FixEncoding(msg.Share.ShareText)
+		}
+		for j := range msg.Reactions {
+			msg.Reactions[j].Reaction = FixEncoding(msg.Reactions[j].Reaction)
+			msg.Reactions[j].Actor = FixEncoding(msg.Reactions[j].Actor)
+		}
+		for _, group := range [][]Media{msg.Photos, msg.Videos, msg.AudioFiles, msg.Files, msg.Gifs} {
+			for j := range group {
+				group[j].URI = FixEncoding(group[j].URI)
+			}
+		}
+		if msg.Sticker != nil {
+			msg.Sticker.URI = FixEncoding(msg.Sticker.URI)
+		}
+	}
+}
+
+// FixEncoding undoes the mojibake in Meta's exports. They escape each byte of the UTF-8
+// text as its own \u00XX character, so "é" arrives as "Ã©". Text with any character above
+// U+00FF, or whose bytes aren't valid UTF-8, wasn't mangled and is returned unchanged.
+func FixEncoding(s string) string {
+	raw := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return s
+		}
+		raw = append(raw, byte(r))
+	}
+	if !utf8.Valid(raw) {
+		return s
+	}
+	return string(raw)
+}
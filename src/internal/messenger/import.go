@@ -0,0 +1,346 @@
+package messenger
+
+import (
+	"database/sql"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// AttachmentsDir is where converted images are written, beside the book. Media is read in
+// place from the download, but the TeX output refers to the converted copies.
+const AttachmentsDir = "messenger-attachments"
+
+// Services recorded on handles, depending on which app the download came from
+const (
+	ServiceMessenger = "Messenger"
+	ServiceInstagram = "Instagram"
+)
+
+// tapbacks maps reaction emoji to the chat.db reaction types that draw them. Others become
+// custom emoji tapbacks.
+var tapbacks = map[string]int{
+	"❤": 2000, "❤️": 2000, "😍": 2000,
+	"👍": 2001,
+	"👎": 2002,
+	"😂": 2003, "😆": 2003,
+	"‼️": 2004, "‼": 2004, "❗": 2004,
+	"❓": 2005,
+}
+
+// Result summarizes a converted download
+type Result struct {
+	Threads     int
+	Messages    int
+	Attachments int
+	Missing     int    // Media files named in the export but not found on disk
+	Owner       string // Participant treated as the account holder
+}
+
+// Open converts the conversations at exportPath into a temporary database and points
+// config at it, with converted images going to AttachmentsDir beside the output. The
+// account holder is config.MyName when set, otherwise guessed from the participants.
+// Call the returned cleanup function once generation is done.
+func Open(exportPath string, config *models.BookConfig) (func(), error) {
+	workDir, err := os.MkdirTemp("", "threadbound-messenger-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(workDir) }
+
+	dbPath := filepath.Join(workDir, "chat.db")
+	result, err := Convert(exportPath, dbPath, config.MyName)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	config.Log().Info("💬 Imported Messenger export", "messages", result.Messages, "attachments", result.Attachments,
+		"conversations", result.Threads, "owner", result.Owner)
+	if result.Missing > 0 {
+		config.Log().Warn("⚠️  Media files named in the export were not found", "count", result.Missing)
+	}
+
+	config.DatabasePath = dbPath
+	config.AttachmentsPath = filepath.Join(filepath.Dir(config.OutputPath), AttachmentsDir)
+	return cleanup, nil
+}
+
+// Convert writes the conversations at exportPath to a new database at dbPath. Messages
+// from owner count as sent; when owner is empty or in no conversation, Owner guesses.
+func Convert(exportPath, dbPath, owner string) (*Result, error) {
+	threads, err := FindThreads(exportPath)
+	if err != nil {
+		return nil, err
+	}
+	if owner == "" || !participates(threads, owner) {
+		owner = Owner(threads)
+	}
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	conn := db.GetConnection()
+	if _, err := conn.Exec(database.Schema); err != nil {
+		return nil, fmt.Errorf("failed to create database: %w", err)
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c := &converter{
+		tx:      tx,
+		owner:   owner,
+		handles: make(map[string]int64),
+		result:  &Result{Threads: len(threads), Owner: owner},
+	}
+	for _, thread := range threads {
+		if err := c.addThread(thread); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to save messages: %w", err)
+	}
+	return c.result, nil
+}
+
+// Owner guesses the account holder: the only participant in every conversation, or when
+// that's ambiguous (a download with a single conversation, say), the last participant
+// listed in the first one, where Meta puts the account holder
+func Owner(threads []*Thread) string {
+	if len(threads) == 0 {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	for _, thread := range threads {
+		seen := make(map[string]bool)
+		for _, p := range thread.Participants {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				counts[p.Name]++
+			}
+		}
+	}
+	var everywhere []string
+	for name, count := range counts {
+		if count == len(threads) {
+			everywhere = append(everywhere, name)
+		}
+	}
+	if len(everywhere) == 1 {
+		return everywhere[0]
+	}
+
+	participants := threads[0].Participants
+	if len(participants) == 0 {
+		return ""
+	}
+	return participants[len(participants)-1].Name
+}
+
+// participates reports whether name is a participant in any of the threads
+func participates(threads []*Thread, name string) bool {
+	for _, thread := range threads {
+		for _, p := range thread.Participants {
+			if p.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// converter inserts threads, giving each participant one handle
+type converter struct {
+	tx      *sql.Tx
+	owner   string
+	handles map[string]int64
+	result  *Result
+}
+
+func (c *converter) addThread(thread *Thread) error {
+	service := ServiceMessenger
+	if strings.Contains(filepath.ToSlash(thread.Dir), "instagram") {
+		service = ServiceInstagram
+	}
+
+	for _, msg := range thread.Messages {
+		if msg.IsUnsent {
+			continue
+		}
+
+		var handleID int64
+		fromMe := msg.SenderName == c.owner
+		if !fromMe {
+			id, err := c.handle(msg.SenderName, service)
+			if err != nil {
+				return err
+			}
+			handleID = id
+		}
+
+		media := msg.AllMedia()
+		guid := fmt.Sprintf("meta-%d", c.result.Messages+1)
+		messageID, err := c.addMessage(guid, messageText(msg, len(media)), msg.TimestampMS, fromMe, handleID, "", 0, len(media) > 0)
+		if err != nil {
+			return err
+		}
+		c.result.Messages++
+
+		for i, item := range media {
+			if err := c.addAttachment(messageID, fmt.Sprintf("%s-%d", guid, i+1), thread.Dir, item, fromMe); err != nil {
+				return err
+			}
+		}
+
+		// Reactions become tapbacks pointing at the message, as chat.db stores them
+		for i, reaction := range msg.Reactions {
+			var reactorID int64
+			reactorIsMe := reaction.Actor == c.owner
+			if !reactorIsMe {
+				if reactorID, err = c.handle(reaction.Actor, service); err != nil {
+					return err
+				}
+			}
+			// Custom emoji tapbacks carry their emoji in their text, as older chat.db versions do
+			tapback, known := tapbacks[reaction.Reaction]
+			text := ""
+			if !known {
+				tapback = int(models.ReactionCustom)
+				text = fmt.Sprintf("Reacted %s to a message", reaction.Reaction)
+			}
+			if _, err := c.addMessage(fmt.Sprintf("%s-reaction-%d", guid, i+1), text, msg.TimestampMS, reactorIsMe,
+				reactorID, "p:0/"+guid, tapback, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// messageText is the message's text with any shared link, or, like chat.db, one object
+// replacement character per attachment for media sent without a caption. Meta's own
+// "sent an attachment" captions are dropped in favor of the media.
+func messageText(msg Message, mediaCount int) string {
+	text := msg.Content
+	if mediaCount > 0 && strings.HasSuffix(text, " sent an attachment.") {
+		text = ""
+	}
+	if msg.Share != nil && msg.Share.Link != "" && !strings.Contains(text, msg.Share.Link) {
+		text = strings.TrimSpace(text + "\n" + msg.Share.Link)
+	}
+	if text == "" {
+		text = strings.Repeat("\uFFFC", mediaCount)
+	}
+	return text
+}
+
+// addMessage inserts a message and returns its ROWID. A handle ID of 0 is stored as NULL.
+func (c *converter) addMessage(guid, text string, timestampMS int64, fromMe bool, handleID int64,
+	associatedGUID string, associatedType int, hasAttachments bool) (int64, error) {
+	date := time.UnixMilli(timestampMS).Sub(database.AppleEpoch).Nanoseconds()
+	res, err := c.tx.Exec(`INSERT INTO message (guid, text, date, date_read, date_delivered, is_from_me,
+		handle_id, associated_message_guid, associated_message_type, cache_has_attachments)
+		VALUES (?, ?, ?, 0, 0, ?, ?, ?, ?, ?)`,
+		guid, database.Nullable(text), date, fromMe, nullableID(handleID), database.Nullable(associatedGUID), associatedType, hasAttachments)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert message: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// addAttachment links a media file to its message. The file stays where it is in the
+// download; its absolute path is stored as the filename, which attachment lookup reads
+// in place.
+func (c *converter) addAttachment(messageID int64, guid, threadDir string, media Media, outgoing bool) error {
+	path, size := locateMedia(threadDir, media.URI)
+	if size < 0 {
+		c.result.Missing++
+		size = 0
+	}
+
+	res, err := c.tx.Exec(`INSERT INTO attachment (guid, filename, mime_type, total_bytes, is_outgoing)
+		VALUES (?, ?, ?, ?, ?)`, guid, path, database.Nullable(mime.TypeByExtension(filepath.Ext(path))), size, outgoing)
+	if err != nil {
+		return fmt.Errorf("failed to insert attachment: %w", err)
+	}
+	attachmentID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if _, err := c.tx.Exec(`INSERT INTO message_attachment_join (message_id, attachment_id) VALUES (?, ?)`,
+		messageID, attachmentID); err != nil {
+		return fmt.Errorf("failed to link attachment: %w", err)
+	}
+	c.result.Attachments++
+	return nil
+}
+
+// locateMedia finds a media file from its URI, which is relative to the root of the
+// download. The root is found by trying each folder above the thread; failing that the
+// file is looked for in the thread's own folder, for threads copied out of the download.
+// The size is -1 when the file can't be found.
+func locateMedia(threadDir, uri string) (string, int64) {
+	relative := filepath.FromSlash(uri)
+	var candidates []string
+	if abs, err := filepath.Abs(threadDir); err == nil {
+		for dir := abs; ; dir = filepath.Dir(dir) {
+			candidates = append(candidates, filepath.Join(dir, relative))
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+		// photos/123.jpg under the thread folder
+		candidates = append(candidates, filepath.Join(abs, filepath.Base(filepath.Dir(relative)), filepath.Base(relative)))
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, info.Size()
+		}
+	}
+	if len(candidates) == 0 {
+		return uri, -1
+	}
+	return candidates[0], -1
+}
+
+// handle returns the handle for a participant, creating it the first time they're seen.
+// Meta exports only carry display names, so the name is the handle's address too.
+func (c *converter) handle(name, service string) (int64, error) {
+	if id, exists := c.handles[name]; exists {
+		return id, nil
+	}
+
+	res, err := c.tx.Exec(`INSERT INTO handle (id, country, service) VALUES (?, '', ?)`, name, service)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert handle: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	c.handles[name] = id
+	return id, nil
+}
+
+// nullableID stores a zero handle ID as NULL, as chat.db does for sent messages
+func nullableID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
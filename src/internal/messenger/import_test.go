@@ -0,0 +1,200 @@
+package messenger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"threadbound/internal/attachments"
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// Page 2 of the thread holds the older messages. Text is mangled the way Meta exports it:
+// "é" as Ã©, "😂" as ð\u009f\u0098\u0082 and "❤" as â\u009d¤.
+const testThreadOlder = `{
+  "participants": [{"name": "RenÃ©e"}, {"name": "Jordan Lee"}],
+  "messages": [
+    {"sender_name": "Jordan Lee", "timestamp_ms": 1700000060000, "content": "CafÃ© at 9?"},
+    {"sender_name": "RenÃ©e", "timestamp_ms": 1700000000000, "content": "Morning!"}
+  ],
+  "title": "RenÃ©e",
+  "thread_path": "inbox/renee_123"
+}`
+
+const testThreadNewer = `{
+  "participants": [{"name": "RenÃ©e"}, {"name": "Jordan Lee"}],
+  "messages": [
+    {"sender_name": "Jordan Lee", "timestamp_ms": 1700000240000, "content": "oops", "is_unsent": true},
+    {"sender_name": "Jordan Lee", "timestamp_ms": 1700000180000, "share": {"link": "https://example.com/menu"}},
+    {"sender_name": "RenÃ©e", "timestamp_ms": 1700000120000, "content": "RenÃ©e sent an attachment.",
+     "photos": [{"uri": "your_facebook_activity/messages/inbox/renee_123/photos/latte.png"}],
+     "reactions": [{"reaction": "â\u009d¤", "actor": "Jordan Lee"}, {"reaction": "ð\u009f\u0098\u0082", "actor": "RenÃ©e"}, {"reaction": "ð\u009f\u0094¥", "actor": "Jordan Lee"}]}
+  ],
+  "title": "RenÃ©e",
+  "thread_path": "inbox/renee_123"
+}`
+
+// writeTestExport lays out a download with one conversation, plus a message request that
+// should be skipped, and returns its root
+func writeTestExport(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	thread := filepath.Join(root, "your_facebook_activity", "messages", "inbox", "renee_123")
+	requests := filepath.Join(root, "your_facebook_activity", "messages", "message_requests", "spam_456")
+	for _, dir := range []string{filepath.Join(thread, "photos"), requests} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]string{
+		filepath.Join(thread, "message_1.json"):      testThreadNewer,
+		filepath.Join(thread, "message_2.json"):      testThreadOlder,
+		filepath.Join(thread, "photos", "latte.png"): "png",
+		filepath.Join(requests, "message_1.json"):    `{"participants": [{"name": "Spammer"}, {"name": "Jordan Lee"}], "messages": [{"sender_name": "Spammer", "timestamp_ms": 1, "content": "Win!"}]}`,
+	}
+	for path, contents := range files {
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestFixEncoding(t *testing.T) {
+	tests := map[string]string{
+		"RenÃ©e":              "Renée",
+		"ð\u009f\u0098\u0082": "😂",
+		"plain":               "plain",
+		"Renée 😂":             "Renée 😂", // Not mangled: has characters above U+00FF
+		"café":                "café",    // Not mangled: é alone isn't valid UTF-8
+	}
+	for in, want := range tests {
+		if got := FixEncoding(in); got != want {
+			t.Errorf("FixEncoding(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	root := writeTestExport(t)
+	dbPath := filepath.Join(t.TempDir(), "chat.db")
+
+	result, err := Convert(root, dbPath, "")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Threads != 1 || result.Messages != 4 || result.Attachments != 1 || result.Missing != 0 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if result.Owner != "Jordan Lee" {
+		t.Errorf("Expected the last participant as owner, got %q", result.Owner)
+	}
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	messages, err := db.GetMessages()
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 messages (unsent and reactions skipped), got %d", len(messages))
+	}
+
+	texts := []string{"Morning!", "Café at 9?", "\uFFFC", "https://example.com/menu"}
+	for i, want := range texts {
+		if got := *messages[i].Text; got != want {
+			t.Errorf("Message %d: expected %q, got %q", i, want, got)
+		}
+	}
+	if messages[0].IsFromMe || !messages[1].IsFromMe {
+		t.Error("Expected first message received and second sent")
+	}
+	if want := "2023-11-14 22:13:20"; messages[0].FormattedDate.Format("2006-01-02 15:04:05") != want {
+		t.Errorf("Expected date %s, got %s", want, messages[0].FormattedDate)
+	}
+
+	handles, err := db.GetHandles(nil)
+	if err != nil {
+		t.Fatalf("GetHandles failed: %v", err)
+	}
+	if handle := handles[*messages[0].HandleID]; handle.DisplayName != "Renée" || handle.Service != ServiceMessenger {
+		t.Errorf("Unexpected handle %+v", handle)
+	}
+
+	reactions, err := db.GetReactions(handles)
+	if err != nil {
+		t.Fatalf("GetReactions failed: %v", err)
+	}
+	photoReactions := reactions[messages[2].GUID]
+	if len(photoReactions) != 3 || photoReactions[0].ReactionEmoji != "❤️" || photoReactions[1].ReactionEmoji != "😂" || photoReactions[2].ReactionEmoji != "🔥" {
+		t.Errorf("Unexpected reactions %+v", photoReactions)
+	}
+
+	all, err := db.GetAllAttachments()
+	if err != nil {
+		t.Fatalf("GetAllAttachments failed: %v", err)
+	}
+	att := all[messages[2].ID][0]
+	path, err := attachments.Locate(filepath.Join(root, AttachmentsDir), att.GUID, *att.Filename)
+	if err != nil || filepath.Base(path) != "latte.png" {
+		t.Errorf("Expected photo found in the download, got %q, %v", path, err)
+	}
+}
+
+func TestConvertWithOwner(t *testing.T) {
+	root := writeTestExport(t)
+
+	thread := filepath.Join(root, "your_facebook_activity", "messages", "inbox", "renee_123")
+	result, err := Convert(thread, filepath.Join(t.TempDir(), "chat.db"), "Renée")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Owner != "Renée" {
+		t.Errorf("Expected configured owner, got %q", result.Owner)
+	}
+
+	// A name that isn't in any conversation falls back to the guess
+	result, err = Convert(thread, filepath.Join(t.TempDir(), "chat.db"), "Me")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if result.Owner != "Jordan Lee" {
+		t.Errorf("Expected guessed owner, got %q", result.Owner)
+	}
+}
+
+func TestOwner(t *testing.T) {
+	threads := []*Thread{
+		{Participants: []Participant{{Name: "Jordan Lee"}, {Name: "Sam"}}},
+		{Participants: []Participant{{Name: "Alex"}, {Name: "Jordan Lee"}, {Name: "Kim"}}},
+	}
+	if got := Owner(threads); got != "Jordan Lee" {
+		t.Errorf("Expected the participant in every conversation, got %q", got)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	root := writeTestExport(t)
+	outDir := t.TempDir()
+
+	config := &models.BookConfig{OutputPath: filepath.Join(outDir, "book.tex"), MyName: "Jordan Lee"}
+	cleanup, err := Open(root, config)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer cleanup()
+
+	if _, err := os.Stat(config.DatabasePath); err != nil {
+		t.Errorf("Expected database at %s: %v", config.DatabasePath, err)
+	}
+	if config.AttachmentsPath != filepath.Join(outDir, AttachmentsDir) {
+		t.Errorf("Expected attachments beside the output, got %s", config.AttachmentsPath)
+	}
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the name of every environment variable a setting can be given in
+const EnvPrefix = "THREADBOUND_"
+
+// Setting is a BookConfig field with the names it can be set by. Each is set from, in
+// increasing precedence, the config file, the environment and a command-line flag.
+type Setting struct {
+	Key   string   // Dotted key in the config file, such as images.target_dpi
+	Env   string   // Environment variable, such as THREADBOUND_IMAGES_TARGET_DPI
+	Flags []string // Flags that set it, from the field's flag tag, such as target-dpi
+	index []int    // Path to the field through BookConfig
+}
+
+var (
+	settings     []Setting
+	settingsOnce sync.Once
+)
+
+// Settings lists every setting of BookConfig, including those of its sections such as
+// images and theme. Maps, lists and durations are single settings.
+func Settings() []Setting {
+	settingsOnce.Do(func() {
+		settings = collectSettings(reflect.TypeOf(BookConfig{}), "", nil)
+	})
+	return settings
+}
+
+// collectSettings lists the settings of a struct type, under the key prefix and field path
+func collectSettings(t reflect.Type, prefix string, index []int) []Setting {
+	var found []Setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		path := append(append([]int(nil), index...), i)
+
+		if field.Type.Kind() == reflect.Struct {
+			found = append(found, collectSettings(field.Type, key+".", path)...)
+			continue
+		}
+		setting := Setting{
+			Key:   key,
+			Env:   EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_")),
+			index: path,
+		}
+		if flags := field.Tag.Get("flag"); flags != "" {
+			setting.Flags = strings.Split(flags, ",")
+		}
+		found = append(found, setting)
+	}
+	return found
+}
+
+// Bind sets c from the config file at path, if there is one, and then from the
+// environment, leaving alone the settings given by a flag: flagChanged reports whether the
+// named flag was given. Settings missing from both keep the value they have, so c should
+// hold the defaults and flag values. A non-empty profile names an entry of the file's
+// profiles section whose settings replace those at the top of the file.
+func (c *BookConfig) Bind(path, profile string, lookupEnv func(string) (string, bool), flagChanged func(string) bool) error {
+	var file BookConfig
+	var keys map[string]interface{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		if err := yaml.Unmarshal(data, &keys); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if profile != "" {
+			if keys, err = applyProfile(keys, profile); err != nil {
+				return fmt.Errorf("config file %s: %w", path, err)
+			}
+			if data, err = yaml.Marshal(keys); err != nil {
+				return err
+			}
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		file.NormalizePaths()
+	} else if profile != "" {
+		return fmt.Errorf("profile %q needs a config file with a profiles section", profile)
+	}
+
+	config := reflect.ValueOf(c).Elem()
+	for _, setting := range Settings() {
+		if setting.givenByFlag(flagChanged) {
+			continue
+		}
+		field := config.FieldByIndex(setting.index)
+		if hasKey(keys, setting.Key) {
+			field.Set(reflect.ValueOf(file).FieldByIndex(setting.index))
+		}
+		if value, ok := lookupEnv(setting.Env); ok {
+			if err := setFromEnv(field, value); err != nil {
+				return fmt.Errorf("invalid %s: %w", setting.Env, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyProfile returns the keys of a config file with those of the named profile laid over
+// them, and the profiles section left out
+func applyProfile(keys map[string]interface{}, profile string) (map[string]interface{}, error) {
+	profiles, _ := keys["profiles"].(map[string]interface{})
+	selected, exists := profiles[profile]
+	if !exists {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("profile %q not found; the file has no profiles", profile)
+		}
+		return nil, fmt.Errorf("profile %q not found; profiles are %s", profile, strings.Join(names, ", "))
+	}
+	settings, ok := selected.(map[string]interface{})
+	if !ok && selected != nil {
+		return nil, fmt.Errorf("profile %q must be a section of settings", profile)
+	}
+
+	merged := mergeKeys(keys, settings)
+	delete(merged, "profiles")
+	return merged, nil
+}
+
+// mergeKeys lays over onto base, merging sections such as images and maps such as
+// contact_names key by key. Lists and other values in over replace those in base.
+func mergeKeys(base, over map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(over))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range over {
+		baseSection, baseIsSection := merged[key].(map[string]interface{})
+		overSection, overIsSection := value.(map[string]interface{})
+		if baseIsSection && overIsSection {
+			value = mergeKeys(baseSection, overSection)
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// givenByFlag reports whether any of the setting's flags was given
+func (s Setting) givenByFlag(flagChanged func(string) bool) bool {
+	for _, flag := range s.Flags {
+		if flagChanged(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKey reports whether the config file sets a dotted key, even to an empty value
+func hasKey(keys map[string]interface{}, key string) bool {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		section, ok := keys[part].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		keys = section
+	}
+	_, exists := keys[parts[len(parts)-1]]
+	return exists
+}
+
+// setFromEnv parses an environment variable into a field. Strings are taken as they are,
+// lists are comma-separated, and anything else, such as numbers, booleans, durations and
+// maps, is read as YAML: THREADBOUND_CONTACT_NAMES='{"+15551234567": Mom}'.
+func setFromEnv(field reflect.Value, value string) error {
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+		return nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items).Convert(field.Type()))
+		return nil
+	case field.Type() == reflect.TypeOf(time.Duration(0)):
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	parsed := reflect.New(field.Type())
+	if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+		return err
+	}
+	field.Set(parsed.Elem())
+	return nil
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettings(t *testing.T) {
+	byKey := make(map[string]Setting)
+	flags := make(map[string]string)
+	for _, setting := range Settings() {
+		byKey[setting.Key] = setting
+		for _, flag := range setting.Flags {
+			if other, exists := flags[flag]; exists {
+				t.Errorf("Flag --%s sets both %s and %s", flag, other, setting.Key)
+			}
+			flags[flag] = setting.Key
+		}
+	}
+
+	tests := []struct {
+		key   string
+		env   string
+		flags []string
+	}{
+		{"title", "THREADBOUND_TITLE", []string{"title"}},
+		{"database_path", "THREADBOUND_DATABASE_PATH", []string{"db"}},
+		{"output_path", "THREADBOUND_OUTPUT_PATH", []string{"output", "input"}},
+		{"images.target_dpi", "THREADBOUND_IMAGES_TARGET_DPI", []string{"target-dpi"}},
+		{"theme.sent_color", "THREADBOUND_THEME_SENT_COLOR", nil},
+		{"contact_names", "THREADBOUND_CONTACT_NAMES", nil},
+	}
+	for _, test := range tests {
+		setting, exists := byKey[test.key]
+		if !exists {
+			t.Errorf("Expected a setting for %s", test.key)
+			continue
+		}
+		if setting.Env != test.env || !reflect.DeepEqual(setting.Flags, test.flags) {
+			t.Errorf("Setting %s has env %s and flags %v, expected %s and %v", test.key, setting.Env, setting.Flags, test.env, test.flags)
+		}
+	}
+	if _, exists := byKey["log_output"]; exists {
+		t.Error("Expected fields without a YAML key to be left out")
+	}
+}
+
+func TestBind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+title: From File
+author: File Author
+include_images: false
+page_width: 6in
+images:
+  jpeg_quality: 70
+theme:
+  font: Georgia
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"THREADBOUND_AUTHOR":           "Env Author",
+		"THREADBOUND_PAGE_WIDTH":       "7in",
+		"THREADBOUND_URL_TIMEOUT":      "2m",
+		"THREADBOUND_EXCLUDE_CONTACTS": "Alice, Bob",
+		"THREADBOUND_CONTACT_NAMES":    `{"+15551234567": Mom}`,
+		"THREADBOUND_FILTER_SPAM":      "true",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	// --page-width was given, so neither the file nor the environment changes it
+	flagChanged := func(name string) bool { return name == "page-width" }
+
+	config := *GetDefaultConfig()
+	config.PageWidth = "5in"
+	if err := config.Bind(path, "", lookupEnv, flagChanged); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+
+	if config.Title != "From File" {
+		t.Errorf("Expected the title from the file, got %q", config.Title)
+	}
+	if config.Author != "Env Author" {
+		t.Errorf("Expected the environment to take precedence over the file, got %q", config.Author)
+	}
+	if config.PageWidth != "5in" {
+		t.Errorf("Expected the flag to take precedence, got %q", config.PageWidth)
+	}
+	if config.IncludeImages {
+		t.Error("Expected include_images: false in the file to be kept")
+	}
+	if config.PageHeight != "8.5in" {
+		t.Errorf("Expected settings set nowhere to keep their default, got %q", config.PageHeight)
+	}
+	if config.Images.JPEGQuality != 70 || config.Theme.Font != "Georgia" {
+		t.Errorf("Expected section settings from the file, got %+v and %+v", config.Images, config.Theme)
+	}
+	if config.URLTimeout != 2*time.Minute || !config.FilterSpam {
+		t.Errorf("Expected a duration and a boolean from the environment, got %v and %v", config.URLTimeout, config.FilterSpam)
+	}
+	if !reflect.DeepEqual(config.ExcludeContacts, []string{"Alice", "Bob"}) {
+		t.Errorf("Expected a comma-separated list from the environment, got %v", config.ExcludeContacts)
+	}
+	if config.ContactNames["+15551234567"] != "Mom" {
+		t.Errorf("Expected a YAML map from the environment, got %v", config.ContactNames)
+	}
+}
+
+func TestBindInvalidEnv(t *testing.T) {
+	config := *GetDefaultConfig()
+	lookupEnv := func(name string) (string, bool) {
+		return "many", name == "THREADBOUND_TOC_DEPTH"
+	}
+	if err := config.Bind("", "", lookupEnv, func(string) bool { return false }); err == nil {
+		t.Error("Expected an error for a number that doesn't parse")
+	}
+}
+
+func TestBindProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+title: Our Messages
+author: Sam
+images:
+  grayscale: true
+  jpeg_quality: 70
+exclude_contacts: [Alice]
+profiles:
+  anniversary:
+    title: Five Years
+    filter: "after:2023-06-01"
+    images:
+      grayscale: false
+    exclude_contacts: []
+  empty:
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noEnv := func(string) (string, bool) { return "", false }
+	noFlags := func(string) bool { return false }
+
+	config := *GetDefaultConfig()
+	if err := config.Bind(path, "anniversary", noEnv, noFlags); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if config.Title != "Five Years" || config.Filter != "after:2023-06-01" {
+		t.Errorf("Expected the profile's settings, got title %q and filter %q", config.Title, config.Filter)
+	}
+	if config.Author != "Sam" {
+		t.Errorf("Expected settings the profile leaves out to come from the top of the file, got %q", config.Author)
+	}
+	if config.Images.Grayscale || config.Images.JPEGQuality != 70 {
+		t.Errorf("Expected the images section to be merged key by key, got %+v", config.Images)
+	}
+	if len(config.ExcludeContacts) != 0 {
+		t.Errorf("Expected the profile's list to replace the file's, got %v", config.ExcludeContacts)
+	}
+
+	config = *GetDefaultConfig()
+	if err := config.Bind(path, "empty", noEnv, noFlags); err != nil {
+		t.Fatalf("Bind failed for an empty profile: %v", err)
+	}
+	if config.Title != "Our Messages" || !config.Images.Grayscale {
+		t.Errorf("Expected an empty profile to keep the file's settings, got %q and %+v", config.Title, config.Images)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		profile string
+		message string
+	}{
+		{"unknown profile", path, "wedding", "profiles are anniversary, empty"},
+		{"no config file", "", "anniversary", "needs a config file"},
+	}
+	for _, test := range tests {
+		config := *GetDefaultConfig()
+		err := config.Bind(test.path, test.profile, noEnv, noFlags)
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.message, err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizePath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"chat.db", "chat.db"},
+		{`exports\Attachments`, filepath.Join("exports", "Attachments")},
+		{"exports/Attachments/", filepath.Join("exports", "Attachments")},
+		{"~/Library/Messages/chat.db", filepath.Join(home, "Library", "Messages", "chat.db")},
+	}
+
+	for _, test := range tests {
+		if result := NormalizePath(test.input); result != test.expected {
+			t.Errorf("NormalizePath(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestNotesPagesAt(t *testing.T) {
+	tests := []struct {
+		notes    NotesPagesConfig
+		position string
+		expected bool
+	}{
+		{NotesPagesConfig{}, NotesAtEnd, false},
+		{NotesPagesConfig{Count: 2}, NotesAtEnd, true},
+		{NotesPagesConfig{Count: 2}, NotesAtYear, false},
+		{NotesPagesConfig{Count: 2, Positions: []string{NotesAtYear}}, NotesAtYear, true},
+		{NotesPagesConfig{Count: 2, Positions: []string{NotesAtYear}}, NotesAtEnd, false},
+	}
+
+	for _, test := range tests {
+		if result := test.notes.At(test.position); result != test.expected {
+			t.Errorf("%+v.At(%q) = %v, expected %v", test.notes, test.position, result, test.expected)
+		}
+	}
+}
+
+func TestNotesPagesValidate(t *testing.T) {
+	valid := NotesPagesConfig{Count: 4, Style: NotesStyleBlank, Positions: []string{NotesAtEnd, NotesAtYear}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid config, got: %v", err)
+	}
+
+	invalid := []NotesPagesConfig{
+		{Count: -1},
+		{Count: 1, Style: "dotted"},
+		{Count: 1, Positions: []string{"chapter"}},
+	}
+	for _, notes := range invalid {
+		if err := notes.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", notes)
+		}
+	}
+}
+
+func TestVolumesValidate(t *testing.T) {
+	valid := VolumesConfig{MaxPages: 800, SplitBy: SplitByYear, Numbering: NumberingContinue}
+	if err := valid.Validate(); err != nil || !valid.Enabled() {
+		t.Errorf("Expected valid, enabled config, got: %v", err)
+	}
+	if (VolumesConfig{Numbering: NumberingContinue}).Enabled() {
+		t.Error("Expected numbering alone not to split the book")
+	}
+
+	invalid := []VolumesConfig{
+		{MaxPages: -1},
+		{SplitBy: "month"},
+		{MaxPages: 800, Numbering: "roman"},
+	}
+	for _, volumes := range invalid {
+		if err := volumes.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", volumes)
+		}
+	}
+}
+
+func TestSummariesValidate(t *testing.T) {
+	if err := (SummariesConfig{}).Validate(); err != nil {
+		t.Errorf("Expected summaries that are off to need nothing, got: %v", err)
+	}
+	valid := SummariesConfig{Enabled: true, Endpoint: "http://localhost:11434/v1", Model: "llama3.1"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid config, got: %v", err)
+	}
+
+	invalid := []SummariesConfig{
+		{Enabled: true, Model: "llama3.1"},
+		{Enabled: true, Endpoint: "localhost:11434", Model: "llama3.1"},
+		{Enabled: true, Endpoint: "http://localhost:11434/v1"},
+		{Enabled: true, Endpoint: "http://localhost:11434/v1", Model: "llama3.1", Timeout: -1},
+	}
+	for _, summaries := range invalid {
+		if err := summaries.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", summaries)
+		}
+	}
+}
+
+func TestTextConfigValidate(t *testing.T) {
+	for _, text := range []TextConfig{{}, {Format: TextFormatPlain}, {Format: TextFormatJSONL, ChunkTokens: 8000}} {
+		if err := text.Validate(); err != nil {
+			t.Errorf("Expected valid config for %+v, got: %v", text, err)
+		}
+	}
+	for _, text := range []TextConfig{{Format: "csv"}, {Format: "JSON"}, {ChunkTokens: -1}} {
+		if err := text.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", text)
+		}
+	}
+}
+
+func TestURLPolicies(t *testing.T) {
+	policies := URLPolicies{
+		"bit.ly":            URLPolicySkip,
+		"www.YouTube.com":   URLPolicyScreenshot,
+		"music.youtube.com": URLPolicyText,
+	}
+	for link, want := range map[string]string{
+		"https://bit.ly/3abc":                 URLPolicySkip,
+		"https://youtube.com/watch?v=1":       URLPolicyScreenshot,
+		"https://m.youtube.com/watch?v=1":     URLPolicyScreenshot,
+		"https://music.youtube.com/watch?v=1": URLPolicyText,
+		"https://notyoutube.com/":             URLPolicyAuto,
+		"https://example.com/":                URLPolicyAuto,
+	} {
+		if got := policies.For(link); got != want {
+			t.Errorf("For(%q) = %q, want %q", link, got, want)
+		}
+	}
+	if err := policies.Validate(); err != nil {
+		t.Errorf("Expected valid policies, got: %v", err)
+	}
+	if err := (URLPolicies{"example.com": "never"}).Validate(); err == nil {
+		t.Error("Expected error for an unknown policy")
+	}
+}
+
+func TestAvatars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sam.jpg")
+	os.WriteFile(path, []byte("jpeg"), 0644)
+
+	avatars := Avatars{"+15551234567": path, "Bob": "bob.png"}
+	if got := avatars.For("+15551234567", "Sam"); got != path {
+		t.Errorf("Expected the avatar found by contact ID, got %q", got)
+	}
+	if got := avatars.For("bob@example.com", "Bob"); got != "bob.png" {
+		t.Errorf("Expected the avatar found by display name, got %q", got)
+	}
+	if got := avatars.For("", "Alice"); got != "" {
+		t.Errorf("Expected no avatar, got %q", got)
+	}
+
+	if err := (Avatars{"Sam": path}).Validate(); err != nil {
+		t.Errorf("Expected valid avatars, got: %v", err)
+	}
+	if err := avatars.Validate(); err == nil {
+		t.Error("Expected error for a missing image")
+	}
+}
+
+func TestValidateContents(t *testing.T) {
+	foreword := filepath.Join(t.TempDir(), "foreword.md")
+	os.WriteFile(foreword, []byte("Hello"), 0644)
+
+	for _, config := range []BookConfig{
+		{},
+		{ChapterStrategy: ChaptersByQuarter, TOCDepth: 1},
+		{ChapterStrategy: ChaptersNone, TOCDepth: MaxTOCDepth},
+		{ForewordPath: foreword},
+		{PhotoLayout: PhotoLayoutInsert},
+		{LinkStyle: LinkStyleFootnote},
+		{WordCloud: WordCloudYear},
+		{GreatestHits: 10},
+	} {
+		if err := config.ValidateContents(); err != nil {
+			t.Errorf("Expected %+v valid, got: %v", config, err)
+		}
+	}
+	for _, config := range []BookConfig{
+		{ChapterStrategy: "week"},
+		{TOCDepth: 3},
+		{TOCDepth: -1},
+		{DayMinLines: -2},
+		{GapDays: -1},
+		{PhotoLayout: "gallery"},
+		{LinkStyle: "bold"},
+		{WordCloud: "month"},
+		{GreatestHits: -1},
+		{ForewordPath: filepath.Join(filepath.Dir(foreword), "missing.md")},
+		{ForewordPath: filepath.Join(filepath.Dir(foreword), "foreword.docx")},
+	} {
+		if err := config.ValidateContents(); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+	if got := (&BookConfig{}).Chapters(); got != ChaptersByMonth {
+		t.Errorf("Expected month chapters by default, got %q", got)
+	}
+	if got := (&BookConfig{}).DaySpace(); got != DefaultDayMinLines {
+		t.Errorf("Expected %d lines below day headings by default, got %d", DefaultDayMinLines, got)
+	}
+}
+
+func TestChatName(t *testing.T) {
+	handles := map[int]Handle{1: {DisplayName: "Sam"}, 2: {DisplayName: "Alex"}}
+	if got := (Chat{DisplayName: "Hiking Crew", HandleIDs: []int{1, 2}}).Name(handles); got != "Hiking Crew" {
+		t.Errorf("Expected the group name, got %q", got)
+	}
+	if got := (Chat{HandleIDs: []int{1, 2}}).Name(handles); got != "Sam, Alex" {
+		t.Errorf("Expected the participants, got %q", got)
+	}
+	if got := (Chat{Identifier: "chat123", HandleIDs: []int{9}}).Name(handles); got != "chat123" {
+		t.Errorf("Expected the identifier, got %q", got)
+	}
+}
+
+func TestThemeWithDefaults(t *testing.T) {
+	theme := ThemeConfig{Font: "Georgia", BubbleMaxWidth: 0.6}.WithDefaults()
+	if theme.Font != "Georgia" || theme.BubbleMaxWidth != 0.6 {
+		t.Errorf("Expected configured values to be kept, got %+v", theme)
+	}
+	if theme.EmojiFont != DefaultEmojiFont || theme.SentColor != DefaultSentColor || theme.CornerRadius != DefaultCornerRadius {
+		t.Errorf("Expected defaults for empty fields, got %+v", theme)
+	}
+}
+
+func TestThemeValidate(t *testing.T) {
+	valid := []ThemeConfig{
+		{},
+		{Font: "Helvetica Neue", EmojiFont: "Noto Emoji", SentColor: "#34c759", ReceivedColor: "#E5E5EA", CornerRadius: "2.5mm", BubbleMaxWidth: 0.8},
+	}
+	for _, theme := range valid {
+		if err := theme.Validate(); err != nil {
+			t.Errorf("Expected valid theme %+v, got: %v", theme, err)
+		}
+	}
+
+	invalid := []ThemeConfig{
+		{Font: `Arial}\input{secret`},
+		{SentColor: "blue"},
+		{ReceivedColor: "#FFF"},
+		{CornerRadius: "4"},
+		{BubbleMaxWidth: 0.9},
+		{BubbleMaxWidth: -0.1},
+	}
+	for _, theme := range invalid {
+		if err := theme.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", theme)
+		}
+	}
+}
+
+func TestImageConfig(t *testing.T) {
+	images := ImageConfig{JPEGQuality: 70}.WithDefaults()
+	if images.TargetDPI != DefaultTargetDPI || images.MaxDimension != DefaultMaxDimension || images.JPEGQuality != 70 {
+		t.Errorf("Expected defaults filled in around the quality, got %+v", images)
+	}
+	if err := images.Validate(); err != nil {
+		t.Errorf("Expected %+v valid, got: %v", images, err)
+	}
+	for _, images := range []ImageConfig{{TargetDPI: -1}, {MaxDimension: -1}, {JPEGQuality: 101}} {
+		if err := images.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", images)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	if loc, err := (&BookConfig{}).Location(); err != nil || loc != time.Local {
+		t.Errorf("Expected the local zone by default, got %v (err %v)", loc, err)
+	}
+	if loc, err := (&BookConfig{Timezone: "UTC"}).Location(); err != nil || loc.String() != "UTC" {
+		t.Errorf("Expected UTC, got %v (err %v)", loc, err)
+	}
+	if _, err := (&BookConfig{Timezone: "Nowhere/Special"}).Location(); err == nil {
+		t.Error("Expected an error for an unknown timezone")
+	}
+}
@@ -2,8 +2,14 @@ package models
 
 import (
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
+	_ "time/tzdata" // Embed zone names for Timezone where the system has none, such as Windows
 
 	"gopkg.in/yaml.v3"
 )
@@ -37,57 +43,93 @@ func (rt ReactionType) String() string {
 	}
 }
 
+// Balloon bundle IDs of messages that carry a drawing instead of text
+const (
+	HandwritingBundleID  = "com.apple.Handwriting.HandwritingProvider"
+	DigitalTouchBundleID = "com.apple.DigitalTouchBalloonProvider"
+)
+
+// sendEffects names the bubble and screen effects by their expressive_send_style_id
+var sendEffects = map[string]string{
+	"com.apple.MobileSMS.expressivesend.impact":       "Slam 💥",
+	"com.apple.MobileSMS.expressivesend.loud":         "Loud 📢",
+	"com.apple.MobileSMS.expressivesend.gentle":       "Gentle 🤫",
+	"com.apple.MobileSMS.expressivesend.invisibleink": "Invisible Ink 🙈",
+	"com.apple.messages.effect.CKConfettiEffect":      "Confetti 🎉",
+	"com.apple.messages.effect.CKHappyBirthdayEffect": "Balloons 🎈",
+	"com.apple.messages.effect.CKHeartEffect":         "Love ❤️",
+	"com.apple.messages.effect.CKLasersEffect":        "Lasers ⚡",
+	"com.apple.messages.effect.CKFireworksEffect":     "Fireworks 🎆",
+	"com.apple.messages.effect.CKSparklesEffect":      "Celebration ✨",
+	"com.apple.messages.effect.CKShootingStarEffect":  "Shooting Star 🌠",
+	"com.apple.messages.effect.CKEchoEffect":          "Echo 🔁",
+	"com.apple.messages.effect.CKSpotlightEffect":     "Spotlight 🔦",
+}
+
 // Message represents an iMessage from the database
 type Message struct {
-	ID                    int       `db:"ROWID"`
-	GUID                  string    `db:"guid"`
-	Text                  *string   `db:"text"`
-	Date                  int64     `db:"date"`
-	DateRead              *int64    `db:"date_read"`
-	DateDelivered         *int64    `db:"date_delivered"`
-	IsFromMe              bool      `db:"is_from_me"`
-	IsDelivered           bool      `db:"is_delivered"`
-	IsRead                bool      `db:"is_read"`
-	HandleID              *int      `db:"handle_id"`
-	HasAttachments        bool      `db:"cache_has_attachments"`
-	Subject               *string   `db:"subject"`
-	IsAudioMessage        bool      `db:"is_audio_message"`
-	AssociatedMessageGUID *string   `db:"associated_message_guid"`
-	AssociatedMessageType int       `db:"associated_message_type"`
-	ItemType              int       `db:"item_type"`
+	ID                    int     `db:"ROWID"`
+	GUID                  string  `db:"guid"`
+	Text                  *string `db:"text"`
+	Date                  int64   `db:"date"`
+	DateRead              *int64  `db:"date_read"`
+	DateDelivered         *int64  `db:"date_delivered"`
+	IsFromMe              bool    `db:"is_from_me"`
+	IsDelivered           bool    `db:"is_delivered"`
+	IsRead                bool    `db:"is_read"`
+	HandleID              *int    `db:"handle_id"`
+	HasAttachments        bool    `db:"cache_has_attachments"`
+	Subject               *string `db:"subject"`
+	IsAudioMessage        bool    `db:"is_audio_message"`
+	AssociatedMessageGUID *string `db:"associated_message_guid"`
+	AssociatedMessageType int     `db:"associated_message_type"`
+	ItemType              int     `db:"item_type"`
+	ExpressiveSendStyleID *string `db:"expressive_send_style_id"` // Bubble or screen effect, e.g. confetti
+	BalloonBundleID       *string `db:"balloon_bundle_id"`        // iMessage app that drew the message, if any
 
 	// Threading fields
-	ReplyToGUID            *string `db:"reply_to_guid"`
-	ThreadOriginatorGUID   *string `db:"thread_originator_guid"`
-	ThreadOriginatorPart   *string `db:"thread_originator_part"`
+	ReplyToGUID          *string `db:"reply_to_guid"`
+	ThreadOriginatorGUID *string `db:"thread_originator_guid"`
+	ThreadOriginatorPart *string `db:"thread_originator_part"`
 
 	// Computed fields
-	FormattedDate   time.Time
-	SenderName      string
-	Attachments     []Attachment
-	Reactions       []Reaction
+	FormattedDate time.Time
+	ChatID        int // ROWID of the conversation the message is in, 0 if it isn't in one
+	SenderName    string
+	Attachments   []Attachment
+	Reactions     []Reaction
 
 	// Threading computed fields
-	ReplyToMessage     *Message  // Populated when loading thread context
-	ThreadReplies      []Message // Messages that reply to this message
-	ThreadOriginator   *Message  // The original message that started this thread
-	IsReaction         bool      // True if this is a reaction (tapback)
-	ReactionType       ReactionType
+	ReplyToMessage   *Message  // Populated when loading thread context
+	ThreadReplies    []Message // Messages that reply to this message
+	ThreadOriginator *Message  // The original message that started this thread
+	IsReaction       bool      // True if this is a reaction (tapback)
+	ReactionType     ReactionType
+
+	// Memory is set on placeholder messages for photos inserted from outside the chat
+	Memory *Memory
+}
+
+// Memory is a photo from outside the conversation, placed in the timeline at its capture date
+type Memory struct {
+	Path          string    // Original photo on disk
+	TakenAt       time.Time // EXIF capture time, or modification time when missing
+	ProcessedPath string    // Converted image ready for the book
 }
 
 // Attachment represents a file attachment
 type Attachment struct {
-	ID          int     `db:"ROWID"`
-	GUID        string  `db:"guid"`
-	Filename    *string `db:"filename"`
-	UTI         *string `db:"uti"`
-	MimeType    *string `db:"mime_type"`
-	TotalBytes  int64   `db:"total_bytes"`
-	IsSticker   bool    `db:"is_sticker"`
-	IsOutgoing  bool    `db:"is_outgoing"`
+	ID         int     `db:"ROWID"`
+	GUID       string  `db:"guid"`
+	Filename   *string `db:"filename"`
+	UTI        *string `db:"uti"`
+	MimeType   *string `db:"mime_type"`
+	TotalBytes int64   `db:"total_bytes"`
+	IsSticker  bool    `db:"is_sticker"`
+	IsOutgoing bool    `db:"is_outgoing"`
 
 	// Computed fields
-	LocalPath   string
+	LocalPath     string
 	ProcessedPath string
 }
 
@@ -95,6 +137,7 @@ type Attachment struct {
 type Reaction struct {
 	Type          int
 	SenderName    string
+	IsFromMe      bool
 	Timestamp     time.Time
 	ReactionEmoji string
 }
@@ -110,20 +153,727 @@ type Handle struct {
 	DisplayName string
 }
 
+// Chat is a conversation in chat.db, with the handles taking part and how many messages it has
+type Chat struct {
+	ID           int    `db:"ROWID"`
+	Identifier   string `db:"chat_identifier"` // Phone number, email or chatNNN for group chats
+	DisplayName  string `db:"display_name"`    // Group name, empty for one-to-one chats
+	HandleIDs    []int
+	MessageCount int
+	FirstMessage time.Time // Zero when the chat has no messages
+	LastMessage  time.Time
+}
+
+// Name labels a chat: its group name, else its participants' names, else its identifier
+func (c Chat) Name(handles map[int]Handle) string {
+	if c.DisplayName != "" {
+		return c.DisplayName
+	}
+	var names []string
+	for _, id := range c.HandleIDs {
+		if handle, exists := handles[id]; exists {
+			names = append(names, handle.DisplayName)
+		}
+	}
+	if len(names) > 0 {
+		return strings.Join(names, ", ")
+	}
+	return c.Identifier
+}
+
 // BookConfig holds configuration for book generation
 type BookConfig struct {
-	Title           string            `yaml:"title"`
-	Author          string            `yaml:"author"`
-	DatabasePath    string            `yaml:"database_path"`
-	AttachmentsPath string            `yaml:"attachments_path"`
-	OutputPath      string            `yaml:"output_path"`
-	TemplateDir     string            `yaml:"template_dir"`
-	IncludeImages   bool              `yaml:"include_images"`
+	Title           string            `yaml:"title" flag:"title"`
+	Author          string            `yaml:"author" flag:"author"`
+	DatabasePath    string            `yaml:"database_path" flag:"db"`
+	AttachmentsPath string            `yaml:"attachments_path" flag:"attachments"`
+	OutputPath      string            `yaml:"output_path" flag:"output,input"`
+	Format          string            `yaml:"format" flag:"format"` // Output plugin, such as poster; chosen from the output extension when empty
+	TemplateDir     string            `yaml:"template_dir" flag:"template-dir"`
+	IncludeImages   bool              `yaml:"include_images" flag:"include-images"`
 	IncludePreviews bool              `yaml:"include_previews"`
-	PageWidth       string            `yaml:"page_width"`
-	PageHeight      string            `yaml:"page_height"`
-	ContactNames    map[string]string `yaml:"contact_names"` // Maps contact IDs to custom display names
-	MyName          string            `yaml:"my_name"`       // Custom name for messages sent by you (default: "Me")
+	PageWidth       string            `yaml:"page_width" flag:"page-width"`
+	PageHeight      string            `yaml:"page_height" flag:"page-height"`
+	PrintPreset     string            `yaml:"print_preset" flag:"print-preset"` // kdp, ingram or lulu: add bleed and service margins
+	PrintPages      int               `yaml:"print_pages" flag:"print-pages"`   // Expected page count for the gutter (0 = estimate)
+	ContactNames    map[string]string `yaml:"contact_names"`                    // Maps contact IDs to custom display names
+	MyName          string            `yaml:"my_name"`                          // Custom name for messages and reactions sent by you (default: the account owner, else "Me")
+	Timezone        string            `yaml:"timezone" flag:"timezone"`         // Zone messages are dated in, such as America/New_York (default: the local zone)
+	Locale          string            `yaml:"locale" flag:"locale"`             // Language of headings, dates and "Me": en (default), de, es or fr
+
+	AttachmentWorkers int    `yaml:"attachment_workers" flag:"workers"` // Concurrent attachment workers (0 = one per CPU)
+	AttachmentReport  string `yaml:"attachment_report" flag:"report"`   // Write a JSON report of missing, unreadable and unsupported attachments here
+	Timings           bool   `yaml:"timings" flag:"timings"`            // Record and report per-stage wall time
+
+	ImageConverter string      `yaml:"image_converter" flag:"image-converter"` // auto, magick, sips, heif-convert or native
+	Images         ImageConfig `yaml:"images"`                                 // Resolution, size, quality and color of photos prepared for print
+
+	URLWorkers      int           `yaml:"url_workers" flag:"url-workers"` // Messages whose link previews are made at once (0 = 8)
+	URLHostInterval time.Duration `yaml:"url_host_interval"`              // Least time between downloads from one site (default: 1s)
+	URLTimeout      time.Duration `yaml:"url_timeout" flag:"url-timeout"` // Time budget for preview downloads (default: 5m)
+	URLCacheTTL     time.Duration `yaml:"url_cache_ttl"`                  // How long previews and failures are reused (default: 720h)
+	URLPolicies     URLPolicies   `yaml:"url_policies"`                   // How previews are made for links to some domains, e.g. bit.ly: skip
+
+	MemoriesPath string `yaml:"memories_path" flag:"memories"` // Folder of extra photos inserted by capture date
+
+	NotesPages NotesPagesConfig `yaml:"notes_pages"` // Blank or ruled pages for handwritten notes
+
+	Volumes VolumesConfig `yaml:"volumes"` // Split the book into several volumes, e.g. under a print service's page limit
+
+	ChapterStats bool   `yaml:"chapter_stats" flag:"chapter-stats"` // End each chapter with a small stats box
+	StatsChapter bool   `yaml:"stats_chapter" flag:"stats-chapter"` // Add a "By the Numbers" chapter at the end of the book
+	CalendarPage bool   `yaml:"calendar_page" flag:"calendar-page"` // Add a calendar heatmap of every day to the front matter
+	WordCloud    string `yaml:"word_cloud" flag:"word-cloud"`       // Divider pages with a cloud of the most used words: book, before the first chapter, or year, before each year's
+	PhotoIndex   bool   `yaml:"photo_index" flag:"photo-index"`     // Add a "List of Photos" to the back of a TeX book
+	GreatestHits int    `yaml:"greatest_hits" flag:"greatest-hits"` // List this many of the most reacted-to messages on a "Greatest Hits" page (0 = none)
+
+	PhotoLayout string `yaml:"photo_layout" flag:"photo-layout"` // inline (default), or insert: thumbnails in the text, full photos on pages at each chapter's end
+
+	BuildPDF  bool   `yaml:"build_pdf" flag:"pdf"`         // Also build the TeX output into a PDF next to it
+	PDFEngine string `yaml:"pdf_engine" flag:"pdf-engine"` // xelatex, lualatex, latexmk or tectonic (default: xelatex, else tectonic if only it is installed)
+	WorkDir   string `yaml:"work_dir" flag:"workdir"`      // Folder for each run's intermediate files (default: the system temp folder)
+
+	Dedupe       bool          `yaml:"dedupe" flag:"dedupe"`               // Collapse identical consecutive messages from one sender
+	DedupeWindow time.Duration `yaml:"dedupe_window" flag:"dedupe-window"` // Max gap between duplicates (default: 1m)
+
+	RedactProfile  string                 `yaml:"redact_profile" flag:"redact-profile"` // Redaction profile applied before rendering
+	RedactProfiles map[string]RedactRules `yaml:"redact_profiles"`                      // Custom profiles, overriding built-ins by name
+
+	Digest      bool        `yaml:"digest" flag:"digest"` // Keep only the highlights of the conversation and the messages around them
+	DigestRules DigestRules `yaml:"digest_rules"`         // How digest scores messages
+
+	ExcludeContacts []string `yaml:"exclude_contacts" flag:"exclude-contacts"` // Drop messages from these contact IDs or display names
+	ExcludeKeywords []string `yaml:"exclude_keywords" flag:"exclude-keywords"` // Drop messages containing any of these phrases
+	FilterSpam      bool     `yaml:"filter_spam" flag:"filter-spam"`           // Drop short-code senders and one-time passcodes
+	Filter          string   `yaml:"filter" flag:"filter"`                     // Keep only messages matching this query, e.g. "from:Alice has:image"
+	Preview         string   `yaml:"preview" flag:"preview"`                   // Build only N messages, or N days as "30d", to check the layout quickly
+	PreviewFrom     string   `yaml:"preview_from" flag:"preview-from"`         // Take the preview from the start (default) or end
+	OnThisDay       string   `yaml:"on_this_day" flag:"on-this-day"`           // Keep only messages sent on this date, MM-DD, in every year
+	ChatID          int      `yaml:"chat_id" flag:"chat"`                      // Only include this conversation (chat ROWID; 0 = every message)
+
+	ShowEffects bool `yaml:"show_effects" flag:"show-effects"` // Note the effect a message was sent with, e.g. "sent with Confetti 🎉"
+
+	SmartTypography bool `yaml:"smart_typography" flag:"smart-typography"` // Curl quotes, set dashes and ellipses, and space punctuation the way the book's locale does
+
+	ShowAvatars bool    `yaml:"show_avatars" flag:"show-avatars"` // Draw an avatar beside the first bubble of each run of someone else's messages
+	Avatars     Avatars `yaml:"avatars"`                          // Avatar images by contact ID or display name; others get their initials
+
+	URLFootnotes bool   `yaml:"url_footnotes" flag:"url-footnotes"` // Print URLs as footnotes in the TeX book instead of preview images
+	LinkStyle    string `yaml:"link_style" flag:"link-style"`       // How links without a preview are printed: url (default), or footnote: the site in blue with the address in a footnote, or a link in HTML
+
+	SelfContained bool `yaml:"self_contained" flag:"self-contained"` // Inline the images of an HTML book as data URIs, so it's a single file
+
+	ChapterStrategy string `yaml:"chapter_strategy" flag:"chapter-strategy"` // What each chapter covers: month (default), quarter, year, chat or none
+	HideTOC         bool   `yaml:"hide_toc" flag:"hide-toc"`                 // Leave out the table of contents
+	TOCDepth        int    `yaml:"toc_depth" flag:"toc-depth"`               // Levels in the table of contents: 1 chapters, 2 chapters and days (default: 2)
+	RectoChapters   bool   `yaml:"recto_chapters" flag:"recto-chapters"`     // Start each chapter on a right-hand page
+	DayMinLines     int    `yaml:"day_min_lines" flag:"day-min-lines"`       // Lines that must fit below a day's heading, or the day starts on the next page (default: 6)
+	GapDays         int    `yaml:"gap_days" flag:"gap-days"`                 // Mark a silence of at least this many days with a separator such as "three weeks later" (0 = never)
+
+	Summaries SummariesConfig `yaml:"summaries"` // Generated "In this chapter" summaries of each month; nothing is sent anywhere unless enabled
+
+	Dedication   string `yaml:"dedication" flag:"dedication"`  // Dedication printed on a page of its own after the copyright page
+	ForewordPath string `yaml:"foreword_path" flag:"foreword"` // Markdown (.md) or TeX (.tex) file printed as a foreword before the first chapter
+
+	SplitChapters bool `yaml:"split_chapters" flag:"split-chapters"` // Write each month chapter of a TeX book to its own file, included from the main file
+	Incremental   bool `yaml:"incremental" flag:"incremental"`       // Reuse the TeX rendered for unchanged messages by the last incremental run
+
+	Text TextConfig `yaml:"text"` // What the plain text output records of each message, and how it's split, for AI tools
+
+	OverflowLines int `yaml:"overflow_lines" flag:"overflow-lines"` // Cut messages longer than this many lines and print them in full in an appendix (0 = never)
+
+	Theme ThemeConfig `yaml:"theme"` // Fonts and message bubble style of the TeX book
+
+	Logger *slog.Logger `yaml:"-" json:"-"` // Where progress is logged, such as a server's job logger
+
+	DerivedPath string `yaml:"-" json:"-"` // Where converted photos, link previews and summaries are kept, when not in AttachmentsPath
+}
+
+// DerivedDir returns the folder files made from the attachments are kept in: DerivedPath,
+// or AttachmentsPath when it's unset
+func (c *BookConfig) DerivedDir() string {
+	if c.DerivedPath != "" {
+		return c.DerivedPath
+	}
+	return c.AttachmentsPath
+}
+
+// Log returns the logger generation progress is written to: Logger, which whoever starts
+// generating sets once, or slog's default logger when it's unset
+func (c *BookConfig) Log() *slog.Logger {
+	if c != nil && c.Logger != nil {
+		return c.Logger
+	}
+	return slog.Default()
+}
+
+// RedactRules selects what a redaction profile masks in message text
+type RedactRules struct {
+	Emails      bool     `yaml:"emails" json:"emails,omitempty"`
+	Phones      bool     `yaml:"phones" json:"phones,omitempty"`
+	CreditCards bool     `yaml:"credit_cards" json:"credit_cards,omitempty"`
+	Words       []string `yaml:"words" json:"words,omitempty"`             // Whole words or phrases, matched case-insensitively
+	Patterns    []string `yaml:"patterns" json:"patterns,omitempty"`       // Go regular expressions
+	Replacement string   `yaml:"replacement" json:"replacement,omitempty"` // Text substituted for each match (default: [redacted])
+}
+
+// DigestRules score messages for a digest. Each message scores for being long, for each
+// reaction it got and for holding a photo; those scoring MinScore or more are highlights,
+// kept with Context messages of their conversation either side. A negative score counts
+// against a message.
+type DigestRules struct {
+	LongLength    int     `yaml:"long_length"`    // Characters that make a message long (default: 280)
+	LongScore     float64 `yaml:"long_score"`     // Score of a long message (default: 1)
+	ReactionScore float64 `yaml:"reaction_score"` // Score of each reaction (default: 0.5)
+	PhotoScore    float64 `yaml:"photo_score"`    // Score of a message with a photo (default: 1)
+	MinScore      float64 `yaml:"min_score"`      // Score that makes a highlight (default: 1)
+	Context       int     `yaml:"context"`        // Messages kept before and after each highlight (default: 2; -1 for none)
+}
+
+// Digest defaults: a long message or a photo is a highlight alone, as are two reactions
+const (
+	DefaultDigestLongLength    = 280
+	DefaultDigestLongScore     = 1
+	DefaultDigestReactionScore = 0.5
+	DefaultDigestPhotoScore    = 1
+	DefaultDigestMinScore      = 1
+	DefaultDigestContext       = 2
+)
+
+// WithDefaults returns the rules with unset fields filled in
+func (r DigestRules) WithDefaults() DigestRules {
+	if r.LongLength == 0 {
+		r.LongLength = DefaultDigestLongLength
+	}
+	if r.LongScore == 0 {
+		r.LongScore = DefaultDigestLongScore
+	}
+	if r.ReactionScore == 0 {
+		r.ReactionScore = DefaultDigestReactionScore
+	}
+	if r.PhotoScore == 0 {
+		r.PhotoScore = DefaultDigestPhotoScore
+	}
+	if r.MinScore == 0 {
+		r.MinScore = DefaultDigestMinScore
+	}
+	switch {
+	case r.Context == 0:
+		r.Context = DefaultDigestContext
+	case r.Context < 0:
+		r.Context = 0
+	}
+	return r
+}
+
+// Chapter strategies: what each chapter of the book covers
+const (
+	ChaptersByMonth   = "month"   // A chapter per calendar month
+	ChaptersByQuarter = "quarter" // A chapter per three months, starting in January
+	ChaptersByYear    = "year"    // A chapter per calendar year
+	ChaptersByChat    = "chat"    // A chapter per conversation, in the order they started
+	ChaptersNone      = "none"    // No chapters; the days follow one another
+)
+
+// Photo layouts of a TeX book
+const (
+	PhotoLayoutInline = "inline" // Photos at full size where they were sent
+	PhotoLayoutInsert = "insert" // Thumbnails where they were sent; the full photos on insert pages ending each chapter
+)
+
+// Link styles: how links that aren't replaced by a preview are printed
+const (
+	LinkStyleURL      = "url"      // The address as it was sent
+	LinkStyleFootnote = "footnote" // The site in blue, with the address in a footnote in TeX and behind a link in HTML
+)
+
+// Word clouds: what each word cloud page covers
+const (
+	WordCloudBook = "book" // One cloud of the whole book, before the first chapter
+	WordCloudYear = "year" // A cloud of each year, before its first chapter
+)
+
+// MaxTOCDepth is the deepest table of contents, listing each day under its chapter
+const MaxTOCDepth = 2
+
+// DefaultDayMinLines is the room a day's heading needs below it, in lines, when
+// day_min_lines isn't set: enough for the heading and a message or two
+const DefaultDayMinLines = 6
+
+// Chapters returns the chapter strategy, month when it isn't set
+func (c *BookConfig) Chapters() string {
+	if c.ChapterStrategy == "" {
+		return ChaptersByMonth
+	}
+	return c.ChapterStrategy
+}
+
+// DaySpace returns the lines that must fit below a day's heading, DefaultDayMinLines when
+// DayMinLines isn't set
+func (c *BookConfig) DaySpace() int {
+	if c.DayMinLines == 0 {
+		return DefaultDayMinLines
+	}
+	return c.DayMinLines
+}
+
+// Location returns the time zone messages are dated in, and their days and chapters
+// divided by: Timezone, or the local zone when it isn't set
+func (c *BookConfig) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q (use a name such as America/New_York, UTC or Local)", c.Timezone)
+	}
+	return loc, nil
+}
+
+// FootnoteLinks reports whether links are printed as their site, with the address in a
+// footnote
+func (c *BookConfig) FootnoteLinks() bool {
+	return c.LinkStyle == LinkStyleFootnote
+}
+
+// PhotoInserts reports whether photos go on insert pages at the end of each chapter
+func (c *BookConfig) PhotoInserts() bool {
+	return c.PhotoLayout == PhotoLayoutInsert
+}
+
+// ValidateContents checks the chapter strategy, table of contents depth, room below day
+// headings, gaps between days, photo layout, link style, word cloud, greatest hits and
+// foreword file
+func (c *BookConfig) ValidateContents() error {
+	switch c.Chapters() {
+	case ChaptersByMonth, ChaptersByQuarter, ChaptersByYear, ChaptersByChat, ChaptersNone:
+	default:
+		return fmt.Errorf("unknown chapter strategy %q (use %s, %s, %s, %s or %s)", c.ChapterStrategy,
+			ChaptersByYear, ChaptersByQuarter, ChaptersByMonth, ChaptersByChat, ChaptersNone)
+	}
+	if c.TOCDepth < 0 || c.TOCDepth > MaxTOCDepth {
+		return fmt.Errorf("toc depth must be between 1 and %d", MaxTOCDepth)
+	}
+	if c.DayMinLines < 0 {
+		return fmt.Errorf("day min lines can't be negative")
+	}
+	if c.GapDays < 0 {
+		return fmt.Errorf("gap days can't be negative")
+	}
+	switch c.PhotoLayout {
+	case "", PhotoLayoutInline, PhotoLayoutInsert:
+	default:
+		return fmt.Errorf("unknown photo layout %q (use %s or %s)", c.PhotoLayout, PhotoLayoutInline, PhotoLayoutInsert)
+	}
+	switch c.LinkStyle {
+	case "", LinkStyleURL, LinkStyleFootnote:
+	default:
+		return fmt.Errorf("unknown link style %q (use %s or %s)", c.LinkStyle, LinkStyleURL, LinkStyleFootnote)
+	}
+	switch c.WordCloud {
+	case "", WordCloudBook, WordCloudYear:
+	default:
+		return fmt.Errorf("unknown word cloud %q (use %s or %s)", c.WordCloud, WordCloudBook, WordCloudYear)
+	}
+	if c.GreatestHits < 0 {
+		return fmt.Errorf("greatest hits cannot be negative")
+	}
+	if c.ForewordPath != "" {
+		if !IsForewordFile(c.ForewordPath) {
+			return fmt.Errorf("foreword %s must be a markdown (.md) or TeX (.tex) file", c.ForewordPath)
+		}
+		if _, err := os.Stat(c.ForewordPath); err != nil {
+			return fmt.Errorf("foreword not found: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsForewordFile reports whether a file is markdown or TeX, the formats a foreword can be in
+func IsForewordFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".tex":
+		return true
+	}
+	return false
+}
+
+// Positions where notes pages can be inserted
+const (
+	NotesAtEnd  = "end"  // After the last chapter
+	NotesAtYear = "year" // After the last chapter of each year
+)
+
+// Styles for notes pages
+const (
+	NotesStyleBlank = "blank"
+	NotesStyleRuled = "ruled"
+)
+
+// NotesPagesConfig inserts pages for handwritten notes, such as a guest book, in the printed copy
+type NotesPagesConfig struct {
+	Count     int      `yaml:"count" json:"count,omitempty" flag:"notes-pages"`      // Pages at each position (0 disables)
+	Style     string   `yaml:"style" json:"style,omitempty" flag:"notes-style"`      // blank or ruled (default: ruled)
+	Title     string   `yaml:"title" json:"title,omitempty"`                         // Optional heading on the first page, e.g. "Guest Book"
+	Positions []string `yaml:"positions" json:"positions,omitempty" flag:"notes-at"` // end and/or year (default: end)
+}
+
+// At reports whether notes pages should be inserted at the given position
+func (n NotesPagesConfig) At(position string) bool {
+	if n.Count <= 0 {
+		return false
+	}
+	if len(n.Positions) == 0 {
+		return position == NotesAtEnd
+	}
+	for _, p := range n.Positions {
+		if p == position {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the style and positions are ones the book generator understands
+func (n NotesPagesConfig) Validate() error {
+	if n.Count < 0 {
+		return fmt.Errorf("notes pages count cannot be negative")
+	}
+	if n.Style != "" && n.Style != NotesStyleBlank && n.Style != NotesStyleRuled {
+		return fmt.Errorf("unknown notes page style %q (use %s or %s)", n.Style, NotesStyleBlank, NotesStyleRuled)
+	}
+	for _, p := range n.Positions {
+		if p != NotesAtEnd && p != NotesAtYear {
+			return fmt.Errorf("unknown notes page position %q (use %s or %s)", p, NotesAtEnd, NotesAtYear)
+		}
+	}
+	return nil
+}
+
+// Ways to split a book into volumes besides a page budget
+const (
+	SplitByYear = "year" // A volume per year, or more when a year is over the page budget
+)
+
+// Page numbering across volumes
+const (
+	NumberingRestart  = "restart"  // Every volume starts at page 1
+	NumberingContinue = "continue" // Each volume carries on from the last page of the one before
+)
+
+// Image defaults for photos prepared for print
+const (
+	DefaultTargetDPI    = 300  // What print services ask for
+	DefaultMaxDimension = 1200 // Pixels on the longest side, enough for 300 DPI at the printed size
+	DefaultJPEGQuality  = 85
+)
+
+// ImageConfig controls how photos are converted for the book
+type ImageConfig struct {
+	TargetDPI    int  `yaml:"target_dpi" flag:"target-dpi"`        // Photos printing below this resolution are warned about (default: 300)
+	MaxDimension int  `yaml:"max_dimension" flag:"max-image-size"` // Longest side of converted photos in pixels (default: 1200)
+	JPEGQuality  int  `yaml:"jpeg_quality" flag:"jpeg-quality"`    // JPEG quality of converted photos, 1-100 (default: 85)
+	Grayscale    bool `yaml:"grayscale" flag:"grayscale"`          // Convert photos to grayscale for black-and-white printing
+}
+
+// WithDefaults fills in the settings left unset
+func (i ImageConfig) WithDefaults() ImageConfig {
+	if i.TargetDPI == 0 {
+		i.TargetDPI = DefaultTargetDPI
+	}
+	if i.MaxDimension == 0 {
+		i.MaxDimension = DefaultMaxDimension
+	}
+	if i.JPEGQuality == 0 {
+		i.JPEGQuality = DefaultJPEGQuality
+	}
+	return i
+}
+
+// Validate checks the settings are in range
+func (i ImageConfig) Validate() error {
+	if i.TargetDPI < 0 {
+		return fmt.Errorf("target DPI cannot be negative")
+	}
+	if i.MaxDimension < 0 {
+		return fmt.Errorf("max image dimension cannot be negative")
+	}
+	if i.JPEGQuality < 0 || i.JPEGQuality > 100 {
+		return fmt.Errorf("JPEG quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// SummariesConfig opts in to a short "In this chapter" summary at the start of each month
+// chapter, written by a model behind an OpenAI-compatible chat completions endpoint. Each
+// month's messages are sent to the endpoint, so nothing leaves the computer unless Enabled
+// is set, and an endpoint on the same machine keeps them there. The API key is read from an
+// environment variable so it's never written to a config file.
+type SummariesConfig struct {
+	Enabled   bool          `yaml:"enabled" flag:"summaries"` // Send each month's messages to Endpoint for a summary
+	Endpoint  string        `yaml:"endpoint"`                 // Base URL of the API, e.g. http://localhost:11434/v1 or https://api.openai.com/v1
+	Model     string        `yaml:"model"`                    // Model the endpoint serves, e.g. llama3.1
+	APIKeyEnv string        `yaml:"api_key_env"`              // Environment variable holding the API key, if the endpoint needs one (default: OPENAI_API_KEY)
+	Prompt    string        `yaml:"prompt"`                   // Instructions sent before each month's messages (default: the book's locale's)
+	MaxChars  int           `yaml:"max_chars"`                // Characters of each month's messages sent; later ones are left out (default: 24000)
+	Timeout   time.Duration `yaml:"timeout"`                  // Time allowed for each summary (default: 2m)
+}
+
+// Summary defaults
+const (
+	DefaultSummariesAPIKeyEnv = "OPENAI_API_KEY"
+	DefaultSummariesMaxChars  = 24000
+	DefaultSummariesTimeout   = 2 * time.Minute
+)
+
+// WithDefaults fills in the settings left unset
+func (s SummariesConfig) WithDefaults() SummariesConfig {
+	if s.APIKeyEnv == "" {
+		s.APIKeyEnv = DefaultSummariesAPIKeyEnv
+	}
+	if s.MaxChars == 0 {
+		s.MaxChars = DefaultSummariesMaxChars
+	}
+	if s.Timeout == 0 {
+		s.Timeout = DefaultSummariesTimeout
+	}
+	return s
+}
+
+// Validate checks an enabled integration has an endpoint and model to ask
+func (s SummariesConfig) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	endpoint, err := url.Parse(s.Endpoint)
+	if s.Endpoint == "" || err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("summaries need an http or https endpoint, got %q", s.Endpoint)
+	}
+	if s.Model == "" {
+		return fmt.Errorf("summaries need a model")
+	}
+	if s.MaxChars < 0 {
+		return fmt.Errorf("summary max chars cannot be negative")
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("summary timeout cannot be negative")
+	}
+	return nil
+}
+
+// TextConfig shapes the plain text output, which is meant for feeding to AI tools: what it
+// records of each message, whether it's written as text or JSON Lines, and whether it's
+// split into files small enough for a model's context
+type TextConfig struct {
+	Format         string `yaml:"format" flag:"text-format"`                   // plain (default), or jsonl: a JSON object per message
+	GUIDs          bool   `yaml:"guids" flag:"text-guids"`                     // Record each message's GUID
+	ISOTimestamps  bool   `yaml:"iso_timestamps" flag:"text-iso-timestamps"`   // Time messages with full ISO 8601 dates rather than the locale's time of day; always so in jsonl
+	SystemMessages bool   `yaml:"system_messages" flag:"text-system-messages"` // Mark events such as a renamed conversation rather than leave them out
+	ChunkTokens    int    `yaml:"chunk_tokens" flag:"text-chunk-tokens"`       // Also split the output into files of about this many tokens each (0 = one file)
+}
+
+// Plain text output formats
+const (
+	TextFormatPlain = "plain"
+	TextFormatJSONL = "jsonl"
+)
+
+// JSONL reports whether messages are written as JSON Lines
+func (t TextConfig) JSONL() bool {
+	return t.Format == TextFormatJSONL
+}
+
+// Validate checks the format is one the text output knows
+func (t TextConfig) Validate() error {
+	switch t.Format {
+	case "", TextFormatPlain, TextFormatJSONL:
+	default:
+		return fmt.Errorf("unknown text format %q (use %s or %s)", t.Format, TextFormatPlain, TextFormatJSONL)
+	}
+	if t.ChunkTokens < 0 {
+		return fmt.Errorf("text chunk tokens cannot be negative")
+	}
+	return nil
+}
+
+// VolumesConfig splits a long conversation into several books, each with its own title and
+// copyright pages. Volumes always break between month chapters.
+type VolumesConfig struct {
+	MaxPages  int    `yaml:"max_pages" flag:"max-pages"`      // Start a new volume before one would pass this many pages (0 = no limit)
+	SplitBy   string `yaml:"split_by" flag:"split-by"`        // year to start a new volume every year
+	Numbering string `yaml:"numbering" flag:"page-numbering"` // restart or continue (default: restart)
+}
+
+// Enabled reports whether the book should be split at all
+func (v VolumesConfig) Enabled() bool {
+	return v.MaxPages > 0 || v.SplitBy != ""
+}
+
+// Validate checks the split and numbering are ones the book builder understands
+func (v VolumesConfig) Validate() error {
+	if v.MaxPages < 0 {
+		return fmt.Errorf("max pages cannot be negative")
+	}
+	if v.SplitBy != "" && v.SplitBy != SplitByYear {
+		return fmt.Errorf("unknown volume split %q (use %s)", v.SplitBy, SplitByYear)
+	}
+	if v.Numbering != "" && v.Numbering != NumberingRestart && v.Numbering != NumberingContinue {
+		return fmt.Errorf("unknown page numbering %q (use %s or %s)", v.Numbering, NumberingRestart, NumberingContinue)
+	}
+	return nil
+}
+
+// How link previews are made, chosen per domain
+const (
+	URLPolicyAuto       = "auto"       // iMessage's preview data, else its image or a domain card
+	URLPolicySkip       = "skip"       // No preview; the link stays in the text as it is
+	URLPolicyScreenshot = "screenshot" // A screenshot of the page
+	URLPolicyText       = "text"       // The title, description and domain without an image
+)
+
+// URLPolicies maps domains to the way previews are made for links to them. A domain
+// covers its subdomains, and the longest match wins.
+type URLPolicies map[string]string
+
+// For returns the policy for a link, URLPolicyAuto when no domain matches
+func (p URLPolicies) For(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || len(p) == 0 {
+		return URLPolicyAuto
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+
+	policy, matched := URLPolicyAuto, ""
+	for domain, domainPolicy := range p {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > len(matched) {
+			policy, matched = domainPolicy, domain
+		}
+	}
+	return policy
+}
+
+// Validate checks every policy is one the URL processor understands
+func (p URLPolicies) Validate() error {
+	for domain, policy := range p {
+		switch policy {
+		case URLPolicyAuto, URLPolicySkip, URLPolicyScreenshot, URLPolicyText:
+		default:
+			return fmt.Errorf("unknown link preview policy %q for %s (use %s, %s, %s or %s)",
+				policy, domain, URLPolicyAuto, URLPolicySkip, URLPolicyScreenshot, URLPolicyText)
+		}
+	}
+	return nil
+}
+
+// Avatars maps contact IDs or display names to avatar image files
+type Avatars map[string]string
+
+// For returns the avatar image of a contact, by ID first and then by display name, or ""
+// if they don't have one
+func (a Avatars) For(contact, name string) string {
+	if path := a[contact]; path != "" && contact != "" {
+		return path
+	}
+	return a[name]
+}
+
+// Validate checks every avatar image exists
+func (a Avatars) Validate() error {
+	for contact, path := range a {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("avatar for %s not found: %w", contact, err)
+		}
+	}
+	return nil
+}
+
+// Theme defaults, matching the look of the built-in templates
+const (
+	DefaultFont           = "Arial"
+	DefaultEmojiFont      = "Symbola"
+	DefaultCJKFont        = "Noto Sans CJK SC"
+	DefaultArabicFont     = "Noto Naskh Arabic"
+	DefaultHebrewFont     = "Noto Sans Hebrew"
+	DefaultSentColor      = "#CCCCFF"
+	DefaultReceivedColor  = "#CCCCCC"
+	DefaultCornerRadius   = "4pt"
+	DefaultBubbleMaxWidth = 0.7
+)
+
+// MaxBubbleWidth leaves room beside a bubble for its reactions and the sender's avatar
+const MaxBubbleWidth = 0.8
+
+var (
+	hexColorRegex  = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+	texLengthRegex = regexp.MustCompile(`^\d+(\.\d+)?(pt|bp|mm|cm|in|em|ex)$`)
+)
+
+// ThemeConfig sets the fonts and message bubble style of the printed book. Empty fields
+// keep the defaults.
+type ThemeConfig struct {
+	Font           string  `yaml:"font" json:"font,omitempty"`                         // Main text font (default: Arial)
+	EmojiFont      string  `yaml:"emoji_font" json:"emoji_font,omitempty"`             // Font used for emoji (default: Symbola)
+	CJKFont        string  `yaml:"cjk_font" json:"cjk_font,omitempty"`                 // Font for Chinese, Japanese and Korean, when a book has any (default: Noto Sans CJK SC)
+	ArabicFont     string  `yaml:"arabic_font" json:"arabic_font,omitempty"`           // Font for Arabic script, when a book has any (default: Noto Naskh Arabic)
+	HebrewFont     string  `yaml:"hebrew_font" json:"hebrew_font,omitempty"`           // Font for Hebrew, when a book has any (default: Noto Sans Hebrew)
+	SentColor      string  `yaml:"sent_color" json:"sent_color,omitempty"`             // Fill of your bubbles as #RRGGBB (default: #CCCCFF)
+	ReceivedColor  string  `yaml:"received_color" json:"received_color,omitempty"`     // Fill of received bubbles as #RRGGBB (default: #CCCCCC)
+	CornerRadius   string  `yaml:"corner_radius" json:"corner_radius,omitempty"`       // Bubble corner radius as a TeX length (default: 4pt)
+	BubbleMaxWidth float64 `yaml:"bubble_max_width" json:"bubble_max_width,omitempty"` // Width text wraps at, as a fraction of the text width (default: 0.7)
+}
+
+// WithDefaults returns the theme with empty fields filled in
+func (t ThemeConfig) WithDefaults() ThemeConfig {
+	if t.Font == "" {
+		t.Font = DefaultFont
+	}
+	if t.EmojiFont == "" {
+		t.EmojiFont = DefaultEmojiFont
+	}
+	if t.CJKFont == "" {
+		t.CJKFont = DefaultCJKFont
+	}
+	if t.ArabicFont == "" {
+		t.ArabicFont = DefaultArabicFont
+	}
+	if t.HebrewFont == "" {
+		t.HebrewFont = DefaultHebrewFont
+	}
+	if t.SentColor == "" {
+		t.SentColor = DefaultSentColor
+	}
+	if t.ReceivedColor == "" {
+		t.ReceivedColor = DefaultReceivedColor
+	}
+	if t.CornerRadius == "" {
+		t.CornerRadius = DefaultCornerRadius
+	}
+	if t.BubbleMaxWidth == 0 {
+		t.BubbleMaxWidth = DefaultBubbleMaxWidth
+	}
+	return t
+}
+
+// Validate checks the theme values can be written into the TeX preamble as-is
+func (t ThemeConfig) Validate() error {
+	for _, font := range []string{t.Font, t.EmojiFont, t.CJKFont, t.ArabicFont, t.HebrewFont} {
+		if strings.ContainsAny(font, "{}\\%#$&^_~") {
+			return fmt.Errorf("font name %q cannot contain TeX special characters", font)
+		}
+	}
+	for _, color := range []string{t.SentColor, t.ReceivedColor} {
+		if color != "" && !hexColorRegex.MatchString(color) {
+			return fmt.Errorf("invalid bubble color %q (use #RRGGBB)", color)
+		}
+	}
+	if t.CornerRadius != "" && !texLengthRegex.MatchString(t.CornerRadius) {
+		return fmt.Errorf("invalid corner radius %q (use a length such as 4pt or 2mm)", t.CornerRadius)
+	}
+	if t.BubbleMaxWidth < 0 || t.BubbleMaxWidth > MaxBubbleWidth {
+		return fmt.Errorf("bubble max width must be between 0 and %.1f of the text width", MaxBubbleWidth)
+	}
+	return nil
 }
 
 // LoadConfigFromFile loads configuration from a YAML file
@@ -138,9 +888,36 @@ func LoadConfigFromFile(configPath string) (*BookConfig, error) {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
 
+	config.NormalizePaths()
 	return &config, nil
 }
 
+// NormalizePaths converts configured paths to the host's conventions so a
+// config written on Windows works on macOS/Linux and vice versa
+func (c *BookConfig) NormalizePaths() {
+	c.DatabasePath = NormalizePath(c.DatabasePath)
+	c.AttachmentsPath = NormalizePath(c.AttachmentsPath)
+	c.OutputPath = NormalizePath(c.OutputPath)
+	c.TemplateDir = NormalizePath(c.TemplateDir)
+	c.MemoriesPath = NormalizePath(c.MemoriesPath)
+	c.WorkDir = NormalizePath(c.WorkDir)
+}
+
+// NormalizePath expands a leading ~ and converts either slash style to the host separator
+func NormalizePath(path string) string {
+	if path == "" {
+		return path
+	}
+
+	path = filepath.FromSlash(strings.ReplaceAll(path, "\\", "/"))
+	if path == "~" || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return filepath.Clean(path)
+}
+
 // GetDefaultConfig returns a BookConfig with default values
 func GetDefaultConfig() *BookConfig {
 	return &BookConfig{
@@ -149,7 +926,7 @@ func GetDefaultConfig() *BookConfig {
 		DatabasePath:    "chat.db",
 		AttachmentsPath: "Attachments",
 		OutputPath:      "book.md",
-		TemplateDir:     "internal/plugins/tex/templates",
+		TemplateDir:     "", // Resolved from the data directory, falling back to embedded templates
 		IncludeImages:   true,
 		IncludePreviews: true,
 		PageWidth:       "5.5in",
@@ -165,15 +942,32 @@ type BookStats struct {
 	AttachmentCount int
 	StartDate       time.Time
 	EndDate         time.Time
+
+	ExcludedByContact int // Messages dropped by exclude_contacts
+	ExcludedByKeyword int // Messages dropped by exclude_keywords
+	ExcludedAsSpam    int // Messages dropped by filter_spam
+	ExcludedByFilter  int // Messages not matching filter
 }
 
 // PDFInfo holds information about a generated PDF
 type PDFInfo struct {
-	FilePath   string
-	FileSize   int64
-	CreatedAt  time.Time
-	PageWidth  string
-	PageHeight string
+	FilePath     string
+	FileSize     int64
+	CreatedAt    time.Time
+	PageWidth    string
+	PageHeight   string
+	PageCount    int // 0 when neither the XeLaTeX log nor the PDF reported it
+	Title        string
+	Author       string
+	Producer     string    // The program that wrote the PDF, such as xdvipdfmx
+	CreationDate time.Time // From the PDF's metadata; zero when it couldn't be read
+	Fonts        []PDFFont // Sorted by name
+}
+
+// PDFFont is a font a generated PDF uses. Print services want every font embedded.
+type PDFFont struct {
+	Name     string
+	Embedded bool
 }
 
 // Message helper methods for threading
@@ -198,6 +992,24 @@ func (m *Message) GetReactionType() ReactionType {
 	return ReactionType(m.AssociatedMessageType)
 }
 
+// IsHandwritten returns true for handwritten and Digital Touch messages, whose content is a
+// drawing stored in payload_data
+func (m *Message) IsHandwritten() bool {
+	if m.BalloonBundleID == nil {
+		return false
+	}
+	return *m.BalloonBundleID == HandwritingBundleID || strings.HasPrefix(*m.BalloonBundleID, DigitalTouchBundleID)
+}
+
+// GetSendEffect returns the name and emoji of the effect the message was sent with, or an
+// empty string when it was sent without one or the effect isn't known
+func (m *Message) GetSendEffect() string {
+	if m.ExpressiveSendStyleID == nil {
+		return ""
+	}
+	return sendEffects[*m.ExpressiveSendStyleID]
+}
+
 // IsReactionMessage returns true if this message is a reaction/tapback
 func (m *Message) IsReactionMessage() bool {
 	return m.AssociatedMessageGUID != nil && m.AssociatedMessageType != 0
@@ -227,4 +1039,4 @@ func (m *Message) GetThreadDepth() int {
 	// For now, assume direct replies are depth 1
 	// Could be enhanced to calculate actual depth by traversing the chain
 	return 1
-}
\ No newline at end of file
+}
@@ -0,0 +1,59 @@
+package output
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"threadbound/internal/models"
+)
+
+// Avatar is drawn beside the first bubble of each run of a contact's messages: their image
+// from the avatars config, or their initials on a colored circle
+type Avatar struct {
+	Path     string // Image file; empty to draw the initials
+	Initials string
+	Color    string // Circle color as hex, without the "#"
+}
+
+// avatarColors are the circles initials are drawn on, picked by name so a contact keeps theirs
+var avatarColors = []string{"FF9500", "FF2D55", "AF52DE", "5856D6", "007AFF", "34C759", "30B0C7", "A2845E"}
+
+// GetAvatar returns the avatar of a message's sender, or nil for your own messages or when
+// avatars aren't shown
+func GetAvatar(msg models.Message, handles map[int]models.Handle, config *models.BookConfig) *Avatar {
+	if msg.IsFromMe || config == nil || !config.ShowAvatars {
+		return nil
+	}
+	name := GetSenderName(msg, handles, config)
+	contact := ""
+	if msg.HandleID != nil {
+		contact = handles[*msg.HandleID].Contact
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	return &Avatar{
+		Path:     config.Avatars.For(contact, name),
+		Initials: Initials(name),
+		Color:    avatarColors[hash.Sum32()%uint32(len(avatarColors))],
+	}
+}
+
+// Initials returns the first letters of a name's first and last words, e.g. "SL" for
+// "Sam Lee", or "#" for a name without letters such as a phone number
+func Initials(name string) string {
+	var words []string
+	for _, word := range strings.Fields(name) {
+		if r := []rune(word)[0]; unicode.IsLetter(r) {
+			words = append(words, string(unicode.ToUpper(r)))
+		}
+	}
+	switch len(words) {
+	case 0:
+		return "#"
+	case 1:
+		return words[0]
+	}
+	return words[0] + words[len(words)-1]
+}
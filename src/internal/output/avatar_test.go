@@ -0,0 +1,49 @@
+package output
+
+import (
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestGetAvatar(t *testing.T) {
+	handleID := 1
+	handles := map[int]models.Handle{1: {ID: 1, Contact: "+15551234567", DisplayName: "Sam Lee"}}
+	config := &models.BookConfig{ShowAvatars: true}
+	msg := models.Message{HandleID: &handleID}
+
+	avatar := GetAvatar(msg, handles, config)
+	if avatar == nil || avatar.Initials != "SL" || avatar.Path != "" || len(avatar.Color) != 6 {
+		t.Fatalf("Expected initials on a colored circle, got %+v", avatar)
+	}
+	if again := GetAvatar(msg, handles, config); again.Color != avatar.Color {
+		t.Error("Expected a contact to keep their color")
+	}
+
+	config.Avatars = models.Avatars{"+15551234567": "sam.jpg"}
+	if avatar := GetAvatar(msg, handles, config); avatar.Path != "sam.jpg" {
+		t.Errorf("Expected the configured image, got %+v", avatar)
+	}
+
+	if GetAvatar(models.Message{IsFromMe: true}, handles, config) != nil {
+		t.Error("Expected no avatar on your own messages")
+	}
+	config.ShowAvatars = false
+	if GetAvatar(msg, handles, config) != nil {
+		t.Error("Expected no avatar unless asked for")
+	}
+}
+
+func TestInitials(t *testing.T) {
+	for name, want := range map[string]string{
+		"Sam Lee":           "SL",
+		"alice":             "A",
+		"Mary Jane Watson":  "MW",
+		"+1 (555) 123-4567": "#",
+		"Renée ":            "R",
+	} {
+		if got := Initials(name); got != want {
+			t.Errorf("Initials(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -4,15 +4,17 @@ import (
 	"strings"
 	"time"
 
+	"threadbound/internal/i18n"
 	"threadbound/internal/models"
+	"threadbound/internal/typography"
 )
 
 // BasePlugin provides common functionality that plugins can embed
 type BasePlugin struct {
-	id          string
-	name        string
-	description string
-	extension   string
+	id           string
+	name         string
+	description  string
+	extension    string
 	capabilities PluginCapabilities
 }
 
@@ -118,28 +120,41 @@ func FormatTimestamp(t time.Time, format string) string {
 	}
 }
 
-// GetSenderName determines the display name for a message sender
-func GetSenderName(msg models.Message, handles map[int]models.Handle) string {
-	if msg.IsFromMe {
-		return "Me"
+// DefaultMyName labels the messages and reactions you sent in an English book when my_name
+// isn't set
+const DefaultMyName = "Me"
+
+// MyName returns the name shown on the messages and reactions you sent, "Me" in the book's
+// language unless my_name is set
+func MyName(config *models.BookConfig) string {
+	if config != nil && config.MyName != "" {
+		return config.MyName
 	}
+	return Localizer(config).T("me")
+}
 
-	if msg.HandleID != nil {
-		if handle, exists := handles[*msg.HandleID]; exists {
-			return handle.DisplayName
-		}
+// Localizer returns what translates the book's headings and dates into its locale
+func Localizer(config *models.BookConfig) *i18n.Localizer {
+	if config == nil {
+		return i18n.For(i18n.DefaultLocale)
 	}
+	return i18n.For(config.Locale)
+}
 
-	return "Unknown"
+// PolishText sets message text in the typography of the book's locale when smart_typography
+// is on, and returns it unchanged otherwise. Plugins call it before escaping.
+func PolishText(text string, config *models.BookConfig) string {
+	if config == nil || !config.SmartTypography {
+		return text
+	}
+	return typography.For(config.Locale).Polish(text)
 }
 
-// GetSenderNameWithConfig determines the display name for a message sender with config override
-func GetSenderNameWithConfig(msg models.Message, handles map[int]models.Handle, config *models.BookConfig) string {
+// GetSenderName determines the display name for a message sender, naming your own messages
+// with MyName
+func GetSenderName(msg models.Message, handles map[int]models.Handle, config *models.BookConfig) string {
 	if msg.IsFromMe {
-		if config != nil && config.MyName != "" {
-			return config.MyName
-		}
-		return "Me"
+		return MyName(config)
 	}
 
 	if msg.HandleID != nil {
@@ -151,6 +166,18 @@ func GetSenderNameWithConfig(msg models.Message, handles map[int]models.Handle,
 	return "Unknown"
 }
 
+// NameMyReactions labels the reactions you sent with MyName
+func NameMyReactions(reactions map[string][]models.Reaction, config *models.BookConfig) {
+	name := MyName(config)
+	for _, messageReactions := range reactions {
+		for i := range messageReactions {
+			if messageReactions[i].IsFromMe {
+				messageReactions[i].SenderName = name
+			}
+		}
+	}
+}
+
 // IsImageFile checks if a filename represents an image
 func IsImageFile(filename string) bool {
 	if filename == "" {
@@ -166,4 +193,4 @@ func IsImageFile(filename string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
@@ -0,0 +1,33 @@
+package output
+
+import (
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestGetSenderName(t *testing.T) {
+	handleID := 3
+	handles := map[int]models.Handle{3: {DisplayName: "Sam"}}
+	config := &models.BookConfig{MyName: "Jordan"}
+
+	if got := GetSenderName(models.Message{IsFromMe: true}, handles, config); got != "Jordan" {
+		t.Errorf("Expected my_name on your messages, got %q", got)
+	}
+	if got := GetSenderName(models.Message{IsFromMe: true}, handles, nil); got != DefaultMyName {
+		t.Errorf("Expected %q without my_name, got %q", DefaultMyName, got)
+	}
+	if got := GetSenderName(models.Message{HandleID: &handleID}, handles, config); got != "Sam" {
+		t.Errorf("Expected the contact's name, got %q", got)
+	}
+}
+
+func TestNameMyReactions(t *testing.T) {
+	reactions := map[string][]models.Reaction{
+		"m1": {{SenderName: "Me", IsFromMe: true}, {SenderName: "Sam"}},
+	}
+	NameMyReactions(reactions, &models.BookConfig{MyName: "Jordan"})
+	if reactions["m1"][0].SenderName != "Jordan" || reactions["m1"][1].SenderName != "Sam" {
+		t.Errorf("Expected only your reaction renamed, got %+v", reactions["m1"])
+	}
+}
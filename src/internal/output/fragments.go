@@ -0,0 +1,130 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Fragment is the output rendered for one message, with the hash of everything it was
+// rendered from
+type Fragment struct {
+	Hash string          `json:"hash"`
+	Text string          `json:"text"`
+	Data json.RawMessage `json:"data,omitempty"` // Whatever else the plugin needs to use the text again
+}
+
+// FragmentCache keeps the output rendered for each message between runs, keyed by message
+// GUID, so an incremental run only renders the messages whose inputs changed. A nil cache
+// finds nothing and stores nothing.
+type FragmentCache struct {
+	Fragments map[string]*Fragment `json:"fragments"`
+
+	path   string
+	used   map[string]bool // GUIDs looked up or stored this run; the rest are dropped on Save
+	hits   int
+	misses int
+}
+
+// FragmentCachePath returns where the fragment cache for a book is kept, beside it
+func FragmentCachePath(outputPath string) string {
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	return base + ".fragments.json"
+}
+
+// LoadFragmentCache reads the fragment cache at path, starting an empty cache if there
+// isn't one. A cache that can't be parsed is started again too, as it only saves time.
+func LoadFragmentCache(path string) (*FragmentCache, error) {
+	cache := &FragmentCache{Fragments: make(map[string]*Fragment), path: path, used: make(map[string]bool)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return cache, fmt.Errorf("failed to read fragment cache: %w", err)
+	}
+	if err := json.Unmarshal(data, cache); err != nil || cache.Fragments == nil {
+		cache.Fragments = make(map[string]*Fragment)
+	}
+	return cache, nil
+}
+
+// Lookup returns the fragment cached for a message if it was rendered from inputs with the
+// same hash
+func (c *FragmentCache) Lookup(guid, hash string) (*Fragment, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.used[guid] = true
+	fragment := c.Fragments[guid]
+	if fragment == nil || fragment.Hash != hash {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return fragment, true
+}
+
+// Store records the fragment rendered for a message
+func (c *FragmentCache) Store(guid string, fragment *Fragment) {
+	if c == nil {
+		return
+	}
+	c.used[guid] = true
+	c.Fragments[guid] = fragment
+}
+
+// Hits counts the fragments Lookup has found
+func (c *FragmentCache) Hits() int {
+	if c == nil {
+		return 0
+	}
+	return c.hits
+}
+
+// Misses counts the messages Lookup had to leave to be rendered again
+func (c *FragmentCache) Misses() int {
+	if c == nil {
+		return 0
+	}
+	return c.misses
+}
+
+// Save writes the cache, dropping fragments of messages that weren't in this run
+func (c *FragmentCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	for guid := range c.Fragments {
+		if !c.used[guid] {
+			delete(c.Fragments, guid)
+		}
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode fragment cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write fragment cache: %w", err)
+	}
+	return nil
+}
+
+// HashInputs hashes the JSON encoding of values a fragment is rendered from. Values that
+// can't be encoded hash to an empty string, which callers treat as uncacheable.
+func HashInputs(values ...interface{}) string {
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	for _, value := range values {
+		if err := encoder.Encode(value); err != nil {
+			return ""
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,56 @@
+package output
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFragmentCache(t *testing.T) {
+	if got := FragmentCachePath("out/book.tex"); got != "out/book.fragments.json" {
+		t.Errorf("Expected the cache beside the book, got %s", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "book.fragments.json")
+	cache, err := LoadFragmentCache(path)
+	if err != nil {
+		t.Fatalf("Expected an empty cache when there's no file, got %v", err)
+	}
+	hash := HashInputs("Sam", true, []string{"a"})
+	if _, found := cache.Lookup("1", hash); found {
+		t.Error("Expected nothing found in an empty cache")
+	}
+	cache.Store("1", &Fragment{Hash: hash, Text: "one"})
+	cache.Store("2", &Fragment{Hash: hash, Text: "two"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+
+	cache, err = LoadFragmentCache(path)
+	if err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+	if fragment, found := cache.Lookup("1", hash); !found || fragment.Text != "one" {
+		t.Errorf("Expected the stored fragment, got %+v, %v", fragment, found)
+	}
+	if _, found := cache.Lookup("1", HashInputs("Sam", false, []string{"a"})); found {
+		t.Error("Expected a fragment rendered from other inputs not to be found")
+	}
+	if cache.Hits() != 1 || cache.Misses() != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d and %d", cache.Hits(), cache.Misses())
+	}
+
+	// Messages not in this run are dropped
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+	cache, _ = LoadFragmentCache(path)
+	if _, kept := cache.Fragments["2"]; kept || len(cache.Fragments) != 1 {
+		t.Errorf("Expected only the fragment looked up kept, got %v", cache.Fragments)
+	}
+
+	var none *FragmentCache
+	none.Store("1", &Fragment{})
+	if _, found := none.Lookup("1", hash); found || none.Save() != nil {
+		t.Error("Expected a nil cache to do nothing")
+	}
+}
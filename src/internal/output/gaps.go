@@ -0,0 +1,39 @@
+package output
+
+import (
+	"math"
+	"time"
+
+	"threadbound/internal/i18n"
+)
+
+// DaysBetween counts the calendar days from one message to the next, in the time zone of
+// the later one, so messages either side of midnight are a day apart
+func DaysBetween(from, to time.Time) int {
+	from = from.In(to.Location())
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// GapLabel describes a silence between messages, such as "three weeks later", when at least
+// minDays calendar days passed between them; otherwise, or if minDays is 0, it's "". Gaps
+// are told in days up to two weeks, then weeks up to two months, then months up to two
+// years, and then years.
+func GapLabel(from, to time.Time, minDays int, l *i18n.Localizer) string {
+	days := DaysBetween(from, to)
+	if minDays <= 0 || days < minDays {
+		return ""
+	}
+
+	id, count := "gap_days", days
+	switch {
+	case days >= 730:
+		id, count = "gap_years", int(math.Round(float64(days)/365.25))
+	case days >= 60:
+		id, count = "gap_months", int(math.Round(float64(days)/30.44))
+	case days >= 14:
+		id, count = "gap_weeks", int(math.Round(float64(days)/7))
+	}
+	return l.Plural(id, count, "Words", l.Number(count))
+}
@@ -0,0 +1,38 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/i18n"
+)
+
+func TestGapLabel(t *testing.T) {
+	start := time.Date(2023, 3, 4, 23, 30, 0, 0, time.UTC)
+	en := i18n.For("en")
+
+	tests := []struct {
+		to       time.Time
+		minDays  int
+		expected string
+	}{
+		{start.Add(time.Hour), 1, "a day later"}, // Past midnight
+		{start.AddDate(0, 0, 3), 7, ""},
+		{start.AddDate(0, 0, 5), 0, ""},
+		{start.AddDate(0, 0, 9), 7, "nine days later"},
+		{start.AddDate(0, 0, 20), 7, "three weeks later"},
+		{start.AddDate(0, 4, 0), 7, "four months later"},
+		{start.AddDate(0, 18, 0), 7, "18 months later"},
+		{start.AddDate(3, 0, 0), 7, "three years later"},
+	}
+
+	for _, test := range tests {
+		if result := GapLabel(start, test.to, test.minDays, en); result != test.expected {
+			t.Errorf("GapLabel to %s (min %d) = %q, expected %q", test.to.Format("2006-01-02"), test.minDays, result, test.expected)
+		}
+	}
+
+	if result := GapLabel(start, start.AddDate(0, 0, 21), 7, i18n.For("fr")); result != "trois semaines plus tard" {
+		t.Errorf("Expected the gap in French, got %q", result)
+	}
+}
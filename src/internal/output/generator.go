@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"threadbound/internal/i18n"
 	"threadbound/internal/models"
 )
 
@@ -89,10 +90,11 @@ func (ctx *GenerationContext) GetTemplateData() *TemplateData {
 	return &TemplateData{
 		Title:      ctx.Config.Title,
 		Author:     ctx.Config.Author,
-		Date:       time.Now().Format("January 2, 2006"),
+		Date:       Localizer(ctx.Config).Format(time.Now(), i18n.DateFormat),
 		PageWidth:  ctx.Config.PageWidth,
 		PageHeight: ctx.Config.PageHeight,
 		Stats:      ctx.Stats,
+		Volume:     ctx.Volume,
 	}
 }
 
@@ -130,4 +132,4 @@ func ValidatePluginExists(pluginID string) error {
 		return fmt.Errorf("unknown output format '%s' and no plugins are registered", pluginID)
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,25 @@
+package output
+
+import (
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// LinkRegex finds links in message text. A match can take in punctuation from the sentence
+// the link ends; TrimLink drops it.
+var LinkRegex = regexp.MustCompile(`https?://[^\s<>"{}|\\^` + "`" + `\[\]]+`)
+
+// TrimLink drops the punctuation a link found by LinkRegex picked up from its sentence
+func TrimLink(link string) string {
+	return strings.TrimRight(link, ".,;!?)")
+}
+
+// LinkSite returns the site a link is on, such as example.com, or "" if it has none
+func LinkSite(link string) string {
+	parsed, err := neturl.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
@@ -0,0 +1,84 @@
+package output
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OverflowLineChars is about how many characters fit on a line of a message bubble at the
+// default page size. Paragraphs count as the lines they wrap to at this width.
+const OverflowLineChars = 40
+
+// Overflow is a message cut short in the conversation and printed in full in the appendix
+type Overflow struct {
+	Number int // 1-based, in book order; links the cut message and its full text
+	Sender string
+	Date   time.Time
+	Text   string // Full text
+}
+
+// TruncateLines cuts text down to its first maxLines lines, counting each paragraph as the
+// lines it wraps to, and reports whether anything was cut. Cuts fall between words; a
+// maxLines of 0 or less never cuts.
+func TruncateLines(text string, maxLines int) (string, bool) {
+	if maxLines <= 0 {
+		return text, false
+	}
+
+	var kept []string
+	lines := 0
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		ends := wordLineEnds(words)
+		paragraphLines := 1
+		if len(ends) > 0 {
+			paragraphLines = ends[len(ends)-1] + 1
+		}
+		if lines+paragraphLines <= maxLines {
+			kept = append(kept, paragraph)
+			lines += paragraphLines
+			continue
+		}
+
+		// Keep the words that end on the lines left
+		room := maxLines - lines
+		n := 0
+		for n < len(words) && ends[n] < room {
+			n++
+		}
+		if n > 0 {
+			kept = append(kept, strings.Join(words[:n], " "))
+		} else if room > 0 && len(words) > 0 {
+			// One word too long for the room left, such as a URL, is cut mid-word
+			kept = append(kept, string([]rune(words[0])[:room*OverflowLineChars]))
+		}
+		return strings.TrimRight(strings.Join(kept, "\n"), " \n"), true
+	}
+	return text, false
+}
+
+// wordLineEnds wraps words into lines of at most OverflowLineChars characters, breaking
+// between words, and returns the 0-based line each word ends on. A word too long for one
+// line runs over as many lines as it fills.
+func wordLineEnds(words []string) []int {
+	ends := make([]int, len(words))
+	line, used := 0, 0
+	for i, word := range words {
+		length := utf8.RuneCountInString(word)
+		if used > 0 && used+1+length > OverflowLineChars {
+			line++
+			used = 0
+		}
+		if used > 0 {
+			used++
+		}
+		used += length
+		for used > OverflowLineChars {
+			line++
+			used -= OverflowLineChars
+		}
+		ends[i] = line
+	}
+	return ends
+}
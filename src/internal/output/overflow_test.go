@@ -0,0 +1,38 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateLines(t *testing.T) {
+	short := "Back by six"
+	if got, cut := TruncateLines(short, 2); cut || got != short {
+		t.Errorf("Expected a short message left alone, got %q, %v", got, cut)
+	}
+
+	long := strings.Repeat("word ", 30) // 150 characters wrap to 4 lines
+	if got, cut := TruncateLines(long, 0); cut || got != long {
+		t.Error("Expected no cut with no line limit")
+	}
+	got, cut := TruncateLines(long, 2)
+	if !cut {
+		t.Fatal("Expected the long message cut")
+	}
+	if len(got) > 2*OverflowLineChars || strings.HasSuffix(got, " ") || strings.HasSuffix(got, "wor") {
+		t.Errorf("Expected two lines of whole words, got %q", got)
+	}
+
+	// Paragraphs count as at least one line each
+	got, cut = TruncateLines("one\ntwo\nthree", 2)
+	if !cut || got != "one\ntwo" {
+		t.Errorf("Expected the first two paragraphs, got %q, %v", got, cut)
+	}
+
+	// A word too long for the room left is cut mid-word
+	url := "https://example.com/" + strings.Repeat("x", 100)
+	got, cut = TruncateLines(url, 1)
+	if !cut || got != url[:OverflowLineChars] {
+		t.Errorf("Expected the first line of the URL, got %q", got)
+	}
+}
@@ -1,17 +1,21 @@
 package output
 
 import (
+	"time"
+
+	"threadbound/internal/i18n"
 	"threadbound/internal/models"
+	"threadbound/internal/timing"
 )
 
 // PluginCapabilities defines what features a plugin supports
 type PluginCapabilities struct {
-	SupportsImages     bool   // Can handle image attachments
-	SupportsAttachments bool   // Can handle non-image attachments
-	SupportsReactions  bool   // Can display message reactions
-	SupportsURLPreviews bool   // Can display URL previews
-	RequiresTemplates  bool   // Needs template files to function
-	SupportsPagination bool   // Can handle page breaks/pagination
+	SupportsImages      bool // Can handle image attachments
+	SupportsAttachments bool // Can handle non-image attachments
+	SupportsReactions   bool // Can display message reactions
+	SupportsURLPreviews bool // Can display URL previews
+	RequiresTemplates   bool // Needs template files to function
+	SupportsPagination  bool // Can handle page breaks/pagination
 }
 
 // OutputPlugin defines the interface that all output format plugins must implement
@@ -49,6 +53,35 @@ type GenerationContext struct {
 	Config        *models.BookConfig
 	URLThumbnails map[string]*URLThumbnail
 	Stats         *models.BookStats
+	Timings       *timing.Recorder  // Nil unless --timings is enabled
+	PageCount     int               // Set by plugins that typeset the book, such as PDF
+	Volume        *Volume           // Set when the book is one of several volumes
+	ChatNames     map[int]string    // Conversation names by chat ROWID, set for chapters by chat
+	Fragments     *FragmentCache    // Output rendered by the last run, set for incremental generation
+	Summaries     map[string]string // "In this chapter" summaries keyed by analytics.ChapterKeyFormat, set when summaries are enabled
+}
+
+// Volume places a book among the volumes a conversation was split into
+type Volume struct {
+	Number    int
+	Count     int
+	From, To  time.Time // Dates of the volume's first and last messages
+	FirstPage int       // Page number the volume starts at: 1, or after the previous volume's last page
+}
+
+// Range describes the months a volume covers, such as "March 2019 – June 2021"
+func (v *Volume) Range() string {
+	return v.RangeIn(i18n.For(i18n.DefaultLocale))
+}
+
+// RangeIn describes the months a volume covers with l's month names, such as
+// "mars 2019 – juin 2021"
+func (v *Volume) RangeIn(l *i18n.Localizer) string {
+	from, to := l.Format(v.From, i18n.MonthFormat), l.Format(v.To, i18n.MonthFormat)
+	if from == to {
+		return from
+	}
+	return from + " – " + to
 }
 
 // URLThumbnail represents a processed URL preview
@@ -60,6 +93,7 @@ type URLThumbnail struct {
 	ImagePath     string // Alias for ThumbnailPath (deprecated)
 	Success       bool
 	Error         string
+	Source        string // Where the preview came from: attachment, download, album, domain, screenshot or text
 }
 
 // PluginError represents an error that occurred during plugin execution
@@ -84,6 +118,7 @@ type TemplateData struct {
 	PageWidth  string
 	PageHeight string
 	Stats      *models.BookStats
+	Volume     *Volume // Set when the book is one of several volumes
 }
 
 // MessageTemplateData provides message-specific data for templating
@@ -98,4 +133,5 @@ type MessageTemplateData struct {
 	Attachments   []models.Attachment
 	HasURL        bool
 	URLPreviews   []*URLThumbnail
-}
\ No newline at end of file
+	Effect        string // e.g. "Confetti 🎉", set when effects are shown
+}
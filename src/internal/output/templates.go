@@ -2,20 +2,28 @@ package output
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
 	"text/template"
+
+	"threadbound/internal/i18n"
 )
 
 // TemplateManager handles loading and executing templates
 type TemplateManager struct {
 	templateDir    string
 	templates      map[string]*template.Template
+	sources        map[string][]byte // Text of each loaded template, for Fingerprint
 	embeddedFS     embed.FS
 	embeddedPrefix string
+	overrides      bool            // Templates in templateDir take the place of embedded ones
+	localizer      *i18n.Localizer // Translates the t, plural and date functions into the book's locale
 }
 
 // NewTemplateManager creates a new template manager
@@ -23,6 +31,7 @@ func NewTemplateManager(templateDir string) *TemplateManager {
 	return &TemplateManager{
 		templateDir: templateDir,
 		templates:   make(map[string]*template.Template),
+		sources:     make(map[string][]byte),
 	}
 }
 
@@ -31,11 +40,34 @@ func NewTemplateManagerWithEmbed(templateDir string, embeddedFS embed.FS, embedd
 	return &TemplateManager{
 		templateDir:    templateDir,
 		templates:      make(map[string]*template.Template),
+		sources:        make(map[string][]byte),
 		embeddedFS:     embeddedFS,
 		embeddedPrefix: embeddedPrefix,
 	}
 }
 
+// NewTemplateManagerWithDefaults creates a template manager whose embedded templates are
+// defaults: a template of the same name in templateDir is used instead
+func NewTemplateManagerWithDefaults(templateDir string, embeddedFS embed.FS, embeddedPrefix string) *TemplateManager {
+	tm := NewTemplateManagerWithEmbed(templateDir, embeddedFS, embeddedPrefix)
+	tm.overrides = true
+	return tm
+}
+
+// Localize makes the t, plural and date functions of the templates loaded after it write
+// in l's locale. Until it's called they write English.
+func (tm *TemplateManager) Localize(l *i18n.Localizer) {
+	tm.localizer = l
+}
+
+// Localizer returns the localizer the templates translate with
+func (tm *TemplateManager) Localizer() *i18n.Localizer {
+	if tm.localizer == nil {
+		return i18n.For(i18n.DefaultLocale)
+	}
+	return tm.localizer
+}
+
 // LoadTemplate loads and parses a template file
 func (tm *TemplateManager) LoadTemplate(filename string) (*template.Template, error) {
 	// Check if template is already loaded
@@ -43,6 +75,33 @@ func (tm *TemplateManager) LoadTemplate(filename string) (*template.Template, er
 		return tmpl, nil
 	}
 
+	content, err := tm.ReadTemplate(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse template
+	tmpl, err := template.New(filename).Funcs(tm.Localizer().Funcs()).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", filename, err)
+	}
+
+	// Cache the template
+	tm.templates[filename] = tmpl
+	tm.sources[filename] = content
+
+	return tmpl, nil
+}
+
+// ReadTemplate returns the text of a template file without parsing it, for plugins that
+// parse their templates another way, such as with html/template
+func (tm *TemplateManager) ReadTemplate(filename string) ([]byte, error) {
+	if tm.overrides && tm.templateDir != "" {
+		if content, err := ioutil.ReadFile(filepath.Join(tm.templateDir, filename)); err == nil {
+			return content, nil
+		}
+	}
+
 	var content []byte
 	var err error
 
@@ -65,16 +124,7 @@ func (tm *TemplateManager) LoadTemplate(filename string) (*template.Template, er
 		}
 	}
 
-	// Parse template
-	tmpl, err := template.New(filename).Parse(string(content))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse template %s: %w", filename, err)
-	}
-
-	// Cache the template
-	tm.templates[filename] = tmpl
-
-	return tmpl, nil
+	return content, nil
 }
 
 // ExecuteTemplate executes a template with the given data
@@ -120,8 +170,27 @@ func (tm *TemplateManager) MustExecuteTemplate(filename string, data interface{}
 	return result
 }
 
+// Fingerprint loads the named templates and hashes their text, so output rendered from
+// them can be cached until one changes
+func (tm *TemplateManager) Fingerprint(filenames []string) (string, error) {
+	if err := tm.LoadTemplates(filenames); err != nil {
+		return "", err
+	}
+	names := append([]string(nil), filenames...)
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write(tm.sources[name])
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // GetTemplate returns a loaded template by name
 func (tm *TemplateManager) GetTemplate(filename string) (*template.Template, bool) {
 	tmpl, exists := tm.templates[filename]
 	return tmpl, exists
-}
\ No newline at end of file
+}
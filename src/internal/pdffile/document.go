@@ -0,0 +1,656 @@
+package pdffile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	readChunk   = 4 << 10  // How much is read for an object at first
+	maxRead     = 64 << 20 // The most read for one object or cross-reference section
+	readMargin  = 64       // How far from the end of what was read an object must end
+	tailSize    = 1 << 10  // How much of the end of the file is searched for startxref
+	maxSections = 1 << 10  // How many cross-reference sections are followed
+	scanChunk   = 1 << 20  // How much of a damaged file is scanned at a time
+	scanOverlap = 64       // How far back the next scanned chunk starts
+)
+
+// entry is where the cross-reference table says an object is
+type entry struct {
+	kind   int   // 0 when the object is free, 1 when it's in the file and 2 in an object stream
+	offset int64 // Where it starts, for one in the file
+	stream int   // The object stream holding it, for one in an object stream
+	index  int   // Its position in that stream
+}
+
+// streamObject is an object read from an object stream
+type streamObject struct {
+	number int
+	value  object
+}
+
+// document reads a PDF's objects as they're needed
+type document struct {
+	r       io.ReaderAt
+	size    int64
+	entries map[int]entry
+	objects map[int]object         // Objects read so far, nil while one is being read
+	streams map[int][]streamObject // Object streams read so far
+	root    object
+	info    object
+}
+
+// newDocument returns a document reading size bytes through r
+func newDocument(r io.ReaderAt, size int64) *document {
+	return &document{
+		r:       r,
+		size:    size,
+		entries: make(map[int]entry),
+		objects: make(map[int]object),
+		streams: make(map[int][]streamObject),
+	}
+}
+
+// openDocument reads a PDF's cross-reference table, starting from the section startxref
+// points to and following the sections before it
+func openDocument(r io.ReaderAt, size int64) (*document, error) {
+	doc := newDocument(r, size)
+	from := size - tailSize
+	if from < 0 {
+		from = 0
+	}
+	tail, err := doc.readAt(from, tailSize)
+	if err != nil {
+		return nil, err
+	}
+	at := bytes.LastIndex(tail, []byte("startxref"))
+	if at < 0 {
+		return nil, fmt.Errorf("PDF has no startxref")
+	}
+	p := &parser{data: tail[at+len("startxref"):]}
+	p.skipSpace()
+	offset, err := strconv.ParseInt(p.token(), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("PDF has an invalid startxref")
+	}
+
+	visited := make(map[int64]bool)
+	for pending := []int64{offset}; len(pending) > 0; pending = pending[1:] {
+		offset := pending[0]
+		if visited[offset] {
+			continue
+		}
+		if visited[offset] = true; len(visited) > maxSections {
+			return nil, fmt.Errorf("PDF has too many cross-reference sections")
+		}
+
+		trailer, err := doc.readSection(offset)
+		if err != nil {
+			return nil, err
+		}
+		// The newest section, read first, says which catalog is current
+		if doc.root == nil {
+			doc.root = trailer["Root"]
+		}
+		if doc.info == nil {
+			doc.info = trailer["Info"]
+		}
+		// A hybrid file's xref stream holds the entries its table leaves out, so it's read
+		// before the older sections
+		for _, key := range []string{"XRefStm", "Prev"} {
+			if n, ok := trailer[key].(int); ok {
+				pending = append(pending, int64(n))
+			}
+		}
+	}
+	if doc.root == nil {
+		return nil, fmt.Errorf("PDF has no document catalog")
+	}
+	return doc, nil
+}
+
+// readSection reads the cross-reference section at offset, a table or an xref stream, and
+// returns its trailer dictionary
+func (doc *document) readSection(offset int64) (dict, error) {
+	var entries map[int]entry
+	var trailer dict
+	isTable := false
+	err := doc.parseAt(offset, func(p *parser) error {
+		p.skipSpace()
+		if isTable = p.token() == "xref"; !isTable {
+			return nil
+		}
+		var err error
+		entries, trailer, err = readTable(p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !isTable {
+		return doc.readXRefStream(offset)
+	}
+
+	for number, e := range entries {
+		doc.add(number, e)
+	}
+	return trailer, nil
+}
+
+// readTable reads a cross-reference table after its xref keyword, and its trailer
+func readTable(p *parser) (map[int]entry, dict, error) {
+	entries := make(map[int]entry)
+	for {
+		p.skipSpace()
+		token := p.token()
+		if token == "trailer" {
+			break
+		}
+		start, err := strconv.Atoi(token)
+		p.skipSpace()
+		count, countErr := strconv.Atoi(p.token())
+		if err != nil || countErr != nil || start < 0 || count < 0 {
+			return nil, nil, p.fail("invalid cross-reference subsection")
+		}
+
+		for i := 0; i < count; i++ {
+			p.skipSpace()
+			offset, err := strconv.ParseInt(p.token(), 10, 64)
+			p.skipSpace()
+			p.token()
+			p.skipSpace()
+			kind := p.token()
+			if err != nil || (kind != "n" && kind != "f") {
+				return nil, nil, p.fail("invalid cross-reference entry")
+			}
+			if _, exists := entries[start+i]; exists {
+				continue
+			}
+			if kind == "n" {
+				entries[start+i] = entry{kind: 1, offset: offset}
+			} else {
+				entries[start+i] = entry{}
+			}
+		}
+	}
+
+	value, err := p.value()
+	if err != nil {
+		return nil, nil, err
+	}
+	trailer, ok := value.(dict)
+	if !ok {
+		return nil, nil, p.fail("invalid trailer")
+	}
+	return entries, trailer, nil
+}
+
+// readXRefStream reads the xref stream at offset, whose dictionary is its trailer
+func (doc *document) readXRefStream(offset int64) (dict, error) {
+	_, value, dataStart, err := doc.readIndirect(offset)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := value.(dict)
+	if !ok || d["Type"] != name("XRef") || dataStart < 0 {
+		return nil, fmt.Errorf("no cross-reference section at offset %d", offset)
+	}
+
+	w, _ := d["W"].(array)
+	widths := make([]int, 3)
+	for i := range widths {
+		if i < len(w) {
+			widths[i], _ = w[i].(int)
+		}
+		if widths[i] < 0 || widths[i] > 8 {
+			return nil, fmt.Errorf("invalid xref stream at offset %d", offset)
+		}
+	}
+	width := widths[0] + widths[1] + widths[2]
+	if width == 0 {
+		return nil, fmt.Errorf("invalid xref stream at offset %d", offset)
+	}
+	index, ok := d["Index"].(array)
+	if !ok {
+		index = array{0, d["Size"]}
+	}
+
+	data, err := doc.streamData(d, dataStart)
+	if err != nil {
+		return nil, err
+	}
+	// A field of no width takes its default: type 1, and 0 for the rest
+	field := func(row []byte, from, n int, fallback int64) int64 {
+		if n == 0 {
+			return fallback
+		}
+		var v int64
+		for _, b := range row[from : from+n] {
+			v = v<<8 | int64(b)
+		}
+		return v
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int)
+		count, _ := index[i+1].(int)
+		if start < 0 || count < 0 {
+			return nil, fmt.Errorf("invalid xref stream at offset %d", offset)
+		}
+		for j := 0; j < count && len(data) >= width; j++ {
+			row := data[:width]
+			data = data[width:]
+			a := field(row, widths[0], widths[1], 0)
+			b := field(row, widths[0]+widths[1], widths[2], 0)
+			switch field(row, 0, widths[0], 1) {
+			case 1:
+				doc.add(start+j, entry{kind: 1, offset: a})
+			case 2:
+				doc.add(start+j, entry{kind: 2, stream: int(a), index: int(b)})
+			default:
+				doc.add(start+j, entry{})
+			}
+		}
+	}
+	return d, nil
+}
+
+// add records where an object is, unless a newer section already said
+func (doc *document) add(number int, e entry) {
+	if _, exists := doc.entries[number]; !exists && number >= 0 {
+		doc.entries[number] = e
+	}
+}
+
+// scanPattern matches the start of an indirect object or a trailer
+var scanPattern = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b|trailer\s*<<`)
+
+// scanDocument rebuilds the cross-reference table of a damaged PDF by scanning it for its
+// objects, a chunk at a time
+func scanDocument(r io.ReaderAt, size int64) (*document, error) {
+	doc := newDocument(r, size)
+	var trailers []int64
+	for pos := int64(0); pos < size; {
+		chunk, err := doc.readAt(pos, scanChunk)
+		if err != nil {
+			return nil, err
+		}
+		last := pos+int64(len(chunk)) >= size
+		loc := scanPattern.FindSubmatchIndex(chunk)
+
+		// A match close to the end of the chunk may be cut short, so it's looked for again
+		// in the next, which starts before any number the chunk ends inside
+		if loc == nil || (!last && len(chunk)-loc[1] < scanOverlap && loc[0] > 0) {
+			if last {
+				break
+			}
+			next := len(chunk) - scanOverlap
+			if loc != nil && loc[0] < next {
+				next = loc[0]
+			}
+			for next > 1 && chunk[next-1] >= '0' && chunk[next-1] <= '9' {
+				next--
+			}
+			pos += int64(next)
+			continue
+		}
+
+		at := pos + int64(loc[0])
+		pos += int64(loc[1])
+		if loc[2] < 0 {
+			trailers = append(trailers, at)
+			continue
+		}
+		number, value, dataStart, err := doc.readIndirect(at)
+		if err != nil {
+			continue
+		}
+		// Later objects replace earlier ones, as an update appended to the file would
+		doc.entries[number] = entry{kind: 1, offset: at}
+		doc.objects[number] = value
+		// Skip the stream's data when its length is known, so nothing in it is taken for
+		// an object
+		d, _ := value.(dict)
+		if length, ok := d["Length"].(int); ok && dataStart >= 0 && length >= 0 && dataStart+int64(length) <= size {
+			pos = dataStart + int64(length)
+		}
+	}
+
+	// Objects in object streams are found by reading the streams, unless the file holds
+	// them uncompressed too
+	numbers := make([]int, 0, len(doc.entries))
+	for number := range doc.entries {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool {
+		return doc.entries[numbers[i]].offset < doc.entries[numbers[j]].offset
+	})
+	for _, number := range numbers {
+		if doc.dict(doc.objects[number])["Type"] != name("ObjStm") {
+			continue
+		}
+		for i, object := range doc.objectStream(number) {
+			doc.add(object.number, entry{kind: 2, stream: number, index: i})
+		}
+	}
+
+	// The newest trailer or xref stream that names a catalog says which is current, and
+	// failing those any catalog will do
+	for i := len(trailers) - 1; i >= 0 && doc.root == nil; i-- {
+		var trailer object
+		err := doc.parseAt(trailers[i], func(p *parser) error {
+			p.skipSpace()
+			p.token()
+			var err error
+			trailer, err = p.value()
+			return err
+		})
+		if d, ok := trailer.(dict); err == nil && ok {
+			doc.root, doc.info = d["Root"], d["Info"]
+		}
+	}
+	for i := len(numbers) - 1; i >= 0 && doc.root == nil; i-- {
+		if d := doc.dict(doc.objects[numbers[i]]); d["Type"] == name("XRef") {
+			doc.root, doc.info = d["Root"], d["Info"]
+		}
+	}
+	for i := len(numbers) - 1; i >= 0 && doc.root == nil; i-- {
+		if doc.dict(reference(numbers[i]))["Type"] == name("Catalog") {
+			doc.root = reference(numbers[i])
+		}
+	}
+	if doc.root == nil {
+		return nil, fmt.Errorf("PDF has no document catalog")
+	}
+	return doc, nil
+}
+
+// object returns the object numbered n, or nil when there isn't one
+func (doc *document) object(n int) object {
+	if value, read := doc.objects[n]; read {
+		return value
+	}
+	// Objects that refer to themselves while they're read come out as nil
+	doc.objects[n] = nil
+
+	var value object
+	switch e := doc.entries[n]; e.kind {
+	case 1:
+		number, v, _, err := doc.readIndirect(e.offset)
+		if err == nil && number == n {
+			value = v
+		}
+	case 2:
+		objects := doc.objectStream(e.stream)
+		if e.index < len(objects) && objects[e.index].number == n {
+			value = objects[e.index].value
+		} else {
+			for _, object := range objects {
+				if object.number == n {
+					value = object.value
+				}
+			}
+		}
+	}
+	doc.objects[n] = value
+	return value
+}
+
+// objectStream returns the objects in the object stream numbered n, or none when it can't
+// be read
+func (doc *document) objectStream(n int) []streamObject {
+	if objects, read := doc.streams[n]; read {
+		return objects
+	}
+	doc.streams[n] = nil
+	objects, err := doc.readObjectStream(n)
+	if err != nil {
+		return nil
+	}
+	doc.streams[n] = objects
+	return objects
+}
+
+// readObjectStream reads the objects in the object stream numbered n
+func (doc *document) readObjectStream(n int) ([]streamObject, error) {
+	e := doc.entries[n]
+	if e.kind != 1 {
+		return nil, fmt.Errorf("object stream %d isn't in the file", n)
+	}
+	number, value, dataStart, err := doc.readIndirect(e.offset)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := value.(dict)
+	if number != n || !ok || d["Type"] != name("ObjStm") || dataStart < 0 {
+		return nil, fmt.Errorf("object %d isn't an object stream", n)
+	}
+	data, err := doc.streamData(d, dataStart)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stream starts with the number and offset of each object, and the offsets count
+	// from First
+	count := doc.integer(d["N"])
+	first := doc.integer(d["First"])
+	if count < 0 || first < 0 || first > len(data) || count > first {
+		return nil, fmt.Errorf("invalid object stream %d", n)
+	}
+	header := &parser{data: data[:first]}
+	objects := make([]streamObject, 0, count)
+	for i := 0; i < count; i++ {
+		numberValue, err := header.value()
+		if err != nil {
+			return nil, err
+		}
+		offsetValue, err := header.value()
+		if err != nil {
+			return nil, err
+		}
+		number, isNumber := numberValue.(int)
+		at, isOffset := offsetValue.(int)
+		if !isNumber || !isOffset || at < 0 || first+at >= len(data) {
+			return nil, fmt.Errorf("invalid object stream %d", n)
+		}
+
+		p := &parser{data: data, pos: first + at}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, streamObject{number: number, value: value})
+	}
+	return objects, nil
+}
+
+// readIndirect reads the indirect object at offset, returning its number, its value and,
+// for a stream, where the stream's data starts, or -1
+func (doc *document) readIndirect(offset int64) (int, object, int64, error) {
+	var number int
+	var value object
+	var dataStart int64
+	err := doc.parseAt(offset, func(p *parser) error {
+		dataStart = -1
+		var err error
+		if number, err = p.indirect(); err != nil {
+			return err
+		}
+		if value, err = p.value(); err != nil {
+			return err
+		}
+		if start, ok := p.streamStart(value); ok {
+			dataStart = offset + int64(start)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, -1, err
+	}
+	return number, value, dataStart, nil
+}
+
+// parseAt runs parse over the file from offset, reading more of the file while it runs out
+// of data or ends too close to the end of what was read to be sure it's complete
+func (doc *document) parseAt(offset int64, parse func(p *parser) error) error {
+	if offset < 0 || offset >= doc.size {
+		return fmt.Errorf("offset %d is outside the PDF", offset)
+	}
+	for n := int64(readChunk); ; n *= 4 {
+		data, err := doc.readAt(offset, n)
+		if err != nil {
+			return err
+		}
+		p := &parser{data: data}
+		err = parse(p)
+
+		complete := offset+int64(len(data)) >= doc.size
+		if err == errEnd && complete {
+			return fmt.Errorf("PDF ends inside the object at offset %d", offset)
+		}
+		if complete || (err != nil && err != errEnd) || (err == nil && len(data)-p.pos >= readMargin) {
+			return err
+		}
+		if n >= maxRead {
+			return fmt.Errorf("object at offset %d is too large", offset)
+		}
+	}
+}
+
+// readAt reads up to n bytes from offset, fewer when the file ends first
+func (doc *document) readAt(offset, n int64) ([]byte, error) {
+	if offset+n > doc.size {
+		n = doc.size - offset
+	}
+	data := make([]byte, n)
+	if read, err := doc.r.ReadAt(data, offset); err != nil && !(err == io.EOF && int64(read) == n) {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return data, nil
+}
+
+// streamData reads and decodes the data of the stream d, which starts at dataStart
+func (doc *document) streamData(d dict, dataStart int64) ([]byte, error) {
+	length := int64(doc.integer(d["Length"]))
+	if length < 0 || length > maxRead || dataStart+length > doc.size {
+		return nil, fmt.Errorf("invalid stream length at offset %d", dataStart)
+	}
+	data, err := doc.readAt(dataStart, length)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := doc.resolve(d["Filter"])
+	params := doc.dict(d["DecodeParms"])
+	if filters, ok := filter.(array); ok {
+		if len(filters) > 1 {
+			return nil, fmt.Errorf("unsupported stream filters at offset %d", dataStart)
+		}
+		filter = nil
+		if len(filters) == 1 {
+			filter = doc.resolve(filters[0])
+		}
+		if all, ok := doc.resolve(d["DecodeParms"]).(array); ok && len(all) == 1 {
+			params = doc.dict(all[0])
+		}
+	}
+
+	switch filter {
+	case nil:
+		return data, nil
+	case name("FlateDecode"):
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid stream at offset %d: %w", dataStart, err)
+		}
+		decoded, err := io.ReadAll(io.LimitReader(reader, maxRead+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid stream at offset %d: %w", dataStart, err)
+		}
+		if len(decoded) > maxRead {
+			return nil, fmt.Errorf("stream at offset %d is too large", dataStart)
+		}
+		return doc.unpredict(decoded, params)
+	default:
+		return nil, fmt.Errorf("unsupported stream filter %v at offset %d", filter, dataStart)
+	}
+}
+
+// unpredict undoes the PNG predictors that xref streams are usually compressed with
+func (doc *document) unpredict(data []byte, params dict) ([]byte, error) {
+	predictor := doc.integer(params["Predictor"])
+	if predictor <= 1 {
+		return data, nil
+	}
+	if predictor < 10 {
+		return nil, fmt.Errorf("unsupported predictor %d", predictor)
+	}
+
+	setting := func(key string, fallback int) int {
+		if n := doc.integer(params[key]); n > 0 {
+			return n
+		}
+		return fallback
+	}
+	bits := setting("Colors", 1) * setting("BitsPerComponent", 8)
+	columns := setting("Columns", 1)
+	if bits > 256 || columns > maxRead {
+		return nil, fmt.Errorf("invalid predictor parameters")
+	}
+	pixel := (bits + 7) / 8
+	rowLength := (bits*columns + 7) / 8
+
+	var result []byte
+	previous := make([]byte, rowLength)
+	for len(data) > rowLength {
+		filter := data[0]
+		row := append([]byte(nil), data[1:rowLength+1]...)
+		data = data[rowLength+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= pixel {
+				left, upLeft = row[i-pixel], previous[i-pixel]
+			}
+			up := previous[i]
+			switch filter {
+			case 0:
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("invalid PNG filter %d", filter)
+			}
+		}
+		result = append(result, row...)
+		previous = row
+	}
+	return result, nil
+}
+
+// paeth returns whichever of left, up and upLeft is closest to left + up - upLeft
+func paeth(left, up, upLeft byte) byte {
+	abs := func(n int) int {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	estimate := int(left) + int(up) - int(upLeft)
+	distanceLeft, distanceUp, distanceUpLeft := abs(estimate-int(left)), abs(estimate-int(up)), abs(estimate-int(upLeft))
+	if distanceLeft <= distanceUp && distanceLeft <= distanceUpLeft {
+		return left
+	}
+	if distanceUp <= distanceUpLeft {
+		return up
+	}
+	return upLeft
+}
@@ -0,0 +1,326 @@
+package pdffile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// The PDF objects the parser reads. Numbers are ints, or float64 when they have a
+// fraction, and booleans and null are bool and nil.
+type (
+	object    interface{}
+	dict      map[string]object
+	array     []object
+	name      string
+	pdfString string // The string's bytes, still encoded
+	reference int    // The object number of an indirect reference, "12 0 R"
+)
+
+// errEnd is returned when the data ends inside an object, so more of the file is needed
+var errEnd = errors.New("unexpected end of PDF")
+
+// maxDepth is how deeply arrays and dictionaries may nest, against files made to exhaust
+// the stack
+const maxDepth = 64
+
+// parser reads PDF objects from data
+type parser struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// isSpace reports whether c is PDF whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// isDelimiter reports whether c ends a name, number or keyword
+func isDelimiter(c byte) bool {
+	return isSpace(c) || bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace moves past whitespace and comments
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case isSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token reads a run of regular characters, such as a keyword or number
+func (p *parser) token() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// indirect reads the start of an indirect object, "12 0 obj", and returns its number
+func (p *parser) indirect() (int, error) {
+	p.skipSpace()
+	number, err := strconv.Atoi(p.token())
+	if err != nil || number < 0 {
+		return 0, p.fail("expected an object number")
+	}
+	p.skipSpace()
+	if _, err := strconv.Atoi(p.token()); err != nil {
+		return 0, p.fail("expected a generation number")
+	}
+	p.skipSpace()
+	if p.token() != "obj" {
+		return 0, p.fail("expected obj")
+	}
+	return number, nil
+}
+
+// fail returns errEnd when the data ran out, and otherwise an error saying what was wrong
+func (p *parser) fail(message string) error {
+	if p.pos >= len(p.data) {
+		return errEnd
+	}
+	return fmt.Errorf("%s at offset %d", message, p.pos)
+}
+
+// value reads the next object
+func (p *parser) value() (object, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, errEnd
+	}
+
+	switch c := p.data[p.pos]; {
+	case bytes.HasPrefix(p.data[p.pos:], []byte("<<")):
+		p.pos += 2
+		return p.nested(p.dict)
+	case c == '<':
+		p.pos++
+		return p.hexString()
+	case c == '[':
+		p.pos++
+		return p.nested(p.array)
+	case c == '(':
+		p.pos++
+		return p.literalString()
+	case c == '/':
+		p.pos++
+		return p.name(), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	}
+
+	switch keyword := p.token(); keyword {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return nil, p.fail(fmt.Sprintf("unexpected %q", keyword))
+	}
+}
+
+// nested reads an array or dictionary, no deeper than maxDepth
+func (p *parser) nested(read func() (object, error)) (object, error) {
+	if p.depth++; p.depth > maxDepth {
+		return nil, fmt.Errorf("objects nested too deeply at offset %d", p.pos)
+	}
+	defer func() { p.depth-- }()
+	return read()
+}
+
+// dict reads a dictionary after its <<
+func (p *parser) dict() (object, error) {
+	d := make(dict)
+	for {
+		p.skipSpace()
+		if bytes.HasPrefix(p.data[p.pos:], []byte(">>")) {
+			p.pos += 2
+			return d, nil
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != '/' {
+			return nil, p.fail("expected a dictionary key")
+		}
+		p.pos++
+		key := p.name()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		d[string(key)] = value
+	}
+}
+
+// array reads an array after its [
+func (p *parser) array() (object, error) {
+	var a array
+	for {
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return a, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, value)
+	}
+}
+
+// name reads a name after its /, decoding #xx escapes
+func (p *parser) name() name {
+	raw := p.token()
+	var decoded []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if b, err := strconv.ParseUint(raw[i+1:i+3], 16, 8); err == nil {
+				decoded = append(decoded, byte(b))
+				i += 2
+				continue
+			}
+		}
+		decoded = append(decoded, raw[i])
+	}
+	return name(decoded)
+}
+
+// number reads an integer or real, or a reference when an integer is followed by its
+// generation and R
+func (p *parser) number() (object, error) {
+	token := p.token()
+	n, err := strconv.Atoi(token)
+	if err != nil {
+		real, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, p.fail(fmt.Sprintf("invalid number %q", token))
+		}
+		return real, nil
+	}
+
+	start := p.pos
+	p.skipSpace()
+	if generation := p.token(); generation != "" {
+		if _, err := strconv.Atoi(generation); err == nil && n >= 0 {
+			p.skipSpace()
+			if p.token() == "R" {
+				return reference(n), nil
+			}
+		}
+	}
+	p.pos = start
+	return n, nil
+}
+
+// literalString reads a string after its (, with its escapes and balanced parentheses
+func (p *parser) literalString() (object, error) {
+	var s []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return pdfString(s), nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				return nil, errEnd
+			}
+			c = p.data[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// A backslash at the end of a line continues the string on the next
+				if c == '\r' && p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					octal := int(c - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						octal = octal*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(octal)
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return nil, errEnd
+}
+
+// hexString reads a string of hex digits after its <
+func (p *parser) hexString() (object, error) {
+	end := bytes.IndexByte(p.data[p.pos:], '>')
+	if end < 0 {
+		return nil, errEnd
+	}
+	var digits []byte
+	for _, c := range p.data[p.pos : p.pos+end] {
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	p.pos += end + 1
+	// A missing last digit is read as 0
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	s := make([]byte, len(digits)/2)
+	for i := range s {
+		b, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string at offset %d", p.pos)
+		}
+		s[i] = byte(b)
+	}
+	return pdfString(s), nil
+}
+
+// streamStart returns where the data of a stream starts, when the stream keyword follows
+// the dictionary just read
+func (p *parser) streamStart(value object) (int, bool) {
+	if _, ok := value.(dict); !ok {
+		return 0, false
+	}
+	p.skipSpace()
+	if !bytes.HasPrefix(p.data[p.pos:], []byte("stream")) {
+		return 0, false
+	}
+	start := p.pos + len("stream")
+	if bytes.HasPrefix(p.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(p.data) && (p.data[start] == '\n' || p.data[start] == '\r') {
+		start++
+	}
+	return start, true
+}
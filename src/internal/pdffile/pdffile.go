@@ -0,0 +1,276 @@
+// Package pdffile reads the page count, document information and fonts of a PDF without
+// external tools. It follows the cross-reference table at the end of the file to read only
+// the objects it needs, including those packed into compressed object streams as XeLaTeX
+// writes them, and rebuilds the table by scanning the file when it's damaged. It copes with
+// the PDFs threadbound builds but isn't a general PDF reader: encrypted files, for one,
+// aren't supported.
+package pdffile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+// Info is what a PDF says about itself
+type Info struct {
+	Pages        int
+	Title        string
+	Author       string
+	Producer     string
+	CreationDate time.Time // Zero when the PDF doesn't say
+	Fonts        []Font    // Sorted by name
+}
+
+// Font is a font a PDF uses
+type Font struct {
+	Name     string // Without the tag of a subset, such as ABCDEF+
+	Embedded bool
+}
+
+// Read reads the PDF at path, reading only the parts of the file it needs
+func Read(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return read(f, stat.Size())
+}
+
+// Parse reads a PDF's information from its bytes
+func Parse(data []byte) (*Info, error) {
+	return read(bytes.NewReader(data), int64(len(data)))
+}
+
+// read reads a PDF's information through r, which holds size bytes
+func read(r io.ReaderAt, size int64) (*Info, error) {
+	header := make([]byte, 5)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header) != "%PDF-" {
+		return nil, fmt.Errorf("not a PDF")
+	}
+
+	doc, err := openDocument(r, size)
+	if err != nil {
+		if doc, err = scanDocument(r, size); err != nil {
+			return nil, err
+		}
+	}
+
+	catalog := doc.dict(doc.root)
+	if catalog == nil {
+		return nil, fmt.Errorf("PDF has no document catalog")
+	}
+
+	pages := doc.walkPages(catalog["Pages"])
+	result := &Info{Pages: doc.integer(doc.dict(catalog["Pages"])["Count"]), Fonts: pages.fonts}
+	if result.Pages <= 0 {
+		result.Pages = pages.count
+	}
+
+	if info := doc.dict(doc.info); info != nil {
+		result.Title = doc.text(info["Title"])
+		result.Author = doc.text(info["Author"])
+		result.Producer = doc.text(info["Producer"])
+		result.CreationDate, _ = ParseDate(doc.text(info["CreationDate"]))
+	}
+	return result, nil
+}
+
+// datePattern matches a PDF date, D:YYYYMMDDHHmmSSOHH'mm', of which only the year is
+// required
+var datePattern = regexp.MustCompile(`^(?:D:)?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?(?:([Zz])|([+-])(\d{2})'?(?:(\d{2})'?)?)?`)
+
+// ParseDate parses a PDF date string. Dates without a time zone are read as UTC.
+func ParseDate(s string) (time.Time, error) {
+	match := datePattern.FindStringSubmatch(s)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("invalid PDF date %q", s)
+	}
+
+	part := func(i, fallback int) int {
+		if match[i] == "" {
+			return fallback
+		}
+		n, _ := strconv.Atoi(match[i])
+		return n
+	}
+	location := time.UTC
+	if match[8] != "" {
+		offset := part(9, 0)*3600 + part(10, 0)*60
+		if match[8] == "-" {
+			offset = -offset
+		}
+		location = time.FixedZone("", offset)
+	}
+	return time.Date(part(1, 0), time.Month(part(2, 1)), part(3, 1), part(4, 0), part(5, 0), part(6, 0), 0, location), nil
+}
+
+// maxPages is how many pages and page tree nodes are walked, against trees made to loop
+const maxPages = 1 << 20
+
+// pageTree is what walking the page tree finds
+type pageTree struct {
+	count int
+	fonts []Font
+}
+
+// walkPages counts the pages under the page tree node root and finds the fonts their
+// resources and the forms they draw use
+func (doc *document) walkPages(root object) pageTree {
+	var tree pageTree
+	visited := make(map[reference]bool)
+	embedded := make(map[string]bool)
+
+	var visitResources func(resources dict)
+	visitResources = func(resources dict) {
+		for _, value := range doc.dict(resources["Font"]) {
+			if r, ok := value.(reference); ok {
+				if visited[r] {
+					continue
+				}
+				visited[r] = true
+			}
+			if fontName, isEmbedded, ok := doc.font(doc.dict(value)); ok {
+				embedded[fontName] = embedded[fontName] || isEmbedded
+			}
+		}
+		for _, value := range doc.dict(resources["XObject"]) {
+			r, ok := value.(reference)
+			if !ok || visited[r] {
+				continue
+			}
+			visited[r] = true
+			if form := doc.dict(r); form["Subtype"] == name("Form") {
+				visitResources(doc.dict(form["Resources"]))
+			}
+		}
+	}
+
+	// Pages inherit the resources of the nodes above them
+	var visit func(node object, inherited dict, depth int)
+	visit = func(node object, inherited dict, depth int) {
+		if r, ok := node.(reference); ok {
+			if visited[r] {
+				return
+			}
+			visited[r] = true
+		}
+		d := doc.dict(node)
+		if d == nil || depth > maxDepth || len(visited) > maxPages {
+			return
+		}
+		resources := inherited
+		if own := doc.dict(d["Resources"]); own != nil {
+			resources = own
+		}
+
+		kids, isNode := doc.resolve(d["Kids"]).(array)
+		if !isNode {
+			tree.count++
+			visitResources(resources)
+			return
+		}
+		for _, kid := range kids {
+			visit(kid, resources, depth+1)
+		}
+	}
+	visit(root, nil, 0)
+
+	tree.fonts = make([]Font, 0, len(embedded))
+	for fontName, isEmbedded := range embedded {
+		tree.fonts = append(tree.fonts, Font{Name: fontName, Embedded: isEmbedded})
+	}
+	sort.Slice(tree.fonts, func(i, j int) bool { return tree.fonts[i].Name < tree.fonts[j].Name })
+	return tree
+}
+
+// font returns a font's name and whether it's embedded: when its descriptor holds a font
+// file, or it's a Type 3 font drawn by the PDF itself. Composite fonts are named by their
+// Type 0 font and embedded with their descendant.
+func (doc *document) font(font dict) (string, bool, bool) {
+	baseFont, ok := doc.resolve(font["BaseFont"]).(name)
+	if !ok {
+		return "", false, false
+	}
+
+	descriptor := doc.dict(font["FontDescriptor"])
+	if descendants, ok := doc.resolve(font["DescendantFonts"]).(array); ok && len(descendants) > 0 {
+		if descendant := doc.dict(descendants[0]); descendant != nil {
+			descriptor = doc.dict(descendant["FontDescriptor"])
+		}
+	}
+	embedded := font["Subtype"] == name("Type3")
+	for _, key := range []string{"FontFile", "FontFile2", "FontFile3"} {
+		if _, exists := descriptor[key]; exists {
+			embedded = true
+		}
+	}
+	return withoutSubsetTag(string(baseFont)), embedded, true
+}
+
+// subsetTagPattern matches the six capital letters and plus that name a font subset
+var subsetTagPattern = regexp.MustCompile(`^[A-Z]{6}\+`)
+
+// withoutSubsetTag returns a font's name without the tag of its subset
+func withoutSubsetTag(fontName string) string {
+	return subsetTagPattern.ReplaceAllString(fontName, "")
+}
+
+// resolve follows a reference to the object it refers to
+func (doc *document) resolve(value object) object {
+	// Guard against references that refer to each other
+	for i := 0; i < 8; i++ {
+		r, ok := value.(reference)
+		if !ok {
+			return value
+		}
+		value = doc.object(int(r))
+	}
+	return nil
+}
+
+// dict resolves value to a dictionary, or nil when it isn't one
+func (doc *document) dict(value object) dict {
+	d, _ := doc.resolve(value).(dict)
+	return d
+}
+
+// integer resolves value to an integer, or 0 when it isn't one
+func (doc *document) integer(value object) int {
+	n, _ := doc.resolve(value).(int)
+	return n
+}
+
+// text resolves value to a text string, decoding UTF-16 with its byte order mark and
+// otherwise reading PDFDocEncoding as Latin-1, which it matches for the printable
+// characters
+func (doc *document) text(value object) string {
+	s, ok := doc.resolve(value).(pdfString)
+	if !ok {
+		return ""
+	}
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i, b := range []byte(s) {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
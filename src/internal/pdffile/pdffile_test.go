@@ -0,0 +1,273 @@
+package pdffile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// classicObjects are the objects of a small PDF with a stream and fonts both embedded and
+// not, written as most tools other than XeLaTeX do
+const classicObjects = `%PDF-1.4
+%âãÏÓ
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>
+endobj
+3 0 obj
+<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 6 0 R /F2 7 0 R >> >> /Contents 5 0 R >>
+endobj
+4 0 obj
+<< /Type /Page /Parent 2 0 R >>
+endobj
+5 0 obj
+<< /Length 44 >>
+stream
+BT /F1 12 Tf 72 712 Td (3 0 obj Hello) Tj ET
+endstream
+endobj
+6 0 obj
+<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>
+endobj
+7 0 obj
+<< /Type /Font /Subtype /TrueType /BaseFont /ABCDEF+Lato#2DRegular /FontDescriptor 8 0 R >>
+endobj
+8 0 obj
+<< /Type /FontDescriptor /FontName /ABCDEF+Lato-Regular /FontFile2 9 0 R >>
+endobj
+9 0 obj
+<< /Length 4 >>
+stream
+font
+endstream
+endobj
+10 0 obj
+<< /Title (Sam \(& Me\)\t\351t\351) /Author (Alex) /Producer (Ghostscript 10.02) /CreationDate (D:20240309140530+05'30') >>
+endobj
+`
+
+// objectPattern matches the start of an object in a fixture
+var objectPattern = regexp.MustCompile(`(?m)^(\d+) 0 obj`)
+
+// classicPDF returns classicObjects with a cross-reference table and trailer
+func classicPDF() []byte {
+	offsets := map[int]int{}
+	for _, match := range objectPattern.FindAllStringSubmatchIndex(classicObjects, -1) {
+		number, _ := strconv.Atoi(classicObjects[match[2]:match[3]])
+		offsets[number] = match[0]
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString(classicObjects)
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for i := 1; i <= len(offsets); i++ {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offsets[i])
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R /Info 10 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return pdf.Bytes()
+}
+
+// damaged returns pdf with startxref pointing at the start of the file, so its objects can
+// only be found by scanning
+func damaged(pdf []byte) []byte {
+	return regexp.MustCompile(`startxref\n\d+`).ReplaceAll(pdf, []byte("startxref\n0"))
+}
+
+func TestParseClassic(t *testing.T) {
+	for _, pdf := range [][]byte{classicPDF(), damaged(classicPDF())} {
+		info, err := Parse(pdf)
+		if err != nil {
+			t.Fatalf("Failed to parse: %v", err)
+		}
+		if info.Pages != 2 {
+			t.Errorf("Expected 2 pages, got %d", info.Pages)
+		}
+		if info.Title != "Sam (& Me)\tété" {
+			t.Errorf("Expected the title with its escapes read, got %q", info.Title)
+		}
+		if info.Author != "Alex" || info.Producer != "Ghostscript 10.02" {
+			t.Errorf("Unexpected author %q and producer %q", info.Author, info.Producer)
+		}
+		want := time.Date(2024, 3, 9, 14, 5, 30, 0, time.FixedZone("", 5*3600+30*60))
+		if !info.CreationDate.Equal(want) {
+			t.Errorf("Expected %v, got %v", want, info.CreationDate)
+		}
+
+		fonts := []Font{{Name: "Helvetica", Embedded: false}, {Name: "Lato-Regular", Embedded: true}}
+		if fmt.Sprint(info.Fonts) != fmt.Sprint(fonts) {
+			t.Errorf("Expected fonts %v, got %v", fonts, info.Fonts)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.pdf")
+	if err := os.WriteFile(path, classicPDF(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := Read(path)
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if info.Pages != 2 {
+		t.Errorf("Expected 2 pages, got %d", info.Pages)
+	}
+}
+
+// compressedPDF builds a PDF as XeLaTeX writes them: its objects packed into a compressed
+// object stream, and a compressed xref stream with a PNG predictor in place of a table
+func compressedPDF() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R /F2 7 0 R >> >> >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /QRSTUV+LMRoman10-Regular /DescendantFonts [5 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /QRSTUV+LMRoman10-Regular /FontDescriptor 6 0 R >>",
+		"<< /Type /FontDescriptor /FontFile3 7 0 R >>",
+		"<< /Type /Font /Subtype /Type3 /BaseFont /Emoji >>",
+		"<< /Title <FEFF00530061006D0020D83DDC9B> /Producer (xdvipdfmx \\(20240305\\)) /CreationDate (D:20241015) >>",
+	}
+	var header, body bytes.Buffer
+	for i, object := range objects {
+		fmt.Fprintf(&header, "%d %d ", i+1, body.Len())
+		body.WriteString(object + "\n")
+	}
+	compress := func(data []byte) []byte {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		writer.Write(data)
+		writer.Close()
+		return compressed.Bytes()
+	}
+	stream := compress(append(header.Bytes(), body.Bytes()...))
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.5\n")
+	offsets := []int{pdf.Len()}
+	fmt.Fprintf(&pdf, "9 0 obj\n<< /Type /ObjStm /N %d /First %d /Length 10 0 R /Filter /FlateDecode >>\nstream\n", len(objects), header.Len())
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\n")
+	offsets = append(offsets, pdf.Len())
+	fmt.Fprintf(&pdf, "10 0 obj\n%d\nendobj\n", len(stream))
+	offsets = append(offsets, pdf.Len())
+
+	// Each row is a type, a 4 byte field and a 1 byte field, filtered by the difference
+	// from the row above
+	rows := [][]byte{{0, 0, 0, 0, 0, 255}}
+	for i := range objects {
+		rows = append(rows, []byte{2, 0, 0, 0, 9, byte(i)})
+	}
+	for _, offset := range offsets {
+		rows = append(rows, []byte{1, byte(offset >> 24), byte(offset >> 16), byte(offset >> 8), byte(offset), 0})
+	}
+	var xref []byte
+	previous := make([]byte, 6)
+	for _, row := range rows {
+		xref = append(xref, 2)
+		for i := range row {
+			xref = append(xref, row[i]-previous[i])
+		}
+		previous = row
+	}
+	xref = compress(xref)
+	fmt.Fprintf(&pdf, "11 0 obj\n<< /Type /XRef /Size 12 /Root 1 0 R /Info 8 0 R /W [1 4 1] /Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 6 >> /Length %d >>\nstream\n", len(xref))
+	pdf.Write(xref)
+	fmt.Fprintf(&pdf, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", offsets[2])
+	return pdf.Bytes()
+}
+
+func TestParseObjectStreams(t *testing.T) {
+	for _, pdf := range [][]byte{compressedPDF(), damaged(compressedPDF())} {
+		info, err := Parse(pdf)
+		if err != nil {
+			t.Fatalf("Failed to parse: %v", err)
+		}
+		if info.Pages != 1 {
+			t.Errorf("Expected 1 page, got %d", info.Pages)
+		}
+		if info.Title != "Sam 💛" {
+			t.Errorf("Expected the UTF-16 title, got %q", info.Title)
+		}
+		if info.Producer != "xdvipdfmx (20240305)" {
+			t.Errorf("Expected the producer, got %q", info.Producer)
+		}
+		if want := time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC); !info.CreationDate.Equal(want) {
+			t.Errorf("Expected %v, got %v", want, info.CreationDate)
+		}
+
+		fonts := []Font{{Name: "Emoji", Embedded: true}, {Name: "LMRoman10-Regular", Embedded: true}}
+		if fmt.Sprint(info.Fonts) != fmt.Sprint(fonts) {
+			t.Errorf("Expected fonts %v, got %v", fonts, info.Fonts)
+		}
+	}
+}
+
+func TestParseRejectsInvalidData(t *testing.T) {
+	inputs := []string{
+		"",
+		"not a PDF",
+		"%PDF-1.4\n1 0 obj\n<< /Type /Pages /Count 3 >>\nendobj\n",
+		strings.Replace(classicObjects, "/Type /Catalog", "/Type /Nothing", 1)[:200],
+		// An object stream whose header puts its object before the start of the stream
+		"%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 1 /First 5 /Length 9 >>\nstream\n5 -9 <<>>\nendstream\nendobj\ntrailer\n<< /Root 5 0 R >>\n",
+		// Pages that are their own kids, and a catalog nested deeper than it may be
+		"%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 1 0 R /Kids [1 0 R] >>\nendobj\ntrailer\n<< /Root 2 0 R >>\n",
+		"%PDF-1.4\n1 0 obj\n" + strings.Repeat("[", 10000) + "\nendobj\ntrailer\n<< /Root 1 0 R >>\n",
+	}
+	for _, input := range inputs {
+		if info, err := Parse([]byte(input)); err == nil {
+			t.Errorf("Expected an error for %q, got %+v", input, info)
+		}
+	}
+}
+
+func TestParseDamagedData(t *testing.T) {
+	// However a PDF is cut short or corrupted, reading it mustn't panic
+	for _, pdf := range [][]byte{classicPDF(), compressedPDF()} {
+		for i := range pdf {
+			Parse(pdf[:i])
+
+			corrupted := append([]byte(nil), pdf...)
+			corrupted[i] ^= 0xff
+			Parse(corrupted)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		date string
+		want time.Time
+	}{
+		{"D:20240309140530+05'30'", time.Date(2024, 3, 9, 8, 35, 30, 0, time.UTC)},
+		{"D:20240309140530-04'00", time.Date(2024, 3, 9, 18, 5, 30, 0, time.UTC)},
+		{"D:20240309140530Z", time.Date(2024, 3, 9, 14, 5, 30, 0, time.UTC)},
+		{"D:20240309140530Z00'00'", time.Date(2024, 3, 9, 14, 5, 30, 0, time.UTC)},
+		{"20240309", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{"D:2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.date)
+		if err != nil {
+			t.Errorf("ParseDate(%q) failed: %v", tt.date, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+
+	if _, err := ParseDate("yesterday"); err == nil {
+		t.Error("Expected an error for a date that isn't one")
+	}
+}
@@ -0,0 +1,89 @@
+package plist
+
+import "fmt"
+
+// KeyedArchive is a decoded NSKeyedArchiver plist, the format iMessage uses
+// for payload_data blobs such as rich link metadata
+type KeyedArchive struct {
+	Objects []interface{}
+	Top     map[string]interface{}
+}
+
+// DecodeKeyedArchive parses a binary plist produced by NSKeyedArchiver
+func DecodeKeyedArchive(data []byte) (*KeyedArchive, error) {
+	root, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dict, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("keyed archive root is not a dictionary")
+	}
+	objects, ok := dict["$objects"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("keyed archive has no $objects")
+	}
+	top, _ := dict["$top"].(map[string]interface{})
+
+	return &KeyedArchive{Objects: objects, Top: top}, nil
+}
+
+// Resolve follows a UID to the object it references; other values are returned unchanged
+func (a *KeyedArchive) Resolve(value interface{}) interface{} {
+	uid, ok := value.(UID)
+	if !ok {
+		return value
+	}
+	if uint64(uid) >= uint64(len(a.Objects)) {
+		return nil
+	}
+	return a.Objects[uid]
+}
+
+// ResolveString resolves a value and returns it if it is a string
+func (a *KeyedArchive) ResolveString(value interface{}) string {
+	s, _ := a.Resolve(value).(string)
+	return s
+}
+
+// FindString returns the first string stored under key in any archived object
+func (a *KeyedArchive) FindString(key string) string {
+	for _, object := range a.Objects {
+		dict, ok := object.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, exists := dict[key]; exists {
+			if s := a.ResolveString(value); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// FindInt returns the first integer stored under key in any archived object
+func (a *KeyedArchive) FindInt(key string) (int64, bool) {
+	for _, object := range a.Objects {
+		dict, ok := object.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := a.Resolve(dict[key]).(int64); ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// Strings returns every string object in archive order
+func (a *KeyedArchive) Strings() []string {
+	var strs []string
+	for _, object := range a.Objects {
+		if s, ok := object.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
@@ -0,0 +1,268 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+// UID is a reference into the $objects array of an NSKeyedArchiver archive
+type UID uint64
+
+// maxDepth bounds recursion so malformed or cyclic plists cannot exhaust the stack
+const maxDepth = 256
+
+// appleEpoch is the reference date for plist date values
+var appleEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// decoder reads objects from a binary plist (bplist00)
+type decoder struct {
+	data          []byte
+	offsets       []uint64
+	objectRefSize int
+}
+
+// Decode parses a binary plist and returns its top object. Values decode to
+// nil, bool, int64, float64, time.Time, []byte, string, UID,
+// []interface{} and map[string]interface{}.
+func Decode(data []byte) (interface{}, error) {
+	if len(data) < 40 || !bytes.HasPrefix(data, []byte("bplist00")) {
+		return nil, fmt.Errorf("not a binary plist")
+	}
+
+	trailer := data[len(data)-32:]
+	offsetIntSize := int(trailer[6])
+	objectRefSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	offsetTableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetIntSize < 1 || offsetIntSize > 8 || objectRefSize < 1 || objectRefSize > 8 {
+		return nil, fmt.Errorf("invalid plist trailer")
+	}
+	tableEnd := offsetTableOffset + numObjects*uint64(offsetIntSize)
+	if numObjects == 0 || topObject >= numObjects || offsetTableOffset >= uint64(len(data)) ||
+		tableEnd > uint64(len(data)-32) || tableEnd < offsetTableOffset {
+		return nil, fmt.Errorf("invalid plist offset table")
+	}
+
+	d := &decoder{
+		data:          data,
+		offsets:       make([]uint64, numObjects),
+		objectRefSize: objectRefSize,
+	}
+	for i := range d.offsets {
+		start := offsetTableOffset + uint64(i*offsetIntSize)
+		d.offsets[i] = readUint(data[start : start+uint64(offsetIntSize)])
+	}
+
+	return d.object(topObject, 0)
+}
+
+// object decodes the object with the given index
+func (d *decoder) object(index uint64, depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("plist nested too deeply")
+	}
+	if index >= uint64(len(d.offsets)) {
+		return nil, fmt.Errorf("object reference %d out of range", index)
+	}
+
+	offset := d.offsets[index]
+	if offset >= uint64(len(d.data)) {
+		return nil, fmt.Errorf("object offset %d out of range", offset)
+	}
+
+	marker := d.data[offset]
+	kind, info := marker>>4, marker&0x0F
+
+	switch kind {
+	case 0x0:
+		switch info {
+		case 0x8:
+			return false, nil
+		case 0x9:
+			return true, nil
+		default:
+			return nil, nil
+		}
+	case 0x1:
+		raw, err := d.slice(offset+1, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		// 16-byte integers only occur for values above int64; keep the low 8 bytes
+		if len(raw) > 8 {
+			raw = raw[len(raw)-8:]
+		}
+		// Shorter ints are unsigned; 8-byte ints are two's complement, which the cast preserves
+		return int64(readUint(raw)), nil
+	case 0x2:
+		raw, err := d.slice(offset+1, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		return readFloat(raw), nil
+	case 0x3:
+		raw, err := d.slice(offset+1, 8)
+		if err != nil {
+			return nil, err
+		}
+		seconds := readFloat(raw)
+		return appleEpoch.Add(time.Duration(seconds * float64(time.Second))), nil
+	case 0x4:
+		start, count, err := d.length(offset, info)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.slice(start, count)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), raw...), nil
+	case 0x5:
+		start, count, err := d.length(offset, info)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.slice(start, count)
+		if err != nil {
+			return nil, err
+		}
+		return string(raw), nil
+	case 0x6:
+		start, count, err := d.length(offset, info)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.slice(start, count*2)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(raw[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0x8:
+		raw, err := d.slice(offset+1, uint64(info)+1)
+		if err != nil {
+			return nil, err
+		}
+		return UID(readUint(raw)), nil
+	case 0xA, 0xC:
+		start, count, err := d.length(offset, info)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(start, count)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]interface{}, len(refs))
+		for i, ref := range refs {
+			if array[i], err = d.object(ref, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case 0xD:
+		start, count, err := d.length(offset, info)
+		if err != nil {
+			return nil, err
+		}
+		keyRefs, err := d.refs(start, count)
+		if err != nil {
+			return nil, err
+		}
+		valueRefs, err := d.refs(start+count*uint64(d.objectRefSize), count)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]interface{}, count)
+		for i := range keyRefs {
+			key, err := d.object(keyRefs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("dictionary key is not a string")
+			}
+			if dict[keyString], err = d.object(valueRefs[i], depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+
+	return nil, fmt.Errorf("unsupported plist object type 0x%x", kind)
+}
+
+// length returns where an object's payload starts and its element count,
+// following the extended int when the marker nibble is 0xF
+func (d *decoder) length(offset uint64, info byte) (uint64, uint64, error) {
+	if info != 0x0F {
+		return offset + 1, uint64(info), nil
+	}
+
+	intMarker, err := d.slice(offset+1, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if intMarker[0]>>4 != 0x1 {
+		return 0, 0, fmt.Errorf("invalid length marker")
+	}
+	size := uint64(1) << (intMarker[0] & 0x0F)
+	raw, err := d.slice(offset+2, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	return offset + 2 + size, readUint(raw), nil
+}
+
+// refs reads count object references starting at offset
+func (d *decoder) refs(offset, count uint64) ([]uint64, error) {
+	raw, err := d.slice(offset, count*uint64(d.objectRefSize))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, count)
+	for i := range refs {
+		start := i * d.objectRefSize
+		refs[i] = readUint(raw[start : start+d.objectRefSize])
+	}
+	return refs, nil
+}
+
+// slice returns size bytes at offset, bounds-checked against the object area
+func (d *decoder) slice(offset, size uint64) ([]byte, error) {
+	end := offset + size
+	if end < offset || end > uint64(len(d.data)-32) {
+		return nil, fmt.Errorf("plist object extends past end of data")
+	}
+	return d.data[offset:end], nil
+}
+
+// readUint decodes a big-endian unsigned integer of up to 8 bytes
+func readUint(raw []byte) uint64 {
+	var value uint64
+	for _, b := range raw {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// readFloat decodes a big-endian 4- or 8-byte float
+func readFloat(raw []byte) float64 {
+	switch len(raw) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(raw))
+	}
+	return 0
+}
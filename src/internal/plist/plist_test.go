@@ -0,0 +1,67 @@
+package plist
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// richLinkArchive is a minimal NSKeyedArchiver payload in the shape of an
+// iMessage rich link, generated with Python's plistlib
+const richLinkArchive = "" +
+	"62706c6973743030d401020304050612155924617263686976657258246f626a656374735424746f7058247665727369" +
+	"6f6e5f100f4e534b657965644172636869766572a507080f101155246e756c6cd3090a0b0c0d0e5f1026726963684c69" +
+	"6e6b496d6167654174746163686d656e7453756273746974757465496e6465785773756d6d617279557469746c651000" +
+	"800380026b00430061006600e9002020130020004d0065006e00755f10134265737420636f6666656520696e20746f77" +
+	"6e5f101f68747470733a2f2f6578616d706c652e636f6d2f707265766965772e6a7067d1131454726f6f748001120001" +
+	"86a008111b242932444a505780888e909294abc1e3e6ebed000000000000010100000000000000160000000000000000" +
+	"00000000000000f2"
+
+func TestDecodeKeyedArchive(t *testing.T) {
+	data, err := hex.DecodeString(richLinkArchive)
+	if err != nil {
+		t.Fatalf("Invalid fixture: %v", err)
+	}
+
+	archive, err := DecodeKeyedArchive(data)
+	if err != nil {
+		t.Fatalf("DecodeKeyedArchive failed: %v", err)
+	}
+
+	// UTF-16 strings (non-ASCII) and UID references must both resolve
+	if title := archive.FindString("title"); title != "Café – Menu" {
+		t.Errorf("Expected title 'Café – Menu', got '%s'", title)
+	}
+	if summary := archive.FindString("summary"); summary != "Best coffee in town" {
+		t.Errorf("Expected summary 'Best coffee in town', got '%s'", summary)
+	}
+	if index, ok := archive.FindInt("richLinkImageAttachmentSubstituteIndex"); !ok || index != 0 {
+		t.Errorf("Expected image index 0, got %d (found: %v)", index, ok)
+	}
+	if archive.FindString("missing") != "" {
+		t.Error("Expected empty string for missing key")
+	}
+
+	found := false
+	for _, s := range archive.Strings() {
+		if s == "https://example.com/preview.jpg" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected preview URL among archive strings")
+	}
+}
+
+func TestDecodeRejectsInvalidData(t *testing.T) {
+	inputs := [][]byte{
+		nil,
+		[]byte("not a plist at all, just some bytes padding it out"),
+		append([]byte("bplist00"), make([]byte, 40)...),
+	}
+
+	for _, input := range inputs {
+		if _, err := Decode(input); err == nil {
+			t.Errorf("Expected error decoding %q", input)
+		}
+	}
+}
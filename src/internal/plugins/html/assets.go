@@ -0,0 +1,52 @@
+package html
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// assetFunc returns the "asset" template function, which gives the src of an image. In a
+// self-contained book it's the image itself as a data URI; otherwise, or if the image
+// can't be read, it's the path, and a warning is written to log.
+func assetFunc(selfContained bool, log *slog.Logger) func(path string) interface{} {
+	uris := make(map[string]template.URL) // Avatars repeat through the book
+	return func(path string) interface{} {
+		if !selfContained || path == "" {
+			return path
+		}
+		if uri, exists := uris[path]; exists {
+			return uri
+		}
+		uri, err := dataURI(path)
+		if err != nil {
+			log.Warn("⚠️  Linking the file instead of including it", "path", path, "error", err)
+			return path
+		}
+		uris[path] = uri
+		return uri
+	}
+}
+
+// dataURI encodes an image as a data: URL, typed by its extension or else its contents
+func dataURI(path string) (template.URL, error) {
+	data, err := os.ReadFile(filepath.FromSlash(path))
+	if err != nil {
+		return "", err
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	// The URL is trusted in src attributes, so it must hold an image
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("not an image (%s)", mimeType)
+	}
+	return template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
@@ -0,0 +1,200 @@
+package html
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"threadbound/internal/analytics"
+	"threadbound/internal/i18n"
+)
+
+// Chart viewBox dimensions; the SVGs scale to the page width
+const (
+	chartWidth  = 600
+	chartHeight = 200
+	labelHeight = 16
+)
+
+// Colors match the message bubbles
+const (
+	chartBlue  = "#007AFF"
+	chartGray  = "#8e8e93"
+	chartEmpty = "#f2f2f7"
+)
+
+// volumeChart draws messages per month as an inline SVG bar chart, labelled in l's locale
+func volumeChart(months []analytics.MonthCount, l *i18n.Localizer) template.HTML {
+	max := 0
+	for _, month := range months {
+		if month.Count > max {
+			max = month.Count
+		}
+	}
+	if max == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	barWidth := float64(chartWidth) / float64(len(months))
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %d" role="img" aria-label="Messages per month">`, chartWidth, chartHeight+labelHeight)
+	fmt.Fprintf(&b, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="%s"/>`, chartHeight, chartWidth, chartHeight, chartGray)
+
+	for i, month := range months {
+		x := float64(i) * barWidth
+		if month.Count > 0 {
+			height := float64(chartHeight) * float64(month.Count) / float64(max)
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %d</title></rect>`,
+				x+barWidth*0.1, float64(chartHeight)-height, barWidth*0.8, height, chartBlue,
+				l.Format(month.Month, i18n.MonthFormat), month.Count)
+		}
+
+		// Label every month in short books, otherwise only the start of each year
+		label := ""
+		if len(months) <= 12 {
+			label = l.FormatLayout(month.Month, "Jan")
+		} else if i == 0 || month.Month.Month() == time.January {
+			label = month.Month.Format("2006")
+		}
+		if label != "" {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" font-size="10" fill="%s" text-anchor="middle">%s</text>`,
+				x+barWidth/2, chartHeight+labelHeight-4, chartGray, label)
+		}
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// weekdayOrder lists weekdays Monday first, as rows of the heatmap
+var weekdayOrder = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// hourHeatmap draws messages by weekday and hour as an inline SVG grid shaded by volume,
+// with the weekdays named in l's locale
+func hourHeatmap(grid *analytics.HourGrid, l *i18n.Localizer) template.HTML {
+	if grid == nil || grid.Max() == 0 {
+		return ""
+	}
+
+	const labelWidth = 32
+	max := grid.Max()
+	cell := float64(chartWidth-labelWidth) / 24
+	height := cell*float64(len(weekdayOrder)) + labelHeight
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %.1f" role="img" aria-label="Messages by weekday and hour">`, chartWidth, height)
+
+	for row, weekday := range weekdayOrder {
+		y := float64(row) * cell
+		fmt.Fprintf(&b, `<text x="0" y="%.1f" font-size="10" fill="%s" dominant-baseline="middle">%s</text>`,
+			y+cell/2, chartGray, l.ShortWeekdayName(weekday))
+		for hour := 0; hour < 24; hour++ {
+			count := grid[weekday][hour]
+			x := labelWidth + float64(hour)*cell
+			if count == 0 {
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="2" fill="%s"/>`, x, y, cell*0.9, cell*0.9, chartEmpty)
+				continue
+			}
+			// Keep the faintest non-empty cell visible
+			opacity := 0.15 + 0.85*float64(count)/float64(max)
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="2" fill="%s" fill-opacity="%.2f"><title>%s %d:00: %d</title></rect>`,
+				x, y, cell*0.9, cell*0.9, chartBlue, opacity, l.WeekdayName(weekday), hour, count)
+		}
+	}
+
+	for _, hour := range []int{0, 6, 12, 18} {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="10" fill="%s" text-anchor="middle">%d:00</text>`,
+			labelWidth+float64(hour)*cell+cell/2, height-4, chartGray, hour)
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// calendarChart is one year's calendar heatmap, ready for the template
+type calendarChart struct {
+	Year     int
+	Messages int
+	Chart    template.HTML
+}
+
+// calendarCharts draws a calendar heatmap for each year, with months named in l's locale
+func calendarCharts(calendars []analytics.CalendarYear, l *i18n.Localizer) []calendarChart {
+	charts := make([]calendarChart, len(calendars))
+	for i, calendar := range calendars {
+		messages := 0
+		for _, day := range calendar.Days {
+			messages += day.Count
+		}
+		charts[i] = calendarChart{Year: calendar.Year, Messages: messages, Chart: calendarHeatmap(calendar, l)}
+	}
+	return charts
+}
+
+// calendarHeatmap draws every day of a year as an inline SVG grid of weeks, Monday at the
+// top of each column, shaded by how many messages were sent that day. Months and weekdays
+// are named in l's locale.
+func calendarHeatmap(calendar analytics.CalendarYear, l *i18n.Localizer) template.HTML {
+	const labelWidth = 32
+	cell := float64(chartWidth-labelWidth) / 53
+	width := labelWidth + cell*float64(calendar.Weeks)
+	height := labelHeight + cell*float64(len(weekdayOrder))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart calendar-chart" viewBox="0 0 %.1f %.1f" role="img" aria-label="Messages per day in %d">`, width, height, calendar.Year)
+
+	for _, row := range []int{0, 2, 4} {
+		fmt.Fprintf(&b, `<text x="0" y="%.1f" font-size="10" fill="%s" dominant-baseline="middle">%s</text>`,
+			labelHeight+float64(row)*cell+cell/2, chartGray, l.ShortWeekdayName(weekdayOrder[row]))
+	}
+
+	for _, day := range calendar.Days {
+		x := labelWidth + float64(day.Week)*cell
+		y := labelHeight + float64(day.Weekday)*cell
+
+		if day.Date.Day() == 1 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" font-size="10" fill="%s">%s</text>`, x, labelHeight-4, chartGray, l.FormatLayout(day.Date, "Jan"))
+		}
+
+		if day.Count == 0 {
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="2" fill="%s"><title>%s</title></rect>`,
+				x, y, cell*0.85, cell*0.85, chartEmpty, l.Format(day.Date, i18n.DayMonth))
+			continue
+		}
+		// Keep the faintest day with messages visible
+		opacity := 0.15 + 0.85*float64(day.Count)/float64(calendar.Max)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="2" fill="%s" fill-opacity="%.2f"><title>%s: %d</title></rect>`,
+			x, y, cell*0.85, cell*0.85, chartBlue, opacity, l.Format(day.Date, i18n.DayMonth), day.Count)
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// wordCloud is a year's or the whole book's word cloud, ready for the template
+type wordCloud struct {
+	Title string
+	Chart template.HTML
+}
+
+// wordCloudChart draws a word cloud as an inline SVG, the most used words largest and
+// darkest, titled in l's locale
+func wordCloudChart(cloud analytics.WordCloud, l *i18n.Localizer) *wordCloud {
+	title := l.T("our_words")
+	if cloud.Year != 0 {
+		title = l.T("year_in_words", "Year", cloud.Year)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart word-cloud-chart" viewBox="0 0 %d %d" role="img" aria-label="%s">`,
+		analytics.WordCloudWidth, analytics.WordCloudHeight, template.HTMLEscapeString(title))
+	for _, word := range cloud.Words {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="%.1f" fill="%s" fill-opacity="%.2f" text-anchor="middle" dominant-baseline="central"><title>%d</title>%s</text>`,
+			word.X, word.Y, word.Size, chartBlue, 0.4+0.6*word.Weight, word.Count, template.HTMLEscapeString(word.Word))
+	}
+	b.WriteString(`</svg>`)
+	return &wordCloud{Title: title, Chart: template.HTML(b.String())}
+}
@@ -0,0 +1,36 @@
+package html
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"threadbound/internal/output"
+)
+
+// linksFunc returns the "links" template function, which writes message text as HTML. With
+// the footnote link style each link in the text becomes an anchor showing the site it's on;
+// otherwise the text is only escaped, as {{.Text}} would be.
+func linksFunc(footnoteLinks bool) func(text string) template.HTML {
+	return func(text string) template.HTML {
+		if !footnoteLinks {
+			return template.HTML(template.HTMLEscapeString(text))
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, match := range output.LinkRegex.FindAllStringIndex(text, -1) {
+			link := output.TrimLink(text[match[0]:match[1]])
+			site := output.LinkSite(link)
+			if site == "" {
+				continue
+			}
+			b.WriteString(template.HTMLEscapeString(text[last:match[0]]))
+			fmt.Fprintf(&b, `<a class="link" href="%s" title="%s">%s</a>`,
+				template.HTMLEscapeString(link), template.HTMLEscapeString(link), template.HTMLEscapeString(site))
+			last = match[0] + len(link)
+		}
+		b.WriteString(template.HTMLEscapeString(text[last:]))
+		return template.HTML(b.String())
+	}
+}
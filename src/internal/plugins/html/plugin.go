@@ -1,14 +1,29 @@
 package html
 
 import (
+	"embed"
 	"fmt"
 	"html/template"
+	"io/fs"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"threadbound/internal/analytics"
+	"threadbound/internal/i18n"
 	"threadbound/internal/models"
 	"threadbound/internal/output"
 )
 
+//go:embed templates/*
+var embeddedTemplates embed.FS
+
+// Templates returns the built-in templates, keyed by file name such as book.html
+func Templates() fs.FS {
+	templates, _ := fs.Sub(embeddedTemplates, "templates")
+	return templates
+}
+
 // HTMLPlugin implements the OutputPlugin interface for HTML generation
 type HTMLPlugin struct {
 	*output.BasePlugin
@@ -21,7 +36,7 @@ func NewHTMLPlugin() *HTMLPlugin {
 		SupportsAttachments: true,
 		SupportsReactions:   true,
 		SupportsURLPreviews: true,
-		RequiresTemplates:   false,
+		RequiresTemplates:   true,
 		SupportsPagination:  false,
 	}
 
@@ -40,9 +55,12 @@ func NewHTMLPlugin() *HTMLPlugin {
 
 // Generate creates an HTML book from the message data
 func (h *HTMLPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
+	// Templates in the template directory replace the embedded ones
+	tm := output.NewTemplateManagerWithDefaults(ctx.Config.TemplateDir, embeddedTemplates, "templates")
+	tm.Localize(output.Localizer(ctx.Config))
 	templateData := h.prepareTemplateData(ctx)
 
-	htmlContent, err := h.generateHTML(templateData)
+	htmlContent, err := h.generateHTML(tm, templateData, assetFunc(ctx.Config.SelfContained, ctx.Config.Log()), linksFunc(ctx.Config.FootnoteLinks()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate HTML: %w", err)
 	}
@@ -54,6 +72,28 @@ func (h *HTMLPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
 type HTMLTemplateData struct {
 	*output.TemplateData
 	MessagesByDate map[string][]MessageData
+	ChapterEnds    map[string]*analytics.ChapterStats // Stats keyed by the chapter's last date key
+	Numbers        *analytics.Numbers                 // Set when the "By the Numbers" chapter is enabled
+	VolumeChart    template.HTML                      // Inline SVG of messages per month
+	HourChart      template.HTML                      // Inline SVG heatmap of messages by weekday and hour
+	Calendars      []calendarChart                    // Inline SVG calendar heatmaps, a year each
+	CalendarPage   bool                               // Show the calendars up front as well as in the numbers
+	Theme          *ThemeStyle                        // Set when the config customizes the theme
+	Overflows      []output.Overflow                  // Long messages cut short, printed in full in the appendix
+	Locale         string                             // Language of the book, for the lang attribute
+	VolumeRange    string                             // Months the volume covers, in the book's language
+	Gaps           map[string]string                  // Separators such as "three weeks later", keyed by the date key of the day after the silence
+	WordClouds     map[string]*wordCloud              // Inline SVG word clouds, keyed by the date key of the first day each covers
+	GreatestHits   []greatestHit                      // Messages that got the most reactions, most first
+}
+
+// greatestHit is a message on the "Greatest Hits" page
+type greatestHit struct {
+	Sender    string
+	Date      time.Time
+	Text      string
+	Reactions []analytics.ReactionCount
+	Total     int
 }
 
 // MessageData represents a message for HTML templating
@@ -61,23 +101,56 @@ type MessageData struct {
 	*output.MessageTemplateData
 	FormattedDate string
 	DateKey       string
+	MemoryPath    string         // Set for photos inserted from the memories folder
+	Overflow      int            // Number of the message's full text in the appendix, when it was cut short
+	EffectClass   string         // CSS classes playing the message's effect, e.g. "effect-screen effect-confetti"
+	Avatar        *output.Avatar // The sender's avatar, on the first message of their run
+	AvatarSpace   bool           // Keeps room for the avatar on the rest of the run
 }
 
 // prepareTemplateData organizes the data for HTML templating
 func (h *HTMLPlugin) prepareTemplateData(ctx *output.GenerationContext) *HTMLTemplateData {
 	baseData := ctx.GetTemplateData()
+	l := output.Localizer(ctx.Config)
 
 	// Group messages by date
 	messagesByDate := make(map[string][]MessageData)
+	var overflows []output.Overflow
+	lastSenders := make(map[string]string) // Who sent the last message of each day, to find runs
+	gaps := make(map[string]string)
+	var lastMessage time.Time
+
+	// Word clouds by year, or the whole book's as year 0, each shown before its first day
+	clouds := make(map[int]analytics.WordCloud)
+	if ctx.Config.WordCloud != "" {
+		for _, cloud := range analytics.WordClouds(ctx.Messages, ctx.Config.WordCloud, l.IsStopword) {
+			clouds[cloud.Year] = cloud
+		}
+	}
+	wordClouds := make(map[string]*wordCloud)
 
 	for _, msg := range ctx.Messages {
-		if msg.Text == nil || strings.TrimSpace(*msg.Text) == "" {
+		if msg.Memory == nil && (msg.Text == nil || strings.TrimSpace(*msg.Text) == "") {
 			continue
 		}
 
 		dateKey := msg.FormattedDate.Format("2006-01-02")
-		senderName := output.GetSenderNameWithConfig(msg, ctx.Handles, ctx.Config)
-		timeStr := output.FormatTimestamp(msg.FormattedDate, "time")
+		cloudYear := 0
+		if ctx.Config.WordCloud == models.WordCloudYear {
+			cloudYear = msg.FormattedDate.Year()
+		}
+		if cloud, exists := clouds[cloudYear]; exists {
+			wordClouds[dateKey] = wordCloudChart(cloud, l)
+			delete(clouds, cloudYear)
+		}
+		if !lastMessage.IsZero() {
+			if label := output.GapLabel(lastMessage, msg.FormattedDate, ctx.Config.GapDays, l); label != "" {
+				gaps[dateKey] = label
+			}
+		}
+		lastMessage = msg.FormattedDate
+		senderName := output.GetSenderName(msg, ctx.Handles, ctx.Config)
+		timeStr := l.Format(msg.FormattedDate, i18n.TimeFormat)
 
 		// Get reactions for this message
 		reactions := ctx.Reactions[msg.GUID]
@@ -86,112 +159,130 @@ func (h *HTMLPlugin) prepareTemplateData(ctx *output.GenerationContext) *HTMLTem
 			MessageTemplateData: output.CreateMessageTemplateData(
 				msg, senderName, timeStr, true, true, reactions,
 			),
-			FormattedDate: msg.FormattedDate.Format("January 2, 2006"),
+			FormattedDate: l.Format(msg.FormattedDate, i18n.DateFormat),
 			DateKey:       dateKey,
 		}
+		msgData.Text = output.PolishText(msgData.Text, ctx.Config)
+		if ctx.Config.ShowEffects {
+			msgData.Effect = msg.GetSendEffect()
+			if msgData.Effect != "" {
+				msgData.EffectClass = effectClass(*msg.ExpressiveSendStyleID)
+			}
+		}
+		if msg.Memory != nil {
+			msgData.MemoryPath = filepath.ToSlash(msg.Memory.ProcessedPath)
+			senderName = ""
+		}
+		if avatar := output.GetAvatar(msg, ctx.Handles, ctx.Config); avatar != nil && msg.Memory == nil {
+			if senderName != lastSenders[dateKey] {
+				avatar.Path = filepath.ToSlash(avatar.Path)
+				msgData.Avatar = avatar
+			} else {
+				msgData.AvatarSpace = true
+			}
+		}
+		lastSenders[dateKey] = senderName
+		if cut, truncated := output.TruncateLines(msgData.Text, ctx.Config.OverflowLines); truncated {
+			overflows = append(overflows, output.Overflow{Number: len(overflows) + 1, Sender: senderName, Date: msg.FormattedDate, Text: msgData.Text})
+			msgData.Overflow = len(overflows)
+			msgData.Text = cut
+		}
 
 		messagesByDate[dateKey] = append(messagesByDate[dateKey], msgData)
 	}
 
-	return &HTMLTemplateData{
+	// Chapter stats follow the final day shown in each month
+	chapterEnds := make(map[string]*analytics.ChapterStats)
+	if ctx.Config.ChapterStats {
+		chapterStats := analytics.ByChapter(ctx.Messages)
+		lastDays := make(map[string]string)
+		for dateKey := range messagesByDate {
+			monthKey := dateKey[:len(analytics.ChapterKeyFormat)]
+			if dateKey > lastDays[monthKey] {
+				lastDays[monthKey] = dateKey
+			}
+		}
+		for monthKey, dateKey := range lastDays {
+			if stats, exists := chapterStats[monthKey]; exists {
+				chapterEnds[dateKey] = stats
+			}
+		}
+	}
+
+	data := &HTMLTemplateData{
 		TemplateData:   baseData,
 		MessagesByDate: messagesByDate,
+		ChapterEnds:    chapterEnds,
+		Theme:          themeStyle(ctx.Config.Theme),
+		Overflows:      overflows,
+		Locale:         l.Locale(),
+		Gaps:           gaps,
+		WordClouds:     wordClouds,
+	}
+	if ctx.Volume != nil {
+		data.VolumeRange = ctx.Volume.RangeIn(l)
+	}
+	if ctx.Config.StatsChapter {
+		data.Numbers = analytics.Summarize(ctx.Messages, func(msg models.Message) string {
+			return output.GetSenderName(msg, ctx.Handles, ctx.Config)
+		})
+		data.VolumeChart = volumeChart(data.Numbers.Monthly, l)
+		data.HourChart = hourHeatmap(data.Numbers.Hourly, l)
+	}
+	if ctx.Config.GreatestHits > 0 {
+		for _, hit := range analytics.GreatestHits(ctx.Messages, ctx.Reactions, ctx.Config.GreatestHits) {
+			data.GreatestHits = append(data.GreatestHits, greatestHit{
+				Sender:    output.GetSenderName(hit.Message, ctx.Handles, ctx.Config),
+				Date:      hit.Message.FormattedDate,
+				Text:      output.PolishText(strings.TrimSpace(strings.ReplaceAll(*hit.Message.Text, "\uFFFC", "")), ctx.Config),
+				Reactions: hit.Reactions,
+				Total:     hit.Total,
+			})
+		}
 	}
+	if ctx.Config.StatsChapter || ctx.Config.CalendarPage {
+		data.Calendars = calendarCharts(analytics.Calendars(ctx.Messages), l)
+		data.CalendarPage = ctx.Config.CalendarPage
+	}
+
+	return data
 }
 
-// generateHTML creates the HTML content using embedded templates
-func (h *HTMLPlugin) generateHTML(data *HTMLTemplateData) (string, error) {
-	tmpl := template.New("book")
-
-	// Define the main template
-	mainTemplate := `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>{{.Title}}</title>
-    <style>
-        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; margin: 0; padding: 20px; background: #f5f5f5; }
-        .container { max-width: 800px; margin: 0 auto; background: white; border-radius: 12px; overflow: hidden; box-shadow: 0 4px 6px rgba(0,0,0,0.1); }
-        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 40px; text-align: center; }
-        .header h1 { margin: 0; font-size: 2.5em; }
-        .header p { margin: 10px 0 0 0; opacity: 0.9; }
-        .content { padding: 20px; }
-        .date-section { margin: 30px 0; }
-        .date-header { font-size: 1.2em; font-weight: bold; color: #333; margin-bottom: 15px; padding-bottom: 5px; border-bottom: 2px solid #eee; }
-        .message { margin: 10px 0; display: flex; }
-        .message.from-me { justify-content: flex-end; }
-        .message-bubble { max-width: 70%; padding: 12px 16px; border-radius: 18px; position: relative; }
-        .message.from-me .message-bubble { background: #007AFF; color: white; }
-        .message:not(.from-me) .message-bubble { background: #E5E5EA; color: black; }
-        .message-meta { font-size: 0.8em; opacity: 0.7; margin-top: 4px; }
-        .reactions { margin-top: 8px; }
-        .reaction { display: inline-block; background: rgba(0,0,0,0.1); padding: 2px 6px; border-radius: 10px; font-size: 0.8em; margin-right: 4px; }
-        .attachments { margin-top: 8px; }
-        .attachment { padding: 8px; background: rgba(0,0,0,0.05); border-radius: 8px; margin: 4px 0; }
-        .stats { background: #f8f9fa; padding: 20px; margin: 20px 0; border-radius: 8px; }
-        .stats h3 { margin-top: 0; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>{{.Title}}</h1>
-            {{if .Author}}<p>by {{.Author}}</p>{{end}}
-            <p>Generated on {{.Date}}</p>
-        </div>
-
-        {{if .Stats}}
-        <div class="stats">
-            <h3>📊 Book Statistics</h3>
-            <p><strong>Messages:</strong> {{.Stats.TotalMessages}} ({{.Stats.TextMessages}} with text)</p>
-            <p><strong>Contacts:</strong> {{.Stats.TotalContacts}}</p>
-            <p><strong>Attachments:</strong> {{.Stats.AttachmentCount}}</p>
-        </div>
-        {{end}}
-
-        <div class="content">
-            {{range $dateKey, $messages := .MessagesByDate}}
-            <div class="date-section">
-                <div class="date-header">{{(index $messages 0).FormattedDate}}</div>
-                {{range $messages}}
-                <div class="message{{if .IsFromMe}} from-me{{end}}">
-                    <div class="message-bubble">
-                        {{.Text}}
-                        <div class="message-meta">
-                            {{if not .IsFromMe}}{{.Sender}} • {{end}}{{.Timestamp}}
-                        </div>
-                        {{if .Reactions}}
-                        <div class="reactions">
-                            {{range .Reactions}}
-                            <span class="reaction">{{.ReactionEmoji}} {{.SenderName}}</span>
-                            {{end}}
-                        </div>
-                        {{end}}
-                        {{if .Attachments}}
-                        <div class="attachments">
-                            {{range .Attachments}}
-                            <div class="attachment">📎 {{.Filename}}</div>
-                            {{end}}
-                        </div>
-                        {{end}}
-                    </div>
-                </div>
-                {{end}}
-            </div>
-            {{end}}
-        </div>
-    </div>
-</body>
-</html>`
-
-	tmpl, err := tmpl.Parse(mainTemplate)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+// screenEffectPrefix starts the expressive_send_style_id of effects that fill the screen,
+// rather than change the bubble
+const screenEffectPrefix = "com.apple.messages.effect."
+
+// effectClass names the CSS classes that play an effect, from the last part of its
+// expressive_send_style_id: effect-impact for Slam, effect-screen effect-confetti for Confetti
+func effectClass(styleID string) string {
+	name := styleID[strings.LastIndex(styleID, ".")+1:]
+	class := "effect-" + strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, "CK"), "Effect"))
+	if strings.HasPrefix(styleID, screenEffectPrefix) {
+		return "effect-screen " + class
+	}
+	return class
+}
+
+// generateHTML creates the HTML content from book.html and style.css, in the template
+// directory or else embedded. Images are given to the templates' "asset" function and
+// message text to "links", and they translate with t, plural and date.
+func (h *HTMLPlugin) generateHTML(tm *output.TemplateManager, data *HTMLTemplateData, asset func(string) interface{}, links func(string) template.HTML) (string, error) {
+	funcs := template.FuncMap(tm.Localizer().Funcs())
+	funcs["asset"] = asset
+	funcs["links"] = links
+	tmpl := template.New("book").Funcs(funcs)
+	for _, filename := range h.GetRequiredTemplates() {
+		source, err := tm.ReadTemplate(filename)
+		if err != nil {
+			return "", err
+		}
+		if _, err := tmpl.New(filename).Parse(string(source)); err != nil {
+			return "", fmt.Errorf("failed to parse template %s: %w", filename, err)
+		}
 	}
 
 	var buf strings.Builder
-	if err := tmpl.Execute(&buf, data); err != nil {
+	if err := tmpl.ExecuteTemplate(&buf, "book.html", data); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
@@ -202,4 +293,10 @@ func (h *HTMLPlugin) generateHTML(data *HTMLTemplateData) (string, error) {
 func (h *HTMLPlugin) ValidateConfig(config *models.BookConfig) error {
 	// Call base validation
 	return h.BasePlugin.ValidateConfig(config)
-}
\ No newline at end of file
+}
+
+// GetRequiredTemplates returns the templates of an HTML book: the page, which includes the
+// stylesheet with {{template "style.css" .}}
+func (h *HTMLPlugin) GetRequiredTemplates() []string {
+	return []string{"book.html", "style.css"}
+}
@@ -1,10 +1,13 @@
 package html
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"threadbound/internal/logging"
 	"threadbound/internal/models"
 	"threadbound/internal/output"
 )
@@ -31,17 +34,17 @@ func TestHTMLPlugin(t *testing.T) {
 	if !caps.SupportsReactions {
 		t.Error("HTML plugin should support reactions")
 	}
-	if caps.RequiresTemplates {
-		t.Error("HTML plugin should not require templates")
+	if !caps.RequiresTemplates {
+		t.Error("HTML plugin should require templates")
 	}
 	if caps.SupportsPagination {
 		t.Error("HTML plugin should not support pagination")
 	}
 
-	// Test required templates (should be empty)
+	// Test required templates
 	templates := plugin.GetRequiredTemplates()
-	if len(templates) != 0 {
-		t.Errorf("Expected 0 templates, got %d", len(templates))
+	if len(templates) != 2 || templates[0] != "book.html" || templates[1] != "style.css" {
+		t.Errorf("Expected book.html and style.css, got %v", templates)
 	}
 }
 
@@ -209,6 +212,475 @@ func TestHTMLPluginPrepareTemplateData(t *testing.T) {
 	}
 }
 
+func TestHTMLPluginChapterStats(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	september := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	october := time.Date(2023, 10, 2, 9, 0, 0, 0, time.UTC)
+	messages := []models.Message{
+		{ID: 1, GUID: "msg1", Text: stringPtr("First"), IsFromMe: true, FormattedDate: september},
+		{ID: 2, GUID: "msg2", Text: stringPtr("Second"), IsFromMe: true, FormattedDate: september.Add(time.Hour)},
+		{ID: 3, GUID: "msg3", Text: stringPtr("Third"), IsFromMe: true, FormattedDate: october},
+	}
+
+	ctx := &output.GenerationContext{
+		Messages:  messages,
+		Handles:   map[int]models.Handle{},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "Test", ChapterStats: true},
+		Stats:     &models.BookStats{},
+	}
+
+	templateData := plugin.prepareTemplateData(ctx)
+
+	stats, exists := templateData.ChapterEnds["2023-09-15"]
+	if !exists {
+		t.Fatal("Expected September stats after its last day")
+	}
+	if stats.Messages != 2 {
+		t.Errorf("Expected 2 messages in September, got %d", stats.Messages)
+	}
+	if _, exists := templateData.ChapterEnds["2023-10-02"]; !exists {
+		t.Error("Expected October stats after its last day")
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	if !strings.Contains(string(data), "September 2023 in numbers") {
+		t.Error("HTML should contain the September stats box")
+	}
+
+	// Disabled by default
+	ctx.Config.ChapterStats = false
+	if templateData := plugin.prepareTemplateData(ctx); len(templateData.ChapterEnds) != 0 {
+		t.Errorf("Expected no chapter stats when disabled, got %d", len(templateData.ChapterEnds))
+	}
+}
+
+func TestHTMLPluginNumbers(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	date := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Morning 🌅"), IsFromMe: true, FormattedDate: date},
+			{ID: 2, GUID: "msg2", Text: stringPtr("Hi"), HandleID: intPtr(1), FormattedDate: date.AddDate(0, 0, 1)},
+		},
+		Handles:   map[int]models.Handle{1: {ID: 1, DisplayName: "Alice"}},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "Test", StatsChapter: true},
+		Stats:     &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{"By the Numbers", "<td>Alice</td>", "2 days in a row", "🌅 1",
+		`aria-label="Messages per month"`, "<title>Friday 10:00: 1</title>", `aria-label="Messages per day in 2023"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML should contain %q", want)
+		}
+	}
+
+	// Disabled by default
+	ctx.Config.StatsChapter = false
+	if templateData := plugin.prepareTemplateData(ctx); templateData.Numbers != nil {
+		t.Error("Expected no numbers chapter when disabled")
+	}
+}
+
+func TestHTMLPluginCalendarPage(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	date := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Morning"), IsFromMe: true, FormattedDate: date},
+			{ID: 2, GUID: "msg2", Text: stringPtr("Hi"), HandleID: intPtr(1), FormattedDate: date},
+		},
+		Handles:   map[int]models.Handle{1: {ID: 1, DisplayName: "Alice"}},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "Test", CalendarPage: true},
+		Stats:     &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{`<div class="calendar">`, "<h3>2023</h3>", "2 messages",
+		"<title>Friday, September 15: 2</title>", "<title>Saturday, September 16</title>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML should contain %q", want)
+		}
+	}
+	if strings.Contains(html, "By the Numbers") {
+		t.Error("Expected the calendar page without the numbers chapter")
+	}
+	if got := strings.Count(html, "<rect"); got != 365 {
+		t.Errorf("Expected a day for every day of 2023, got %d", got)
+	}
+}
+
+func TestHTMLPluginOverflow(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	long := strings.Repeat("All the way to the lake and back. ", 10)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr(long), IsFromMe: true, FormattedDate: time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)},
+		},
+		Handles:   map[int]models.Handle{},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "Test", OverflowLines: 2},
+		Stats:     &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{`id="overflow-1-from"`, `href="#overflow-1"`, `id="overflow-1"`, `href="#overflow-1-from"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML should contain %q", want)
+		}
+	}
+	if strings.Count(html, strings.TrimSpace(long)) != 1 {
+		t.Error("Expected the full text once, in the appendix")
+	}
+}
+
+func TestHTMLPluginEffects(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	date := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Happy birthday!"), IsFromMe: true, FormattedDate: date,
+				ExpressiveSendStyleID: stringPtr("com.apple.messages.effect.CKConfettiEffect")},
+			{ID: 2, GUID: "msg2", Text: stringPtr("Secret"), HandleID: intPtr(1), FormattedDate: date.Add(time.Minute),
+				ExpressiveSendStyleID: stringPtr("com.apple.MobileSMS.expressivesend.invisibleink")},
+		},
+		Handles:   map[int]models.Handle{1: {ID: 1, DisplayName: "Alice"}},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "Test", ShowEffects: true},
+		Stats:     &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{
+		`class="message from-me effect effect-screen effect-confetti"`,
+		`class="message effect effect-invisibleink"`,
+		`<div class="effect-note">sent with Confetti 🎉</div>`,
+		`<div class="effect-note">sent with Invisible Ink 🙈</div>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML should contain %q", want)
+		}
+	}
+
+	ctx.Config.ShowEffects = false
+	data, _ = plugin.Generate(ctx)
+	if strings.Contains(string(data), `class="effect-note"`) || strings.Contains(string(data), "effect effect-") {
+		t.Error("Expected no effects shown unless asked for")
+	}
+}
+
+func TestHTMLPluginLinkStyle(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Look <here>: https://www.example.com/a?b=1&c=2."), IsFromMe: true, FormattedDate: time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)},
+		},
+		Handles:   map[int]models.Handle{},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "Test", LinkStyle: models.LinkStyleFootnote},
+		Stats:     &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	want := `Look &lt;here&gt;: <a class="link" href="https://www.example.com/a?b=1&amp;c=2" title="https://www.example.com/a?b=1&amp;c=2">example.com</a>.`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("HTML should contain %q", want)
+	}
+
+	ctx.Config.LinkStyle = models.LinkStyleURL
+	data, _ = plugin.Generate(ctx)
+	if strings.Contains(string(data), `class="link"`) || !strings.Contains(string(data), "https://www.example.com/a?b=1&amp;c=2.") {
+		t.Error("Expected the address printed as sent with the url link style")
+	}
+}
+
+func TestHTMLPluginDayGaps(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	start := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Bye"), IsFromMe: true, FormattedDate: start},
+			{ID: 2, GUID: "msg2", Text: stringPtr("Back"), IsFromMe: true, FormattedDate: start.AddDate(0, 2, 0)},
+		},
+		Handles:   map[int]models.Handle{},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "Test", GapDays: 30},
+		Stats:     &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	if want := `<div class="day-gap">• • •&emsp;two months later&emsp;• • •</div>`; strings.Count(string(data), want) != 1 {
+		t.Errorf("HTML should contain %q once", want)
+	}
+}
+
+func TestHTMLPluginWordClouds(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	start := time.Date(2023, 12, 30, 10, 30, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Pizza & games"), IsFromMe: true, FormattedDate: start},
+			{ID: 2, GUID: "msg2", Text: stringPtr("Happy new year"), IsFromMe: true, FormattedDate: start.AddDate(0, 0, 3)},
+		},
+		Handles:   map[int]models.Handle{},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "Test", WordCloud: models.WordCloudYear},
+		Stats:     &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{"<h2>2023 in Words</h2>", "<h2>2024 in Words</h2>", ">pizza</text>", ">happy</text>"} {
+		if strings.Count(html, want) != 1 {
+			t.Errorf("HTML should contain %q once", want)
+		}
+	}
+	if strings.Index(html, "2024 in Words") > strings.Index(html, "Happy new year") {
+		t.Error("Expected the 2024 cloud before its first day")
+	}
+
+	ctx.Config.WordCloud = ""
+	if data, _ := plugin.Generate(ctx); strings.Contains(string(data), `class="word-cloud"`) {
+		t.Error("Expected no word clouds unless word_cloud is set")
+	}
+}
+
+func TestHTMLPluginGreatestHits(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	date := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Nobody laughed"), IsFromMe: true, FormattedDate: date},
+			{ID: 2, GUID: "msg2", Text: stringPtr("The cat ate the cake"), HandleID: intPtr(1), FormattedDate: date.Add(time.Minute)},
+		},
+		Handles: map[int]models.Handle{1: {ID: 1, DisplayName: "Alice"}},
+		Reactions: map[string][]models.Reaction{
+			"msg2": {{ReactionEmoji: "😂"}, {ReactionEmoji: "😂"}, {ReactionEmoji: "❤️"}},
+		},
+		Config: &models.BookConfig{Title: "Test", GreatestHits: 5},
+		Stats:  &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	hits := strings.Index(html, "<h2>Greatest Hits</h2>")
+	if hits < 0 {
+		t.Fatal("Expected the Greatest Hits page")
+	}
+	for _, want := range []string{"<strong>Alice</strong>", "The cat ate the cake", `<span class="emoji">😂 2</span>`, "3 reactions"} {
+		if !strings.Contains(html[hits:], want) {
+			t.Errorf("Greatest Hits should contain %q", want)
+		}
+	}
+	if strings.Contains(html[hits:], "Nobody laughed") {
+		t.Error("Expected only messages with reactions")
+	}
+
+	ctx.Config.GreatestHits = 0
+	if data, _ := plugin.Generate(ctx); strings.Contains(string(data), `class="greatest-hits"`) {
+		t.Error("Expected no Greatest Hits unless greatest_hits is set")
+	}
+}
+
+func TestHTMLPluginAvatars(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	date := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Hi all"), HandleID: intPtr(1), FormattedDate: date},
+			{ID: 2, GUID: "msg2", Text: stringPtr("Anyone up?"), HandleID: intPtr(1), FormattedDate: date.Add(time.Minute)},
+			{ID: 3, GUID: "msg3", Text: stringPtr("Me!"), HandleID: intPtr(2), FormattedDate: date.Add(2 * time.Minute)},
+			{ID: 4, GUID: "msg4", Text: stringPtr("Same"), IsFromMe: true, FormattedDate: date.Add(3 * time.Minute)},
+		},
+		Handles: map[int]models.Handle{
+			1: {ID: 1, Contact: "+15551234567", DisplayName: "Alice Smith"},
+			2: {ID: 2, Contact: "bob@example.com", DisplayName: "Bob"},
+		},
+		Reactions: map[string][]models.Reaction{},
+		Config: &models.BookConfig{Title: "Test", ShowAvatars: true,
+			Avatars: models.Avatars{"bob@example.com": "avatars/bob.jpg"}},
+		Stats: &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	if strings.Count(html, `">AS</div>`) != 1 || strings.Count(html, `<div class="avatar-space"></div>`) != 1 {
+		t.Error("Expected Alice's initials on the first message of her run and room kept on the next")
+	}
+	if !strings.Contains(html, `<img class="avatar" src="avatars/bob.jpg" alt="B">`) {
+		t.Error("Expected Bob's avatar image")
+	}
+	if strings.Count(html, `class="avatar`) != 3 {
+		t.Error("Expected no avatar on your own messages")
+	}
+}
+
+func TestHTMLPluginTheme(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	date := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Hello"), IsFromMe: true, FormattedDate: date},
+		},
+		Handles:   map[int]models.Handle{},
+		Reactions: map[string][]models.Reaction{},
+		Config: &models.BookConfig{Title: "Test", Theme: models.ThemeConfig{
+			Font: "Georgia", SentColor: "#1D3557", CornerRadius: "6bp", BubbleMaxWidth: 0.6,
+		}},
+		Stats: &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{"max-width: 60%; border-radius: 6pt; font-family: 'Georgia', sans-serif;",
+		"background: #1D3557; color: white;", "background: #CCCCCC; color: black;"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML should contain %q", want)
+		}
+	}
+
+	// Without a theme the built-in style is left alone
+	ctx.Config.Theme = models.ThemeConfig{}
+	if templateData := plugin.prepareTemplateData(ctx); templateData.Theme != nil {
+		t.Error("Expected no theme style when none is configured")
+	}
+}
+
+func TestHTMLPluginTemplateDir(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	// A stylesheet in the template directory replaces the built-in one; book.html is still
+	// the embedded default
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), []byte(".message-bubble { color: {{.Title}}; }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Hello <b>"), IsFromMe: true, FormattedDate: time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)},
+		},
+		Handles:   map[int]models.Handle{},
+		Reactions: map[string][]models.Reaction{},
+		Config:    &models.BookConfig{Title: "teal", TemplateDir: dir},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	if !strings.Contains(html, ".message-bubble { color: teal; }") || strings.Contains(html, "border-radius: 18px") {
+		t.Errorf("Expected the custom stylesheet alone, got:\n%s", html)
+	}
+	if !strings.Contains(html, "Hello &lt;b&gt;") {
+		t.Error("Expected message text escaped")
+	}
+
+	// A template that doesn't parse is reported
+	if err := os.WriteFile(filepath.Join(dir, "book.html"), []byte("{{.Title"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plugin.Generate(ctx); err == nil || !strings.Contains(err.Error(), "book.html") {
+		t.Errorf("Expected an error naming book.html, got %v", err)
+	}
+}
+
+func TestHTMLPluginSelfContained(t *testing.T) {
+	plugin := NewHTMLPlugin()
+
+	dir := t.TempDir()
+	photo := filepath.ToSlash(filepath.Join(dir, "beach.png"))
+	if err := os.WriteFile(photo, []byte("\x89PNG\r\n\x1a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notes := filepath.ToSlash(filepath.Join(dir, "notes.txt"))
+	if err := os.WriteFile(notes, []byte("not a picture"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	var log strings.Builder
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: -1, GUID: "memory", Memory: &models.Memory{ProcessedPath: photo}, FormattedDate: date},
+			{ID: 1, GUID: "msg1", Text: stringPtr("Hi"), HandleID: intPtr(1), FormattedDate: date.Add(time.Minute)},
+		},
+		Handles:   map[int]models.Handle{1: {ID: 1, Contact: "bob@example.com", DisplayName: "Bob"}},
+		Reactions: map[string][]models.Reaction{},
+		Config: &models.BookConfig{Title: "Test", SelfContained: true, ShowAvatars: true, Logger: logging.New(&log, logging.Options{}),
+			Avatars: models.Avatars{"bob@example.com": notes}},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate HTML: %v", err)
+	}
+	html := string(data)
+	if !strings.Contains(html, `<img src="data:image/png;base64,iVBORw0KGgo=" alt="Memory`) || strings.Contains(html, photo) {
+		t.Errorf("Expected the photo inlined, got:\n%s", html)
+	}
+	// Files that aren't images are linked, with a warning
+	if !strings.Contains(html, `src="`+notes+`"`) || !strings.Contains(log.String(), "not an image") {
+		t.Errorf("Expected the text file linked and a warning, got %q", log.String())
+	}
+
+	ctx.Config.SelfContained = false
+	if data, _ := plugin.Generate(ctx); !strings.Contains(string(data), `src="`+photo+`"`) {
+		t.Error("Expected the photo linked without self_contained")
+	}
+}
+
 // Helper functions for creating pointers
 func stringPtr(s string) *string {
 	return &s
@@ -216,4 +688,4 @@ func stringPtr(s string) *string {
 
 func intPtr(i int) *int {
 	return &i
-}
\ No newline at end of file
+}
@@ -0,0 +1,58 @@
+package html
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// ThemeStyle is the bubble style of a configured theme in CSS terms. Books without a theme
+// keep the built-in iMessage look.
+type ThemeStyle struct {
+	Font          string
+	SentColor     string
+	SentText      string // Text color readable on SentColor
+	ReceivedColor string
+	ReceivedText  string
+	CornerRadius  string
+	MaxWidth      string
+}
+
+// themeStyle converts the configured theme, or returns nil when none is set
+func themeStyle(theme models.ThemeConfig) *ThemeStyle {
+	if theme == (models.ThemeConfig{}) {
+		return nil
+	}
+	theme = theme.WithDefaults()
+
+	// A TeX point is 1/72.27in, close enough to CSS's 1/72in; TeX's big point is CSS's pt
+	radius := theme.CornerRadius
+	if strings.HasSuffix(radius, "bp") {
+		radius = strings.TrimSuffix(radius, "bp") + "pt"
+	}
+
+	return &ThemeStyle{
+		Font:          theme.Font,
+		SentColor:     theme.SentColor,
+		SentText:      textColor(theme.SentColor),
+		ReceivedColor: theme.ReceivedColor,
+		ReceivedText:  textColor(theme.ReceivedColor),
+		CornerRadius:  radius,
+		MaxWidth:      fmt.Sprintf("%.0f%%", theme.BubbleMaxWidth*100),
+	}
+}
+
+// textColor picks black or white text, whichever reads better on a #RRGGBB fill
+func textColor(fill string) string {
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(fill, "#"), 16, 32)
+	if err != nil {
+		return "black"
+	}
+	r, g, b := float64(rgb>>16&0xFF), float64(rgb>>8&0xFF), float64(rgb&0xFF)
+	if 0.299*r+0.587*g+0.114*b < 150 {
+		return "white"
+	}
+	return "black"
+}
@@ -6,6 +6,7 @@ import (
 	"threadbound/internal/latex"
 	"threadbound/internal/models"
 	"threadbound/internal/output"
+	"threadbound/internal/workspace"
 )
 
 // PDFPlugin implements the OutputPlugin interface for PDF generation via XeLaTeX
@@ -45,22 +46,26 @@ func (p *PDFPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
 		return nil, fmt.Errorf("failed to generate TeX: %w", err)
 	}
 
-	// Write TeX to temporary file
-	tempTexPath := "temp_book.tex"
+	// Write TeX to a workspace for this run, where the PDF is built too
+	ws, err := workspace.New(ctx.Config.WorkDir, "book")
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Remove()
+
+	tempTexPath := ws.Path("book.tex")
 	if err := writeToFile(tempTexPath, texContent); err != nil {
 		return nil, fmt.Errorf("failed to write temporary TeX: %w", err)
 	}
-	defer removeFile(tempTexPath)
-
-	// Generate temporary PDF path
-	tempPDFPath := "temp_book.pdf"
-	defer removeFile(tempPDFPath)
+	tempPDFPath := ws.Path("book.pdf")
 
 	// Convert TeX to PDF using XeLaTeX builder
 	latexBuilder := latex.NewBuilder(ctx.Config)
+	latexBuilder.SetTimings(ctx.Timings)
 	if err := latexBuilder.BuildPDF(tempTexPath, tempPDFPath); err != nil {
 		return nil, fmt.Errorf("failed to convert to PDF: %w", err)
 	}
+	ctx.PageCount = latexBuilder.PageCount()
 
 	// Read the generated PDF file
 	pdfData, err := readFile(tempPDFPath)
@@ -124,4 +129,4 @@ func (p *PDFPlugin) GetRequiredTemplates() []string {
 		"image-placeholder.tex",
 		"attachment.tex",
 	}
-}
\ No newline at end of file
+}
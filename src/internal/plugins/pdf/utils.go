@@ -19,8 +19,3 @@ func writeToFile(path string, data []byte) error {
 func readFile(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
-
-// removeFile removes a file if it exists
-func removeFile(path string) {
-	os.Remove(path) // Ignore errors for cleanup
-}
\ No newline at end of file
@@ -0,0 +1,178 @@
+package poster
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"threadbound/internal/analytics"
+	"threadbound/internal/i18n"
+	"threadbound/internal/latex"
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+	"threadbound/internal/plugins/tex"
+	"threadbound/internal/workspace"
+)
+
+// Poster page size, a common frame size
+const (
+	PosterWidth  = "18in"
+	PosterHeight = "24in"
+)
+
+// quoteLines is how many lines of a quote the poster shows before cutting it short
+const quoteLines = 6
+
+// PosterPlugin implements the OutputPlugin interface for a year-in-review poster: one
+// large page per year with a calendar heatmap, top photos, top emoji and notable quotes,
+// typeset with XeLaTeX
+type PosterPlugin struct {
+	*output.BasePlugin
+}
+
+// NewPosterPlugin creates a new poster plugin instance
+func NewPosterPlugin() *PosterPlugin {
+	capabilities := output.PluginCapabilities{
+		SupportsImages:      true,
+		SupportsAttachments: false,
+		SupportsReactions:   true,
+		SupportsURLPreviews: false,
+		RequiresTemplates:   false,
+		SupportsPagination:  true,
+	}
+
+	base := output.NewBasePlugin(
+		"poster",
+		"Year in Review Poster",
+		"Generate a framable PDF with one page per year of the conversation",
+		"pdf",
+		capabilities,
+	)
+
+	return &PosterPlugin{
+		BasePlugin: base,
+	}
+}
+
+// Generate writes the poster as TeX and converts it with XeLaTeX
+func (p *PosterPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
+	ws, err := workspace.New(ctx.Config.WorkDir, "poster")
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Remove()
+
+	tempTexPath := ws.Path("poster.tex")
+	if err := os.WriteFile(tempTexPath, []byte(p.generateTeX(ctx)), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write temporary TeX: %w", err)
+	}
+	tempPDFPath := ws.Path("poster.pdf")
+
+	// The builder reports the page size it builds at
+	posterConfig := *ctx.Config
+	posterConfig.PageWidth, posterConfig.PageHeight = PosterWidth, PosterHeight
+	latexBuilder := latex.NewBuilder(&posterConfig)
+	latexBuilder.SetTimings(ctx.Timings)
+	if err := latexBuilder.BuildPDF(tempTexPath, tempPDFPath); err != nil {
+		return nil, fmt.Errorf("failed to convert to PDF: %w", err)
+	}
+	ctx.PageCount = latexBuilder.PageCount()
+
+	pdfData, err := os.ReadFile(tempPDFPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated PDF: %w", err)
+	}
+	return pdfData, nil
+}
+
+// generateTeX writes the whole poster document, a page per year
+func (p *PosterPlugin) generateTeX(ctx *output.GenerationContext) string {
+	senderName := func(msg models.Message) string {
+		return output.GetSenderName(msg, ctx.Handles, ctx.Config)
+	}
+
+	var body strings.Builder
+	for _, review := range analytics.ReviewYears(ctx.Messages, ctx.Reactions, senderName) {
+		p.writeYear(&body, ctx.Config, review, senderName)
+	}
+
+	theme := ctx.Config.Theme.WithDefaults()
+	var b strings.Builder
+	b.WriteString("\\documentclass{article}\n")
+	fmt.Fprintf(&b, "\\usepackage[paperwidth=%s, paperheight=%s, margin=1.25in]{geometry}\n", PosterWidth, PosterHeight)
+	b.WriteString("\\usepackage{fontspec}\n\\usepackage{xcolor}\n\\usepackage{graphicx}\n\\usepackage{tikz}\n\\usepackage{newunicodechar}\n")
+	fmt.Fprintf(&b, "\\setmainfont{%s}\n", theme.Font)
+	fmt.Fprintf(&b, "\\newfontfamily\\emojifont{%s}\n", theme.EmojiFont)
+	// The color names the book uses, which the calendar heatmap draws with
+	fmt.Fprintf(&b, "\\definecolor{sentmessage}{HTML}{%s}\n", strings.ToUpper(strings.TrimPrefix(theme.SentColor, "#")))
+	fmt.Fprintf(&b, "\\definecolor{receivedmessage}{HTML}{%s}\n", strings.ToUpper(strings.TrimPrefix(theme.ReceivedColor, "#")))
+	b.WriteString("\\definecolor{timestampgray}{RGB}{142, 142, 147}\n")
+	b.WriteString("\\pagestyle{empty}\n\\setlength{\\parindent}{0pt}\n")
+	b.WriteString(tex.EmojiDefinitions(body.String()))
+	b.WriteString(tex.ScriptDefinitions(ctx.Config.Title+body.String(), ctx.Config.Theme))
+	b.WriteString("\\begin{document}\n")
+	b.WriteString(body.String())
+	b.WriteString("\\end{document}\n")
+	return b.String()
+}
+
+// writeYear writes one year's page
+func (p *PosterPlugin) writeYear(b *strings.Builder, config *models.BookConfig, review analytics.YearReview, senderName func(models.Message) string) {
+	numbers := review.Numbers
+	l := output.Localizer(config)
+
+	b.WriteString("\\begin{center}\n")
+	fmt.Fprintf(b, "{\\fontsize{120}{130}\\selectfont\\bfseries %d}\\\\[0.4in]\n", review.Year)
+	fmt.Fprintf(b, "{\\fontsize{40}{48}\\selectfont %s}\\\\[0.3in]\n", tex.EscapeLaTeX(config.Title))
+	summary := l.Plural("messages", numbers.Messages) + " \\textperiodcentered{} " + l.Plural("active_days", numbers.ActiveDays)
+	if numbers.LongestStreak > 1 {
+		summary += " \\textperiodcentered{} " + l.Plural("days_in_a_row", numbers.LongestStreak)
+	}
+	fmt.Fprintf(b, "{\\LARGE\\color{timestampgray} %s}\n", summary)
+	b.WriteString("\\end{center}\n\\vspace{0.5in}\n\n")
+
+	fmt.Fprintf(b, "\\resizebox{\\textwidth}{!}{%%\n%s}\n\n\\vspace{0.6in}\n\n", tex.CalendarHeatmap(review.Calendar, l))
+
+	if len(review.Photos) > 0 {
+		b.WriteString("\\begin{center}\n")
+		for i, photo := range review.Photos {
+			if i > 0 && i%3 == 0 {
+				b.WriteString("\\\\[0.2in]\n")
+			}
+			fmt.Fprintf(b, "\\includegraphics[width=0.31\\textwidth, height=0.24\\textwidth, keepaspectratio]{%s}\\hfill\n", filepath.ToSlash(photo))
+		}
+		b.WriteString("\\end{center}\n\\vspace{0.5in}\n\n")
+	}
+
+	if len(numbers.TopEmoji) > 0 {
+		b.WriteString("\\begin{center}\n")
+		for i, emoji := range numbers.TopEmoji {
+			if i > 0 {
+				b.WriteString("\\hspace{0.6in}")
+			}
+			fmt.Fprintf(b, "{\\fontsize{60}{66}\\selectfont %s}\\,{\\Large\\color{timestampgray}%d}", emoji.Emoji, emoji.Count)
+		}
+		b.WriteString("\n\\end{center}\n\\vspace{0.5in}\n\n")
+	}
+
+	for _, quote := range review.Quotes {
+		text, cut := output.TruncateLines(output.PolishText(strings.TrimSpace(strings.ReplaceAll(*quote.Text, "\uFFFC", "")), config), quoteLines)
+		if cut {
+			text += "\u2026"
+		}
+		var lines []string
+		for _, line := range strings.Split(tex.EscapeLaTeX(text), "\n") {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		text = strings.Join(lines, "\\\\\n")
+		b.WriteString("\\begin{center}\n\\begin{minipage}{0.8\\textwidth}\n\\centering\n")
+		fmt.Fprintf(b, "{\\huge\\itshape ``%s''}\\\\[0.15in]\n", text)
+		fmt.Fprintf(b, "{\\Large\\color{timestampgray} %s, %s}\n", tex.EscapeLaTeX(senderName(quote)), tex.EscapeLaTeX(l.Format(quote.FormattedDate, i18n.MonthDay)))
+		b.WriteString("\\end{minipage}\n\\end{center}\n\\vspace{0.3in}\n\n")
+	}
+
+	b.WriteString("\\clearpage\n\n")
+}
@@ -0,0 +1,60 @@
+package poster
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+func TestPosterPlugin(t *testing.T) {
+	plugin := NewPosterPlugin()
+
+	if plugin.ID() != "poster" {
+		t.Errorf("Expected ID 'poster', got '%s'", plugin.ID())
+	}
+	if plugin.FileExtension() != "pdf" {
+		t.Errorf("Expected extension 'pdf', got '%s'", plugin.FileExtension())
+	}
+	if len(plugin.GetRequiredTemplates()) != 0 {
+		t.Error("Poster plugin should not need templates")
+	}
+}
+
+func TestPosterGenerateTeX(t *testing.T) {
+	plugin := NewPosterPlugin()
+
+	text := func(s string) *string { return &s }
+	jpeg := "image/jpeg"
+	alice := 1
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("Road trip 🚗 & snacks"), HandleID: &alice, FormattedDate: time.Date(2022, 8, 1, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text("\uFFFC"), IsFromMe: true, FormattedDate: time.Date(2023, 5, 6, 9, 0, 0, 0, time.UTC),
+				Attachments: []models.Attachment{{MimeType: &jpeg, ProcessedPath: "/tmp/processed/lake.jpg"}}},
+		},
+		Handles:   map[int]models.Handle{alice: {ID: alice, DisplayName: "Alice"}},
+		Reactions: map[string][]models.Reaction{"1": {{Type: 2000}}},
+		Config:    &models.BookConfig{Title: "Summer & Co"},
+	}
+
+	result := plugin.generateTeX(ctx)
+	if got := strings.Count(result, "\\clearpage"); got != 2 {
+		t.Errorf("Expected a page per year, got %d", got)
+	}
+	for _, want := range []string{
+		"paperwidth=18in, paperheight=24in",
+		"\\bfseries 2022}", "\\bfseries 2023}",
+		"Summer \\& Co",
+		"``Road trip 🚗 \\& snacks''", "Alice, August 1",
+		"{/tmp/processed/lake.jpg}",
+		"\\newunicodechar{🚗}",
+		"\\fill[sentmessage!100]",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in poster:\n%s", want, result)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"threadbound/internal/output"
 	"threadbound/internal/plugins/html"
 	"threadbound/internal/plugins/pdf"
+	"threadbound/internal/plugins/poster"
+	"threadbound/internal/plugins/sqlite"
 	"threadbound/internal/plugins/tex"
 	"threadbound/internal/plugins/text"
 )
@@ -34,6 +36,18 @@ func RegisterBuiltinPlugins() error {
 		return err
 	}
 
+	// Register poster plugin
+	posterPlugin := poster.NewPosterPlugin()
+	if err := output.Register(posterPlugin); err != nil {
+		return err
+	}
+
+	// Register SQLite archive plugin
+	sqlitePlugin := sqlite.NewSQLitePlugin()
+	if err := output.Register(sqlitePlugin); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -43,4 +57,4 @@ func init() {
 	if err := RegisterBuiltinPlugins(); err != nil {
 		panic("Failed to register built-in plugins: " + err.Error())
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,265 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+	"threadbound/internal/workspace"
+)
+
+// FormatVersion is written to the archive table, and raised when the schema changes
+const FormatVersion = 1
+
+// MeSenderID is the sender ID of your own messages; everyone else keeps their handle's
+const MeSenderID = 0
+
+// Schema is the archive's own schema, made to be read without knowing chat.db's. Dates are
+// ISO 8601 in UTC, so they sort as text.
+const Schema = `
+	CREATE TABLE archive (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+	CREATE TABLE senders (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		contact TEXT,
+		service TEXT,
+		is_me INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE messages (
+		id INTEGER PRIMARY KEY,
+		guid TEXT NOT NULL UNIQUE,
+		sent_at TEXT NOT NULL,
+		sender_id INTEGER REFERENCES senders(id),
+		text TEXT,
+		subject TEXT,
+		effect TEXT,
+		reply_to_guid TEXT
+	);
+	CREATE TABLE attachments (
+		id INTEGER PRIMARY KEY,
+		message_id INTEGER NOT NULL REFERENCES messages(id),
+		guid TEXT,
+		filename TEXT,
+		mime_type TEXT,
+		uti TEXT,
+		size INTEGER NOT NULL DEFAULT 0,
+		is_sticker INTEGER NOT NULL DEFAULT 0,
+		original_path TEXT
+	);
+	CREATE TABLE reactions (
+		id INTEGER PRIMARY KEY,
+		message_id INTEGER NOT NULL REFERENCES messages(id),
+		sender TEXT NOT NULL,
+		is_from_me INTEGER NOT NULL DEFAULT 0,
+		emoji TEXT NOT NULL,
+		reacted_at TEXT
+	);
+	CREATE INDEX messages_sent_at ON messages (sent_at);
+	CREATE INDEX attachments_message ON attachments (message_id);
+	CREATE INDEX reactions_message ON reactions (message_id);
+`
+
+// SQLitePlugin implements the OutputPlugin interface for an archive of the conversation as
+// a plain SQLite database, with a schema of its own rather than Apple's, for keeping long
+// after chat.db has changed shape
+type SQLitePlugin struct {
+	*output.BasePlugin
+}
+
+// NewSQLitePlugin creates a new SQLite archive plugin instance
+func NewSQLitePlugin() *SQLitePlugin {
+	capabilities := output.PluginCapabilities{
+		SupportsImages:      false,
+		SupportsAttachments: true,
+		SupportsReactions:   true,
+		SupportsURLPreviews: false,
+		RequiresTemplates:   false,
+		SupportsPagination:  false,
+	}
+
+	base := output.NewBasePlugin(
+		"sqlite",
+		"SQLite Archive",
+		"Generate a SQLite database of the messages, senders, attachments and reactions in a simple schema, for archiving",
+		"sqlite",
+		capabilities,
+	)
+
+	return &SQLitePlugin{
+		BasePlugin: base,
+	}
+}
+
+// Generate writes the archive to a temporary database and returns its file
+func (s *SQLitePlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
+	ws, err := workspace.New(ctx.Config.WorkDir, "archive")
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Remove()
+
+	path := ws.Path("archive.sqlite")
+	if err := s.writeArchive(path, ctx, time.Now()); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return data, nil
+}
+
+// writeArchive creates the database at path and fills it in one transaction
+func (s *SQLitePlugin) writeArchive(path string, ctx *output.GenerationContext, now time.Time) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(Schema); err != nil {
+		return fmt.Errorf("failed to create archive schema: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := s.insert(tx, ctx, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return db.Close()
+}
+
+// insert writes the archive's rows: the senders of the messages, then each message with
+// its attachments and reactions
+func (s *SQLitePlugin) insert(tx *sql.Tx, ctx *output.GenerationContext, now time.Time) error {
+	info := [][2]string{
+		{"format_version", fmt.Sprint(FormatVersion)},
+		{"exported_at", now.UTC().Format(time.RFC3339)},
+		{"title", ctx.Config.Title},
+	}
+	for _, row := range info {
+		if _, err := tx.Exec(`INSERT INTO archive (key, value) VALUES (?, ?)`, row[0], row[1]); err != nil {
+			return fmt.Errorf("failed to write archive info: %w", err)
+		}
+	}
+
+	senders := make(map[int]bool)
+	for _, msg := range ctx.Messages {
+		if msg.Memory != nil {
+			continue
+		}
+		id, known := senderID(msg, ctx.Handles)
+		if !known || senders[id] {
+			continue
+		}
+		senders[id] = true
+
+		var contact, service interface{}
+		if id != MeSenderID {
+			handle := ctx.Handles[id]
+			contact, service = handle.Contact, nullable(handle.Service)
+		}
+		if _, err := tx.Exec(`INSERT INTO senders (id, name, contact, service, is_me) VALUES (?, ?, ?, ?, ?)`,
+			id, output.GetSenderName(msg, ctx.Handles, ctx.Config), contact, service, id == MeSenderID); err != nil {
+			return fmt.Errorf("failed to write sender: %w", err)
+		}
+	}
+
+	for _, msg := range ctx.Messages {
+		// Memories are photos from outside the conversation
+		if msg.Memory != nil {
+			continue
+		}
+
+		var sender interface{}
+		if id, known := senderID(msg, ctx.Handles); known {
+			sender = id
+		}
+		var text interface{}
+		if msg.Text != nil {
+			text = nullable(strings.TrimSpace(strings.ReplaceAll(*msg.Text, "\uFFFC", "")))
+		}
+		var subject interface{}
+		if msg.Subject != nil {
+			subject = nullable(*msg.Subject)
+		}
+		var replyTo interface{}
+		if msg.ThreadOriginatorGUID != nil {
+			replyTo = nullable(*msg.ThreadOriginatorGUID)
+		}
+		result, err := tx.Exec(`INSERT INTO messages (guid, sent_at, sender_id, text, subject, effect, reply_to_guid) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			msg.GUID, archiveTime(msg.FormattedDate), sender, text, subject, nullable(msg.GetSendEffect()), replyTo)
+		if err != nil {
+			return fmt.Errorf("failed to write message %s: %w", msg.GUID, err)
+		}
+		messageID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		for _, att := range msg.Attachments {
+			var filename, originalPath interface{}
+			if att.Filename != nil {
+				filename, originalPath = filepath.Base(*att.Filename), *att.Filename
+			}
+			if _, err := tx.Exec(`INSERT INTO attachments (message_id, guid, filename, mime_type, uti, size, is_sticker, original_path) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				messageID, att.GUID, filename, att.MimeType, att.UTI, att.TotalBytes, att.IsSticker, originalPath); err != nil {
+				return fmt.Errorf("failed to write attachment %s: %w", att.GUID, err)
+			}
+		}
+
+		for _, reaction := range ctx.Reactions[msg.GUID] {
+			var reactedAt interface{}
+			if !reaction.Timestamp.IsZero() {
+				reactedAt = archiveTime(reaction.Timestamp)
+			}
+			if _, err := tx.Exec(`INSERT INTO reactions (message_id, sender, is_from_me, emoji, reacted_at) VALUES (?, ?, ?, ?, ?)`,
+				messageID, reaction.SenderName, reaction.IsFromMe, reaction.ReactionEmoji, reactedAt); err != nil {
+				return fmt.Errorf("failed to write reaction: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// senderID returns the archive's sender ID for the sender of msg, and false when the
+// message doesn't say who sent it
+func senderID(msg models.Message, handles map[int]models.Handle) (int, bool) {
+	if msg.IsFromMe {
+		return MeSenderID, true
+	}
+	if msg.HandleID == nil {
+		return 0, false
+	}
+	if _, exists := handles[*msg.HandleID]; !exists {
+		return 0, false
+	}
+	return *msg.HandleID, true
+}
+
+// archiveTime formats t as the archive stores dates
+func archiveTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// nullable stores an empty string as NULL
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
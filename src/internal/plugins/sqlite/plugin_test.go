@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+func TestSQLitePluginGenerate(t *testing.T) {
+	plugin := NewSQLitePlugin()
+
+	sent := time.Date(2023, 9, 15, 10, 30, 0, 0, time.FixedZone("EDT", -4*3600))
+	handleID := 7
+	filename := "~/Library/Messages/Attachments/ab/IMG_0001.HEIC"
+	mimeType := "image/heic"
+	effect := "com.apple.messages.effect.CKConfettiEffect"
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "msg1", Text: stringPtr("Look \uFFFC"), IsFromMe: true, FormattedDate: sent, ExpressiveSendStyleID: &effect,
+				Attachments: []models.Attachment{{GUID: "att1", Filename: &filename, MimeType: &mimeType, TotalBytes: 2048}}},
+			{GUID: "msg2", Text: stringPtr("Gorgeous"), HandleID: &handleID, FormattedDate: sent.Add(time.Minute)},
+			{GUID: "memory", FormattedDate: sent.Add(time.Hour), Memory: &models.Memory{Path: "beach.jpg"}},
+		},
+		Handles: map[int]models.Handle{7: {ID: 7, Contact: "+15551234567", Service: "iMessage", DisplayName: "Alice"}},
+		Reactions: map[string][]models.Reaction{
+			"msg1": {{SenderName: "Alice", ReactionEmoji: "❤️", Timestamp: sent.Add(2 * time.Minute)}},
+		},
+		Config: &models.BookConfig{Title: "Us"},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate archive: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "archive.sqlite")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer db.Close()
+
+	var version, title string
+	if err := db.QueryRow(`SELECT value FROM archive WHERE key = 'format_version'`).Scan(&version); err != nil || version != "1" {
+		t.Errorf("Expected format version 1, got %q, %v", version, err)
+	}
+	if err := db.QueryRow(`SELECT value FROM archive WHERE key = 'title'`).Scan(&title); err != nil || title != "Us" {
+		t.Errorf("Expected title Us, got %q, %v", title, err)
+	}
+
+	rows, err := db.Query(`SELECT m.guid, m.sent_at, s.name, s.is_me, m.text, m.effect FROM messages m JOIN senders s ON s.id = m.sender_id ORDER BY m.sent_at`)
+	if err != nil {
+		t.Fatalf("Failed to query messages: %v", err)
+	}
+	defer rows.Close()
+	type row struct {
+		guid, sentAt, sender string
+		isMe                 bool
+		text, effect         sql.NullString
+	}
+	var got []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.guid, &r.sentAt, &r.sender, &r.isMe, &r.text, &r.effect); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 messages without the memory, got %d", len(got))
+	}
+	if got[0].guid != "msg1" || got[0].sentAt != "2023-09-15T14:30:00Z" || got[0].sender != "Me" || !got[0].isMe || got[0].text.String != "Look" || got[0].effect.String != "Confetti 🎉" {
+		t.Errorf("Unexpected first message %+v", got[0])
+	}
+	if got[1].guid != "msg2" || got[1].sender != "Alice" || got[1].isMe || got[1].text.String != "Gorgeous" || got[1].effect.Valid {
+		t.Errorf("Unexpected second message %+v", got[1])
+	}
+
+	var contact, attachment, originalPath string
+	var size int
+	if err := db.QueryRow(`SELECT contact FROM senders WHERE name = 'Alice'`).Scan(&contact); err != nil || contact != "+15551234567" {
+		t.Errorf("Expected Alice's number, got %q, %v", contact, err)
+	}
+	if err := db.QueryRow(`SELECT a.filename, a.original_path, a.size FROM attachments a JOIN messages m ON m.id = a.message_id WHERE m.guid = 'msg1'`).Scan(&attachment, &originalPath, &size); err != nil ||
+		attachment != "IMG_0001.HEIC" || originalPath != filename || size != 2048 {
+		t.Errorf("Unexpected attachment %q, %q, %d, %v", attachment, originalPath, size, err)
+	}
+
+	var sender, emoji, reactedAt string
+	if err := db.QueryRow(`SELECT r.sender, r.emoji, r.reacted_at FROM reactions r JOIN messages m ON m.id = r.message_id WHERE m.guid = 'msg1'`).Scan(&sender, &emoji, &reactedAt); err != nil ||
+		sender != "Alice" || emoji != "❤️" || reactedAt != "2023-09-15T14:32:00Z" {
+		t.Errorf("Unexpected reaction %q, %q, %q, %v", sender, emoji, reactedAt, err)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
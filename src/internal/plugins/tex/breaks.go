@@ -0,0 +1,73 @@
+package tex
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// wordBreak marks a place a long word may break; EscapeLaTeX turns it into \allowbreak
+const wordBreak = "\u200b"
+
+var (
+	// longWordRegex finds words longer than the narrowest bubble can be relied on to fit
+	longWordRegex = regexp.MustCompile(`\S{21,}`)
+
+	// texCommandRegex finds the commands the text holds before it's escaped, such as the
+	// link preview placeholders and footnote marks, which mustn't be broken
+	texCommandRegex = regexp.MustCompile(`\\[a-zA-Z]+\{[^}]*\}`)
+)
+
+// breakEvery is how many letters or digits in a row a long word may go without a break
+const breakEvery = 8
+
+// breakLongWords lets TeX break the words in a message too long for its bubble, such as
+// links, hashtags and German compounds, which would otherwise run into the margin. Words
+// break after the punctuation in them, such as the slashes of a link, and their runs of
+// letters and digits every few characters.
+func breakLongWords(text string) string {
+	if !longWordRegex.MatchString(text) {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, command := range texCommandRegex.FindAllStringIndex(text, -1) {
+		b.WriteString(longWordRegex.ReplaceAllStringFunc(text[last:command[0]], breakWord))
+		b.WriteString(text[command[0]:command[1]])
+		last = command[1]
+	}
+	b.WriteString(longWordRegex.ReplaceAllStringFunc(text[last:], breakWord))
+	return b.String()
+}
+
+// breakWord marks where a long word may break: after each punctuation mark followed by a
+// letter or digit, and within runs of letters and digits every breakEvery characters
+func breakWord(word string) string {
+	runes := []rune(word)
+	var b strings.Builder
+	run := 0
+	for i, r := range runes {
+		b.WriteRune(r)
+		if i == 0 || i+1 == len(runes) || !isWordRune(runes[i+1]) {
+			run++
+			continue
+		}
+		if isWordRune(r) {
+			run++
+			if run < breakEvery {
+				continue
+			}
+		} else if !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteString(wordBreak)
+		run = 0
+	}
+	return b.String()
+}
+
+// isWordRune reports whether r is a letter or digit
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
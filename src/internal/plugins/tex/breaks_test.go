@@ -0,0 +1,24 @@
+package tex
+
+import "testing"
+
+func TestBreakLongWords(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"short words", "see you at the station at 7", "see you at the station at 7"},
+		{"link", "https://example.com/some/long_path", `https://\allowbreak{}example.\allowbreak{}com/\allowbreak{}some/\allowbreak{}long\_\allowbreak{}path`},
+		{"hashtag", "#throwbacktothesummerof2019", `\#throwba\allowbreak{}cktothes\allowbreak{}ummerof2\allowbreak{}019`},
+		{"compound", "Donaudampfschifffahrtsgesellschaft", `Donaudam\allowbreak{}pfschiff\allowbreak{}fahrtsge\allowbreak{}sellscha\allowbreak{}ft`},
+		{"commands kept whole", `\urlanchor{url:0123456789abcdef0123}\linkpreview{0}`, `\urlanchor{url:0123456789abcdef0123}\linkpreview{0}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EscapeLaTeX(breakLongWords(tt.text)); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
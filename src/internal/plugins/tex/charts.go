@@ -0,0 +1,155 @@
+package tex
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"threadbound/internal/analytics"
+	"threadbound/internal/i18n"
+)
+
+// Chart dimensions in centimetres; charts are scaled to the text width when drawn
+const (
+	chartWidth  = 12.0
+	chartHeight = 4.0
+)
+
+// volumeChart draws messages per month as a TikZ bar chart, labelled in l's locale
+func volumeChart(months []analytics.MonthCount, l *i18n.Localizer) string {
+	if len(months) == 0 {
+		return ""
+	}
+
+	max := 0
+	for _, month := range months {
+		if month.Count > max {
+			max = month.Count
+		}
+	}
+	if max == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	barWidth := chartWidth / float64(len(months))
+	b.WriteString("\\begin{tikzpicture}\n")
+	fmt.Fprintf(&b, "\\draw[timestampgray] (0,0) -- (%.2f,0);\n", chartWidth)
+
+	for i, month := range months {
+		x := float64(i) * barWidth
+		if month.Count > 0 {
+			height := chartHeight * float64(month.Count) / float64(max)
+			fmt.Fprintf(&b, "\\fill[sentmessage] (%.2f,0) rectangle (%.2f,%.2f);\n", x+barWidth*0.1, x+barWidth*0.9, height)
+		}
+
+		// Label every month in short books, otherwise only the start of each year
+		label := ""
+		if len(months) <= 12 {
+			label = l.FormatLayout(month.Month, "Jan")
+		} else if i == 0 || month.Month.Month() == time.January {
+			label = month.Month.Format("2006")
+		}
+		if label != "" {
+			fmt.Fprintf(&b, "\\node[anchor=north, font=\\tiny, text=timestampgray] at (%.2f,0) {%s};\n", x+barWidth/2, label)
+		}
+	}
+
+	fmt.Fprintf(&b, "\\node[anchor=south west, font=\\tiny, text=timestampgray] at (0,%.2f) {%d};\n", chartHeight, max)
+	b.WriteString("\\end{tikzpicture}")
+	return b.String()
+}
+
+// weekdayOrder lists weekdays Monday first, as rows of the heatmap
+var weekdayOrder = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// hourHeatmap draws messages by weekday and hour as a TikZ grid shaded by volume, with the
+// weekdays named in l's locale
+func hourHeatmap(grid *analytics.HourGrid, l *i18n.Localizer) string {
+	if grid == nil || grid.Max() == 0 {
+		return ""
+	}
+
+	max := grid.Max()
+	cell := chartWidth / 24
+	var b strings.Builder
+	b.WriteString("\\begin{tikzpicture}\n")
+
+	for row, weekday := range weekdayOrder {
+		// Monday at the top
+		y := float64(len(weekdayOrder)-1-row) * cell
+		fmt.Fprintf(&b, "\\node[anchor=east, font=\\tiny, text=timestampgray] at (0,%.2f) {%s};\n", y+cell/2, l.ShortWeekdayName(weekday))
+		for hour := 0; hour < 24; hour++ {
+			count := grid[weekday][hour]
+			x := float64(hour) * cell
+			if count == 0 {
+				fmt.Fprintf(&b, "\\fill[receivedmessage!40] (%.2f,%.2f) rectangle +(%.2f,%.2f);\n", x, y, cell*0.92, cell*0.92)
+				continue
+			}
+			// Keep the faintest non-empty cell visible
+			shade := 15 + 85*count/max
+			fmt.Fprintf(&b, "\\fill[sentmessage!%d] (%.2f,%.2f) rectangle +(%.2f,%.2f);\n", shade, x, y, cell*0.92, cell*0.92)
+		}
+	}
+
+	for _, hour := range []int{0, 6, 12, 18} {
+		fmt.Fprintf(&b, "\\node[anchor=north, font=\\tiny, text=timestampgray] at (%.2f,0) {%d:00};\n", float64(hour)*cell+cell/2, hour)
+	}
+	b.WriteString("\\end{tikzpicture}")
+	return b.String()
+}
+
+// calendarCell is the size of one day in a calendar heatmap, in centimetres
+const calendarCell = 0.5
+
+// CalendarHeatmap draws every day of a year as a TikZ grid of weeks, Monday at the top of
+// each column, shaded by how many messages were sent that day. It uses the sentmessage,
+// receivedmessage and timestampgray colors, so documents other than the book define them too.
+// Months and weekdays are named in l's locale.
+func CalendarHeatmap(calendar analytics.CalendarYear, l *i18n.Localizer) string {
+	var b strings.Builder
+	b.WriteString("\\begin{tikzpicture}\n")
+	for _, row := range []int{0, 2, 4} {
+		fmt.Fprintf(&b, "\\node[anchor=east, font=\\tiny, text=timestampgray] at (0,%.2f) {%s};\n", calendarY(row)+calendarCell/2, l.ShortWeekdayName(weekdayOrder[row]))
+	}
+
+	for _, day := range calendar.Days {
+		x := float64(day.Week) * calendarCell
+		y := calendarY(day.Weekday)
+
+		if day.Date.Day() == 1 {
+			fmt.Fprintf(&b, "\\node[anchor=south west, font=\\tiny, text=timestampgray] at (%.2f,%.2f) {%s};\n", x, calendarY(0)+calendarCell, l.FormatLayout(day.Date, "Jan"))
+		}
+
+		if day.Count == 0 {
+			fmt.Fprintf(&b, "\\fill[receivedmessage!40] (%.2f,%.2f) rectangle +(%.2f,%.2f);\n", x, y, calendarCell*0.85, calendarCell*0.85)
+			continue
+		}
+		// Keep the faintest day with messages visible
+		shade := 15 + 85*day.Count/calendar.Max
+		fmt.Fprintf(&b, "\\fill[sentmessage!%d] (%.2f,%.2f) rectangle +(%.2f,%.2f);\n", shade, x, y, calendarCell*0.85, calendarCell*0.85)
+	}
+	b.WriteString("\\end{tikzpicture}")
+	return b.String()
+}
+
+// calendarY is the bottom of a calendar row, Monday (row 0) at the top
+func calendarY(row int) float64 {
+	return float64(len(weekdayOrder)-1-row) * calendarCell
+}
+
+// wordCloudChart draws a word cloud in TikZ, the most used words largest and darkest
+func wordCloudChart(words []analytics.CloudWord) string {
+	var b strings.Builder
+	b.WriteString("\\begin{tikzpicture}[x=1pt, y=1pt]\n")
+	// The whole box, so clouds of few words are scaled like full ones
+	fmt.Fprintf(&b, "\\path (0,0) rectangle (%d,%d);\n", analytics.WordCloudWidth, analytics.WordCloudHeight)
+	for _, word := range words {
+		fmt.Fprintf(&b, "\\node[inner sep=0, font=\\fontsize{%.1f}{%.1f}\\selectfont, text=sentmessage!%d!timestampgray] at (%.1f,%.1f) {%s};\n",
+			word.Size, word.Size, 30+int(70*word.Weight), word.X, analytics.WordCloudHeight-word.Y, EscapeLaTeX(word.Word))
+	}
+	b.WriteString("\\end{tikzpicture}")
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package tex
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"threadbound/internal/analytics"
+)
+
+// emojiJoiners are the invisible parts of emoji sequences: zero-width joiner, variation
+// selectors and the keycap enclosure. The emoji font has no glyphs for combined sequences,
+// so these are dropped and the component emoji are drawn side by side.
+var emojiJoiners = map[rune]bool{0x200D: true, 0xFE0E: true, 0xFE0F: true, 0x20E3: true}
+
+// isSkinTone reports whether r is a Fitzpatrick skin tone modifier
+func isSkinTone(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+// EmojiDefinitions emits a \newunicodechar mapping for every emoji character used in text,
+// so each one is drawn from the emoji font instead of breaking XeLaTeX or showing as tofu
+func EmojiDefinitions(text string) string {
+	used := make(map[rune]bool)
+	for _, r := range text {
+		if analytics.IsEmoji(r) || emojiJoiners[r] || isSkinTone(r) {
+			used[r] = true
+		}
+	}
+	if len(used) == 0 {
+		return ""
+	}
+
+	runes := make([]rune, 0, len(used))
+	for r := range used {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	var b strings.Builder
+	b.WriteString("% Emoji used in this book\n")
+	for _, r := range runes {
+		switch {
+		case emojiJoiners[r]:
+			// Written in ^^^^ notation since the characters are invisible
+			fmt.Fprintf(&b, "\\newunicodechar{^^^^%04x}{}\n", r)
+		case isSkinTone(r):
+			fmt.Fprintf(&b, "\\newunicodechar{%c}{}\n", r)
+		default:
+			fmt.Fprintf(&b, "\\newunicodechar{%c}{{\\emojifont\\symbol{\"%X}}}\n", r, r)
+		}
+	}
+	return b.String()
+}
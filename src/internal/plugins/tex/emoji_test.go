@@ -0,0 +1,40 @@
+package tex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmojiDefinitions(t *testing.T) {
+	// Family ZWJ sequence, a skin-toned thumbs up, a keycap and a plain heart
+	text := "Family 👨‍👩‍👧 👍🏽 1️⃣ ❤️ ❤ done"
+
+	defs := EmojiDefinitions(text)
+
+	for _, want := range []string{
+		`\newunicodechar{👨}{{\emojifont\symbol{"1F468}}}`,
+		`\newunicodechar{👧}{{\emojifont\symbol{"1F467}}}`,
+		`\newunicodechar{👍}{{\emojifont\symbol{"1F44D}}}`,
+		`\newunicodechar{❤}{{\emojifont\symbol{"2764}}}`,
+		`\newunicodechar{^^^^200d}{}`,
+		`\newunicodechar{^^^^fe0f}{}`,
+		`\newunicodechar{^^^^20e3}{}`,
+		"\\newunicodechar{🏽}{}",
+	} {
+		if !strings.Contains(defs, want) {
+			t.Errorf("Expected %s in definitions:\n%s", want, defs)
+		}
+	}
+
+	// Each character is defined once, however often it appears
+	if n := strings.Count(defs, "{❤}"); n != 1 {
+		t.Errorf("Expected ❤ defined once, got %d", n)
+	}
+	if strings.Contains(defs, "{1}") || strings.Contains(defs, "{F}") {
+		t.Errorf("Expected plain text to be left alone:\n%s", defs)
+	}
+
+	if EmojiDefinitions("no emoji here") != "" {
+		t.Error("Expected no definitions for text without emoji")
+	}
+}
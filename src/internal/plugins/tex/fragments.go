@@ -0,0 +1,133 @@
+package tex
+
+import (
+	"encoding/json"
+	"strings"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+// cachedMessage is what writeMessages needs, besides its text, to use a message rendered by
+// the last incremental run again
+type cachedMessage struct {
+	Photos []indexedPhoto `json:"photos,omitempty"`
+	Seen   []string       `json:"seen,omitempty"` // Links whose preview card the message showed
+}
+
+// fragmentSettings are the settings a message's TeX is rendered from. Changing any other,
+// such as the title, theme or page size, reuses every cached message.
+type fragmentSettings struct {
+	ShowEffects   bool
+	URLFootnotes  bool
+	LinkStyle     string
+	IncludeImages bool
+	PhotoIndex    bool
+	PhotoLayout   string
+	Locale        string // The language of "sent with" under effects
+}
+
+// fragmentTemplates are the templates a message's TeX is rendered from
+var fragmentTemplates = []string{
+	"sent-message.tex", "received-message.tex", "link-preview.tex", "image-attachment.tex",
+	"image-placeholder.tex", "attachment.tex", "photo-thumbnail.tex",
+}
+
+// fragmentRun hashes what every message of a run is rendered from: the templates and the
+// settings above. It's empty, so nothing is cached, if a template can't be loaded.
+func fragmentRun(config *models.BookConfig, tm *output.TemplateManager) string {
+	templates, err := tm.Fingerprint(fragmentTemplates)
+	if err != nil {
+		return ""
+	}
+	return output.HashInputs(templates, fragmentSettings{
+		ShowEffects:   config.ShowEffects,
+		URLFootnotes:  config.URLFootnotes,
+		LinkStyle:     config.LinkStyle,
+		IncludeImages: config.IncludeImages,
+		PhotoIndex:    config.PhotoIndex,
+		PhotoLayout:   config.PhotoLayout,
+		Locale:        tm.Localizer().Locale(),
+	})
+}
+
+// messageInput is the part of a message its TeX is rendered from
+type messageInput struct {
+	ID          int
+	Date        string
+	IsFromMe    bool
+	Effect      string
+	Attachments []models.Attachment
+}
+
+// fragmentLink is a link in a message with the preview it would be shown with
+type fragmentLink struct {
+	URL       string
+	Thumbnail *output.URLThumbnail
+	Seen      bool // Its card was shown by an earlier message, so this one refers back to it
+}
+
+// fragmentLinks returns the links in a message's text that could get a preview card, with
+// whether they've been shown already
+func fragmentLinks(ctx *output.GenerationContext, text string, seenURLs map[string]bool) []fragmentLink {
+	if ctx.Config.URLFootnotes || len(ctx.URLThumbnails) == 0 {
+		return nil
+	}
+	var links []fragmentLink
+	for _, link := range output.LinkRegex.FindAllString(text, -1) {
+		link = output.TrimLink(link)
+		if thumbnail, exists := ctx.URLThumbnails[link]; exists {
+			links = append(links, fragmentLink{URL: link, Thumbnail: thumbnail, Seen: seenURLs[link]})
+		}
+	}
+	return links
+}
+
+// writeFragment writes a message with render, or with the TeX the last incremental run
+// rendered for it when nothing it's rendered from has changed. inputs are the values
+// writeMessages worked out for the message, such as its sender and text. It returns the
+// photos printed, and marks the links whose cards were shown as seen either way.
+func (p *TeXPlugin) writeFragment(builder *strings.Builder, ctx *output.GenerationContext, run string, msg models.Message,
+	text string, inputs []interface{}, seenURLs map[string]bool, render func(*strings.Builder) []indexedPhoto) []indexedPhoto {
+	if ctx.Fragments == nil || run == "" {
+		return render(builder)
+	}
+
+	links := fragmentLinks(ctx, text, seenURLs)
+	message := messageInput{
+		ID:          msg.ID,
+		Date:        msg.FormattedDate.Format("2006-01-02T15:04:05"),
+		IsFromMe:    msg.IsFromMe,
+		Effect:      msg.GetSendEffect(),
+		Attachments: msg.Attachments,
+	}
+	hash := output.HashInputs(append([]interface{}{run, message, text, links, output.GetAvatar(msg, ctx.Handles, ctx.Config)}, inputs...)...)
+	if fragment, found := ctx.Fragments.Lookup(msg.GUID, hash); found && hash != "" {
+		var cached cachedMessage
+		if err := json.Unmarshal(fragment.Data, &cached); err == nil {
+			builder.WriteString(fragment.Text)
+			for _, link := range cached.Seen {
+				seenURLs[link] = true
+			}
+			return cached.Photos
+		}
+	}
+
+	var fragment strings.Builder
+	printed := render(&fragment)
+	builder.WriteString(fragment.String())
+	if hash == "" {
+		return printed
+	}
+
+	cached := cachedMessage{Photos: printed}
+	for _, link := range links {
+		if !link.Seen && seenURLs[link.URL] {
+			cached.Seen = append(cached.Seen, link.URL)
+		}
+	}
+	if data, err := json.Marshal(cached); err == nil {
+		ctx.Fragments.Store(msg.GUID, &output.Fragment{Hash: hash, Text: fragment.String(), Data: data})
+	}
+	return printed
+}
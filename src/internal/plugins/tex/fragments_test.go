@@ -0,0 +1,76 @@
+package tex
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+func TestWriteMessagesFragments(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	handle := 1
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 7, GUID: "1", Text: text("Look https://example.com/"), IsFromMe: true, HasAttachments: true,
+				FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC),
+				Attachments:   []models.Attachment{{Filename: text("IMG_1.jpg"), ProcessedPath: "/tmp/IMG_1.jpg"}}},
+			{ID: 8, GUID: "2", Text: text("Seen it https://example.com/"), HandleID: &handle,
+				FormattedDate: time.Date(2023, 3, 4, 9, 5, 0, 0, time.UTC)},
+			{ID: 9, GUID: "3", Text: text("Dinner?"), HandleID: &handle,
+				FormattedDate: time.Date(2023, 3, 5, 18, 30, 0, 0, time.UTC)},
+		},
+		Handles: map[int]models.Handle{1: {ID: 1, Contact: "+15555550100", DisplayName: "Sam"}},
+		URLThumbnails: map[string]*output.URLThumbnail{
+			"https://example.com/": {URL: "https://example.com/", ThumbnailPath: "a.png", Success: true, Source: "download", Title: "Example"},
+		},
+		Config: &models.BookConfig{IncludeImages: true, PhotoIndex: true},
+	}
+	full := plugin.generateContent(ctx, tm)
+
+	path := filepath.Join(t.TempDir(), "book.fragments.json")
+	run := func() string {
+		cache, err := output.LoadFragmentCache(path)
+		if err != nil {
+			t.Fatalf("Failed to load cache: %v", err)
+		}
+		ctx.Fragments = cache
+		result := plugin.generateContent(ctx, tm)
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Failed to save cache: %v", err)
+		}
+		return result
+	}
+
+	if result := run(); result != full || ctx.Fragments.Misses() != 3 {
+		t.Errorf("Expected every message rendered as without the cache, got %d misses:\n%s", ctx.Fragments.Misses(), result)
+	}
+	// Reused messages still mark their previews seen and list their photos
+	if result := run(); result != full || ctx.Fragments.Hits() != 3 {
+		t.Errorf("Expected every message reused, got %d hits:\n%s", ctx.Fragments.Hits(), result)
+	}
+	if !strings.Contains(full, `\sharedagain{`) || !strings.Contains(full, `\pageref{photo:7-0}`) {
+		t.Errorf("Expected a shared-again link and a listed photo:\n%s", full)
+	}
+
+	// Only the changed message is rendered again, and a change in sender name reaches the
+	// messages it's shown on
+	ctx.Messages[2].Text = text("Dinner at 7?")
+	ctx.Handles[1] = models.Handle{ID: 1, Contact: "+15555550100", DisplayName: "Samantha"}
+	result := run()
+	if ctx.Fragments.Hits() != 1 || ctx.Fragments.Misses() != 2 {
+		t.Errorf("Expected 1 message reused and 2 rendered, got %d and %d", ctx.Fragments.Hits(), ctx.Fragments.Misses())
+	}
+	if !strings.Contains(result, "Dinner at 7?") || !strings.Contains(result, "Samantha") {
+		t.Errorf("Expected the changes rendered:\n%s", result)
+	}
+}
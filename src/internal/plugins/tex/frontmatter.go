@@ -0,0 +1,133 @@
+package tex
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"threadbound/internal/output"
+)
+
+var (
+	// Markdown headings, list items and emphasis, the parts of markdown a foreword uses
+	markdownHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	markdownListItem = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	markdownEmphasis = regexp.MustCompile(`\*\*(.+?)\*\*|\*(.+?)\*`)
+)
+
+// generateFrontMatter writes the dedication and foreword pages that come between the
+// copyright page and the first chapter. A book split into volumes has them only in the first.
+func (p *TeXPlugin) generateFrontMatter(ctx *output.GenerationContext, tm *output.TemplateManager) (string, error) {
+	if ctx.Volume != nil && ctx.Volume.Number > 1 {
+		return "", nil
+	}
+	var builder strings.Builder
+
+	if dedication := strings.TrimSpace(ctx.Config.Dedication); dedication != "" {
+		// Each line of the dedication is a line of its own on the page
+		text := strings.ReplaceAll(p.escapeLaTeX(dedication), "\n", "\\\\\n")
+		result, err := tm.ExecuteTemplate("dedication-page.tex", text)
+		if err != nil {
+			result = fmt.Sprintf("\\clearpage\n\\thispagestyle{empty}\n\\vspace*{0.3\\textheight}\n\\begin{center}\\itshape\n%s\n\\end{center}\n\\clearpage", text)
+		}
+		builder.WriteString(result)
+		builder.WriteString("\n\n")
+	}
+
+	if ctx.Config.ForewordPath != "" {
+		source, err := os.ReadFile(ctx.Config.ForewordPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read foreword: %w", err)
+		}
+		// TeX forewords are used as they are; anything else is markdown
+		text := string(source)
+		if !strings.EqualFold(filepath.Ext(ctx.Config.ForewordPath), ".tex") {
+			text = p.markdownToTeX(text)
+		}
+		result, err := tm.ExecuteTemplate("foreword.tex", text)
+		if err != nil {
+			foreword := p.escapeLaTeX(tm.Localizer().T("foreword"))
+			result = fmt.Sprintf("\\chapter*{%s}\n\\markboth{%s}{}\n\\pdfbookmark[0]{%s}{foreword}\n\n", foreword, foreword, foreword) + text
+		}
+		builder.WriteString(result)
+		builder.WriteString("\n\n")
+	}
+
+	return builder.String(), nil
+}
+
+// markdownToTeX converts the markdown of a foreword: paragraphs, headings, bulleted lists,
+// **bold** and *italics*. Everything else is printed as written.
+func (p *TeXPlugin) markdownToTeX(markdown string) string {
+	var builder strings.Builder
+	var paragraph []string
+	inList := false
+
+	endParagraph := func() {
+		if len(paragraph) > 0 {
+			builder.WriteString(strings.Join(paragraph, "\n") + "\n\n")
+			paragraph = nil
+		}
+	}
+	endList := func() {
+		if inList {
+			builder.WriteString("\\end{itemize}\n\n")
+			inList = false
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			endParagraph()
+			endList()
+		case markdownHeading.MatchString(line):
+			endParagraph()
+			endList()
+			match := markdownHeading.FindStringSubmatch(line)
+			// Top-level headings are sections; the foreword itself is the chapter
+			command := "section"
+			if len(match[1]) > 1 {
+				command = "subsection"
+			}
+			builder.WriteString(fmt.Sprintf("\\%s*{%s}\n\n", command, p.markdownInline(match[2])))
+		case markdownListItem.MatchString(line):
+			endParagraph()
+			if !inList {
+				builder.WriteString("\\begin{itemize}\n")
+				inList = true
+			}
+			builder.WriteString("\\item " + p.markdownInline(markdownListItem.FindStringSubmatch(line)[1]) + "\n")
+		default:
+			// A line after a list item continues it
+			if inList {
+				builder.WriteString(p.markdownInline(line) + "\n")
+				continue
+			}
+			paragraph = append(paragraph, p.markdownInline(line))
+		}
+	}
+	endParagraph()
+	endList()
+	return strings.TrimSpace(builder.String())
+}
+
+// markdownInline escapes a line of markdown for TeX, keeping its bold and italics
+func (p *TeXPlugin) markdownInline(text string) string {
+	var builder strings.Builder
+	last := 0
+	for _, match := range markdownEmphasis.FindAllStringSubmatchIndex(text, -1) {
+		builder.WriteString(p.escapeLaTeX(text[last:match[0]]))
+		if match[2] >= 0 {
+			builder.WriteString("\\textbf{" + p.escapeLaTeX(text[match[2]:match[3]]) + "}")
+		} else {
+			builder.WriteString("\\emph{" + p.escapeLaTeX(text[match[4]:match[5]]) + "}")
+		}
+		last = match[1]
+	}
+	builder.WriteString(p.escapeLaTeX(text[last:]))
+	return builder.String()
+}
@@ -0,0 +1,59 @@
+package tex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+func TestGenerateFrontMatter(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	foreword := filepath.Join(t.TempDir(), "foreword.md")
+	os.WriteFile(foreword, []byte("# How this started\n\nWe met in *2019* & never\nstopped **texting**.\n\n- 50% jokes\n- the rest plans\n"), 0644)
+	ctx := &output.GenerationContext{
+		Config: &models.BookConfig{Dedication: "For Sam\nwho kept every message", ForewordPath: foreword},
+	}
+
+	result, err := plugin.generateFrontMatter(ctx, tm)
+	if err != nil {
+		t.Fatalf("Failed to generate front matter: %v", err)
+	}
+	for _, want := range []string{
+		"For Sam\\\\\nwho kept every message",
+		"\\chapter*{Foreword}",
+		"\\pdfbookmark[0]{Foreword}{foreword}",
+		"\\section*{How this started}",
+		"We met in \\emph{2019} \\& never\nstopped \\textbf{texting}.",
+		"\\begin{itemize}\n\\item 50\\% jokes\n\\item the rest plans\n\\end{itemize}",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in front matter:\n%s", want, result)
+		}
+	}
+	if strings.Index(result, "For Sam") > strings.Index(result, "Foreword") {
+		t.Error("Expected the dedication before the foreword")
+	}
+
+	// TeX forewords are used as written
+	texForeword := filepath.Join(t.TempDir(), "foreword.tex")
+	os.WriteFile(texForeword, []byte("Hello \\emph{there} 100%"), 0644)
+	ctx.Config = &models.BookConfig{ForewordPath: texForeword}
+	if result, _ := plugin.generateFrontMatter(ctx, tm); !strings.Contains(result, "Hello \\emph{there} 100%") {
+		t.Errorf("Expected the TeX foreword unchanged:\n%s", result)
+	}
+
+	// Later volumes go straight to their chapters
+	ctx.Volume = &output.Volume{Number: 2, Count: 2}
+	if result, _ := plugin.generateFrontMatter(ctx, tm); result != "" {
+		t.Errorf("Expected no front matter in a later volume, got:\n%s", result)
+	}
+}
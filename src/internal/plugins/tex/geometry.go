@@ -0,0 +1,98 @@
+package tex
+
+import (
+	"fmt"
+	"strings"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+	"threadbound/internal/printing"
+)
+
+// generateGeometry writes the page size and margins. With a print preset it also adds
+// bleed, sets each page's TrimBox and BleedBox, and marks the PDF for PDF/X preflight.
+func (p *TeXPlugin) generateGeometry(ctx *output.GenerationContext) (string, error) {
+	layout, preset, pages, err := pageLayout(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\\usepackage[\n    paperwidth=%s,\n    paperheight=%s,\n    inner=%s,\n    outer=%s,\n    top=%s,\n    bottom=%s\n]{geometry}\n",
+		inches(layout.PaperWidth()), inches(layout.PaperHeight()),
+		inches(layout.Inner), inches(layout.Outer), inches(layout.Top), inches(layout.Bottom))
+
+	if preset == nil {
+		return b.String(), nil
+	}
+
+	ctx.Config.Log().Info("🖨️  Print layout", "preset", preset.Name, "trim_width", inches(layout.TrimWidth),
+		"trim_height", inches(layout.TrimHeight), "bleed", inches(layout.Bleed), "inside_margin", inches(layout.Inner), "pages", pages)
+
+	// Page boxes depend on which side of the spread a page falls, so count physical pages
+	// rather than trusting the page number
+	fmt.Fprintf(&b, "\n%% Print-ready output for %s (about %d pages)\n", preset.Name, pages)
+	b.WriteString("\\usepackage{atbegshi}\n\\newcount\\physicalpage\n")
+	b.WriteString("\\AtBeginShipout{\\global\\advance\\physicalpage by 1 \\ifodd\\physicalpage\n")
+	b.WriteString("    " + pageBoxes(layout, true) + "\n\\else\n    " + pageBoxes(layout, false) + "\n\\fi}\n")
+
+	// Declare the document untrapped and intended for a registered print condition
+	b.WriteString("\\AtBeginDocument{%\n")
+	b.WriteString("    \\special{pdf:docinfo << /Trapped /False /GTS_PDFXVersion (PDF/X-3:2002) >>}%\n")
+	b.WriteString("    \\special{pdf:obj @outputintent << /Type /OutputIntent /S /GTS_PDFX /OutputCondition (SWOP) /OutputConditionIdentifier (CGATS TR 001) /RegistryName (http://www.color.org) >>}%\n")
+	b.WriteString("    \\special{pdf:put @catalog << /OutputIntents [ @outputintent ] >>}%\n")
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// pageLayout works out the layout for the configured page size and print preset. The
+// preset and page count are only returned when a preset is in use.
+func pageLayout(ctx *output.GenerationContext) (printing.Layout, *printing.Preset, int, error) {
+	width, height := ctx.Config.PageWidth, ctx.Config.PageHeight
+	if width == "" {
+		width = "5.5in"
+	}
+	if height == "" {
+		height = "8.5in"
+	}
+	trimWidth, err := printing.ParseLength(width)
+	if err != nil {
+		return printing.Layout{}, nil, 0, fmt.Errorf("page width: %w", err)
+	}
+	trimHeight, err := printing.ParseLength(height)
+	if err != nil {
+		return printing.Layout{}, nil, 0, fmt.Errorf("page height: %w", err)
+	}
+
+	if ctx.Config.PrintPreset == "" {
+		return printing.DesignLayout(trimWidth, trimHeight), nil, 0, nil
+	}
+
+	preset, err := printing.Lookup(ctx.Config.PrintPreset)
+	if err != nil {
+		return printing.Layout{}, nil, 0, err
+	}
+	pages := ctx.Config.PrintPages
+	if pages <= 0 {
+		pages = printing.EstimatePages(ctx.Messages, ctx.Config.IncludeImages)
+	}
+	return preset.Layout(trimWidth, trimHeight, pages), &preset, pages, nil
+}
+
+// pageBoxes sets the TrimBox and BleedBox of the page being shipped out
+func pageBoxes(layout printing.Layout, recto bool) string {
+	trim := layout.TrimBox(recto)
+	return fmt.Sprintf("\\special{pdf:put @thispage << /TrimBox [%.2f %.2f %.2f %.2f] /BleedBox [0 0 %.2f %.2f] >>}",
+		trim[0], trim[1], trim[2], trim[3], layout.PaperWidth()*72, layout.PaperHeight()*72)
+}
+
+// inches formats a length in inches for TeX, without trailing zeros
+func inches(value float64) string {
+	return fmt.Sprintf("%gin", float64(int(value*1000+0.5))/1000)
+}
+
+// validatePrintPreset checks the preset name and page size before any output is written
+func validatePrintPreset(config *models.BookConfig) error {
+	_, _, _, err := pageLayout(&output.GenerationContext{Config: config})
+	return err
+}
@@ -0,0 +1,46 @@
+package tex
+
+import (
+	"strings"
+	"testing"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+func TestGenerateGeometry(t *testing.T) {
+	plugin := NewTeXPlugin()
+
+	design, err := plugin.generateGeometry(&output.GenerationContext{Config: &models.BookConfig{PageWidth: "5.5in", PageHeight: "8.5in"}})
+	if err != nil {
+		t.Fatalf("generateGeometry failed: %v", err)
+	}
+	for _, want := range []string{"paperwidth=5.5in", "paperheight=8.5in", "inner=0.5in", "top=0.6in"} {
+		if !strings.Contains(design, want) {
+			t.Errorf("Expected %s in geometry:\n%s", want, design)
+		}
+	}
+	if strings.Contains(design, "TrimBox") {
+		t.Errorf("Expected no page boxes without a preset:\n%s", design)
+	}
+
+	kdp, err := plugin.generateGeometry(&output.GenerationContext{
+		Config: &models.BookConfig{PageWidth: "5.5in", PageHeight: "8.5in", PrintPreset: "kdp", PrintPages: 320},
+	})
+	if err != nil {
+		t.Fatalf("generateGeometry failed: %v", err)
+	}
+	for _, want := range []string{
+		"paperwidth=5.625in", "paperheight=8.75in", "inner=0.625in", "outer=0.625in",
+		"/TrimBox [0.00 9.00 396.00 621.00]", "/TrimBox [9.00 9.00 405.00 621.00]",
+		"/GTS_PDFXVersion", "/OutputIntents",
+	} {
+		if !strings.Contains(kdp, want) {
+			t.Errorf("Expected %s in KDP geometry:\n%s", want, kdp)
+		}
+	}
+
+	if err := validatePrintPreset(&models.BookConfig{PrintPreset: "blurb"}); err == nil {
+		t.Error("Expected error for unknown preset")
+	}
+}
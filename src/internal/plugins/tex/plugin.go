@@ -1,6 +1,7 @@
 package tex
 
 import (
+	"crypto/md5"
 	"database/sql"
 	"embed"
 	"fmt"
@@ -8,10 +9,14 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"threadbound/internal/analytics"
+	"threadbound/internal/i18n"
+	"threadbound/internal/latex"
 	"threadbound/internal/models"
 	"threadbound/internal/output"
 	"threadbound/internal/urlprocessor"
@@ -20,6 +25,12 @@ import (
 //go:embed templates/*
 var embeddedTemplates embed.FS
 
+// Templates returns the built-in templates, keyed by file name such as book.tex
+func Templates() fs.FS {
+	templates, _ := fs.Sub(embeddedTemplates, "templates")
+	return templates
+}
+
 // TeXPlugin implements the OutputPlugin interface for TeX generation
 type TeXPlugin struct {
 	*output.BasePlugin
@@ -53,16 +64,17 @@ func NewTeXPlugin() *TeXPlugin {
 func (p *TeXPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
 	// Create template manager with embedded templates
 	tm := output.NewTemplateManagerWithEmbed(ctx.Config.TemplateDir, embeddedTemplates, "templates")
+	tm.Localize(output.Localizer(ctx.Config))
 
 	// Load all required templates
 	if err := tm.LoadTemplates(p.GetRequiredTemplates()); err != nil {
 		return nil, fmt.Errorf("failed to load templates: %w", err)
 	}
 
-	// Process URLs if enabled
-	if ctx.Config.IncludePreviews {
+	// Process URLs if enabled; footnoted URLs don't need previews
+	if ctx.Config.IncludePreviews && !ctx.Config.URLFootnotes {
 		if err := p.processURLs(ctx); err != nil {
-			fmt.Printf("⚠️  Warning: URL processing failed: %v\n", err)
+			ctx.Config.Log().Warn("⚠️  URL processing failed", "error", err)
 		}
 	}
 
@@ -77,6 +89,8 @@ func (p *TeXPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
 
 // processURLs finds and processes all URLs in messages
 func (p *TeXPlugin) processURLs(ctx *output.GenerationContext) error {
+	defer ctx.Timings.Start("url previews")()
+
 	// Create a database connection for URL processing
 	db, err := sql.Open("sqlite3", ctx.Config.DatabasePath)
 	if err != nil {
@@ -85,47 +99,13 @@ func (p *TeXPlugin) processURLs(ctx *output.GenerationContext) error {
 	defer db.Close()
 
 	urlProcessor := urlprocessor.New(ctx.Config, db)
-	processedURLs := make(map[string]bool)
-
-	fmt.Printf("🔗 Processing URLs using existing iMessage preview data...\n")
-
-	// Process each message that might have URL previews
-	for _, msg := range ctx.Messages {
-		if msg.Text != nil {
-			urls := urlProcessor.FindURLsInText(*msg.Text)
-			if len(urls) > 0 {
-				// Extract existing preview data from this message
-				messagePreviews := urlProcessor.ProcessMessageForURLPreviews(int64(msg.ID))
-				for url, thumbnail := range messagePreviews {
-					if !processedURLs[url] {
-						ctx.URLThumbnails[url] = thumbnail
-						processedURLs[url] = true
-						if thumbnail.Success {
-							fmt.Printf("✅ Found existing preview for: %s (title: %s)\n", url, thumbnail.Title)
-						} else {
-							fmt.Printf("⚠️  No preview data found for: %s\n", url)
-						}
-					}
-				}
 
-				// For URLs without existing preview data, try the fallback method
-				for _, url := range urls {
-					if !processedURLs[url] {
-						thumbnail := urlProcessor.ProcessURL(url)
-						ctx.URLThumbnails[url] = thumbnail
-						processedURLs[url] = true
-						if thumbnail.Success {
-							fmt.Printf("✅ Generated fallback thumbnail for: %s\n", url)
-						} else {
-							fmt.Printf("⚠️  Failed to generate thumbnail for: %s\n", url)
-						}
-					}
-				}
-			}
-		}
+	ctx.Config.Log().Info("🔗 Processing URLs using existing iMessage preview data...")
+	for url, thumbnail := range urlProcessor.ProcessAll(ctx.Messages, urlprocessor.LimitsFromConfig(ctx.Config)) {
+		ctx.URLThumbnails[url] = thumbnail
 	}
 
-	fmt.Printf("🔗 Processed %d unique URLs\n", len(ctx.URLThumbnails))
+	ctx.Config.Log().Info("🔗 Processed unique URLs", "count", len(ctx.URLThumbnails))
 	return nil
 }
 
@@ -150,21 +130,79 @@ func (p *TeXPlugin) generateBook(ctx *output.GenerationContext, tm *output.Templ
 	}
 
 	// Generate each component
+	geometry, err := p.generateGeometry(ctx)
+	if err != nil {
+		return "", err
+	}
 	variables := p.generateVariables(ctx)
+	pdfMetadata := p.generatePDFMetadata(ctx, time.Now())
 	titlePage := p.generateTitlePage(ctx)
 	copyrightPage := p.generateCopyrightPage(ctx)
+	frontMatter, err := p.generateFrontMatter(ctx, tm)
+	if err != nil {
+		return "", err
+	}
+	calendarPage := p.generateCalendarPage(ctx, tm)
 	content := p.generateContent(ctx, tm)
+	pageStructure := p.generatePageStructure(ctx, tm)
+
+	// Chapters open on the next page unless they're to start on the right
+	chapterOpening := "openany"
+	if ctx.Config.RectoChapters {
+		chapterOpening = "openright"
+	}
 
 	// Replace placeholders in template
 	result := string(templateBytes)
+	result = strings.ReplaceAll(result, "%%CHAPTER_OPENING%%", chapterOpening)
+	result = strings.ReplaceAll(result, "%%GEOMETRY%%", geometry)
+	result = strings.ReplaceAll(result, "%%THEME%%", themeDefinitions(ctx.Config.Theme))
 	result = strings.ReplaceAll(result, "%%VARIABLES%%", variables)
+	result = strings.ReplaceAll(result, "%%PDF_METADATA%%", pdfMetadata)
 	result = strings.ReplaceAll(result, "%%TITLE_PAGE%%", titlePage)
 	result = strings.ReplaceAll(result, "%%COPYRIGHT_PAGE%%", copyrightPage)
+	result = strings.ReplaceAll(result, "%%FRONT_MATTER%%", frontMatter)
+	result = strings.ReplaceAll(result, "%%CALENDAR_PAGE%%", calendarPage)
+	result = strings.ReplaceAll(result, "%%PAGE_STRUCTURE%%", pageStructure)
 	result = strings.ReplaceAll(result, "%%CONTENT%%", content)
 
+	// Map exactly the emoji that appear anywhere in the book to the emoji font, and set up
+	// the scripts it's written in that need fonts of their own
+	body := titlePage + copyrightPage + frontMatter + content
+	result = strings.ReplaceAll(result, "%%EMOJI_DEFINITIONS%%", EmojiDefinitions(body))
+	result = strings.ReplaceAll(result, "%%SCRIPTS%%", ScriptDefinitions(ctx.Config.Title+body, ctx.Config.Theme))
+
 	return result, nil
 }
 
+// generatePageStructure writes the table of contents, unless it's hidden
+func (p *TeXPlugin) generatePageStructure(ctx *output.GenerationContext, tm *output.TemplateManager) string {
+	depth := ctx.Config.TOCDepth
+	if depth == 0 {
+		depth = models.MaxTOCDepth
+	}
+	// LaTeX counts chapters as level 0 and the days' sections as 1
+	data := struct {
+		TOC   bool
+		Depth int
+	}{TOC: !ctx.Config.HideTOC, Depth: depth - 1}
+
+	result, err := tm.ExecuteTemplate("page-structure.tex", data)
+	if err != nil {
+		if ctx.Config.HideTOC {
+			return ""
+		}
+		return "\\tableofcontents\n\\newpage\n"
+	}
+	return result
+}
+
+// texPath converts a filesystem path to the forward-slash form TeX requires,
+// since backslashes start control sequences even on Windows
+func texPath(path string) string {
+	return filepath.ToSlash(path)
+}
+
 // readTemplateFile reads a template file as raw bytes
 func readTemplateFile(path string) ([]byte, error) {
 	return os.ReadFile(path)
@@ -173,17 +211,43 @@ func readTemplateFile(path string) ([]byte, error) {
 // generateVariables creates LaTeX variable definitions
 func (p *TeXPlugin) generateVariables(ctx *output.GenerationContext) string {
 	var builder strings.Builder
+	l := output.Localizer(ctx.Config)
 
 	builder.WriteString(fmt.Sprintf("\\newcommand{\\booktitle}{%s}\n", p.escapeLaTeX(ctx.Config.Title)))
 	if ctx.Config.Author != "" {
 		builder.WriteString(fmt.Sprintf("\\newcommand{\\bookauthor}{%s}\n", p.escapeLaTeX(ctx.Config.Author)))
 	}
-	builder.WriteString(fmt.Sprintf("\\newcommand{\\bookdate}{%s}\n", time.Now().Format("January 2, 2006")))
+	builder.WriteString(fmt.Sprintf("\\newcommand{\\bookdate}{%s}\n", l.Format(time.Now(), i18n.DateFormat)))
 	builder.WriteString(fmt.Sprintf("\\newcommand{\\bookyear}{%d}\n", time.Now().Year()))
 
+	// Headings LaTeX writes itself, in the book's language
+	builder.WriteString(fmt.Sprintf("\\renewcommand{\\contentsname}{%s}\n", p.escapeLaTeX(l.T("contents"))))
+	builder.WriteString(fmt.Sprintf("\\renewcommand{\\chaptername}{%s}\n", p.escapeLaTeX(l.T("chapter"))))
+
+	return builder.String()
+}
+
+// generatePDFMetadata fills in the PDF's document information for readers and asset
+// managers. Chapters and days get their bookmarks from their headings.
+func (p *TeXPlugin) generatePDFMetadata(ctx *output.GenerationContext, now time.Time) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("    pdftitle={%s},\n", p.escapeLaTeX(ctx.Config.Title)))
+	if ctx.Config.Author != "" {
+		builder.WriteString(fmt.Sprintf("    pdfauthor={%s},\n", p.escapeLaTeX(ctx.Config.Author)))
+	}
+	builder.WriteString("    pdfcreator={threadbound},\n")
+	builder.WriteString(fmt.Sprintf("    pdfcreationdate={%s},\n", pdfDate(now)))
+
 	return builder.String()
 }
 
+// pdfDate formats t as a PDF date string, D:YYYYMMDDHHmmSS+HH'mm'
+func pdfDate(t time.Time) string {
+	zone := t.Format("-0700")
+	return fmt.Sprintf("D:%s%s'%s'", t.Format("20060102150405"), zone[:3], zone[3:])
+}
+
 // generateTitlePage creates the title page content
 func (p *TeXPlugin) generateTitlePage(ctx *output.GenerationContext) string {
 	var builder strings.Builder
@@ -199,16 +263,29 @@ func (p *TeXPlugin) generateTitlePage(ctx *output.GenerationContext) string {
 		builder.WriteString("\\vspace{1cm}\n\n")
 	}
 
+	if ctx.Volume != nil {
+		l := output.Localizer(ctx.Config)
+		builder.WriteString(fmt.Sprintf("{\\Large %s}\n\n", p.escapeLaTeX(l.T("volume", "Number", ctx.Volume.Number))))
+		builder.WriteString(fmt.Sprintf("{\\large %s}\n\n", p.escapeLaTeX(ctx.Volume.RangeIn(l))))
+		builder.WriteString("\\vspace{1cm}\n\n")
+	}
+
 	builder.WriteString("{\\large \\bookdate}\n\n")
 	builder.WriteString("\\vfill\n")
 	builder.WriteString("\\end{titlepage}\n")
 
+	// Carry on from the previous volume's last page; the title page counts as the first
+	if ctx.Volume != nil && ctx.Volume.FirstPage > 1 {
+		builder.WriteString(fmt.Sprintf("\\setcounter{page}{%d}\n", ctx.Volume.FirstPage+1))
+	}
+
 	return builder.String()
 }
 
 // generateCopyrightPage creates the copyright page content
 func (p *TeXPlugin) generateCopyrightPage(ctx *output.GenerationContext) string {
 	var builder strings.Builder
+	l := output.Localizer(ctx.Config)
 
 	builder.WriteString("\\newpage\n\n")
 	builder.WriteString("\\thispagestyle{empty}\n\n")
@@ -222,55 +299,195 @@ func (p *TeXPlugin) generateCopyrightPage(ctx *output.GenerationContext) string
 	}
 
 	builder.WriteString("\\\\[0.5cm]\n\n")
-	builder.WriteString("This book contains personal messages and conversations.\n")
-	builder.WriteString("All rights reserved. No part of this publication may be reproduced,\n")
-	builder.WriteString("distributed, or transmitted in any form or by any means without\n")
-	builder.WriteString("the prior written permission of the copyright holder.\n\n")
-	builder.WriteString("Generated using threadbound.\n")
+	builder.WriteString(p.escapeLaTeX(l.T("copyright_notice")) + "\n\n")
+	if ctx.Volume != nil {
+		volume := l.T("volume_of", "Number", ctx.Volume.Number, "Count", ctx.Volume.Count)
+		builder.WriteString(fmt.Sprintf("%s, %s.\n\n", p.escapeLaTeX(volume), p.escapeLaTeX(ctx.Volume.RangeIn(l))))
+	}
+	builder.WriteString(p.escapeLaTeX(l.T("generated_with")) + "\n")
 	builder.WriteString("\\end{flushleft}\n\n")
 	builder.WriteString("\\newpage\n")
 
 	return builder.String()
 }
 
+// generateCalendarPage creates the front-matter page with a calendar heatmap of every year
+func (p *TeXPlugin) generateCalendarPage(ctx *output.GenerationContext, tm *output.TemplateManager) string {
+	if !ctx.Config.CalendarPage {
+		return ""
+	}
+	calendars := calendarCharts(analytics.Calendars(ctx.Messages), tm.Localizer())
+	if len(calendars) == 0 {
+		return ""
+	}
+
+	result, err := tm.ExecuteTemplate("calendar-page.tex", struct{ Calendars []calendarChart }{calendars})
+	if err != nil {
+		// Fallback to bare charts
+		var builder strings.Builder
+		for _, calendar := range calendars {
+			builder.WriteString(fmt.Sprintf("\\resizebox{\\linewidth}{!}{%%\n%s}\n\n", calendar.Chart))
+		}
+		builder.WriteString("\\newpage\n")
+		return builder.String()
+	}
+	return result
+}
+
+// calendarChart is one year's calendar heatmap, ready for a template
+type calendarChart struct {
+	Year     int
+	Messages int
+	Chart    string
+}
+
+// calendarCharts draws a calendar heatmap for each year, with months named in l's locale
+func calendarCharts(calendars []analytics.CalendarYear, l *i18n.Localizer) []calendarChart {
+	charts := make([]calendarChart, len(calendars))
+	for i, calendar := range calendars {
+		messages := 0
+		for _, day := range calendar.Days {
+			messages += day.Count
+		}
+		charts[i] = calendarChart{Year: calendar.Year, Messages: messages, Chart: CalendarHeatmap(calendar, l)}
+	}
+	return charts
+}
+
 // generateContent creates the main message content
 func (p *TeXPlugin) generateContent(ctx *output.GenerationContext, tm *output.TemplateManager) string {
 	var builder strings.Builder
-	p.writeMessages(&builder, ctx, tm)
+	overflows, photos := p.writeMessages(&builder, ctx, tm)
+
+	if ctx.Config.GreatestHits > 0 {
+		p.writeGreatestHits(&builder, ctx, tm)
+	}
+	if ctx.Config.StatsChapter {
+		p.writeNumbers(&builder, ctx, tm)
+	}
+	p.writeOverflowAppendix(&builder, tm, overflows)
+	p.writePhotoIndex(&builder, tm, photos)
+
+	// The end of the book is also the end of the final year
+	notes := ctx.Config.NotesPages
+	if notes.At(models.NotesAtEnd) || notes.At(models.NotesAtYear) {
+		p.writeNotesPages(&builder, tm, notes)
+	}
 	return builder.String()
 }
 
-// writeMessages writes all messages in conversation format
-func (p *TeXPlugin) writeMessages(builder *strings.Builder, ctx *output.GenerationContext, tm *output.TemplateManager) {
+// writeMessages writes all messages in conversation format and returns the long messages
+// it cut short, for the appendix, and the photos it labelled, for the List of Photos
+func (p *TeXPlugin) writeMessages(builder *strings.Builder, ctx *output.GenerationContext, tm *output.TemplateManager) ([]output.Overflow, []indexedPhoto) {
+	var overflows []output.Overflow
+	var photos []indexedPhoto
+	var plates []indexedPhoto // Photos for the insert ending the chapter being written
+	inserts := ctx.Config.PhotoInserts()
 	var lastDate string
-	var lastMonth string
+	var lastMessage time.Time // When the last message was sent, to mark long silences
+	var chapterKey string     // Key of the chapter being written; empty before the first
 	var lastSender string
 	var lastTimestamp string
+	var lastYear int
+
+	// URLs whose preview card has already been shown
+	seenURLs := make(map[string]bool)
 
-	for _, msg := range ctx.Messages {
-		// Skip empty messages
-		if msg.Text == nil || strings.TrimSpace(*msg.Text) == "" {
+	// What every message is rendered from, for reusing the last incremental run's output
+	var run string
+	if ctx.Fragments != nil {
+		run = fragmentRun(ctx.Config, tm)
+	}
+
+	chapters := ctx.Config.Chapters()
+	messages := ctx.Messages
+	if chapters == models.ChaptersByChat {
+		messages = groupByChat(messages)
+	}
+
+	// Stats boxes sum up months
+	var chapterStats map[string]*analytics.ChapterStats
+	if ctx.Config.ChapterStats && chapters == models.ChaptersByMonth {
+		chapterStats = analytics.ByChapter(ctx.Messages)
+	}
+
+	// Word cloud pages, by year or the whole book's as year 0, each written once
+	clouds := make(map[int]analytics.WordCloud)
+	if ctx.Config.WordCloud != "" {
+		for _, cloud := range analytics.WordClouds(ctx.Messages, ctx.Config.WordCloud, tm.Localizer().IsStopword) {
+			clouds[cloud.Year] = cloud
+		}
+	}
+
+	for _, msg := range messages {
+		// Skip empty messages; memories have no text but still get a page
+		if msg.Memory == nil && (msg.Text == nil || strings.TrimSpace(*msg.Text) == "") {
 			continue
 		}
+		cloudYear := 0
+		if ctx.Config.WordCloud == models.WordCloudYear {
+			cloudYear = msg.FormattedDate.Year()
+		}
 
-		// Add month chapter header if month changed
-		currentMonth := msg.FormattedDate.Format("January 2006")
-		if currentMonth != lastMonth {
-			builder.WriteString(fmt.Sprintf("\n\\chapter{%s}\n\n", p.escapeLaTeX(currentMonth)))
-			lastMonth = currentMonth
+		// Start a chapter when the message belongs to a new one
+		if key, title := p.chapterOf(msg, chapters, ctx.ChatNames, tm.Localizer()); key != chapterKey {
+			if chapterKey != "" {
+				// Pages between chapters, such as notes, aren't part of anyone's run
+				builder.WriteString("\\clearspeaker\n")
+				p.writeChapterStats(builder, tm, chapterStats, chapterKey)
+				p.writePhotoInsert(builder, tm, plates)
+				plates = nil
+				builder.WriteString(chapterEndMarker + chapterKey + "\n")
+			}
+
+			// Leave pages for notes after the last chapter of each year
+			currentYear := msg.FormattedDate.Year()
+			if lastYear != 0 && currentYear != lastYear && ctx.Config.NotesPages.At(models.NotesAtYear) {
+				p.writeNotesPages(builder, tm, ctx.Config.NotesPages)
+			}
+			lastYear = currentYear
+			// A word cloud divides the book before the first chapter it covers
+			p.writeWordCloud(builder, tm, clouds, cloudYear)
+
+			chapterKey = key
+			builder.WriteString("\n" + chapterStartMarker + key + "\n")
+			builder.WriteString(fmt.Sprintf("\\chapter{%s}\n\n", p.escapeLaTeX(title)))
+			p.writeChapterSummary(builder, tm, ctx.Summaries[key])
+			// Each chapter opens with the heading of its first day
+			lastDate = ""
+			// Silences are marked within chapters, whose headings already mark a break, and
+			// chapters can be patched on their own
+			lastMessage = time.Time{}
 		}
 
 		// Add date section header if day changed
-		currentDate := msg.FormattedDate.Format("Monday, January 2, 2006")
+		currentDate := tm.Localizer().Format(msg.FormattedDate, i18n.DayFormat)
 		if currentDate != lastDate {
-			builder.WriteString(fmt.Sprintf("\n\\section{%s}\n\n", p.escapeLaTeX(currentDate)))
+			// Without chapters, a word cloud divides the book before the first day it covers
+			if chapters == models.ChaptersNone {
+				p.writeWordCloud(builder, tm, clouds, cloudYear)
+			}
+			// Keep a heading from the foot of a page with nothing of its day below it
+			builder.WriteString(fmt.Sprintf("\n\\needspace{%d\\baselineskip}\n", ctx.Config.DaySpace()))
+			if !lastMessage.IsZero() {
+				p.writeDayGap(builder, tm, output.GapLabel(lastMessage, msg.FormattedDate, ctx.Config.GapDays, tm.Localizer()))
+			}
+			builder.WriteString(fmt.Sprintf("\\section{%s}\n", p.escapeLaTeX(currentDate)))
+			builder.WriteString(fmt.Sprintf("\\daymark{%s}\n\n", p.escapeLaTeX(tm.Localizer().Format(msg.FormattedDate, i18n.ShortDate))))
 			lastDate = currentDate
 			lastSender = ""
 			lastTimestamp = ""
 		}
+		lastMessage = msg.FormattedDate
+
+		if msg.Memory != nil {
+			p.writeMemory(builder, tm, msg)
+			lastSender = ""
+			continue
+		}
 
 		// Determine sender
-		senderName := output.GetSenderNameWithConfig(msg, ctx.Handles, ctx.Config)
+		senderName := output.GetSenderName(msg, ctx.Handles, ctx.Config)
 
 		// Check if we should show sender name (when it changes)
 		showSender := (senderName != lastSender)
@@ -279,7 +496,7 @@ func (p *TeXPlugin) writeMessages(builder *strings.Builder, ctx *output.Generati
 		}
 
 		// Format time
-		timeStr := msg.FormattedDate.Format("3:04 PM")
+		timeStr := tm.Localizer().Format(msg.FormattedDate, i18n.TimeFormat)
 
 		// Check if we should show timestamp (when sender changes or timestamp changes)
 		showTimestamp := showSender || (timeStr != lastTimestamp)
@@ -290,54 +507,389 @@ func (p *TeXPlugin) writeMessages(builder *strings.Builder, ctx *output.Generati
 		// Get reactions for this message
 		messageReactions := ctx.Reactions[msg.GUID]
 
-		// Write message content
-		p.writeMessageBubble(builder, ctx, tm, msg, *msg.Text, timeStr, senderName, showSender, showTimestamp, messageReactions)
+		// Cut long messages short; the full text goes in the appendix
+		text := output.PolishText(*msg.Text, ctx.Config)
+		overflowLabel := ""
+		if cut, truncated := output.TruncateLines(text, ctx.Config.OverflowLines); truncated {
+			overflows = append(overflows, output.Overflow{Number: len(overflows) + 1, Sender: senderName, Date: msg.FormattedDate, Text: text})
+			overflowLabel = fmt.Sprintf("overflow:%d", len(overflows))
+			text = cut
+		}
+
+		inputs := []interface{}{senderName, timeStr, showSender, showTimestamp, messageReactions, overflowLabel}
+		printed := p.writeFragment(builder, ctx, run, msg, text, inputs, seenURLs, func(builder *strings.Builder) []indexedPhoto {
+			// Mark where the message starts, so build errors can name it
+			builder.WriteString(latex.Marker(msg.ID, msg.GUID, msg.FormattedDate) + "\n")
+
+			// Write message content
+			p.writeMessageBubble(builder, ctx, tm, msg, text, timeStr, senderName, showSender, showTimestamp, messageReactions, seenURLs, overflowLabel)
+
+			// Add attachments if any
+			var printed []indexedPhoto
+			if msg.HasAttachments && ctx.Config.IncludeImages {
+				printed = p.writeAttachments(builder, tm, msg, ctx.Config.PhotoIndex || inserts, inserts)
+			}
+
+			builder.WriteString("\n")
+			return printed
+		})
+		for _, photo := range printed {
+			photo.Sender = senderName
+			photo.Date = msg.FormattedDate
+			if ctx.Config.PhotoIndex {
+				photos = append(photos, photo)
+			}
+			if inserts {
+				plates = append(plates, photo)
+			}
+		}
+	}
+
+	if chapterKey != "" {
+		builder.WriteString("\\clearspeaker\n")
+		p.writeChapterStats(builder, tm, chapterStats, chapterKey)
+		p.writePhotoInsert(builder, tm, plates)
+		builder.WriteString(chapterEndMarker + chapterKey + "\n")
+	} else {
+		// Without chapters, the photos are collected at the end
+		p.writePhotoInsert(builder, tm, plates)
+	}
+	return overflows, photos
+}
+
+// chapterOf returns the key of the chapter a message belongs to, which its chapter markers
+// carry, and the chapter's title in l's locale. Books without chapters key every message "".
+func (p *TeXPlugin) chapterOf(msg models.Message, chapters string, chatNames map[int]string, l *i18n.Localizer) (string, string) {
+	date := msg.FormattedDate
+	switch chapters {
+	case models.ChaptersNone:
+		return "", ""
+	case models.ChaptersByYear:
+		year := date.Format("2006")
+		return year, year
+	case models.ChaptersByQuarter:
+		quarter := (int(date.Month()) - 1) / 3
+		first := time.Month(quarter*3 + 1)
+		return fmt.Sprintf("%d-Q%d", date.Year(), quarter+1), fmt.Sprintf("%s – %s %d", l.MonthName(first), l.MonthName(first+2), date.Year())
+	case models.ChaptersByChat:
+		name := chatNames[msg.ChatID]
+		if name == "" {
+			name = l.T("other_messages")
+		}
+		return fmt.Sprintf("chat-%d", msg.ChatID), name
+	}
+	return date.Format(analytics.ChapterKeyFormat), l.Format(date, i18n.MonthFormat)
+}
 
-		// Add attachments if any
-		if msg.HasAttachments && ctx.Config.IncludeImages {
-			p.writeAttachments(builder, tm, msg.Attachments)
+// groupByChat reorders messages so each conversation's are together, in date order, with
+// the conversations in the order they started. Memories stay with the message before them.
+func groupByChat(messages []models.Message) []models.Message {
+	var order []int
+	groups := make(map[int][]models.Message)
+	chatID := 0
+	for _, msg := range messages {
+		if msg.Memory == nil {
+			chatID = msg.ChatID
 		}
+		if _, exists := groups[chatID]; !exists {
+			order = append(order, chatID)
+		}
+		groups[chatID] = append(groups[chatID], msg)
+	}
+
+	grouped := make([]models.Message, 0, len(messages))
+	for _, id := range order {
+		grouped = append(grouped, groups[id]...)
+	}
+	return grouped
+}
+
+// writeChapterStats closes a chapter with its stats box when chapter stats are enabled
+func (p *TeXPlugin) writeChapterStats(builder *strings.Builder, tm *output.TemplateManager,
+	chapterStats map[string]*analytics.ChapterStats, monthKey string) {
+
+	stats, exists := chapterStats[monthKey]
+	if !exists {
+		return
+	}
+	l := tm.Localizer()
+
+	data := struct {
+		Month           string
+		Messages        int
+		Photos          int
+		MostActiveDay   string
+		MostActiveCount int
+	}{
+		Month:           l.Format(stats.Month, i18n.MonthFormat),
+		Messages:        stats.Messages,
+		Photos:          stats.Photos,
+		MostActiveDay:   l.Format(stats.MostActiveDay, i18n.DayMonth),
+		MostActiveCount: stats.MostActiveCount,
+	}
 
+	result, err := tm.ExecuteTemplate("chapter-stats.tex", data)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\\begin{center}\\small %s, %s\\end{center}\n", l.Plural("messages", stats.Messages), l.Plural("photos", stats.Photos)))
+	} else {
+		builder.WriteString(result)
+	}
+	builder.WriteString("\n\n")
+}
+
+// writeChapterSummary opens a chapter with its "In this chapter" summary, when it has one
+func (p *TeXPlugin) writeChapterSummary(builder *strings.Builder, tm *output.TemplateManager, summary string) {
+	if summary == "" {
+		return
+	}
+	data := struct {
+		Summary string
+	}{
+		Summary: p.escapeLaTeX(strings.Join(strings.Fields(summary), " ")),
+	}
+
+	result, err := tm.ExecuteTemplate("chapter-summary.tex", data)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\\begin{quote}\\small\\itshape %s\\end{quote}\n\n", data.Summary))
+	} else {
+		builder.WriteString(result)
+	}
+}
+
+// writeNumbers writes the "By the Numbers" chapter summarizing the whole conversation
+func (p *TeXPlugin) writeNumbers(builder *strings.Builder, ctx *output.GenerationContext, tm *output.TemplateManager) {
+	numbers := analytics.Summarize(ctx.Messages, func(msg models.Message) string {
+		return output.GetSenderName(msg, ctx.Handles, ctx.Config)
+	})
+
+	people := make([]analytics.PersonStats, len(numbers.People))
+	for i, person := range numbers.People {
+		people[i] = person
+		people[i].Name = p.escapeLaTeX(person.Name)
+	}
+
+	l := tm.Localizer()
+	data := struct {
+		Messages        int
+		Words           int
+		ActiveDays      int
+		People          []analytics.PersonStats
+		BusiestDay      string
+		BusiestDayCount int
+		LongestStreak   int
+		StreakStart     string
+		StreakEnd       string
+		TopEmoji        []analytics.EmojiCount
+		VolumeChart     string
+		HourChart       string
+		Calendars       []calendarChart
+	}{
+		Messages:        numbers.Messages,
+		Words:           numbers.Words,
+		ActiveDays:      numbers.ActiveDays,
+		People:          people,
+		BusiestDay:      l.Format(numbers.BusiestDay, i18n.DayFormat),
+		BusiestDayCount: numbers.BusiestDayCount,
+		LongestStreak:   numbers.LongestStreak,
+		StreakStart:     l.Format(numbers.StreakStart, i18n.DateFormat),
+		StreakEnd:       l.Format(numbers.StreakEnd, i18n.DateFormat),
+		TopEmoji:        numbers.TopEmoji,
+		VolumeChart:     volumeChart(numbers.Monthly, l),
+		HourChart:       hourHeatmap(numbers.Hourly, l),
+		Calendars:       calendarCharts(analytics.Calendars(ctx.Messages), l),
+	}
+
+	result, err := tm.ExecuteTemplate("by-the-numbers.tex", data)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\n\\chapter{%s}\n\n%s, %s.\n", p.escapeLaTeX(l.T("by_the_numbers")), l.Plural("messages", numbers.Messages), l.Plural("words", numbers.Words)))
+	} else {
 		builder.WriteString("\n")
+		builder.WriteString(result)
 	}
+	builder.WriteString("\n")
 }
 
-// writeMessageBubble formats a single message as a conversation bubble
+// writeGreatestHits writes the "Greatest Hits" chapter of the messages that got the most
+// reactions
+func (p *TeXPlugin) writeGreatestHits(builder *strings.Builder, ctx *output.GenerationContext, tm *output.TemplateManager) {
+	hits := analytics.GreatestHits(ctx.Messages, ctx.Reactions, ctx.Config.GreatestHits)
+	if len(hits) == 0 {
+		return
+	}
+
+	type entry struct {
+		Sender    string
+		Date      string
+		Text      string
+		Reactions []reactionBadge
+		Total     int
+	}
+	entries := make([]entry, len(hits))
+	for i, hit := range hits {
+		text := strings.TrimSpace(strings.ReplaceAll(*hit.Message.Text, "\uFFFC", ""))
+		entries[i] = entry{
+			Sender: p.escapeLaTeX(output.GetSenderName(hit.Message, ctx.Handles, ctx.Config)),
+			Date:   p.escapeLaTeX(tm.Localizer().Format(hit.Message.FormattedDate, i18n.DayTimeFormat)),
+			// Each line of the message is a paragraph of its own
+			Text:  strings.ReplaceAll(p.escapeLaTeX(breakLongWords(output.PolishText(text, ctx.Config))), "\n", "\n\n"),
+			Total: hit.Total,
+		}
+		for _, reaction := range hit.Reactions {
+			entries[i].Reactions = append(entries[i].Reactions, reactionBadge{Emoji: p.unicodeToTeX(reaction.Emoji), Count: reaction.Count})
+		}
+	}
+
+	result, err := tm.ExecuteTemplate("greatest-hits.tex", entries)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\n\\chapter{%s}\n\n", p.escapeLaTeX(tm.Localizer().T("greatest_hits"))))
+		for _, e := range entries {
+			builder.WriteString(fmt.Sprintf("\\textbf{%s} %s\n\n%s\n\n", e.Sender, e.Date, e.Text))
+		}
+	} else {
+		builder.WriteString("\n")
+		builder.WriteString(result)
+	}
+	builder.WriteString("\n")
+}
+
+// writeOverflowAppendix prints the long messages cut short in the conversation in full,
+// each pointing back to where it was cut
+func (p *TeXPlugin) writeOverflowAppendix(builder *strings.Builder, tm *output.TemplateManager, overflows []output.Overflow) {
+	if len(overflows) == 0 {
+		return
+	}
+
+	type entry struct {
+		Number int
+		Sender string
+		Date   string
+		Text   string
+	}
+	entries := make([]entry, len(overflows))
+	for i, overflow := range overflows {
+		entries[i] = entry{
+			Number: overflow.Number,
+			Sender: p.escapeLaTeX(overflow.Sender),
+			Date:   p.escapeLaTeX(tm.Localizer().Format(overflow.Date, i18n.DayTimeFormat)),
+			// Each line of the message is a paragraph of its own
+			Text: strings.ReplaceAll(p.escapeLaTeX(overflow.Text), "\n", "\n\n"),
+		}
+	}
+
+	result, err := tm.ExecuteTemplate("long-messages.tex", entries)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\n\\chapter{%s}\n\n", p.escapeLaTeX(tm.Localizer().T("long_messages"))))
+		for _, e := range entries {
+			builder.WriteString(fmt.Sprintf("\\phantomsection\\label{overflow:%d}\\textbf{%s} %s\n\n%s\n\n", e.Number, e.Sender, e.Date, e.Text))
+		}
+	} else {
+		builder.WriteString("\n")
+		builder.WriteString(result)
+	}
+	builder.WriteString("\n")
+}
+
+// indexedPhoto is a photo printed in the book, as the List of Photos and photo inserts list it
+type indexedPhoto struct {
+	Label  string // The \label on the photo; on its thumbnail it has a ":from" suffix
+	Path   string
+	Sender string
+	Date   time.Time
+}
+
+// writePhotoIndex lists every labelled photo with its date, sender and page
+func (p *TeXPlugin) writePhotoIndex(builder *strings.Builder, tm *output.TemplateManager, photos []indexedPhoto) {
+	if len(photos) == 0 {
+		return
+	}
+
+	type entry struct {
+		Label  string
+		Sender string
+		Date   string
+	}
+	entries := make([]entry, len(photos))
+	for i, photo := range photos {
+		entries[i] = entry{
+			Label:  photo.Label,
+			Sender: p.escapeLaTeX(photo.Sender),
+			Date:   p.escapeLaTeX(tm.Localizer().Format(photo.Date, i18n.DateTimeFormat)),
+		}
+	}
+
+	result, err := tm.ExecuteTemplate("photo-index.tex", entries)
+	if err != nil {
+		l := tm.Localizer()
+		builder.WriteString(fmt.Sprintf("\n\\chapter{%s}\n\n", p.escapeLaTeX(l.T("list_of_photos"))))
+		for _, e := range entries {
+			builder.WriteString(fmt.Sprintf("\\noindent %s, %s \\dotfill %s~\\pageref{%s}\\par\n", e.Date, e.Sender, l.T("page_abbreviation"), e.Label))
+		}
+	} else {
+		builder.WriteString("\n")
+		builder.WriteString(result)
+	}
+	builder.WriteString("\n")
+}
+
+// writeMessageBubble formats a single message as a conversation bubble. A message cut short
+// by writeMessages gets the label of its full text in the appendix.
 func (p *TeXPlugin) writeMessageBubble(builder *strings.Builder, ctx *output.GenerationContext, tm *output.TemplateManager,
-	msg models.Message, text, timeStr, senderName string, showSender, showTimestamp bool, reactions []models.Reaction) {
+	msg models.Message, text, timeStr, senderName string, showSender, showTimestamp bool, reactions []models.Reaction,
+	seenURLs map[string]bool, overflowLabel string) {
 
 	// Process text for URLs
 	processedText := text
-	if ctx.URLThumbnails != nil && len(ctx.URLThumbnails) > 0 {
-		processedText = p.replaceURLsWithImages(text, ctx.URLThumbnails)
+	var footnotes []string
+	var previews []*output.URLThumbnail
+	if ctx.Config.URLFootnotes {
+		processedText, footnotes = p.markURLFootnotes(text, ctx.Config.FootnoteLinks())
+	} else {
+		if ctx.URLThumbnails != nil && len(ctx.URLThumbnails) > 0 {
+			processedText, previews = p.replaceURLsWithImages(text, ctx.URLThumbnails, seenURLs)
+		}
+		// Links left without a preview
+		if ctx.Config.FootnoteLinks() {
+			processedText, footnotes = p.markURLFootnotes(processedText, true)
+		}
 	}
+	processedText = breakLongWords(processedText)
 
 	// Escape LaTeX special characters
 	escapedText := p.escapeLaTeX(processedText)
 
 	// Replace newlines with line breaks
 	escapedText = strings.ReplaceAll(escapedText, "\n", "  \n")
+	escapedText = p.writeLinkPreviews(escapedText, tm, previews)
+	if overflowLabel != "" {
+		escapedText += fmt.Sprintf("\\overflownote{%s}", overflowLabel)
+	}
+
+	effect := ""
+	if ctx.Config.ShowEffects {
+		effect = msg.GetSendEffect()
+	}
 
 	if msg.IsFromMe {
-		p.writeSentMessage(builder, tm, escapedText, timeStr, reactions)
+		p.writeSentMessage(builder, tm, escapedText, timeStr, effect, reactions)
 	} else {
-		p.writeReceivedMessage(builder, tm, escapedText, timeStr, senderName, showSender, showTimestamp, reactions)
+		avatar := p.texAvatar(output.GetAvatar(msg, ctx.Handles, ctx.Config))
+		p.writeReceivedMessage(builder, tm, escapedText, timeStr, senderName, effect, showSender, showTimestamp, reactions, avatar)
 	}
+	p.writeURLFootnotes(builder, footnotes)
 }
 
 // writeSentMessage formats a message sent by the user
-func (p *TeXPlugin) writeSentMessage(builder *strings.Builder, tm *output.TemplateManager, text, timeStr string, reactions []models.Reaction) {
-	// Convert Unicode emojis to LaTeX format for reactions
-	texReactions := p.convertReactionsToTeX(reactions)
-
+func (p *TeXPlugin) writeSentMessage(builder *strings.Builder, tm *output.TemplateManager, text, timeStr, effect string, reactions []models.Reaction) {
 	data := struct {
 		Text      string
 		Timestamp string
-		Reactions []models.Reaction
+		Effect    string
+		Reactions []reactionBadge
 	}{
 		Text:      text,
 		Timestamp: timeStr,
-		Reactions: texReactions,
+		Effect:    effect,
+		Reactions: p.reactionBadges(reactions),
 	}
 
 	result, err := tm.ExecuteTemplate("sent-message.tex", data)
@@ -351,26 +903,27 @@ func (p *TeXPlugin) writeSentMessage(builder *strings.Builder, tm *output.Templa
 }
 
 // writeReceivedMessage formats a message received from others
-func (p *TeXPlugin) writeReceivedMessage(builder *strings.Builder, tm *output.TemplateManager, text, timeStr, senderName string,
-	showSender, showTimestamp bool, reactions []models.Reaction) {
-
-	// Convert Unicode emojis to LaTeX format for reactions
-	texReactions := p.convertReactionsToTeX(reactions)
+func (p *TeXPlugin) writeReceivedMessage(builder *strings.Builder, tm *output.TemplateManager, text, timeStr, senderName, effect string,
+	showSender, showTimestamp bool, reactions []models.Reaction, avatar *avatarData) {
 
 	data := struct {
 		Text          string
 		Timestamp     string
 		Sender        string
+		Effect        string
 		ShowSender    bool
 		ShowTimestamp bool
-		Reactions     []models.Reaction
+		Reactions     []reactionBadge
+		Avatar        *avatarData // Drawn beside the first bubble of a run, and room kept for it beside the rest
 	}{
 		Text:          text,
 		Timestamp:     timeStr,
 		Sender:        senderName,
+		Effect:        effect,
 		ShowSender:    showSender,
 		ShowTimestamp: showTimestamp,
-		Reactions:     texReactions,
+		Reactions:     p.reactionBadges(reactions),
+		Avatar:        avatar,
 	}
 
 	result, err := tm.ExecuteTemplate("received-message.tex", data)
@@ -383,24 +936,128 @@ func (p *TeXPlugin) writeReceivedMessage(builder *strings.Builder, tm *output.Te
 	builder.WriteString("\n\n")
 }
 
-// replaceURLsWithImages replaces URLs with LaTeX image commands
-func (p *TeXPlugin) replaceURLsWithImages(text string, thumbnails map[string]*output.URLThumbnail) string {
-	urlRegex := regexp.MustCompile(`https?://[^\s<>"{}|\\^` + "`" + `\[\]]+`)
+// markURLFootnotes marks each URL in the text for a footnote, returning the URLs in order.
+// With site set the URL is replaced by its site in link colour; otherwise it's kept.
+// Footnotes can't be typeset inside a bubble, so only the mark goes there and
+// writeURLFootnotes adds the text after the message.
+func (p *TeXPlugin) markURLFootnotes(text string, site bool) (string, []string) {
+	var urls []string
+	marked := output.LinkRegex.ReplaceAllStringFunc(text, func(url string) string {
+		cleanURL := output.TrimLink(url)
+		urls = append(urls, cleanURL)
+		shown := cleanURL
+		if name := output.LinkSite(cleanURL); site && name != "" {
+			shown = fmt.Sprintf("\\linktext{%s}", p.escapeLaTeX(name))
+		}
+		return shown + "\\footnotemark{}" + url[len(cleanURL):]
+	})
+	return marked, urls
+}
 
-	return urlRegex.ReplaceAllStringFunc(text, func(url string) string {
-		cleanURL := strings.TrimRight(url, ".,;!?)")
+// writeURLFootnotes writes the footnote text for each URL marked in the last message,
+// winding the counter back so the texts match the marks
+func (p *TeXPlugin) writeURLFootnotes(builder *strings.Builder, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	builder.WriteString(fmt.Sprintf("\\addtocounter{footnote}{-%d}", len(urls)))
+	for _, url := range urls {
+		breakable := strings.ReplaceAll(p.escapeLaTeX(url), "/", "/\\allowbreak{}")
+		builder.WriteString(fmt.Sprintf("\\stepcounter{footnote}\\footnotetext{\\urlnote{%s}}", breakable))
+	}
+	builder.WriteString("\n\n")
+}
 
-		if thumbnail, exists := thumbnails[cleanURL]; exists && thumbnail.Success && thumbnail.ThumbnailPath != "" {
-			return fmt.Sprintf("\\messageimage{%s}", thumbnail.ThumbnailPath)
+// replaceURLsWithImages replaces URLs with link preview placeholders, returning the previews
+// they stand for; writeLinkPreviews swaps in the cards once the text is escaped. Only the
+// first share of a URL gets the full preview; later shares refer back to its page.
+func (p *TeXPlugin) replaceURLsWithImages(text string, thumbnails map[string]*output.URLThumbnail, seenURLs map[string]bool) (string, []*output.URLThumbnail) {
+	var previews []*output.URLThumbnail
+	replaced := output.LinkRegex.ReplaceAllStringFunc(text, func(url string) string {
+		cleanURL := output.TrimLink(url)
+
+		if thumbnail, exists := thumbnails[cleanURL]; exists && thumbnail.Success && (thumbnail.ThumbnailPath != "" || thumbnail.Source == "text") {
+			label := urlLabel(cleanURL)
+			if seenURLs[cleanURL] {
+				return fmt.Sprintf("\\sharedagain{%s}", label)
+			}
+			seenURLs[cleanURL] = true
+			previews = append(previews, thumbnail)
+			return fmt.Sprintf("\\urlanchor{%s}\\linkpreview{%d}", label, len(previews)-1)
 		}
 
 		return url
 	})
+	return replaced, previews
+}
+
+// linkPreviewRegex finds the placeholders replaceURLsWithImages leaves for link previews
+var linkPreviewRegex = regexp.MustCompile(`\\linkpreview\{(\d+)\}`)
+
+// maxPreviewDescription is the most characters of a description shown on a preview card,
+// about the two lines iMessage shows
+const maxPreviewDescription = 140
+
+// writeLinkPreviews replaces the link preview placeholders in escaped message text with
+// cards. Cards drawn by the URL processor, for shared albums and bare domains, already
+// carry their text, so only their image is shown.
+func (p *TeXPlugin) writeLinkPreviews(text string, tm *output.TemplateManager, previews []*output.URLThumbnail) string {
+	return linkPreviewRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		var i int
+		fmt.Sscanf(linkPreviewRegex.FindStringSubmatch(placeholder)[1], "%d", &i)
+		if i >= len(previews) {
+			return ""
+		}
+		thumbnail := previews[i]
+
+		data := struct {
+			Path        string
+			Title       string
+			Description string
+			Domain      string
+		}{Path: texPath(thumbnail.ThumbnailPath)}
+		if thumbnail.Source != "album" && thumbnail.Source != "domain" {
+			data.Title = p.escapeLaTeX(strings.Join(strings.Fields(thumbnail.Title), " "))
+			data.Description = p.escapeLaTeX(previewDescription(thumbnail.Description))
+			data.Domain = p.escapeLaTeX(output.LinkSite(thumbnail.URL))
+		}
+
+		result, err := tm.ExecuteTemplate("link-preview.tex", data)
+		if err != nil && data.Path != "" {
+			return fmt.Sprintf("\\messageimage{%s}", data.Path)
+		} else if err != nil {
+			return fmt.Sprintf("\\textbf{%s}", data.Title)
+		}
+		return result
+	})
+}
+
+// previewDescription puts a description on one line and cuts it short between words
+func previewDescription(description string) string {
+	var kept []string
+	length := 0
+	for _, word := range strings.Fields(description) {
+		length += len([]rune(word)) + 1
+		if len(kept) > 0 && length > maxPreviewDescription {
+			return strings.Join(kept, " ") + "…"
+		}
+		kept = append(kept, word)
+	}
+	return strings.Join(kept, " ")
+}
+
+// urlLabel returns a LaTeX label for a URL that is safe to use in \label and \pageref
+func urlLabel(url string) string {
+	return fmt.Sprintf("url:%x", md5.Sum([]byte(url)))[:16]
 }
 
-// writeAttachments adds attachment references to the output
-func (p *TeXPlugin) writeAttachments(builder *strings.Builder, tm *output.TemplateManager, attachments []models.Attachment) {
-	for _, att := range attachments {
+// writeAttachments adds a message's attachment references to the output and returns the
+// images printed. With label set, each image is labelled after the message's ROWID and its
+// place among the attachments, so the labels survive patching. With inserts set, images
+// are printed as thumbnails pointing to the full photo in the chapter's photo insert.
+func (p *TeXPlugin) writeAttachments(builder *strings.Builder, tm *output.TemplateManager, msg models.Message, label, inserts bool) []indexedPhoto {
+	var printed []indexedPhoto
+	for i, att := range msg.Attachments {
 		if att.Filename != nil {
 			filename := *att.Filename
 			ext := strings.ToLower(filepath.Ext(filename))
@@ -408,7 +1065,19 @@ func (p *TeXPlugin) writeAttachments(builder *strings.Builder, tm *output.Templa
 			// Handle images
 			if p.isImageFile(ext) {
 				if att.ProcessedPath != "" {
-					p.writeImageAttachment(builder, tm, filename, att.ProcessedPath)
+					photo := indexedPhoto{Path: att.ProcessedPath}
+					if label {
+						photo.Label = fmt.Sprintf("photo:%d-%d", msg.ID, i)
+					}
+					if inserts {
+						p.writePhotoThumbnail(builder, tm, photo)
+					} else {
+						if photo.Label != "" {
+							builder.WriteString(fmt.Sprintf("\\phantomsection\\label{%s}%%\n", photo.Label))
+						}
+						p.writeImageAttachment(builder, tm, filename, att.ProcessedPath)
+					}
+					printed = append(printed, photo)
 				} else {
 					p.writeImagePlaceholder(builder, tm, filename)
 				}
@@ -418,10 +1087,77 @@ func (p *TeXPlugin) writeAttachments(builder *strings.Builder, tm *output.Templa
 			}
 		}
 	}
+	return printed
+}
+
+// writePhotoThumbnail writes a small copy of a photo that's printed in full in a photo
+// insert, with the page it's on
+func (p *TeXPlugin) writePhotoThumbnail(builder *strings.Builder, tm *output.TemplateManager, photo indexedPhoto) {
+	data := struct {
+		Label string
+		Path  string
+	}{
+		Label: photo.Label,
+		Path:  texPath(photo.Path),
+	}
+
+	result, err := tm.ExecuteTemplate("photo-thumbnail.tex", data)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\\phantomsection\\label{%s:from}\\includegraphics[width=1in]{%s} {\\scriptsize p.~\\pageref{%s}}\n", data.Label, data.Path, data.Label))
+	} else {
+		builder.WriteString(result)
+	}
+	builder.WriteString("\n\n")
+}
+
+// photoInsertColumns is the number of photos side by side on a photo insert page
+const photoInsertColumns = 2
+
+// writePhotoInsert prints the full photos of a chapter in a grid on pages of their own,
+// each captioned with its date and sender and the page it was sent on
+func (p *TeXPlugin) writePhotoInsert(builder *strings.Builder, tm *output.TemplateManager, plates []indexedPhoto) {
+	if len(plates) == 0 {
+		return
+	}
+
+	type plate struct {
+		Label  string
+		Path   string
+		Sender string
+		Date   string
+	}
+	var rows [][]plate
+	for i, photo := range plates {
+		if i%photoInsertColumns == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], plate{
+			Label:  photo.Label,
+			Path:   texPath(photo.Path),
+			Sender: p.escapeLaTeX(photo.Sender),
+			Date:   p.escapeLaTeX(tm.Localizer().Format(photo.Date, i18n.DateFormat)),
+		})
+	}
+
+	result, err := tm.ExecuteTemplate("photo-insert.tex", rows)
+	if err != nil {
+		builder.WriteString("\\clearpage\n")
+		for _, row := range rows {
+			for _, pl := range row {
+				builder.WriteString(fmt.Sprintf("\\phantomsection\\label{%s}\\includegraphics[width=0.45\\textwidth]{%s}\n%s, %s, %s~\\pageref{%s:from}\n\n", pl.Label, pl.Path, pl.Date, pl.Sender, tm.Localizer().T("page_abbreviation"), pl.Label))
+			}
+		}
+		builder.WriteString("\\clearpage\n")
+	} else {
+		builder.WriteString("\n")
+		builder.WriteString(result)
+	}
+	builder.WriteString("\n")
 }
 
 // writeImageAttachment writes an image attachment
 func (p *TeXPlugin) writeImageAttachment(builder *strings.Builder, tm *output.TemplateManager, filename, path string) {
+	path = texPath(path)
 	data := struct {
 		Filename string
 		Path     string
@@ -439,6 +1175,111 @@ func (p *TeXPlugin) writeImageAttachment(builder *strings.Builder, tm *output.Te
 	builder.WriteString("\n\n")
 }
 
+// notesPageLines is the number of rules on a ruled notes page; they are spread to fill the page
+const notesPageLines = 18
+
+// writeWordCloud writes the word cloud page of a year, or of the whole book for year 0, the
+// first time it's asked for
+func (p *TeXPlugin) writeWordCloud(builder *strings.Builder, tm *output.TemplateManager, clouds map[int]analytics.WordCloud, year int) {
+	cloud, exists := clouds[year]
+	if !exists {
+		return
+	}
+	delete(clouds, year)
+
+	l := tm.Localizer()
+	title := l.T("our_words")
+	if cloud.Year != 0 {
+		title = l.T("year_in_words", "Year", cloud.Year)
+	}
+	data := struct {
+		Title string
+		Chart string
+	}{
+		Title: p.escapeLaTeX(title),
+		Chart: wordCloudChart(cloud.Words),
+	}
+
+	result, err := tm.ExecuteTemplate("word-cloud.tex", data)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\\clearpage\n\\begin{center}\n%s\n\\end{center}\n\\clearpage\n", data.Chart))
+	} else {
+		builder.WriteString(result)
+	}
+}
+
+// writeNotesPages writes blank or ruled pages for handwritten notes in the printed book
+func (p *TeXPlugin) writeNotesPages(builder *strings.Builder, tm *output.TemplateManager, notes models.NotesPagesConfig) {
+	ruled := notes.Style != models.NotesStyleBlank
+	lines := make([]int, notesPageLines)
+
+	for i := 0; i < notes.Count; i++ {
+		// Only the first page carries the heading
+		title := ""
+		if i == 0 {
+			title = p.escapeLaTeX(notes.Title)
+		}
+
+		data := struct {
+			Title string
+			Ruled bool
+			Lines []int
+		}{
+			Title: title,
+			Ruled: ruled,
+			Lines: lines,
+		}
+
+		result, err := tm.ExecuteTemplate("notes-page.tex", data)
+		if err != nil {
+			builder.WriteString("\\clearpage\n\\thispagestyle{empty}\n\\mbox{}\n\\clearpage\n")
+		} else {
+			builder.WriteString(result)
+		}
+		builder.WriteString("\n")
+	}
+}
+
+// writeMemory writes a full-page photo inserted from the memories folder
+func (p *TeXPlugin) writeMemory(builder *strings.Builder, tm *output.TemplateManager, msg models.Message) {
+	path := texPath(msg.Memory.ProcessedPath)
+	data := struct {
+		Path string
+		Date string
+	}{
+		Path: path,
+		Date: p.escapeLaTeX(tm.Localizer().Format(msg.FormattedDate, i18n.DayFormat)),
+	}
+
+	result, err := tm.ExecuteTemplate("memory-page.tex", data)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\\clearpage\n\\includegraphics[width=\\textwidth]{%s}\n\\clearpage\n", path))
+	} else {
+		builder.WriteString(result)
+	}
+	builder.WriteString("\n\n")
+}
+
+// writeDayGap marks a long silence before a day with a separator such as "• • •  three weeks
+// later  • • •", or writes nothing when label is empty
+func (p *TeXPlugin) writeDayGap(builder *strings.Builder, tm *output.TemplateManager, label string) {
+	if label == "" {
+		return
+	}
+	data := struct {
+		Label string
+	}{
+		Label: p.escapeLaTeX(label),
+	}
+
+	result, err := tm.ExecuteTemplate("day-gap.tex", data)
+	if err != nil {
+		builder.WriteString(fmt.Sprintf("\\begin{center}\\itshape %s\\end{center}\n", data.Label))
+	} else {
+		builder.WriteString(result)
+	}
+}
+
 // writeImagePlaceholder writes an image placeholder
 func (p *TeXPlugin) writeImagePlaceholder(builder *strings.Builder, tm *output.TemplateManager, filename string) {
 	data := struct {
@@ -475,16 +1316,28 @@ func (p *TeXPlugin) writeAttachment(builder *strings.Builder, tm *output.Templat
 
 // escapeLaTeX escapes special LaTeX characters while preserving image commands
 func (p *TeXPlugin) escapeLaTeX(text string) string {
-	// First, protect image commands by temporarily replacing them
-	imageCommands := make(map[string]string)
-	imageRegex := regexp.MustCompile(`\\messageimage\{[^}]+\}`)
-	matches := imageRegex.FindAllString(text, -1)
+	return EscapeLaTeX(text)
+}
 
-	for i, match := range matches {
-		placeholder := fmt.Sprintf("IMAGECOMMAND%d", i)
-		imageCommands[placeholder] = match
-		text = strings.ReplaceAll(text, match, placeholder)
-	}
+var (
+	// protectedCommandRegex matches the commands EscapeLaTeX leaves as they are
+	protectedCommandRegex = regexp.MustCompile(`\\(messageimage|urlanchor|sharedagain|linkpreview|linktext)\{[^}]+\}|\\footnotemark\{\}`)
+	// placeholderRegex matches the placeholders EscapeLaTeX swaps them for
+	placeholderRegex = regexp.MustCompile("\x00[0-9]+\x00")
+)
+
+// EscapeLaTeX escapes special LaTeX characters while preserving image commands, for other
+// plugins that typeset message text
+func EscapeLaTeX(text string) string {
+	// First, protect image commands and footnote marks by swapping them for numbered
+	// placeholders. Each is closed by a NUL so none can be read as the start of another,
+	// and NULs in the text itself, which LaTeX couldn't print anyway, are dropped.
+	var commands []string
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = protectedCommandRegex.ReplaceAllStringFunc(text, func(command string) string {
+		commands = append(commands, command)
+		return fmt.Sprintf("\x00%d\x00", len(commands)-1)
+	})
 
 	// Replace LaTeX special characters
 	text = strings.ReplaceAll(text, "\\", "\\textbackslash{}")
@@ -497,13 +1350,17 @@ func (p *TeXPlugin) escapeLaTeX(text string) string {
 	text = strings.ReplaceAll(text, "^", "\\textasciicircum{}")
 	text = strings.ReplaceAll(text, "_", "\\_")
 	text = strings.ReplaceAll(text, "~", "\\textasciitilde{}")
+	text = strings.ReplaceAll(text, "\u00a0", "~") // Non-breaking spaces, as smart typography sets them
+	text = strings.ReplaceAll(text, wordBreak, "\\allowbreak{}")
 
 	// Restore protected image commands
-	for placeholder, imageCommand := range imageCommands {
-		text = strings.ReplaceAll(text, placeholder, imageCommand)
-	}
+	text = placeholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		i, _ := strconv.Atoi(strings.Trim(placeholder, "\x00"))
+		return commands[i]
+	})
 
-	return text
+	// Set Arabic and Hebrew right to left
+	return markDirection(text)
 }
 
 // isImageFile checks if the file extension indicates an image
@@ -517,32 +1374,61 @@ func (p *TeXPlugin) isImageFile(ext string) bool {
 	return false
 }
 
-// convertReactionsToTeX converts Unicode emoji reactions to LaTeX format
-func (p *TeXPlugin) convertReactionsToTeX(reactions []models.Reaction) []models.Reaction {
-	if len(reactions) == 0 {
-		return reactions
+// avatarData is a sender's avatar ready for the received-message template
+type avatarData struct {
+	Path     string // Image in TeX's path form; empty to draw the initials
+	Initials string // Escaped for LaTeX
+	RGB      string // Circle color in xcolor's rgb,255 form, e.g. "red,52;green,199;blue,89"
+}
+
+// texAvatar prepares an avatar for the received-message template, or returns nil for none
+func (p *TeXPlugin) texAvatar(avatar *output.Avatar) *avatarData {
+	if avatar == nil {
+		return nil
+	}
+	data := &avatarData{Initials: p.escapeLaTeX(avatar.Initials)}
+	if avatar.Path != "" {
+		data.Path = texPath(avatar.Path)
 	}
+	var r, g, b int
+	fmt.Sscanf(avatar.Color, "%02x%02x%02x", &r, &g, &b)
+	data.RGB = fmt.Sprintf("red,%d;green,%d;blue,%d", r, g, b)
+	return data
+}
 
-	texReactions := make([]models.Reaction, len(reactions))
-	copy(texReactions, reactions)
+// reactionBadge is one emoji in the row of reactions on a bubble's corner, with how many
+// people reacted with it
+type reactionBadge struct {
+	Emoji string // In LaTeX format
+	Count int
+}
 
-	for i := range texReactions {
-		texReactions[i].ReactionEmoji = p.unicodeToTeX(texReactions[i].ReactionEmoji)
+// reactionBadges groups a message's reactions by emoji, in the order each was first used,
+// converting the emoji to LaTeX format
+func (p *TeXPlugin) reactionBadges(reactions []models.Reaction) []reactionBadge {
+	var badges []reactionBadge
+	index := make(map[string]int)
+	for _, reaction := range reactions {
+		if i, seen := index[reaction.ReactionEmoji]; seen {
+			badges[i].Count++
+			continue
+		}
+		index[reaction.ReactionEmoji] = len(badges)
+		badges = append(badges, reactionBadge{Emoji: p.unicodeToTeX(reaction.ReactionEmoji), Count: 1})
 	}
-
-	return texReactions
+	return badges
 }
 
 // unicodeToTeX converts Unicode emoji to LaTeX emojifont format
 func (p *TeXPlugin) unicodeToTeX(emoji string) string {
 	emojiMap := map[string]string{
-		"❤️":  `{\emojifont\symbol{"2764}}`,
-		"❤":   `{\emojifont\symbol{"2764}}`,
+		"❤️": `{\emojifont\symbol{"2764}}`,
+		"❤":  `{\emojifont\symbol{"2764}}`,
 		"👍":  `{\emojifont\symbol{"1F44D}}`,
 		"👎":  `{\emojifont\symbol{"1F44E}}`,
 		"😂":  `{\emojifont\symbol{"1F602}}`,
-		"‼️":  `{\emojifont\symbol{"2757}}`,
-		"‼":   `{\emojifont\symbol{"2757}}`,
+		"‼️": `{\emojifont\symbol{"2757}}`,
+		"‼":  `{\emojifont\symbol{"2757}}`,
 		"❓":  `{\emojifont\symbol{"2753}}`,
 	}
 
@@ -563,7 +1449,13 @@ func (p *TeXPlugin) ValidateConfig(config *models.BookConfig) error {
 
 	// Template directory is optional now (we have embedded templates)
 	// It's only needed if user wants custom templates
-	return nil
+	if err := config.NotesPages.Validate(); err != nil {
+		return err
+	}
+	if err := validatePrintPreset(config); err != nil {
+		return err
+	}
+	return config.Theme.Validate()
 }
 
 // GetRequiredTemplates returns the list of template files needed for TeX generation
@@ -574,10 +1466,26 @@ func (p *TeXPlugin) GetRequiredTemplates() []string {
 		"received-message.tex",
 		"title-page.tex",
 		"copyright-page.tex",
+		"dedication-page.tex",
+		"foreword.tex",
 		"page-structure.tex",
 		"yaml-header.yml",
 		"image-attachment.tex",
 		"image-placeholder.tex",
 		"attachment.tex",
+		"link-preview.tex",
+		"memory-page.tex",
+		"notes-page.tex",
+		"chapter-stats.tex",
+		"chapter-summary.tex",
+		"by-the-numbers.tex",
+		"long-messages.tex",
+		"greatest-hits.tex",
+		"photo-index.tex",
+		"photo-thumbnail.tex",
+		"photo-insert.tex",
+		"calendar-page.tex",
+		"day-gap.tex",
+		"word-cloud.tex",
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,1062 @@
+package tex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"threadbound/internal/analytics"
+	"threadbound/internal/i18n"
+	"threadbound/internal/latex"
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+func TestReplaceURLsWithImagesSharedAgain(t *testing.T) {
+	plugin := NewTeXPlugin()
+
+	url := "https://example.com/article"
+	thumbnails := map[string]*output.URLThumbnail{
+		url: {URL: url, ThumbnailPath: "Attachments/url-thumbnails/abc.png", Success: true},
+	}
+	seenURLs := make(map[string]bool)
+
+	first, previews := plugin.replaceURLsWithImages("Read this "+url, thumbnails, seenURLs)
+	if !strings.Contains(first, `\linkpreview{0}`) || len(previews) != 1 || previews[0] != thumbnails[url] {
+		t.Errorf("Expected a preview on first share, got %q, %v", first, previews)
+	}
+	if !strings.Contains(first, `\urlanchor{`+urlLabel(url)+`}`) {
+		t.Errorf("Expected anchor on first share, got %q", first)
+	}
+
+	again, previews := plugin.replaceURLsWithImages("Did you read "+url+"?", thumbnails, seenURLs)
+	if strings.Contains(again, `\linkpreview`) || len(previews) != 0 {
+		t.Errorf("Expected no preview on repeat share, got %q", again)
+	}
+	if !strings.Contains(again, `\sharedagain{`+urlLabel(url)+`}`) {
+		t.Errorf("Expected shared-again reference on repeat share, got %q", again)
+	}
+
+	// Cross-reference commands must survive escaping
+	escaped := plugin.escapeLaTeX(again)
+	if !strings.Contains(escaped, `\sharedagain{`+urlLabel(url)+`}`) {
+		t.Errorf("Expected shared-again reference to survive escaping, got %q", escaped)
+	}
+}
+
+func TestEscapeLaTeXManyCommands(t *testing.T) {
+	// Past ten commands the placeholder for the second is a prefix of the eleventh's
+	var text, want strings.Builder
+	for i := 0; i < 12; i++ {
+		fmt.Fprintf(&text, `\messageimage{photo_%d.jpg}\footnotemark{} 50%% `, i)
+		fmt.Fprintf(&want, `\messageimage{photo_%d.jpg}\footnotemark{} 50\%% `, i)
+	}
+	if got := EscapeLaTeX(text.String()); got != want.String() {
+		t.Errorf("Expected %q, got %q", want.String(), got)
+	}
+
+	if got := EscapeLaTeX("a\x001\x00b"); got != "a1b" {
+		t.Errorf("Expected NULs dropped, got %q", got)
+	}
+}
+
+func TestReplaceURLsWithImagesNoThumbnail(t *testing.T) {
+	plugin := NewTeXPlugin()
+
+	text := "See https://example.com/missing"
+	result, _ := plugin.replaceURLsWithImages(text, map[string]*output.URLThumbnail{}, make(map[string]bool))
+	if result != text {
+		t.Errorf("Expected URLs without previews unchanged, got %q", result)
+	}
+}
+
+func TestWriteMessagesReactionBadges(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	sent, received := "On my way", "See you soon"
+	date := time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: &sent, IsFromMe: true, FormattedDate: date},
+			{GUID: "2", Text: &received, FormattedDate: date.Add(time.Minute)},
+		},
+		Reactions: map[string][]models.Reaction{
+			"1": {
+				{Type: 2000, SenderName: "Sam", ReactionEmoji: "❤️"},
+				{Type: 2006, SenderName: "Alex", ReactionEmoji: "🎉"},
+				{Type: 2000, SenderName: "Jo", ReactionEmoji: "❤️"},
+			},
+			"2": {{Type: 2001, SenderName: "Me", ReactionEmoji: "👍"}},
+		},
+		Config: &models.BookConfig{},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	for _, want := range []string{
+		// Reactions with the same emoji share a badge, counted
+		`[reaction badge, anchor=south east] at ([xshift=6pt, yshift=-6pt]textnode.north west) { {\emojifont\symbol{"2764}}\,{\scriptsize 2}\,🎉 }`,
+		`[reaction badge, anchor=south west] at ([xshift=-6pt, yshift=-6pt]textnode.north east) { {\emojifont\symbol{"1F44D}} }`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "tabular") {
+		t.Errorf("Expected no reaction column, got:\n%s", result)
+	}
+}
+
+func TestWriteMessagesAvatars(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	first, second, third := "Hi all", "Anyone up?", "Me!"
+	alice, bob := 1, 2
+	date := time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: &first, HandleID: &alice, FormattedDate: date},
+			{GUID: "2", Text: &second, HandleID: &alice, FormattedDate: date.Add(time.Minute)},
+			{GUID: "3", Text: &third, HandleID: &bob, FormattedDate: date.Add(2 * time.Minute)},
+		},
+		Handles: map[int]models.Handle{
+			1: {ID: 1, Contact: "+15551234567", DisplayName: "Alice Smith"},
+			2: {ID: 2, Contact: "bob@example.com", DisplayName: "Bob"},
+		},
+		Config: &models.BookConfig{ShowAvatars: true, Avatars: models.Avatars{"Bob": "avatars/bob.jpg"}},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	for _, want := range []string{
+		`fill={rgb,255:red,`,
+		`at ([xshift=-4pt]textnode.north west) { AS };`,
+		// Later bubbles in the run line up with the first
+		`\path ([xshift=-4pt-\avatarsize]textnode.north west);`,
+		`\includegraphics[width=\avatarsize,height=\avatarsize]{ avatars/bob.jpg }`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in:\n%s", want, result)
+		}
+	}
+
+	ctx.Config.ShowAvatars = false
+	builder.Reset()
+	plugin.writeMessages(&builder, ctx, tm)
+	if strings.Contains(builder.String(), "avatar") {
+		t.Error("Expected no avatars unless asked for")
+	}
+}
+
+func TestWriteMessagesLinkPreviewCards(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := "Look https://www.example.com/a_b and https://example.org/ and https://text.example/"
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: &text, IsFromMe: true, FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)},
+		},
+		URLThumbnails: map[string]*output.URLThumbnail{
+			"https://www.example.com/a_b": {URL: "https://www.example.com/a_b", ThumbnailPath: "a.png", Success: true, Source: "download",
+				Title: "Tips & Tricks", Description: strings.Repeat("word ", 50)},
+			"https://example.org/": {URL: "https://example.org/", ThumbnailPath: "org.png", Success: true, Source: "domain",
+				Title: "example.org", Description: "Web link"},
+			"https://text.example/": {URL: "https://text.example/", Success: true, Source: "text", Title: "Text only"},
+		},
+		Config: &models.BookConfig{},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	for _, want := range []string{
+		`\includegraphics[width=\linewidth,height=0.3\textheight,keepaspectratio]{ a.png }`,
+		`{\small\bfseries Tips \& Tricks}`,
+		`word…`,
+		`{ example.com }`,
+		`{ org.png }`,
+		// A text-only preview is a card without an image
+		`\begin{minipage}{\linewidth}` + "\n" + `\colorbox{white}`,
+		`{\small\bfseries Text only}`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "Web link") || strings.Contains(result, `\linkpreview`) {
+		t.Errorf("Expected a drawn domain card shown as its image alone, got:\n%s", result)
+	}
+	if strings.Contains(result, strings.Repeat("word ", 30)) {
+		t.Error("Expected the long description cut short")
+	}
+}
+
+func TestWriteMessagesURLFootnotes(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := "Read https://example.com/a_b?x=1%20 and https://example.org/c."
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: &text, IsFromMe: true, FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)},
+		},
+		URLThumbnails: map[string]*output.URLThumbnail{
+			"https://example.org/c": {URL: "https://example.org/c", ThumbnailPath: "c.png", Success: true},
+		},
+		Config: &models.BookConfig{URLFootnotes: true},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	// The text keeps both links, each marked and free to break, and the previews are left out
+	for _, want := range []string{
+		`https://\allowbreak{}example.\allowbreak{}com/\allowbreak{}a\_\allowbreak{}b?\allowbreak{}x=\allowbreak{}1\%\allowbreak{}20\footnotemark{} and https://\allowbreak{}example.\allowbreak{}org/\allowbreak{}c\footnotemark{}.`,
+		`\addtocounter{footnote}{-2}`,
+		`\stepcounter{footnote}\footnotetext{\urlnote{https:/\allowbreak{}/\allowbreak{}example.com/\allowbreak{}a\_b?x=1\%20}}`,
+		`\footnotetext{\urlnote{https:/\allowbreak{}/\allowbreak{}example.org/\allowbreak{}c}}`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "messageimage") {
+		t.Error("Expected no preview images with URL footnotes")
+	}
+}
+
+func TestWriteMessagesFootnoteLinkStyle(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := "Read https://www.example.com/a_b and https://example.org/c."
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: &text, IsFromMe: true, FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)},
+		},
+		URLThumbnails: map[string]*output.URLThumbnail{
+			"https://example.org/c": {URL: "https://example.org/c", ThumbnailPath: "c.png", Success: true},
+		},
+		Config: &models.BookConfig{LinkStyle: models.LinkStyleFootnote},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	// The link without a preview shows its site and is footnoted; the other keeps its preview
+	for _, want := range []string{
+		`Read \linktext{example.com}\footnotemark{} and \urlanchor{`,
+		`\addtocounter{footnote}{-1}`,
+		`\footnotetext{\urlnote{https:/\allowbreak{}/\allowbreak{}www.example.com/\allowbreak{}a\_b}}`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "example.org/\\allowbreak{}c}") {
+		t.Error("Expected no footnote for the link with a preview")
+	}
+}
+
+func TestWriteNumbers(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	alice := 1
+	date := time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("hi 👋"), HandleID: &alice, FormattedDate: date},
+			{GUID: "2", Text: text("hello there"), IsFromMe: true, FormattedDate: date.Add(time.Minute)},
+		},
+		Handles: map[int]models.Handle{alice: {ID: alice, DisplayName: "Alice & Co"}},
+		Config:  &models.BookConfig{StatsChapter: true},
+	}
+
+	var builder strings.Builder
+	plugin.writeNumbers(&builder, ctx, tm)
+	result := builder.String()
+
+	for _, want := range []string{`\chapter{By the Numbers}`, `Alice \& Co & 1 message & 2 words`, "Saturday, March 4, 2023", "👋", `\begin{tikzpicture}`, `\section*{Every Day}`} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in numbers chapter, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestWriteMessagesSendEffects(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	confetti := "com.apple.messages.effect.CKConfettiEffect"
+	slam := "com.apple.MobileSMS.expressivesend.impact"
+	alice := 1
+	date := time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)
+	messages := []models.Message{
+		{GUID: "1", Text: text("happy birthday"), IsFromMe: true, ExpressiveSendStyleID: &confetti, FormattedDate: date},
+		{GUID: "2", Text: text("thank you"), HandleID: &alice, ExpressiveSendStyleID: &slam, FormattedDate: date.Add(time.Minute)},
+	}
+
+	for _, show := range []bool{true, false} {
+		ctx := &output.GenerationContext{
+			Messages: messages,
+			Handles:  map[int]models.Handle{alice: {ID: alice, DisplayName: "Alice"}},
+			Config:   &models.BookConfig{ShowEffects: show},
+		}
+
+		var builder strings.Builder
+		plugin.writeMessages(&builder, ctx, tm)
+		result := builder.String()
+
+		for _, want := range []string{"sent with Confetti 🎉", "sent with Slam 💥"} {
+			if strings.Contains(result, want) != show {
+				t.Errorf("With ShowEffects=%v, expected %q present=%v, got:\n%s", show, want, show, result)
+			}
+		}
+	}
+}
+
+func TestWriteMessagesSmartTypography(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := `on dit "d'accord"?`
+	messages := []models.Message{{GUID: "1", Text: &text, IsFromMe: true, FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)}}
+
+	for _, smart := range []bool{true, false} {
+		ctx := &output.GenerationContext{
+			Messages: messages,
+			Config:   &models.BookConfig{Locale: "fr", SmartTypography: smart},
+		}
+
+		var builder strings.Builder
+		plugin.writeMessages(&builder, ctx, tm)
+		result := builder.String()
+
+		if want := "on dit «~d’accord~»~?"; strings.Contains(result, want) != smart {
+			t.Errorf("With SmartTypography=%v, expected %q present=%v, got:\n%s", smart, want, smart, result)
+		}
+	}
+}
+
+func TestWriteMessagesSourceMarkers(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	date := time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{{ID: 12, GUID: "ABC-1", Text: text("hello"), IsFromMe: true, FormattedDate: date}},
+		Config:   &models.BookConfig{},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	marker := latex.Marker(12, "ABC-1", date) + "\n"
+	if !strings.Contains(result, marker) || strings.Index(result, marker) > strings.Index(result, "hello") {
+		t.Errorf("Expected %q before the message, got:\n%s", marker, result)
+	}
+}
+
+func TestWriteMessagesSpeakerMarks(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	alice := 1
+	date := time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("first"), HandleID: &alice, FormattedDate: date},
+			{GUID: "2", Text: text("second"), HandleID: &alice, FormattedDate: date.Add(time.Minute)},
+			{GUID: "3", Text: text("reply"), IsFromMe: true, FormattedDate: date.Add(2 * time.Minute)},
+			{GUID: "4", Text: text("next month"), HandleID: &alice, FormattedDate: date.AddDate(0, 1, 0)},
+		},
+		Handles: map[int]models.Handle{alice: {ID: alice, DisplayName: "Alice"}},
+		Config:  &models.BookConfig{},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	// Only Alice's second message continues her run; the rest start one
+	if got := strings.Count(result, "\\speakercontinues{ Alice }"); got != 1 {
+		t.Errorf("Expected one continued mark, got %d:\n%s", got, result)
+	}
+	if got := strings.Count(result, "\\speakerstarts"); got != 3 {
+		t.Errorf("Expected three starting marks, got %d", got)
+	}
+	continued := strings.Index(result, "\\speakercontinues")
+	if !strings.Contains(result[continued:], "second") || strings.Contains(result[continued:], "first") {
+		t.Error("Expected the continued mark on the second message")
+	}
+
+	// Each chapter ends by clearing the speaker
+	if got := strings.Count(result, "\\clearspeaker"); got != 2 {
+		t.Errorf("Expected the speaker cleared after both chapters, got %d", got)
+	}
+}
+
+func TestWriteMessagesOverflow(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	long := "Packing list: " + strings.Repeat("tent stakes & rope, ", 20)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("Short one"), IsFromMe: true, FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text(long), IsFromMe: true, FormattedDate: time.Date(2023, 3, 4, 9, 1, 0, 0, time.UTC)},
+		},
+		Handles: map[int]models.Handle{},
+		Config:  &models.BookConfig{OverflowLines: 3},
+	}
+
+	result := plugin.generateContent(ctx, tm)
+	if got := strings.Count(result, "\\overflownote{overflow:1}"); got != 1 {
+		t.Errorf("Expected one cut message, got %d:\n%s", got, result)
+	}
+	appendix := strings.Index(result, "Appendix A: Long Messages")
+	if appendix < 0 {
+		t.Fatalf("Expected the appendix, got:\n%s", result)
+	}
+	if !strings.Contains(result[appendix:], "\\label{overflow:1}") || !strings.Contains(result[appendix:], "\\pageref{overflow:1:from}") {
+		t.Error("Expected the appendix entry to link back to the cut message")
+	}
+	if strings.Count(result, "rope, tent stakes \\& rope") <= strings.Count(result[:appendix], "rope, tent stakes \\& rope") {
+		t.Error("Expected the full text only in the appendix")
+	}
+
+	// Without a limit nothing is cut
+	ctx.Config.OverflowLines = 0
+	if result := plugin.generateContent(ctx, tm); strings.Contains(result, "Appendix A") {
+		t.Error("Expected no appendix without overflow_lines")
+	}
+}
+
+func TestGreatestHits(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	handle := 1
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("Nobody laughed"), IsFromMe: true, FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text("The cat ate 100% of the cake"), HandleID: &handle, FormattedDate: time.Date(2023, 3, 4, 9, 1, 0, 0, time.UTC)},
+		},
+		Handles: map[int]models.Handle{1: {DisplayName: "Alice"}},
+		Reactions: map[string][]models.Reaction{
+			"2": {{ReactionEmoji: "😂"}, {ReactionEmoji: "😂"}, {ReactionEmoji: "❤️"}},
+		},
+		Config: &models.BookConfig{GreatestHits: 5},
+	}
+
+	result := plugin.generateContent(ctx, tm)
+	hits := strings.Index(result, "\\chapter{Greatest Hits}")
+	if hits < 0 {
+		t.Fatalf("Expected the Greatest Hits chapter, got:\n%s", result)
+	}
+	for _, want := range []string{"\\textbf{ Alice }", "The cat ate 100\\% of the cake", "{\\emojifont\\symbol{\"1F602}}\\,2", "3 reactions"} {
+		if !strings.Contains(result[hits:], want) {
+			t.Errorf("Expected %s in the chapter:\n%s", want, result[hits:])
+		}
+	}
+	if strings.Contains(result[hits:], "Nobody laughed") {
+		t.Error("Expected only messages with reactions")
+	}
+
+	ctx.Config.GreatestHits = 0
+	if result := plugin.generateContent(ctx, tm); strings.Contains(result, "Greatest Hits") {
+		t.Error("Expected no Greatest Hits without greatest_hits")
+	}
+}
+
+func TestWritePhotoIndex(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	handle := 1
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 7, GUID: "1", Text: text("Look"), IsFromMe: true, HasAttachments: true, FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC),
+				Attachments: []models.Attachment{
+					{Filename: text("notes.pdf")},
+					{Filename: text("IMG_1.jpg"), ProcessedPath: "/tmp/IMG_1.jpg"},
+				}},
+			{ID: 8, GUID: "2", Text: text("Nice"), HandleID: &handle, HasAttachments: true, FormattedDate: time.Date(2023, 3, 5, 18, 30, 0, 0, time.UTC),
+				Attachments: []models.Attachment{
+					{Filename: text("IMG_2.heic"), ProcessedPath: "/tmp/IMG_2.jpg"},
+					{Filename: text("IMG_3.jpg")},
+				}},
+		},
+		Handles: map[int]models.Handle{1: {ID: 1, Contact: "+15555550100", DisplayName: "Sam"}},
+		Config:  &models.BookConfig{IncludeImages: true, PhotoIndex: true},
+	}
+
+	result := plugin.generateContent(ctx, tm)
+	list := strings.Index(result, "\\chapter{List of Photos}")
+	if list < 0 {
+		t.Fatalf("Expected the List of Photos, got:\n%s", result)
+	}
+	// Only images printed in the book are labelled and listed
+	for _, label := range []string{"photo:7-1", "photo:8-0"} {
+		if !strings.Contains(result[:list], "\\label{"+label+"}") || !strings.Contains(result[list:], "\\pageref{"+label+"}") {
+			t.Errorf("Expected %s labelled and listed:\n%s", label, result)
+		}
+	}
+	if strings.Count(result, "\\label{photo:") != 2 {
+		t.Errorf("Expected only the printed images labelled:\n%s", result)
+	}
+	if !strings.Contains(result[list:], "March 5, 2023, 6:30 PM \\textbullet\\ Sam") {
+		t.Errorf("Expected each photo's date and sender, got:\n%s", result[list:])
+	}
+
+	ctx.Config.PhotoIndex = false
+	if result := plugin.generateContent(ctx, tm); strings.Contains(result, "List of Photos") || strings.Contains(result, "\\label{photo:") {
+		t.Error("Expected no List of Photos without photo_index")
+	}
+}
+
+func TestWriteMessagesPhotoInserts(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	photo := func(id int, date time.Time) models.Message {
+		return models.Message{ID: id, GUID: fmt.Sprint(id), Text: text("Look"), IsFromMe: true, HasAttachments: true, FormattedDate: date,
+			Attachments: []models.Attachment{{Filename: text("IMG.jpg"), ProcessedPath: fmt.Sprintf("/tmp/IMG_%d.jpg", id)}}}
+	}
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			photo(1, time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)),
+			photo(2, time.Date(2023, 3, 9, 9, 0, 0, 0, time.UTC)),
+			photo(3, time.Date(2023, 4, 1, 9, 0, 0, 0, time.UTC)),
+		},
+		Handles: map[int]models.Handle{},
+		Config:  &models.BookConfig{IncludeImages: true, PhotoLayout: models.PhotoLayoutInsert, PhotoIndex: true},
+	}
+
+	result := plugin.generateContent(ctx, tm)
+	march := result[:strings.Index(result, chapterEndMarker+"2023-03")]
+	april := result[len(march):strings.Index(result, chapterEndMarker+"2023-04")]
+
+	// Each chapter ends with its own photos, which point back to their thumbnails
+	if got := strings.Count(march, "\\label{photo:1-0}") + strings.Count(march, "\\label{photo:2-0}"); got != 2 {
+		t.Errorf("Expected March's photos in its insert:\n%s", march)
+	}
+	if strings.Index(march, "\\label{photo:2-0:from}") > strings.Index(march, "\\label{photo:1-0}") {
+		t.Error("Expected the insert after the chapter's messages")
+	}
+	if !strings.Contains(march, "\\pageref{photo:1-0:from}") || !strings.Contains(march, "\\pageref{photo:1-0}") {
+		t.Error("Expected the thumbnail and full photo to refer to each other")
+	}
+	if !strings.Contains(march, "max width=1in") || strings.Count(march, "\\begin{minipage}") != 2 {
+		t.Errorf("Expected thumbnails inline and one grid cell per photo:\n%s", march)
+	}
+	if !strings.Contains(april, "\\label{photo:3-0}") || strings.Contains(april, "photo:1-0}") {
+		t.Errorf("Expected only April's photo in its insert:\n%s", april)
+	}
+
+	// The List of Photos gives the page of each full photo
+	if !strings.Contains(result, "\\pageref{photo:3-0}\\par") {
+		t.Errorf("Expected the List of Photos to point to the insert:\n%s", result)
+	}
+
+	ctx.Config.PhotoLayout = models.PhotoLayoutInline
+	if result := plugin.generateContent(ctx, tm); strings.Contains(result, "minipage") || strings.Contains(result, ":from}") {
+		t.Error("Expected photos inline without insert pages")
+	}
+}
+
+func TestVolumeChart(t *testing.T) {
+	month := func(m time.Month, count int) analytics.MonthCount {
+		return analytics.MonthCount{Month: time.Date(2023, m, 1, 0, 0, 0, 0, time.UTC), Count: count}
+	}
+
+	chart := volumeChart([]analytics.MonthCount{month(1, 10), month(2, 0), month(3, 5)}, i18n.For("en"))
+	if strings.Count(chart, `\fill[sentmessage]`) != 2 {
+		t.Errorf("Expected a bar for each month with messages, got:\n%s", chart)
+	}
+	for _, label := range []string{"{Jan}", "{Feb}", "{Mar}", "{10}"} {
+		if !strings.Contains(chart, label) {
+			t.Errorf("Expected label %s in chart", label)
+		}
+	}
+
+	if volumeChart([]analytics.MonthCount{month(1, 0)}, i18n.For("en")) != "" {
+		t.Error("Expected no chart without messages")
+	}
+}
+
+func TestHourHeatmap(t *testing.T) {
+	var grid analytics.HourGrid
+	grid[time.Monday][9] = 4
+	grid[time.Sunday][22] = 1
+
+	chart := hourHeatmap(&grid, i18n.For("en"))
+	if !strings.Contains(chart, `sentmessage!100`) || !strings.Contains(chart, `sentmessage!36`) {
+		t.Errorf("Expected cells shaded by volume, got:\n%s", chart)
+	}
+	if strings.Count(chart, `\fill[`) != 7*24 {
+		t.Errorf("Expected a cell for every weekday and hour, got %d", strings.Count(chart, `\fill[`))
+	}
+
+	if hourHeatmap(&analytics.HourGrid{}, i18n.For("en")) != "" {
+		t.Error("Expected no heatmap without messages")
+	}
+}
+
+func TestCalendarHeatmap(t *testing.T) {
+	chart := CalendarHeatmap(analytics.Calendar(2023, map[string]int{"2023-01-01": 4, "2023-07-04": 1}), i18n.For("en"))
+
+	// Every day is drawn, the busiest darkest
+	if got := strings.Count(chart, `\fill`); got != 365 {
+		t.Errorf("Expected 365 days, got %d", got)
+	}
+	if !strings.Contains(chart, `sentmessage!100`) || !strings.Contains(chart, `sentmessage!36`) {
+		t.Errorf("Expected days shaded by volume, got:\n%s", chart)
+	}
+	if got := strings.Count(chart, "{Jan}"); got != 1 || !strings.Contains(chart, "{Dec}") {
+		t.Error("Expected each month labelled once")
+	}
+
+	// January 1, 2023 was a Sunday, the bottom of the first week
+	if !strings.Contains(chart, `\fill[sentmessage!100] (0.00,0.00)`) {
+		t.Error("Expected January 1 at the bottom of the first column")
+	}
+}
+
+func TestGenerateCalendarPage(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("hi"), FormattedDate: time.Date(2022, 3, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text("hello"), FormattedDate: time.Date(2023, 3, 4, 9, 0, 0, 0, time.UTC)},
+		},
+		Config: &models.BookConfig{},
+	}
+
+	if page := plugin.generateCalendarPage(ctx, tm); page != "" {
+		t.Errorf("Expected no calendar page unless enabled, got:\n%s", page)
+	}
+
+	ctx.Config.CalendarPage = true
+	page := plugin.generateCalendarPage(ctx, tm)
+	if got := strings.Count(page, `\begin{tikzpicture}`); got != 2 {
+		t.Errorf("Expected a calendar per year, got %d", got)
+	}
+	for _, want := range []string{"Every Day", `{\small\bfseries 2022}`, `{\small\bfseries 2023}`, "1 message "} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected %q in calendar page, got:\n%s", want, page)
+		}
+	}
+}
+
+func TestVolumeFrontMatter(t *testing.T) {
+	plugin := NewTeXPlugin()
+	ctx := &output.GenerationContext{
+		Config: &models.BookConfig{Title: "Us"},
+		Volume: &output.Volume{
+			Number:    2,
+			Count:     3,
+			From:      time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+			To:        time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+			FirstPage: 301,
+		},
+	}
+
+	title := plugin.generateTitlePage(ctx)
+	for _, want := range []string{"Volume 2}", "March 2021 – June 2022", "\\setcounter{page}{302}"} {
+		if !strings.Contains(title, want) {
+			t.Errorf("Expected %q in title page:\n%s", want, title)
+		}
+	}
+	if copyright := plugin.generateCopyrightPage(ctx); !strings.Contains(copyright, "Volume 2 of 3, March 2021 – June 2022.") {
+		t.Errorf("Expected the volume on the copyright page:\n%s", copyright)
+	}
+
+	// Restarted numbering leaves the page counter alone
+	ctx.Volume.FirstPage = 1
+	if title := plugin.generateTitlePage(ctx); strings.Contains(title, "setcounter{page}") {
+		t.Errorf("Expected no page counter for restarted numbering:\n%s", title)
+	}
+	ctx.Volume = nil
+	if title := plugin.generateTitlePage(ctx); strings.Contains(title, "Volume") {
+		t.Errorf("Expected no volume on an unsplit book:\n%s", title)
+	}
+}
+
+func TestWriteMessagesChapterStrategies(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", ChatID: 1, Text: text("hi"), FormattedDate: time.Date(2022, 1, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", ChatID: 2, Text: text("group hello"), FormattedDate: time.Date(2022, 2, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "3", ChatID: 1, Text: text("again"), FormattedDate: time.Date(2022, 5, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "4", ChatID: 1, Text: text("new year"), FormattedDate: time.Date(2023, 1, 4, 9, 0, 0, 0, time.UTC)},
+		},
+		ChatNames: map[int]string{1: "Sam", 2: "Hiking Crew"},
+	}
+
+	for _, tt := range []struct {
+		strategy string
+		chapters []string
+	}{
+		{"", []string{`\chapter{January 2022}`, `\chapter{February 2022}`, `\chapter{May 2022}`, `\chapter{January 2023}`}},
+		{models.ChaptersByQuarter, []string{`\chapter{January – March 2022}`, `\chapter{April – June 2022}`, `\chapter{January – March 2023}`}},
+		{models.ChaptersByYear, []string{`\chapter{2022}`, `\chapter{2023}`}},
+		{models.ChaptersByChat, []string{`\chapter{Sam}`, `\chapter{Hiking Crew}`}},
+		{models.ChaptersNone, nil},
+	} {
+		ctx.Config = &models.BookConfig{ChapterStrategy: tt.strategy}
+		var builder strings.Builder
+		plugin.writeMessages(&builder, ctx, tm)
+		result := builder.String()
+
+		if got := strings.Count(result, `\chapter{`); got != len(tt.chapters) {
+			t.Errorf("%q: expected %d chapters, got %d:\n%s", tt.strategy, len(tt.chapters), got, result)
+		}
+		last := -1
+		for _, chapter := range tt.chapters {
+			at := strings.Index(result, chapter)
+			if at <= last {
+				t.Errorf("%q: expected %s after the chapters before it:\n%s", tt.strategy, chapter, result)
+			}
+			last = at
+		}
+	}
+
+	// Chapters by chat keep each conversation together, in date order
+	ctx.Config = &models.BookConfig{ChapterStrategy: models.ChaptersByChat}
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+	if !(strings.Index(result, "new year") < strings.Index(result, `\chapter{Hiking Crew}`)) {
+		t.Errorf("Expected Sam's messages before the group's:\n%s", result)
+	}
+	if !strings.Contains(result, chapterStartMarker+"chat-2\n") {
+		t.Errorf("Expected chapter markers keyed by chat:\n%s", result)
+	}
+}
+
+func TestGeneratePageStructure(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	ctx := &output.GenerationContext{Config: &models.BookConfig{}}
+	if page := plugin.generatePageStructure(ctx, tm); !strings.Contains(page, `\setcounter{tocdepth}{ 1 }`) || !strings.Contains(page, `\tableofcontents`) {
+		t.Errorf("Expected a contents page listing days, got:\n%s", page)
+	}
+	ctx.Config.TOCDepth = 1
+	if page := plugin.generatePageStructure(ctx, tm); !strings.Contains(page, `\setcounter{tocdepth}{ 0 }`) {
+		t.Errorf("Expected a contents page listing chapters only, got:\n%s", page)
+	}
+	ctx.Config.HideTOC = true
+	if page := plugin.generatePageStructure(ctx, tm); page != "" {
+		t.Errorf("Expected no contents page, got:\n%s", page)
+	}
+}
+
+func TestGeneratePDFMetadata(t *testing.T) {
+	plugin := NewTeXPlugin()
+	ctx := &output.GenerationContext{Config: &models.BookConfig{Title: "Sam & Me", Author: "Alex"}}
+
+	metadata := plugin.generatePDFMetadata(ctx, time.Date(2024, 3, 9, 14, 5, 30, 0, time.FixedZone("IST", 5*3600+30*60)))
+	for _, want := range []string{
+		"pdftitle={Sam \\& Me},",
+		"pdfauthor={Alex},",
+		"pdfcreator={threadbound},",
+		"pdfcreationdate={D:20240309140530+05'30'},",
+	} {
+		if !strings.Contains(metadata, want) {
+			t.Errorf("Expected %q in:\n%s", want, metadata)
+		}
+	}
+
+	ctx.Config.Author = ""
+	if metadata := plugin.generatePDFMetadata(ctx, time.Now()); strings.Contains(metadata, "pdfauthor") {
+		t.Errorf("Expected no author without one configured, got:\n%s", metadata)
+	}
+}
+
+func TestWriteMessagesDayMarks(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("morning"), FormattedDate: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text("evening"), FormattedDate: time.Date(2024, 3, 4, 21, 0, 0, 0, time.UTC)},
+			{GUID: "3", Text: text("next day"), FormattedDate: time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)},
+		},
+		Config: &models.BookConfig{},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	// Each day's heading marks its short date for the running header
+	if !strings.Contains(result, "\\section{Monday, March 4, 2024}\n\\daymark{Mar 4, 2024}\n") || !strings.Contains(result, `\daymark{Mar 5, 2024}`) {
+		t.Errorf("Expected a day mark after each day's heading:\n%s", result)
+	}
+	if got := strings.Count(result, `\daymark{`); got != 2 {
+		t.Errorf("Expected a mark per day, got %d", got)
+	}
+}
+
+func TestWriteMessagesPageBreaks(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	alice := 1
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("morning"), HandleID: &alice, FormattedDate: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text("hi"), IsFromMe: true, FormattedDate: time.Date(2024, 3, 4, 9, 1, 0, 0, time.UTC)},
+		},
+		Handles: map[int]models.Handle{alice: {ID: alice, DisplayName: "Alice"}},
+		Config:  &models.BookConfig{DayMinLines: 10},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	// The day's heading needs room below it, and labels stay with their bubbles
+	for _, want := range []string{
+		"\\needspace{10\\baselineskip}\n\\section{Monday, March 4, 2024}",
+		"\\textbf{ Alice } \\small\\textcolor{gray}{ 9:00 AM }\n\n\\nopagebreak\n\\speakerstarts",
+		"\\small\\textcolor{gray}{ 9:01 AM }\n\n\\nopagebreak\n\\speakerstarts",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in:\n%s", want, result)
+		}
+	}
+}
+
+func TestWriteMessagesDayGaps(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("see you"), IsFromMe: true, FormattedDate: start},
+			{GUID: "2", Text: text("next day"), IsFromMe: true, FormattedDate: start.AddDate(0, 0, 1)},
+			{GUID: "3", Text: text("long time"), IsFromMe: true, FormattedDate: start.AddDate(0, 0, 22)},
+		},
+		Config: &models.BookConfig{GapDays: 7},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	if got := strings.Count(result, `\textbullet`); got != 6 {
+		t.Errorf("Expected one separator, got %d bullets:\n%s", got, result)
+	}
+	if !strings.Contains(result, `{\itshape three weeks later}`) || !strings.Contains(result, "\\nopagebreak\n\\section{Tuesday, March 26, 2024}") {
+		t.Errorf("Expected \"three weeks later\" before the last day:\n%s", result)
+	}
+
+	ctx.Config.GapDays = 0
+	builder.Reset()
+	plugin.writeMessages(&builder, ctx, tm)
+	if strings.Contains(builder.String(), `\textbullet`) {
+		t.Error("Expected no separators unless gap_days is set")
+	}
+}
+
+func TestWriteMessagesChapterSummaries(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("moving day"), IsFromMe: true, FormattedDate: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text("unpacked"), IsFromMe: true, FormattedDate: time.Date(2024, 4, 2, 9, 0, 0, 0, time.UTC)},
+		},
+		Config:    &models.BookConfig{},
+		Summaries: map[string]string{"2024-03": "They moved flats & found 100% of the boxes."},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	if !strings.Contains(result, "\\chapter{March 2024}\n\n\\begin{quote}\n\\small\\textbf{In this chapter}") {
+		t.Errorf("Expected the summary under the March heading:\n%s", result)
+	}
+	if !strings.Contains(result, `{\itshape They moved flats \& found 100\% of the boxes.\par}`) {
+		t.Errorf("Expected the summary escaped:\n%s", result)
+	}
+	if strings.Count(result, "In this chapter") != 1 {
+		t.Errorf("Expected no summary for April, which has none:\n%s", result)
+	}
+}
+
+func TestWriteMessagesWordClouds(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("the pizza was great"), IsFromMe: true, FormattedDate: time.Date(2023, 12, 30, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text("happy new year"), IsFromMe: true, FormattedDate: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)},
+			{GUID: "3", Text: text("more pizza"), IsFromMe: true, FormattedDate: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		Config: &models.BookConfig{WordCloud: models.WordCloudYear},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	result := builder.String()
+
+	for _, want := range []string{"{\\Large\\bfseries 2023 in Words}", "{\\Large\\bfseries 2024 in Words}", "{pizza};", "{happy};"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %s in the word clouds:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "{the};") {
+		t.Errorf("Expected stopwords left out:\n%s", result)
+	}
+	// Each year's cloud comes before its first chapter, and only once
+	if strings.Count(result, "in Words}") != 2 || strings.Index(result, "2024 in Words") > strings.Index(result, `\chapter{January 2024}`) {
+		t.Errorf("Expected a cloud before the first chapter of each year:\n%s", result)
+	}
+
+	ctx.Config.WordCloud = models.WordCloudBook
+	ctx.Config.ChapterStrategy = models.ChaptersNone
+	builder.Reset()
+	plugin.writeMessages(&builder, ctx, tm)
+	result = builder.String()
+	if strings.Count(result, "Our Words") != 1 || strings.Index(result, "Our Words") > strings.Index(result, `\section{`) {
+		t.Errorf("Expected one cloud before the first day:\n%s", result)
+	}
+}
+
+func TestWriteMessagesLocale(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	tm.Localize(i18n.For("fr"))
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	date := time.Date(2024, 3, 4, 21, 5, 0, 0, time.UTC)
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("bonsoir"), IsFromMe: true, FormattedDate: date},
+			{GUID: "2", Text: text("salut"), IsFromMe: true, FormattedDate: date.Add(time.Minute)},
+		},
+		Config: &models.BookConfig{Locale: "fr", StatsChapter: true},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	plugin.writeNumbers(&builder, ctx, tm)
+	result := builder.String()
+
+	for _, want := range []string{`\chapter{mars 2024}`, `\section{lundi 4 mars 2024}`, `\daymark{4 mars 2024}`, "21:05", `\chapter{En chiffres}`, "messages sur 1 jour"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in a French book, got:\n%s", want, result)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package tex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"threadbound/internal/models"
+)
+
+// Scripts the main font seldom covers, each set up in the preamble only when a book uses it
+var (
+	cjkScripts = []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Bopomofo}
+	rtlScripts = map[string]*unicode.RangeTable{"arabic": unicode.Arabic, "hebrew": unicode.Hebrew}
+)
+
+// scriptsBlock matches the definitions ScriptDefinitions writes into the preamble
+var scriptsBlock = regexp.MustCompile(`(?s)% Scripts used in this book\n.*?% End of scripts\n`)
+
+// rtlScript names the right-to-left script of r, arabic or hebrew, or returns "" for any
+// other character
+func rtlScript(r rune) string {
+	for name, table := range rtlScripts {
+		if unicode.Is(table, r) && !unicode.IsDigit(r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// ScriptDefinitions sets up the scripts used in text that the main font can't be relied on
+// for, so they don't print as tofu: Chinese, Japanese and Korean through xeCJK (luatexja
+// under LuaLaTeX), and Arabic and Hebrew with fonts of their own and the \arabictext and
+// \hebrewtext commands escaped text wraps them in, which set them right to left. Fonts come
+// from the theme.
+func ScriptDefinitions(text string, theme models.ThemeConfig) string {
+	theme = theme.WithDefaults()
+	var cjk bool
+	rtl := make(map[string]bool)
+	for _, r := range text {
+		if unicode.In(r, cjkScripts...) {
+			cjk = true
+		} else if name := rtlScript(r); name != "" {
+			rtl[name] = true
+		}
+	}
+	if !cjk && len(rtl) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("% Scripts used in this book\n")
+	b.WriteString("\\usepackage{iftex}\n")
+	if cjk {
+		fmt.Fprintf(&b, "\\ifLuaTeX\n\\usepackage{luatexja-fontspec}\n\\setmainjfont{%s}\n\\else\n\\usepackage{xeCJK}\n\\setCJKmainfont{%s}\n\\fi\n", theme.CJKFont, theme.CJKFont)
+	}
+	if len(rtl) > 0 {
+		// XeTeX reverses text with TeX--XeT, LuaTeX with its own direction primitives
+		b.WriteString("\\ifLuaTeX\n")
+		b.WriteString("\\DeclareRobustCommand{\\righttoleft}[1]{{\\textdir TRT #1}}\n")
+		b.WriteString("\\DeclareRobustCommand{\\lefttoright}[1]{{\\textdir TLT #1}}\n")
+		b.WriteString("\\else\n\\TeXXeTstate=1\n")
+		b.WriteString("\\DeclareRobustCommand{\\righttoleft}[1]{{\\beginR #1\\endR}}\n")
+		b.WriteString("\\DeclareRobustCommand{\\lefttoright}[1]{{\\beginL #1\\endL}}\n")
+		b.WriteString("\\fi\n")
+		// Bookmarks take the text without the commands; documents without hyperref have none
+		b.WriteString("\\providecommand{\\pdfstringdefDisableCommands}[1]{}\n")
+		b.WriteString("\\pdfstringdefDisableCommands{\\def\\righttoleft#1{#1}\\def\\lefttoright#1{#1}}\n")
+	}
+	for _, script := range []struct{ name, font, otf string }{
+		{"arabic", theme.ArabicFont, "Arabic"},
+		{"hebrew", theme.HebrewFont, "Hebrew"},
+	} {
+		if !rtl[script.name] {
+			continue
+		}
+		// LuaLaTeX only joins Arabic letters with the HarfBuzz renderer
+		fmt.Fprintf(&b, "\\ifLuaTeX\n\\newfontfamily\\%sfont[Script=%s, Renderer=HarfBuzz]{%s}\n\\else\n\\newfontfamily\\%sfont[Script=%s]{%s}\n\\fi\n",
+			script.name, script.otf, script.font, script.name, script.otf, script.font)
+		fmt.Fprintf(&b, "\\DeclareRobustCommand{\\%stext}[1]{{\\%sfont\\righttoleft{#1}}}\n", script.name, script.name)
+		fmt.Fprintf(&b, "\\pdfstringdefDisableCommands{\\def\\%stext#1{#1}}\n", script.name)
+	}
+	b.WriteString("% End of scripts\n")
+	return b.String()
+}
+
+// markDirection wraps each run of Arabic or Hebrew in escaped text in \arabictext or
+// \hebrewtext, so it's set right to left. A run takes in the spaces, punctuation and numbers
+// between its words, and ends at its last letter before any letter of another script.
+// Numbers in a run stay left to right.
+func markDirection(text string) string {
+	if !strings.ContainsFunc(text, func(r rune) bool { return rtlScript(r) != "" }) {
+		return text
+	}
+
+	runes := []rune(text)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		script := rtlScript(runes[i])
+		if script == "" {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		end := i + 1
+		for j := i + 1; j < len(runes); j++ {
+			if name := rtlScript(runes[j]); name == script {
+				end = j + 1
+			} else if name != "" || unicode.IsLetter(runes[j]) {
+				break
+			}
+		}
+		fmt.Fprintf(&b, "\\%stext{%s}", script, markNumbers(runes[i:end]))
+		i = end
+	}
+	return b.String()
+}
+
+// markNumbers wraps the numbers in a right-to-left run in \lefttoright, keeping separators
+// such as the point in 3.5 or the colon in 10:30 inside them
+func markNumbers(run []rune) string {
+	var b strings.Builder
+	for i := 0; i < len(run); {
+		if !unicode.IsDigit(run[i]) {
+			b.WriteRune(run[i])
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(run) && (unicode.IsDigit(run[end]) ||
+			(strings.ContainsRune(".,:/", run[end]) && end+1 < len(run) && unicode.IsDigit(run[end+1]))) {
+			end++
+		}
+		fmt.Fprintf(&b, "\\lefttoright{%s}", string(run[i:end]))
+		i = end
+	}
+	return b.String()
+}
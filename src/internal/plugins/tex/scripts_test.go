@@ -0,0 +1,57 @@
+package tex
+
+import (
+	"strings"
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestScriptDefinitions(t *testing.T) {
+	if ScriptDefinitions("plain English, café 👋", models.ThemeConfig{}) != "" {
+		t.Error("Expected no definitions for text in Latin script")
+	}
+
+	defs := ScriptDefinitions("晚饭吃什么? and שלום", models.ThemeConfig{HebrewFont: "David CLM"})
+	for _, want := range []string{
+		`\usepackage{xeCJK}`, `\setCJKmainfont{Noto Sans CJK SC}`, `\setmainjfont{Noto Sans CJK SC}`,
+		`\newfontfamily\hebrewfont[Script=Hebrew]{David CLM}`, `\DeclareRobustCommand{\hebrewtext}`, `\TeXXeTstate=1`,
+	} {
+		if !strings.Contains(defs, want) {
+			t.Errorf("Expected %s in definitions:\n%s", want, defs)
+		}
+	}
+	if strings.Contains(defs, `\arabicfont`) {
+		t.Errorf("Expected no Arabic font for text without Arabic:\n%s", defs)
+	}
+	if !scriptsBlock.MatchString(defs) {
+		t.Errorf("Expected the definitions to be one block:\n%s", defs)
+	}
+
+	// Right-to-left text alone doesn't need xeCJK
+	if defs := ScriptDefinitions("مرحبا", models.ThemeConfig{}); strings.Contains(defs, "CJK") || !strings.Contains(defs, `\arabicfont[Script=Arabic]{Noto Naskh Arabic}`) {
+		t.Errorf("Expected only Arabic definitions:\n%s", defs)
+	}
+}
+
+func TestMarkDirection(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no right-to-left text", "see you at 7", "see you at 7"},
+		{"whole message", "مرحبا بك!", `\arabictext{مرحبا بك}!`},
+		{"embedded in English", "she said שלום עליכם to me", `she said \hebrewtext{שלום עליכם} to me`},
+		{"numbers stay left to right", "נתראה ב-10:30 מחר", `\hebrewtext{נתראה ב-\lefttoright{10:30} מחר}`},
+		{"scripts are separate runs", "שלום مرحبا", `\hebrewtext{שלום} \arabictext{مرحبا}`},
+		{"escaped characters", `100% نعم`, `100\% \arabictext{نعم}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EscapeLaTeX(tt.text); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package tex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// Chapter markers are TeX comments around each month's chapter, keyed by
+// analytics.ChapterKeyFormat, so chapters can be found again and replaced in a written book
+const (
+	chapterStartMarker = "% threadbound:chapter "
+	chapterEndMarker   = "% threadbound:end-chapter "
+)
+
+// emojiBlock matches the emoji definitions EmojiDefinitions writes into the preamble
+var emojiBlock = regexp.MustCompile(`(?m)^% Emoji used in this book\n(?:\\newunicodechar.*\n)*`)
+
+// chapterSpan is where one chapter sits in a book, end marker included
+type chapterSpan struct {
+	key        string
+	start, end int
+}
+
+// findChapters locates every marked chapter in a book, in order
+func findChapters(book string) ([]chapterSpan, error) {
+	var spans []chapterSpan
+	offset := 0
+	for {
+		start := strings.Index(book[offset:], chapterStartMarker)
+		if start < 0 {
+			return spans, nil
+		}
+		start += offset
+
+		keyEnd := strings.IndexByte(book[start:], '\n')
+		if keyEnd < 0 {
+			return nil, fmt.Errorf("chapter marker at the end of the book")
+		}
+		key := book[start+len(chapterStartMarker) : start+keyEnd]
+
+		endMarker := chapterEndMarker + key + "\n"
+		end := strings.Index(book[start:], endMarker)
+		if end < 0 {
+			return nil, fmt.Errorf("chapter %s has no end marker", key)
+		}
+		end += start + len(endMarker)
+
+		spans = append(spans, chapterSpan{key: key, start: start, end: end})
+		offset = end
+	}
+}
+
+// SpliceChapters replaces the chapters of book whose keys are in months with the chapters
+// of the same month in patch, a book generated from just those months. Chapters of those
+// months missing from patch, now without messages, are dropped. The emoji and script
+// definitions are rewritten for the spliced book, with the theme's fonts. It returns the new
+// book and the months replaced.
+func SpliceChapters(book, patch string, months map[string]bool, theme models.ThemeConfig) (string, []string, error) {
+	bookChapters, err := findChapters(book)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(bookChapters) == 0 {
+		return "", nil, fmt.Errorf("the book has no chapter markers; generate it again in full first")
+	}
+	patchChapters, err := findChapters(patch)
+	if err != nil {
+		return "", nil, err
+	}
+
+	replacements := make(map[string]string)
+	for _, span := range patchChapters {
+		replacements[span.key] = patch[span.start:span.end]
+	}
+	inBook := make(map[string]bool)
+	for _, span := range bookChapters {
+		inBook[span.key] = true
+	}
+	for key := range replacements {
+		if !inBook[key] {
+			return "", nil, fmt.Errorf("chapter %s isn't in the book; generate it again in full to add new chapters", key)
+		}
+	}
+
+	var b strings.Builder
+	var replaced []string
+	last := 0
+	for _, span := range bookChapters {
+		if !months[span.key] {
+			continue
+		}
+		b.WriteString(book[last:span.start])
+		b.WriteString(replacements[span.key])
+		last = span.end
+		replaced = append(replaced, span.key)
+	}
+	b.WriteString(book[last:])
+	result := b.String()
+
+	// Emoji in the new chapters need definitions; ones no longer used can go
+	const beginDocument = "\\begin{document}"
+	bodyStart := strings.Index(result, beginDocument)
+	if bodyStart < 0 {
+		return "", nil, fmt.Errorf("the book has no \\begin{document}")
+	}
+	emoji := EmojiDefinitions(result[bodyStart:])
+	if location := emojiBlock.FindStringIndex(result[:bodyStart]); location != nil {
+		result = result[:location[0]] + emoji + result[location[1]:]
+	} else {
+		result = result[:bodyStart] + emoji + result[bodyStart:]
+	}
+
+	// As do the scripts they're written in
+	bodyStart = strings.Index(result, beginDocument)
+	scripts := ScriptDefinitions(result[bodyStart:], theme)
+	if location := scriptsBlock.FindStringIndex(result[:bodyStart]); location != nil {
+		result = result[:location[0]] + scripts + result[location[1]:]
+	} else {
+		result = result[:bodyStart] + scripts + result[bodyStart:]
+	}
+
+	return result, replaced, nil
+}
@@ -0,0 +1,97 @@
+package tex
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+// markedChapter writes a chapter the way writeMessages marks it
+func markedChapter(key, body string) string {
+	return "\n" + chapterStartMarker + key + "\n\\chapter{" + key + "}\n\n" + body + "\n" + chapterEndMarker + key + "\n"
+}
+
+func TestSpliceChapters(t *testing.T) {
+	book := "\\documentclass{book}\n% Emoji used in this book\n\\newunicodechar{😀}{{\\emojifont\\symbol{\"1F600}}}\n\\begin{document}\n" +
+		markedChapter("2023-12", "Old December 😀") +
+		"\\clearpage notes\n" +
+		markedChapter("2024-01", "Typo in Janaury") +
+		markedChapter("2024-02", "February") +
+		markedChapter("2024-03", "March") +
+		"\\end{document}\n"
+	patch := "\\begin{document}\n" + markedChapter("2024-01", "Fixed January 🎉") + "\\end{document}\n"
+
+	result, replaced, err := SpliceChapters(book, patch, map[string]bool{"2024-01": true, "2024-02": true}, models.ThemeConfig{})
+	if err != nil {
+		t.Fatalf("Failed to splice: %v", err)
+	}
+	if strings.Join(replaced, ",") != "2024-01,2024-02" {
+		t.Errorf("Unexpected chapters replaced: %v", replaced)
+	}
+
+	for _, want := range []string{"Old December", "\\clearpage notes", "Fixed January 🎉", "March", "\\symbol{\"1F389}", "\\symbol{\"1F600}"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in spliced book:\n%s", want, result)
+		}
+	}
+	// February had no messages left, so its chapter goes
+	for _, unwanted := range []string{"Janaury", "February"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("Expected %q gone from spliced book:\n%s", unwanted, result)
+		}
+	}
+	if strings.Count(result, "% Emoji used in this book") != 1 {
+		t.Error("Expected the emoji definitions rewritten in place")
+	}
+}
+
+func TestSpliceChaptersErrors(t *testing.T) {
+	book := "\\begin{document}\n" + markedChapter("2024-01", "January") + "\\end{document}\n"
+
+	if _, _, err := SpliceChapters("\\begin{document}\n\\chapter{January 2024}\n", book, map[string]bool{"2024-01": true}, models.ThemeConfig{}); err == nil {
+		t.Error("Expected an error for a book without markers")
+	}
+
+	patch := "\\begin{document}\n" + markedChapter("2024-02", "February") + "\\end{document}\n"
+	if _, _, err := SpliceChapters(book, patch, map[string]bool{"2024-02": true}, models.ThemeConfig{}); err == nil {
+		t.Error("Expected an error for a chapter the book doesn't have")
+	}
+
+	unclosed := "\\begin{document}\n" + chapterStartMarker + "2024-01\n\\chapter{January 2024}\n"
+	if _, _, err := SpliceChapters(unclosed, patch, map[string]bool{"2024-01": true}, models.ThemeConfig{}); err == nil {
+		t.Error("Expected an error for a chapter without an end marker")
+	}
+}
+
+func TestWriteMessagesChapterMarkers(t *testing.T) {
+	plugin := NewTeXPlugin()
+	tm := output.NewTemplateManagerWithEmbed("", embeddedTemplates, "templates")
+	if err := tm.LoadTemplates(plugin.GetRequiredTemplates()); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{GUID: "1", Text: text("January"), IsFromMe: true, FormattedDate: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)},
+			{GUID: "2", Text: text("February"), IsFromMe: true, FormattedDate: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		Config: &models.BookConfig{},
+	}
+
+	var builder strings.Builder
+	plugin.writeMessages(&builder, ctx, tm)
+	chapters, err := findChapters(builder.String())
+	if err != nil {
+		t.Fatalf("Failed to find chapters: %v", err)
+	}
+	if len(chapters) != 2 || chapters[0].key != "2024-01" || chapters[1].key != "2024-02" {
+		t.Fatalf("Expected both months marked, got %+v", chapters)
+	}
+	if first := builder.String()[chapters[0].start:chapters[0].end]; strings.Contains(first, "February") || !strings.Contains(first, "\\chapter{January 2024}") {
+		t.Errorf("Unexpected January chapter:\n%s", first)
+	}
+}
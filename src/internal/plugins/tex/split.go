@@ -0,0 +1,85 @@
+package tex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// includeLine matches the \include a split book leaves in place of a chapter
+var includeLine = regexp.MustCompile(`(?m)^\\include\{([^}]*)\}$`)
+
+// SplitChapters moves each marked chapter of book out into its own file, leaving an
+// \include of it between the chapter's markers. dir is the folder the chapter files go
+// in, as TeX will look for them. It returns the master file and each chapter's file
+// contents, keyed by analytics.ChapterKeyFormat.
+func SplitChapters(book, dir string) (string, map[string]string, error) {
+	spans, err := findChapters(book)
+	if err != nil {
+		return "", nil, err
+	}
+	if strings.ContainsAny(dir, " %#{}") {
+		return "", nil, fmt.Errorf("chapter folder %q can't be included by TeX; avoid spaces and %%#{} in the output name", dir)
+	}
+
+	chapters := make(map[string]string, len(spans))
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(book[last:span.start])
+		b.WriteString(chapterStartMarker + span.key + "\n")
+		b.WriteString("\\include{" + texPath(dir+"/"+span.key) + "}\n")
+		b.WriteString(chapterEndMarker + span.key + "\n")
+		chapters[span.key] = book[span.start:span.end]
+		last = span.end
+	}
+	b.WriteString(book[last:])
+	return b.String(), chapters, nil
+}
+
+// IncludedChapters lists the chapters a split master file includes: the name each is
+// included by, keyed by analytics.ChapterKeyFormat. A book that isn't split has none.
+func IncludedChapters(master string) (map[string]string, error) {
+	spans, err := findChapters(master)
+	if err != nil {
+		return nil, err
+	}
+
+	included := make(map[string]string)
+	for _, span := range spans {
+		if match := includeLine.FindStringSubmatch(master[span.start:span.end]); match != nil {
+			included[span.key] = match[1]
+		}
+	}
+	return included, nil
+}
+
+// JoinChapters puts the chapters of a split master file back in place of their \include,
+// reading each with read, which is given the included name. It reports whether any
+// chapters were included, so a book that was never split is returned as it is.
+func JoinChapters(master string, read func(name string) (string, error)) (string, bool, error) {
+	spans, err := findChapters(master)
+	if err != nil {
+		return "", false, err
+	}
+
+	var b strings.Builder
+	joined := false
+	last := 0
+	for _, span := range spans {
+		match := includeLine.FindStringSubmatch(master[span.start:span.end])
+		if match == nil {
+			continue
+		}
+		chapter, err := read(match[1])
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read chapter %s: %w", span.key, err)
+		}
+		b.WriteString(master[last:span.start])
+		b.WriteString(chapter)
+		last = span.end
+		joined = true
+	}
+	b.WriteString(master[last:])
+	return b.String(), joined, nil
+}
@@ -0,0 +1,65 @@
+package tex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSplitAndJoinChapters(t *testing.T) {
+	book := "\\documentclass{book}\n\\begin{document}\n" +
+		markedChapter("2024-01", "January") +
+		"\\clearpage notes\n" +
+		markedChapter("2024-02", "February") +
+		"\\end{document}\n"
+
+	master, chapters, err := SplitChapters(book, "out/book-chapters")
+	if err != nil {
+		t.Fatalf("Failed to split: %v", err)
+	}
+	if len(chapters) != 2 || !strings.Contains(chapters["2024-01"], "January") || strings.Contains(chapters["2024-01"], "notes") {
+		t.Errorf("Unexpected chapters: %v", chapters)
+	}
+	for _, want := range []string{"\\include{out/book-chapters/2024-01}\n", "\\include{out/book-chapters/2024-02}\n", "\\clearpage notes"} {
+		if !strings.Contains(master, want) {
+			t.Errorf("Expected %q in master:\n%s", want, master)
+		}
+	}
+	if strings.Contains(master, "January") {
+		t.Errorf("Expected the chapters moved out of the master:\n%s", master)
+	}
+
+	included, err := IncludedChapters(master)
+	if err != nil || included["2024-02"] != "out/book-chapters/2024-02" {
+		t.Errorf("Unexpected included chapters %v (%v)", included, err)
+	}
+
+	joined, split, err := JoinChapters(master, func(name string) (string, error) {
+		return chapters[strings.TrimPrefix(name, "out/book-chapters/")], nil
+	})
+	if err != nil || !split {
+		t.Fatalf("Failed to join: %v", err)
+	}
+	if joined != book {
+		t.Errorf("Expected the book back, got:\n%s", joined)
+	}
+}
+
+func TestJoinChaptersUnsplit(t *testing.T) {
+	book := "\\begin{document}\n" + markedChapter("2024-01", "January") + "\\end{document}\n"
+	joined, split, err := JoinChapters(book, func(string) (string, error) { return "", fmt.Errorf("not split") })
+	if err != nil || split || joined != book {
+		t.Errorf("Expected an unsplit book returned as it is, got %v %v", split, err)
+	}
+
+	if included, err := IncludedChapters(book); err != nil || len(included) != 0 {
+		t.Errorf("Expected no included chapters, got %v", included)
+	}
+}
+
+func TestSplitChaptersRejectsSpaces(t *testing.T) {
+	book := "\\begin{document}\n" + markedChapter("2024-01", "January") + "\\end{document}\n"
+	if _, _, err := SplitChapters(book, "My Book-chapters"); err == nil {
+		t.Error("Expected an error for a folder TeX can't include from")
+	}
+}
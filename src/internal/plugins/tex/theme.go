@@ -0,0 +1,33 @@
+package tex
+
+import (
+	"fmt"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// themeDefinitions writes the preamble for the configured fonts and bubble style. Message
+// templates draw bubbles with the "sent bubble" and "received bubble" TikZ styles, and the
+// reactions on a bubble's corner with "reaction badge" and senders' avatars with "avatar".
+func themeDefinitions(theme models.ThemeConfig) string {
+	theme = theme.WithDefaults()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\\setmainfont{%s}\n", theme.Font)
+	fmt.Fprintf(&b, "\\newfontfamily\\emojifont{%s}\n", theme.EmojiFont)
+	fmt.Fprintf(&b, "\\definecolor{sentbubble}{HTML}{%s}\n", strings.ToUpper(strings.TrimPrefix(theme.SentColor, "#")))
+	fmt.Fprintf(&b, "\\definecolor{receivedbubble}{HTML}{%s}\n", strings.ToUpper(strings.TrimPrefix(theme.ReceivedColor, "#")))
+
+	// A macro rather than a length so \textwidth is read where it's used, after geometry
+	// has set it
+	fmt.Fprintf(&b, "\\newcommand{\\bubblewidth}{%.2f\\textwidth}\n", theme.BubbleMaxWidth)
+
+	fmt.Fprintf(&b, "\\tikzset{bubble/.style={draw=none, rounded corners=%s, text width=\\bubblewidth, align=left, inner sep=8pt}}\n", theme.CornerRadius)
+	b.WriteString("\\tikzset{sent bubble/.style={bubble, fill=sentbubble}}\n")
+	b.WriteString("\\tikzset{received bubble/.style={bubble, fill=receivedbubble}}\n")
+	b.WriteString("\\newcommand{\\avatarsize}{0.7cm}\n")
+	b.WriteString("\\tikzset{avatar/.style={circle, minimum size=\\avatarsize, inner sep=0pt, text=white, font=\\footnotesize\\bfseries}}\n")
+	b.WriteString("\\tikzset{reaction badge/.style={draw=receivedbubble, fill=white, line width=0.8pt, rounded corners=6pt, inner sep=2pt, font=\\small}}\n")
+	return b.String()
+}
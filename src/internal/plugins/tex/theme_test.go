@@ -0,0 +1,31 @@
+package tex
+
+import (
+	"strings"
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestThemeDefinitions(t *testing.T) {
+	defs := themeDefinitions(models.ThemeConfig{
+		Font:           "Georgia",
+		SentColor:      "#34c759",
+		CornerRadius:   "10pt",
+		BubbleMaxWidth: 0.6,
+	})
+
+	for _, want := range []string{
+		`\setmainfont{Georgia}`,
+		`\newfontfamily\emojifont{Symbola}`,
+		`\definecolor{sentbubble}{HTML}{34C759}`,
+		`\definecolor{receivedbubble}{HTML}{CCCCCC}`,
+		`\newcommand{\bubblewidth}{0.60\textwidth}`,
+		`reaction badge/.style`,
+		`rounded corners=10pt`,
+	} {
+		if !strings.Contains(defs, want) {
+			t.Errorf("Expected %s in theme:\n%s", want, defs)
+		}
+	}
+}
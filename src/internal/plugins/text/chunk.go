@@ -0,0 +1,78 @@
+package text
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken is about how many characters of English make a token for the tokenizers of
+// the common models; it's an estimate, so chunks are kept a little under their budget
+const charsPerToken = 4
+
+// EstimateTokens estimates how many tokens a model reads text as
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// Chunk splits output into pieces of at most about tokens each. Pieces break at blank
+// lines, which end each day of plain output, where they can; then between lines, which
+// each hold a message of JSON Lines; and only cut a line that alone is over the budget.
+// The pieces joined make the output again.
+func Chunk(output string, tokens int) []string {
+	if tokens <= 0 || EstimateTokens(output) <= tokens {
+		return []string{output}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	size := 0
+	add := func(part string) {
+		partSize := EstimateTokens(part)
+		if size > 0 && size+partSize > tokens {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			size = 0
+		}
+		current.WriteString(part)
+		size += partSize
+	}
+
+	for _, block := range splitAfter(output, "\n\n") {
+		if EstimateTokens(block) <= tokens {
+			add(block)
+			continue
+		}
+		for _, line := range splitAfter(block, "\n") {
+			for EstimateTokens(line) > tokens {
+				cut := cutAt(line, tokens*charsPerToken)
+				add(line[:cut])
+				line = line[cut:]
+			}
+			add(line)
+		}
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// splitAfter splits s after each sep, keeping it at the end of its piece
+func splitAfter(s, sep string) []string {
+	parts := strings.SplitAfter(s, sep)
+	if parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return parts
+}
+
+// cutAt returns the byte offset after the first n runes of s
+func cutAt(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
@@ -0,0 +1,66 @@
+package text
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"héllo wörld", 3},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestChunk(t *testing.T) {
+	day := func(name string) string {
+		return "--- " + name + " ---\n[10:00] Me: " + strings.Repeat("x", 40) + "\n[10:01] Alice: " + strings.Repeat("y", 40) + "\n\n"
+	}
+	output := "=== Book ===\n\n" + day("Monday") + day("Tuesday") + day("Wednesday")
+
+	if chunks := Chunk(output, 0); len(chunks) != 1 || chunks[0] != output {
+		t.Errorf("Expected no budget to keep one chunk, got %d", len(chunks))
+	}
+	if chunks := Chunk(output, 10000); len(chunks) != 1 {
+		t.Errorf("Expected output under the budget in one chunk, got %d", len(chunks))
+	}
+
+	// A day is about 30 tokens, so each chunk holds one
+	chunks := Chunk(output, 40)
+	if strings.Join(chunks, "") != output {
+		t.Error("Expected the chunks to make the output again")
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d: %q", len(chunks), chunks)
+	}
+	if !strings.HasPrefix(chunks[1], "--- Tuesday ---") || !strings.HasPrefix(chunks[2], "--- Wednesday ---") {
+		t.Errorf("Expected chunks to break between days, got %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if EstimateTokens(chunk) > 40 {
+			t.Errorf("Chunk over budget: %q", chunk)
+		}
+	}
+
+	// Days over the budget break between messages, and messages over it are cut
+	chunks = Chunk(output, 14)
+	if strings.Join(chunks, "") != output {
+		t.Error("Expected the chunks to make the output again")
+	}
+	for _, chunk := range chunks {
+		if EstimateTokens(chunk) > 14 {
+			t.Errorf("Chunk over budget: %q", chunk)
+		}
+	}
+}
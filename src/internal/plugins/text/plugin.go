@@ -2,12 +2,14 @@ package text
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 	"text/template"
 	"time"
 
+	"threadbound/internal/i18n"
 	"threadbound/internal/models"
 	"threadbound/internal/output"
 )
@@ -48,12 +50,17 @@ func (t *TextPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
 	if t.templateManager == nil {
 		t.templateManager = output.NewTemplateManager(ctx.Config.TemplateDir)
 	}
+	t.templateManager.Localize(output.Localizer(ctx.Config))
 
 	// Load templates
 	if err := t.loadTemplates(); err != nil {
 		return nil, fmt.Errorf("failed to load templates: %w", err)
 	}
 
+	if ctx.Config.Text.JSONL() {
+		return t.generateJSONL(ctx)
+	}
+
 	var buf bytes.Buffer
 
 	// Generate header
@@ -65,7 +72,7 @@ func (t *TextPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
 	buf.WriteString("\n")
 
 	// Group messages by date
-	messagesByDate := t.groupMessagesByDate(ctx.Messages)
+	messagesByDate := t.groupMessagesByDate(ctx.Messages, ctx.Config.Text.SystemMessages)
 
 	// Get sorted date keys
 	var dateKeys []string
@@ -82,7 +89,7 @@ func (t *TextPlugin) Generate(ctx *output.GenerationContext) ([]byte, error) {
 		}
 
 		// Generate date separator
-		dateSeparator, err := t.generateDateSeparator(messages[0].FormattedDate)
+		dateSeparator, err := t.generateDateSeparator(messages[0].FormattedDate, ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate date separator: %w", err)
 		}
@@ -125,8 +132,8 @@ func (t *TextPlugin) createDefaultTemplate(name string) error {
 	switch name {
 	case "header.txt":
 		content = `=== {{.Title}} ==={{if .Author}}
-by {{.Author}}{{end}}{{if .Stats}}
-Messages: {{.Stats.TotalMessages}} | Text Messages: {{.Stats.TextMessages}} | Contacts: {{.Stats.TotalContacts}}{{if not .Stats.StartDate.IsZero}} | Date Range: {{.Stats.StartDate.Format "Jan 2, 2006"}} - {{.Stats.EndDate.Format "Jan 2, 2006"}}{{end}}{{end}}
+{{t "by_author" "Author" .Author}}{{end}}{{if .Stats}}
+Messages: {{.Stats.TotalMessages}} | Text Messages: {{.Stats.TextMessages}} | Contacts: {{.Stats.TotalContacts}}{{if not .Stats.StartDate.IsZero}} | Date Range: {{date .Stats.StartDate "short_date"}} - {{date .Stats.EndDate "short_date"}}{{end}}{{end}}
 
 `
 	case "date-separator.txt":
@@ -140,7 +147,7 @@ Messages: {{.Stats.TotalMessages}} | Text Messages: {{.Stats.TextMessages}} | Co
 	}
 
 	// Parse and cache the template
-	tmpl, err := template.New(name).Parse(content)
+	tmpl, err := template.New(name).Funcs(t.templateManager.Localizer().Funcs()).Parse(content)
 	if err != nil {
 		return fmt.Errorf("failed to parse embedded template %s: %w", name, err)
 	}
@@ -158,12 +165,12 @@ func (t *TextPlugin) generateHeader(ctx *output.GenerationContext) (string, erro
 
 	// Use embedded template if not loaded from file
 	headerTemplate := `=== {{.Title}} ==={{if .Author}}
-by {{.Author}}{{end}}{{if .Stats}}
-Messages: {{.Stats.TotalMessages}} | Text Messages: {{.Stats.TextMessages}} | Contacts: {{.Stats.TotalContacts}}{{if not .Stats.StartDate.IsZero}} | Date Range: {{.Stats.StartDate.Format "Jan 2, 2006"}} - {{.Stats.EndDate.Format "Jan 2, 2006"}}{{end}}{{end}}
+{{t "by_author" "Author" .Author}}{{end}}{{if .Stats}}
+Messages: {{.Stats.TotalMessages}} | Text Messages: {{.Stats.TextMessages}} | Contacts: {{.Stats.TotalContacts}}{{if not .Stats.StartDate.IsZero}} | Date Range: {{date .Stats.StartDate "short_date"}} - {{date .Stats.EndDate "short_date"}}{{end}}{{end}}
 
 `
 
-	tmpl, err := template.New("header").Parse(headerTemplate)
+	tmpl, err := template.New("header").Funcs(output.Localizer(ctx.Config).Funcs()).Parse(headerTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -176,8 +183,8 @@ Messages: {{.Stats.TotalMessages}} | Text Messages: {{.Stats.TextMessages}} | Co
 	return buf.String(), nil
 }
 
-// generateDateSeparator generates a date separator line
-func (t *TextPlugin) generateDateSeparator(date time.Time) (string, error) {
+// generateDateSeparator generates a date separator line, in the book's locale
+func (t *TextPlugin) generateDateSeparator(date time.Time, ctx *output.GenerationContext) (string, error) {
 	dateTemplate := `--- {{.FormattedDate}} ---
 `
 
@@ -185,7 +192,7 @@ func (t *TextPlugin) generateDateSeparator(date time.Time) (string, error) {
 		FormattedDate string
 	}
 
-	formattedDate := date.Format("Monday, January 2, 2006")
+	formattedDate := output.Localizer(ctx.Config).Format(date, i18n.DayFormat)
 	data := DateData{FormattedDate: formattedDate}
 
 	tmpl, err := template.New("date-separator").Parse(dateTemplate)
@@ -201,23 +208,41 @@ func (t *TextPlugin) generateDateSeparator(date time.Time) (string, error) {
 	return buf.String(), nil
 }
 
+// messageData adds what the text output can record beyond the other formats
+type messageData struct {
+	*output.MessageTemplateData
+	GUID  string // Set when GUIDs are recorded
+	Event string // What a system message records, such as "renamed the conversation"
+}
+
 // generateMessage generates a single message
 func (t *TextPlugin) generateMessage(msg models.Message, ctx *output.GenerationContext) (string, error) {
-	// Skip messages without text
-	if msg.Text == nil || strings.TrimSpace(*msg.Text) == "" {
+	settings := ctx.Config.Text
+	if !included(msg, settings.SystemMessages) {
 		return "", nil
 	}
 
-	senderName := output.GetSenderNameWithConfig(msg, ctx.Handles, ctx.Config)
-	timeStr := output.FormatTimestamp(msg.FormattedDate, "time")
+	senderName := output.GetSenderName(msg, ctx.Handles, ctx.Config)
+	timeStr := output.Localizer(ctx.Config).Format(msg.FormattedDate, i18n.TimeFormat)
+	if settings.ISOTimestamps {
+		timeStr = msg.FormattedDate.Format(time.RFC3339)
+	}
 	reactions := ctx.Reactions[msg.GUID]
 
-	msgData := output.CreateMessageTemplateData(
-		msg, senderName, timeStr, true, true, reactions,
-	)
+	msgData := messageData{
+		MessageTemplateData: output.CreateMessageTemplateData(
+			msg, senderName, timeStr, true, true, reactions,
+		),
+	}
+	if settings.GUIDs {
+		msgData.GUID = msg.GUID
+	}
+	if settings.SystemMessages && msg.ItemType != 0 {
+		msgData.Event = systemEvent(msg.ItemType)
+	}
 
-	messageTemplate := `[{{.Timestamp}}] {{.Sender}}: {{.Text}}{{if .Reactions}} {{range .Reactions}}[{{.SenderName}}: {{.ReactionEmoji}}]{{end}}{{end}}{{if .Attachments}}
-  Attachments: {{range $i, $a := .Attachments}}{{if $i}}, {{end}}{{$a.Filename}}{{end}}{{end}}`
+	messageTemplate := `[{{.Timestamp}}] {{if .GUID}}<{{.GUID}}> {{end}}{{if .Event}}* {{.Sender}} {{.Event}}{{else}}{{.Sender}}: {{.Text}}{{if .Reactions}} {{range .Reactions}}[{{.SenderName}}: {{.ReactionEmoji}}]{{end}}{{end}}{{if .Attachments}}
+  Attachments: {{range $i, $a := .Attachments}}{{if $i}}, {{end}}{{$a.Filename}}{{end}}{{end}}{{end}}`
 
 	tmpl, err := template.New("message").Parse(messageTemplate)
 	if err != nil {
@@ -232,13 +257,72 @@ func (t *TextPlugin) generateMessage(msg models.Message, ctx *output.GenerationC
 	return buf.String(), nil
 }
 
-// groupMessagesByDate groups messages by date
-func (t *TextPlugin) groupMessagesByDate(messages []models.Message) map[string][]models.Message {
+// jsonMessage is a message as a line of JSON Lines output
+type jsonMessage struct {
+	GUID        string         `json:"guid,omitempty"`
+	Date        string         `json:"date"`
+	Sender      string         `json:"sender"`
+	FromMe      bool           `json:"from_me"`
+	Text        string         `json:"text,omitempty"`
+	Event       string         `json:"event,omitempty"`
+	Reactions   []jsonReaction `json:"reactions,omitempty"`
+	Attachments []string       `json:"attachments,omitempty"`
+}
+
+type jsonReaction struct {
+	Sender string `json:"sender"`
+	Emoji  string `json:"emoji"`
+}
+
+// generateJSONL writes each message as a JSON object on a line of its own, in date order,
+// with ISO 8601 dates. There's no header, so every line is a message.
+func (t *TextPlugin) generateJSONL(ctx *output.GenerationContext) ([]byte, error) {
+	settings := ctx.Config.Text
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	for _, msg := range ctx.Messages {
+		if !included(msg, settings.SystemMessages) {
+			continue
+		}
+
+		line := jsonMessage{
+			Date:   msg.FormattedDate.Format(time.RFC3339),
+			Sender: output.GetSenderName(msg, ctx.Handles, ctx.Config),
+			FromMe: msg.IsFromMe,
+		}
+		if settings.GUIDs {
+			line.GUID = msg.GUID
+		}
+		if settings.SystemMessages && msg.ItemType != 0 {
+			line.Event = systemEvent(msg.ItemType)
+		} else {
+			// Attachments are listed by name instead of their placeholders
+			line.Text = strings.TrimSpace(strings.ReplaceAll(*msg.Text, "\uFFFC", ""))
+			for _, reaction := range ctx.Reactions[msg.GUID] {
+				line.Reactions = append(line.Reactions, jsonReaction{Sender: reaction.SenderName, Emoji: reaction.ReactionEmoji})
+			}
+			for _, attachment := range msg.Attachments {
+				if attachment.Filename != nil {
+					line.Attachments = append(line.Attachments, *attachment.Filename)
+				}
+			}
+		}
+
+		if err := encoder.Encode(line); err != nil {
+			return nil, fmt.Errorf("failed to encode message %s: %w", msg.GUID, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// groupMessagesByDate groups the messages the text output includes by date
+func (t *TextPlugin) groupMessagesByDate(messages []models.Message, systemMessages bool) map[string][]models.Message {
 	grouped := make(map[string][]models.Message)
 
 	for _, msg := range messages {
-		// Skip empty messages
-		if msg.Text == nil || strings.TrimSpace(*msg.Text) == "" {
+		if !included(msg, systemMessages) {
 			continue
 		}
 
@@ -249,6 +333,29 @@ func (t *TextPlugin) groupMessagesByDate(messages []models.Message) map[string][
 	return grouped
 }
 
+// included reports whether the text output records msg: messages with text, and system
+// messages when they're marked
+func included(msg models.Message, systemMessages bool) bool {
+	if systemMessages && msg.ItemType != 0 {
+		return true
+	}
+	return msg.Text != nil && strings.TrimSpace(*msg.Text) != ""
+}
+
+// systemEvent describes the event a system message records, by its item_type
+func systemEvent(itemType int) string {
+	switch itemType {
+	case 1:
+		return "changed who is in the conversation"
+	case 2:
+		return "renamed the conversation"
+	case 3:
+		return "changed the conversation's photo or left it"
+	default:
+		return fmt.Sprintf("caused a system event (item type %d)", itemType)
+	}
+}
+
 // ValidateConfig validates the text plugin configuration
 func (t *TextPlugin) ValidateConfig(config *models.BookConfig) error {
 	// Call base validation
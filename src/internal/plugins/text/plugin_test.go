@@ -265,7 +265,7 @@ func TestTextPluginGroupMessagesByDate(t *testing.T) {
 		},
 	}
 
-	grouped := plugin.groupMessagesByDate(messages)
+	grouped := plugin.groupMessagesByDate(messages, false)
 
 	if len(grouped) != 2 {
 		t.Errorf("Expected 2 date groups, got %d", len(grouped))
@@ -306,7 +306,7 @@ func TestTextPluginSkipsEmptyMessages(t *testing.T) {
 		},
 	}
 
-	grouped := plugin.groupMessagesByDate(messages)
+	grouped := plugin.groupMessagesByDate(messages, false)
 
 	dateKey := testTime.Format("2006-01-02")
 	if msgs, exists := grouped[dateKey]; !exists || len(msgs) != 1 {
@@ -314,6 +314,60 @@ func TestTextPluginSkipsEmptyMessages(t *testing.T) {
 	}
 }
 
+func TestTextPluginOptions(t *testing.T) {
+	plugin := NewTextPlugin()
+
+	testTime := time.Date(2023, 9, 15, 10, 30, 0, 0, time.UTC)
+	filename := "IMG_0001.HEIC"
+	ctx := &output.GenerationContext{
+		Messages: []models.Message{
+			{ID: 1, GUID: "msg1", Text: stringPtr("Look \uFFFC"), IsFromMe: true, FormattedDate: testTime,
+				Attachments: []models.Attachment{{Filename: &filename}}},
+			{ID: 2, GUID: "msg2", HandleID: intPtr(1), ItemType: 2, FormattedDate: testTime.Add(time.Minute)},
+		},
+		Handles:   map[int]models.Handle{1: {ID: 1, DisplayName: "Alice"}},
+		Reactions: map[string][]models.Reaction{"msg1": {{SenderName: "Alice", ReactionEmoji: "😍"}}},
+		Config:    &models.BookConfig{Title: "Test", Text: models.TextConfig{GUIDs: true, ISOTimestamps: true, SystemMessages: true}},
+		Stats:     &models.BookStats{},
+	}
+
+	data, err := plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate text: %v", err)
+	}
+	text := string(data)
+	for _, want := range []string{"[2023-09-15T10:30:00Z] <msg1> Me: Look", "[2023-09-15T10:31:00Z] <msg2> * Alice renamed the conversation"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text should contain %q, got:\n%s", want, text)
+		}
+	}
+
+	ctx.Config.Text = models.TextConfig{}
+	data, _ = plugin.Generate(ctx)
+	if text := string(data); strings.Contains(text, "msg1") || strings.Contains(text, "renamed") || strings.Contains(text, "2023-09-15T") {
+		t.Errorf("Expected no GUIDs, ISO dates or system messages by default, got:\n%s", text)
+	}
+
+	ctx.Config.Text = models.TextConfig{Format: models.TextFormatJSONL, SystemMessages: true}
+	data, err = plugin.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Failed to generate JSON Lines: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{
+		`{"date":"2023-09-15T10:30:00Z","sender":"Me","from_me":true,"text":"Look","reactions":[{"sender":"Alice","emoji":"😍"}],"attachments":["IMG_0001.HEIC"]}`,
+		`{"date":"2023-09-15T10:31:00Z","sender":"Alice","from_me":false,"event":"renamed the conversation"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %d:\n%s", len(want), len(lines), data)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("Expected %s, got %s", want[i], lines[i])
+		}
+	}
+}
+
 // Helper functions for creating pointers
 func stringPtr(s string) *string {
 	return &s
@@ -0,0 +1,114 @@
+package printing
+
+import (
+	"math"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// Layout is the page geometry of the book in inches. Margins are measured from the edge
+// of the PDF page, so on bleed edges they include the bleed.
+type Layout struct {
+	TrimWidth     float64
+	TrimHeight    float64
+	Bleed         float64 // 0 when printing without a preset
+	BleedAllSides bool
+	Top           float64
+	Bottom        float64
+	Inner         float64
+	Outer         float64
+}
+
+// DesignLayout is the template's own layout: the trim size with no bleed and fixed margins
+func DesignLayout(trimWidth, trimHeight float64) Layout {
+	return Layout{
+		TrimWidth:  trimWidth,
+		TrimHeight: trimHeight,
+		Top:        DesignTopInset,
+		Bottom:     DesignTopInset,
+		Inner:      DesignMargin,
+		Outer:      DesignMargin,
+	}
+}
+
+// Layout widens the design margins to the preset's minimums for a book of the given page
+// count and adds bleed around the trim
+func (p Preset) Layout(trimWidth, trimHeight float64, pages int) Layout {
+	inside := Bleed
+	if !p.BleedAllSides {
+		inside = 0
+	}
+	return Layout{
+		TrimWidth:     trimWidth,
+		TrimHeight:    trimHeight,
+		Bleed:         Bleed,
+		BleedAllSides: p.BleedAllSides,
+		Top:           math.Max(DesignTopInset, p.MinMargin) + Bleed,
+		Bottom:        math.Max(DesignTopInset, p.MinMargin) + Bleed,
+		Inner:         math.Max(DesignMargin, p.Inside(pages)) + inside,
+		Outer:         math.Max(DesignMargin, p.MinMargin) + Bleed,
+	}
+}
+
+// PaperWidth is the width of the PDF page: the trim plus bleed on the bleed edges
+func (l Layout) PaperWidth() float64 {
+	if l.BleedAllSides {
+		return l.TrimWidth + 2*l.Bleed
+	}
+	return l.TrimWidth + l.Bleed
+}
+
+// PaperHeight is the height of the PDF page: the trim plus bleed top and bottom
+func (l Layout) PaperHeight() float64 {
+	return l.TrimHeight + 2*l.Bleed
+}
+
+// TrimBox returns the trimmed page as [left bottom right top] in PDF points. Without
+// bleed on the inside edge, the bleed is on the right of recto (odd) pages and on the
+// left of verso pages.
+func (l Layout) TrimBox(recto bool) [4]float64 {
+	left := l.Bleed
+	if recto && !l.BleedAllSides {
+		left = 0
+	}
+	return [4]float64{
+		left * 72,
+		l.Bleed * 72,
+		(left + l.TrimWidth) * 72,
+		(l.Bleed + l.TrimHeight) * 72,
+	}
+}
+
+// Rough page budget used by EstimatePages
+const (
+	frontMatterPages = 4  // Title, copyright and contents
+	messagesPerPage  = 12 // Short text bubbles with timestamps
+	imagesPerPage    = 2
+)
+
+// EstimatePages guesses the printed page count from the messages, to pick a gutter before
+// the book has been typeset. Each month starts a chapter on a new right-hand page.
+func EstimatePages(messages []models.Message, includeImages bool) int {
+	texts, images := 0, 0
+	months := make(map[string]bool)
+	for _, msg := range messages {
+		if msg.Memory != nil {
+			images += imagesPerPage // Memories get a page of their own
+			continue
+		}
+		if msg.Text == nil || strings.TrimSpace(*msg.Text) == "" {
+			continue
+		}
+		texts++
+		months[msg.FormattedDate.Format("2006-01")] = true
+		if includeImages {
+			images += len(msg.Attachments)
+		}
+	}
+
+	// A chapter averages half a page of padding to reach the next right-hand page
+	pages := float64(frontMatterPages) + float64(len(months))*1.5 +
+		float64(texts)/messagesPerPage + float64(images)/imagesPerPage
+	return int(math.Ceil(pages))
+}
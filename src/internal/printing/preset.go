@@ -0,0 +1,113 @@
+// Package printing lays out the printed page for print-on-demand services: trim size,
+// bleed and the inside margin each service asks for at a given page count
+package printing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Preset names accepted by --print-preset
+const (
+	KDP    = "kdp"
+	Ingram = "ingram"
+	Lulu   = "lulu"
+)
+
+// Design margins of the built-in template, in inches. Presets only ever widen them.
+const (
+	DesignMargin   = 0.5
+	DesignTopInset = 0.6
+)
+
+// Bleed is how far artwork must extend past the trim on bleed edges, in inches
+const Bleed = 0.125
+
+// gutterStep is the minimum inside margin for books of up to MaxPages pages
+type gutterStep struct {
+	MaxPages int
+	Inside   float64
+}
+
+// Preset describes one print-on-demand service's interior requirements
+type Preset struct {
+	Name          string
+	BleedAllSides bool         // Bleed on the inside edge too, rather than only top, bottom and outside
+	MinMargin     float64      // Minimum top, bottom and outside margin inside the trim
+	Gutter        []gutterStep // Minimum inside margin by page count, smallest books first
+}
+
+var presets = map[string]Preset{
+	// Kindle Direct Publishing paperback guidelines
+	KDP: {
+		Name:      "Amazon KDP",
+		MinMargin: 0.375,
+		Gutter: []gutterStep{
+			{150, 0.375}, {300, 0.5}, {500, 0.625}, {700, 0.75}, {0, 0.875},
+		},
+	},
+	// IngramSpark interior guidelines
+	Ingram: {
+		Name:      "IngramSpark",
+		MinMargin: 0.5,
+		Gutter: []gutterStep{
+			{150, 0.5}, {400, 0.625}, {0, 0.75},
+		},
+	},
+	// Lulu asks for a 0.5in safety margin plus a gutter that grows with page count
+	Lulu: {
+		Name:          "Lulu",
+		BleedAllSides: true,
+		MinMargin:     0.5,
+		Gutter: []gutterStep{
+			{60, 0.5}, {150, 0.625}, {400, 1.0}, {600, 1.125}, {0, 1.25},
+		},
+	},
+}
+
+// Names lists the known presets in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the preset with the given name
+func Lookup(name string) (Preset, error) {
+	preset, exists := presets[strings.ToLower(name)]
+	if !exists {
+		return Preset{}, fmt.Errorf("unknown print preset %q (use %s)", name, strings.Join(Names(), ", "))
+	}
+	return preset, nil
+}
+
+// Inside returns the minimum inside margin for a book of the given page count
+func (p Preset) Inside(pages int) float64 {
+	for _, step := range p.Gutter {
+		if step.MaxPages == 0 || pages <= step.MaxPages {
+			return step.Inside
+		}
+	}
+	return p.Gutter[len(p.Gutter)-1].Inside
+}
+
+// ParseLength converts a TeX-style length such as "5.5in", "140mm" or "396pt" to inches
+func ParseLength(length string) (float64, error) {
+	units := map[string]float64{"in": 1, "mm": 1 / 25.4, "cm": 1 / 2.54, "pt": 1 / 72.27, "bp": 1.0 / 72}
+	length = strings.TrimSpace(length)
+	for unit, scale := range units {
+		if strings.HasSuffix(length, unit) {
+			value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(length, unit)), 64)
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("invalid length %q", length)
+			}
+			return value * scale, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid length %q (use in, mm, cm, pt or bp)", length)
+}
@@ -0,0 +1,101 @@
+package printing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestPresetInside(t *testing.T) {
+	kdp, err := Lookup("KDP")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	tests := []struct {
+		pages int
+		want  float64
+	}{
+		{24, 0.375}, {150, 0.375}, {151, 0.5}, {500, 0.625}, {701, 0.875}, {2000, 0.875},
+	}
+	for _, tt := range tests {
+		if got := kdp.Inside(tt.pages); got != tt.want {
+			t.Errorf("Inside(%d) = %v, want %v", tt.pages, got, tt.want)
+		}
+	}
+
+	if _, err := Lookup("blurb"); err == nil {
+		t.Error("Expected error for unknown preset")
+	}
+}
+
+func TestPresetLayout(t *testing.T) {
+	kdp, _ := Lookup(KDP)
+	layout := kdp.Layout(5.5, 8.5, 400)
+
+	// Bleed on the outside edge only: 1/8in wider, 1/4in taller
+	if layout.PaperWidth() != 5.625 || layout.PaperHeight() != 8.75 {
+		t.Errorf("Expected 5.625 x 8.75 paper, got %v x %v", layout.PaperWidth(), layout.PaperHeight())
+	}
+	if layout.Inner != 0.625 || layout.Outer != 0.625 || layout.Top != 0.725 {
+		t.Errorf("Unexpected margins %+v", layout)
+	}
+
+	// Recto pages bleed on the right, verso pages on the left
+	if got := layout.TrimBox(true); got != [4]float64{0, 9, 396, 621} {
+		t.Errorf("Unexpected recto TrimBox %v", got)
+	}
+	if got := layout.TrimBox(false); got != [4]float64{9, 9, 405, 621} {
+		t.Errorf("Unexpected verso TrimBox %v", got)
+	}
+
+	lulu, _ := Lookup(Lulu)
+	layout = lulu.Layout(6, 9, 100)
+	if layout.PaperWidth() != 6.25 || layout.Inner != 0.75 {
+		t.Errorf("Expected bleed on every side for Lulu, got %+v", layout)
+	}
+	if got := layout.TrimBox(true); got[0] != 9 {
+		t.Errorf("Expected Lulu TrimBox inset on the inside edge, got %v", got)
+	}
+}
+
+func TestParseLength(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"5.5in", 5.5}, {"127mm", 5}, {"2.54cm", 1}, {"72bp", 1},
+	}
+	for _, tt := range tests {
+		got, err := ParseLength(tt.in)
+		if err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ParseLength(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "5.5", "wide in", "-1in"} {
+		if _, err := ParseLength(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestEstimatePages(t *testing.T) {
+	text := "hello"
+	march := time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC)
+	var messages []models.Message
+	for i := 0; i < 24; i++ {
+		messages = append(messages, models.Message{Text: &text, FormattedDate: march})
+	}
+	messages[0].Attachments = []models.Attachment{{GUID: "a"}, {GUID: "b"}}
+
+	// 4 front matter + 1.5 for one chapter + 2 pages of text + 1 page of images
+	if got := EstimatePages(messages, true); got != 9 {
+		t.Errorf("Expected 9 pages, got %d", got)
+	}
+	if got := EstimatePages(messages, false); got != 8 {
+		t.Errorf("Expected 8 pages without images, got %d", got)
+	}
+}
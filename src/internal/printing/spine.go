@@ -0,0 +1,54 @@
+package printing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PaperStock is an interior paper and how thick each printed page of it is
+type PaperStock struct {
+	Name    string
+	Caliper float64 // Inches per page, as published in the services' cover calculators
+}
+
+// Interior stocks offered by most print-on-demand services, thinnest first
+var stocks = []PaperStock{
+	{Name: "white", Caliper: 0.002252},
+	{Name: "color", Caliper: 0.002347},
+	{Name: "cream", Caliper: 0.0025},
+}
+
+// Stocks lists the known paper stocks, thinnest first
+func Stocks() []PaperStock {
+	return append([]PaperStock(nil), stocks...)
+}
+
+// LookupStock returns the paper stock with the given name
+func LookupStock(name string) (PaperStock, error) {
+	names := make([]string, 0, len(stocks))
+	for _, stock := range stocks {
+		if strings.EqualFold(stock.Name, name) {
+			return stock, nil
+		}
+		names = append(names, stock.Name)
+	}
+	return PaperStock{}, fmt.Errorf("unknown paper stock %q (use %s)", name, strings.Join(names, ", "))
+}
+
+// SpineWidth returns the spine width in inches of a book with the given page count. Pages
+// are printed on both sides of a sheet, so an odd count is rounded up to the next even one.
+func (s PaperStock) SpineWidth(pages int) float64 {
+	if pages <= 0 {
+		return 0
+	}
+	return float64(pages+pages%2) * s.Caliper
+}
+
+// SpineWidths returns the spine width in inches on every known stock, keyed by stock name
+func SpineWidths(pages int) map[string]float64 {
+	widths := make(map[string]float64, len(stocks))
+	for _, stock := range stocks {
+		widths[stock.Name] = stock.SpineWidth(pages)
+	}
+	return widths
+}
@@ -0,0 +1,42 @@
+package printing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpineWidth(t *testing.T) {
+	white, err := LookupStock("White")
+	if err != nil {
+		t.Fatalf("LookupStock failed: %v", err)
+	}
+
+	tests := []struct {
+		pages int
+		want  float64
+	}{
+		{0, 0}, {100, 0.2252}, {101, 0.229704}, {300, 0.6756},
+	}
+	for _, tt := range tests {
+		if got := white.SpineWidth(tt.pages); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("SpineWidth(%d) = %v, want %v", tt.pages, got, tt.want)
+		}
+	}
+
+	if _, err := LookupStock("glossy"); err == nil {
+		t.Error("Expected error for unknown stock")
+	}
+}
+
+func TestSpineWidths(t *testing.T) {
+	widths := SpineWidths(200)
+	if len(widths) != len(Stocks()) {
+		t.Fatalf("Expected %d widths, got %d", len(Stocks()), len(widths))
+	}
+	if widths["cream"] != 0.5 {
+		t.Errorf("Expected 0.5in spine on cream, got %v", widths["cream"])
+	}
+	if widths["white"] >= widths["cream"] {
+		t.Errorf("Expected white spine (%v) thinner than cream (%v)", widths["white"], widths["cream"])
+	}
+}
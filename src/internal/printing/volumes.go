@@ -0,0 +1,39 @@
+package printing
+
+import (
+	"threadbound/internal/models"
+)
+
+// SplitVolumes divides messages, in date order, into volumes by config. Volumes break
+// between month chapters: at each new year when splitting by year, and before a volume's
+// estimated page count would pass the budget. A month longer than the budget on its own
+// still makes a single volume.
+func SplitVolumes(messages []models.Message, config models.VolumesConfig, includeImages bool) [][]models.Message {
+	var volumes [][]models.Message
+	var current []models.Message
+	start := 0
+	for start < len(messages) {
+		// Take the next month of messages
+		month := messages[start].FormattedDate.Format("2006-01")
+		end := start
+		for end < len(messages) && messages[end].FormattedDate.Format("2006-01") == month {
+			end++
+		}
+		chapter := messages[start:end]
+		start = end
+
+		if len(current) > 0 {
+			newYear := config.SplitBy == models.SplitByYear && current[0].FormattedDate.Year() != chapter[0].FormattedDate.Year()
+			overBudget := config.MaxPages > 0 && EstimatePages(append(current[:len(current):len(current)], chapter...), includeImages) > config.MaxPages
+			if newYear || overBudget {
+				volumes = append(volumes, current)
+				current = nil
+			}
+		}
+		current = append(current, chapter...)
+	}
+	if len(current) > 0 {
+		volumes = append(volumes, current)
+	}
+	return volumes
+}
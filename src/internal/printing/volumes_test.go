@@ -0,0 +1,65 @@
+package printing
+
+import (
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// monthsOfMessages makes count text messages in each of the given months
+func monthsOfMessages(count int, months ...time.Time) []models.Message {
+	text := "hello"
+	var messages []models.Message
+	for _, month := range months {
+		for i := 0; i < count; i++ {
+			messages = append(messages, models.Message{Text: &text, FormattedDate: month.Add(time.Duration(i) * time.Hour)})
+		}
+	}
+	return messages
+}
+
+func TestSplitVolumesByPages(t *testing.T) {
+	month := func(year int, m time.Month) time.Time { return time.Date(year, m, 1, 9, 0, 0, 0, time.UTC) }
+	// 120 messages a month: 10 pages of text and 1.5 of chapter padding each
+	messages := monthsOfMessages(120, month(2023, 1), month(2023, 2), month(2023, 3), month(2023, 4))
+
+	volumes := SplitVolumes(messages, models.VolumesConfig{MaxPages: 30}, true)
+	if len(volumes) != 2 || len(volumes[0]) != 240 || len(volumes[1]) != 240 {
+		t.Fatalf("Expected two months per volume, got %d volumes", len(volumes))
+	}
+	for _, volume := range volumes {
+		if pages := EstimatePages(volume, true); pages > 30 {
+			t.Errorf("Expected volumes within the budget, got %d pages", pages)
+		}
+	}
+
+	// A month over the budget on its own still makes one volume
+	if volumes := SplitVolumes(messages, models.VolumesConfig{MaxPages: 5}, true); len(volumes) != 4 {
+		t.Errorf("Expected a volume per month, got %d", len(volumes))
+	}
+	if volumes := SplitVolumes(messages, models.VolumesConfig{}, true); len(volumes) != 1 {
+		t.Errorf("Expected no split without a budget, got %d volumes", len(volumes))
+	}
+}
+
+func TestSplitVolumesByYear(t *testing.T) {
+	messages := monthsOfMessages(120,
+		time.Date(2022, 11, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2022, 12, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2023, 2, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC),
+	)
+
+	volumes := SplitVolumes(messages, models.VolumesConfig{SplitBy: models.SplitByYear}, true)
+	if len(volumes) != 2 || volumes[1][0].FormattedDate.Year() != 2023 {
+		t.Fatalf("Expected a volume per year, got %d", len(volumes))
+	}
+
+	// 2023 is over the budget, so it's split again
+	volumes = SplitVolumes(messages, models.VolumesConfig{SplitBy: models.SplitByYear, MaxPages: 30}, true)
+	if len(volumes) != 3 || len(volumes[0]) != 240 || len(volumes[1]) != 240 || len(volumes[2]) != 120 {
+		t.Errorf("Expected 2022, then 2023 in two volumes, got %d volumes", len(volumes))
+	}
+}
@@ -0,0 +1,150 @@
+// Package redact masks sensitive data in message text so books can be shared safely
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// DefaultReplacement is used when a profile doesn't set its own
+const DefaultReplacement = "[redacted]"
+
+// Built-in patterns. Card candidates are confirmed with a Luhn check before redacting.
+var (
+	emailRegex = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phoneRegex = regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{3}\)\s?|\b\d{3}[\s.\-]?)\d{3}[\s.\-]?\d{4}\b`)
+	cardRegex  = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+)
+
+// builtinProfiles can be selected by name without any config
+var builtinProfiles = map[string]models.RedactRules{
+	"contacts": {Emails: true, Phones: true},
+	"strict":   {Emails: true, Phones: true, CreditCards: true},
+}
+
+// Profile looks up a redaction profile, preferring profiles defined in the config file
+func Profile(name string, custom map[string]models.RedactRules) (models.RedactRules, error) {
+	if rules, exists := custom[name]; exists {
+		return rules, nil
+	}
+	if rules, exists := builtinProfiles[name]; exists {
+		return rules, nil
+	}
+
+	var names []string
+	for n := range builtinProfiles {
+		names = append(names, n)
+	}
+	for n := range custom {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return models.RedactRules{}, fmt.Errorf("unknown redaction profile '%s'. Available profiles: %s", name, strings.Join(names, ", "))
+}
+
+// Redactor applies one set of rules to message text
+type Redactor struct {
+	rules       models.RedactRules
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+// New compiles the rules' custom patterns and word list
+func New(rules models.RedactRules) (*Redactor, error) {
+	r := &Redactor{rules: rules, replacement: rules.Replacement}
+	if r.replacement == "" {
+		r.replacement = DefaultReplacement
+	}
+
+	for _, pattern := range rules.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	for _, word := range rules.Words {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		r.patterns = append(r.patterns, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(word)+`\b`))
+	}
+
+	return r, nil
+}
+
+// Redact masks every match in text and returns the result with the number of matches
+func (r *Redactor) Redact(text string) (string, int) {
+	count := 0
+	replace := func(re *regexp.Regexp, keep func(string) bool) {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			if keep != nil && keep(match) {
+				return match
+			}
+			count++
+			return r.replacement
+		})
+	}
+
+	// Cards run before phones so long digit runs aren't half-matched as phone numbers
+	if r.rules.CreditCards {
+		replace(cardRegex, func(match string) bool { return !luhnValid(match) })
+	}
+	if r.rules.Emails {
+		replace(emailRegex, nil)
+	}
+	if r.rules.Phones {
+		replace(phoneRegex, nil)
+	}
+	for _, re := range r.patterns {
+		replace(re, nil)
+	}
+
+	return text, count
+}
+
+// Apply redacts message text in place and returns how many matches were masked
+func (r *Redactor) Apply(messages []models.Message) int {
+	total := 0
+	for i := range messages {
+		for _, field := range []*string{messages[i].Text, messages[i].Subject} {
+			if field == nil || *field == "" {
+				continue
+			}
+			redacted, count := r.Redact(*field)
+			*field = redacted
+			total += count
+		}
+	}
+	return total
+}
+
+// luhnValid checks the card-number checksum, ignoring spaces and dashes
+func luhnValid(number string) bool {
+	sum := 0
+	digits := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
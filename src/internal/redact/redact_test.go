@@ -0,0 +1,93 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestRedact(t *testing.T) {
+	redactor, err := New(models.RedactRules{
+		Emails:      true,
+		Phones:      true,
+		CreditCards: true,
+		Words:       []string{"Project Falcon"},
+		Patterns:    []string{`ACCT-\d+`},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		count    int
+	}{
+		{"mail me at jane.doe@example.com", "mail me at [redacted]", 1},
+		{"call (555) 123-4567 or +1 555.987.6543", "call [redacted] or [redacted]", 2},
+		{"card 4111 1111 1111 1111 exp 12/26", "card [redacted] exp 12/26", 1},
+		{"order 1234567890123 shipped", "order 1234567890123 shipped", 0}, // fails the Luhn check
+		{"don't mention project falcon yet", "don't mention [redacted] yet", 1},
+		{"ref ACCT-00912", "ref [redacted]", 1},
+		{"see you at 7", "see you at 7", 0},
+	}
+
+	for _, test := range tests {
+		result, count := redactor.Redact(test.input)
+		if result != test.expected || count != test.count {
+			t.Errorf("Redact(%q) = %q (%d), expected %q (%d)", test.input, result, count, test.expected, test.count)
+		}
+	}
+}
+
+func TestRedactOnlyEnabledRules(t *testing.T) {
+	redactor, err := New(models.RedactRules{Emails: true, Replacement: "***"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, _ := redactor.Redact("jane@example.com 555-123-4567")
+	if result != "*** 555-123-4567" {
+		t.Errorf("Expected only the email masked, got %q", result)
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	if _, err := New(models.RedactRules{Patterns: []string{"("}}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	custom := map[string]models.RedactRules{
+		"strict": {Words: []string{"secret"}},
+		"family": {Phones: true},
+	}
+
+	if rules, err := Profile("strict", custom); err != nil || len(rules.Words) != 1 {
+		t.Errorf("Expected the config profile to override the built-in, got %+v (%v)", rules, err)
+	}
+	if rules, err := Profile("contacts", custom); err != nil || !rules.Emails {
+		t.Errorf("Expected the built-in contacts profile, got %+v (%v)", rules, err)
+	}
+
+	_, err := Profile("missing", custom)
+	if err == nil || !strings.Contains(err.Error(), "family") {
+		t.Errorf("Expected an error listing available profiles, got %v", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	text := "email bob@example.com"
+	subject := "re: bob@example.com"
+	messages := []models.Message{{Text: &text, Subject: &subject}, {}}
+
+	redactor, _ := New(models.RedactRules{Emails: true})
+	if count := redactor.Apply(messages); count != 2 {
+		t.Errorf("Expected 2 redactions, got %d", count)
+	}
+	if *messages[0].Text != "email [redacted]" {
+		t.Errorf("Expected text redacted in place, got %q", *messages[0].Text)
+	}
+}
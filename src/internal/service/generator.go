@@ -2,8 +2,14 @@ package service
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"threadbound/internal/analytics"
+	"threadbound/internal/attachments"
 	"threadbound/internal/book"
+	"threadbound/internal/latex"
 	"threadbound/internal/models"
 )
 
@@ -22,11 +28,33 @@ func NewGeneratorService(config *models.BookConfig) *GeneratorService {
 // GenerateResult contains the result of a generation operation
 type GenerateResult struct {
 	OutputPath string
+	PDFPath    string // Set when the TeX output was also built into a PDF
 	Stats      *models.BookStats
+	PageCount  int           // 0 unless the output is a PDF; the total across volumes
+	Volumes    []book.Volume // Set when the book was split into volumes
+
+	Attachments *attachments.Report // Attachments missing from disk, unreadable or unsupported
 }
 
-// Generate executes the book generation process
+// Generate executes the book generation process, building the TeX into a PDF as well when
+// the config asks for it
 func (s *GeneratorService) Generate() (*GenerateResult, error) {
+	if s.config.BuildPDF && !strings.EqualFold(filepath.Ext(s.config.OutputPath), ".tex") {
+		return nil, fmt.Errorf("building a PDF needs TeX output; use an --output ending in .tex, or .pdf without --pdf")
+	}
+	// Find out before spending minutes on the TeX
+	if s.config.BuildPDF {
+		engine, err := latex.SelectEngine(s.config.PDFEngine, latex.Installed)
+		if err != nil {
+			return nil, err
+		}
+		for _, program := range engine.Programs() {
+			if !latex.Installed(program) {
+				return nil, fmt.Errorf("%s not found; run threadbound doctor to see what's missing", program)
+			}
+		}
+	}
+
 	// Create book builder
 	builder, err := book.New(s.config)
 	if err != nil {
@@ -34,22 +62,69 @@ func (s *GeneratorService) Generate() (*GenerateResult, error) {
 	}
 	defer builder.Close()
 
-	// Get statistics
-	stats, err := builder.GetStats()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get stats: %w", err)
-	}
-
 	// Generate the book
+	if s.config.BuildPDF {
+		s.step(1, "Generating", "path", s.config.OutputPath)
+		// Each volume of a split book is built as soon as it's written, so page numbers
+		// continued into the next volume come from the typeset pages
+		builder.AfterVolume(func(path string) (int, error) {
+			return s.buildPDF(path, book.PDFPathFor(path))
+		})
+	}
 	err = builder.Generate()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate book: %w", err)
 	}
 
-	return &GenerateResult{
+	result := &GenerateResult{
 		OutputPath: s.config.OutputPath,
-		Stats:      stats,
-	}, nil
+		Stats:      builder.Stats(),
+		PageCount:  builder.PageCount(),
+		Volumes:    builder.Volumes(),
+
+		Attachments: builder.AttachmentReport(),
+	}
+	if !s.config.BuildPDF || len(result.Volumes) > 0 {
+		return result, nil
+	}
+
+	// Build the PDF from the TeX just written, keeping the TeX for build-pdf and patch
+	result.PDFPath = book.PDFPathFor(s.config.OutputPath)
+	s.step(2, "Building PDF", "path", result.PDFPath)
+	if result.PageCount, err = s.buildPDF(s.config.OutputPath, result.PDFPath); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// buildPDF builds a TeX file into a PDF and returns its page count, or 0 if it couldn't be read
+func (s *GeneratorService) buildPDF(texPath, pdfPath string) (int, error) {
+	pdfBuilder := book.NewPDFBuilder(s.config)
+	if err := pdfBuilder.BuildPDF(texPath, pdfPath); err != nil {
+		return 0, fmt.Errorf("failed to build PDF: %w", err)
+	}
+	info, err := pdfBuilder.GetPDFInfo(pdfPath)
+	if err != nil {
+		return 0, nil
+	}
+	return info.PageCount, nil
+}
+
+// step announces a stage of generating and building a PDF in one go
+func (s *GeneratorService) step(number int, task string, args ...interface{}) {
+	s.config.Log().Info("━━ "+task, append([]interface{}{"step", number, "of", 2}, args...)...)
+}
+
+// Patch regenerates the chapters covering from up to to and splices them into the TeX
+// book at the configured output path, returning the months replaced
+func (s *GeneratorService) Patch(from, to time.Time) ([]string, error) {
+	builder, err := book.New(s.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create builder: %w", err)
+	}
+	defer builder.Close()
+
+	return builder.Patch(from, to)
 }
 
 // GetStats returns statistics about the messages without generating
@@ -62,3 +137,15 @@ func (s *GeneratorService) GetStats() (*models.BookStats, error) {
 
 	return builder.GetStats()
 }
+
+// GetDetailedStats returns the statistics with a breakdown by person, attachment kind and
+// reaction, without generating
+func (s *GeneratorService) GetDetailedStats() (*models.BookStats, *analytics.Breakdown, error) {
+	builder, err := book.New(s.config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create builder: %w", err)
+	}
+	defer builder.Close()
+
+	return builder.GetDetailedStats()
+}
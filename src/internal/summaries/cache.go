@@ -0,0 +1,96 @@
+package summaries
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheFile is the cache manifest kept in the summaries folder
+const CacheFile = "cache.json"
+
+// CacheEntry records a summary the model wrote
+type CacheEntry struct {
+	Summary   string    `json:"summary"`
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Cache remembers summaries between runs, keyed by everything that was sent for them, so a
+// month is only summarized again when its messages, the model or the prompt change
+type Cache struct {
+	Entries map[string]*CacheEntry `json:"entries"`
+
+	dir     string
+	hits    int
+	changed bool
+}
+
+// CacheKey identifies what was sent for a summary
+func CacheKey(endpoint, model, prompt, transcript string) string {
+	sum := sha256.Sum256([]byte(endpoint + "\x00" + model + "\x00" + prompt + "\x00" + transcript))
+	return fmt.Sprintf("%x", sum)
+}
+
+// LoadCache reads the cache manifest in dir, starting an empty cache if there isn't one
+func LoadCache(dir string) (*Cache, error) {
+	cache := &Cache{Entries: make(map[string]*CacheEntry), dir: dir}
+
+	data, err := os.ReadFile(filepath.Join(dir, CacheFile))
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return cache, fmt.Errorf("failed to read summary cache: %w", err)
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return cache, fmt.Errorf("failed to parse summary cache: %w", err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]*CacheEntry)
+	}
+	return cache, nil
+}
+
+// Lookup returns the summary cached under key
+func (c *Cache) Lookup(key string) (string, bool) {
+	entry := c.Entries[key]
+	if entry == nil {
+		return "", false
+	}
+	c.hits++
+	return entry.Summary, true
+}
+
+// Store records a summary under key
+func (c *Cache) Store(key, summary, model string, now time.Time) {
+	c.Entries[key] = &CacheEntry{Summary: summary, Model: model, CreatedAt: now}
+	c.changed = true
+}
+
+// Hits counts the summaries Lookup has found
+func (c *Cache) Hits() int {
+	return c.hits
+}
+
+// Save writes the cache manifest if summaries were added to it
+func (c *Cache) Save() error {
+	if !c.changed {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create summary cache folder: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, CacheFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write summary cache: %w", err)
+	}
+	c.changed = false
+	return nil
+}
@@ -0,0 +1,33 @@
+package summaries
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := LoadCache(dir)
+	if err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+	key := CacheKey("http://localhost:11434/v1", "llama3.1", "Summarize", "[2024-03-04] Me: moving day\n")
+	cache.Store(key, "They moved flats.", "llama3.1", time.Now())
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	cache, err = LoadCache(dir)
+	if err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+	if summary, ok := cache.Lookup(key); !ok || summary != "They moved flats." {
+		t.Errorf("Expected the cached summary, got %q", summary)
+	}
+	if _, ok := cache.Lookup(CacheKey("http://localhost:11434/v1", "llama3.2", "Summarize", "[2024-03-04] Me: moving day\n")); ok {
+		t.Error("Expected another model's summary not to be found")
+	}
+	if cache.Hits() != 1 {
+		t.Errorf("Expected 1 hit, got %d", cache.Hits())
+	}
+}
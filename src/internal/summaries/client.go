@@ -0,0 +1,103 @@
+package summaries
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// Client asks an OpenAI-compatible chat completions endpoint, such as OpenAI's, Ollama's or
+// llama.cpp's server, to complete a prompt
+type Client struct {
+	endpoint string
+	model    string
+	apiKey   string
+	http     *http.Client
+}
+
+// NewClient creates a client for the endpoint and model in settings, reading the API key
+// from the environment variable they name
+func NewClient(settings models.SummariesConfig) *Client {
+	settings = settings.WithDefaults()
+	return &Client{
+		endpoint: strings.TrimRight(settings.Endpoint, "/") + "/chat/completions",
+		model:    settings.Model,
+		apiKey:   os.Getenv(settings.APIKeyEnv),
+		http:     &http.Client{Timeout: settings.Timeout},
+	}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Complete sends the instructions and the text they're about, and returns the model's reply
+func (c *Client) Complete(instructions, text string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: c.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: instructions},
+			{Role: "user", Content: text},
+		},
+		Temperature: 0.3, // Summaries of the same messages should read alike
+	})
+	if err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read reply: %w", err)
+	}
+	var reply chatResponse
+	if err := json.Unmarshal(data, &reply); err != nil && response.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("failed to parse reply: %w", err)
+	}
+	if reply.Error != nil && reply.Error.Message != "" {
+		return "", fmt.Errorf("%s: %s", response.Status, reply.Error.Message)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", response.Status)
+	}
+	if len(reply.Choices) == 0 || strings.TrimSpace(reply.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("empty reply")
+	}
+	return strings.TrimSpace(reply.Choices[0].Message.Content), nil
+}
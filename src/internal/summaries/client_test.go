@@ -0,0 +1,57 @@
+package summaries
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestClientComplete(t *testing.T) {
+	var request chatRequest
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected a request to /v1/chat/completions, got %s", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "  They moved flats.\n"}}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_SUMMARIES_KEY", "secret")
+	client := NewClient(models.SummariesConfig{Endpoint: server.URL + "/v1/", Model: "llama3.1", APIKeyEnv: "TEST_SUMMARIES_KEY"})
+	reply, err := client.Complete("Summarize", "[2024-03-04] Me: moving day\n")
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if reply != "They moved flats." {
+		t.Errorf("Expected the reply trimmed, got %q", reply)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Expected the API key from the environment, got %q", auth)
+	}
+	if request.Model != "llama3.1" || len(request.Messages) != 2 || request.Messages[0].Role != "system" || request.Messages[1].Content != "[2024-03-04] Me: moving day\n" {
+		t.Errorf("Unexpected request %+v", request)
+	}
+}
+
+func TestClientCompleteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Error("Expected no API key when the variable is unset")
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"message": "model \"nope\" not found"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_SUMMARIES_KEY", "")
+	client := NewClient(models.SummariesConfig{Endpoint: server.URL, Model: "nope", APIKeyEnv: "TEST_SUMMARIES_KEY"})
+	if _, err := client.Complete("Summarize", "text"); err == nil || err.Error() != `404 Not Found: model "nope" not found` {
+		t.Errorf("Expected the endpoint's error, got %v", err)
+	}
+}
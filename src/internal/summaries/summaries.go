@@ -0,0 +1,102 @@
+// Package summaries writes a short "In this chapter" summary of each month with a model
+// behind an OpenAI-compatible chat completions endpoint. It's only used when the config's
+// summaries are enabled, and nothing is sent otherwise. Summaries are cached by what was
+// sent for them, so a month is summarized again only when its messages change.
+package summaries
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"threadbound/internal/analytics"
+	"threadbound/internal/i18n"
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+// CacheDir is the folder summaries are cached in, inside the attachments folder or the
+// config's DerivedPath
+const CacheDir = "summaries"
+
+// ByMonth summarizes each month of messages, keyed by analytics.ChapterKeyFormat. Summaries
+// the endpoint fails to write are left out with a warning so the book is still made; after
+// the first failure only cached summaries are used.
+func ByMonth(messages []models.Message, handles map[int]models.Handle, config *models.BookConfig) map[string]string {
+	settings := config.Summaries.WithDefaults()
+	l := output.Localizer(config)
+	client := NewClient(settings)
+
+	cache, err := LoadCache(filepath.Join(config.DerivedDir(), CacheDir))
+	if err != nil {
+		// Start afresh rather than fail the book; the cache is rewritten at the end
+		config.Log().Warn("⚠️  Summary cache unavailable", "error", err)
+	}
+
+	var months []string
+	byMonth := make(map[string][]models.Message)
+	for _, msg := range messages {
+		key := msg.FormattedDate.Format(analytics.ChapterKeyFormat)
+		if _, seen := byMonth[key]; !seen {
+			months = append(months, key)
+		}
+		byMonth[key] = append(byMonth[key], msg)
+	}
+
+	summaries := make(map[string]string)
+	failed := false
+	for _, key := range months {
+		transcript := Transcript(byMonth[key], handles, config, settings.MaxChars)
+		if transcript == "" {
+			continue
+		}
+		prompt := settings.Prompt
+		if prompt == "" {
+			prompt = l.T("summary_prompt", "Month", l.Format(byMonth[key][0].FormattedDate, i18n.MonthFormat))
+		}
+
+		cacheKey := CacheKey(settings.Endpoint, settings.Model, prompt, transcript)
+		if summary, ok := cache.Lookup(cacheKey); ok {
+			summaries[key] = summary
+			continue
+		}
+		if failed {
+			continue
+		}
+		summary, err := client.Complete(prompt, transcript)
+		if err != nil {
+			config.Log().Warn("⚠️  Could not summarize, leaving the chapters that aren't cached without summaries", "chapter", key, "error", err)
+			failed = true
+			continue
+		}
+		cache.Store(cacheKey, summary, settings.Model, time.Now())
+		summaries[key] = summary
+	}
+
+	if hits := cache.Hits(); hits > 0 {
+		config.Log().Info("🤖 Reused cached summaries", "count", hits)
+	}
+	if err := cache.Save(); err != nil {
+		config.Log().Warn("⚠️  Could not save summaries", "error", err)
+	}
+	return summaries
+}
+
+// Transcript writes messages one per line with their date and sender, as the model reads
+// them, stopping before the line that would pass maxChars
+func Transcript(messages []models.Message, handles map[int]models.Handle, config *models.BookConfig, maxChars int) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		if msg.Text == nil || strings.TrimSpace(*msg.Text) == "" {
+			continue
+		}
+		text := strings.Join(strings.Fields(*msg.Text), " ")
+		line := fmt.Sprintf("[%s] %s: %s\n", msg.FormattedDate.Format("2006-01-02"), output.GetSenderName(msg, handles, config), text)
+		if maxChars > 0 && b.Len()+len(line) > maxChars {
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
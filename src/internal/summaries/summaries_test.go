@@ -0,0 +1,98 @@
+package summaries
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"threadbound/internal/logging"
+	"threadbound/internal/models"
+)
+
+func TestByMonth(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var request chatRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		reply := "They moved flats."
+		if !strings.Contains(request.Messages[0].Content, "March 2024") {
+			reply = "They unpacked."
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{"message": chatMessage{Role: "assistant", Content: reply}}},
+		})
+	}))
+	defer server.Close()
+
+	text := func(s string) *string { return &s }
+	handle := 1
+	messages := []models.Message{
+		{Text: text("moving day"), IsFromMe: true, FormattedDate: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+		{Text: text("need a hand?"), HandleID: &handle, FormattedDate: time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)},
+		{Text: text("unpacked"), IsFromMe: true, FormattedDate: time.Date(2024, 4, 2, 9, 0, 0, 0, time.UTC)},
+	}
+	handles := map[int]models.Handle{1: {DisplayName: "Alice"}}
+	config := &models.BookConfig{
+		AttachmentsPath: t.TempDir(),
+		Summaries:       models.SummariesConfig{Enabled: true, Endpoint: server.URL, Model: "llama3.1"},
+		Logger:          logging.New(&bytes.Buffer{}, logging.Options{}),
+	}
+
+	summaries := ByMonth(messages, handles, config)
+	if summaries["2024-03"] != "They moved flats." || summaries["2024-04"] != "They unpacked." {
+		t.Errorf("Unexpected summaries %v", summaries)
+	}
+
+	// Unchanged months are summarized from the cache, without asking again
+	if summaries := ByMonth(messages, handles, config); requests != 2 || len(summaries) != 2 {
+		t.Errorf("Expected the cached summaries without new requests, got %d requests and %v", requests, summaries)
+	}
+}
+
+func TestByMonthEndpointDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	endpoint := server.URL
+	server.Close()
+
+	text := func(s string) *string { return &s }
+	messages := []models.Message{
+		{Text: text("moving day"), IsFromMe: true, FormattedDate: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+		{Text: text("unpacked"), IsFromMe: true, FormattedDate: time.Date(2024, 4, 2, 9, 0, 0, 0, time.UTC)},
+	}
+	var log bytes.Buffer
+	config := &models.BookConfig{
+		AttachmentsPath: t.TempDir(),
+		Summaries:       models.SummariesConfig{Enabled: true, Endpoint: endpoint, Model: "llama3.1"},
+		Logger:          logging.New(&log, logging.Options{}),
+	}
+
+	if summaries := ByMonth(messages, nil, config); len(summaries) != 0 {
+		t.Errorf("Expected no summaries, got %v", summaries)
+	}
+	if got := strings.Count(log.String(), "Could not summarize"); got != 1 {
+		t.Errorf("Expected one warning and no more requests after the first failure, got %d:\n%s", got, log.String())
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	text := func(s string) *string { return &s }
+	date := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	messages := []models.Message{
+		{Text: text("moving\n  day"), IsFromMe: true, FormattedDate: date},
+		{Text: text(" "), IsFromMe: true, FormattedDate: date},
+		{Text: text("boxes everywhere"), IsFromMe: true, FormattedDate: date},
+	}
+	config := &models.BookConfig{MyName: "Sam"}
+
+	if got := Transcript(messages, nil, config, 0); got != "[2024-03-04] Sam: moving day\n[2024-03-04] Sam: boxes everywhere\n" {
+		t.Errorf("Unexpected transcript %q", got)
+	}
+	if got := Transcript(messages, nil, config, 40); got != "[2024-03-04] Sam: moving day\n" {
+		t.Errorf("Expected the transcript cut at 40 characters, got %q", got)
+	}
+}
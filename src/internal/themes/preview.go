@@ -0,0 +1,134 @@
+package themes
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+	_ "threadbound/internal/plugins" // Import to register plugins
+)
+
+// ContactSheet is the page Preview writes to compare the rendered themes
+const ContactSheet = "index.html"
+
+// Formats a preview can be rendered in
+const (
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+)
+
+// previewCard is one theme on the contact sheet
+type previewCard struct {
+	Theme
+	File  string // Rendered sample chapter, relative to the contact sheet
+	YAML  string
+	Error string
+}
+
+// Preview renders the sample chapter once per theme into dir and writes a contact sheet
+// showing them side by side. base supplies the title and page size; everything that
+// would slow the preview down, such as images and URL previews, is turned off. A theme
+// that fails to render is shown with its error rather than stopping the others.
+func Preview(themes []Theme, base models.BookConfig, format, dir string) (string, error) {
+	if format != FormatHTML && format != FormatPDF {
+		return "", fmt.Errorf("unsupported preview format %q (use %s or %s)", format, FormatHTML, FormatPDF)
+	}
+	if len(themes) == 0 {
+		return "", fmt.Errorf("no themes to preview")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create preview directory: %w", err)
+	}
+
+	messages, handles, reactions := SampleChapter()
+	generator := output.New()
+
+	cards := make([]previewCard, 0, len(themes))
+	failed := 0
+	for _, theme := range themes {
+		base.Log().Info("🎨 Rendering theme", "theme", theme.Name)
+
+		config := base
+		config.Theme = theme.Config.WithDefaults()
+		config.OutputPath = filepath.Join(dir, theme.Name+"."+format)
+		config.IncludeImages = false
+		config.IncludePreviews = false
+		config.ChapterStats = false
+		config.StatsChapter = false
+		config.PrintPreset = ""
+
+		card := previewCard{Theme: theme, File: filepath.Base(config.OutputPath), YAML: theme.YAML()}
+		ctx := output.CreateContext(messages, handles, reactions, &config, &models.BookStats{})
+		data, _, err := generator.Generate(format, ctx)
+		if err == nil {
+			err = os.WriteFile(config.OutputPath, data, 0644)
+		}
+		if err != nil {
+			base.Log().Warn("⚠️  Could not render theme", "theme", theme.Name, "error", err)
+			card.File = ""
+			card.Error = err.Error()
+			failed++
+		}
+		cards = append(cards, card)
+	}
+	if failed == len(themes) {
+		return "", fmt.Errorf("no theme could be rendered as %s", format)
+	}
+
+	sheetPath := filepath.Join(dir, ContactSheet)
+	if err := writeContactSheet(sheetPath, cards); err != nil {
+		return "", err
+	}
+	return sheetPath, nil
+}
+
+// contactSheetTemplate lays the rendered chapters out in a grid, each above the config
+// snippet that selects its theme
+var contactSheetTemplate = template.Must(template.New("contact-sheet").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>Theme Preview</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; margin: 0; padding: 20px; background: #f5f5f5; }
+        h1 { margin: 0 0 20px 0; }
+        .sheet { display: grid; grid-template-columns: repeat(auto-fill, minmax(360px, 1fr)); gap: 20px; }
+        .theme { background: white; border-radius: 12px; overflow: hidden; box-shadow: 0 4px 6px rgba(0,0,0,0.1); }
+        .theme h2 { margin: 0; padding: 12px 16px; font-size: 1.2em; }
+        .theme .source { color: #8e8e93; font-size: 0.8em; font-weight: normal; margin-left: 8px; }
+        .theme iframe { width: 100%; height: 560px; border: 0; border-top: 1px solid #eee; border-bottom: 1px solid #eee; }
+        .theme .error { padding: 16px; color: #c00; }
+        .theme pre { margin: 0; padding: 12px 16px; font-size: 0.85em; background: #f8f9fa; }
+    </style>
+</head>
+<body>
+    <h1>Theme Preview</h1>
+    <div class="sheet">
+        {{range .}}
+        <div class="theme">
+            <h2>{{.Name}}<span class="source">{{.Source}}</span></h2>
+            {{if .File}}<iframe src="{{.File}}" title="{{.Name}} theme"></iframe>{{else}}<div class="error">{{.Error}}</div>{{end}}
+            <pre>{{.YAML}}</pre>
+        </div>
+        {{end}}
+    </div>
+</body>
+</html>
+`))
+
+// writeContactSheet writes the comparison page for the rendered themes
+func writeContactSheet(path string, cards []previewCard) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create contact sheet: %w", err)
+	}
+	defer file.Close()
+
+	if err := contactSheetTemplate.Execute(file, cards); err != nil {
+		return fmt.Errorf("failed to write contact sheet: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package themes
+
+import (
+	"fmt"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// sampleHandleID is the other person in the sample chapter
+const sampleHandleID = 1
+
+// sampleLines is a short conversation mixing short and long bubbles, so previews show
+// how each theme wraps text
+var sampleLines = []struct {
+	fromMe bool
+	text   string
+}{
+	{false, "Are we still on for Saturday?"},
+	{true, "Yes! I booked the table for seven."},
+	{false, "Perfect. Should I bring anything?"},
+	{true, "Just yourself. Although if you happened to stop at the bakery on the corner, nobody would complain about a loaf of that sourdough."},
+	{false, "Ha, deal. I'll grab two."},
+	{true, "See you then"},
+	{false, "Running ten minutes late, the train stopped between stations for no reason at all."},
+	{true, "No rush, we're at the bar"},
+}
+
+// SampleChapter returns the conversation every preview renders, with its contacts and
+// reactions
+func SampleChapter() ([]models.Message, map[int]models.Handle, map[string][]models.Reaction) {
+	start := time.Date(2024, 6, 14, 18, 2, 0, 0, time.UTC)
+	handleID := sampleHandleID
+
+	messages := make([]models.Message, 0, len(sampleLines))
+	for i, line := range sampleLines {
+		text := line.text
+		msg := models.Message{
+			ID:            i + 1,
+			GUID:          fmt.Sprintf("sample-%d", i+1),
+			Text:          &text,
+			IsFromMe:      line.fromMe,
+			FormattedDate: start.Add(time.Duration(i*7) * time.Minute),
+		}
+		if !line.fromMe {
+			msg.HandleID = &handleID
+		}
+		messages = append(messages, msg)
+	}
+
+	handles := map[int]models.Handle{
+		sampleHandleID: {ID: sampleHandleID, Service: "iMessage", Contact: "+15555550123", DisplayName: "Sam"},
+	}
+	reactions := map[string][]models.Reaction{
+		messages[3].GUID: {{Type: int(models.ReactionLaughed), SenderName: "Sam", Timestamp: start.Add(25 * time.Minute), ReactionEmoji: "😂"}},
+	}
+	return messages, handles, reactions
+}
@@ -0,0 +1,133 @@
+// Package themes lists the book themes a user can choose from and renders previews of
+// them side by side. Themes are built in or installed as YAML files in the themes data
+// directory, each holding the keys of the config file's theme section.
+package themes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"threadbound/internal/assets"
+	"threadbound/internal/models"
+)
+
+// BuiltIn is the Source of themes compiled into the binary
+const BuiltIn = "built-in"
+
+// Theme is a named set of theme settings
+type Theme struct {
+	Name   string
+	Source string // BuiltIn, or the YAML file the theme was loaded from
+	Config models.ThemeConfig
+}
+
+// builtIns keep the default fonts so every theme builds wherever the default book does,
+// and light fills since TeX bubbles always have black text
+var builtIns = []Theme{
+	{Name: "default", Source: BuiltIn},
+	{Name: "ocean", Source: BuiltIn, Config: models.ThemeConfig{
+		SentColor: "#B9DCF5", ReceivedColor: "#E6EEF3", CornerRadius: "9pt", BubbleMaxWidth: 0.7,
+	}},
+	{Name: "paper", Source: BuiltIn, Config: models.ThemeConfig{
+		SentColor: "#E8DFCC", ReceivedColor: "#F4F1EA", CornerRadius: "2pt", BubbleMaxWidth: 0.75,
+	}},
+	{Name: "mono", Source: BuiltIn, Config: models.ThemeConfig{
+		SentColor: "#D9D9D9", ReceivedColor: "#F2F2F2", CornerRadius: "0pt", BubbleMaxWidth: 0.65,
+	}},
+}
+
+// Installed returns the built-in themes and those in the themes data directories, sorted
+// by name. An installed theme replaces a built-in one of the same name, and earlier data
+// directories win over later ones.
+func Installed() ([]Theme, error) {
+	byName := make(map[string]Theme)
+	for _, theme := range builtIns {
+		byName[theme.Name] = theme
+	}
+
+	seen := make(map[string]bool)
+	for _, dir := range assets.SearchDirs() {
+		paths, err := filepath.Glob(filepath.Join(dir, assets.Themes, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+			if seen[name] {
+				continue
+			}
+			theme, err := Load(path)
+			if err != nil {
+				return nil, err
+			}
+			seen[name] = true
+			byName[name] = theme
+		}
+	}
+
+	themes := make([]Theme, 0, len(byName))
+	for _, theme := range byName {
+		themes = append(themes, theme)
+	}
+	sort.Slice(themes, func(i, j int) bool { return themes[i].Name < themes[j].Name })
+	return themes, nil
+}
+
+// Load reads a theme file, naming the theme after the file
+func Load(path string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, fmt.Errorf("failed to read theme %s: %w", path, err)
+	}
+
+	var config models.ThemeConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return Theme{}, fmt.Errorf("failed to parse theme %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return Theme{}, fmt.Errorf("invalid theme %s: %w", path, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return Theme{Name: name, Source: path, Config: config}, nil
+}
+
+// Select keeps the named themes, in the order given. No names keeps them all.
+func Select(themes []Theme, names []string) ([]Theme, error) {
+	if len(names) == 0 {
+		return themes, nil
+	}
+
+	byName := make(map[string]Theme, len(themes))
+	available := make([]string, 0, len(themes))
+	for _, theme := range themes {
+		byName[theme.Name] = theme
+		available = append(available, theme.Name)
+	}
+
+	selected := make([]Theme, 0, len(names))
+	for _, name := range names {
+		theme, exists := byName[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown theme %q (installed: %s)", name, strings.Join(available, ", "))
+		}
+		selected = append(selected, theme)
+	}
+	return selected, nil
+}
+
+// YAML returns the theme as the theme section of a config file, with defaults filled in
+func (t Theme) YAML() string {
+	data, err := yaml.Marshal(struct {
+		Theme models.ThemeConfig `yaml:"theme"`
+	}{t.Config.WithDefaults()})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
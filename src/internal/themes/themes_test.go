@@ -0,0 +1,106 @@
+package themes
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/assets"
+	"threadbound/internal/logging"
+	"threadbound/internal/models"
+)
+
+func TestInstalled(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv(assets.EnvDataDir, dataDir)
+
+	themesDir := filepath.Join(dataDir, assets.Themes)
+	if err := os.MkdirAll(themesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(themesDir, "forest.yaml"), []byte("sent_color: \"#B7D7B0\"\ncorner_radius: 6pt\n"), 0644)
+	os.WriteFile(filepath.Join(themesDir, "paper.yaml"), []byte("sent_color: \"#FFFFFF\"\n"), 0644)
+	os.WriteFile(filepath.Join(themesDir, "notes.txt"), []byte("not a theme"), 0644)
+
+	themes, err := Installed()
+	if err != nil {
+		t.Fatalf("Installed failed: %v", err)
+	}
+
+	var names []string
+	byName := make(map[string]Theme)
+	for _, theme := range themes {
+		names = append(names, theme.Name)
+		byName[theme.Name] = theme
+	}
+	if got := strings.Join(names, ","); got != "default,forest,mono,ocean,paper" {
+		t.Errorf("Unexpected themes %s", got)
+	}
+	if byName["forest"].Config.CornerRadius != "6pt" || byName["forest"].Source != filepath.Join(themesDir, "forest.yaml") {
+		t.Errorf("Unexpected installed theme %+v", byName["forest"])
+	}
+	if byName["paper"].Config.SentColor != "#FFFFFF" {
+		t.Errorf("Expected installed paper theme to replace the built-in one, got %+v", byName["paper"])
+	}
+
+	// A broken theme file is reported rather than skipped
+	os.WriteFile(filepath.Join(themesDir, "broken.yaml"), []byte("sent_color: blue\n"), 0644)
+	if _, err := Installed(); err == nil || !strings.Contains(err.Error(), "broken.yaml") {
+		t.Errorf("Expected error naming the broken theme, got %v", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	selected, err := Select(builtIns, []string{"paper", "default"})
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if len(selected) != 2 || selected[0].Name != "paper" || selected[1].Name != "default" {
+		t.Errorf("Expected paper then default, got %+v", selected)
+	}
+
+	if all, _ := Select(builtIns, nil); len(all) != len(builtIns) {
+		t.Errorf("Expected every theme without names, got %d", len(all))
+	}
+	if _, err := Select(builtIns, []string{"neon"}); err == nil {
+		t.Error("Expected error for unknown theme")
+	}
+}
+
+func TestPreviewHTML(t *testing.T) {
+	dir := t.TempDir()
+	base := models.BookConfig{Title: "Preview", Logger: logging.New(io.Discard, logging.Options{})}
+
+	sheet, err := Preview(builtIns[:2], base, FormatHTML, dir)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if sheet != filepath.Join(dir, ContactSheet) {
+		t.Errorf("Unexpected contact sheet path %s", sheet)
+	}
+
+	// The default theme is rendered with its defaults spelled out, not the HTML book's own style
+	page, err := os.ReadFile(filepath.Join(dir, "default.html"))
+	if err != nil {
+		t.Fatalf("Expected a page for the default theme: %v", err)
+	}
+	if !strings.Contains(string(page), models.DefaultSentColor) || !strings.Contains(string(page), "sourdough") {
+		t.Error("Expected the sample chapter in the default theme's colors")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ocean.html")); err != nil {
+		t.Errorf("Expected a page for the ocean theme: %v", err)
+	}
+
+	contents, _ := os.ReadFile(sheet)
+	for _, want := range []string{`src="default.html"`, `src="ocean.html"`, "sent_color: &#39;#B9DCF5&#39;"} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("Contact sheet should contain %q", want)
+		}
+	}
+
+	if _, err := Preview(builtIns, base, "docx", dir); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
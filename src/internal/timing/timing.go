@@ -0,0 +1,73 @@
+package timing
+
+import (
+	"sync"
+	"time"
+)
+
+// Stage records the wall time spent in one step of the pipeline
+type Stage struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Recorder collects stage timings. A nil Recorder is valid and records nothing,
+// so callers never need to check whether timing is enabled.
+type Recorder struct {
+	mutex   sync.Mutex
+	started time.Time
+	stages  []Stage
+}
+
+// New creates a recorder, or returns nil when timing is disabled
+func New(enabled bool) *Recorder {
+	if !enabled {
+		return nil
+	}
+	return &Recorder{started: time.Now()}
+}
+
+// Start begins timing a stage and returns a function that ends it
+func (r *Recorder) Start(name string) func() {
+	if r == nil {
+		return func() {}
+	}
+
+	begin := time.Now()
+	return func() {
+		r.Record(name, time.Since(begin))
+	}
+}
+
+// Record adds a stage with an already measured duration
+func (r *Recorder) Record(name string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stages = append(r.stages, Stage{Name: name, Duration: duration})
+}
+
+// Stages returns a copy of the recorded stages in the order they finished
+func (r *Recorder) Stages() []Stage {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stages := make([]Stage, len(r.stages))
+	copy(stages, r.stages)
+	return stages
+}
+
+// Elapsed returns the wall time since the recorder was created, which the stages add up to
+// at most
+func (r *Recorder) Elapsed() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return time.Since(r.started)
+}
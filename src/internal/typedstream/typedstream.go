@@ -0,0 +1,89 @@
+// Package typedstream reads the text of the attributedBody blobs chat.db keeps for each
+// message. They're NSAttributedStrings archived with NSArchiver's typedstream format, and
+// newer versions of Messages leave the text column empty and keep the text only there.
+package typedstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Header starts every typedstream
+var Header = []byte("streamtyped")
+
+// stringClass names the class of the attributed string's own string. A mutable string is
+// archived with its superclass, so this finds those too.
+var stringClass = []byte("NSString")
+
+// Typedstream markers around the string's bytes
+const (
+	stringMarker = '+'  // Starts the string's length and bytes
+	int16Marker  = 0x81 // The length follows as a little-endian int16
+	int32Marker  = 0x82 // The length follows as a little-endian int32
+)
+
+// markerWindow is how far past the class name the string's marker may be, past the class
+// version and the references typedstream writes between them
+const markerWindow = 16
+
+// Text returns the plain text of an archived NSAttributedString. Only the string is read:
+// the attributes around it, such as mentions and link ranges, are left out.
+func Text(data []byte) (string, error) {
+	if !bytes.Contains(data, Header) {
+		return "", fmt.Errorf("not a typedstream")
+	}
+
+	start := bytes.Index(data, stringClass)
+	if start < 0 {
+		return "", fmt.Errorf("typedstream has no string")
+	}
+	start += len(stringClass)
+
+	window := data[start:]
+	if len(window) > markerWindow {
+		window = window[:markerWindow]
+	}
+	marker := bytes.IndexByte(window, stringMarker)
+	if marker < 0 {
+		return "", fmt.Errorf("typedstream string has no contents")
+	}
+	rest := data[start+marker+1:]
+
+	length, rest, err := readLength(rest)
+	if err != nil {
+		return "", err
+	}
+	if length > len(rest) {
+		return "", fmt.Errorf("typedstream string is cut short: %d of %d bytes", len(rest), length)
+	}
+	text := rest[:length]
+	if !utf8.Valid(text) {
+		return "", fmt.Errorf("typedstream string isn't UTF-8")
+	}
+	return string(text), nil
+}
+
+// readLength reads a typedstream integer: one byte, or a marker and two or four bytes
+func readLength(data []byte) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, fmt.Errorf("typedstream string has no length")
+	}
+	switch data[0] {
+	case int16Marker:
+		if len(data) < 3 {
+			return 0, nil, fmt.Errorf("typedstream string length is cut short")
+		}
+		return int(binary.LittleEndian.Uint16(data[1:3])), data[3:], nil
+	case int32Marker:
+		if len(data) < 5 {
+			return 0, nil, fmt.Errorf("typedstream string length is cut short")
+		}
+		return int(binary.LittleEndian.Uint32(data[1:5])), data[5:], nil
+	}
+	if data[0] > 0x7f {
+		return 0, nil, fmt.Errorf("unexpected typedstream string length %#x", data[0])
+	}
+	return int(data[0]), data[1:], nil
+}
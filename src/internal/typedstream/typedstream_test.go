@@ -0,0 +1,71 @@
+package typedstream
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// archived builds an attributedBody in the shape Messages writes, with the string's
+// length encoded as typedstream does and the start of an attribute dictionary after it
+func archived(text string) []byte {
+	data := []byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x12NSAttributedString\x00" +
+		"\x84\x84\x08NSObject\x00\x85\x92\x84\x84\x84\x08NSString\x01\x94\x84\x01+")
+	switch n := len(text); {
+	case n < 0x80:
+		data = append(data, byte(n))
+	case n <= 0xffff:
+		data = append(data, int16Marker)
+		data = binary.LittleEndian.AppendUint16(data, uint16(n))
+	default:
+		data = append(data, int32Marker)
+		data = binary.LittleEndian.AppendUint32(data, uint32(n))
+	}
+	data = append(data, text...)
+	return append(data, "\x86\x84\x02iI\x01\x05\x92\x84\x84\x84\x0cNSDictionary\x00"...)
+}
+
+func TestText(t *testing.T) {
+	tests := []string{
+		"Hello!",
+		"",
+		"Café ☕️ at 8? 🎉",
+		strings.Repeat("A long message. ", 20),
+		strings.Repeat("x", 70000),
+	}
+	for _, want := range tests {
+		got, err := Text(archived(want))
+		if err != nil {
+			t.Errorf("Text failed for %d bytes: %v", len(want), err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestTextMutableString(t *testing.T) {
+	data := []byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x19NSMutableAttributedString\x00" +
+		"\x84\x84\x12NSAttributedString\x00\x84\x84\x08NSObject\x00\x85\x92\x84\x84\x84\x0fNSMutableString" +
+		"\x01\x84\x84\x08NSString\x01\x95\x84\x01+\x05Hello\x86")
+	if got, err := Text(data); err != nil || got != "Hello" {
+		t.Errorf("Expected Hello, got %q, %v", got, err)
+	}
+}
+
+func TestTextRejectsInvalidData(t *testing.T) {
+	full := archived("Hello there")
+	inputs := [][]byte{
+		nil,
+		[]byte("not a typedstream"),
+		[]byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@"),
+		full[:len(full)-30],
+		[]byte("\x04\x0bstreamtyped\x84\x84\x08NSString\x01\x94\x84\x01+\x03\xff\xfe\xfd"),
+	}
+	for _, data := range inputs {
+		if text, err := Text(data); err == nil {
+			t.Errorf("Expected an error for %q, got %q", data, text)
+		}
+	}
+}
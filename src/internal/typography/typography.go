@@ -0,0 +1,168 @@
+// Package typography polishes message text for print: straight quotes become curly ones,
+// double hyphens dashes and three dots an ellipsis, and punctuation that a language spaces
+// from its word is kept on the same line with a non-breaking space. Links are left alone.
+package typography
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"threadbound/internal/i18n"
+)
+
+// NoBreakSpace keeps the words either side of it on one line
+const NoBreakSpace = "\u00a0"
+
+// Style is how a language writes quotes and spaces punctuation
+type Style struct {
+	OpenDouble, CloseDouble string // Outer quotes, such as “ and ” or « and »
+	OpenSingle, CloseSingle string // Quotes within quotes
+	SpaceBefore             string // Punctuation set off from the word before it, such as French ;!?
+	SpaceInsideQuotes       bool   // Double quotes are set off from what they quote: « comme ça »
+}
+
+// styles by language; others are written in English style
+var styles = map[string]Style{
+	"en": {OpenDouble: "“", CloseDouble: "”", OpenSingle: "‘", CloseSingle: "’"},
+	"de": {OpenDouble: "„", CloseDouble: "“", OpenSingle: "‚", CloseSingle: "‘"},
+	"es": {OpenDouble: "«", CloseDouble: "»", OpenSingle: "“", CloseSingle: "”"},
+	"fr": {OpenDouble: "«", CloseDouble: "»", OpenSingle: "“", CloseSingle: "”", SpaceBefore: ";:!?", SpaceInsideQuotes: true},
+}
+
+// For returns the style of a locale such as fr or fr-CA, or English's for any other
+func For(locale string) Style {
+	return styles[i18n.For(locale).Locale()]
+}
+
+var (
+	// Links keep their quotes, hyphens and dots
+	linkRegex = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+	ellipsisRegex   = regexp.MustCompile(`\.\.\.+|\. \. \.`)
+	numberDashRegex = regexp.MustCompile(`(\d)--(\d)`)
+)
+
+// Polish returns text set in the style, except for the links in it
+func (s Style) Polish(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, link := range linkRegex.FindAllStringIndex(text, -1) {
+		b.WriteString(s.polish(text[last:link[0]]))
+		b.WriteString(text[link[0]:link[1]])
+		last = link[1]
+	}
+	b.WriteString(s.polish(text[last:]))
+	return b.String()
+}
+
+// polish sets text without links in the style
+func (s Style) polish(text string) string {
+	if text == "" {
+		return text
+	}
+	text = ellipsisRegex.ReplaceAllString(text, "…")
+	text = strings.ReplaceAll(text, "---", "—")
+	text = numberDashRegex.ReplaceAllString(text, "$1–$2") // Ranges such as 10–12
+	text = strings.ReplaceAll(text, "--", "—")
+	text = s.curlQuotes(text)
+	if s.SpaceBefore != "" {
+		text = s.spacePunctuation(text)
+	}
+	return text
+}
+
+// curlQuotes turns each straight quote into an opening or closing one, from the characters
+// either side of it. A single quote within a word, or after one with no quote open, is an
+// apostrophe.
+func (s Style) curlQuotes(text string) string {
+	if !strings.ContainsAny(text, `"'`) {
+		return text
+	}
+
+	runes := []rune(text)
+	var b strings.Builder
+	openDouble, openSingle := false, false
+	for i, r := range runes {
+		prev, next := ' ', ' '
+		if i > 0 {
+			prev = runes[i-1]
+		}
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch r {
+		case '"':
+			if opens(prev) && !(openDouble && unicode.IsSpace(next)) {
+				b.WriteString(s.OpenDouble)
+				if s.SpaceInsideQuotes {
+					b.WriteString(NoBreakSpace)
+				}
+				openDouble = true
+			} else {
+				if s.SpaceInsideQuotes {
+					b.WriteString(NoBreakSpace)
+				}
+				b.WriteString(s.CloseDouble)
+				openDouble = false
+			}
+		case '\'':
+			switch {
+			case isWordChar(prev) && isWordChar(next):
+				b.WriteString("’") // don't, l'amour
+			case opens(prev) && unicode.IsDigit(next):
+				b.WriteString("’") // '90s
+			case opens(prev):
+				b.WriteString(s.OpenSingle)
+				openSingle = true
+			case openSingle:
+				b.WriteString(s.CloseSingle)
+				openSingle = false
+			default:
+				b.WriteString("’") // James'
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// opens reports whether a quote after r opens a quotation: at the start of the text, after
+// a space, or after an opening bracket or dash
+func opens(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("([{<—–-/“‘«„‚", r)
+}
+
+// isWordChar reports whether r is part of a word
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// spacePunctuation puts a non-breaking space before the style's spaced punctuation,
+// replacing the space typed there. A colon is only spaced when a space was typed before
+// it, so times such as 10:30 are left alone.
+func (s Style) spacePunctuation(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		if !strings.ContainsRune(s.SpaceBefore, r) || i == 0 {
+			b.WriteRune(r)
+			continue
+		}
+		prev := runes[i-1]
+		switch {
+		case prev == ' ':
+			// Replace the space just written
+			spaced := strings.TrimSuffix(b.String(), " ")
+			b.Reset()
+			b.WriteString(spaced)
+			b.WriteString(NoBreakSpace)
+		case r != ':' && (isWordChar(prev) || strings.ContainsRune("»”)", prev)):
+			b.WriteString(NoBreakSpace)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package typography
+
+import "testing"
+
+func TestPolishEnglish(t *testing.T) {
+	style := For("en-GB")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`she said "see you soon"`, "she said “see you soon”"},
+		{`"it's 'fine'," he said`, "“it’s ‘fine’,” he said"},
+		{"James' car, back in the '90s", "James’ car, back in the ’90s"},
+		{"wait... what", "wait… what"},
+		{"pages 10--12 -- or not", "pages 10–12 — or not"},
+		{"well---maybe", "well—maybe"},
+		{"see you at 10:30!", "see you at 10:30!"},
+		{`see https://example.com/a--b...c "here"`, "see https://example.com/a--b...c “here”"},
+	}
+
+	for _, test := range tests {
+		if result := style.Polish(test.input); result != test.expected {
+			t.Errorf("Polish(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestPolishFrench(t *testing.T) {
+	style := For("fr")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`il a dit "bonjour"`, "il a dit «\u00a0bonjour\u00a0»"},
+		{"vraiment ? oui!", "vraiment\u00a0? oui\u00a0!"},
+		{"l'heure : 10:30; demain", "l’heure\u00a0: 10:30\u00a0; demain"},
+	}
+
+	for _, test := range tests {
+		if result := style.Polish(test.input); result != test.expected {
+			t.Errorf("Polish(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestForUnknownLocale(t *testing.T) {
+	if result := For("xx").Polish(`"hi"`); result != "“hi”" {
+		t.Errorf("Expected English quotes for an unknown locale, got %q", result)
+	}
+	if result := For("de").Polish(`"hallo"`); result != "„hallo“" {
+		t.Errorf("Expected German quotes, got %q", result)
+	}
+}
@@ -0,0 +1,168 @@
+package urlprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// CacheFile is the cache manifest kept in the thumbnail folder
+const CacheFile = "cache.json"
+
+// DefaultCacheTTL is how long a cached preview, or a failure to make one, is reused
+const DefaultCacheTTL = 30 * 24 * time.Hour
+
+// budgetSpent marks a preview made without a download the time budget didn't allow, which
+// isn't cached so the next run can try again
+const budgetSpent = "time budget for previews spent"
+
+// CacheEntry records the preview made for a link
+type CacheEntry struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Source      string    `json:"source,omitempty"`    // As in URLThumbnail.Source
+	Policy      string    `json:"policy,omitempty"`    // The url_policies policy it was made under, when not auto
+	Thumbnail   string    `json:"thumbnail,omitempty"` // File name in the thumbnail folder
+	FetchedAt   time.Time `json:"fetched_at"`
+	Failed      bool      `json:"failed,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Cache remembers the previews made for links between runs, keyed by URL, so they aren't
+// fetched again until they expire
+type Cache struct {
+	Entries map[string]*CacheEntry `json:"entries"`
+
+	dir   string
+	ttl   time.Duration
+	hits  int
+	mutex sync.Mutex
+}
+
+// LoadCache reads the cache manifest in dir, starting an empty cache if there isn't one.
+// A ttl of zero or less uses DefaultCacheTTL.
+func LoadCache(dir string, ttl time.Duration) (*Cache, error) {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	cache := &Cache{Entries: make(map[string]*CacheEntry), dir: dir, ttl: ttl}
+
+	data, err := os.ReadFile(filepath.Join(dir, CacheFile))
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return cache, fmt.Errorf("failed to read preview cache: %w", err)
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return cache, fmt.Errorf("failed to parse preview cache: %w", err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]*CacheEntry)
+	}
+	return cache, nil
+}
+
+// Lookup returns the cached preview for a link if it hasn't expired and was made under the
+// same policy. A failure is returned too, so the link isn't tried again until then.
+func (c *Cache) Lookup(link, policy string, now time.Time) (*URLThumbnail, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := c.Entries[link]
+	if entry == nil || now.Sub(entry.FetchedAt) >= c.ttl || entry.Policy != cachedPolicy(policy) {
+		return nil, false
+	}
+
+	thumbnail := &URLThumbnail{
+		URL:         link,
+		Title:       entry.Title,
+		Description: entry.Description,
+		Source:      entry.Source,
+		Error:       entry.Error,
+	}
+	if !entry.Failed {
+		// The image may have been deleted since; text-only previews don't have one
+		if entry.Thumbnail != "" {
+			path := filepath.Join(c.dir, entry.Thumbnail)
+			if _, err := os.Stat(path); err != nil {
+				return nil, false
+			}
+			thumbnail.ThumbnailPath = path
+		}
+		thumbnail.Success = true
+	}
+	c.hits++
+	return thumbnail, true
+}
+
+// Store records the preview made for a link under a policy
+func (c *Cache) Store(thumbnail *URLThumbnail, policy string, now time.Time) {
+	if c == nil || thumbnail.Error == budgetSpent {
+		return
+	}
+	entry := &CacheEntry{
+		Title:       thumbnail.Title,
+		Description: thumbnail.Description,
+		Source:      thumbnail.Source,
+		Policy:      cachedPolicy(policy),
+		FetchedAt:   now,
+		Failed:      !thumbnail.Success,
+		Error:       thumbnail.Error,
+	}
+	if thumbnail.Success && thumbnail.ThumbnailPath != "" {
+		entry.Thumbnail = filepath.Base(thumbnail.ThumbnailPath)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Entries[thumbnail.URL] = entry
+}
+
+// cachedPolicy is a policy as cache entries record it, empty for the default
+func cachedPolicy(policy string) string {
+	if policy == models.URLPolicyAuto {
+		return ""
+	}
+	return policy
+}
+
+// Hits counts the previews Lookup has found
+func (c *Cache) Hits() int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hits
+}
+
+// Save writes the cache manifest, dropping entries that have expired
+func (c *Cache) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for u, entry := range c.Entries {
+		if now.Sub(entry.FetchedAt) >= c.ttl {
+			delete(c.Entries, u)
+		}
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode preview cache: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, CacheFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write preview cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package urlprocessor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	os.WriteFile(filepath.Join(dir, "abc.png"), []byte("png"), 0644)
+
+	cache, err := LoadCache(dir, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+	cache.Store(&URLThumbnail{URL: "https://example.com/", Title: "Example", Description: "An example", ThumbnailPath: filepath.Join("elsewhere", "abc.png"), Success: true, Source: "download"}, models.URLPolicyAuto, now)
+	cache.Store(&URLThumbnail{URL: "https://broken.example/", Error: "404"}, models.URLPolicyAuto, now)
+	cache.Store(&URLThumbnail{URL: "https://slow.example/", Error: budgetSpent}, models.URLPolicyAuto, now)
+	cache.Store(&URLThumbnail{URL: "https://text.example/", Title: "Text.example", Success: true, Source: "text"}, models.URLPolicyText, now)
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	cache, err = LoadCache(dir, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+	thumbnail, ok := cache.Lookup("https://example.com/", models.URLPolicyAuto, now.Add(time.Hour))
+	if !ok || !thumbnail.Success || thumbnail.Title != "Example" || thumbnail.Description != "An example" || thumbnail.ThumbnailPath != filepath.Join(dir, "abc.png") {
+		t.Errorf("Unexpected cached preview %+v", thumbnail)
+	}
+	if thumbnail, ok := cache.Lookup("https://broken.example/", models.URLPolicyAuto, now.Add(time.Hour)); !ok || thumbnail.Success || thumbnail.Error != "404" {
+		t.Errorf("Expected the failure cached, got %+v", thumbnail)
+	}
+	if thumbnail, ok := cache.Lookup("https://text.example/", models.URLPolicyText, now.Add(time.Hour)); !ok || !thumbnail.Success || thumbnail.ThumbnailPath != "" {
+		t.Errorf("Expected the text-only preview cached, got %+v", thumbnail)
+	}
+	if _, ok := cache.Lookup("https://slow.example/", models.URLPolicyAuto, now.Add(time.Hour)); ok {
+		t.Error("Expected a preview cut short by the time budget not cached")
+	}
+	if _, ok := cache.Lookup("https://example.com/", models.URLPolicyAuto, now.Add(25*time.Hour)); ok {
+		t.Error("Expected an expired preview ignored")
+	}
+	if _, ok := cache.Lookup("https://example.com/", models.URLPolicyText, now.Add(time.Hour)); ok {
+		t.Error("Expected a preview made under another policy ignored")
+	}
+	if cache.Hits() != 3 {
+		t.Errorf("Expected 3 hits, got %d", cache.Hits())
+	}
+
+	os.Remove(filepath.Join(dir, "abc.png"))
+	if _, ok := cache.Lookup("https://example.com/", models.URLPolicyAuto, now.Add(time.Hour)); ok {
+		t.Error("Expected a preview whose image was deleted ignored")
+	}
+}
+
+func TestLoadCacheCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, CacheFile), []byte("{not json"), 0644)
+	cache, err := LoadCache(dir, 0)
+	if err == nil {
+		t.Error("Expected an error for a corrupt cache")
+	}
+	if cache == nil || cache.Entries == nil || cache.ttl != DefaultCacheTTL {
+		t.Errorf("Expected an empty cache to carry on with, got %+v", cache)
+	}
+}
@@ -0,0 +1,220 @@
+package urlprocessor
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"threadbound/internal/models"
+)
+
+// Defaults for ProcessAll's limits
+const (
+	DefaultWorkers      = 8
+	DefaultHostInterval = time.Second
+	DefaultBudget       = 5 * time.Minute
+)
+
+// Limits bound how hard and how long ProcessAll fetches previews
+type Limits struct {
+	Workers      int           // Messages processed at once (0 = DefaultWorkers)
+	HostInterval time.Duration // Least time between downloads from one host (0 = DefaultHostInterval)
+	Budget       time.Duration // Total time for downloads; later links get a domain card (0 = DefaultBudget)
+}
+
+// LimitsFromConfig reads the URL preview limits from the book config
+func LimitsFromConfig(config *models.BookConfig) Limits {
+	return Limits{
+		Workers:      config.URLWorkers,
+		HostInterval: config.URLHostInterval,
+		Budget:       config.URLTimeout,
+	}
+}
+
+// withDefaults fills in the limits left unset
+func (l Limits) withDefaults() Limits {
+	if l.Workers <= 0 {
+		l.Workers = DefaultWorkers
+	}
+	if l.HostInterval <= 0 {
+		l.HostInterval = DefaultHostInterval
+	}
+	if l.Budget <= 0 {
+		l.Budget = DefaultBudget
+	}
+	return l
+}
+
+// urlJob is a message whose links need previews: the links it's the first to contain
+type urlJob struct {
+	messageID int64
+	first     string // The message's first link, which its preview data is for
+	urls      []string
+}
+
+// ProcessAll makes a preview for every link in the messages, each once, using iMessage's
+// own preview data where the message has it. Messages are processed by a pool of workers;
+// downloads are spaced out per host and stop once the time budget is spent.
+func (p *URLProcessor) ProcessAll(messages []models.Message, limits Limits) map[string]*URLThumbnail {
+	limits = limits.withDefaults()
+	p.limiter = newHostLimiter(limits.HostInterval, time.Now().Add(limits.Budget))
+	defer func() { p.limiter = nil }()
+
+	cache, err := LoadCache(p.cacheDir, p.config.URLCacheTTL)
+	if err != nil {
+		// Start afresh rather than fail the book; the cache is rewritten at the end
+		p.config.Log().Warn("⚠️  Link preview cache unavailable", "error", err)
+	}
+
+	// Each link belongs to the first message it's in, as when they were done in order
+	claimed := make(map[string]bool)
+	var jobs []urlJob
+	for _, msg := range messages {
+		if msg.Text == nil {
+			continue
+		}
+		found := p.FindURLsInText(*msg.Text)
+		var urls []string
+		for _, u := range found {
+			// Links to skipped domains get no preview, and nothing is fetched for them
+			if !claimed[u] && p.config.URLPolicies.For(u) != models.URLPolicySkip {
+				claimed[u] = true
+				urls = append(urls, u)
+			}
+		}
+		if len(urls) > 0 {
+			jobs = append(jobs, urlJob{messageID: int64(msg.ID), first: found[0], urls: urls})
+		}
+	}
+
+	thumbnails := make(map[string]*URLThumbnail)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan urlJob)
+
+	for w := 0; w < limits.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				results := p.processJob(job, cache)
+				mutex.Lock()
+				for u, thumbnail := range results {
+					thumbnails[u] = thumbnail
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	if skipped := p.limiter.Skipped(); skipped > 0 {
+		p.config.Log().Warn("⏱️  URL preview time budget used up; downloads skipped", "budget", limits.Budget, "skipped", skipped)
+	}
+	if hits := cache.Hits(); hits > 0 {
+		p.config.Log().Info("💾 Link previews reused from the cache", "reused", hits, "total", len(thumbnails))
+	}
+	if err := cache.Save(); err != nil {
+		p.config.Log().Warn("⚠️  Could not save link previews", "error", err)
+	}
+	return thumbnails
+}
+
+// processJob makes previews for a message's links, from the cache or the message's
+// preview data if it has some, and records the new ones in the cache
+func (p *URLProcessor) processJob(job urlJob, cache *Cache) map[string]*URLThumbnail {
+	results := make(map[string]*URLThumbnail)
+	for _, u := range job.urls {
+		if thumbnail, ok := cache.Lookup(u, p.config.URLPolicies.For(u), time.Now()); ok {
+			results[u] = thumbnail
+		}
+	}
+
+	// The preview data is for the message's first link, unless an earlier message has it.
+	// Screenshots are taken whatever the message has.
+	policy := p.config.URLPolicies.For(job.first)
+	if _, cached := results[job.first]; !cached && job.first == job.urls[0] && policy != models.URLPolicyScreenshot {
+		if thumbnail := p.ProcessMessageForURLPreviews(job.messageID)[job.first]; thumbnail != nil {
+			results[job.first] = thumbnail
+			cache.Store(thumbnail, policy, time.Now())
+			if thumbnail.Success {
+				p.config.Log().Debug("✅ Found existing preview", "url", job.first, "title", thumbnail.Title)
+			} else {
+				p.config.Log().Debug("⚠️  No preview data found", "url", job.first)
+			}
+		}
+	}
+
+	// For URLs without existing preview data, try the fallback method
+	for _, u := range job.urls {
+		if _, ok := results[u]; ok {
+			continue
+		}
+		thumbnail := p.ProcessURL(u)
+		results[u] = thumbnail
+		cache.Store(thumbnail, p.config.URLPolicies.For(u), time.Now())
+		if thumbnail.Success {
+			p.config.Log().Debug("✅ Generated fallback thumbnail", "url", u)
+		} else {
+			p.config.Log().Warn("⚠️  Failed to generate thumbnail", "url", u)
+		}
+	}
+	return results
+}
+
+// hostLimiter spaces out downloads from each host and refuses them after a deadline
+type hostLimiter struct {
+	interval time.Duration
+	deadline time.Time
+	next     map[string]time.Time // When each host may next be downloaded from
+	skipped  int
+	mutex    sync.Mutex
+}
+
+// newHostLimiter creates a limiter allowing one download per host each interval
+func newHostLimiter(interval time.Duration, deadline time.Time) *hostLimiter {
+	return &hostLimiter{interval: interval, deadline: deadline, next: make(map[string]time.Time)}
+}
+
+// Wait blocks until a download from rawURL's host is allowed, and returns how long it may
+// take. It returns false if the deadline would pass first. A nil limiter allows anything.
+func (l *hostLimiter) Wait(rawURL string) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		host = parsed.Hostname()
+	}
+
+	l.mutex.Lock()
+	at := time.Now()
+	if next := l.next[host]; next.After(at) {
+		at = next
+	}
+	if !at.Before(l.deadline) {
+		l.skipped++
+		l.mutex.Unlock()
+		return 0, false
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mutex.Unlock()
+
+	time.Sleep(time.Until(at))
+	return time.Until(l.deadline), true
+}
+
+// Skipped counts the downloads refused because the deadline had passed
+func (l *hostLimiter) Skipped() int {
+	if l == nil {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.skipped
+}
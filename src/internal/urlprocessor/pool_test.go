@@ -0,0 +1,118 @@
+package urlprocessor
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"threadbound/internal/database"
+	"threadbound/internal/logging"
+	"threadbound/internal/models"
+)
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(50*time.Millisecond, time.Now().Add(time.Hour))
+
+	start := time.Now()
+	limiter.Wait("https://example.com/a.jpg")
+	limiter.Wait("https://other.example/b.jpg")
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Expected different hosts not to wait on each other, took %v", elapsed)
+	}
+	limiter.Wait("https://example.com/c.jpg")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected a second download from one host to wait, took %v", elapsed)
+	}
+}
+
+func TestHostLimiterDeadline(t *testing.T) {
+	limiter := newHostLimiter(time.Hour, time.Now().Add(time.Second))
+	if remaining, ok := limiter.Wait("https://example.com/a.jpg"); !ok || remaining <= 0 || remaining > time.Second {
+		t.Errorf("Expected the first download allowed within the budget, got %v, %v", remaining, ok)
+	}
+	if _, ok := limiter.Wait("https://example.com/b.jpg"); ok {
+		t.Error("Expected a download past the deadline refused")
+	}
+	if limiter.Skipped() != 1 {
+		t.Errorf("Expected 1 skipped download, got %d", limiter.Skipped())
+	}
+
+	var none *hostLimiter
+	if _, ok := none.Wait("https://example.com/"); !ok {
+		t.Error("Expected a nil limiter to allow everything")
+	}
+}
+
+func TestProcessAll(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetConnection().Exec(database.Schema); err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	messages := []models.Message{
+		{ID: 1, Text: text("see https://example.com/a and https://example.org/b")},
+		{ID: 2, Text: text("again https://example.com/a")},
+		{ID: 3},
+		{ID: 4, Text: text("https://example.net/c.")},
+	}
+
+	config := &models.BookConfig{AttachmentsPath: t.TempDir(), Logger: logging.New(io.Discard, logging.Options{})}
+	thumbnails := New(config, db.GetConnection()).ProcessAll(messages, Limits{Workers: 3})
+	for _, u := range []string{"https://example.com/a", "https://example.org/b", "https://example.net/c"} {
+		if thumbnails[u] == nil || thumbnails[u].URL != u {
+			t.Errorf("Expected a preview for %s, got %+v", u, thumbnails[u])
+		}
+	}
+	if len(thumbnails) != 3 {
+		t.Errorf("Expected each link once, got %d", len(thumbnails))
+	}
+
+	// Failures are remembered, so a second run doesn't try again
+	cache, err := LoadCache(filepath.Join(config.AttachmentsPath, "url-thumbnails"), 0)
+	if err != nil || len(cache.Entries) != 3 {
+		t.Fatalf("Expected the previews cached, got %v, %v", cache, err)
+	}
+	New(config, db.GetConnection()).ProcessAll(messages, Limits{Workers: 3})
+	again, _ := LoadCache(filepath.Join(config.AttachmentsPath, "url-thumbnails"), 0)
+	for u, entry := range cache.Entries {
+		if again.Entries[u] == nil || !again.Entries[u].FetchedAt.Equal(entry.FetchedAt) {
+			t.Errorf("Expected %s reused from the cache, got %+v", u, again.Entries[u])
+		}
+	}
+}
+
+func TestProcessAllPolicies(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetConnection().Exec(database.Schema); err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	messages := []models.Message{
+		{ID: 1, Text: text("https://bit.ly/3abc and https://news.example.com/story")},
+	}
+	config := &models.BookConfig{
+		AttachmentsPath: t.TempDir(),
+		Logger:          logging.New(io.Discard, logging.Options{}),
+		URLPolicies:     models.URLPolicies{"bit.ly": models.URLPolicySkip, "example.com": models.URLPolicyText},
+	}
+
+	thumbnails := New(config, db.GetConnection()).ProcessAll(messages, Limits{})
+	if _, ok := thumbnails["https://bit.ly/3abc"]; ok {
+		t.Error("Expected no preview for a skipped domain")
+	}
+	preview := thumbnails["https://news.example.com/story"]
+	if preview == nil || !preview.Success || preview.Source != "text" || preview.ThumbnailPath != "" || preview.Title != "News.example.com" {
+		t.Errorf("Expected a text-only preview, got %+v", preview)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/md5"
 	"database/sql"
 	"fmt"
+	"math"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
@@ -14,14 +16,17 @@ import (
 
 	"threadbound/internal/models"
 	"threadbound/internal/output"
+	"threadbound/internal/plist"
+	"threadbound/internal/workspace"
 )
 
 // URLProcessor handles URL detection and preview extraction from iMessage database
 type URLProcessor struct {
-	config    *models.BookConfig
-	cacheDir  string
-	urlRegex  *regexp.Regexp
-	db        *sql.DB
+	config   *models.BookConfig
+	cacheDir string
+	urlRegex *regexp.Regexp
+	db       *sql.DB
+	limiter  *hostLimiter // Paces downloads while ProcessAll runs
 }
 
 // URLThumbnail is an alias to output.URLThumbnail for backward compatibility
@@ -30,7 +35,7 @@ type URLThumbnail = output.URLThumbnail
 // New creates a new URL processor
 func New(config *models.BookConfig, db *sql.DB) *URLProcessor {
 	// Create cache directory for URL thumbnails
-	cacheDir := filepath.Join(config.AttachmentsPath, "url-thumbnails")
+	cacheDir := filepath.Join(config.DerivedDir(), "url-thumbnails")
 	os.MkdirAll(cacheDir, 0755)
 
 	// Regex to match HTTP/HTTPS URLs
@@ -120,6 +125,22 @@ func (p *URLProcessor) ProcessURL(urlStr string) *URLThumbnail {
 	hash := fmt.Sprintf("%x", md5.Sum([]byte(urlStr)))
 	thumbnailPath := filepath.Join(p.cacheDir, hash+".png")
 
+	// The domain's policy decides before anything is fetched
+	switch p.config.URLPolicies.For(urlStr) {
+	case models.URLPolicySkip:
+		result.Error = "skipped by url_policies"
+		return result
+	case models.URLPolicyText:
+		result.Title = p.extractDomainTitle(urlStr)
+		result.Success = true
+		result.Source = "text"
+		return result
+	case models.URLPolicyScreenshot:
+		if p.screenshotURL(urlStr, filepath.Join(p.cacheDir, hash+"-screenshot.png"), result) {
+			return result
+		}
+	}
+
 	// Check if thumbnail already exists
 	if _, err := os.Stat(thumbnailPath); err == nil {
 		result.ThumbnailPath = thumbnailPath
@@ -129,7 +150,12 @@ func (p *URLProcessor) ProcessURL(urlStr string) *URLThumbnail {
 	}
 
 	// Generate a simple domain card as fallback
-	success := p.generateDomainCard(urlStr, thumbnailPath, result)
+	var success bool
+	if isSharedAlbumURL(urlStr) {
+		success = p.generateSharedAlbumCard("", 0, thumbnailPath, result)
+	} else {
+		success = p.generateDomainCard(urlStr, thumbnailPath, result)
+	}
 
 	result.Success = success
 	if success {
@@ -141,15 +167,15 @@ func (p *URLProcessor) ProcessURL(urlStr string) *URLThumbnail {
 
 // RichLinkMetadata represents extracted metadata from iMessage rich links
 type RichLinkMetadata struct {
-	Title       string
-	Summary     string
-	SiteName    string
-	ImageIndex  int
-	IconIndex   int
-	HasImage    bool
-	HasIcon     bool
-	ImageURL    string
-	IconURL     string
+	Title      string
+	Summary    string
+	SiteName   string
+	ImageIndex int
+	IconIndex  int
+	HasImage   bool
+	HasIcon    bool
+	ImageURL   string
+	IconURL    string
 }
 
 // MessageAttachment represents an attachment linked to a message
@@ -163,65 +189,32 @@ type MessageAttachment struct {
 
 // extractRichLinkMetadata parses the payload_data plist to extract metadata
 func (p *URLProcessor) extractRichLinkMetadata(payloadData []byte, originalURL string) (*RichLinkMetadata, error) {
-	// Write payload data to temporary file
-	tmpFile := filepath.Join(os.TempDir(), "payload_data.plist")
-	err := os.WriteFile(tmpFile, payloadData, 0644)
+	// payload_data is an NSKeyedArchiver binary plist; decode it directly so
+	// this works without macOS's plutil
+	archive, err := plist.DecodeKeyedArchive(payloadData)
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(tmpFile)
 
-	// Use plutil to convert to readable format
-	cmd := exec.Command("plutil", "-p", tmpFile)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse the output to extract metadata
-	metadata := &RichLinkMetadata{}
-	outputStr := string(output)
-
-	// Extract title via UID reference
-	if titleUID := extractPlistValue(outputStr, `"title" => <[^>]+>\{value = (\d+)\}`); titleUID != "" {
-		if idx := parseInt(titleUID); idx >= 0 {
-			pattern := fmt.Sprintf(`\s+%d => "([^"]+)"`, idx)
-			if title := extractPlistValue(outputStr, pattern); title != "" {
-				metadata.Title = title
-			}
-		}
-	}
-
-	// Extract summary via UID reference
-	if summaryUID := extractPlistValue(outputStr, `"summary" => <[^>]+>\{value = (\d+)\}`); summaryUID != "" {
-		if idx := parseInt(summaryUID); idx >= 0 {
-			pattern := fmt.Sprintf(`\s+%d => "([^"]+)"`, idx)
-			if summary := extractPlistValue(outputStr, pattern); summary != "" {
-				metadata.Summary = summary
-			}
-		}
-	}
-
-	// Extract site name via UID reference
-	if siteNameUID := extractPlistValue(outputStr, `"siteName" => <[^>]+>\{value = (\d+)\}`); siteNameUID != "" {
-		if idx := parseInt(siteNameUID); idx >= 0 {
-			pattern := fmt.Sprintf(`\s+%d => "([^"]+)"`, idx)
-			if siteName := extractPlistValue(outputStr, pattern); siteName != "" {
-				metadata.SiteName = siteName
-			}
-		}
+	metadata := &RichLinkMetadata{
+		Title:    archive.FindString("title"),
+		Summary:  archive.FindString("summary"),
+		SiteName: archive.FindString("siteName"),
 	}
 
 	// Check for image attachment substitute index
-	if imageIndex := extractPlistValue(outputStr, `"richLinkImageAttachmentSubstituteIndex" => (\d+)`); imageIndex != "" {
-		if idx := parseInt(imageIndex); idx >= 0 {
-			metadata.ImageIndex = idx
-			metadata.HasImage = true
-		}
+	if imageIndex, ok := archive.FindInt("richLinkImageAttachmentSubstituteIndex"); ok && imageIndex >= 0 {
+		metadata.ImageIndex = int(imageIndex)
+		metadata.HasImage = true
 	}
 
 	// Extract all URLs from the plist and categorize them
-	allURLs := extractAllURLs(outputStr)
+	var allURLs []string
+	for _, s := range archive.Strings() {
+		if strings.HasPrefix(s, "https://") {
+			allURLs = append(allURLs, s)
+		}
+	}
 
 	// Categorize URLs by priority
 	var previewURLs []string
@@ -239,13 +232,13 @@ func (p *URLProcessor) extractRichLinkMetadata(payloadData []byte, originalURL s
 	if len(previewURLs) > 0 {
 		metadata.ImageURL = previewURLs[0]
 		metadata.HasImage = true
-		fmt.Printf("🖼️ Found preview image: %s\n", metadata.ImageURL)
+		p.config.Log().Debug("🖼️ Found preview image", "url", metadata.ImageURL)
 	} else {
 		// Try to reconstruct preview URLs for services that don't include them
-		if reconstructedURL := p.reconstructPreviewURL(outputStr, originalURL); reconstructedURL != "" {
+		if reconstructedURL := p.reconstructPreviewURL(archive, originalURL); reconstructedURL != "" {
 			metadata.ImageURL = reconstructedURL
 			metadata.HasImage = true
-			fmt.Printf("🔧 Reconstructed preview image: %s\n", metadata.ImageURL)
+			p.config.Log().Debug("🔧 Reconstructed preview image", "url", metadata.ImageURL)
 		}
 	}
 
@@ -253,7 +246,7 @@ func (p *URLProcessor) extractRichLinkMetadata(payloadData []byte, originalURL s
 	if len(iconURLs) > 0 {
 		metadata.IconURL = iconURLs[0]
 		metadata.HasIcon = true
-		fmt.Printf("🔗 Found icon: %s\n", metadata.IconURL)
+		p.config.Log().Debug("🔗 Found icon", "url", metadata.IconURL)
 	}
 
 	return metadata, nil
@@ -306,6 +299,24 @@ func (p *URLProcessor) createThumbnailFromMetadata(url string, metadata *RichLin
 	hash := fmt.Sprintf("%x", md5.Sum([]byte(url)))
 	thumbnailPath := filepath.Join(p.cacheDir, hash+".png")
 
+	// Text-only previews show the title and summary alone, so nothing is fetched or copied
+	if p.config.URLPolicies.For(url) == models.URLPolicyText {
+		if result.Title == "" {
+			result.Title = p.extractDomainTitle(url)
+		}
+		result.Success = true
+		result.Source = "text"
+		return result
+	}
+
+	// Shared iCloud albums get their own card instead of the album's cover photo
+	if isSharedAlbumURL(url) {
+		count := parseAlbumItemCount(metadata.Summary, metadata.Title)
+		if p.generateSharedAlbumCard(metadata.Title, count, thumbnailPath, result) {
+			return result
+		}
+	}
+
 	// Try 1: If we have an image attachment, try to copy it
 	if metadata.HasImage && metadata.ImageIndex < len(attachments) {
 		att := attachments[metadata.ImageIndex]
@@ -355,6 +366,7 @@ func (p *URLProcessor) copyAttachmentAsImage(att MessageAttachment, url string,
 			if p.copyAndConvertImage(sourcePath, thumbnailPath) {
 				result.ThumbnailPath = thumbnailPath
 				result.Success = true
+				result.Source = "attachment"
 				return true
 			}
 		}
@@ -372,22 +384,39 @@ func (p *URLProcessor) copyAndConvertImage(sourcePath, targetPath string) bool {
 
 // downloadImageFromURL downloads an image from a URL and converts it to PNG
 func (p *URLProcessor) downloadImageFromURL(imageURL, targetPath string, result *URLThumbnail) bool {
-	fmt.Printf("📥 Downloading image from: %s\n", imageURL)
+	remaining, ok := p.limiter.Wait(imageURL)
+	if !ok {
+		p.config.Log().Debug("⏱️  Out of time for URL previews, skipping", "url", imageURL)
+		result.Error = budgetSpent
+		return false
+	}
+	maxTime := 10 * time.Second
+	if p.limiter != nil && remaining < maxTime {
+		// curl takes whole seconds, and 0 would mean no limit at all
+		maxTime = max(remaining, time.Second)
+	}
+
+	p.config.Log().Debug("📥 Downloading image", "url", imageURL)
 
-	// Create temporary file for download
-	tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("url_image_%x", md5.Sum([]byte(imageURL))))
+	// Create temporary file for download; other workers may be fetching the same image
+	tmp, err := os.CreateTemp("", "url_image_*")
+	if err != nil {
+		return false
+	}
+	tmp.Close()
+	tmpFile := tmp.Name()
 	defer os.Remove(tmpFile)
 
 	// Download the image using curl
-	cmd := exec.Command("curl", "-L", "-s", "--max-time", "10", "-o", tmpFile, imageURL)
+	cmd := exec.Command("curl", "-L", "-s", "--max-time", fmt.Sprintf("%.0f", math.Ceil(maxTime.Seconds())), "-o", tmpFile, imageURL)
 	if err := cmd.Run(); err != nil {
-		fmt.Printf("⚠️  Failed to download image: %v\n", err)
+		p.config.Log().Warn("⚠️  Failed to download image", "error", err)
 		return false
 	}
 
 	// Check if file was downloaded
 	if stat, err := os.Stat(tmpFile); err != nil || stat.Size() == 0 {
-		fmt.Printf("⚠️  Downloaded file is empty or missing\n")
+		p.config.Log().Warn("⚠️  Downloaded file is empty or missing")
 		return false
 	}
 
@@ -395,46 +424,15 @@ func (p *URLProcessor) downloadImageFromURL(imageURL, targetPath string, result
 	if p.copyAndConvertImage(tmpFile, targetPath) {
 		result.ThumbnailPath = targetPath
 		result.Success = true
-		fmt.Printf("✅ Downloaded and converted image from: %s\n", imageURL)
+		result.Source = "download"
+		p.config.Log().Debug("✅ Downloaded and converted image", "url", imageURL)
 		return true
 	}
 
-	fmt.Printf("⚠️  Failed to convert downloaded image\n")
+	p.config.Log().Warn("⚠️  Failed to convert downloaded image")
 	return false
 }
 
-// Helper functions for parsing plist output
-func extractPlistValue(text, pattern string) string {
-	re := regexp.MustCompile(pattern)
-	matches := re.FindStringSubmatch(text)
-	if len(matches) > 1 {
-		return matches[1]
-	}
-	return ""
-}
-
-func parseInt(s string) int {
-	if s == "" {
-		return -1
-	}
-	var result int
-	fmt.Sscanf(s, "%d", &result)
-	return result
-}
-
-// extractAllURLs finds all HTTP/HTTPS URLs in the plist output
-func extractAllURLs(text string) []string {
-	re := regexp.MustCompile(`"(https://[^"]+)"`)
-	matches := re.FindAllStringSubmatch(text, -1)
-	var urls []string
-	for _, match := range matches {
-		if len(match) > 1 {
-			urls = append(urls, match[1])
-		}
-	}
-	return urls
-}
-
 // isPreviewImageURL determines if a URL is likely a preview image (not an icon)
 func isPreviewImageURL(url string) bool {
 	// High priority: CDN preview services
@@ -447,15 +445,15 @@ func isPreviewImageURL(url string) bool {
 
 	// Medium priority: Image files that aren't obviously icons
 	if strings.Contains(url, ".jpg") || strings.Contains(url, ".jpeg") ||
-	   strings.Contains(url, ".png") || strings.Contains(url, ".webp") ||
-	   strings.Contains(url, ".gif") {
+		strings.Contains(url, ".png") || strings.Contains(url, ".webp") ||
+		strings.Contains(url, ".gif") {
 		// Exclude small images and favicons
 		if strings.Contains(url, "favicon") || strings.Contains(url, "icon") {
 			return false
 		}
 		// Exclude obviously small dimensions
 		if strings.Contains(url, "32x32") || strings.Contains(url, "16x16") ||
-		   strings.Contains(url, "64x64") {
+			strings.Contains(url, "64x64") {
 			return false
 		}
 		return true
@@ -467,17 +465,17 @@ func isPreviewImageURL(url string) bool {
 // isIconURL determines if a URL is likely an icon/favicon
 func isIconURL(url string) bool {
 	return strings.Contains(url, "favicon") ||
-		   strings.Contains(url, "icon") ||
-		   strings.Contains(url, "32x32") ||
-		   strings.Contains(url, "16x16") ||
-		   strings.Contains(url, "64x64")
+		strings.Contains(url, "icon") ||
+		strings.Contains(url, "32x32") ||
+		strings.Contains(url, "16x16") ||
+		strings.Contains(url, "64x64")
 }
 
 // reconstructPreviewURL attempts to reconstruct preview image URLs for services that store them as attachments
-func (p *URLProcessor) reconstructPreviewURL(plistOutput, originalURL string) string {
+func (p *URLProcessor) reconstructPreviewURL(archive *plist.KeyedArchive, originalURL string) string {
 	// Apple Music/iTunes Store reconstruction
 	if strings.Contains(originalURL, "music.apple.com") || strings.Contains(originalURL, "itunes.apple.com") {
-		return p.reconstructAppleMusicArtwork(plistOutput, originalURL)
+		return p.reconstructAppleMusicArtwork(archive, originalURL)
 	}
 
 	// Could add other services here as needed
@@ -485,7 +483,7 @@ func (p *URLProcessor) reconstructPreviewURL(plistOutput, originalURL string) st
 }
 
 // reconstructAppleMusicArtwork builds Apple Music artwork URL from metadata
-func (p *URLProcessor) reconstructAppleMusicArtwork(plistOutput, originalURL string) string {
+func (p *URLProcessor) reconstructAppleMusicArtwork(archive *plist.KeyedArchive, originalURL string) string {
 	// For Apple Music, we can try using their public API to get artwork
 	// However, playlist artwork is often not available via direct URL reconstruction
 	//
@@ -501,7 +499,7 @@ func (p *URLProcessor) reconstructAppleMusicArtwork(plistOutput, originalURL str
 
 // fetchOpenGraphThumbnail attempts to fetch Open Graph metadata and image
 func (p *URLProcessor) fetchOpenGraphThumbnail(urlStr, outputPath string, result *URLThumbnail) bool {
-	fmt.Printf("🔍 Fetching metadata for: %s\n", urlStr)
+	p.config.Log().Debug("🔍 Fetching metadata", "url", urlStr)
 
 	// Use curl to fetch the webpage and extract Open Graph data
 	metadata := p.extractWebMetadata(urlStr)
@@ -514,7 +512,7 @@ func (p *URLProcessor) fetchOpenGraphThumbnail(urlStr, outputPath string, result
 
 	// Try to download Open Graph image if available
 	if metadata.ImageURL != "" {
-		fmt.Printf("📸 Downloading Open Graph image: %s\n", metadata.ImageURL)
+		p.config.Log().Debug("📸 Downloading Open Graph image", "url", metadata.ImageURL)
 		if p.downloadImage(metadata.ImageURL, outputPath) {
 			return true
 		}
@@ -522,7 +520,7 @@ func (p *URLProcessor) fetchOpenGraphThumbnail(urlStr, outputPath string, result
 
 	// Try to get favicon as fallback
 	if metadata.FaviconURL != "" {
-		fmt.Printf("🎭 Downloading favicon: %s\n", metadata.FaviconURL)
+		p.config.Log().Debug("🎭 Downloading favicon", "url", metadata.FaviconURL)
 		if p.downloadAndResizeFavicon(metadata.FaviconURL, outputPath, result.Title, result.Description) {
 			return true
 		}
@@ -547,7 +545,7 @@ func (p *URLProcessor) extractWebMetadata(urlStr string) WebMetadata {
 	cmd := exec.Command("curl", "-L", "-A", "Mozilla/5.0 (compatible; iMessages-Book)", "--max-time", "10", urlStr)
 	output, err := cmd.Output()
 	if err != nil {
-		fmt.Printf("⚠️  Failed to fetch %s: %v\n", urlStr, err)
+		p.config.Log().Warn("⚠️  Failed to fetch", "url", urlStr, "error", err)
 		return metadata
 	}
 
@@ -647,7 +645,7 @@ func (p *URLProcessor) downloadImage(imageURL, outputPath string) bool {
 	cmd := exec.Command("curl", "-L", "--max-time", "15", "-o", outputPath, imageURL)
 	err := cmd.Run()
 	if err != nil {
-		fmt.Printf("⚠️  Failed to download image %s: %v\n", imageURL, err)
+		p.config.Log().Warn("⚠️  Failed to download image", "url", imageURL, "error", err)
 		return false
 	}
 
@@ -663,13 +661,16 @@ func (p *URLProcessor) downloadImage(imageURL, outputPath string) bool {
 
 // downloadAndResizeFavicon downloads a favicon and creates a card with it
 func (p *URLProcessor) downloadAndResizeFavicon(faviconURL, outputPath, title, description string) bool {
-	// Download favicon to temporary location
-	tempFavicon := filepath.Join(p.cacheDir, "temp_favicon.ico")
-	defer os.Remove(tempFavicon)
+	// Download favicon to a workspace of its own; other workers may be fetching one too
+	ws, err := workspace.New(p.config.WorkDir, "favicon")
+	if err != nil {
+		return false
+	}
+	defer ws.Remove()
+	tempFavicon := ws.Path("favicon.ico")
 
 	cmd := exec.Command("curl", "-L", "--max-time", "10", "-o", tempFavicon, faviconURL)
-	err := cmd.Run()
-	if err != nil {
+	if err := cmd.Run(); err != nil {
 		return false
 	}
 
@@ -684,31 +685,31 @@ func (p *URLProcessor) downloadAndResizeFavicon(faviconURL, outputPath, title, d
 
 // optimizeDownloadedImage resizes and optimizes a downloaded image
 func (p *URLProcessor) optimizeDownloadedImage(imagePath string) bool {
-	// Check if the file is actually an image first
-	cmd := exec.Command("file", imagePath)
-	output, err := cmd.Output()
+	// Sniff the content type rather than shelling out to file(1), which Windows lacks
+	header := make([]byte, 512)
+	f, err := os.Open(imagePath)
 	if err != nil {
 		return false
 	}
+	n, _ := f.Read(header)
+	f.Close()
 
-	// If it's not an image format we can handle, just return success
-	outputStr := strings.ToLower(string(output))
-	if !strings.Contains(outputStr, "image") && !strings.Contains(outputStr, "jpeg") && !strings.Contains(outputStr, "png") && !strings.Contains(outputStr, "gif") {
-		fmt.Printf("⚠️  File %s is not a recognized image format\n", imagePath)
+	if !strings.HasPrefix(http.DetectContentType(header[:n]), "image/") {
+		p.config.Log().Warn("⚠️  File is not a recognized image format", "path", imagePath)
 		return false
 	}
 
 	// Use ImageMagick to resize and optimize
-	cmd = exec.Command("magick", imagePath,
+	cmd := exec.Command("magick", imagePath,
 		"-resize", "800x600>", // Resize maintaining aspect ratio
 		"-quality", "85",
-		"-strip", // Remove metadata
+		"-strip",       // Remove metadata
 		"-auto-orient", // Fix orientation
-		imagePath) // Overwrite original
+		imagePath)      // Overwrite original
 
 	err = cmd.Run()
 	if err != nil {
-		fmt.Printf("⚠️  Failed to optimize image %s: %v\n", imagePath, err)
+		p.config.Log().Warn("⚠️  Failed to optimize image", "path", imagePath, "error", err)
 		// Don't return false - the image might still be usable
 		return true
 	}
@@ -748,16 +749,37 @@ func (p *URLProcessor) createFaviconCard(faviconPath, outputPath, title, descrip
 
 	err := cmd.Run()
 	if err != nil {
-		fmt.Printf("⚠️  Failed to create favicon card: %v\n", err)
+		p.config.Log().Warn("⚠️  Failed to create favicon card", "error", err)
 		return false
 	}
 
 	return true
 }
 
+// screenshotURL makes a screenshot the preview for a link, reusing one taken before. It
+// waits its turn for the site like a download, and returns false when no screenshot could
+// be taken, so the caller can fall back to a domain card.
+func (p *URLProcessor) screenshotURL(urlStr, outputPath string, result *URLThumbnail) bool {
+	if _, err := os.Stat(outputPath); err == nil {
+		result.Title = p.extractDomainTitle(urlStr)
+	} else if _, ok := p.limiter.Wait(urlStr); !ok {
+		p.config.Log().Debug("⏱️  Out of time for URL previews, skipping screenshot", "url", urlStr)
+		result.Error = budgetSpent
+		return false
+	} else if !p.takeScreenshot(urlStr, outputPath, result) {
+		p.config.Log().Warn("⚠️  Could not take a screenshot (needs playwright or webkit2png)", "url", urlStr)
+		return false
+	}
+
+	result.ThumbnailPath = outputPath
+	result.Success = true
+	result.Source = "screenshot"
+	return true
+}
+
 // takeScreenshot captures a screenshot of the webpage
 func (p *URLProcessor) takeScreenshot(urlStr, outputPath string, result *URLThumbnail) bool {
-	fmt.Printf("📸 Taking screenshot of: %s\n", urlStr)
+	p.config.Log().Debug("📸 Taking screenshot", "url", urlStr)
 
 	// Use headless browser approach if available
 	// For macOS, we can try using built-in screenshot tools
@@ -808,15 +830,19 @@ const { chromium } = require('playwright');
 })();
 `, urlStr, outputPath)
 
-	scriptPath := filepath.Join(p.cacheDir, "screenshot.js")
-	err := os.WriteFile(scriptPath, []byte(script), 0644)
+	// The script goes in a workspace of its own, out of the thumbnail cache
+	ws, err := workspace.New(p.config.WorkDir, "screenshot")
 	if err != nil {
 		return false
 	}
-	defer os.Remove(scriptPath)
+	defer ws.Remove()
+	scriptPath := ws.Path("screenshot.js")
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		return false
+	}
 
 	cmd := exec.Command("node", scriptPath)
-	cmd.Dir = p.cacheDir
+	cmd.Dir = ws.Dir
 
 	// Set timeout
 	done := make(chan error, 1)
@@ -846,9 +872,12 @@ func (p *URLProcessor) tryWebKit2PNG(urlStr, outputPath string) bool {
 		return false
 	}
 
-	tempDir := filepath.Join(p.cacheDir, "temp")
-	os.MkdirAll(tempDir, 0755)
-	defer os.RemoveAll(tempDir)
+	ws, err := workspace.New(p.config.WorkDir, "screenshot")
+	if err != nil {
+		return false
+	}
+	defer ws.Remove()
+	tempDir := ws.Dir
 
 	cmd := exec.Command("webkit2png",
 		"--clipped",
@@ -858,8 +887,7 @@ func (p *URLProcessor) tryWebKit2PNG(urlStr, outputPath string) bool {
 		"--dir="+tempDir,
 		urlStr)
 
-	err := cmd.Run()
-	if err != nil {
+	if err := cmd.Run(); err != nil {
 		return false
 	}
 
@@ -877,7 +905,7 @@ func (p *URLProcessor) tryWebKit2PNG(urlStr, outputPath string) bool {
 
 // generateDomainCard creates a simple text-based card for the domain
 func (p *URLProcessor) generateDomainCard(urlStr, outputPath string, result *URLThumbnail) bool {
-	fmt.Printf("🎨 Generating domain card for: %s\n", urlStr)
+	p.config.Log().Debug("🎨 Generating domain card", "url", urlStr)
 
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -909,10 +937,88 @@ func (p *URLProcessor) generateDomainCard(urlStr, outputPath string, result *URL
 
 	err = cmd.Run()
 	if err != nil {
-		fmt.Printf("⚠️  Failed to generate domain card: %v\n", err)
+		p.config.Log().Warn("⚠️  Failed to generate domain card", "error", err)
+		return false
+	}
+
+	result.Source = "domain"
+	return true
+}
+
+// albumCountRegex matches item counts such as "24 Photos" or "3 videos" in album previews
+var albumCountRegex = regexp.MustCompile(`(?i)(\d[\d,]*)\s+(photos?|videos?|items?)\b`)
+
+// isSharedAlbumURL checks for iCloud Shared Album links such as https://www.icloud.com/sharedalbum/#B0abc
+func isSharedAlbumURL(urlStr string) bool {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
 		return false
 	}
 
+	host := strings.TrimPrefix(strings.ToLower(parsedURL.Host), "www.")
+	return host == "icloud.com" && strings.HasPrefix(parsedURL.Path, "/sharedalbum")
+}
+
+// parseAlbumItemCount totals the photo and video counts in the first text that mentions any
+func parseAlbumItemCount(texts ...string) int {
+	for _, text := range texts {
+		total := 0
+		for _, match := range albumCountRegex.FindAllStringSubmatch(text, -1) {
+			var n int
+			fmt.Sscanf(strings.ReplaceAll(match[1], ",", ""), "%d", &n)
+			total += n
+		}
+		if total > 0 {
+			return total
+		}
+	}
+	return 0
+}
+
+// generateSharedAlbumCard creates a "Shared Album: <name>" card with the album's item count
+func (p *URLProcessor) generateSharedAlbumCard(name string, count int, outputPath string, result *URLThumbnail) bool {
+	title := "Shared Album"
+	if name != "" {
+		title = "Shared Album: " + name
+	}
+
+	subtitle := "iCloud Photos"
+	if count == 1 {
+		subtitle = "1 item · iCloud Photos"
+	} else if count > 1 {
+		subtitle = fmt.Sprintf("%d items · iCloud Photos", count)
+	}
+
+	result.Title = title
+	result.Description = subtitle
+
+	// Album names come from other people; keep ImageMagick from treating them as
+	// @file references or % escapes
+	annotation := strings.ReplaceAll(strings.TrimLeft(title, "@"), "%", "%%")
+
+	// Use ImageMagick to create a card styled after the Photos app
+	cmd := exec.Command("magick",
+		"-size", "400x200",
+		"xc:#FFF8E7",
+		"-gravity", "center",
+		"-pointsize", "22",
+		"-fill", "black",
+		"-annotate", "+0-20", annotation,
+		"-pointsize", "14",
+		"-fill", "#8E8E93",
+		"-annotate", "+0+20", subtitle,
+		"-border", "2x2",
+		"-bordercolor", "#FF9500",
+		outputPath)
+
+	if err := cmd.Run(); err != nil {
+		p.config.Log().Warn("⚠️  Failed to generate shared album card", "error", err)
+		return false
+	}
+
+	result.ThumbnailPath = outputPath
+	result.Success = true
+	result.Source = "album"
 	return true
 }
 
@@ -957,4 +1063,4 @@ func (p *URLProcessor) ReplaceURLsWithImages(text string, thumbnails map[string]
 	}
 
 	return result
-}
\ No newline at end of file
+}
@@ -0,0 +1,42 @@
+package urlprocessor
+
+import "testing"
+
+func TestIsSharedAlbumURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://www.icloud.com/sharedalbum/#B0a5Ab1c2D3e4F", true},
+		{"https://icloud.com/sharedalbum/#B0a5Ab1c2D3e4F", true},
+		{"https://www.icloud.com/photos/#0a1b2c3d", false},
+		{"https://www.icloud.com.example.com/sharedalbum/", false},
+		{"https://example.com/sharedalbum/", false},
+	}
+
+	for _, test := range tests {
+		if result := isSharedAlbumURL(test.url); result != test.expected {
+			t.Errorf("isSharedAlbumURL(%q) = %v, expected %v", test.url, result, test.expected)
+		}
+	}
+}
+
+func TestParseAlbumItemCount(t *testing.T) {
+	tests := []struct {
+		texts    []string
+		expected int
+	}{
+		{[]string{"24 Photos"}, 24},
+		{[]string{"12 Photos, 3 Videos"}, 15},
+		{[]string{"1 photo"}, 1},
+		{[]string{"1,204 items"}, 1204},
+		{[]string{"", "Beach Trip – 8 Photos"}, 8},
+		{[]string{"Beach Trip"}, 0},
+	}
+
+	for _, test := range tests {
+		if result := parseAlbumItemCount(test.texts...); result != test.expected {
+			t.Errorf("parseAlbumItemCount(%q) = %d, expected %d", test.texts, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package vcard reads the .vcf files Messages sends for shared contacts and locations, so
+// they can be shown as a contact card or a place instead of a bare attachment name
+package vcard
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"threadbound/internal/models"
+)
+
+// Labels shown when a card's file can't be read
+const (
+	ContactLabel  = "👤 Contact card"
+	LocationLabel = "📍 Shared location"
+)
+
+// currentLocation is the name Messages gives a location shared with "Send My Current Location"
+const currentLocation = "Current Location"
+
+// Card is a contact or location read from a vCard
+type Card struct {
+	Name         string
+	Organization string
+	Phones       []string
+	Emails       []string
+
+	// Set for shared locations, which are vCards with an Apple Maps link
+	Place               string
+	Latitude, Longitude float64
+	HasLocation         bool
+}
+
+// IsCard reports whether an attachment is a vCard, either a contact or a shared location
+func IsCard(att models.Attachment) bool {
+	if att.MimeType != nil {
+		switch strings.ToLower(*att.MimeType) {
+		case "text/vcard", "text/x-vcard", "text/directory", "text/x-vlocation":
+			return true
+		}
+	}
+	return att.Filename != nil && strings.EqualFold(filepath.Ext(*att.Filename), ".vcf")
+}
+
+// IsLocation reports whether a vCard attachment is a shared location, named like CL.loc.vcf
+func IsLocation(att models.Attachment) bool {
+	if att.MimeType != nil && strings.EqualFold(*att.MimeType, "text/x-vlocation") {
+		return true
+	}
+	return att.Filename != nil && strings.HasSuffix(strings.ToLower(*att.Filename), ".loc.vcf")
+}
+
+// Parse reads the first card in a vCard file
+func Parse(data []byte) (*Card, error) {
+	card := &Card{}
+	found := false
+	for _, line := range unfold(string(data)) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Drop the parameters and any group, as in item1.URL;type=pref
+		name, _, _ = strings.Cut(name, ";")
+		if i := strings.LastIndex(name, "."); i != -1 {
+			name = name[i+1:]
+		}
+
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			found = true
+		case "END":
+			if found {
+				return card, nil
+			}
+		case "FN":
+			card.Name = unescape(value)
+		case "N":
+			if card.Name == "" {
+				card.Name = structuredName(value)
+			}
+		case "ORG":
+			card.Organization = unescape(strings.Split(value, ";")[0])
+		case "TEL":
+			card.Phones = append(card.Phones, unescape(value))
+		case "EMAIL":
+			card.Emails = append(card.Emails, unescape(value))
+		case "URL":
+			card.readMapURL(unescape(value))
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no vCard found")
+	}
+	return card, nil
+}
+
+// Summary describes the card in a few lines: a place and its coordinates for a shared
+// location, or the name, organization, phone numbers and email addresses of a contact
+func (c *Card) Summary() string {
+	if c.HasLocation {
+		place := c.Place
+		if place == "" && c.Name != currentLocation {
+			place = c.Name
+		}
+		if place == "" {
+			place = currentLocation
+		}
+		return fmt.Sprintf("📍 %s\n%.5f, %.5f", place, c.Latitude, c.Longitude)
+	}
+
+	name := c.Name
+	if name == "" {
+		name = "Contact"
+	}
+	lines := []string{"👤 " + name}
+	if c.Organization != "" {
+		lines = append(lines, c.Organization)
+	}
+	for _, phone := range c.Phones {
+		lines = append(lines, "📞 "+phone)
+	}
+	for _, email := range c.Emails {
+		lines = append(lines, "✉️ "+email)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Label describes a card whose file can't be read, naming the contact from the file name
+func Label(att models.Attachment) string {
+	if IsLocation(att) {
+		return LocationLabel
+	}
+	if att.Filename != nil {
+		name := strings.TrimSuffix(filepath.Base(*att.Filename), filepath.Ext(*att.Filename))
+		if name != "" && !strings.EqualFold(name, "vcard") {
+			return "👤 " + name
+		}
+	}
+	return ContactLabel
+}
+
+// readMapURL takes the place and coordinates from an Apple Maps link such as
+// http://maps.apple.com/?ll=37.3317,-122.0307&q=Apple%20Park
+func (c *Card) readMapURL(raw string) {
+	link, err := url.Parse(raw)
+	if err != nil || !strings.Contains(link.Host, "maps.apple.com") {
+		return
+	}
+	query := link.Query()
+	lat, lon, ok := coordinates(query.Get("ll"))
+	if !ok {
+		return
+	}
+	c.Latitude, c.Longitude, c.HasLocation = lat, lon, true
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		if _, _, isCoordinates := coordinates(q); !isCoordinates {
+			c.Place = q
+		}
+	}
+}
+
+// coordinates parses "latitude,longitude"
+func coordinates(s string) (float64, float64, bool) {
+	latText, lonText, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonText), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// structuredName turns N's "Family;Given;Middle;Prefix;Suffix" into "Given Middle Family"
+func structuredName(value string) string {
+	parts := strings.Split(value, ";")
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	return strings.Join(strings.Fields(unescape(parts[1]+" "+parts[2]+" "+parts[0])), " ")
+}
+
+// unfold joins lines continued on the next line with a leading space or tab
+func unfold(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// unescape undoes vCard escaping of commas, semicolons, newlines and backslashes
+func unescape(value string) string {
+	replacer := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`)
+	return strings.TrimSpace(replacer.Replace(value))
+}
@@ -0,0 +1,62 @@
+package vcard
+
+import (
+	"testing"
+
+	"threadbound/internal/models"
+)
+
+func TestParseContact(t *testing.T) {
+	data := "BEGIN:VCARD\r\nVERSION:3.0\r\nN:Appleseed;Jane;;;\r\nORG:Acme\\, Inc.;Sales\r\n" +
+		"item1.TEL;type=CELL;type=pref:+1 (555) 010-\r\n 0123\r\nEMAIL;type=INTERNET:jane@example.com\r\nEND:VCARD\r\n"
+	card, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := "👤 Jane Appleseed\nAcme, Inc.\n📞 +1 (555) 010-0123\n✉️ jane@example.com"
+	if got := card.Summary(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	data := "BEGIN:VCARD\nVERSION:3.0\nN:;Current Location;;;\nFN:Current Location\n" +
+		"item1.URL;type=pref:http://maps.apple.com/?ll=37.331686\\,-122.030656&q=37.331686\\,-122.030656\n" +
+		"item1.X-ABLabel:map url\nEND:VCARD\n"
+	card, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got, want := card.Summary(), "📍 Current Location\n37.33169, -122.03066"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	card, _ = Parse([]byte("BEGIN:VCARD\nFN:Dropped Pin\nURL:https://maps.apple.com/?ll=51.5,-0.12&q=Trafalgar%20Square\nEND:VCARD\n"))
+	if got, want := card.Summary(), "📍 Trafalgar Square\n51.50000, -0.12000"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	if _, err := Parse([]byte("not a card")); err == nil {
+		t.Error("Expected an error for a file without a vCard")
+	}
+}
+
+func TestIsCardAndLabel(t *testing.T) {
+	name := func(s string) *string { return &s }
+	contact := models.Attachment{Filename: name("~/Library/Messages/Attachments/ab/Jane Appleseed.vcf")}
+	location := models.Attachment{Filename: name("CL.loc.vcf"), MimeType: name("text/x-vlocation")}
+	photo := models.Attachment{Filename: name("IMG_0001.HEIC"), MimeType: name("image/heic")}
+
+	if !IsCard(contact) || !IsCard(location) || IsCard(photo) {
+		t.Error("Expected only the .vcf attachments to be cards")
+	}
+	if IsLocation(contact) || !IsLocation(location) {
+		t.Error("Expected only CL.loc.vcf to be a location")
+	}
+	if got := Label(contact); got != "👤 Jane Appleseed" {
+		t.Errorf("Unexpected label %q", got)
+	}
+	if got := Label(location); got != LocationLabel {
+		t.Errorf("Unexpected label %q", got)
+	}
+}
@@ -0,0 +1,361 @@
+// Package wizard asks a few questions in the terminal and writes a config file for the
+// generate command, so a first book doesn't start with guessing flags
+package wizard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"gopkg.in/yaml.v3"
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// DefaultConfigPath is where the wizard writes the config unless told otherwise
+const DefaultConfigPath = "config.yaml"
+
+// listedChats is how many chats are listed at once; the rest scroll into view or are searched for
+const listedChats = 20
+
+// PageSize is a trim size the wizard offers
+type PageSize struct {
+	Name   string
+	Width  string
+	Height string
+}
+
+// PageSizes are the sizes offered, the default first
+var PageSizes = []PageSize{
+	{Name: "5.5 x 8.5 in (digest)", Width: "5.5in", Height: "8.5in"},
+	{Name: "6 x 9 in (trade paperback)", Width: "6in", Height: "9in"},
+	{Name: "8 x 10 in (photo book)", Width: "8in", Height: "10in"},
+	{Name: "A5", Width: "148mm", Height: "210mm"},
+}
+
+// Options seed the wizard's questions
+type Options struct {
+	DatabasePath string // Suggested database; DefaultDatabasePath when empty
+	ConfigPath   string // Config file to write; DefaultConfigPath when empty
+	Force        bool   // Overwrite an existing config file without asking
+}
+
+// Config is the part of the book config the wizard fills in. Its keys are the ones
+// LoadConfigFromFile reads.
+type Config struct {
+	Title           string            `yaml:"title"`
+	Author          string            `yaml:"author,omitempty"`
+	DatabasePath    string            `yaml:"database_path"`
+	AttachmentsPath string            `yaml:"attachments_path"`
+	OutputPath      string            `yaml:"output_path"`
+	IncludeImages   bool              `yaml:"include_images"`
+	PageWidth       string            `yaml:"page_width"`
+	PageHeight      string            `yaml:"page_height"`
+	ChatID          int               `yaml:"chat_id,omitempty"`
+	MyName          string            `yaml:"my_name,omitempty"`
+	ContactNames    map[string]string `yaml:"contact_names,omitempty"`
+}
+
+// ErrCanceled is returned when the user declines to overwrite an existing config file or
+// quits with Ctrl+C
+var ErrCanceled = errors.New("canceled")
+
+// DefaultDatabasePath returns the Messages database of the current macOS user when it
+// exists, otherwise chat.db in the working directory
+func DefaultDatabasePath() string {
+	path := models.NormalizePath("~/Library/Messages/chat.db")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return "chat.db"
+}
+
+// Run asks its questions in the terminal on in and out, writes the config file and
+// returns what it wrote
+func Run(in terminal.FileReader, out terminal.FileWriter, opts Options) (*Config, error) {
+	return run(&surveyPrompter{in: in, out: out}, out, opts)
+}
+
+// run asks its questions through p, printing notes and warnings on out
+func run(p prompter, out io.Writer, opts Options) (*Config, error) {
+	w := &wizard{prompt: p, out: out}
+	if opts.ConfigPath == "" {
+		opts.ConfigPath = DefaultConfigPath
+	}
+	if opts.DatabasePath == "" {
+		opts.DatabasePath = DefaultDatabasePath()
+	}
+
+	fmt.Fprintln(out, "📚 Let's set up your book. Press Enter to accept a default; type to search a list.")
+	fmt.Fprintln(out)
+
+	db, dbPath, chats, err := w.openDatabase(opts.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	handles, err := db.GetHandles(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{
+		DatabasePath:    dbPath,
+		AttachmentsPath: defaultAttachmentsPath(dbPath),
+		OutputPath:      "book.tex",
+		IncludeImages:   true,
+	}
+
+	var chat *models.Chat
+	if len(chats) == 0 {
+		fmt.Fprintln(out, "No conversations are listed in this database; the book will include every message.")
+	} else {
+		if chat, err = w.chooseChat(chats, handles); err != nil {
+			return nil, err
+		}
+		config.ChatID = chat.ID
+	}
+
+	if chat != nil && len(chat.HandleIDs) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "👥 How should each person be named in the book?")
+		for _, id := range chat.HandleIDs {
+			handle, exists := handles[id]
+			if !exists {
+				continue
+			}
+			name, err := w.prompt.Input(fmt.Sprintf("Name for %s", handle.Contact), "")
+			if err != nil {
+				return nil, err
+			}
+			if name != "" {
+				if config.ContactNames == nil {
+					config.ContactNames = make(map[string]string)
+				}
+				config.ContactNames[handle.Contact] = name
+			}
+		}
+	}
+
+	// The owner's phone number or Apple ID beats "Me" as a suggestion
+	myName := "Me"
+	if owner := db.DetectOwner(); owner != "" {
+		myName = owner
+	}
+
+	fmt.Fprintln(out)
+	if config.MyName, err = w.prompt.Input("Your name, shown on the messages you sent", myName); err != nil {
+		return nil, err
+	}
+	if config.MyName == "Me" {
+		config.MyName = ""
+	}
+	if config.Title, err = w.prompt.Input("Book title", defaultTitle(chat, handles, config.ContactNames)); err != nil {
+		return nil, err
+	}
+	if config.Author, err = w.prompt.Input("Author", config.MyName); err != nil {
+		return nil, err
+	}
+
+	size, err := w.choosePageSize()
+	if err != nil {
+		return nil, err
+	}
+	config.PageWidth, config.PageHeight = size.Width, size.Height
+
+	if err := w.write(opts.ConfigPath, config, opts.Force); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// prompter asks the wizard's questions. Answers left empty take the default.
+type prompter interface {
+	// Input asks for a line of text
+	Input(message, def string) (string, error)
+	// Select asks for one of options and returns its index. filter, when set, decides
+	// which options match what the user types.
+	Select(message string, options []string, def int, filter func(typed string, index int) bool) (int, error)
+	// Confirm asks a yes or no question
+	Confirm(message string, def bool) (bool, error)
+}
+
+// surveyPrompter asks questions with survey's terminal prompts
+type surveyPrompter struct {
+	in  terminal.FileReader
+	out terminal.FileWriter
+}
+
+func (p *surveyPrompter) Input(message, def string) (string, error) {
+	var answer string
+	err := p.ask(&survey.Input{Message: message, Default: def}, &answer)
+	return strings.TrimSpace(answer), err
+}
+
+func (p *surveyPrompter) Select(message string, options []string, def int, filter func(string, int) bool) (int, error) {
+	prompt := &survey.Select{Message: message, Options: options, Default: def, PageSize: listedChats}
+	if filter != nil {
+		prompt.Filter = func(typed, _ string, index int) bool { return filter(typed, index) }
+	}
+	var index int
+	err := p.ask(prompt, &index)
+	return index, err
+}
+
+func (p *surveyPrompter) Confirm(message string, def bool) (bool, error) {
+	answer := def
+	err := p.ask(&survey.Confirm{Message: message, Default: def}, &answer)
+	return answer, err
+}
+
+// ask runs one survey prompt, treating Ctrl+C as canceling the wizard
+func (p *surveyPrompter) ask(prompt survey.Prompt, response interface{}) error {
+	err := survey.AskOne(prompt, response, survey.WithStdio(p.in, p.out, p.out))
+	if errors.Is(err, terminal.InterruptErr) {
+		return ErrCanceled
+	}
+	return err
+}
+
+// wizard asks its questions through a prompter
+type wizard struct {
+	prompt prompter
+	out    io.Writer
+}
+
+// openDatabase asks for the database until one opens and its chats can be read
+func (w *wizard) openDatabase(suggested string) (*database.DB, string, []models.Chat, error) {
+	for {
+		answer, err := w.prompt.Input("Messages database (chat.db)", suggested)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		path := models.NormalizePath(answer)
+
+		if _, err := os.Stat(path); err != nil {
+			fmt.Fprintf(w.out, "⚠️  %s not found\n", path)
+			suggested = answer
+			continue
+		}
+		db, err := database.New(path)
+		if err == nil {
+			var chats []models.Chat
+			if chats, err = db.ListChats(); err == nil {
+				return db, path, chats, nil
+			}
+			db.Close()
+		}
+
+		fmt.Fprintf(w.out, "⚠️  Could not read %s: %v\n", path, err)
+		fmt.Fprintln(w.out, "   On macOS, give your terminal Full Disk Access in System Settings > Privacy & Security, or copy chat.db somewhere else first.")
+		suggested = answer
+	}
+}
+
+// chooseChat lists the chats, busiest first, with their participants and message counts.
+// Typing searches the chat names, participants and identifiers.
+func (w *wizard) chooseChat(chats []models.Chat, handles map[int]models.Handle) (*models.Chat, error) {
+	options := make([]string, len(chats))
+	for i, chat := range chats {
+		options[i] = fmt.Sprintf("%s (%d messages)", chatName(chat, handles, nil), chat.MessageCount)
+	}
+	matches := func(typed string, index int) bool {
+		chat := chats[index]
+		return strings.Contains(strings.ToLower(chatName(chat, handles, nil)+" "+chat.Identifier), strings.ToLower(typed))
+	}
+
+	fmt.Fprintln(w.out)
+	n, err := w.prompt.Select("💬 Conversation", options, 0, matches)
+	if err != nil {
+		return nil, err
+	}
+	return &chats[n], nil
+}
+
+// choosePageSize offers PageSizes
+func (w *wizard) choosePageSize() (PageSize, error) {
+	options := make([]string, len(PageSizes))
+	for i, size := range PageSizes {
+		options[i] = size.Name
+	}
+
+	fmt.Fprintln(w.out)
+	n, err := w.prompt.Select("📏 Page size", options, 0, nil)
+	if err != nil {
+		return PageSize{}, err
+	}
+	return PageSizes[n], nil
+}
+
+// write saves the config, asking before replacing an existing file unless force is set
+func (w *wizard) write(path string, config *Config, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		overwrite, err := w.prompt.Confirm(fmt.Sprintf("%s exists. Overwrite it?", path), false)
+		if err != nil {
+			return err
+		}
+		if !overwrite {
+			return ErrCanceled
+		}
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	header := "# Written by threadbound init. See config.example.yaml for every option.\n"
+	if err := os.WriteFile(path, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// chatName is a chat's group name, or its participants' names, using any names already
+// chosen in contactNames
+func chatName(chat models.Chat, handles map[int]models.Handle, contactNames map[string]string) string {
+	if chat.DisplayName != "" {
+		return chat.DisplayName
+	}
+
+	var names []string
+	for _, id := range chat.HandleIDs {
+		if handle, exists := handles[id]; exists {
+			if name := contactNames[handle.Contact]; name != "" {
+				names = append(names, name)
+			} else {
+				names = append(names, handle.DisplayName)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return chat.Identifier
+	}
+	return strings.Join(names, ", ")
+}
+
+// defaultTitle suggests a title naming the chat
+func defaultTitle(chat *models.Chat, handles map[int]models.Handle, contactNames map[string]string) string {
+	if chat == nil {
+		return "Our Messages"
+	}
+	if chat.DisplayName != "" {
+		return chat.DisplayName
+	}
+	return "Messages with " + chatName(*chat, handles, contactNames)
+}
+
+// defaultAttachmentsPath is the Attachments folder beside the database, where Messages
+// keeps it, when there is one
+func defaultAttachmentsPath(dbPath string) string {
+	beside := filepath.Join(filepath.Dir(dbPath), "Attachments")
+	if info, err := os.Stat(beside); err == nil && info.IsDir() {
+		return beside
+	}
+	return "Attachments"
+}
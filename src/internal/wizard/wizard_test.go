@@ -0,0 +1,179 @@
+package wizard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/database"
+	"threadbound/internal/models"
+)
+
+// newTestDB writes a database with a busy one-to-one chat and a quieter group chat
+func newTestDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "chat.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.GetConnection().Exec(database.Schema + `
+		INSERT INTO handle (ROWID, id, country, service) VALUES
+			(1, '+15551234567', 'us', 'iMessage'), (2, 'sam@example.com', 'us', 'iMessage');
+		INSERT INTO chat (ROWID, chat_identifier, display_name) VALUES
+			(7, 'chat123', 'Camping Crew'), (8, '+15551234567', '');
+		INSERT INTO chat_handle_join (chat_id, handle_id) VALUES (7, 1), (7, 2), (8, 1);
+		INSERT INTO message (ROWID, guid, text, date, handle_id) VALUES
+			(1, 'm1', 'Tents?', 1, 1), (2, 'm2', 'Hi', 2, 1), (3, 'm3', 'Hey', 3, 1), (4, 'm4', 'Yo', 4, 2);
+		INSERT INTO chat_message_join (chat_id, message_id) VALUES (7, 1), (8, 2), (8, 3), (8, 4);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// scriptedPrompter answers from a list: empty takes the default, a Select answer picks the
+// first option it matches, and a Confirm answer of "y" agrees
+type scriptedPrompter struct {
+	answers []string
+	selects [][]string // Options of each Select asked
+}
+
+func (p *scriptedPrompter) next() (string, error) {
+	if len(p.answers) == 0 {
+		return "", io.EOF
+	}
+	answer := p.answers[0]
+	p.answers = p.answers[1:]
+	return answer, nil
+}
+
+func (p *scriptedPrompter) Input(message, def string) (string, error) {
+	answer, err := p.next()
+	if answer == "" {
+		return def, err
+	}
+	return answer, err
+}
+
+func (p *scriptedPrompter) Select(message string, options []string, def int, filter func(string, int) bool) (int, error) {
+	p.selects = append(p.selects, options)
+	answer, err := p.next()
+	if err != nil || answer == "" {
+		return def, err
+	}
+	for i, option := range options {
+		if filter != nil && filter(answer, i) || filter == nil && strings.Contains(strings.ToLower(option), strings.ToLower(answer)) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no option matches %q", answer)
+}
+
+func (p *scriptedPrompter) Confirm(message string, def bool) (bool, error) {
+	answer, err := p.next()
+	if answer == "" {
+		return def, err
+	}
+	return answer == "y", err
+}
+
+func TestRun(t *testing.T) {
+	dbPath := newTestDB(t)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	// Search for the group chat, name one person, then take the defaults
+	prompt := &scriptedPrompter{answers: []string{
+		dbPath,
+		"camping",
+		"Alex",
+		"",
+		"Jordan",
+		"",
+		"",
+		"6 x 9",
+	}}
+
+	var out bytes.Buffer
+	config, err := run(prompt, &out, Options{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, out.String())
+	}
+
+	// The busiest chat is listed first
+	if len(prompt.selects) == 0 || prompt.selects[0][0] != "+15551234567 (3 messages)" {
+		t.Errorf("Expected chats listed busiest first, got %v", prompt.selects)
+	}
+
+	loaded, err := models.LoadConfigFromFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load written config: %v", err)
+	}
+	if loaded.ChatID != 7 || loaded.DatabasePath != dbPath || loaded.Title != "Camping Crew" || loaded.Author != "Jordan" {
+		t.Errorf("Unexpected config %+v", config)
+	}
+	if loaded.MyName != "Jordan" || len(loaded.ContactNames) != 1 || loaded.ContactNames["+15551234567"] != "Alex" {
+		t.Errorf("Unexpected names %q %v", loaded.MyName, loaded.ContactNames)
+	}
+	if loaded.PageWidth != "6in" || loaded.PageHeight != "9in" || !loaded.IncludeImages {
+		t.Errorf("Unexpected page setup %+v", loaded)
+	}
+
+	// An existing config is only replaced when confirmed
+	_, err = run(&scriptedPrompter{answers: []string{dbPath, "", "", "", "", "", "", "", "n"}}, &out, Options{ConfigPath: configPath})
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got %v", err)
+	}
+	if reloaded, _ := models.LoadConfigFromFile(configPath); reloaded.ChatID != 7 {
+		t.Error("Expected the existing config to be kept")
+	}
+}
+
+func TestRunRetriesDatabase(t *testing.T) {
+	dbPath := newTestDB(t)
+	missing := filepath.Join(t.TempDir(), "missing.db")
+
+	var out bytes.Buffer
+	answers := []string{missing, dbPath, "", "", "", "", "", ""}
+	config, err := run(&scriptedPrompter{answers: answers}, &out, Options{ConfigPath: filepath.Join(t.TempDir(), "config.yaml")})
+	if err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "not found") || config.DatabasePath != dbPath {
+		t.Errorf("Expected a second try at the database, got %q:\n%s", config.DatabasePath, out.String())
+	}
+	if config.ChatID != 8 || config.Title != "Messages with +15551234567" || config.PageWidth != "5.5in" {
+		t.Errorf("Expected the defaults, got %+v", config)
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("Expected no database created at the missing path")
+	}
+
+	// The database's owner is the suggested name and author
+	conn, err := database.New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.GetConnection().Exec(`INSERT INTO message (guid, date, is_from_me, destination_caller_id) VALUES ('m5', 5, 1, 'jordan@icloud.com')`)
+	conn.Close()
+	config, err = run(&scriptedPrompter{answers: answers}, &out, Options{ConfigPath: filepath.Join(t.TempDir(), "config.yaml")})
+	if err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, out.String())
+	}
+	if config.MyName != "jordan@icloud.com" || config.Author != "jordan@icloud.com" {
+		t.Errorf("Expected the owner as name and author, got %q and %q", config.MyName, config.Author)
+	}
+
+	if _, err := run(&scriptedPrompter{answers: []string{dbPath}}, &out, Options{ConfigPath: filepath.Join(t.TempDir(), "config.yaml")}); err == nil {
+		t.Error("Expected error when input ends early")
+	}
+}
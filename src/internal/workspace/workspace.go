@@ -0,0 +1,78 @@
+// Package workspace makes the folders a run keeps its intermediate files in, such as the
+// TeX engine's .aux and .log files, so they stay out of the working directory and beside
+// the book, and finished files are copied out only once they're complete.
+package workspace
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Prefix starts the name of every workspace folder
+const Prefix = "threadbound-"
+
+// Workspace is a folder of one run's intermediate files
+type Workspace struct {
+	Dir string
+}
+
+// New creates a workspace for one run in base, or in the system temp folder when base is
+// empty, named for what it's used for
+func New(base, purpose string) (*Workspace, error) {
+	if base == "" {
+		base = os.TempDir()
+	}
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work folder: %w", err)
+	}
+	dir, err := os.MkdirTemp(base, Prefix+purpose+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	return &Workspace{Dir: dir}, nil
+}
+
+// Path returns where a file of the workspace goes
+func (w *Workspace) Path(name string) string {
+	return filepath.Join(w.Dir, name)
+}
+
+// Export moves a finished file out of the workspace to dest. Across file systems it's
+// copied beside dest first and renamed into place, so dest is never left half-written.
+func (w *Workspace) Export(name, dest string) error {
+	source := w.Path(name)
+	if err := os.Rename(source, dest); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	defer os.Remove(out.Name())
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	if err := os.Chmod(out.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dest)
+}
+
+// Remove deletes the workspace and everything in it
+func (w *Workspace) Remove() error {
+	return os.RemoveAll(w.Dir)
+}
@@ -0,0 +1,48 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "work")
+	ws, err := New(base, "pdf")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if filepath.Dir(ws.Dir) != base || !strings.HasPrefix(filepath.Base(ws.Dir), "threadbound-pdf-") {
+		t.Errorf("Expected a pdf workspace in %s, got %s", base, ws.Dir)
+	}
+
+	// Each run gets a workspace of its own
+	other, err := New(base, "pdf")
+	if err != nil || other.Dir == ws.Dir {
+		t.Errorf("Expected a second workspace, got %v, %v", other, err)
+	}
+
+	os.WriteFile(ws.Path("book.pdf"), []byte("%PDF-1.5"), 0644)
+	os.WriteFile(ws.Path("book.aux"), []byte("\\relax"), 0644)
+	dest := filepath.Join(t.TempDir(), "book.pdf")
+	if err := ws.Export("book.pdf", dest); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != "%PDF-1.5" {
+		t.Errorf("Expected the PDF exported, got %q, %v", data, err)
+	}
+	if err := ws.Export("missing.pdf", dest); err == nil {
+		t.Error("Expected an error exporting a file that isn't there")
+	}
+
+	if err := ws.Remove(); err != nil {
+		t.Fatalf("Failed to remove workspace: %v", err)
+	}
+	if _, err := os.Stat(ws.Dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the workspace removed, got %v", err)
+	}
+	if _, err := os.Stat(other.Dir); err != nil {
+		t.Errorf("Expected the other workspace kept: %v", err)
+	}
+}
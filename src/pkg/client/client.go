@@ -0,0 +1,212 @@
+// Package client is a typed Go client for the threadbound REST API served by
+// `threadbound serve`. Its methods and types follow the OpenAPI document the server
+// publishes at /api/openapi.json, which its tests check them against. It doesn't import
+// the server, so programs using it don't build it in.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned when the server responds with an error status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client talks to a threadbound API server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// New creates a client for the server at baseURL, such as http://localhost:8080
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for requests
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	c.httpClient = httpClient
+	return c
+}
+
+// Health checks that the server is up
+func (c *Client) Health(ctx context.Context) error {
+	var resp map[string]string
+	return c.do(ctx, http.MethodGet, "/api/health", nil, "", &resp)
+}
+
+// Upload sends a chat.db file to the server. Pass the returned ID as
+// GenerateRequest.UploadID to generate a book from it.
+func (c *Client) Upload(ctx context.Context, dbPath string) (*UploadResponse, error) {
+	file, err := os.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer file.Close()
+
+	var resp UploadResponse
+	if err := c.do(ctx, http.MethodPost, "/api/uploads", file, "application/octet-stream", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Generate starts a generation job
+func (c *Client) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var resp GenerateResponse
+	if err := c.do(ctx, http.MethodPost, "/api/generate", bytes.NewReader(body), "application/json", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Job returns the status of a job
+func (c *Client) Job(ctx context.Context, jobID string) (*JobStatusResponse, error) {
+	var resp JobStatusResponse
+	if err := c.do(ctx, http.MethodGet, "/api/jobs/"+jobID, nil, "", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Jobs lists every job the server knows about
+func (c *Client) Jobs(ctx context.Context) ([]JobStatusResponse, error) {
+	var resp []JobStatusResponse
+	if err := c.do(ctx, http.MethodGet, "/api/jobs", nil, "", &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Logs returns the last tail lines of a job's output, or every line the server kept when
+// tail is 0
+func (c *Client) Logs(ctx context.Context, jobID string, tail int) (*JobLogsResponse, error) {
+	path := "/api/jobs/" + jobID + "/logs"
+	if tail > 0 {
+		path += "?tail=" + strconv.Itoa(tail)
+	}
+
+	var resp JobLogsResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, "", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Wait polls a job every interval until it completes or fails. A failed job is returned
+// along with an error carrying the server's message.
+func (c *Client) Wait(ctx context.Context, jobID string, interval time.Duration) (*JobStatusResponse, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job, err := c.Job(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+		switch job.Status {
+		case JobStatusCompleted:
+			return job, nil
+		case JobStatusFailed:
+			return job, fmt.Errorf("job %s failed: %s", jobID, job.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Download writes the output of a completed job to w
+func (c *Client) Download(ctx context.Context, jobID string, w io.Writer) error {
+	resp, err := c.send(ctx, http.MethodGet, "/api/jobs/"+jobID+"/output", nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download output: %w", err)
+	}
+	return nil
+}
+
+// DownloadArtifact writes a file listed in a job's artifacts to w
+func (c *Client) DownloadArtifact(ctx context.Context, artifact Artifact, w io.Writer) error {
+	resp, err := c.send(ctx, http.MethodGet, "/api/artifacts/"+artifact.ID, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", artifact.Name, err)
+	}
+	return nil
+}
+
+// do sends a request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, contentType string, out interface{}) error {
+	resp, err := c.send(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send sends a request, turning error statuses into an *APIError
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", c.baseURL, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	var errResp errorResponse
+	if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Message != "" {
+		apiErr.Message = errResp.Message
+	}
+	return nil, apiErr
+}
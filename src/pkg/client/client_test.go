@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"threadbound/internal/api"
+)
+
+func newTestServer(t *testing.T) *Client {
+	router := mux.NewRouter()
+	api.NewHandler().RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return New(server.URL + "/")
+}
+
+func TestHealth(t *testing.T) {
+	c := newTestServer(t)
+	if err := c.Health(context.Background()); err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+}
+
+func TestUploadGenerateAndWait(t *testing.T) {
+	c := newTestServer(t)
+	ctx := context.Background()
+
+	// A bare header uploads fine but can't be opened, so the job fails
+	dbPath := filepath.Join(t.TempDir(), "chat.db")
+	if err := os.WriteFile(dbPath, []byte("SQLite format 3\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	upload, err := c.Upload(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if upload.UploadID == "" || upload.Size != 16 {
+		t.Errorf("Unexpected upload response %+v", upload)
+	}
+
+	gen, err := c.Generate(ctx, GenerateRequest{UploadID: upload.UploadID})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	job, err := c.Wait(ctx, gen.JobID, 10*time.Millisecond)
+	if err == nil || job == nil || job.Status != JobStatusFailed {
+		t.Errorf("Expected failed job, got %+v (err %v)", job, err)
+	}
+
+	logs, err := c.Logs(ctx, gen.JobID, 1)
+	if err != nil || len(logs.Lines) != 1 {
+		t.Errorf("Expected the last log line, got %+v (err %v)", logs, err)
+	}
+
+	jobs, err := c.Jobs(ctx)
+	if err != nil || len(jobs) != 1 {
+		t.Errorf("Expected 1 job, got %d (err %v)", len(jobs), err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	c := newTestServer(t)
+
+	_, err := c.Job(context.Background(), "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", apiErr.StatusCode)
+	}
+
+	_, err = c.Upload(context.Background(), filepath.Join(t.TempDir(), "missing.db"))
+	if err == nil {
+		t.Error("Expected error uploading a missing file")
+	}
+}
+
+func TestTypesMatchOpenAPI(t *testing.T) {
+	c := newTestServer(t)
+	resp, err := c.send(context.Background(), http.MethodGet, "/api/openapi.json", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Enum       []string                   `json:"enum"`
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatalf("Spec is not valid JSON: %v", err)
+	}
+
+	// Schemas have the same fields as the client's types
+	types := map[string]interface{}{
+		"GenerateRequest":       GenerateRequest{},
+		"RedactRules":           RedactRules{},
+		"Theme":                 Theme{},
+		"NotesPages":            NotesPages{},
+		"UploadResponse":        UploadResponse{},
+		"GenerateResponse":      GenerateResponse{},
+		"JobStatusResponse":     JobStatusResponse{},
+		"Artifact":              Artifact{},
+		"FormatStatus":          FormatStatus{},
+		"JobStats":              JobStats{},
+		"AttachmentReport":      AttachmentReport{},
+		"AttachmentReportEntry": AttachmentReportEntry{},
+		"JobLogsResponse":       JobLogsResponse{},
+		"ErrorResponse":         errorResponse{},
+	}
+	for name, value := range types {
+		schema, exists := spec.Components.Schemas[name]
+		if !exists {
+			t.Errorf("Schema %s is missing from the spec", name)
+			continue
+		}
+		var documented []string
+		for property := range schema.Properties {
+			documented = append(documented, property)
+		}
+		sort.Strings(documented)
+		if fields := jsonFields(reflect.TypeOf(value)); !reflect.DeepEqual(fields, documented) {
+			t.Errorf("Schema %s has properties %v, but the client encodes %v", name, documented, fields)
+		}
+	}
+
+	statuses := []string{string(JobStatusPending), string(JobStatusRunning), string(JobStatusCompleted), string(JobStatusFailed)}
+	if documented := spec.Components.Schemas["JobStatus"].Enum; !reflect.DeepEqual(statuses, documented) {
+		t.Errorf("Expected job statuses %v, got %v", documented, statuses)
+	}
+}
+
+// jsonFields returns the JSON names of a struct's fields, sorted
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,177 @@
+package client
+
+import "time"
+
+// JobStatus is the state of a generation job
+type JobStatus string
+
+// Job statuses reported by the server
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+// GenerateRequest asks the server to generate a book
+type GenerateRequest struct {
+	DatabasePath    string            `json:"database_path,omitempty"` // Path on the server
+	UploadID        string            `json:"upload_id,omitempty"`     // Use a database sent with Upload instead of DatabasePath
+	AttachmentsPath string            `json:"attachments_path,omitempty"`
+	OutputPath      string            `json:"output_path,omitempty"` // File name in the job's directory, or an absolute path the server allows
+	Title           string            `json:"title,omitempty"`
+	Author          string            `json:"author,omitempty"`
+	PageWidth       string            `json:"page_width,omitempty"`
+	PageHeight      string            `json:"page_height,omitempty"`
+	IncludeImages   bool              `json:"include_images"`
+	ContactNames    map[string]string `json:"contact_names,omitempty"`
+	MyName          string            `json:"my_name,omitempty"`
+	Filter          string            `json:"filter,omitempty"`   // Filter query, as for --filter
+	ChatID          int               `json:"chat_id,omitempty"`  // Only include this conversation, by chat ROWID
+	Timezone        string            `json:"timezone,omitempty"` // Zone messages are dated in, such as America/New_York (default: the server's)
+	Locale          string            `json:"locale,omitempty"`   // Language of headings, dates and "Me": en (default), de, es or fr
+	Formats         []string          `json:"formats,omitempty"`  // tex, pdf, html and/or txt, each written beside output_path; empty writes output_path alone
+
+	RedactProfile   string                 `json:"redact_profile,omitempty"`   // contacts, strict, or one of RedactProfiles
+	RedactProfiles  map[string]RedactRules `json:"redact_profiles,omitempty"`  // Custom profiles, overriding built-ins by name
+	ExcludeContacts []string               `json:"exclude_contacts,omitempty"` // Drop messages from these contact IDs or display names
+	ExcludeKeywords []string               `json:"exclude_keywords,omitempty"` // Drop messages containing any of these phrases
+	FilterSpam      bool                   `json:"filter_spam,omitempty"`      // Drop short-code senders and one-time passcodes
+	Dedupe          bool                   `json:"dedupe,omitempty"`           // Collapse identical consecutive messages from one sender
+
+	Theme        Theme      `json:"theme"`                   // Fonts and message bubble style of the TeX book
+	PrintPreset  string     `json:"print_preset,omitempty"`  // kdp, ingram or lulu: add bleed and service margins
+	PrintPages   int        `json:"print_pages,omitempty"`   // Expected page count for the gutter (0 = estimate)
+	NotesPages   NotesPages `json:"notes_pages"`             // Blank or ruled pages for handwritten notes
+	ChapterStats bool       `json:"chapter_stats,omitempty"` // End each chapter with a small stats box
+	StatsChapter bool       `json:"stats_chapter,omitempty"` // Add a "By the Numbers" chapter at the end of the book
+}
+
+// RedactRules selects what a redaction profile masks in message text
+type RedactRules struct {
+	Emails      bool     `json:"emails,omitempty"`
+	Phones      bool     `json:"phones,omitempty"`
+	CreditCards bool     `json:"credit_cards,omitempty"`
+	Words       []string `json:"words,omitempty"`       // Whole words or phrases, matched case-insensitively
+	Patterns    []string `json:"patterns,omitempty"`    // Go regular expressions
+	Replacement string   `json:"replacement,omitempty"` // Text substituted for each match (default: [redacted])
+}
+
+// Theme sets the fonts and message bubble style of a TeX book; empty fields keep the defaults
+type Theme struct {
+	Font           string  `json:"font,omitempty"`
+	EmojiFont      string  `json:"emoji_font,omitempty"`
+	CJKFont        string  `json:"cjk_font,omitempty"`
+	ArabicFont     string  `json:"arabic_font,omitempty"`
+	HebrewFont     string  `json:"hebrew_font,omitempty"`
+	SentColor      string  `json:"sent_color,omitempty"`       // #RRGGBB
+	ReceivedColor  string  `json:"received_color,omitempty"`   // #RRGGBB
+	CornerRadius   string  `json:"corner_radius,omitempty"`    // A TeX length, such as 4pt
+	BubbleMaxWidth float64 `json:"bubble_max_width,omitempty"` // Fraction of the text width
+}
+
+// NotesPages adds blank or ruled pages for handwritten notes
+type NotesPages struct {
+	Count     int      `json:"count,omitempty"`     // Pages at each position (0 disables)
+	Style     string   `json:"style,omitempty"`     // blank or ruled (default: ruled)
+	Title     string   `json:"title,omitempty"`     // Optional heading on the first page, e.g. "Guest Book"
+	Positions []string `json:"positions,omitempty"` // end and/or year (default: end)
+}
+
+// UploadResponse identifies a database uploaded for a later generate request
+type UploadResponse struct {
+	UploadID string `json:"upload_id"`
+	Size     int64  `json:"size"`
+}
+
+// GenerateResponse is the server's answer to a generate request
+type GenerateResponse struct {
+	JobID     string    `json:"job_id"`
+	Status    JobStatus `json:"status"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// JobStatusResponse is the status of a job
+type JobStatusResponse struct {
+	JobID     string     `json:"job_id"`
+	Status    JobStatus  `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Artifacts []Artifact `json:"artifacts,omitempty"` // Files the job produced, once it completes
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Stats     *JobStats  `json:"stats,omitempty"`
+
+	// Set when the request asked for formats: how each went and the files it produced
+	Formats []FormatStatus `json:"formats,omitempty"`
+
+	// Set for PDF output, to size the cover. Spine widths are in inches, keyed by paper stock.
+	PageCount   int                `json:"page_count,omitempty"`
+	SpineWidths map[string]float64 `json:"spine_widths,omitempty"`
+
+	// Attachments missing from disk, unreadable or unsupported, once the job completes
+	AttachmentReport *AttachmentReport `json:"attachment_report,omitempty"`
+}
+
+// Artifact is a file produced by a job, downloaded with Client.Artifact
+type Artifact struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	URL  string `json:"url"` // Download path on the server
+}
+
+// FormatStatus is how one of the formats a job was asked for went
+type FormatStatus struct {
+	Format    string     `json:"format"`
+	Status    JobStatus  `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+}
+
+// JobStats contains statistics about the generated book
+type JobStats struct {
+	TotalMessages   int       `json:"total_messages"`
+	TextMessages    int       `json:"text_messages"`
+	TotalContacts   int       `json:"total_contacts"`
+	AttachmentCount int       `json:"attachment_count"`
+	StartDate       time.Time `json:"start_date,omitempty"`
+	EndDate         time.Time `json:"end_date,omitempty"`
+
+	ExcludedByContact int `json:"excluded_by_contact,omitempty"`
+	ExcludedByKeyword int `json:"excluded_by_keyword,omitempty"`
+	ExcludedAsSpam    int `json:"excluded_as_spam,omitempty"`
+}
+
+// AttachmentReport lists the attachments a book couldn't show
+type AttachmentReport struct {
+	Total       int                     `json:"total"`       // Attachments the messages refer to
+	Found       int                     `json:"found"`       // Found on disk and readable
+	Missing     []AttachmentReportEntry `json:"missing"`     // Not on disk, or with no file name recorded
+	Unreadable  []AttachmentReportEntry `json:"unreadable"`  // On disk, but couldn't be read or converted
+	Unsupported []AttachmentReportEntry `json:"unsupported"` // Readable, but a kind the book only names, such as videos
+}
+
+// AttachmentReportEntry is an attachment the report lists
+type AttachmentReportEntry struct {
+	GUID     string `json:"guid"`
+	Filename string `json:"filename,omitempty"` // As recorded in chat.db
+	MimeType string `json:"mime_type,omitempty"`
+	Path     string `json:"path,omitempty"` // Where it was found on the server's disk
+	Error    string `json:"error,omitempty"`
+}
+
+// JobLogsResponse holds the most recent lines of a job's output
+type JobLogsResponse struct {
+	JobID      string    `json:"job_id"`
+	Status     JobStatus `json:"status"`
+	Lines      []string  `json:"lines"`
+	TotalLines int       `json:"total_lines"` // Lines written so far, including any not returned
+}
+
+// errorResponse is the body of an error status
+type errorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,244 @@
+// Package threadbound generates books from an iMessage database for Go programs that embed
+// the generator instead of running the threadbound command:
+//
+//	src, err := threadbound.Open("chat.db", threadbound.Options{Title: "Our Messages"})
+//	src, err = src.Filter("from:Alice after:2023-01-01")
+//	err = src.Generate(ctx, "tex", file)
+//	result, err := threadbound.BuildPDF(ctx, "book.tex", "book.pdf", threadbound.PDFOptions{})
+//
+// Progress is logged to Options.Logger, and discarded when it's nil.
+package threadbound
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"threadbound/internal/assets"
+	"threadbound/internal/book"
+	"threadbound/internal/database"
+	"threadbound/internal/filter"
+	"threadbound/internal/i18n"
+	"threadbound/internal/logging"
+	"threadbound/internal/models"
+	"threadbound/internal/output"
+)
+
+// Stats describes the messages a book is made from
+type Stats = models.BookStats
+
+// Options set what goes into a book and how it looks. Zero values keep the defaults of the
+// threadbound command.
+type Options struct {
+	AttachmentsPath string // Folder chat.db's attachment paths are resolved in (default: Attachments)
+	Title           string // Default: Our Messages
+	Author          string
+	PageWidth       string            // Such as 5.5in (default: 5.5in)
+	PageHeight      string            // Such as 8.5in (default: 8.5in)
+	ExcludeImages   bool              // Name images instead of printing them
+	ContactNames    map[string]string // Display names keyed by phone number or email
+	MyName          string            // Shown on your messages instead of "Me"
+	ChatID          int               // Only include this conversation, by chat ROWID
+	Timezone        string            // Zone messages are dated in, such as America/New_York (default: local)
+	Locale          string            // Language of headings, dates and "Me": en (default), de, es or fr
+	TemplateDir     string            // Templates to use in place of the built-in ones
+	Logger          *slog.Logger      // Where progress is logged; nil discards it
+}
+
+// discard is the logger used when none is given
+var discard = logging.New(io.Discard, logging.Options{})
+
+// Source is an iMessage database, with the filter and options a book is made from it with
+type Source struct {
+	config models.BookConfig
+}
+
+// Open checks that the database at dbPath can be read and returns it as a source for books
+func Open(dbPath string, options Options) (*Source, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("database not found: %s", dbPath)
+	}
+	db, err := database.New(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.Close()
+
+	config := models.BookConfig{
+		DatabasePath:    dbPath,
+		AttachmentsPath: options.AttachmentsPath,
+		Title:           options.Title,
+		Author:          options.Author,
+		PageWidth:       options.PageWidth,
+		PageHeight:      options.PageHeight,
+		TemplateDir:     options.TemplateDir,
+		IncludeImages:   !options.ExcludeImages,
+		IncludePreviews: true,
+		ContactNames:    options.ContactNames,
+		MyName:          options.MyName,
+		ChatID:          options.ChatID,
+		Timezone:        options.Timezone,
+		Locale:          options.Locale,
+		Logger:          options.Logger,
+	}
+
+	// Set defaults
+	defaults := models.GetDefaultConfig()
+	if config.AttachmentsPath == "" {
+		config.AttachmentsPath = defaults.AttachmentsPath
+	}
+	if config.Title == "" {
+		config.Title = defaults.Title
+	}
+	if config.PageWidth == "" {
+		config.PageWidth = defaults.PageWidth
+	}
+	if config.PageHeight == "" {
+		config.PageHeight = defaults.PageHeight
+	}
+	if config.TemplateDir == "" {
+		config.TemplateDir = assets.Dir(assets.Templates) // Empty falls back to templates embedded in the package
+	}
+	if config.Logger == nil {
+		config.Logger = discard
+	}
+	if _, err := config.Location(); err != nil {
+		return nil, err
+	}
+	if _, err := i18n.Lookup(config.Locale); err != nil {
+		return nil, err
+	}
+	return &Source{config: config}, nil
+}
+
+// Filter returns the source keeping only the messages matching query, in the syntax of
+// --filter, such as `from:Alice has:image before:2022-07-01`
+func (s *Source) Filter(query string) (*Source, error) {
+	if _, err := filter.ParseQuery(query); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	filtered := *s
+	filtered.config.Filter = query
+	return &filtered, nil
+}
+
+// Stats counts the messages, contacts and attachments a book would hold, without
+// generating it
+func (s *Source) Stats(ctx context.Context) (*Stats, error) {
+	return run(ctx, func() (*Stats, error) {
+		config := s.config
+		builder, err := book.New(&config)
+		if err != nil {
+			return nil, err
+		}
+		defer builder.Close()
+		return builder.GetStats()
+	})
+}
+
+// Formats lists the formats Generate writes, such as tex, html and txt
+func Formats() []string {
+	return output.GetIDs()
+}
+
+// Generate writes the book in format to w. It's generated in a temporary directory and
+// read back, so a TeX book refers to its images by their paths in the attachments folder.
+func (s *Source) Generate(ctx context.Context, format string, w io.Writer) error {
+	plugin, err := output.Get(format)
+	if err != nil {
+		return fmt.Errorf("unsupported format %q; formats are %s", format, output.FormatList())
+	}
+
+	data, err := run(ctx, func() ([]byte, error) {
+		dir, err := os.MkdirTemp("", "threadbound-*")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		config := s.config
+		config.Format = format
+		config.OutputPath = filepath.Join(dir, "book."+plugin.FileExtension())
+		builder, err := book.New(&config)
+		if err != nil {
+			return nil, err
+		}
+		defer builder.Close()
+		if err := builder.Generate(); err != nil {
+			return nil, err
+		}
+		return os.ReadFile(config.OutputPath)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// PDFOptions set how BuildPDF typesets a book
+type PDFOptions struct {
+	Engine      string       // xelatex, lualatex, latexmk or tectonic (default: xelatex, else tectonic if only it is installed)
+	TemplateDir string       // The templates the TeX was generated with, if not the built-in ones
+	Logger      *slog.Logger // Where progress is logged; nil discards it
+}
+
+// PDFResult describes a PDF built by BuildPDF
+type PDFResult struct {
+	Path      string
+	Size      int64
+	PageCount int // 0 when it couldn't be read
+}
+
+// BuildPDF typesets the TeX book at texPath into a PDF at pdfPath
+func BuildPDF(ctx context.Context, texPath, pdfPath string, options PDFOptions) (*PDFResult, error) {
+	config := *models.GetDefaultConfig()
+	config.PDFEngine = options.Engine
+	config.TemplateDir = options.TemplateDir
+	config.Logger = options.Logger
+	if config.Logger == nil {
+		config.Logger = discard
+	}
+
+	return run(ctx, func() (*PDFResult, error) {
+		builder := book.NewPDFBuilder(&config)
+		if err := builder.BuildPDF(texPath, pdfPath); err != nil {
+			return nil, err
+		}
+		info, err := builder.GetPDFInfo(pdfPath)
+		if err != nil {
+			return nil, err
+		}
+		return &PDFResult{Path: info.FilePath, Size: info.FileSize, PageCount: info.PageCount}, nil
+	})
+}
+
+// run calls fn unless ctx has already ended, and returns when fn does or ctx ends. The
+// generator can't be interrupted, so fn keeps running to the end in the background after
+// ctx ends, and its result is discarded.
+func run[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
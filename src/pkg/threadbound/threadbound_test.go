@@ -0,0 +1,169 @@
+package threadbound
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"threadbound/internal/database"
+)
+
+// newTestDB writes a database with a short conversation
+func newTestDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "chat.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.GetConnection().Exec(database.Schema + `
+		INSERT INTO handle (ROWID, id, country, service) VALUES (1, '+15551234567', 'us', 'iMessage');
+		INSERT INTO message (ROWID, guid, text, date, handle_id, is_from_me) VALUES
+			(1, 'm1', 'Dinner tonight?', 700000000000000000, 1, 0),
+			(2, 'm2', 'Yes please', 700000060000000000, 0, 1);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenMissingDatabase(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "chat.db"), Options{}); err == nil {
+		t.Error("Expected an error for a missing database")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Open(newTestDB(t), Options{Title: "Dinner Plans", AttachmentsPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := src.Generate(context.Background(), "txt", &out); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{"Dinner Plans", "Dinner tonight?", "Yes please"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the book to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	if err := src.Generate(context.Background(), "epub", &out); err == nil || !strings.Contains(err.Error(), "unsupported format") {
+		t.Errorf("Expected an unsupported format error, got %v", err)
+	}
+}
+
+func TestFilterAndStats(t *testing.T) {
+	src, err := Open(newTestDB(t), Options{AttachmentsPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := src.Filter("has:nothing"); err == nil {
+		t.Error("Expected an invalid filter to be rejected")
+	}
+
+	filtered, err := src.Filter("from:me")
+	if err != nil {
+		t.Fatalf("Filter failed: %v", err)
+	}
+	stats, err := filtered.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalMessages != 1 {
+		t.Errorf("Expected 1 message from me, got %d", stats.TotalMessages)
+	}
+	if stats, err := src.Stats(context.Background()); err != nil || stats.TotalMessages != 2 {
+		t.Errorf("Expected filtering to leave the source unchanged, got %+v (err %v)", stats, err)
+	}
+}
+
+func TestStatsReadAttributedBody(t *testing.T) {
+	path := newTestDB(t)
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Newer versions of Messages leave text empty and archive "On my way" in attributedBody
+	_, err = db.GetConnection().Exec(`INSERT INTO message (ROWID, guid, date, handle_id, is_from_me, attributedBody)
+		VALUES (3, 'm3', 700000120000000000, 0, 1, X'040B73747265616D747970656481E803840140848484124E5341747472696275746564537472696E67008484084E534F626A656374008592848484084E53537472696E67019484012B094F6E206D792077617986840269490105928484840C4E5344696374696F6E61727900')`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Open(path, Options{AttachmentsPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	stats, err := src.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TextMessages != 3 {
+		t.Errorf("Expected the archived text counted, as the book prints it, got %d of %d with text", stats.TextMessages, stats.TotalMessages)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	src, err := Open(newTestDB(t), Options{AttachmentsPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	if err := src.Generate(ctx, "txt", &out); err != context.Canceled || out.Len() != 0 {
+		t.Errorf("Expected nothing written for a cancelled context, got %v and %d bytes", err, out.Len())
+	}
+	if _, err := BuildPDF(ctx, "book.tex", "book.pdf", PDFOptions{}); err != context.Canceled {
+		t.Errorf("Expected BuildPDF to stop for a cancelled context, got %v", err)
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	if _, err := Open(newTestDB(t), Options{Timezone: "Mars/Olympus_Mons"}); err == nil {
+		t.Error("Expected an unknown timezone to be rejected")
+	}
+
+	src, err := Open(newTestDB(t), Options{AttachmentsPath: t.TempDir(), Timezone: "Asia/Tokyo"})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	stats, err := src.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	// The first message was sent at 20:26 UTC on March 8, already March 9 in Tokyo
+	if stats.StartDate.Location().String() != "Asia/Tokyo" || stats.StartDate.Day() != 9 {
+		t.Errorf("Expected the messages to be dated in Tokyo, got %v", stats.StartDate)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	if _, err := Open(newTestDB(t), Options{Locale: "tlh"}); err == nil {
+		t.Error("Expected an unknown locale to be rejected")
+	}
+
+	src, err := Open(newTestDB(t), Options{AttachmentsPath: t.TempDir(), Timezone: "UTC", Locale: "fr"})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	var out bytes.Buffer
+	if err := src.Generate(context.Background(), "txt", &out); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{"--- mercredi 8 mars 2023 ---", "Moi: Yes please"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the book to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}